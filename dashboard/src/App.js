@@ -78,7 +78,7 @@ function App() {
 
   const fetchDashboardMetrics = async () => {
     try {
-      const response = await fetch(`${API_URL}/api/analytics/dashboard/metrics`);
+      const response = await fetch(`${API_URL}/api/v1/analytics/dashboard/metrics`);
       const data = await response.json();
       if (data.status === 'success' && data.data) {
         setDashboardMetrics(data.data);
@@ -91,7 +91,7 @@ function App() {
 
   const fetchTopCreators = async () => {
     try {
-      const response = await fetch(`${API_URL}/api/analytics/dashboard/top-creators?limit=10`);
+      const response = await fetch(`${API_URL}/api/v1/analytics/dashboard/top-creators?limit=10`);
       const data = await response.json();
       if (data.status === 'success' && data.data) {
         setTopCreators(data.data);
@@ -105,7 +105,7 @@ function App() {
   const fetchTrending = async () => {
     try {
       setIsLoading(true);
-      const response = await fetch(`${API_URL}/api/analytics/trending?limit=20`);
+      const response = await fetch(`${API_URL}/api/v1/analytics/trending?limit=20`);
       const data = await response.json();
       const trending = data.data || data.trending || [];
       
@@ -63,8 +63,8 @@ else
 fi
 
 echo ""
-echo -n "Testing $API_URL/api/analytics/trending ... "
-trending_status=$(curl -s -o /dev/null -w "%{http_code}" "$API_URL/api/analytics/trending?limit=5")
+echo -n "Testing $API_URL/api/v1/analytics/trending ... "
+trending_status=$(curl -s -o /dev/null -w "%{http_code}" "$API_URL/api/v1/analytics/trending?limit=5")
 
 if [ "$trending_status" -eq 200 ]; then
     echo -e "${GREEN}✓ OK (HTTP $trending_status)${NC}"
@@ -296,7 +296,7 @@ fi
 
 echo ""
 echo -e "${YELLOW}Testing trending endpoint...${NC}"
-if curl -f "${SERVICE_URL}/api/analytics/trending?limit=5" > /dev/null 2>&1; then
+if curl -f "${SERVICE_URL}/api/v1/analytics/trending?limit=5" > /dev/null 2>&1; then
     echo -e "${GREEN}✓ Trending endpoint is working${NC}"
 else
     echo -e "${YELLOW}⚠ Trending endpoint returned an error (this is normal if no data exists yet)${NC}"
@@ -3,19 +3,24 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+	// Embedded zone database so REPORTING_TIMEZONE and ?tz= work in slim runtime images
+	_ "time/tzdata"
 
 	"net/http"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 	"confluent-viral-intelligence/internal/config"
-	"confluent-viral-intelligence/internal/handlers"
+	"confluent-viral-intelligence/internal/grpcapi"
 	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/pb/viralv1"
+	"confluent-viral-intelligence/internal/routes"
 	"confluent-viral-intelligence/internal/services"
 )
 
@@ -34,12 +39,38 @@ func main() {
 	// Initialize services
 	ctx := context.Background()
 
-	// Kafka producer
-	producer, err := services.NewKafkaProducer(cfg)
-	if err != nil {
-		logger.Fatalf("Failed to create Kafka producer: %v", err)
+	// Soft quota and memory warnings are delivered through the alerting channels
+	alerter := services.NewAlerter(cfg)
+	services.Quotas.Configure(cfg, alerter)
+	services.Memory.Configure(cfg, alerter)
+
+	// Daily analytics are bucketed in the reporting time zone
+	if _, err := cfg.ReportingLocation(); err != nil {
+		logger.Fatalf("Invalid REPORTING_TIMEZONE %q: %v", cfg.ReportingTimezone, err)
+	}
+
+	// A mistyped Gemini safety threshold would silently keep the model's default filtering
+	if err := cfg.ValidateGemini(); err != nil {
+		logger.Fatalf("Invalid Gemini settings: %v", err)
+	}
+
+	// The trending score formula from the environment must be usable before Firestore overrides it
+	if err := services.ScoringConfigFrom(cfg).Validate(); err != nil {
+		logger.Fatalf("Invalid trending score formula: %v", err)
+	}
+
+	if cfg.ViralPredictionMode == services.ViralPredictionModeHeuristic {
+		logger.Warn("⚠️ The heuristic viral predictor is deprecated; set VIRAL_PREDICTION_MODE=gemini or endpoint, using VIRAL_PREDICTION_DUAL_RUN_UNTIL to compare them first")
+	}
+	if time.Now().Before(cfg.ViralPredictionDualRunUntil) {
+		logger.Infof("⚖️ Viral predictor dual run active until %s", cfg.ViralPredictionDualRunUntil.Format(time.RFC3339))
+	}
+
+	// Read replicas only serve read endpoints and WebSocket fan-out
+	readReplica := cfg.IsReadReplica()
+	if readReplica {
+		logger.Info("📖 Running as read replica: ingestion, Kafka and background jobs are disabled")
 	}
-	defer producer.Close()
 
 	// Firestore client
 	firestoreClient, err := services.NewFirestoreClient(ctx, cfg)
@@ -48,48 +79,365 @@ func main() {
 	}
 	defer firestoreClient.Close()
 
-	// Vertex AI client
-	vertexAI, err := services.NewVertexAIClient(ctx, cfg)
+	// Every instance scores posts (read replicas for fallback trending), so every instance
+	// follows the formula's Firestore overrides
+	firestoreClient.Scoring().Start()
+	defer firestoreClient.Scoring().Stop()
+
+	// Cache for AI responses (in memory, or in Firestore to survive restarts)
+	aiCache, err := services.NewAICache(cfg, firestoreClient)
 	if err != nil {
-		logger.Fatalf("Failed to create Vertex AI client: %v", err)
+		logger.Fatalf("Failed to create AI cache: %v", err)
 	}
-	defer vertexAI.Close()
 
-	// Event processor
-	eventProcessor := services.NewEventProcessor(producer, firestoreClient, vertexAI, cfg)
+	// Vertex AI client and embedding service; media analysis, comment sentiment, embeddings and
+	// keyword backfill are only available with the vertex AI provider
+	var (
+		vertexAI   *services.VertexAIClient
+		embeddings *services.EmbeddingService
+	)
+	if cfg.AIProvider == services.AIProviderVertex {
+		vertexAI, err = services.NewVertexAIClient(ctx, cfg, aiCache)
+		if err != nil {
+			logger.Fatalf("Failed to create Vertex AI client: %v", err)
+		}
+		defer vertexAI.Close()
 
-	// Start Kafka consumer in background
-	consumer, err := services.NewKafkaConsumer(cfg, eventProcessor)
-	if err != nil {
-		logger.Fatalf("Failed to create Kafka consumer: %v", err)
+		// Embedding service for semantic similarity search
+		embeddings, err = services.NewEmbeddingService(ctx, cfg, firestoreClient)
+		if err != nil {
+			logger.Fatalf("Failed to create embedding service: %v", err)
+		}
+		defer embeddings.Close()
 	}
-	if err := consumer.Start(); err != nil {
-		logger.Fatalf("Failed to start Kafka consumer: %v", err)
+
+	// AI provider behind keyword extraction, moderation and viral prediction
+	aiProvider, err := services.NewAIProvider(cfg, vertexAI)
+	if err != nil {
+		logger.Fatalf("Failed to create AI provider: %v", err)
 	}
-	defer consumer.Close()
+	logger.Infof("🤖 Using %s AI provider", aiProvider.Name())
 
 	// WebSocket hub
 	wsHub := services.NewWebSocketHub()
 	go wsHub.Run()
 
-	// Start trending updater (recalculates scores every 5 minutes)
-	trendingUpdater := services.NewTrendingUpdater(firestoreClient, 5*time.Minute)
-	trendingUpdater.Start()
-	defer trendingUpdater.Stop()
+	// With fan-out, hub messages reach the clients of every replica through Kafka; read
+	// replicas deliver them too, though they publish none
+	if cfg.WebSocketFanout {
+		fanout, err := services.NewHubFanout(cfg, wsHub)
+		if err != nil {
+			logger.Fatalf("Failed to create WebSocket fan-out: %v", err)
+		}
+		if err := fanout.Start(); err != nil {
+			logger.Fatalf("Failed to start WebSocket fan-out: %v", err)
+		}
+		defer fanout.Stop()
+	}
+
+	// Live queries sent over WebSocket connections, answered from Firestore
+	commands := services.NewCommandDispatcher(firestoreClient, time.Duration(cfg.WebSocketWatchIntervalSeconds)*time.Second)
+	wsHub.UseCommands(commands)
+	commands.Start()
+	defer commands.Stop()
+
+	// Live dashboard aggregates, kept in memory rather than read from Firestore
+	if cfg.DashboardTickSeconds > 0 {
+		dashboardTicker := services.NewDashboardTicker(wsHub, services.Dashboard, time.Duration(cfg.DashboardTickSeconds)*time.Second)
+		dashboardTicker.Start()
+		defer dashboardTicker.Stop()
+	}
 
-	// Create post indexer for initial indexing
-	postIndexer := services.NewPostIndexer(firestoreClient)
-	
-	// Run initial indexing in background
-	go func() {
-		logger.Info("🚀 Starting initial post indexing...")
-		if err := postIndexer.IndexAllPosts(); err != nil {
-			logger.Errorf("❌ Initial indexing failed: %v", err)
+	// Dependencies checked by the readiness probe; Kafka is added on processing instances
+	health := services.NewHealthChecker(time.Duration(cfg.HealthCheckTimeoutMs) * time.Millisecond)
+	health.Register("firestore", true, firestoreClient.Ping)
+	if vertexAI != nil {
+		// AI calls fall back when Vertex AI is down, so it does not take the instance out
+		health.Register("vertex_ai", false, vertexAI.Ping)
+	}
+
+	var (
+		eventProcessor    *services.EventProcessor
+		postIndexer       *services.PostIndexer
+		remixArchiver     *services.RemixArchiver
+		keywordBackfiller *services.KeywordBackfiller
+		tierClassifier    *services.CreatorTierClassifier
+		webhooks          *services.WebhookDispatcher
+		operations        *services.OperationQueue
+	)
+
+	if readReplica {
+		// Reads go straight to Firestore; there is nothing to produce or moderate
+		eventProcessor = services.NewEventProcessor(nil, firestoreClient, vertexAI, aiProvider, embeddings, wsHub, cfg)
+	} else {
+		// Kafka producer
+		producer, err := services.NewKafkaProducer(cfg)
+		if err != nil {
+			logger.Fatalf("Failed to create Kafka producer: %v", err)
+		}
+		defer producer.Close()
+		health.Register("kafka_producer", true, producer.Ping)
+
+		if cfg.WebSocketFanout {
+			wsHub.UseRelay(producer)
+		}
+
+		// Content moderation via the AI provider's safety checks
+		moderation := services.NewModerationService(aiProvider, producer, firestoreClient, cfg)
+
+		// Creator audience sketches for overlap analytics
+		audienceTracker := services.NewAudienceTracker(firestoreClient, time.Duration(cfg.AudienceFlushSeconds)*time.Second)
+		audienceTracker.Start()
+		defer audienceTracker.Stop()
+
+		// Daily viewer sketches behind the returning-viewer cohorts
+		retentionTracker := services.NewRetentionTracker(firestoreClient, time.Duration(cfg.RetentionFlushSeconds)*time.Second)
+		retentionTracker.Start()
+		defer retentionTracker.Stop()
+
+		// Jobs that must not run on several instances at once follow the holder of a lease
+		var leader *services.LeaderElector
+		if cfg.LeaderLeaseSeconds > 0 {
+			leader = services.NewLeaderElector(firestoreClient, "background_jobs", time.Duration(cfg.LeaderLeaseSeconds)*time.Second)
+			leader.Start()
+			defer leader.Stop()
 		}
-	}()
+
+		// Viral predictions compared with the engagement posts actually reach; every instance
+		// records predictions, the leader checks their outcomes
+		predictionTracker := services.NewPredictionTracker(firestoreClient, cfg.PredictionViralScoreThreshold, time.Duration(cfg.PredictionCheckIntervalMinutes)*time.Minute)
+		predictionTracker.UseLeaderElector(leader)
+		predictionTracker.Start()
+		defer predictionTracker.Stop()
+
+		// Engagement velocity anomalies (bot rings, unexplained drops) broadcast to WebSocket clients
+		var anomalyDetector *services.AnomalyDetector
+		if cfg.AnomalyWindowSeconds > 0 {
+			anomalyDetector = services.NewAnomalyDetector(firestoreClient, wsHub, time.Duration(cfg.AnomalyWindowSeconds)*time.Second, cfg.AnomalyZScore, cfg.AnomalyMinEvents, cfg.AnomalyWarmupWindows)
+			anomalyDetector.Start()
+			defer anomalyDetector.Stop()
+		}
+
+		// Anonymized, aggregated engagement for data partners
+		var partnerStreamer *services.PartnerStreamer
+		partnerStreams, err := services.ParsePartnerStreams(cfg.PartnerStreams)
+		if err != nil {
+			logger.Fatalf("Failed to configure partner streams: %v", err)
+		}
+		if cfg.PartnerStreamWindowSeconds > 0 && len(partnerStreams) > 0 {
+			partnerStreamer = services.NewPartnerStreamer(producer, firestoreClient, partnerStreams, time.Duration(cfg.PartnerStreamWindowSeconds)*time.Second, cfg.PartnerStreamBucket)
+			partnerStreamer.Start()
+			defer partnerStreamer.Stop()
+		}
+
+		// Posts alerted at a tier are not alerted at it again until the cooldown passes
+		var alertCooldown *services.ViralAlertCooldown
+		if cfg.ViralAlertCooldownMinutes > 0 {
+			alertCooldown = services.NewViralAlertCooldown(firestoreClient, cfg, time.Duration(cfg.ViralAlertCooldownMinutes)*time.Minute, time.Duration(cfg.ViralAlertStateFlushSeconds)*time.Second)
+			alertCooldown.Start()
+			defer alertCooldown.Stop()
+		}
+
+		// Dashboard metrics maintained from consumed events instead of scanned per request
+		metricsAggregator := services.NewMetricsAggregator(firestoreClient, time.Duration(cfg.DashboardMetricsFlushSeconds)*time.Second, time.Duration(cfg.DashboardMetricsRebuildHours)*time.Hour)
+		metricsAggregator.Start()
+		defer metricsAggregator.Stop()
+
+		// Hourly and daily engagement rollups behind the engagement trends
+		rollups := services.NewRollupService(firestoreClient, time.Duration(cfg.RollupFlushSeconds)*time.Second)
+		rollups.Start()
+		defer rollups.Stop()
+
+		// Hourly snapshots of every trending score write behind the score sparklines
+		scoreHistory := services.NewScoreHistory(firestoreClient, time.Duration(cfg.ScoreHistoryFlushSeconds)*time.Second, time.Duration(cfg.ScoreHistoryRetentionDays)*24*time.Hour)
+		firestoreClient.SetScoreHistory(scoreHistory)
+		scoreHistory.Start()
+		defer scoreHistory.Stop()
+
+		// Event processor
+		eventProcessor = services.NewEventProcessor(producer, firestoreClient, vertexAI, aiProvider, embeddings, wsHub, cfg)
+		eventProcessor.UseModeration(moderation)
+		eventProcessor.UseAudience(audienceTracker)
+		eventProcessor.UsePredictions(predictionTracker)
+		eventProcessor.UseAnomalies(anomalyDetector)
+		eventProcessor.UsePartners(partnerStreamer)
+		eventProcessor.UseAlertCooldown(alertCooldown)
+		eventProcessor.UseMetrics(metricsAggregator)
+		eventProcessor.UseRollups(rollups)
+		eventProcessor.UseRetention(retentionTracker)
+
+		// Viral alerts, score thresholds and new trending entries for registered webhooks
+		webhooks = services.NewWebhookDispatcher(firestoreClient, cfg)
+		eventProcessor.UseWebhooks(webhooks)
+		webhooks.Start()
+		defer webhooks.Stop()
+
+		// Category and region rankings behind the scoped trending feeds
+		if cfg.TrendingScopeFlushSeconds > 0 {
+			scopedTrending := services.NewScopedTrending(firestoreClient, time.Duration(cfg.TrendingScopeFlushSeconds)*time.Second, cfg.TrendingRegionGroups)
+			firestoreClient.SetScopedTrending(scopedTrending)
+			eventProcessor.UseScopedTrending(scopedTrending)
+			scopedTrending.Start()
+			defer scopedTrending.Stop()
+		}
+
+		// Posts users viewed, kept out of their recommendations
+		if cfg.SeenPostsTTLHours > 0 {
+			seenPosts := services.NewSeenPostTracker(firestoreClient, time.Duration(cfg.SeenPostsFlushSeconds)*time.Second)
+			eventProcessor.UseSeenPosts(seenPosts)
+			seenPosts.Start()
+			defer seenPosts.Stop()
+		}
+
+		// In-service recommendations, learning from the events consumed from here on
+		var recommendations *services.RecommendationEngine
+		if cfg.RecommendationIntervalMinutes > 0 {
+			recommendations, err = services.NewRecommendationEngine(firestoreClient, time.Duration(cfg.RecommendationFlushSeconds)*time.Second, time.Duration(cfg.RecommendationIntervalMinutes)*time.Minute, time.Duration(cfg.RecommendationActiveDays)*24*time.Hour, cfg.RecommendationTopN, cfg.RecommendationMode)
+			if err != nil {
+				logger.Fatalf("Failed to create recommendation engine: %v", err)
+			}
+			eventProcessor.UseRecommendations(recommendations)
+		}
+
+		// Content ingestion accepted with 202 and run in the background
+		operations = services.NewOperationQueue(cfg.OperationQueueSize, cfg.OperationWorkers, time.Duration(cfg.OperationRetentionMinutes)*time.Minute)
+		operations.Start()
+		defer operations.Stop()
+
+		// Start Kafka consumer in background
+		consumer, err := services.NewKafkaConsumer(cfg, eventProcessor)
+		if err != nil {
+			logger.Fatalf("Failed to create Kafka consumer: %v", err)
+		}
+		if err := consumer.Start(); err != nil {
+			logger.Fatalf("Failed to start Kafka consumer: %v", err)
+		}
+		defer consumer.Close()
+		health.Register("kafka_consumer", false, consumer.CheckAssignment)
+
+		// Start trending updater (recalculates changed scores and a shard of the others every 5 minutes)
+		trendingUpdater := services.NewTrendingUpdater(firestoreClient, 5*time.Minute, cfg.TrendingDecayTop, cfg.TrendingDecayShardSize)
+		firestoreClient.SetTrendingUpdater(trendingUpdater)
+		trendingUpdater.UseLeaderElector(leader)
+		trendingUpdater.Start()
+		defer trendingUpdater.Stop()
+
+		// Recommendations are built by the leader, from the engagement every instance flushes
+		if recommendations != nil {
+			recommendations.UseLeaderElector(leader)
+			recommendations.Start()
+			defer recommendations.Stop()
+		}
+
+		// Expired recommendations are refreshed or deleted by the leader
+		if cfg.RecommendationTTLHours > 0 && cfg.RecommendationSweepMinutes > 0 {
+			recommendationSweeper := services.NewRecommendationSweeper(firestoreClient, time.Duration(cfg.RecommendationSweepMinutes)*time.Minute)
+			recommendationSweeper.UseLeaderElector(leader)
+			recommendationSweeper.Start()
+			defer recommendationSweeper.Stop()
+		}
+
+		// Start remix archiver (the leader rolls finished remix chains into cold storage daily)
+		remixArchiver = services.NewRemixArchiver(firestoreClient, time.Duration(cfg.RemixArchiveAfterDays)*24*time.Hour, 24*time.Hour)
+		remixArchiver.UseLeaderElector(leader)
+		remixArchiver.Start()
+		defer remixArchiver.Stop()
+
+		// The leader classifies creators into tiers and announces tier changes
+		if cfg.CreatorTierIntervalMinutes > 0 {
+			tierClassifier = services.NewCreatorTierClassifier(firestoreClient, producer, time.Duration(cfg.CreatorTierIntervalMinutes)*time.Minute)
+			tierClassifier.UseLeaderElector(leader)
+			tierClassifier.Start()
+			defer tierClassifier.Stop()
+		}
+
+		// Create post indexer for initial indexing
+		postIndexer = services.NewPostIndexer(firestoreClient)
+		if embeddings != nil {
+			postIndexer.UseEmbeddings(embeddings)
+		}
+
+		// Run initial indexing in background, once, on the first instance elected leader
+		var indexOnce sync.Once
+		indexAll := func() {
+			indexOnce.Do(func() {
+				logger.Info("🚀 Starting initial post indexing...")
+				if err := postIndexer.IndexAllPosts(); err != nil {
+					logger.Errorf("❌ Initial indexing failed: %v", err)
+				}
+			})
+		}
+		if leader != nil {
+			leader.OnElected(indexAll)
+		} else {
+			go indexAll()
+		}
+
+		// Keyword backfill for posts created before keyword extraction existed (started via admin API)
+		if vertexAI != nil {
+			keywordBackfiller = services.NewKeywordBackfiller(firestoreClient, vertexAI, cfg)
+		}
+	}
+
+	// Expired entries of the AI response cache and other TTL caches are evicted on one schedule
+	var cacheMaintainer *services.CacheMaintainer
+	if cfg.CacheMaintenanceIntervalSeconds > 0 {
+		cacheMaintainer = services.NewCacheMaintainer(time.Duration(cfg.CacheMaintenanceIntervalSeconds) * time.Second)
+		eventProcessor.RegisterCaches(cacheMaintainer)
+		if operations != nil {
+			operations.RegisterCaches(cacheMaintainer)
+		}
+		cacheMaintainer.Start()
+		defer cacheMaintainer.Stop()
+	}
+
+	// API keys guarding the admin and ingestion routes
+	apiKeys, err := services.NewAPIKeyStore(cfg)
+	if err != nil {
+		logger.Fatalf("Invalid API_KEYS: %v", err)
+	}
+	if cfg.AllowUnauthenticated && cfg.Environment == "production" {
+		logger.Fatalf("ALLOW_UNAUTHENTICATED must not be set in production")
+	}
+	switch {
+	case apiKeys == nil && cfg.Environment == "production":
+		logger.Fatalf("API_KEYS must be configured in production")
+	case apiKeys == nil && cfg.AllowUnauthenticated:
+		logger.Warnf("⚠️ No API keys configured and ALLOW_UNAUTHENTICATED set, admin and ingestion routes are open")
+	case apiKeys == nil:
+		logger.Warnf("⚠️ No API keys configured, admin and ingestion routes reject every request")
+	default:
+		logger.Infof("🔑 %d API keys configured", len(apiKeys.Keys()))
+	}
+
+	// Per-IP and per-user request rate limits on ingestion and analytics
+	requestLimiter, err := services.NewRequestLimiter(cfg)
+	if err != nil {
+		logger.Fatalf("Failed to create request rate limiter: %v", err)
+	}
+	if requestLimiter != nil {
+		defer requestLimiter.Close()
+		if cacheMaintainer != nil {
+			requestLimiter.RegisterCaches(cacheMaintainer)
+		}
+		logger.Infof("🚦 Request rate limits on the %s backend", requestLimiter.Stats().Backend)
+	}
 
 	// Setup HTTP server
-	router := setupRouter(cfg, eventProcessor, wsHub, postIndexer)
+	router := routes.New(cfg, routes.Dependencies{
+		Processor:         eventProcessor,
+		WSHub:             wsHub,
+		PostIndexer:       postIndexer,
+		RemixArchiver:     remixArchiver,
+		KeywordBackfiller: keywordBackfiller,
+		TierClassifier:    tierClassifier,
+		CacheMaintainer:   cacheMaintainer,
+		APIKeys:           apiKeys,
+		RequestLimiter:    requestLimiter,
+		Webhooks:          webhooks,
+		Operations:        operations,
+		Health:            health,
+	})
 
 	// Start server
 	srv := &http.Server{
@@ -106,6 +454,32 @@ func main() {
 
 	logger.Infof("Server started on port %s", cfg.Port)
 
+	// gRPC streaming API on its own port, sharing the event processor and the hub
+	var grpcServer *grpc.Server
+	var grpcAPI *grpcapi.Server
+	if cfg.GRPCPort != "0" {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			logger.Fatalf("Failed to listen on gRPC port %s: %v", cfg.GRPCPort, err)
+		}
+		// The same API keys, rate limits and validation as the HTTP routes
+		access := grpcapi.Access{
+			Keys:                 apiKeys,
+			Limiter:              requestLimiter,
+			RequireRead:          cfg.APIKeyRequireRead,
+			AllowUnauthenticated: cfg.AllowUnauthenticated,
+		}
+		grpcAPI = grpcapi.NewServer(eventProcessor, wsHub, !readReplica)
+		grpcServer = grpc.NewServer(access.ServerOptions()...)
+		viralv1.RegisterViralIntelligenceServer(grpcServer, grpcAPI)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Fatalf("Failed to serve gRPC: %v", err)
+			}
+		}()
+		logger.Infof("gRPC server started on port %s", cfg.GRPCPort)
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -116,81 +490,28 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatal("Server forced to shutdown:" + err.Error())
+	// gRPC drains alongside HTTP: subscriptions are ended, and ingestion streams still open at
+	// the deadline are cut
+	grpcStopped := make(chan struct{})
+	if grpcServer != nil {
+		grpcAPI.Drain()
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
 	}
 
-	logger.Info("Server exited")
-}
-
-func setupRouter(cfg *config.Config, processor *services.EventProcessor, wsHub *services.WebSocketHub, postIndexer *services.PostIndexer) *gin.Engine {
-	if cfg.Environment == "production" {
-		gin.SetMode(gin.ReleaseMode)
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Fatal("Server forced to shutdown:" + err.Error())
 	}
 
-	router := gin.Default()
-
-	// CORS configuration
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     cfg.AllowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy"})
-	})
-
-	// API routes
-	api := router.Group("/api")
-	{
-		// Event ingestion
-		events := api.Group("/events")
-		{
-			h := handlers.NewEventHandler(processor)
-			events.POST("/interaction", h.HandleInteraction)
-			events.POST("/content", h.HandleContentMetadata)
-			events.POST("/view", h.HandleView)
-			events.POST("/remix", h.HandleRemix)
-		}
-
-		// Analytics
-		analytics := api.Group("/analytics")
-		{
-			h := handlers.NewAnalyticsHandler(processor.GetFirestoreClient())
-			analytics.GET("/trending", h.GetTrending)
-			analytics.GET("/post/:id/stats", h.GetPostStats)
-			analytics.GET("/user/:id/recommendations", h.GetRecommendations)
-			
-			// Dashboard analytics
-			analytics.GET("/dashboard/metrics", h.GetDashboardMetrics)
-			analytics.GET("/dashboard/top-creators", h.GetTopCreators)
-			analytics.GET("/dashboard/content-types", h.GetContentTypeBreakdown)
-			analytics.GET("/dashboard/trends", h.GetEngagementTrends)
-		}
-
-		// Admin operations
-		admin := api.Group("/admin")
-		{
-			// Trigger full post indexing
-			admin.POST("/index-posts", func(c *gin.Context) {
-				go func() {
-					if err := postIndexer.IndexAllPosts(); err != nil {
-						logger.Errorf("❌ Post indexing failed: %v", err)
-					}
-				}()
-				c.JSON(200, gin.H{"status": "indexing started"})
-			})
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
 		}
 	}
 
-	// WebSocket endpoint
-	wsHandler := handlers.NewWebSocketHandler(wsHub)
-	router.GET("/ws", wsHandler.HandleWebSocket)
-
-	return router
+	logger.Info("Server exited")
 }
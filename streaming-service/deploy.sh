@@ -49,6 +49,12 @@ if [ -f .env ]; then
     export $(grep -v '^#' .env | xargs)
 fi
 
+# Protected routes need API keys in production
+if [ -z "${API_KEYS}" ]; then
+    echo -e "${RED}Error: API_KEYS is not set; the service refuses to start in production without API keys${NC}"
+    exit 1
+fi
+
 # Deploy to Cloud Run
 echo -e "${YELLOW}Deploying to Cloud Run...${NC}"
 gcloud run deploy ${SERVICE_NAME} \
@@ -61,7 +67,7 @@ gcloud run deploy ${SERVICE_NAME} \
   --timeout 300s \
   --min-instances 1 \
   --max-instances 10 \
-  --set-env-vars "CONFLUENT_BOOTSTRAP_SERVERS=${CONFLUENT_BOOTSTRAP_SERVERS:-pkc-placeholder},CONFLUENT_API_KEY=${CONFLUENT_API_KEY:-U5AZXVJ2MO4TNZQO},CONFLUENT_API_SECRET=${CONFLUENT_API_SECRET:-placeholder},GOOGLE_CLOUD_PROJECT=${GOOGLE_CLOUD_PROJECT:-yarimai},FIRESTORE_PROJECT_ID=${FIRESTORE_PROJECT_ID:-yarimai},VERTEX_AI_LOCATION=${VERTEX_AI_LOCATION:-us-central1},ENVIRONMENT=production,LOG_LEVEL=warn,ALLOWED_ORIGINS=http://localhost:3000;https://viral-intelligence-dashboard.web.app;https://viral-intelligence-dashboard.firebaseapp.com;https://yarimai.web.app;https://yarimai.firebaseapp.com;https://yarimai.com" \
+  --set-env-vars "^|^CONFLUENT_BOOTSTRAP_SERVERS=${CONFLUENT_BOOTSTRAP_SERVERS:-pkc-placeholder}|CONFLUENT_API_KEY=${CONFLUENT_API_KEY:-U5AZXVJ2MO4TNZQO}|CONFLUENT_API_SECRET=${CONFLUENT_API_SECRET:-placeholder}|GOOGLE_CLOUD_PROJECT=${GOOGLE_CLOUD_PROJECT:-yarimai}|FIRESTORE_PROJECT_ID=${FIRESTORE_PROJECT_ID:-yarimai}|VERTEX_AI_LOCATION=${VERTEX_AI_LOCATION:-us-central1}|ENVIRONMENT=production|LOG_LEVEL=warn|ALLOWED_ORIGINS=http://localhost:3000;https://viral-intelligence-dashboard.web.app;https://viral-intelligence-dashboard.firebaseapp.com;https://yarimai.web.app;https://yarimai.firebaseapp.com;https://yarimai.com|API_KEYS=${API_KEYS}|TRUSTED_PROXIES=${TRUSTED_PROXIES:-169.254.0.0/16}" \
   --service-account "${SERVICE_ACCOUNT:-799474804867-compute@developer.gserviceaccount.com}"
 
 # Get the service URL
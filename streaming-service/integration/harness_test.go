@@ -0,0 +1,306 @@
+//go:build integration
+
+// Package integration runs the full streaming service against Redpanda and the Firestore
+// emulator and drives it through its public HTTP, Kafka and WebSocket interfaces. Start the
+// dependencies with docker-compose.yml in this directory, then run
+//
+//	go test -tags integration -count=1 ./integration/...
+//
+// INTEGRATION_KAFKA_BROKERS and FIRESTORE_EMULATOR_HOST point the suite at other instances.
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/contracts"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Project the service and the suite use inside the Firestore emulator
+const firestoreProject = "viral-intelligence-integration"
+
+// API keys the suite calls the service with, configured with the ingest and admin roles
+const (
+	ingestAPIKey = "integration-ingest-key"
+	adminAPIKey  = "integration-admin-key"
+)
+
+var (
+	kafkaBrokers = envOr("INTEGRATION_KAFKA_BROKERS", "localhost:19092")
+	emulatorHost = envOr("FIRESTORE_EMULATOR_HOST", "localhost:8081")
+
+	// Base URL of the service under test
+	serviceURL string
+
+	// Prefix of the documents a run creates, so runs against a long-lived emulator do not collide
+	runID = fmt.Sprintf("it%d", time.Now().UnixNano())
+
+	store *firestore.Client
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	// The Firestore client talks to the emulator whenever this is set
+	os.Setenv("FIRESTORE_EMULATOR_HOST", emulatorHost)
+
+	ctx := context.Background()
+	if err := createTopics(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: Kafka at %s is not reachable: %v\n", kafkaBrokers, err)
+		return 1
+	}
+
+	var err error
+	store, err = firestore.NewClient(ctx, firestoreProject)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: failed to create Firestore client: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	stop, err := startService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: failed to start service: %v\n", err)
+		return 1
+	}
+	defer stop()
+
+	return m.Run()
+}
+
+// createTopics creates every topic the service produces or consumes; the consumer only sees
+// topics that exist when it subscribes
+func createTopics(ctx context.Context) error {
+	admin, err := kafka.NewAdminClient(&kafka.ConfigMap{"bootstrap.servers": kafkaBrokers})
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	var specs []kafka.TopicSpecification
+	for _, name := range append(contracts.Names(), "dead-letter-queue") {
+		specs = append(specs, kafka.TopicSpecification{Topic: name, NumPartitions: 1, ReplicationFactor: 1})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	results, err := admin.CreateTopics(ctx, specs)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if code := result.Error.Code(); code != kafka.ErrNoError && code != kafka.ErrTopicAlreadyExists {
+			return fmt.Errorf("failed to create topic %s: %v", result.Topic, result.Error)
+		}
+	}
+	return nil
+}
+
+// startService builds the service and runs it against the test dependencies with the local
+// AI provider, returning a function that stops it
+func startService() (func(), error) {
+	dir, err := os.MkdirTemp("", "viral-intelligence-integration")
+	if err != nil {
+		return nil, err
+	}
+
+	binary := filepath.Join(dir, "streaming-service")
+	build := exec.Command("go", "build", "-o", binary, "./cmd")
+	build.Dir = ".."
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	serviceURL = "http://localhost:" + port
+
+	service := exec.Command(binary)
+	// Run outside the module so a developer's .env is not picked up
+	service.Dir = dir
+	service.Env = append(os.Environ(),
+		"PORT="+port,
+		"CONFLUENT_BOOTSTRAP_SERVERS="+kafkaBrokers,
+		"CONFLUENT_SECURITY_PROTOCOL=PLAINTEXT",
+		"FIRESTORE_EMULATOR_HOST="+emulatorHost,
+		"FIRESTORE_PROJECT_ID="+firestoreProject,
+		"GOOGLE_CLOUD_PROJECT="+firestoreProject,
+		"AI_PROVIDER=local",
+		"VIRAL_PREDICTION_MODE=heuristic",
+		"LOG_LEVEL=debug",
+		"API_KEYS="+apiKeyEntry("integration", "ingest", ingestAPIKey)+","+apiKeyEntry("integration-admin", "admin", adminAPIKey),
+	)
+	logFile, err := os.Create(filepath.Join(dir, "service.log"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	service.Stdout, service.Stderr = logFile, logFile
+	if err := service.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	stop := func() {
+		service.Process.Signal(os.Interrupt)
+		service.Wait()
+		logFile.Close()
+		if os.Getenv("INTEGRATION_KEEP_LOGS") != "" {
+			fmt.Fprintf(os.Stderr, "integration: service log kept at %s\n", logFile.Name())
+			return
+		}
+		os.RemoveAll(dir)
+	}
+
+	deadline := time.Now().Add(60 * time.Second)
+	for time.Now().Before(deadline) {
+		if resp, err := http.Get(serviceURL + "/health"); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return stop, nil
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	stop()
+	return nil, fmt.Errorf("service did not become healthy, see %s", logFile.Name())
+}
+
+// apiKeyEntry returns the API_KEYS entry of a key: its name, role and SHA-256
+func apiKeyEntry(name, role, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return name + ":" + role + ":" + hex.EncodeToString(sum[:])
+}
+
+func freePort() (string, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	return port, err
+}
+
+func envOr(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// seedPost creates a public post owned by creatorID and returns its ID
+func seedPost(t *testing.T, name, creatorID, contentType string) string {
+	t.Helper()
+	postID := runID + "-" + name
+	_, err := store.Collection("posts").Doc(postID).Set(context.Background(), map[string]interface{}{
+		"userId":        creatorID,
+		"contentType":   contentType,
+		"prompt":        "integration test post " + name,
+		"isPublic":      true,
+		"createdAt":     time.Now(),
+		"view_count":    0,
+		"like_count":    0,
+		"comment_count": 0,
+		"share_count":   0,
+		"remix_count":   0,
+	})
+	if err != nil {
+		t.Fatalf("failed to seed post %s: %v", postID, err)
+	}
+	return postID
+}
+
+// postJSON sends a JSON body to the service with the ingest API key and fails the test unless
+// it answers 200
+func postJSON(t *testing.T, path string, body interface{}) {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to encode %s body: %v", path, err)
+	}
+	req, err := http.NewRequest(http.MethodPost, serviceURL+path, strings.NewReader(string(payload)))
+	if err != nil {
+		t.Fatalf("failed to build POST %s: %v", path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", ingestAPIKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		t.Fatalf("POST %s returned %d: %s", path, resp.StatusCode, data)
+	}
+}
+
+// getData fetches a success response and decodes its data field, reporting the status code
+func getData(path string, data interface{}) (int, error) {
+	return getDataAs(path, "", data)
+}
+
+// getDataAs is getData sending apiKey as X-API-Key
+func getDataAs(path, apiKey string, data interface{}) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, serviceURL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+
+	envelope := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, json.Unmarshal(envelope.Data, data)
+}
+
+// eventually polls check until it reports done or the timeout passes
+func eventually(t *testing.T, timeout time.Duration, what string, check func() (bool, error)) {
+	t.Helper()
+	var lastErr error
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		done, err := check()
+		if done {
+			return
+		}
+		lastErr = err
+		time.Sleep(500 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s (last error: %v)", what, lastErr)
+}
@@ -0,0 +1,165 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/gorilla/websocket"
+)
+
+// Views posted to the API go through Kafka and the consumer before they reach the post stats
+func TestViewsUpdatePostStats(t *testing.T) {
+	postID := seedPost(t, "views", runID+"-creator", "image")
+
+	for i := 0; i < 3; i++ {
+		postJSON(t, "/api/v1/events/view", models.ViewEvent{
+			PostID:   postID,
+			UserID:   fmt.Sprintf("%s-viewer-%d", runID, i),
+			Duration: 5,
+			Platform: "web",
+		})
+	}
+	postJSON(t, "/api/v1/events/interaction", models.InteractionEvent{
+		PostID:    postID,
+		UserID:    runID + "-viewer-0",
+		EventType: "like",
+	})
+
+	eventually(t, 30*time.Second, "view and like counts", func() (bool, error) {
+		var stats models.TrendingScore
+		status, err := getData("/api/v1/analytics/post/"+postID+"/stats", &stats)
+		if err != nil || status != 200 {
+			return false, fmt.Errorf("status %d: %v", status, err)
+		}
+		if stats.ViewCount != 3 || stats.LikeCount != 1 {
+			return false, fmt.Errorf("views %d, likes %d", stats.ViewCount, stats.LikeCount)
+		}
+		return stats.Score > 0, nil
+	})
+}
+
+// A trending score from Flink is run through viral prediction, stored with its viral tier and
+// announced to WebSocket clients
+func TestTrendingScoreBroadcastsViralAlert(t *testing.T) {
+	postID := seedPost(t, "viral", runID+"-creator", "video")
+
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(serviceURL, "http", "ws", 1)+"/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to connect WebSocket: %v", err)
+	}
+	defer conn.Close()
+	// Give the hub time to register the client before anything is broadcast
+	time.Sleep(500 * time.Millisecond)
+
+	publishTrendingScore(t, models.TrendingScore{
+		PostID:             postID,
+		Score:              180,
+		EngagementRate:     0.3,
+		ViewCount:          1000,
+		LikeCount:          200,
+		CommentCount:       50,
+		ShareCount:         30,
+		RemixCount:         10,
+		EngagementVelocity: 25,
+		CalculatedAt:       time.Now(),
+		TimeWindow:         "5min",
+	})
+
+	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("no viral alert for post %s: %v", postID, err)
+		}
+		var alert struct {
+			Type             string  `json:"type"`
+			PostID           string  `json:"post_id"`
+			Tier             string  `json:"tier"`
+			ViralProbability float64 `json:"viral_probability"`
+		}
+		if err := json.Unmarshal(data, &alert); err != nil || alert.Type != "viral_alert" || alert.PostID != postID {
+			continue
+		}
+		if alert.Tier == "" || alert.ViralProbability < 0.7 {
+			t.Errorf("expected a viral tier and high probability, got %+v", alert)
+		}
+		break
+	}
+
+	eventually(t, 10*time.Second, "stored viral tier", func() (bool, error) {
+		var stats models.TrendingScore
+		if _, err := getData("/api/v1/analytics/post/"+postID+"/stats", &stats); err != nil {
+			return false, err
+		}
+		return stats.ViralTier != "" && stats.ViewCount == 1000, fmt.Errorf("tier %q, views %d", stats.ViralTier, stats.ViewCount)
+	})
+}
+
+// Dashboard metrics aggregate the stored trending scores
+func TestDashboardMetricsIncludeNewPosts(t *testing.T) {
+	postID := seedPost(t, "dashboard", runID+"-creator", "music")
+	publishTrendingScore(t, models.TrendingScore{
+		PostID:             postID,
+		Score:              12,
+		ViewCount:          40,
+		LikeCount:          4,
+		EngagementVelocity: 1,
+		CalculatedAt:       time.Now(),
+		TimeWindow:         "5min",
+	})
+
+	eventually(t, 30*time.Second, "dashboard metrics with the new post", func() (bool, error) {
+		var metrics struct {
+			TotalPosts int   `json:"totalPosts"`
+			TotalViews int64 `json:"totalViews"`
+			TopPosts   []struct {
+				PostID string `json:"post_id"`
+			} `json:"topPosts"`
+		}
+		if _, err := getData("/api/v1/analytics/dashboard/metrics", &metrics); err != nil {
+			return false, err
+		}
+		if metrics.TotalPosts == 0 || metrics.TotalViews < 40 {
+			return false, fmt.Errorf("%d posts, %d views", metrics.TotalPosts, metrics.TotalViews)
+		}
+
+		var stats models.TrendingScore
+		status, err := getData("/api/v1/analytics/post/"+postID+"/stats", &stats)
+		return status == 200 && stats.ViewCount == 40, err
+	})
+}
+
+// publishTrendingScore produces a score to the trending scores topic the way the Flink job does
+func publishTrendingScore(t *testing.T, score models.TrendingScore) {
+	t.Helper()
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": kafkaBrokers})
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	defer producer.Close()
+
+	payload, err := json.Marshal(score)
+	if err != nil {
+		t.Fatalf("failed to encode trending score: %v", err)
+	}
+	topic := "trending-scores"
+	delivery := make(chan kafka.Event, 1)
+	err = producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(score.PostID),
+		Value:          payload,
+	}, delivery)
+	if err != nil {
+		t.Fatalf("failed to produce trending score: %v", err)
+	}
+	if msg := (<-delivery).(*kafka.Message); msg.TopicPartition.Error != nil {
+		t.Fatalf("trending score not delivered: %v", msg.TopicPartition.Error)
+	}
+}
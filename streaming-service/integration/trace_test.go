@@ -0,0 +1,49 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+// The audit trail of a traced post is listed newest first, however its entries were written
+func TestPostTraceListsNewestFirst(t *testing.T) {
+	postID := seedPost(t, "trace", runID+"-creator", "image")
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	trace := store.Collection("post_traces").Doc(postID)
+	if _, err := trace.Set(ctx, models.PostTrace{PostID: postID, EnabledAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("failed to enable trace: %v", err)
+	}
+	for _, offset := range []time.Duration{2 * time.Second, 0, 3 * time.Second, time.Second} {
+		entry := models.PostTraceEntry{
+			PostID:    postID,
+			Kind:      "event",
+			Source:    "view",
+			Timestamp: now.Add(-offset),
+			ExpiresAt: now.Add(25 * time.Hour),
+		}
+		if _, _, err := trace.Collection("entries").Add(ctx, entry); err != nil {
+			t.Fatalf("failed to record trace entry: %v", err)
+		}
+	}
+
+	var entries []models.PostTraceEntry
+	status, err := getDataAs("/api/v1/admin/posts/"+postID+"/trace?limit=3", adminAPIKey, &entries)
+	if err != nil || status != 200 {
+		t.Fatalf("failed to read the trace: status %d, %v", status, err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected the 3 latest entries, got %d", len(entries))
+	}
+	for i, offset := range []time.Duration{0, time.Second, 2 * time.Second} {
+		if !entries[i].Timestamp.Equal(now.Add(-offset)) {
+			t.Errorf("entry %d: expected %v, got %v", i, now.Add(-offset), entries[i].Timestamp)
+		}
+	}
+}
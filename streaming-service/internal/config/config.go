@@ -1,10 +1,117 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Gemini use cases that can override the default generation settings
+const (
+	GeminiUseCaseKeywords   = "keywords"
+	GeminiUseCaseVirality   = "virality"
+	GeminiUseCaseModeration = "moderation"
+	GeminiUseCaseSentiment  = "sentiment"
+	GeminiUseCaseVision     = "vision"
+	GeminiUseCaseCoaching   = "coaching"
+)
+
+// Gemini safety block thresholds, applied to all harm categories
+const (
+	GeminiSafetyBlockNone           = "block_none"
+	GeminiSafetyBlockOnlyHigh       = "block_only_high"
+	GeminiSafetyBlockMediumAndAbove = "block_medium_and_above"
+	GeminiSafetyBlockLowAndAbove    = "block_low_and_above"
+)
+
+// Rate limit key of calls to the custom-trained prediction endpoint; Gemini calls are limited
+// per use case
+const VertexAIRateKeyPrediction = "prediction"
+
+// Run modes
+const (
+	// RunModeFull ingests events, consumes Kafka, runs background jobs and serves every endpoint
+	RunModeFull = "full"
+	// RunModeReadReplica serves read endpoints and WebSocket fan-out only, so read traffic can
+	// be scaled independently of the stateful processing instances
+	RunModeReadReplica = "read_replica"
+)
+
+// WebSocket authentication modes
+const (
+	// WebSocketAuthOff accepts every connection as anonymous and ignores tokens
+	WebSocketAuthOff = "off"
+	// WebSocketAuthOptional verifies a token when one is sent and accepts anonymous connections
+	WebSocketAuthOptional = "optional"
+	// WebSocketAuthRequired rejects connections without a valid token
+	WebSocketAuthRequired = "required"
+)
+
+// GeminiSettings are the model and generation parameters of a Gemini call
+type GeminiSettings struct {
+	Model           string
+	Temperature     float64
+	TopP            float64
+	TopK            int
+	MaxOutputTokens int
+	// Safety block threshold applied to all harm categories, one of the GeminiSafety
+	// constants; empty keeps the model default
+	SafetyThreshold string
+}
+
+// Viral alert tiers, from the lowest to the highest
+const (
+	ViralTierWarming   = "warming"
+	ViralTierTrending  = "trending"
+	ViralTierViral     = "viral"
+	ViralTierMegaViral = "mega_viral"
+)
+
+// DefaultViralProbabilityThreshold is the probability at or above which a post is predicted to
+// go viral unless VIRAL_PROBABILITY_THRESHOLD says otherwise
+const DefaultViralProbabilityThreshold = 0.7
+
+// ViralScoreTier maps heuristic viral scores above MinScore to a viral probability
+type ViralScoreTier struct {
+	MinScore    float64 `json:"min_score"`
+	Probability float64 `json:"probability"`
+}
+
+// ViralAlertTier is an alert level reached at MinProbability
+type ViralAlertTier struct {
+	Name           string  `json:"name"`
+	MinProbability float64 `json:"min_probability"`
+}
+
+// DefaultViralScoreTiers is the heuristic's score to probability table
+var DefaultViralScoreTiers = []ViralScoreTier{
+	{MinScore: 200, Probability: 0.95},
+	{MinScore: 150, Probability: 0.85},
+	{MinScore: 100, Probability: 0.75},
+	{MinScore: 70, Probability: 0.65},
+	{MinScore: 50, Probability: 0.55},
+	{MinScore: 30, Probability: 0.40},
+	{MinScore: 20, Probability: 0.30},
+	{MinScore: 10, Probability: 0.20},
+	{MinScore: 5, Probability: 0.10},
+	{MinScore: 0, Probability: 0.05},
+}
+
+// defaultViralAlertTiers returns the alert tiers, with the viral tier at the viral threshold
+func defaultViralAlertTiers(threshold float64) []ViralAlertTier {
+	return []ViralAlertTier{
+		{Name: ViralTierMegaViral, MinProbability: 0.9},
+		{Name: ViralTierViral, MinProbability: threshold},
+		{Name: ViralTierTrending, MinProbability: 0.6},
+		{Name: ViralTierWarming, MinProbability: 0.5},
+	}
+}
+
 type Config struct {
 	// Confluent
 	ConfluentBootstrapServers string
@@ -13,29 +120,428 @@ type Config struct {
 	ConfluentSecurityProtocol string
 	ConfluentSASLMechanism    string
 
+	// AI provider behind keyword extraction, moderation and viral prediction: vertex, openai
+	// (any OpenAI-compatible endpoint) or local (deterministic, for tests and offline development)
+	AIProvider string
+
+	// OpenAI-compatible provider: base URL, API key and chat and moderation models
+	OpenAIBaseURL         string
+	OpenAIAPIKey          string
+	OpenAIModel           string
+	OpenAIModerationModel string
+
 	// Google Cloud
 	GoogleCloudProject string
 	VertexAILocation   string
 	VertexAIEndpointID string
+	EmbeddingModel     string
+
+	// Gemini region, default generation settings and per-use-case overrides
+	GeminiLocation string
+	Gemini         GeminiSettings
+	GeminiUseCases map[string]GeminiSettings
+
+	// Viral prediction mode: heuristic, gemini or endpoint (custom-trained model behind VertexAIEndpointID)
+	ViralPredictionMode string
+
+	// Until this time the heuristic and a model-based predictor run side by side; the model is
+	// the served one, or ViralPredictionDualRunModel when the heuristic is served
+	ViralPredictionDualRunUntil time.Time
+	ViralPredictionDualRunModel string
+
+	// Probability at or above which a post is predicted to go viral, the heuristic's score to
+	// probability table and the alert tiers, both ordered from the highest down
+	ViralProbabilityThreshold float64
+	ViralScoreTiers           []ViralScoreTier
+	ViralAlertTiers           []ViralAlertTier
+
+	// How long a post alerted at a tier is only alerted again on reaching a higher one (0
+	// disables the cooldown), and how often alert states are persisted
+	ViralAlertCooldownMinutes   int
+	ViralAlertStateFlushSeconds int
+
+	// Comment sentiment: how strongly it scales the heuristic viral probability, and the
+	// number of scored comments needed before it counts
+	SentimentViralityWeight float64
+	SentimentMinComments    int
+
+	// AI response cache: memory or firestore backend, entry TTL and size bound
+	AICacheBackend    string
+	AICacheTTLMinutes int
+	AICacheMaxEntries int
+
+	// How often expired entries are evicted from the AI cache and other TTL caches (0 disables)
+	CacheMaintenanceIntervalSeconds int
+
+	// Dashboard and trending responses: how long they are served from the in-process cache
+	// and the browser cache (0 disables both), and the cache's size bound
+	ResponseCacheTTLSeconds int
+	ResponseCacheMaxEntries int
+
+	// API responses of at least ResponseCompressionMinBytes are compressed with brotli, gzip or
+	// deflate for clients that accept it (0 disables compression), at ResponseCompressionLevel
+	// from 1 (fastest) to 9
+	ResponseCompressionMinBytes int
+	ResponseCompressionLevel    int
+
+	// Gemini pricing in USD per million tokens, used to estimate spend
+	GeminiInputCostPerMillionTokens  float64
+	GeminiOutputCostPerMillionTokens float64
+
+	// Vertex AI failure isolation
+	VertexAIBreakerFailureThreshold int
+	VertexAIBreakerOpenSeconds      int
+	VertexAICallTimeoutSeconds      int
+	VertexAIMaxRetries              int
+	VertexAIRetryBaseDelayMs        int
+
+	// Vertex AI client-side rate limiting per use case: requests per second (0 disables),
+	// burst, requests allowed to wait, longest wait and what to shed when the queue is full
+	// (reject_new or drop_oldest). VertexAIKeyQPS holds VERTEX_AI_QPS_<KEY> overrides.
+	VertexAIQPS                 float64
+	VertexAIBurst               int
+	VertexAIQueueLength         int
+	VertexAIQueueTimeoutSeconds int
+	VertexAIShedPolicy          string
+	VertexAIKeyQPS              map[string]float64
 
 	// Firestore
 	FirestoreProjectID string
 
 	// Server
+	RunMode        string
 	Port           string
 	Environment    string
 	AllowedOrigins []string
 
+	// IPs and CIDRs of the proxies whose X-Forwarded-For header gives the client IP, such as
+	// Cloud Run's front end; without any the connection's address is the client IP
+	TrustedProxies []string
+
+	// Port of the gRPC streaming API, "0" to disable it
+	GRPCPort string
+
+	// WebSocket authentication (off, optional or required) and the Firebase project whose ID
+	// tokens identify WebSocket users
+	WebSocketAuth     string
+	FirebaseProjectID string
+
+	// API keys for the admin and ingestion routes (and reads when APIKeyRequireRead is set), as
+	// comma-separated name:role:sha256 entries with an optional :qps; the routes reject every
+	// request when no keys are configured. Requests per second and burst allowed per key by
+	// default.
+	APIKeys              string
+	APIKeyRequireRead    bool
+	APIKeyRateLimitQPS   float64
+	APIKeyRateLimitBurst int
+
+	// Development only: leave the protected routes open when no API keys are configured.
+	// Refused in production.
+	AllowUnauthenticated bool
+
+	// When the unversioned /api aliases of the /api/v1 routes go away, announced in their
+	// Sunset header; unset leaves the date out
+	APILegacySunset time.Time
+
+	// Requests per second and burst allowed per client IP and per user on the ingestion and
+	// analytics routes (QPS 0 disables a limit), and where the buckets live: memory, per
+	// instance, or redis, shared by every replica
+	RateLimitBackend   string
+	RateLimitIPQPS     float64
+	RateLimitIPBurst   int
+	RateLimitUserQPS   float64
+	RateLimitUserBurst int
+
+	// Share of successful ingestion requests written to the access log (failed and slow
+	// requests are always logged), and the latency from which a request is logged as slow
+	AccessLogIngestSampleRate float64
+	AccessLogSlowMs           int
+
+	// How long each dependency check of the readiness probe may take
+	HealthCheckTimeoutMs int
+
+	// Redis server backing the shared rate limits
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+
+	// Whether WebSocket connections may negotiate permessage-deflate compression
+	WebSocketCompression bool
+
+	// Whether hub messages are relayed through TopicHubBroadcasts to every instance
+	WebSocketFanout bool
+
+	// How often posts watched with the watch_post WebSocket command are checked for new stats
+	WebSocketWatchIntervalSeconds int
+
+	// Interval of the dashboard_tick WebSocket message, 0 to disable it
+	DashboardTickSeconds int
+
+	// How often consumed events are added to the stored dashboard metrics, and how often the
+	// metrics are rebuilt from a full scan to correct drift (0 never)
+	DashboardMetricsFlushSeconds int
+	DashboardMetricsRebuildHours int
+
+	// How often consumed events are added to the hourly and daily engagement rollups
+	RollupFlushSeconds int
+
+	// Reporting
+	ReportingTimezone string
+
+	// Backdated events
+	BackdatedEventLatenessMinutes int
+	BackdatedReprocessWindowHours int
+
 	// Kafka Topics
-	TopicUserInteractions string
-	TopicContentMetadata  string
-	TopicTrendingScores   string
-	TopicRecommendations  string
-	TopicViewEvents       string
-	TopicRemixEvents      string
+	TopicUserInteractions       string
+	TopicContentMetadata        string
+	TopicTrendingScores         string
+	TopicRecommendations        string
+	TopicViewEvents             string
+	TopicRemixEvents            string
+	TopicModerationQueue        string
+	TopicDeadLetter             string
+	TopicCommentEvents          string
+	TopicCreatorTiers           string
+	TopicPartnerEvents          string
+	TopicViralAlerts            string
+	TopicHubBroadcasts          string
+	TopicRecommendationFeedback string
+
+	// Reject consumed payloads that violate their topic's contract to the dead letter topic
+	StrictContractValidation bool
+
+	// Remix chain archiving
+	RemixArchiveAfterDays int
+
+	// How often buffered creator audiences are merged into Firestore
+	AudienceFlushSeconds int
+
+	// How often buffered daily viewers behind the retention cohorts are merged into Firestore
+	RetentionFlushSeconds int
+
+	// How often buffered hourly trending score snapshots are written, and how long they are kept
+	ScoreHistoryFlushSeconds  int
+	ScoreHistoryRetentionDays int
+
+	// Score views by a post's approximate unique viewers instead of its raw views, so one user
+	// refreshing a post cannot inflate its trending score
+	ScoreUniqueViewers bool
+
+	// Scale the weight of a post's views in its trending score by its duration-adjusted
+	// completion rate
+	ScoreCompletionRate bool
+
+	// Trending score formula: weights of views, interactions and interactions per hour, time
+	// decay, and the bonus of new posts shrinking over their first hours. Fields set on the
+	// scoring_config/current Firestore document override these, re-read every
+	// ScoringConfigReloadSeconds (0 ignores the document).
+	ScoreViewWeight            float64
+	ScoreLikeWeight            float64
+	ScoreCommentWeight         float64
+	ScoreShareWeight           float64
+	ScoreRemixWeight           float64
+	ScoreVelocityWeight        float64
+	ScoreDecayLambda           float64
+	ScoreRecencyBonus          float64
+	ScoreRecencyHours          float64
+	ScoringConfigReloadSeconds int
+
+	// Trending hashtags: window used when a request names none, and the posts a hashtag needs
+	// within the window to be listed
+	HashtagTrendWindow   string
+	HashtagTrendMinPosts int
+
+	// How often creators are classified into tiers (0 disables the classifier). Each run also
+	// snapshots creator engagement for the rising creators feed.
+	CreatorTierIntervalMinutes int
+
+	// Engagement a creator needs in the last week to be listed as rising
+	RisingCreatorsMinEngagement int
+
+	// Trending feed: posts a response should hold before recent high-quality posts are added as
+	// fallback content (0 disables the fallback), and how far back fallback posts are taken from
+	TrendingMinResults   int
+	TrendingFallbackDays int
+
+	// Trending engagement floor: views and unique viewers a post needs, and hours its creator's
+	// account must exist, before the post enters the trending feeds or raises viral alerts
+	TrendingMinViews           int
+	TrendingMinUniqueViewers   int
+	TrendingMinAccountAgeHours int
+
+	// Trending diversity: posts of one creator in a trending feed, and posts of one content
+	// type in a row before a post of another type is moved up (0 disables either rule)
+	TrendingMaxPerCreator     int
+	TrendingMaxContentTypeRun int
+
+	// Trending updater: every run recalculates the scores written since the last run, then
+	// re-decays the top TrendingDecayTop scores and the next TrendingDecayShardSize scores in
+	// document order, so the whole collection is decayed over several runs
+	TrendingDecayTop       int
+	TrendingDecayShardSize int
+
+	// Leader election: seconds a lease on the background jobs lasts without renewal, after
+	// which another instance takes them over (0 runs them on every instance)
+	LeaderLeaseSeconds int
+
+	// Scoped trending rankings: how often the category and region rankings are written (0
+	// disables them), and the region groups ranked alongside countries, by name
+	TrendingScopeFlushSeconds int
+	TrendingRegionGroups      map[string][]string
+
+	// In-service recommendations: minutes between rebuilds of the recommendations (0 disables
+	// the engine), seconds between flushes of the engagement it learns from, days of engagement
+	// a user needs to count as active, recommendations written per active user and recommender,
+	// and the recommenders run: collaborative, content or hybrid (both)
+	RecommendationIntervalMinutes int
+	RecommendationFlushSeconds    int
+	RecommendationActiveDays      int
+	RecommendationTopN            int
+	RecommendationMode            string
+
+	// Seen posts: hours a post a user viewed stays out of their recommendations (0 stops
+	// tracking views, leaving only the user's own posts filtered out), and seconds between
+	// flushes of the views
+	SeenPostsTTLHours     int
+	SeenPostsFlushSeconds int
+
+	// Days recommendation feedback re-ranks a user's recommendations: dismissed posts and
+	// hidden creators stay out, and creators of dismissed posts and posts shown without a
+	// click are demoted
+	RecommendationFeedbackTTLDays int
+
+	// Recommendation freshness: hours a stored recommendation lives (0 keeps them until
+	// replaced), minutes between sweeps of the expired ones (0 leaves them hidden but stored),
+	// share of each response filled with trending posts, and hours since a trending post's
+	// last score update within which it counts as recent
+	RecommendationTTLHours            int
+	RecommendationSweepMinutes        int
+	RecommendationTrendingBlend       float64
+	RecommendationTrendingRecentHours int
+
+	// Prediction feedback: trending score a post must peak at to count as viral, and how
+	// often prediction outcomes are checked
+	PredictionViralScoreThreshold  float64
+	PredictionCheckIntervalMinutes int
+
+	// Engagement anomaly detection: seconds per velocity window (0 disables detection), the
+	// z-score that flags a window, the events a spike (or a baseline, for drops) needs, and the
+	// windows of history a post needs before it is judged
+	AnomalyWindowSeconds int
+	AnomalyZScore        float64
+	AnomalyMinEvents     int
+	AnomalyWarmupWindows int
+
+	// Partner stream: JSON list of partners and the content types and metrics each receives,
+	// seconds per aggregation window (0 disables the stream) and the bucket counts are rounded
+	// down to
+	PartnerStreams             string
+	PartnerStreamWindowSeconds int
+	PartnerStreamBucket        int
+
+	// Webhooks: delivery attempts per event, seconds before the first retry (doubled on each
+	// retry), request timeout, and how often and how deep the trending list is watched for new
+	// entries (0 seconds disables new_trending_entry events)
+	WebhookMaxAttempts          int
+	WebhookRetryBackoffSeconds  int
+	WebhookTimeoutSeconds       int
+	WebhookTrendingCheckSeconds int
+	WebhookTrendingSize         int
+
+	// Async ingestion: work queued for the operation workers before submissions get 503, the
+	// number of workers, and how long finished operations can be polled
+	OperationQueueSize        int
+	OperationWorkers          int
+	OperationRetentionMinutes int
+
+	// Keyword backfill: posts per Gemini request, parallel requests and request rate
+	KeywordBackfillBatchSize         int
+	KeywordBackfillConcurrency       int
+	KeywordBackfillRequestsPerMinute int
+
+	// Content moderation: a category score at or above the threshold flags the post
+	ModerationThreshold float64
+	ModerateOutputURLs  bool
+
+	// Near-duplicate detection: cosine similarity of post embeddings at or above the threshold
+	// marks a new post as a duplicate (0 disables detection), days of earlier posts it is
+	// compared with, and the weight applied to duplicates' trending scores (1 keeps them as
+	// they are, 0 hides them from trending)
+	DuplicateSimilarityThreshold float64
+	DuplicateWindowDays          int
+	DuplicateTrendingWeight      float64
+
+	// Run Gemini vision on the first output of new posts to extract visual keywords, objects,
+	// colors and NSFW likelihood
+	AnalyzeOutputMedia bool
+
+	// Alerting
+	AlertWebhookURL string
+	AlertEmailTo    []string
+	AlertEmailFrom  string
+	SMTPHost        string
+	SMTPPort        string
+	SMTPUsername    string
+	SMTPPassword    string
+
+	// Daily quotas (0 disables the quota) and the usage ratio that triggers a soft warning
+	QuotaVertexAIDailyCalls  int
+	QuotaFirestoreDailyOps   int
+	QuotaAPIKeyDailyRequests int
+	QuotaWarningRatio        float64
+
+	// Memory ceilings of in-process caches and buffers (0 leaves a pool unbounded) and the
+	// usage ratio that triggers a warning
+	MemoryLimitAICacheMB   int
+	MemoryLimitWebSocketMB int
+	MemoryLimitAudienceMB  int
+	MemoryLimitRetentionMB int
+	MemoryWarningRatio     float64
+
+	// Gemini settings that could not be used, reported by ValidateGemini
+	geminiErr error
 }
 
 func Load() *Config {
+	viralThreshold := getEnvFloat("VIRAL_PROBABILITY_THRESHOLD", DefaultViralProbabilityThreshold)
+	location := getEnv("VERTEX_AI_LOCATION", "us-central1")
+	firestoreProjectID := getEnv("FIRESTORE_PROJECT_ID", "yarimai")
+
+	var geminiErrs []error
+	loadGemini := func(prefix string, defaults GeminiSettings) GeminiSettings {
+		settings, err := loadGeminiSettings(prefix, defaults)
+		if err != nil {
+			geminiErrs = append(geminiErrs, err)
+		}
+		return settings
+	}
+
+	gemini := loadGemini("GEMINI", GeminiSettings{
+		Model:           getEnv("VERTEX_AI_MODEL", "gemini-pro"),
+		Temperature:     0.2, // Lower temperature for more consistent results
+		TopP:            0.8,
+		TopK:            40,
+		MaxOutputTokens: 1024,
+	})
+
+	// Moderation only needs the safety ratings, not a long answer
+	moderation := gemini
+	moderation.MaxOutputTokens = 32
+
+	// Sentiment is a tiny JSON object
+	sentiment := gemini
+	sentiment.MaxOutputTokens = 64
+
+	geminiUseCases := map[string]GeminiSettings{
+		GeminiUseCaseKeywords:   loadGemini("GEMINI_KEYWORDS", gemini),
+		GeminiUseCaseVirality:   loadGemini("GEMINI_VIRALITY", gemini),
+		GeminiUseCaseModeration: loadGemini("GEMINI_MODERATION", moderation),
+		GeminiUseCaseSentiment:  loadGemini("GEMINI_SENTIMENT", sentiment),
+		GeminiUseCaseVision:     loadGemini("GEMINI_VISION", gemini),
+		GeminiUseCaseCoaching:   loadGemini("GEMINI_COACHING", gemini),
+	}
+
 	return &Config{
 		// Confluent
 		ConfluentBootstrapServers: getEnv("CONFLUENT_BOOTSTRAP_SERVERS", ""),
@@ -44,27 +550,488 @@ func Load() *Config {
 		ConfluentSecurityProtocol: getEnv("CONFLUENT_SECURITY_PROTOCOL", "SASL_SSL"),
 		ConfluentSASLMechanism:    getEnv("CONFLUENT_SASL_MECHANISM", "PLAIN"),
 
+		AIProvider: getEnv("AI_PROVIDER", "vertex"),
+
+		OpenAIBaseURL:         getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIAPIKey:          getEnv("OPENAI_API_KEY", ""),
+		OpenAIModel:           getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+		OpenAIModerationModel: getEnv("OPENAI_MODERATION_MODEL", "omni-moderation-latest"),
+
 		// Google Cloud
 		GoogleCloudProject: getEnv("GOOGLE_CLOUD_PROJECT", "yarimai"),
-		VertexAILocation:   getEnv("VERTEX_AI_LOCATION", "us-central1"),
+		VertexAILocation:   location,
 		VertexAIEndpointID: getEnv("VERTEX_AI_ENDPOINT_ID", ""),
+		EmbeddingModel:     getEnv("EMBEDDING_MODEL", "text-embedding-004"),
+
+		GeminiLocation: getEnv("GEMINI_LOCATION", location),
+		Gemini:         gemini,
+		GeminiUseCases: geminiUseCases,
+
+		ViralPredictionMode: getEnv("VIRAL_PREDICTION_MODE", "heuristic"),
+
+		ViralPredictionDualRunUntil: getEnvTime("VIRAL_PREDICTION_DUAL_RUN_UNTIL"),
+		ViralPredictionDualRunModel: getEnv("VIRAL_PREDICTION_DUAL_RUN_MODEL", "gemini"),
+
+		ViralProbabilityThreshold: viralThreshold,
+		ViralScoreTiers:           loadViralScoreTiers("VIRAL_SCORE_TIERS"),
+		ViralAlertTiers:           loadViralAlertTiers("VIRAL_ALERT_TIERS", viralThreshold),
+
+		ViralAlertCooldownMinutes:   getEnvInt("VIRAL_ALERT_COOLDOWN_MINUTES", 60),
+		ViralAlertStateFlushSeconds: getEnvInt("VIRAL_ALERT_STATE_FLUSH_SECONDS", 60),
+
+		SentimentViralityWeight: getEnvFloat("SENTIMENT_VIRALITY_WEIGHT", 0.25),
+		SentimentMinComments:    getEnvInt("SENTIMENT_MIN_COMMENTS", 3),
+
+		AICacheBackend:    getEnv("AI_CACHE_BACKEND", "memory"),
+		AICacheTTLMinutes: getEnvInt("AI_CACHE_TTL_MINUTES", 60),
+		AICacheMaxEntries: getEnvInt("AI_CACHE_MAX_ENTRIES", 10000),
+
+		// AI_CACHE_EVICTION_INTERVAL_SECONDS is the former name of the setting
+		CacheMaintenanceIntervalSeconds: getEnvInt("CACHE_MAINTENANCE_INTERVAL_SECONDS", getEnvInt("AI_CACHE_EVICTION_INTERVAL_SECONDS", 300)),
+
+		ResponseCacheTTLSeconds: getEnvInt("RESPONSE_CACHE_TTL_SECONDS", 10),
+		ResponseCacheMaxEntries: getEnvInt("RESPONSE_CACHE_MAX_ENTRIES", 1000),
+
+		ResponseCompressionMinBytes: getEnvInt("RESPONSE_COMPRESSION_MIN_BYTES", 1024),
+		ResponseCompressionLevel:    getEnvInt("RESPONSE_COMPRESSION_LEVEL", 6),
+
+		GeminiInputCostPerMillionTokens:  getEnvFloat("GEMINI_INPUT_COST_PER_MILLION_TOKENS", 0.5),
+		GeminiOutputCostPerMillionTokens: getEnvFloat("GEMINI_OUTPUT_COST_PER_MILLION_TOKENS", 1.5),
+
+		VertexAIBreakerFailureThreshold: getEnvInt("VERTEX_AI_BREAKER_FAILURE_THRESHOLD", 5),
+		VertexAIBreakerOpenSeconds:      getEnvInt("VERTEX_AI_BREAKER_OPEN_SECONDS", 30),
+		VertexAICallTimeoutSeconds:      getEnvInt("VERTEX_AI_CALL_TIMEOUT_SECONDS", 15),
+		VertexAIMaxRetries:              getEnvInt("VERTEX_AI_MAX_RETRIES", 2),
+		VertexAIRetryBaseDelayMs:        getEnvInt("VERTEX_AI_RETRY_BASE_DELAY_MS", 200),
+
+		VertexAIQPS:                 getEnvFloat("VERTEX_AI_QPS", 5),
+		VertexAIBurst:               getEnvInt("VERTEX_AI_BURST", 10),
+		VertexAIQueueLength:         getEnvInt("VERTEX_AI_QUEUE_LENGTH", 50),
+		VertexAIQueueTimeoutSeconds: getEnvInt("VERTEX_AI_QUEUE_TIMEOUT_SECONDS", 10),
+		VertexAIShedPolicy:          getEnv("VERTEX_AI_SHED_POLICY", "reject_new"),
+		VertexAIKeyQPS: loadKeyQPS("VERTEX_AI_QPS", GeminiUseCaseKeywords, GeminiUseCaseVirality,
+			GeminiUseCaseModeration, GeminiUseCaseSentiment, GeminiUseCaseVision, GeminiUseCaseCoaching, VertexAIRateKeyPrediction),
 
 		// Firestore
-		FirestoreProjectID: getEnv("FIRESTORE_PROJECT_ID", "yarimai"),
+		FirestoreProjectID: firestoreProjectID,
 
 		// Server
+		RunMode:        getEnv("RUN_MODE", RunModeFull),
 		Port:           getEnv("PORT", "8080"),
 		Environment:    getEnv("ENVIRONMENT", "development"),
 		AllowedOrigins: parseAllowedOrigins(getEnv("ALLOWED_ORIGINS", "*")),
+		TrustedProxies: parseAllowedOrigins(getEnv("TRUSTED_PROXIES", "")),
+
+		// gRPC
+		GRPCPort: getEnv("GRPC_PORT", "9090"),
+
+		// WebSocket authentication
+		WebSocketAuth:     getEnv("WEBSOCKET_AUTH", WebSocketAuthOptional),
+		FirebaseProjectID: getEnv("FIREBASE_PROJECT_ID", firestoreProjectID),
+
+		// API key authentication
+		APIKeys:              getEnv("API_KEYS", ""),
+		APIKeyRequireRead:    getEnv("API_KEY_REQUIRE_READ", "false") == "true",
+		APIKeyRateLimitQPS:   getEnvFloat("API_KEY_RATE_LIMIT_QPS", 10),
+		APIKeyRateLimitBurst: getEnvInt("API_KEY_RATE_LIMIT_BURST", 20),
+		AllowUnauthenticated: getEnv("ALLOW_UNAUTHENTICATED", "false") == "true",
+
+		// API versioning
+		APILegacySunset: getEnvTime("API_LEGACY_SUNSET"),
+
+		// Request rate limits
+		RateLimitBackend:   getEnv("RATE_LIMIT_BACKEND", "memory"),
+		RateLimitIPQPS:     getEnvFloat("RATE_LIMIT_IP_QPS", 50),
+		RateLimitIPBurst:   getEnvInt("RATE_LIMIT_IP_BURST", 100),
+		RateLimitUserQPS:   getEnvFloat("RATE_LIMIT_USER_QPS", 20),
+		RateLimitUserBurst: getEnvInt("RATE_LIMIT_USER_BURST", 40),
+
+		// Access log
+		AccessLogIngestSampleRate: getEnvFloat("ACCESS_LOG_INGEST_SAMPLE_RATE", 0.1),
+		AccessLogSlowMs:           getEnvInt("ACCESS_LOG_SLOW_MS", 1000),
+
+		// Readiness probe
+		HealthCheckTimeoutMs: getEnvInt("HEALTH_CHECK_TIMEOUT_MS", 2000),
+
+		// Redis
+		RedisAddr:     getEnv("REDIS_ADDR", ""),
+		RedisPassword: getEnv("REDIS_PASSWORD", ""),
+		RedisDB:       getEnvInt("REDIS_DB", 0),
+
+		// WebSocket framing
+		WebSocketCompression: getEnv("WEBSOCKET_COMPRESSION", "true") == "true",
+
+		// WebSocket fan-out
+		WebSocketFanout: getEnv("WEBSOCKET_FANOUT", "false") == "true",
+
+		// WebSocket commands
+		WebSocketWatchIntervalSeconds: getEnvInt("WEBSOCKET_WATCH_INTERVAL_SECONDS", 5),
+
+		// Dashboard ticks
+		DashboardTickSeconds: getEnvInt("DASHBOARD_TICK_SECONDS", 5),
+
+		// Dashboard metrics
+		DashboardMetricsFlushSeconds: getEnvInt("DASHBOARD_METRICS_FLUSH_SECONDS", 30),
+		DashboardMetricsRebuildHours: getEnvInt("DASHBOARD_METRICS_REBUILD_HOURS", 24),
+
+		// Engagement rollups
+		RollupFlushSeconds: getEnvInt("ROLLUP_FLUSH_SECONDS", 30),
+
+		// Reporting
+		ReportingTimezone: getEnv("REPORTING_TIMEZONE", "UTC"),
+
+		// Backdated events
+		BackdatedEventLatenessMinutes: getEnvInt("BACKDATED_EVENT_LATENESS_MINUTES", 10),
+		BackdatedReprocessWindowHours: getEnvInt("BACKDATED_REPROCESS_WINDOW_HOURS", 72),
 
 		// Kafka Topics
-		TopicUserInteractions: getEnv("TOPIC_USER_INTERACTIONS", "user-interactions"),
-		TopicContentMetadata:  getEnv("TOPIC_CONTENT_METADATA", "content-metadata"),
-		TopicTrendingScores:   getEnv("TOPIC_TRENDING_SCORES", "trending-scores"),
-		TopicRecommendations:  getEnv("TOPIC_RECOMMENDATIONS", "recommendations"),
-		TopicViewEvents:       getEnv("TOPIC_VIEW_EVENTS", "view-events"),
-		TopicRemixEvents:      getEnv("TOPIC_REMIX_EVENTS", "remix-events"),
+		TopicUserInteractions:       getEnv("TOPIC_USER_INTERACTIONS", "user-interactions"),
+		TopicContentMetadata:        getEnv("TOPIC_CONTENT_METADATA", "content-metadata"),
+		TopicTrendingScores:         getEnv("TOPIC_TRENDING_SCORES", "trending-scores"),
+		TopicRecommendations:        getEnv("TOPIC_RECOMMENDATIONS", "recommendations"),
+		TopicViewEvents:             getEnv("TOPIC_VIEW_EVENTS", "view-events"),
+		TopicRemixEvents:            getEnv("TOPIC_REMIX_EVENTS", "remix-events"),
+		TopicModerationQueue:        getEnv("TOPIC_MODERATION_QUEUE", "moderation-queue"),
+		TopicDeadLetter:             getEnv("TOPIC_DEAD_LETTER", "dead-letter-queue"),
+		TopicCommentEvents:          getEnv("TOPIC_COMMENT_EVENTS", "comment-events"),
+		TopicCreatorTiers:           getEnv("TOPIC_CREATOR_TIERS", "creator-tier-changes"),
+		TopicPartnerEvents:          getEnv("TOPIC_PARTNER_EVENTS", "partner-engagement"),
+		TopicViralAlerts:            getEnv("TOPIC_VIRAL_ALERTS", "viral-alerts"),
+		TopicHubBroadcasts:          getEnv("TOPIC_WS_BROADCAST", "ws-broadcast"),
+		TopicRecommendationFeedback: getEnv("TOPIC_RECOMMENDATION_FEEDBACK", "recommendation-feedback"),
+
+		// Contract validation
+		StrictContractValidation: getEnv("STRICT_CONTRACT_VALIDATION", "false") == "true",
+
+		// Remix chain archiving
+		RemixArchiveAfterDays: getEnvInt("REMIX_ARCHIVE_AFTER_DAYS", 30),
+
+		// Audience overlap
+		AudienceFlushSeconds: getEnvInt("AUDIENCE_FLUSH_SECONDS", 60),
+
+		// Viewer retention
+		RetentionFlushSeconds: getEnvInt("RETENTION_FLUSH_SECONDS", 60),
+
+		// Score history
+		ScoreHistoryFlushSeconds:  getEnvInt("SCORE_HISTORY_FLUSH_SECONDS", 60),
+		ScoreHistoryRetentionDays: getEnvInt("SCORE_HISTORY_RETENTION_DAYS", 30),
+
+		// Optional trending score inputs
+		ScoreUniqueViewers:  getEnv("SCORE_UNIQUE_VIEWERS", "false") == "true",
+		ScoreCompletionRate: getEnv("SCORE_COMPLETION_RATE", "true") == "true",
+
+		// Trending score formula
+		ScoreViewWeight:            getEnvFloat("SCORE_VIEW_WEIGHT", 0.1),
+		ScoreLikeWeight:            getEnvFloat("SCORE_LIKE_WEIGHT", 1),
+		ScoreCommentWeight:         getEnvFloat("SCORE_COMMENT_WEIGHT", 2),
+		ScoreShareWeight:           getEnvFloat("SCORE_SHARE_WEIGHT", 3),
+		ScoreRemixWeight:           getEnvFloat("SCORE_REMIX_WEIGHT", 5),
+		ScoreVelocityWeight:        getEnvFloat("SCORE_VELOCITY_WEIGHT", 5),
+		ScoreDecayLambda:           getEnvFloat("SCORE_DECAY_LAMBDA", 0.03),
+		ScoreRecencyBonus:          getEnvFloat("SCORE_RECENCY_BONUS", 10),
+		ScoreRecencyHours:          getEnvFloat("SCORE_RECENCY_HOURS", 24),
+		ScoringConfigReloadSeconds: getEnvInt("SCORING_CONFIG_RELOAD_SECONDS", 60),
+
+		// Trending hashtags
+		HashtagTrendWindow:   getEnv("HASHTAG_TREND_WINDOW", "24h"),
+		HashtagTrendMinPosts: getEnvInt("HASHTAG_TREND_MIN_POSTS", 3),
+
+		// Creator tiers
+		CreatorTierIntervalMinutes: getEnvInt("CREATOR_TIER_INTERVAL_MINUTES", 360),
+
+		// Rising creators
+		RisingCreatorsMinEngagement: getEnvInt("RISING_CREATORS_MIN_ENGAGEMENT", 20),
+
+		// Trending fallback
+		TrendingMinResults:   getEnvInt("TRENDING_MIN_RESULTS", 5),
+		TrendingFallbackDays: getEnvInt("TRENDING_FALLBACK_DAYS", 14),
+
+		// Trending engagement floor
+		TrendingMinViews:           getEnvInt("TRENDING_MIN_VIEWS", 20),
+		TrendingMinUniqueViewers:   getEnvInt("TRENDING_MIN_UNIQUE_VIEWERS", 5),
+		TrendingMinAccountAgeHours: getEnvInt("TRENDING_MIN_ACCOUNT_AGE_HOURS", 0),
+
+		// Trending diversity
+		TrendingMaxPerCreator:     getEnvInt("TRENDING_MAX_PER_CREATOR", 3),
+		TrendingMaxContentTypeRun: getEnvInt("TRENDING_MAX_CONTENT_TYPE_RUN", 3),
+
+		// Trending updater
+		TrendingDecayTop:       getEnvInt("TRENDING_DECAY_TOP", 100),
+		TrendingDecayShardSize: getEnvInt("TRENDING_DECAY_SHARD_SIZE", 500),
+
+		// Leader election
+		LeaderLeaseSeconds: getEnvInt("LEADER_LEASE_SECONDS", 30),
+
+		// Scoped trending
+		TrendingScopeFlushSeconds: getEnvInt("TRENDING_SCOPE_FLUSH_SECONDS", 60),
+		TrendingRegionGroups:      loadRegionGroups("TRENDING_REGION_GROUPS"),
+
+		// In-service recommendations
+		RecommendationIntervalMinutes: getEnvInt("RECOMMENDATION_INTERVAL_MINUTES", 60),
+		RecommendationFlushSeconds:    getEnvInt("RECOMMENDATION_FLUSH_SECONDS", 60),
+		RecommendationActiveDays:      getEnvInt("RECOMMENDATION_ACTIVE_DAYS", 7),
+		RecommendationTopN:            getEnvInt("RECOMMENDATION_TOP_N", 20),
+		RecommendationMode:            getEnv("RECOMMENDATION_MODE", "hybrid"),
+
+		// Seen posts
+		SeenPostsTTLHours:     getEnvInt("SEEN_POSTS_TTL_HOURS", 168),
+		SeenPostsFlushSeconds: getEnvInt("SEEN_POSTS_FLUSH_SECONDS", 60),
+
+		// Recommendation feedback
+		RecommendationFeedbackTTLDays: getEnvInt("RECOMMENDATION_FEEDBACK_TTL_DAYS", 30),
+
+		// Recommendation freshness
+		RecommendationTTLHours:            getEnvInt("RECOMMENDATION_TTL_HOURS", 48),
+		RecommendationSweepMinutes:        getEnvInt("RECOMMENDATION_SWEEP_MINUTES", 30),
+		RecommendationTrendingBlend:       getEnvFloat("RECOMMENDATION_TRENDING_BLEND", 0.2),
+		RecommendationTrendingRecentHours: getEnvInt("RECOMMENDATION_TRENDING_RECENT_HOURS", 24),
+
+		// Prediction feedback
+		PredictionViralScoreThreshold:  getEnvFloat("PREDICTION_VIRAL_SCORE_THRESHOLD", 50),
+		PredictionCheckIntervalMinutes: getEnvInt("PREDICTION_CHECK_INTERVAL_MINUTES", 60),
+
+		// Anomaly detection
+		AnomalyWindowSeconds: getEnvInt("ANOMALY_WINDOW_SECONDS", 60),
+		AnomalyZScore:        getEnvFloat("ANOMALY_Z_SCORE", 4),
+		AnomalyMinEvents:     getEnvInt("ANOMALY_MIN_EVENTS", 20),
+		AnomalyWarmupWindows: getEnvInt("ANOMALY_WARMUP_WINDOWS", 15),
+
+		// Partner stream
+		PartnerStreams:             getEnv("PARTNER_STREAMS", ""),
+		PartnerStreamWindowSeconds: getEnvInt("PARTNER_STREAM_WINDOW_SECONDS", 300),
+		PartnerStreamBucket:        getEnvInt("PARTNER_STREAM_BUCKET", 10),
+
+		// Webhooks
+		WebhookMaxAttempts:          getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookRetryBackoffSeconds:  getEnvInt("WEBHOOK_RETRY_BACKOFF_SECONDS", 2),
+		WebhookTimeoutSeconds:       getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+		WebhookTrendingCheckSeconds: getEnvInt("WEBHOOK_TRENDING_CHECK_SECONDS", 60),
+		WebhookTrendingSize:         getEnvInt("WEBHOOK_TRENDING_SIZE", 10),
+
+		// Async ingestion
+		OperationQueueSize:        getEnvInt("OPERATION_QUEUE_SIZE", 1000),
+		OperationWorkers:          getEnvInt("OPERATION_WORKERS", 4),
+		OperationRetentionMinutes: getEnvInt("OPERATION_RETENTION_MINUTES", 60),
+
+		// Keyword backfill
+		KeywordBackfillBatchSize:         getEnvInt("KEYWORD_BACKFILL_BATCH_SIZE", 10),
+		KeywordBackfillConcurrency:       getEnvInt("KEYWORD_BACKFILL_CONCURRENCY", 4),
+		KeywordBackfillRequestsPerMinute: getEnvInt("KEYWORD_BACKFILL_REQUESTS_PER_MINUTE", 60),
+
+		// Content moderation
+		ModerationThreshold: getEnvFloat("MODERATION_THRESHOLD", 0.6),
+		ModerateOutputURLs:  getEnv("MODERATE_OUTPUT_URLS", "false") == "true",
+
+		// Near-duplicate detection
+		DuplicateSimilarityThreshold: getEnvFloat("DUPLICATE_SIMILARITY_THRESHOLD", 0.95),
+		DuplicateWindowDays:          getEnvInt("DUPLICATE_WINDOW_DAYS", 7),
+		DuplicateTrendingWeight:      getEnvFloat("DUPLICATE_TRENDING_WEIGHT", 0.5),
+
+		// Media understanding
+		AnalyzeOutputMedia: getEnv("ANALYZE_OUTPUT_MEDIA", "false") == "true",
+
+		// Alerting
+		AlertWebhookURL: getEnv("ALERT_WEBHOOK_URL", ""),
+		AlertEmailTo:    parseAllowedOrigins(getEnv("ALERT_EMAIL_TO", "")),
+		AlertEmailFrom:  getEnv("ALERT_EMAIL_FROM", ""),
+		SMTPHost:        getEnv("SMTP_HOST", ""),
+		SMTPPort:        getEnv("SMTP_PORT", "587"),
+		SMTPUsername:    getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:    getEnv("SMTP_PASSWORD", ""),
+
+		// Quotas
+		QuotaVertexAIDailyCalls:  getEnvInt("QUOTA_VERTEX_AI_DAILY_CALLS", 0),
+		QuotaFirestoreDailyOps:   getEnvInt("QUOTA_FIRESTORE_DAILY_OPS", 0),
+		QuotaAPIKeyDailyRequests: getEnvInt("QUOTA_API_KEY_DAILY_REQUESTS", 0),
+		QuotaWarningRatio:        getEnvFloat("QUOTA_WARNING_RATIO", 0.8),
+
+		// Memory guard
+		MemoryLimitAICacheMB:   getEnvInt("MEMORY_LIMIT_AI_CACHE_MB", 64),
+		MemoryLimitWebSocketMB: getEnvInt("MEMORY_LIMIT_WEBSOCKET_MB", 32),
+		MemoryLimitAudienceMB:  getEnvInt("MEMORY_LIMIT_AUDIENCE_MB", 64),
+		MemoryLimitRetentionMB: getEnvInt("MEMORY_LIMIT_RETENTION_MB", 64),
+		MemoryWarningRatio:     getEnvFloat("MEMORY_WARNING_RATIO", 0.9),
+
+		geminiErr: errors.Join(geminiErrs...),
+	}
+}
+
+// IsReadReplica reports whether the instance runs in read replica mode
+func (c *Config) IsReadReplica() bool {
+	return c.RunMode == RunModeReadReplica
+}
+
+// WebSocketAuthMode returns the WebSocket authentication mode, optional when unset or unknown
+func (c *Config) WebSocketAuthMode() string {
+	switch c.WebSocketAuth {
+	case WebSocketAuthOff, WebSocketAuthRequired:
+		return c.WebSocketAuth
+	default:
+		return WebSocketAuthOptional
+	}
+}
+
+// ReportingLocation returns the time zone daily numbers are bucketed in, falling back to
+// UTC when the configured zone is unknown
+func (c *Config) ReportingLocation() (*time.Location, error) {
+	loc, err := time.LoadLocation(c.ReportingTimezone)
+	if err != nil {
+		return time.UTC, err
+	}
+	return loc, nil
+}
+
+// ValidateGemini returns the Gemini settings that could not be used, such as an unknown safety
+// threshold, which would otherwise leave the model default in place unnoticed
+func (c *Config) ValidateGemini() error {
+	return c.geminiErr
+}
+
+// GeminiFor returns the Gemini settings of a use case, falling back to the defaults
+func (c *Config) GeminiFor(useCase string) GeminiSettings {
+	if settings, ok := c.GeminiUseCases[useCase]; ok {
+		return settings
 	}
+	return c.Gemini
+}
+
+// ViralThreshold returns the probability at or above which a post is predicted to go viral
+func (c *Config) ViralThreshold() float64 {
+	if c.ViralProbabilityThreshold > 0 {
+		return c.ViralProbabilityThreshold
+	}
+	return DefaultViralProbabilityThreshold
+}
+
+// ViralProbabilityForScore maps a heuristic viral score to a probability: the probability of
+// the highest tier whose minimum the score exceeds, or of the lowest tier below all of them
+func (c *Config) ViralProbabilityForScore(score float64) float64 {
+	tiers := c.ViralScoreTiers
+	if len(tiers) == 0 {
+		tiers = DefaultViralScoreTiers
+	}
+	for _, tier := range tiers {
+		if score > tier.MinScore {
+			return tier.Probability
+		}
+	}
+	return tiers[len(tiers)-1].Probability
+}
+
+// ViralAlertTierFor returns the highest alert tier a viral probability reaches; ok is false
+// below every tier
+func (c *Config) ViralAlertTierFor(probability float64) (ViralAlertTier, bool) {
+	tiers := c.ViralAlertTiers
+	if len(tiers) == 0 {
+		tiers = defaultViralAlertTiers(c.ViralThreshold())
+	}
+	for _, tier := range tiers {
+		if probability >= tier.MinProbability {
+			return tier, true
+		}
+	}
+	return ViralAlertTier{}, false
+}
+
+// ViralAlertTierRank returns the position of a tier from the lowest (1), or 0 for no tier
+func (c *Config) ViralAlertTierRank(name string) int {
+	tiers := c.ViralAlertTiers
+	if len(tiers) == 0 {
+		tiers = defaultViralAlertTiers(c.ViralThreshold())
+	}
+	for i, tier := range tiers {
+		if tier.Name == name {
+			return len(tiers) - i
+		}
+	}
+	return 0
+}
+
+// loadViralScoreTiers reads a JSON score to probability table, keeping the default table when
+// the variable is unset or invalid
+func loadViralScoreTiers(key string) []ViralScoreTier {
+	var tiers []ViralScoreTier
+	if err := json.Unmarshal([]byte(os.Getenv(key)), &tiers); err != nil || len(tiers) == 0 {
+		return DefaultViralScoreTiers
+	}
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].MinScore > tiers[j].MinScore })
+	return tiers
+}
+
+// DefaultRegionGroups are the region groups ranked when none are configured
+var DefaultRegionGroups = map[string][]string{
+	"EU": {"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GR", "HR", "HU", "IE", "IT", "LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK"},
+}
+
+// loadRegionGroups reads JSON region groups, keeping the default groups when the variable is
+// unset or invalid. Names and countries are uppercased.
+func loadRegionGroups(key string) map[string][]string {
+	var groups map[string][]string
+	if err := json.Unmarshal([]byte(os.Getenv(key)), &groups); err != nil || len(groups) == 0 {
+		return DefaultRegionGroups
+	}
+	normalized := make(map[string][]string, len(groups))
+	for name, countries := range groups {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		for _, country := range countries {
+			normalized[name] = append(normalized[name], strings.ToUpper(strings.TrimSpace(country)))
+		}
+	}
+	return normalized
+}
+
+// loadViralAlertTiers reads JSON alert tiers, keeping the default tiers when the variable is
+// unset or invalid
+func loadViralAlertTiers(key string, threshold float64) []ViralAlertTier {
+	var tiers []ViralAlertTier
+	if err := json.Unmarshal([]byte(os.Getenv(key)), &tiers); err != nil || len(tiers) == 0 {
+		return defaultViralAlertTiers(threshold)
+	}
+	for _, tier := range tiers {
+		if tier.Name == "" {
+			return defaultViralAlertTiers(threshold)
+		}
+	}
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].MinProbability > tiers[j].MinProbability })
+	return tiers
+}
+
+// loadGeminiSettings reads <prefix>_MODEL, _TEMPERATURE, _TOP_P, _TOP_K, _MAX_OUTPUT_TOKENS
+// and _SAFETY_THRESHOLD, keeping the given defaults for unset variables. An unknown safety
+// threshold keeps the default threshold and is returned as an error.
+func loadGeminiSettings(prefix string, defaults GeminiSettings) (GeminiSettings, error) {
+	settings := GeminiSettings{
+		Model:           getEnv(prefix+"_MODEL", defaults.Model),
+		Temperature:     getEnvFloat(prefix+"_TEMPERATURE", defaults.Temperature),
+		TopP:            getEnvFloat(prefix+"_TOP_P", defaults.TopP),
+		TopK:            getEnvInt(prefix+"_TOP_K", defaults.TopK),
+		MaxOutputTokens: getEnvInt(prefix+"_MAX_OUTPUT_TOKENS", defaults.MaxOutputTokens),
+		SafetyThreshold: defaults.SafetyThreshold,
+	}
+
+	key := prefix + "_SAFETY_THRESHOLD"
+	threshold := strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+	switch threshold {
+	case "":
+	case GeminiSafetyBlockNone, GeminiSafetyBlockOnlyHigh, GeminiSafetyBlockMediumAndAbove, GeminiSafetyBlockLowAndAbove:
+		settings.SafetyThreshold = threshold
+	default:
+		return settings, fmt.Errorf("%s: unknown safety threshold %q, expected %s, %s, %s or %s", key, threshold,
+			GeminiSafetyBlockNone, GeminiSafetyBlockOnlyHigh, GeminiSafetyBlockMediumAndAbove, GeminiSafetyBlockLowAndAbove)
+	}
+	return settings, nil
+}
+
+// loadKeyQPS reads <prefix>_<KEY> rate overrides for the given keys, skipping unset ones
+func loadKeyQPS(prefix string, keys ...string) map[string]float64 {
+	overrides := make(map[string]float64)
+	for _, key := range keys {
+		name := prefix + "_" + strings.ToUpper(key)
+		if os.Getenv(name) != "" {
+			overrides[key] = getEnvFloat(name, 0)
+		}
+	}
+	return overrides
 }
 
 func getEnv(key, defaultValue string) string {
@@ -74,6 +1041,37 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvTime parses a date (2006-01-02, UTC midnight) or an RFC 3339 time, returning the zero
+// time when unset or invalid
+func getEnvTime(key string) time.Time {
+	value := os.Getenv(key)
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed
+	}
+	if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		return parsed
+	}
+	return time.Time{}
+}
+
 // parseAllowedOrigins parses ALLOWED_ORIGINS supporting both comma and semicolon separators
 func parseAllowedOrigins(origins string) []string {
 	// Support both comma and semicolon as separators
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadGeminiSettings(t *testing.T) {
+	defaults := GeminiSettings{Model: "gemini-pro", Temperature: 0.2, TopK: 40, MaxOutputTokens: 1024, SafetyThreshold: GeminiSafetyBlockOnlyHigh}
+
+	settings, err := loadGeminiSettings("GEMINI_TEST", defaults)
+	if err != nil || settings != defaults {
+		t.Errorf("Expected the defaults without overrides, got %+v (%v)", settings, err)
+	}
+
+	t.Setenv("GEMINI_TEST_MODEL", "gemini-1.5-flash")
+	t.Setenv("GEMINI_TEST_MAX_OUTPUT_TOKENS", "64")
+	t.Setenv("GEMINI_TEST_SAFETY_THRESHOLD", " BLOCK_NONE ")
+	settings, err = loadGeminiSettings("GEMINI_TEST", defaults)
+	expected := GeminiSettings{Model: "gemini-1.5-flash", Temperature: 0.2, TopK: 40, MaxOutputTokens: 64, SafetyThreshold: GeminiSafetyBlockNone}
+	if err != nil || settings != expected {
+		t.Errorf("Expected %+v, got %+v (%v)", expected, settings, err)
+	}
+
+	t.Setenv("GEMINI_TEST_SAFETY_THRESHOLD", "block_some")
+	settings, err = loadGeminiSettings("GEMINI_TEST", defaults)
+	if err == nil || !strings.Contains(err.Error(), "GEMINI_TEST_SAFETY_THRESHOLD") {
+		t.Errorf("Expected an error naming the variable, got %v", err)
+	}
+	if settings.SafetyThreshold != GeminiSafetyBlockOnlyHigh {
+		t.Errorf("Expected an unknown threshold to keep the default, got %q", settings.SafetyThreshold)
+	}
+}
+
+func TestGeminiForFallsBack(t *testing.T) {
+	t.Setenv("GEMINI_TEMPERATURE", "0.5")
+	t.Setenv("GEMINI_SAFETY_THRESHOLD", GeminiSafetyBlockMediumAndAbove)
+	t.Setenv("GEMINI_MODERATION_SAFETY_THRESHOLD", GeminiSafetyBlockNone)
+	t.Setenv("GEMINI_VISION_MODEL", "gemini-1.5-pro")
+
+	cfg := Load()
+	if err := cfg.ValidateGemini(); err != nil {
+		t.Fatalf("Expected valid settings, got %v", err)
+	}
+
+	// Use cases without overrides inherit the GEMINI_ settings
+	keywords := cfg.GeminiFor(GeminiUseCaseKeywords)
+	if keywords.Temperature != 0.5 || keywords.SafetyThreshold != GeminiSafetyBlockMediumAndAbove || keywords.MaxOutputTokens != 1024 {
+		t.Errorf("Expected keywords to inherit the defaults, got %+v", keywords)
+	}
+
+	// Overrides replace only their own setting, on top of the use case's defaults
+	moderation := cfg.GeminiFor(GeminiUseCaseModeration)
+	if moderation.SafetyThreshold != GeminiSafetyBlockNone || moderation.Temperature != 0.5 || moderation.MaxOutputTokens != 32 {
+		t.Errorf("Expected the moderation threshold overridden, got %+v", moderation)
+	}
+	vision := cfg.GeminiFor(GeminiUseCaseVision)
+	if vision.Model != "gemini-1.5-pro" || vision.SafetyThreshold != GeminiSafetyBlockMediumAndAbove {
+		t.Errorf("Expected the vision model overridden, got %+v", vision)
+	}
+
+	// Unknown use cases get the defaults
+	if settings := cfg.GeminiFor("unknown"); settings != cfg.Gemini {
+		t.Errorf("Expected the defaults for an unknown use case, got %+v", settings)
+	}
+}
+
+func TestValidateGeminiReportsUnknownThresholds(t *testing.T) {
+	t.Setenv("GEMINI_SAFETY_THRESHOLD", "strict")
+	t.Setenv("GEMINI_COACHING_SAFETY_THRESHOLD", "block_all")
+
+	err := Load().ValidateGemini()
+	if err == nil {
+		t.Fatal("Expected unknown thresholds to be reported")
+	}
+	for _, key := range []string{"GEMINI_SAFETY_THRESHOLD", "GEMINI_COACHING_SAFETY_THRESHOLD"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected %s in %v", key, err)
+		}
+	}
+}
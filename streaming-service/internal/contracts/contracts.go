@@ -0,0 +1,214 @@
+// Package contracts holds the canonical JSON payloads of every Kafka topic the service
+// produces or consumes, and validates payloads against them. Producers outside this
+// repository (Flink jobs, the test data generator) should test against the same fixtures.
+package contracts
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Version is the contract version the fixtures in this package describe
+const Version = "v1"
+
+// Contract names, matching the default topic names
+const (
+	UserInteractions       = "user-interactions"
+	ContentMetadata        = "content-metadata"
+	ViewEvents             = "view-events"
+	RemixEvents            = "remix-events"
+	CommentEvents          = "comment-events"
+	RecommendationFeedback = "recommendation-feedback"
+	TrendingScores         = "trending-scores"
+	Recommendations        = "recommendations"
+	ModerationQueue        = "moderation-queue"
+	CreatorTiers           = "creator-tier-changes"
+	PartnerEvents          = "partner-engagement"
+	ViralAlerts            = "viral-alerts"
+	HubBroadcasts          = "ws-broadcast"
+)
+
+// ErrContractViolation is wrapped by every validation failure
+var ErrContractViolation = errors.New("contract violation")
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// Contract describes the payload of one topic
+type Contract struct {
+	Name string
+
+	// Keys that must be present and non-null
+	Required []string
+
+	// Allowed values of string fields; fields not listed are unconstrained
+	Enums map[string][]string
+
+	// newModel returns a pointer to the Go model the payload decodes into
+	newModel func() interface{}
+}
+
+var contracts = map[string]Contract{
+	UserInteractions: {
+		Name:     UserInteractions,
+		Required: []string{"post_id", "user_id", "event_type", "timestamp"},
+		Enums:    map[string][]string{"event_type": {"view", "like", "comment", "share"}},
+		newModel: func() interface{} { return &models.InteractionEvent{} },
+	},
+	ContentMetadata: {
+		Name:     ContentMetadata,
+		Required: []string{"post_id", "user_id", "content_type", "prompt", "created_at"},
+		newModel: func() interface{} { return &models.ContentMetadata{} },
+	},
+	ViewEvents: {
+		Name:     ViewEvents,
+		Required: []string{"post_id", "user_id", "viewed_at", "duration", "platform"},
+		newModel: func() interface{} { return &models.ViewEvent{} },
+	},
+	RemixEvents: {
+		Name:     RemixEvents,
+		Required: []string{"original_post_id", "remix_post_id", "user_id", "remixed_at", "remix_type"},
+		newModel: func() interface{} { return &models.RemixEvent{} },
+	},
+	CommentEvents: {
+		Name:     CommentEvents,
+		Required: []string{"post_id", "user_id", "text", "created_at"},
+		newModel: func() interface{} { return &models.CommentEvent{} },
+	},
+	RecommendationFeedback: {
+		Name:     RecommendationFeedback,
+		Required: []string{"user_id", "post_id", "action", "occurred_at"},
+		Enums:    map[string][]string{"action": {"impression", "click", "dismiss", "hide-creator"}},
+		newModel: func() interface{} { return &models.RecommendationFeedbackEvent{} },
+	},
+	// Flink does not emit viral_probability or the versioning fields, so they stay optional
+	TrendingScores: {
+		Name: TrendingScores,
+		Required: []string{"post_id", "score", "engagement_rate", "view_count", "like_count",
+			"comment_count", "share_count", "remix_count", "engagement_velocity", "calculated_at", "time_window"},
+		newModel: func() interface{} { return &models.TrendingScore{} },
+	},
+	Recommendations: {
+		Name:     Recommendations,
+		Required: []string{"user_id", "post_id", "score", "generated_at"},
+		newModel: func() interface{} { return &models.Recommendation{} },
+	},
+	ModerationQueue: {
+		Name:     ModerationQueue,
+		Required: []string{"post_id", "flagged", "categories", "blocked", "checked_at"},
+		newModel: func() interface{} { return &models.ModerationVerdict{} },
+	},
+	CreatorTiers: {
+		Name:     CreatorTiers,
+		Required: []string{"user_id", "previous_tier", "tier", "changed_at"},
+		Enums: map[string][]string{
+			"previous_tier": {"new", "emerging", "established", "star"},
+			"tier":          {"new", "emerging", "established", "star"},
+		},
+		newModel: func() interface{} { return &models.CreatorTierChange{} },
+	},
+	PartnerEvents: {
+		Name:     PartnerEvents,
+		Required: []string{"partner_id", "post_id", "content_type", "counts", "bucket", "window_start", "window_end"},
+		newModel: func() interface{} { return &models.PartnerEngagementEvent{} },
+	},
+	// Tiers are configurable, so they are not constrained here
+	ViralAlerts: {
+		Name:     ViralAlerts,
+		Required: []string{"post_id", "tier", "previous_tier", "viral_probability", "score", "alerted_at"},
+		newModel: func() interface{} { return &models.ViralAlert{} },
+	},
+	// Internal to the service: hub messages fanned out to every instance
+	HubBroadcasts: {
+		Name:     HubBroadcasts,
+		Required: []string{"message", "sent_at"},
+		newModel: func() interface{} { return &models.HubBroadcast{} },
+	},
+}
+
+// Names returns the names of all contracts, sorted
+func Names() []string {
+	names := make([]string, 0, len(contracts))
+	for name := range contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the contract with the given name
+func Lookup(name string) (Contract, bool) {
+	contract, ok := contracts[name]
+	return contract, ok
+}
+
+// Fixture returns the canonical payload of a contract
+func Fixture(name string) ([]byte, error) {
+	if _, ok := contracts[name]; !ok {
+		return nil, fmt.Errorf("unknown contract %q", name)
+	}
+	return fixtures.ReadFile("fixtures/" + Version + "/" + name + ".json")
+}
+
+// Validate checks a payload against the named contract
+func Validate(name string, payload []byte) error {
+	contract, ok := contracts[name]
+	if !ok {
+		return fmt.Errorf("unknown contract %q", name)
+	}
+	return contract.Validate(payload)
+}
+
+// Validate checks that a payload is a JSON object with every required key, no keys unknown to
+// the Go model, values of the right types and enum fields within their allowed values
+func (c Contract) Validate(payload []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return fmt.Errorf("%w: %s: not a JSON object: %v", ErrContractViolation, c.Name, err)
+	}
+
+	var missing []string
+	for _, key := range c.Required {
+		if value, ok := raw[key]; !ok || value == nil {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s: missing required fields %s", ErrContractViolation, c.Name, strings.Join(missing, ", "))
+	}
+
+	// Unknown fields mean the producer and the Go model have drifted apart
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c.newModel()); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrContractViolation, c.Name, err)
+	}
+
+	for field, allowed := range c.Enums {
+		value, ok := raw[field].(string)
+		if !ok {
+			continue
+		}
+		if !contains(allowed, value) {
+			return fmt.Errorf("%w: %s: %s %q is not one of %s", ErrContractViolation, c.Name, field, value, strings.Join(allowed, ", "))
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestFixturesSatisfyContracts(t *testing.T) {
+	for _, name := range Names() {
+		fixture, err := Fixture(name)
+		if err != nil {
+			t.Fatalf("%s: missing fixture: %v", name, err)
+		}
+		if err := Validate(name, fixture); err != nil {
+			t.Errorf("%s: fixture does not satisfy its contract: %v", name, err)
+		}
+	}
+}
+
+func TestEveryFixtureHasContract(t *testing.T) {
+	entries, err := fs.ReadDir(fixtures, "fixtures/"+Version)
+	if err != nil {
+		t.Fatalf("failed to list fixtures: %v", err)
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		if _, ok := Lookup(name); !ok {
+			t.Errorf("fixture %s has no contract", entry.Name())
+		}
+	}
+}
+
+// Payloads produced from the Go models must satisfy the contracts they are published under
+func TestModelRoundTripSatisfiesContracts(t *testing.T) {
+	for _, name := range Names() {
+		contract, _ := Lookup(name)
+		fixture, _ := Fixture(name)
+
+		model := contract.newModel()
+		if err := json.Unmarshal(fixture, model); err != nil {
+			t.Fatalf("%s: failed to decode fixture: %v", name, err)
+		}
+		payload, err := json.Marshal(model)
+		if err != nil {
+			t.Fatalf("%s: failed to encode model: %v", name, err)
+		}
+		if err := contract.Validate(payload); err != nil {
+			t.Errorf("%s: re-encoded model violates contract: %v", name, err)
+		}
+	}
+}
+
+func TestValidateRejectsDrift(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"not an object", `[1, 2, 3]`},
+		{"missing required field", `{"post_id": "p1", "user_id": "u1", "timestamp": "2024-05-01T12:00:00Z"}`},
+		{"null required field", `{"post_id": "p1", "user_id": null, "event_type": "like", "timestamp": "2024-05-01T12:00:00Z"}`},
+		{"unknown field", `{"post_id": "p1", "user_id": "u1", "event_type": "like", "timestamp": "2024-05-01T12:00:00Z", "event_timestamp": "2024-05-01T12:00:00Z"}`},
+		{"wrong type", `{"post_id": 42, "user_id": "u1", "event_type": "like", "timestamp": "2024-05-01T12:00:00Z"}`},
+		{"non-RFC3339 timestamp", `{"post_id": "p1", "user_id": "u1", "event_type": "like", "timestamp": "2024-05-01 12:00:00.000"}`},
+		{"enum value", `{"post_id": "p1", "user_id": "u1", "event_type": "bookmark", "timestamp": "2024-05-01T12:00:00Z"}`},
+	}
+
+	for _, tt := range tests {
+		err := Validate(UserInteractions, []byte(tt.payload))
+		if !errors.Is(err, ErrContractViolation) {
+			t.Errorf("%s: expected contract violation, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateUnknownContract(t *testing.T) {
+	if err := Validate("no-such-topic", []byte(`{}`)); err == nil {
+		t.Error("expected error for unknown contract")
+	}
+	if _, err := Fixture("no-such-topic"); err == nil {
+		t.Error("expected error for unknown fixture")
+	}
+}
@@ -0,0 +1,742 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is a GraphQL request as clients send it over HTTP and WebSocket
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is left out when the request failed before
+// executing, and null when a non-null root field failed.
+type Response struct {
+	Data   interface{}
+	Errors []*Error
+
+	executed bool
+}
+
+func (r *Response) MarshalJSON() ([]byte, error) {
+	type response struct {
+		Data   *interface{} `json:"data,omitempty"`
+		Errors []*Error     `json:"errors,omitempty"`
+	}
+	out := response{Errors: r.Errors}
+	if r.executed {
+		out.Data = &r.Data
+	}
+	return json.Marshal(out)
+}
+
+// Error is an error of a request, with the path of the field it occurred in
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// requestError is a response to a request that failed before executing
+func requestError(err error) *Response {
+	return &Response{Errors: []*Error{{Message: err.Error()}}}
+}
+
+// Execute runs a query operation
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	e, operation, err := s.prepare(req)
+	if err != nil {
+		return requestError(err)
+	}
+	if operation.Kind != "query" {
+		return requestError(fmt.Errorf("%s operations are not served over this transport", operation.Kind))
+	}
+
+	data, ok := e.executeSelectionSet(ctx, s.query, nil, operation.Selections, nil)
+	response := &Response{Errors: e.errors, executed: true}
+	if ok {
+		response.Data = data
+	}
+	return response
+}
+
+// Subscribe runs a subscription operation, sending a response for every event of its root
+// field until ctx is done or the events end. Query operations get a single response. When
+// the operation cannot start, Subscribe returns the response explaining why.
+func (s *Schema) Subscribe(ctx context.Context, req Request) (<-chan *Response, *Response) {
+	e, operation, err := s.prepare(req)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	if operation.Kind == "query" {
+		responses := make(chan *Response, 1)
+		responses <- s.Execute(ctx, req)
+		close(responses)
+		return responses, nil
+	}
+
+	keys, fields := e.collectFields(s.subscription, operation.Selections, map[string]bool{})
+	if len(keys) != 1 {
+		return nil, requestError(fmt.Errorf("a subscription must select exactly one field"))
+	}
+	key := keys[0]
+	definition := s.subscription.field(fields[key][0].Name)
+	if definition == nil {
+		return nil, requestError(fmt.Errorf("a subscription must select a subscription field"))
+	}
+	args, err := e.coerceArgs(definition, fields[key][0])
+	if err != nil {
+		return nil, requestError(err)
+	}
+	events, err := definition.Subscribe(ctx, args)
+	if err != nil {
+		return nil, requestError(err)
+	}
+
+	t := mustParseTypeRef(definition.Type)
+	responses := make(chan *Response)
+	go func() {
+		defer close(responses)
+		for {
+			var event interface{}
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok = <-events:
+				if !ok {
+					return
+				}
+			}
+
+			run := &execution{schema: s, doc: e.doc, variables: e.variables}
+			response := &Response{executed: true}
+			if value, ok := run.completeValue(ctx, t, fields[key], []interface{}{key}, event); ok {
+				data := newOrderedMap()
+				data.set(key, value)
+				response.Data = data
+			}
+			response.Errors = run.errors
+
+			select {
+			case responses <- response:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return responses, nil
+}
+
+// execution is the state of one operation
+type execution struct {
+	schema    *Schema
+	doc       *Document
+	variables map[string]interface{}
+	errors    []*Error
+}
+
+func (e *execution) errorf(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, &Error{Message: fmt.Sprintf(format, args...), Path: path})
+}
+
+// prepare parses and validates a request, returning its operation and coerced variables
+func (s *Schema) prepare(req Request) (*execution, *Operation, error) {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("syntax error: %w", err)
+	}
+
+	var operation *Operation
+	for _, candidate := range doc.Operations {
+		if req.OperationName == "" || candidate.Name == req.OperationName {
+			if operation != nil {
+				return nil, nil, fmt.Errorf("operationName is required for a document with several operations")
+			}
+			operation = candidate
+		}
+	}
+	if operation == nil {
+		return nil, nil, fmt.Errorf("unknown operation %q", req.OperationName)
+	}
+
+	var root *Object
+	switch operation.Kind {
+	case "query":
+		root = s.query
+	case "subscription":
+		root = s.subscription
+	}
+	if root == nil {
+		return nil, nil, fmt.Errorf("%s operations are not supported", operation.Kind)
+	}
+
+	e := &execution{schema: s, doc: doc, variables: map[string]interface{}{}}
+	for _, definition := range operation.Variables {
+		if err := s.checkType(definition.Type, true); err != nil {
+			return nil, nil, fmt.Errorf("variable $%s: %w", definition.Name, err)
+		}
+		t := mustParseTypeRef(definition.Type)
+		value, present := req.Variables[definition.Name]
+		if !present && definition.HasDefault {
+			value, present = definition.Default, true
+		}
+		if !present {
+			if t.nonNull {
+				return nil, nil, fmt.Errorf("variable $%s of type %s is required", definition.Name, t)
+			}
+			continue
+		}
+		coerced, err := e.coerceInput(t, value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("variable $%s: %w", definition.Name, err)
+		}
+		e.variables[definition.Name] = coerced
+	}
+
+	declared := map[string]bool{}
+	for _, definition := range operation.Variables {
+		declared[definition.Name] = true
+	}
+	if err := e.validate(root, operation.Selections, declared, 1, map[string]bool{}); err != nil {
+		return nil, nil, err
+	}
+	return e, operation, nil
+}
+
+// validate checks the selections of an object against the schema before anything executes
+func (e *execution) validate(object *Object, selections []Selection, declared map[string]bool, depth int, fragments map[string]bool) error {
+	if e.schema.MaxDepth > 0 && depth > e.schema.MaxDepth {
+		return fmt.Errorf("query is nested deeper than %d levels", e.schema.MaxDepth)
+	}
+
+	for _, selection := range selections {
+		switch selection := selection.(type) {
+		case *Field:
+			if err := checkVariables(selection.Arguments, declared); err != nil {
+				return err
+			}
+			if selection.Name == "__typename" {
+				if selection.Selections != nil {
+					return fmt.Errorf("field __typename of type String! must not have a selection")
+				}
+				continue
+			}
+			definition := object.field(selection.Name)
+			if definition == nil {
+				return fmt.Errorf("cannot query field %q on type %s", selection.Name, object.Name)
+			}
+			for name := range selection.Arguments {
+				if !hasArgument(definition, name) {
+					return fmt.Errorf("unknown argument %q on field %s.%s", name, object.Name, definition.Name)
+				}
+			}
+			if _, err := e.coerceArgs(definition, selection); err != nil {
+				return err
+			}
+
+			t := mustParseTypeRef(definition.Type)
+			if child, ok := e.schema.objects[t.namedType()]; ok {
+				if selection.Selections == nil {
+					return fmt.Errorf("field %s.%s of type %s must have a selection of subfields", object.Name, definition.Name, t)
+				}
+				if err := e.validate(child, selection.Selections, declared, depth+1, fragments); err != nil {
+					return err
+				}
+			} else if selection.Selections != nil {
+				return fmt.Errorf("field %s.%s of type %s must not have a selection", object.Name, definition.Name, t)
+			}
+
+		case *FragmentSpread:
+			fragment, ok := e.doc.Fragments[selection.Name]
+			if !ok {
+				return fmt.Errorf("unknown fragment %q", selection.Name)
+			}
+			if fragment.TypeCondition != object.Name {
+				return fmt.Errorf("fragment %s on %s cannot be spread on type %s", fragment.Name, fragment.TypeCondition, object.Name)
+			}
+			if fragments[fragment.Name] {
+				return fmt.Errorf("fragment %s spreads itself", fragment.Name)
+			}
+			fragments[fragment.Name] = true
+			err := e.validate(object, fragment.Selections, declared, depth, fragments)
+			delete(fragments, fragment.Name)
+			if err != nil {
+				return err
+			}
+
+		case *InlineFragment:
+			if selection.TypeCondition != "" && selection.TypeCondition != object.Name {
+				return fmt.Errorf("fragment on %s cannot be spread on type %s", selection.TypeCondition, object.Name)
+			}
+			if err := e.validate(object, selection.Selections, declared, depth, fragments); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkVariables checks that the values of arguments only refer to declared variables
+func checkVariables(value interface{}, declared map[string]bool) error {
+	switch v := value.(type) {
+	case Variable:
+		if !declared[string(v)] {
+			return fmt.Errorf("variable $%s is not defined", v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := checkVariables(item, declared); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if err := checkVariables(item, declared); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasArgument(definition *FieldDefinition, name string) bool {
+	for _, arg := range definition.Args {
+		if arg.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// collectFields groups the fields selected on an object by response key, in selection
+// order, applying fragments and @skip/@include
+func (e *execution) collectFields(object *Object, selections []Selection, visited map[string]bool) ([]string, map[string][]*Field) {
+	var keys []string
+	fields := map[string][]*Field{}
+	var collect func(selections []Selection)
+	collect = func(selections []Selection) {
+		for _, selection := range selections {
+			switch selection := selection.(type) {
+			case *Field:
+				if !e.included(selection.Directives) {
+					continue
+				}
+				key := selection.ResponseKey()
+				if _, ok := fields[key]; !ok {
+					keys = append(keys, key)
+				}
+				fields[key] = append(fields[key], selection)
+			case *FragmentSpread:
+				if !e.included(selection.Directives) || visited[selection.Name] {
+					continue
+				}
+				visited[selection.Name] = true
+				if fragment, ok := e.doc.Fragments[selection.Name]; ok && fragment.TypeCondition == object.Name {
+					collect(fragment.Selections)
+				}
+			case *InlineFragment:
+				if !e.included(selection.Directives) {
+					continue
+				}
+				if selection.TypeCondition == "" || selection.TypeCondition == object.Name {
+					collect(selection.Selections)
+				}
+			}
+		}
+	}
+	collect(selections)
+	return keys, fields
+}
+
+// included evaluates the @skip and @include directives of a selection
+func (e *execution) included(directives []Directive) bool {
+	for _, directive := range directives {
+		condition := directive.Arguments["if"]
+		if variable, ok := condition.(Variable); ok {
+			condition = e.variables[string(variable)]
+		}
+		flag, _ := condition.(bool)
+		if (directive.Name == "skip" && flag) || (directive.Name == "include" && !flag) {
+			return false
+		}
+	}
+	return true
+}
+
+// executeSelectionSet resolves the selected fields of an object. It returns false when a
+// non-null field failed, making the object null.
+func (e *execution) executeSelectionSet(ctx context.Context, object *Object, source interface{}, selections []Selection, path []interface{}) (*orderedMap, bool) {
+	keys, fields := e.collectFields(object, selections, map[string]bool{})
+	result := newOrderedMap()
+	for _, key := range keys {
+		value, ok := e.executeField(ctx, object, source, fields[key], appendPath(path, key))
+		if !ok {
+			return nil, false
+		}
+		result.set(key, value)
+	}
+	return result, true
+}
+
+// executeField resolves and completes one field. It returns false when the field is
+// non-null and failed.
+func (e *execution) executeField(ctx context.Context, object *Object, source interface{}, fields []*Field, path []interface{}) (interface{}, bool) {
+	field := fields[0]
+	if field.Name == "__typename" {
+		return object.Name, true
+	}
+
+	definition := object.field(field.Name)
+	t := mustParseTypeRef(definition.Type)
+	args, err := e.coerceArgs(definition, field)
+	if err != nil {
+		e.errorf(path, "%v", err)
+		return nil, !t.nonNull
+	}
+
+	resolve := definition.Resolve
+	if resolve == nil {
+		resolve = func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			return fieldValue(source, definition.Name), nil
+		}
+	}
+	value, err := resolve(ctx, source, args)
+	if err != nil {
+		e.errorf(path, "%v", err)
+		return nil, !t.nonNull
+	}
+	return e.completeValue(ctx, t, fields, path, value)
+}
+
+// completeValue turns a resolved value into its response value of type t. It returns false
+// when t is non-null and the value is null or failed, so the null propagates to the parent.
+func (e *execution) completeValue(ctx context.Context, t *typeRef, fields []*Field, path []interface{}, value interface{}) (interface{}, bool) {
+	if t.nonNull {
+		errorsBefore := len(e.errors)
+		completed, ok := e.completeValue(ctx, t.nullable(), fields, path, value)
+		if !ok || completed == nil {
+			if len(e.errors) == errorsBefore {
+				e.errorf(path, "cannot return null for non-null field of type %s", t)
+			}
+			return nil, false
+		}
+		return completed, true
+	}
+	if isNil(value) {
+		return nil, true
+	}
+
+	if t.item != nil {
+		list := reflect.ValueOf(value)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			e.errorf(path, "expected a list for type %s, got %T", t, value)
+			return nil, true
+		}
+		items := make([]interface{}, list.Len())
+		for i := range items {
+			item, ok := e.completeValue(ctx, t.item, fields, appendPath(path, i), list.Index(i).Interface())
+			if !ok {
+				return nil, true
+			}
+			items[i] = item
+		}
+		return items, true
+	}
+
+	if scalars[t.name] {
+		serialized, err := serialize(t.name, value)
+		if err != nil {
+			e.errorf(path, "%v", err)
+		}
+		return serialized, true
+	}
+
+	var selections []Selection
+	for _, field := range fields {
+		selections = append(selections, field.Selections...)
+	}
+	object, ok := e.executeSelectionSet(ctx, e.schema.objects[t.name], value, selections, path)
+	if !ok {
+		return nil, true
+	}
+	return object, true
+}
+
+// coerceArgs returns the arguments of a selected field with their defaults, coerced to the
+// argument types
+func (e *execution) coerceArgs(definition *FieldDefinition, field *Field) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	for _, arg := range definition.Args {
+		t := mustParseTypeRef(arg.Type)
+		value, present := field.Arguments[arg.Name]
+		if variable, ok := value.(Variable); ok {
+			value, present = e.variables[string(variable)]
+		}
+		if !present && arg.Default != nil {
+			value, present = arg.Default, true
+		}
+		if !present {
+			if t.nonNull {
+				return nil, fmt.Errorf("argument %q of type %s is required", arg.Name, t)
+			}
+			continue
+		}
+
+		coerced, err := e.coerceInput(t, value)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", arg.Name, err)
+		}
+		args[arg.Name] = coerced
+	}
+	return args, nil
+}
+
+// coerceInput coerces an argument or variable value to an input type. Scalars become ints,
+// float64s, strings, bools and time.Times; a single value is accepted for a list.
+func (e *execution) coerceInput(t *typeRef, value interface{}) (interface{}, error) {
+	if variable, ok := value.(Variable); ok {
+		value = e.variables[string(variable)]
+	}
+	if value == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("expected a non-null %s", t)
+		}
+		return nil, nil
+	}
+
+	if t.item != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		coerced := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			c, err := e.coerceInput(t.item, item)
+			if err != nil {
+				return nil, err
+			}
+			coerced = append(coerced, c)
+		}
+		return coerced, nil
+	}
+
+	switch t.name {
+	case "Int":
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+				return int(v), nil
+			}
+		}
+	case "Float":
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case "String":
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case "ID":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int:
+			return strconv.Itoa(v), nil
+		case float64:
+			if v == math.Trunc(v) {
+				return strconv.FormatFloat(v, 'f', 0, 64), nil
+			}
+		}
+	case "Boolean":
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case "DateTime":
+		if v, ok := value.(string); ok {
+			if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+				return parsed, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("expected a %s, got %s", t, formatValue(value))
+}
+
+// serialize converts a resolved value to the response value of a scalar. Int covers 64-bit
+// counts. Pointers are followed; a zero DateTime is null.
+func serialize(scalar string, value interface{}) (interface{}, error) {
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr {
+		value = v.Elem().Interface()
+	}
+	if t, ok := value.(time.Time); ok {
+		if scalar != "DateTime" && scalar != "String" {
+			return nil, fmt.Errorf("cannot represent a time as %s", scalar)
+		}
+		if t.IsZero() {
+			return nil, nil
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch scalar {
+	case "String", "ID":
+		switch {
+		case v.Kind() == reflect.String:
+			return v.String(), nil
+		case v.CanInt():
+			return strconv.FormatInt(v.Int(), 10), nil
+		case v.CanUint():
+			return strconv.FormatUint(v.Uint(), 10), nil
+		}
+	case "Int":
+		switch {
+		case v.CanInt():
+			return v.Int(), nil
+		case v.CanUint():
+			return int64(v.Uint()), nil
+		case v.CanFloat() && v.Float() == math.Trunc(v.Float()):
+			return int64(v.Float()), nil
+		}
+	case "Float":
+		switch {
+		case v.CanFloat() && !math.IsNaN(v.Float()) && !math.IsInf(v.Float(), 0):
+			return v.Float(), nil
+		case v.CanInt():
+			return float64(v.Int()), nil
+		case v.CanUint():
+			return float64(v.Uint()), nil
+		}
+	case "Boolean":
+		if v.Kind() == reflect.Bool {
+			return v.Bool(), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot represent %v as %s", value, scalar)
+}
+
+// fieldValue reads the struct field or map entry of source named name, ignoring case and
+// underscores, so postId reads PostID. Fields of embedded structs are found too.
+func fieldValue(source interface{}, name string) interface{} {
+	v := reflect.ValueOf(source)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	key := normalizeName(name)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if field, ok := findField(v, key); ok {
+			return field.Interface()
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		for _, k := range v.MapKeys() {
+			if normalizeName(k.String()) == key {
+				return v.MapIndex(k).Interface()
+			}
+		}
+	}
+	return nil
+}
+
+func findField(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() && normalizeName(t.Field(i).Name) == key {
+			return v.Field(i), true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Anonymous && t.Field(i).Type.Kind() == reflect.Struct {
+			if field, ok := findField(v.Field(i), key); ok {
+				return field, true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// isNil reports whether a resolved value is null. Nil slices are empty lists.
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	extended := make([]interface{}, len(path), len(path)+1)
+	copy(extended, path)
+	return append(extended, key)
+}
+
+// orderedMap is a response object, encoded with its fields in selection order
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: map[string]interface{}{}}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testPost struct {
+	PostID    string
+	Score     float64
+	ViewCount int64
+	CreatorID string
+	UpdatedAt time.Time
+}
+
+var testPosts = map[string]*testPost{
+	"p1": {PostID: "p1", Score: 9.5, ViewCount: 120, CreatorID: "c1", UpdatedAt: time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)},
+	"p2": {PostID: "p2", Score: 4, ViewCount: 30, CreatorID: "c2"},
+}
+
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	creator := &Object{Name: "Creator", Fields: []*FieldDefinition{
+		{Name: "id", Type: "ID!", Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			return source, nil
+		}},
+		{Name: "name", Type: "String!", Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			if source == "c2" {
+				return nil, errors.New("creator c2 is unavailable")
+			}
+			return "Creator " + source.(string), nil
+		}},
+	}}
+	post := &Object{Name: "Post", Fields: []*FieldDefinition{
+		{Name: "postId", Type: "ID!"},
+		{Name: "score", Type: "Float!"},
+		{Name: "viewCount", Type: "Int!"},
+		{Name: "updatedAt", Type: "DateTime"},
+		{Name: "creator", Type: "Creator", Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			return source.(*testPost).CreatorID, nil
+		}},
+	}}
+	query := &Object{Name: "Query", Fields: []*FieldDefinition{
+		{Name: "post", Type: "Post", Args: []Argument{{Name: "id", Type: "ID!"}},
+			Resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				return testPosts[args["id"].(string)], nil
+			}},
+		{Name: "trending", Type: "[Post!]!", Args: []Argument{{Name: "limit", Type: "Int", Default: 10}},
+			Resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				posts := []*testPost{testPosts["p1"], testPosts["p2"]}
+				return posts[:min(args["limit"].(int), len(posts))], nil
+			}},
+	}}
+	subscription := &Object{Name: "Subscription", Fields: []*FieldDefinition{
+		{Name: "postUpdated", Type: "Post!", Args: []Argument{{Name: "id", Type: "ID!"}},
+			Subscribe: func(ctx context.Context, args map[string]interface{}) (<-chan interface{}, error) {
+				events := make(chan interface{}, 2)
+				events <- testPosts[args["id"].(string)]
+				events <- testPosts[args["id"].(string)]
+				close(events)
+				return events, nil
+			}},
+	}}
+
+	schema, err := NewSchema(query, subscription, post, creator)
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+	schema.MaxDepth = 3
+	return schema
+}
+
+func encode(t *testing.T, response *Response) string {
+	t.Helper()
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	schema := testSchema(t)
+	response := schema.Execute(context.Background(), Request{
+		Query: `query Feed($n: Int) {
+			top: trending(limit: $n) { ...card, __typename }
+			post(id: "p1") { views: viewCount updatedAt @include(if: true) score @skip(if: true) }
+		}
+		fragment card on Post { postId score creator { name } }`,
+		Variables: map[string]interface{}{"n": float64(1)},
+	})
+
+	want := `{"data":{"top":[{"postId":"p1","score":9.5,"creator":{"name":"Creator c1"},"__typename":"Post"}],` +
+		`"post":{"views":120,"updatedAt":"2024-05-10T12:00:00Z"}}}`
+	if got := encode(t, response); got != want {
+		t.Errorf("Unexpected response\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestExecute_NullPropagation(t *testing.T) {
+	schema := testSchema(t)
+
+	// The failing nullable field becomes null alongside its error
+	response := schema.Execute(context.Background(), Request{Query: `{ post(id: "p2") { postId creator { id name } updatedAt } }`})
+	want := `{"data":{"post":{"postId":"p2","creator":null,"updatedAt":null}},"errors":[{"message":"creator c2 is unavailable","path":["post","creator","name"]}]}`
+	if got := encode(t, response); got != want {
+		t.Errorf("Unexpected response\n got %s\nwant %s", got, want)
+	}
+
+	// A missing post is null without errors
+	response = schema.Execute(context.Background(), Request{Query: `{ post(id: "p9") { postId } }`})
+	if got := encode(t, response); got != `{"data":{"post":null}}` {
+		t.Errorf("Unexpected response %s", got)
+	}
+}
+
+func TestExecute_ValidationErrors(t *testing.T) {
+	schema := testSchema(t)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{ post(id: "p1") { title } }`, `cannot query field "title" on type Post`},
+		{`{ post { postId } }`, `argument "id" of type ID! is required`},
+		{`{ post(id: "p1") }`, `must have a selection of subfields`},
+		{`{ trending(limit: "ten") { postId } }`, `argument "limit": expected a Int`},
+		{`{ trending(limit: $n) { postId } }`, `variable $n is not defined`},
+		{`{ post(id: "p1") { creator { name } } trending { creator { id } } }`, ``},
+		{`{ post(id: "p1") { creator { id } ...deep } } fragment deep on Post { creator { name } }`, ``},
+		{`{ post(id: "p1") { ...loop } } fragment loop on Post { ...loop }`, `fragment loop spreads itself`},
+		{`mutation { post(id: "p1") { postId } }`, `mutation operations are not supported`},
+		{`{ post(id: "p1") { postId }`, `syntax error: unexpected end of document`},
+	}
+	for _, tt := range tests {
+		response := schema.Execute(context.Background(), Request{Query: tt.query})
+		if tt.want == "" {
+			if len(response.Errors) > 0 {
+				t.Errorf("%s: unexpected errors %v", tt.query, response.Errors[0])
+			}
+			continue
+		}
+		if len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, tt.want) {
+			t.Errorf("%s: expected error %q, got %s", tt.query, tt.want, encode(t, response))
+		} else if strings.Contains(encode(t, response), `"data"`) {
+			t.Errorf("%s: expected no data for a request error", tt.query)
+		}
+	}
+}
+
+func TestExecute_MaxDepth(t *testing.T) {
+	schema := testSchema(t)
+	schema.MaxDepth = 2
+	response := schema.Execute(context.Background(), Request{Query: `{ post(id: "p1") { creator { name } } }`})
+	if len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, "deeper than 2") {
+		t.Errorf("Expected the depth limit to reject the query, got %s", encode(t, response))
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	schema := testSchema(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	responses, failed := schema.Subscribe(ctx, Request{Query: `subscription { postUpdated(id: "p1") { postId viewCount } }`})
+	if failed != nil {
+		t.Fatalf("Failed to subscribe: %s", encode(t, failed))
+	}
+	count := 0
+	for response := range responses {
+		count++
+		if got := encode(t, response); got != `{"data":{"postUpdated":{"postId":"p1","viewCount":120}}}` {
+			t.Errorf("Unexpected event %s", got)
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 events, got %d", count)
+	}
+
+	if _, failed := schema.Subscribe(ctx, Request{Query: `subscription { postUpdated(id: "p1") { postId } a: postUpdated(id: "p2") { postId } }`}); failed == nil {
+		t.Error("Expected a subscription of two fields to be refused")
+	}
+}
+
+func TestNewSchema_UnknownType(t *testing.T) {
+	query := &Object{Name: "Query", Fields: []*FieldDefinition{{Name: "post", Type: "Pots"}}}
+	if _, err := NewSchema(query, nil); err == nil || !strings.Contains(err.Error(), "Query.post") {
+		t.Errorf("Expected the unknown type to be reported, got %v", err)
+	}
+}
+
+func TestSDL(t *testing.T) {
+	sdl := testSchema(t).SDL()
+	for _, want := range []string{"subscription: Subscription", "trending(limit: Int = 10): [Post!]!", "type Creator {"} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("Expected the SDL to contain %q:\n%s", want, sdl)
+		}
+	}
+}
+
+func TestParse_Strings(t *testing.T) {
+	doc, err := Parse(`{ post(id: "a\"b\u00e9\n") { postId } }`)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	field := doc.Operations[0].Selections[0].(*Field)
+	if field.Arguments["id"] != "a\"bé\n" {
+		t.Errorf("Unexpected string %q", field.Arguments["id"])
+	}
+}
@@ -0,0 +1,666 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is a parsed GraphQL request document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query, mutation or subscription of a document
+type Operation struct {
+	Kind       string // query, mutation or subscription
+	Name       string
+	Variables  []VariableDefinition
+	Selections []Selection
+}
+
+// VariableDefinition declares a variable of an operation
+type VariableDefinition struct {
+	Name       string
+	Type       string
+	Default    interface{}
+	HasDefault bool
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment
+type Selection interface{}
+
+// Field selects a field, under its alias when it has one
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]interface{}
+	Directives []Directive
+	Selections []Selection
+}
+
+// ResponseKey is the name of the field in the response
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FragmentSpread selects the fields of a named fragment
+type FragmentSpread struct {
+	Name       string
+	Directives []Directive
+}
+
+// InlineFragment selects fields when the object is of its type, or always without one
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []Directive
+	Selections    []Selection
+}
+
+// Fragment is a named set of fields of a type
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+}
+
+// Directive such as @include(if: $flag)
+type Directive struct {
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// Variable refers to a variable of the operation in an argument value
+type Variable string
+
+// EnumValue is an unquoted name in an argument value
+type EnumValue string
+
+// Parse parses a request document. Values are ints, float64s, strings, bools, nil, lists,
+// objects, Variables and EnumValues.
+func Parse(source string) (*Document, error) {
+	p := &parser{lexer: lexer{source: source}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.token.is(tokenPunct, "{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Kind: "query", Selections: selections})
+		case p.token.is(tokenName, "fragment"):
+			fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.Fragments[fragment.Name]; ok {
+				return nil, fmt.Errorf("fragment %q is defined twice", fragment.Name)
+			}
+			doc.Fragments[fragment.Name] = fragment
+		case p.token.kind == tokenName:
+			operation, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, operation)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("document contains no operation")
+	}
+	return doc, nil
+}
+
+type parser struct {
+	lexer lexer
+	token token
+}
+
+func (p *parser) advance() error {
+	token, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = token
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	if p.token.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of document")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", p.token.value, p.token.offset)
+}
+
+// expect consumes a punctuator
+func (p *parser) expect(punct string) error {
+	if !p.token.is(tokenPunct, punct) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+// skip consumes a punctuator when it is next and reports whether it was
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.token.is(tokenPunct, punct) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.token.value
+	return name, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	kind := p.token.value
+	if kind != "query" && kind != "mutation" && kind != "subscription" {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	operation := &Operation{Kind: kind}
+	if p.token.kind == tokenName {
+		operation.Name = p.token.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.token.is(tokenPunct, ")") {
+			definition, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			operation.Variables = append(operation.Variables, definition)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	operation.Selections = selections
+	return operation, nil
+}
+
+func (p *parser) variableDefinition() (VariableDefinition, error) {
+	var definition VariableDefinition
+	if err := p.expect("$"); err != nil {
+		return definition, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return definition, err
+	}
+	definition.Name = name
+	if err := p.expect(":"); err != nil {
+		return definition, err
+	}
+	if definition.Type, err = p.typeReference(); err != nil {
+		return definition, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return definition, err
+	} else if ok {
+		if definition.Default, err = p.value(true); err != nil {
+			return definition, err
+		}
+		definition.HasDefault = true
+	}
+	return definition, nil
+}
+
+// typeReference parses a type such as [String!]! back into its text
+func (p *parser) typeReference() (string, error) {
+	var text string
+	if ok, err := p.skip("["); err != nil {
+		return "", err
+	} else if ok {
+		inner, err := p.typeReference()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		text = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		text = name
+	}
+	if ok, err := p.skip("!"); err != nil {
+		return "", err
+	} else if ok {
+		text += "!"
+	}
+	return text, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if !p.token.is(tokenName, "on") {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	typeCondition, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &Fragment{Name: name, TypeCondition: typeCondition, Selections: selections}, nil
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []Selection
+	for !p.token.is(tokenPunct, "}") {
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("empty selection set at offset %d", p.token.offset)
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) selection() (Selection, error) {
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.fragmentSelection()
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	field := &Field{Name: name}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		field.Alias = name
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if field.Arguments, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if field.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.token.is(tokenPunct, "{") {
+		if field.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+// fragmentSelection parses what follows the ... of a fragment spread or inline fragment
+func (p *parser) fragmentSelection() (Selection, error) {
+	if p.token.kind == tokenName && p.token.value != "on" {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		directives, err := p.directives()
+		if err != nil {
+			return nil, err
+		}
+		return &FragmentSpread{Name: name, Directives: directives}, nil
+	}
+
+	fragment := &InlineFragment{}
+	if p.token.is(tokenName, "on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		typeCondition, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		fragment.TypeCondition = typeCondition
+	}
+	var err error
+	if fragment.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if fragment.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+func (p *parser) arguments() (map[string]interface{}, error) {
+	ok, err := p.skip("(")
+	if err != nil || !ok {
+		return nil, err
+	}
+	arguments := map[string]interface{}{}
+	for !p.token.is(tokenPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arguments[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return arguments, p.advance()
+}
+
+func (p *parser) directives() ([]Directive, error) {
+	var directives []Directive
+	for p.token.is(tokenPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, Directive{Name: name, Arguments: arguments})
+	}
+	return directives, nil
+}
+
+// value parses an argument or default value; constant values may not refer to variables
+func (p *parser) value(constant bool) (interface{}, error) {
+	token := p.token
+	switch {
+	case token.is(tokenPunct, "$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return Variable(name), err
+
+	case token.is(tokenPunct, "["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.token.is(tokenPunct, "]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, p.advance()
+
+	case token.is(tokenPunct, "{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		object := map[string]interface{}{}
+		for !p.token.is(tokenPunct, "}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return object, p.advance()
+
+	case token.kind == tokenInt:
+		n, err := strconv.Atoi(token.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %s at offset %d", token.value, token.offset)
+		}
+		return n, p.advance()
+
+	case token.kind == tokenFloat:
+		f, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s at offset %d", token.value, token.offset)
+		}
+		return f, p.advance()
+
+	case token.kind == tokenString:
+		return token.value, p.advance()
+
+	case token.kind == tokenName:
+		var value interface{}
+		switch token.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			value = EnumValue(token.value)
+		}
+		return value, p.advance()
+	}
+	return nil, p.unexpected()
+}
+
+// Unicode byte order mark, ignored like whitespace
+const byteOrderMark = "\uFEFF"
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind   tokenKind
+	value  string
+	offset int
+}
+
+func (t token) is(kind tokenKind, value string) bool {
+	return t.kind == kind && t.value == value
+}
+
+// lexer splits a document into tokens, skipping whitespace, commas and comments
+type lexer struct {
+	source string
+	pos    int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.source) {
+		c := l.source[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.source) && l.source[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.source[l.pos:], byteOrderMark):
+			l.pos += len(byteOrderMark)
+		default:
+			return l.token()
+		}
+	}
+	return token{kind: tokenEOF, offset: l.pos}, nil
+}
+
+func (l *lexer) token() (token, error) {
+	start := l.pos
+	c := l.source[l.pos]
+	switch {
+	case strings.HasPrefix(l.source[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunct, value: "...", offset: start}, nil
+	case strings.IndexByte("!$():=@[]{}|&", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunct, value: string(c), offset: start}, nil
+	case c == '"':
+		return l.string()
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.source) && (l.source[l.pos] == '_' || isLetter(l.source[l.pos]) || isDigit(l.source[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.source[start:l.pos], offset: start}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	}
+	r, _ := utf8.DecodeRuneInString(l.source[l.pos:])
+	return token{}, fmt.Errorf("unexpected character %q at offset %d", r, start)
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokenInt
+	if l.source[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		from := l.pos
+		for l.pos < len(l.source) && isDigit(l.source[l.pos]) {
+			l.pos++
+		}
+		return l.pos - from
+	}
+	if digits() == 0 {
+		return token{}, fmt.Errorf("invalid number at offset %d", start)
+	}
+	if l.pos < len(l.source) && l.source[l.pos] == '.' {
+		l.pos++
+		kind = tokenFloat
+		if digits() == 0 {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if l.pos < len(l.source) && (l.source[l.pos] == 'e' || l.source[l.pos] == 'E') {
+		l.pos++
+		kind = tokenFloat
+		if l.pos < len(l.source) && (l.source[l.pos] == '+' || l.source[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	return token{kind: kind, value: l.source[start:l.pos], offset: start}, nil
+}
+
+// string reads a quoted string; block strings are not supported
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.source) {
+		c := l.source[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, value: b.String(), offset: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, fmt.Errorf("unterminated string at offset %d", start)
+		case c == '\\':
+			if l.pos+1 >= len(l.source) {
+				return token{}, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			escape := l.source[l.pos+1]
+			l.pos += 2
+			switch escape {
+			case '"', '\\', '/':
+				b.WriteByte(escape)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.source) {
+					return token{}, fmt.Errorf("invalid unicode escape at offset %d", l.pos)
+				}
+				code, err := strconv.ParseUint(l.source[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("invalid unicode escape at offset %d", l.pos)
+				}
+				b.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("invalid escape \\%c at offset %d", escape, l.pos-2)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
@@ -0,0 +1,250 @@
+// Package graphql executes GraphQL queries and subscriptions against a schema of Go resolvers.
+// It covers what the analytics graph needs: object types with field arguments, the built-in
+// scalars plus DateTime, variables, aliases, fragments and @skip/@include. Interfaces,
+// unions, input objects and introspection beyond __typename are not supported.
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResolveFunc returns the value of a field of source, the value its parent field resolved to
+// (nil for root fields), given the field's coerced arguments
+type ResolveFunc func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// SubscribeFunc returns the events of a subscription field until ctx is done. Each event is
+// resolved like the value of the field.
+type SubscribeFunc func(ctx context.Context, args map[string]interface{}) (<-chan interface{}, error)
+
+// Object is an object type of a schema
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*FieldDefinition
+}
+
+// field returns the definition of a field, nil when the object has none of that name
+func (o *Object) field(name string) *FieldDefinition {
+	for _, field := range o.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// FieldDefinition is a field of an object type. Type is written as in SDL, e.g. [Post!]!.
+// Fields without Resolve read the struct field or map key of the same name, ignoring case
+// and underscores.
+type FieldDefinition struct {
+	Name        string
+	Type        string
+	Description string
+	Args        []Argument
+	Resolve     ResolveFunc
+	Subscribe   SubscribeFunc
+}
+
+// Argument is an argument of a field, with an optional default
+type Argument struct {
+	Name        string
+	Type        string
+	Description string
+	Default     interface{}
+}
+
+// Built-in scalars, plus DateTime for time.Time values as RFC 3339 strings
+var scalars = map[string]bool{"ID": true, "String": true, "Int": true, "Float": true, "Boolean": true, "DateTime": true}
+
+// Schema is a validated set of object types with their query and subscription roots
+type Schema struct {
+	query        *Object
+	subscription *Object
+	objects      map[string]*Object
+
+	// Deepest selection a query may nest, 0 for no limit
+	MaxDepth int
+}
+
+// NewSchema validates the types of a schema. subscription may be nil; objects lists every
+// other object type the fields refer to.
+func NewSchema(query, subscription *Object, objects ...*Object) (*Schema, error) {
+	s := &Schema{query: query, subscription: subscription, objects: map[string]*Object{}}
+	roots := []*Object{query}
+	if subscription != nil {
+		roots = append(roots, subscription)
+	}
+	for _, object := range append(roots, objects...) {
+		if _, ok := s.objects[object.Name]; ok || scalars[object.Name] {
+			return nil, fmt.Errorf("type %s is defined twice", object.Name)
+		}
+		s.objects[object.Name] = object
+	}
+
+	for _, object := range s.objects {
+		for _, field := range object.Fields {
+			if err := s.checkType(field.Type, false); err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", object.Name, field.Name, err)
+			}
+			for _, arg := range field.Args {
+				if err := s.checkType(arg.Type, true); err != nil {
+					return nil, fmt.Errorf("%s.%s(%s): %w", object.Name, field.Name, arg.Name, err)
+				}
+			}
+			if object == subscription && field.Subscribe == nil {
+				return nil, fmt.Errorf("subscription field %s has no Subscribe function", field.Name)
+			}
+		}
+	}
+	return s, nil
+}
+
+// checkType checks that a type reference names a known type; inputs must be scalars
+func (s *Schema) checkType(text string, input bool) error {
+	t, err := parseTypeRef(text)
+	if err != nil {
+		return err
+	}
+	name := t.namedType()
+	if scalars[name] {
+		return nil
+	}
+	if _, ok := s.objects[name]; ok && !input {
+		return nil
+	}
+	return fmt.Errorf("unknown %s type %s", map[bool]string{true: "input", false: "output"}[input], name)
+}
+
+// SDL prints the schema in the GraphQL schema definition language
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	b.WriteString("scalar DateTime\n\nschema {\n  query: " + s.query.Name + "\n")
+	if s.subscription != nil {
+		b.WriteString("  subscription: " + s.subscription.Name + "\n")
+	}
+	b.WriteString("}\n")
+
+	names := make([]string, 0, len(s.objects))
+	for name := range s.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		object := s.objects[name]
+		b.WriteString("\n")
+		writeDescription(&b, "", object.Description)
+		b.WriteString("type " + object.Name + " {\n")
+		for _, field := range object.Fields {
+			writeDescription(&b, "  ", field.Description)
+			b.WriteString("  " + field.Name)
+			if len(field.Args) > 0 {
+				args := make([]string, 0, len(field.Args))
+				for _, arg := range field.Args {
+					text := arg.Name + ": " + arg.Type
+					if arg.Default != nil {
+						text += " = " + formatValue(arg.Default)
+					}
+					args = append(args, text)
+				}
+				b.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			b.WriteString(": " + field.Type + "\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description != "" {
+		b.WriteString(indent + `"` + strings.ReplaceAll(description, `"`, `\"`) + `"` + "\n")
+	}
+}
+
+// formatValue prints a default value as a GraphQL literal
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case EnumValue:
+		return string(v)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, formatValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(value)
+}
+
+// typeRef is a parsed type reference: a named type, or a list of an item type, optionally
+// non-null
+type typeRef struct {
+	name    string
+	item    *typeRef
+	nonNull bool
+}
+
+func parseTypeRef(text string) (*typeRef, error) {
+	t := &typeRef{}
+	if rest, ok := strings.CutSuffix(text, "!"); ok {
+		t.nonNull = true
+		text = rest
+	}
+	if inner, ok := strings.CutPrefix(text, "["); ok {
+		inner, ok = strings.CutSuffix(inner, "]")
+		if !ok {
+			return nil, fmt.Errorf("invalid type %s", text)
+		}
+		item, err := parseTypeRef(inner)
+		if err != nil {
+			return nil, err
+		}
+		t.item = item
+		return t, nil
+	}
+	if text == "" || strings.ContainsAny(text, "[]!") {
+		return nil, fmt.Errorf("invalid type %s", text)
+	}
+	t.name = text
+	return t, nil
+}
+
+// mustParseTypeRef parses a type reference the schema has already validated
+func mustParseTypeRef(text string) *typeRef {
+	t, err := parseTypeRef(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// namedType returns the name of the type a reference ends in, e.g. Post for [Post!]!
+func (t *typeRef) namedType() string {
+	for t.item != nil {
+		t = t.item
+	}
+	return t.name
+}
+
+// nullable returns the type without its non-null marker
+func (t *typeRef) nullable() *typeRef {
+	nullable := *t
+	nullable.nonNull = false
+	return &nullable
+}
+
+func (t *typeRef) String() string {
+	text := t.name
+	if t.item != nil {
+		text = "[" + t.item.String() + "]"
+	}
+	if t.nonNull {
+		text += "!"
+	}
+	return text
+}
@@ -0,0 +1,339 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/graphql"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Largest request body accepted
+	maxRequestBytes = 1 << 20
+
+	// How long a subscription waits for hub messages before checking whether it is still wanted
+	subscribeWait = 15 * time.Second
+
+	// How long a WebSocket client has to send connection_init
+	connectionInitWait = 10 * time.Second
+
+	// WebSocket subprotocol of GraphQL over WebSocket
+	transportProtocol = "graphql-transport-ws"
+)
+
+type Handler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+// NewHandler serves the analytics graph from the services behind the REST API. embeddings
+// and hub may be nil: similar posts and subscriptions then fail with an error.
+func NewHandler(firestoreClient *services.FirestoreClient, embeddings *services.EmbeddingService, hub *services.WebSocketHub) *Handler {
+	r := &resolver{
+		posts:     firestoreClient,
+		analytics: services.NewDashboardAnalytics(firestoreClient),
+		hub:       hub,
+	}
+	if embeddings != nil {
+		r.similar = embeddings
+	}
+	return newHandler(r)
+}
+
+func newHandler(r *resolver) *Handler {
+	schema, err := newSchema(r)
+	if err != nil {
+		// The schema is static, so this is a programming error
+		panic(err)
+	}
+	return &Handler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    []string{transportProtocol},
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
+	}
+}
+
+// Serve answers queries sent as JSON with POST or as ?query= with GET, and upgrades GET
+// requests that ask for it to subscriptions over graphql-transport-ws
+func (h *Handler) Serve(c *gin.Context) {
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.serveWebSocket(c)
+		return
+	}
+
+	var req graphql.Request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"errors": []gin.H{{"message": "variables must be a JSON object"}}})
+				return
+			}
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestBytes)).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []gin.H{{"message": "body must be a JSON object with a query"}}})
+		return
+	}
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []gin.H{{"message": "query is required"}}})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.schema.Execute(withLoader(c.Request.Context()), req))
+}
+
+// GetSchema returns the schema in the GraphQL schema definition language
+func (h *Handler) GetSchema(c *gin.Context) {
+	c.String(http.StatusOK, h.schema.SDL())
+}
+
+// Subscription fields
+
+func (r *resolver) trendingUpdated(ctx context.Context, args map[string]interface{}) (<-chan interface{}, error) {
+	posts := map[string]bool{}
+	filters := []string{}
+	for _, postID := range listArg(args, "postIds") {
+		posts[postID] = true
+		filters = append(filters, "post:"+postID)
+	}
+	return r.hubEvents(ctx, "trending_update", filters, func(data []byte) (interface{}, bool) {
+		var update services.TrendingUpdateMessage
+		if err := json.Unmarshal(data, &update); err != nil {
+			return nil, false
+		}
+		return update, len(posts) == 0 || posts[update.PostID]
+	})
+}
+
+func (r *resolver) viralAlerts(ctx context.Context, args map[string]interface{}) (<-chan interface{}, error) {
+	tiers := map[string]bool{}
+	filters := []string{}
+	for _, tier := range listArg(args, "tiers") {
+		tiers[tier] = true
+		filters = append(filters, "tier:"+tier)
+	}
+	return r.hubEvents(ctx, "viral_alert", filters, func(data []byte) (interface{}, bool) {
+		var alert services.ViralAlertMessage
+		if err := json.Unmarshal(data, &alert); err != nil {
+			return nil, false
+		}
+		return alert, len(tiers) == 0 || tiers[alert.Tier]
+	})
+}
+
+// hubEvents registers a hub client for a subscription and passes on the broadcasts of one
+// message type that decode accepts, until ctx is done or the hub drops the client. The type
+// and filters are listed as the client's subscriptions in the admin API.
+func (r *resolver) hubEvents(ctx context.Context, messageType string, filters []string, decode func(data []byte) (interface{}, bool)) (<-chan interface{}, error) {
+	if r.hub == nil {
+		return nil, errors.New("subscriptions are not served by this instance")
+	}
+
+	client := services.NewStreamClient(r.hub, "", "graphql")
+	client.SetSubscriptions(append([]string{messageType}, filters...)...)
+	r.hub.RegisterClient(client)
+
+	events := make(chan interface{})
+	go func() {
+		defer close(events)
+		defer client.Close()
+		for {
+			messages, ok := client.Next(ctx, subscribeWait)
+			if !ok {
+				return
+			}
+			for _, data := range messages {
+				var envelope struct {
+					Type string `json:"type"`
+				}
+				if err := json.Unmarshal(data, &envelope); err != nil || envelope.Type != messageType {
+					continue
+				}
+				event, ok := decode(data)
+				if !ok {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// listArg returns a list of strings argument, nil when it is absent
+func listArg(args map[string]interface{}, name string) []string {
+	items, _ := args[name].([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// GraphQL over WebSocket
+
+// transportMessage is a message of the graphql-transport-ws protocol
+type transportMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Close codes of the graphql-transport-ws protocol
+const (
+	closeInvalidMessage    = 4400
+	closeUnauthorized      = 4401
+	closeInitTimeout       = 4408
+	closeSubscriberExists  = 4409
+	closeTooManyInitialise = 4429
+)
+
+// serveWebSocket runs the operations of a graphql-transport-ws connection, each until it
+// ends, the client completes it or the connection closes
+func (h *Handler) serveWebSocket(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.Warnf("Failed to upgrade GraphQL connection to WebSocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	var writeMu sync.Mutex
+	send := func(message transportMessage) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(message)
+	}
+	closeWith := func(code int, reason string) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	}
+
+	var mu sync.Mutex
+	operations := map[string]context.CancelFunc{}
+	acknowledged := false
+	conn.SetReadDeadline(time.Now().Add(connectionInitWait))
+
+	for {
+		var message transportMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			var netErr net.Error
+			if !acknowledged && errors.As(err, &netErr) && netErr.Timeout() {
+				closeWith(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+
+		switch message.Type {
+		case "connection_init":
+			if acknowledged {
+				closeWith(closeTooManyInitialise, "Too many initialisation requests")
+				return
+			}
+			acknowledged = true
+			conn.SetReadDeadline(time.Time{})
+			if err := send(transportMessage{Type: "connection_ack"}); err != nil {
+				return
+			}
+
+		case "ping":
+			if err := send(transportMessage{Type: "pong"}); err != nil {
+				return
+			}
+
+		case "pong":
+
+		case "subscribe":
+			if !acknowledged {
+				closeWith(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var req graphql.Request
+			if message.ID == "" || json.Unmarshal(message.Payload, &req) != nil {
+				closeWith(closeInvalidMessage, "Invalid subscribe message")
+				return
+			}
+			mu.Lock()
+			if _, ok := operations[message.ID]; ok {
+				mu.Unlock()
+				closeWith(closeSubscriberExists, "Subscriber for "+message.ID+" already exists")
+				return
+			}
+			opCtx, opCancel := context.WithCancel(ctx)
+			operations[message.ID] = opCancel
+			mu.Unlock()
+
+			go func(id string) {
+				defer func() {
+					mu.Lock()
+					delete(operations, id)
+					mu.Unlock()
+					opCancel()
+				}()
+				h.runOperation(opCtx, id, req, send)
+			}(message.ID)
+
+		case "complete":
+			mu.Lock()
+			if opCancel, ok := operations[message.ID]; ok {
+				opCancel()
+			}
+			mu.Unlock()
+
+		default:
+			closeWith(closeInvalidMessage, "Unknown message type "+message.Type)
+			return
+		}
+	}
+}
+
+// runOperation sends the responses of one operation, then completes it unless the client
+// completed it first
+func (h *Handler) runOperation(ctx context.Context, id string, req graphql.Request, send func(transportMessage) error) {
+	responses, failed := h.schema.Subscribe(ctx, req)
+	if failed != nil {
+		payload, _ := json.Marshal(failed.Errors)
+		send(transportMessage{ID: id, Type: "error", Payload: payload})
+		return
+	}
+
+	for response := range responses {
+		payload, err := json.Marshal(response)
+		if err != nil {
+			logger.Errorf("❌ Failed to encode GraphQL response: %v", err)
+			continue
+		}
+		if err := send(transportMessage{ID: id, Type: "next", Payload: payload}); err != nil {
+			return
+		}
+	}
+	if ctx.Err() == nil {
+		send(transportMessage{ID: id, Type: "complete"})
+	}
+}
@@ -0,0 +1,189 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type fakeServices struct {
+	posts        map[string]*models.TrendingScore
+	creatorReads int
+}
+
+func (f *fakeServices) GetPostStats(postID string) (*models.TrendingScore, error) {
+	return f.posts[postID], nil
+}
+
+func (f *fakeServices) GetUserRecommendations(userID string, limit int) ([]models.Recommendation, error) {
+	return []models.Recommendation{{UserID: userID, PostID: "p2", Score: 0.9, Reason: "similar_content"}}, nil
+}
+
+func (f *fakeServices) GetTrendingPostsWithContent(limit int, fields services.FieldSet, creatorTier string) ([]models.TrendingScore, error) {
+	return []models.TrendingScore{*f.posts["p1"], *f.posts["p2"]}[:limit], nil
+}
+
+func (f *fakeServices) GetFilteredTrendingPosts(filter services.ContentFilter, limit int, fields services.FieldSet, creatorTier string) ([]models.TrendingScore, error) {
+	return []models.TrendingScore{*f.posts["p2"]}, nil
+}
+
+func (f *fakeServices) GetTopCreators(limit int, tier string, filter services.ContentFilter, window *services.DashboardWindow) ([]services.CreatorMetrics, error) {
+	return []services.CreatorMetrics{{UserID: "c1", TotalScore: 12}}, nil
+}
+
+func (f *fakeServices) GetCreatorAnalytics(creatorID string, postLimit, days int) (*services.CreatorAnalytics, error) {
+	f.creatorReads++
+	return &services.CreatorAnalytics{
+		UserID:     creatorID,
+		TotalViews: 500,
+		Posts:      []services.CreatorPostMetrics{{PostID: "p1", Views: 300}, {PostID: "p2", Views: 200}},
+	}, nil
+}
+
+func newTestServer(t *testing.T, hub *services.WebSocketHub) (*httptest.Server, *fakeServices) {
+	t.Helper()
+	fake := &fakeServices{posts: map[string]*models.TrendingScore{
+		"p1": {PostID: "p1", Score: 42, ViewCount: 300, CreatorID: "c1", Keywords: []string{"sunset"}},
+		"p2": {PostID: "p2", Score: 17, ViewCount: 200, CreatorID: "c1"},
+	}}
+	h := newHandler(&resolver{posts: fake, analytics: fake, hub: hub})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/graphql", h.Serve)
+	router.POST("/graphql", h.Serve)
+	router.GET("/graphql/schema", h.GetSchema)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, fake
+}
+
+func post(t *testing.T, server *httptest.Server, body string) (int, string) {
+	t.Helper()
+	resp, err := http.Post(server.URL+"/graphql", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to post query: %v", err)
+	}
+	defer resp.Body.Close()
+	var out json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, string(out)
+}
+
+func TestServe_Query(t *testing.T) {
+	server, fake := newTestServer(t, nil)
+
+	status, body := post(t, server, `{"query":"query($n: Int) { trending(limit: $n) { id score keywords creator { id totalViews posts(limit: 1) { postId post { viewCount } } } } }","variables":{"n":2}}`)
+	want := `{"data":{"trending":[` +
+		`{"id":"p1","score":42,"keywords":["sunset"],"creator":{"id":"c1","totalViews":500,"posts":[{"postId":"p1","post":{"viewCount":300}}]}},` +
+		`{"id":"p2","score":17,"keywords":[],"creator":{"id":"c1","totalViews":500,"posts":[{"postId":"p1","post":{"viewCount":300}}]}}]}}`
+	if status != http.StatusOK || body != want {
+		t.Errorf("Unexpected response %d\n got %s\nwant %s", status, body, want)
+	}
+	if fake.creatorReads != 1 {
+		t.Errorf("Expected the shared creator to be read once per request, got %d reads", fake.creatorReads)
+	}
+}
+
+func TestServe_FieldErrors(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+
+	// Similar posts are unavailable without embeddings; the rest of the query still resolves
+	_, body := post(t, server, `{"query":"{ post(id: \"p1\") { id similar { postId } } recommendations(userId: \"u1\") { post { id } } }"}`)
+	want := `{"data":{"post":null,"recommendations":[{"post":{"id":"p2"}}]},"errors":[{"message":"similar posts require the vertex AI provider","path":["post","similar"]}]}`
+	if body != want {
+		t.Errorf("Unexpected response\n got %s\nwant %s", body, want)
+	}
+
+	_, body = post(t, server, `{"query":"{ topCreators(limit: 500) { userId } }"}`)
+	if !strings.Contains(body, "limit must be between 1 and 50") {
+		t.Errorf("Expected the limit to be checked, got %s", body)
+	}
+
+	if status, _ := post(t, server, `not json`); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed body, got %d", status)
+	}
+}
+
+func TestGetSchema(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	resp, err := http.Get(server.URL + "/graphql/schema")
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+	defer resp.Body.Close()
+	sdl, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+	if !strings.Contains(string(sdl), "trendingUpdated(postIds: [ID!]): TrendingUpdate!") {
+		t.Errorf("Expected the subscriptions in the schema:\n%s", sdl)
+	}
+}
+
+func TestServe_Subscription(t *testing.T) {
+	hub := services.NewWebSocketHub()
+	go hub.Run()
+	server, _ := newTestServer(t, hub)
+
+	dialer := websocket.Dialer{Subprotocols: []string{transportProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	read := func() transportMessage {
+		t.Helper()
+		var message transportMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		return message
+	}
+
+	conn.WriteJSON(transportMessage{Type: "connection_init"})
+	if message := read(); message.Type != "connection_ack" {
+		t.Fatalf("Expected connection_ack, got %+v", message)
+	}
+
+	payload, _ := json.Marshal(map[string]string{"query": `subscription { trendingUpdated(postIds: ["p2"]) { postId score post { viewCount } } }`})
+	conn.WriteJSON(transportMessage{ID: "1", Type: "subscribe", Payload: payload})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.GetClientCount() < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the subscription to register a hub client")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	hub.BroadcastTrendingUpdate("p1", 50, 310)
+	hub.BroadcastTrendingUpdate("p2", 20, 210)
+
+	message := read()
+	if message.ID != "1" || message.Type != "next" || string(message.Payload) != `{"data":{"trendingUpdated":{"postId":"p2","score":20,"post":{"viewCount":200}}}}` {
+		t.Errorf("Unexpected message %s %s %s", message.ID, message.Type, message.Payload)
+	}
+
+	// Completing the subscription unregisters its hub client
+	conn.WriteJSON(transportMessage{ID: "1", Type: "complete"})
+	deadline = time.Now().Add(2 * time.Second)
+	for hub.GetClientCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the completed subscription to unregister, got %d clients", hub.GetClientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,476 @@
+// Package graphqlapi serves the analytics graph at /graphql: trending posts, post stats,
+// creators and recommendations as typed objects whose fields resolve from the same services
+// as the REST API, and subscriptions to the WebSocket hub's broadcasts.
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/graphql"
+	"confluent-viral-intelligence/internal/models"
+	"confluent-viral-intelligence/internal/services"
+)
+
+// Deepest selection a query may nest, so one request cannot fan out into unbounded reads
+const maxQueryDepth = 8
+
+// Posts loaded for a creator, enough for the largest posts(limit:) selection
+const creatorPostLimit = 100
+
+// Days of score history loaded for a creator
+const creatorHistoryDays = 30
+
+// postStore reads posts and recommendations; *services.FirestoreClient implements it
+type postStore interface {
+	GetPostStats(postID string) (*models.TrendingScore, error)
+	GetUserRecommendations(userID string, limit int) ([]models.Recommendation, error)
+}
+
+// analytics computes rankings; *services.DashboardAnalytics implements it
+type analytics interface {
+	GetTrendingPostsWithContent(limit int, fields services.FieldSet, creatorTier string) ([]models.TrendingScore, error)
+	GetFilteredTrendingPosts(filter services.ContentFilter, limit int, fields services.FieldSet, creatorTier string) ([]models.TrendingScore, error)
+	GetTopCreators(limit int, tier string, filter services.ContentFilter, window *services.DashboardWindow) ([]services.CreatorMetrics, error)
+	GetCreatorAnalytics(creatorID string, postLimit, days int) (*services.CreatorAnalytics, error)
+}
+
+// similarFinder finds posts with similar content; *services.EmbeddingService implements it
+type similarFinder interface {
+	FindSimilar(postID string, limit int) ([]models.SimilarPost, error)
+}
+
+type resolver struct {
+	posts     postStore
+	analytics analytics
+	similar   similarFinder // nil without the vertex AI provider
+	hub       *services.WebSocketHub
+}
+
+// newSchema builds the analytics graph on top of a resolver
+func newSchema(r *resolver) (*graphql.Schema, error) {
+	postRef := graphql.FieldDefinition{Name: "post", Type: "Post", Description: "The post with its current stats", Resolve: r.postOf}
+
+	post := &graphql.Object{Name: "Post", Description: "A post with its trending score and engagement", Fields: []*graphql.FieldDefinition{
+		{Name: "id", Type: "ID!", Resolve: r.postID},
+		{Name: "score", Type: "Float!"},
+		{Name: "viralProbability", Type: "Float!"},
+		{Name: "viralTier", Type: "String", Description: "Highest viral alert tier the post reaches"},
+		{Name: "engagementRate", Type: "Float!"},
+		{Name: "engagementVelocity", Type: "Float!", Description: "Interactions per minute"},
+		{Name: "viewCount", Type: "Int!"},
+		{Name: "likeCount", Type: "Int!"},
+		{Name: "commentCount", Type: "Int!"},
+		{Name: "shareCount", Type: "Int!"},
+		{Name: "remixCount", Type: "Int!"},
+		{Name: "uniqueViewers", Type: "Int!"},
+		{Name: "completionRate", Type: "Float!"},
+		{Name: "sentimentScore", Type: "Float!"},
+		{Name: "contentType", Type: "String"},
+		{Name: "title", Type: "String"},
+		{Name: "description", Type: "String"},
+		{Name: "outputUrls", Type: "[String!]!"},
+		{Name: "thumbnailUrl", Type: "String"},
+		{Name: "category", Type: "String"},
+		{Name: "style", Type: "String"},
+		{Name: "keywords", Type: "[String!]!"},
+		{Name: "creatorId", Type: "ID"},
+		{Name: "calculatedAt", Type: "DateTime"},
+		{Name: "updatedAt", Type: "DateTime"},
+		{Name: "creator", Type: "Creator", Resolve: r.postCreator},
+		{Name: "similar", Type: "[SimilarPost!]!", Description: "Posts with similar content",
+			Args: []graphql.Argument{{Name: "limit", Type: "Int", Default: 10}}, Resolve: r.similarPosts},
+	}}
+
+	similarPost := &graphql.Object{Name: "SimilarPost", Fields: []*graphql.FieldDefinition{
+		{Name: "postId", Type: "ID!"},
+		{Name: "contentType", Type: "String"},
+		{Name: "similarity", Type: "Float!"},
+		&postRef,
+	}}
+
+	recommendation := &graphql.Object{Name: "Recommendation", Fields: []*graphql.FieldDefinition{
+		{Name: "postId", Type: "ID!"},
+		{Name: "score", Type: "Float!"},
+		{Name: "reason", Type: "String!"},
+		{Name: "category", Type: "String"},
+		{Name: "generatedAt", Type: "DateTime"},
+		&postRef,
+	}}
+
+	creator := &graphql.Object{Name: "Creator", Description: "A creator's engagement across their posts", Fields: []*graphql.FieldDefinition{
+		{Name: "id", Type: "ID!", Resolve: r.creatorID},
+		{Name: "totalViews", Type: "Int!"},
+		{Name: "totalLikes", Type: "Int!"},
+		{Name: "totalComments", Type: "Int!"},
+		{Name: "totalShares", Type: "Int!"},
+		{Name: "totalRemixes", Type: "Int!"},
+		{Name: "postCount", Type: "Int!"},
+		{Name: "viralPostCount", Type: "Int!"},
+		{Name: "totalScore", Type: "Float!"},
+		{Name: "engagementRate", Type: "Float!"},
+		{Name: "bestContentType", Type: "String", Description: "Content type with the most views per post"},
+		{Name: "updatedAt", Type: "DateTime"},
+		{Name: "posts", Type: "[CreatorPost!]!", Description: "Most viewed posts first",
+			Args: []graphql.Argument{{Name: "limit", Type: "Int", Default: 10}}, Resolve: r.creatorPosts},
+	}}
+
+	creatorPost := &graphql.Object{Name: "CreatorPost", Fields: []*graphql.FieldDefinition{
+		{Name: "postId", Type: "ID!"},
+		{Name: "contentType", Type: "String"},
+		{Name: "views", Type: "Int!"},
+		{Name: "likes", Type: "Int!"},
+		{Name: "comments", Type: "Int!"},
+		{Name: "shares", Type: "Int!"},
+		{Name: "remixes", Type: "Int!"},
+		{Name: "score", Type: "Float!"},
+		{Name: "viralAt", Type: "DateTime"},
+		&postRef,
+	}}
+
+	creatorMetrics := &graphql.Object{Name: "CreatorMetrics", Description: "A creator's rank on the dashboard", Fields: []*graphql.FieldDefinition{
+		{Name: "userId", Type: "ID!"},
+		{Name: "username", Type: "String"},
+		{Name: "displayName", Type: "String"},
+		{Name: "photoUrl", Type: "String"},
+		{Name: "tier", Type: "String"},
+		{Name: "totalScore", Type: "Float!"},
+		{Name: "totalViews", Type: "Int!"},
+		{Name: "totalLikes", Type: "Int!"},
+		{Name: "totalComments", Type: "Int!"},
+		{Name: "postCount", Type: "Int!"},
+		{Name: "viralPostCount", Type: "Int!"},
+		{Name: "followerCount", Type: "Int!"},
+		{Name: "engagementRate", Type: "Float!"},
+		{Name: "averageScore", Type: "Float!"},
+		{Name: "creator", Type: "Creator", Resolve: r.metricsCreator},
+	}}
+
+	trendingUpdate := &graphql.Object{Name: "TrendingUpdate", Fields: []*graphql.FieldDefinition{
+		{Name: "postId", Type: "ID!"},
+		{Name: "score", Type: "Float!"},
+		{Name: "viewCount", Type: "Int!"},
+		{Name: "timestamp", Type: "DateTime", Resolve: messageTime},
+		&postRef,
+	}}
+
+	viralAlert := &graphql.Object{Name: "ViralAlert", Fields: []*graphql.FieldDefinition{
+		{Name: "postId", Type: "ID!"},
+		{Name: "tier", Type: "String!"},
+		{Name: "viralProbability", Type: "Float!"},
+		{Name: "score", Type: "Float!"},
+		{Name: "message", Type: "String!"},
+		{Name: "timestamp", Type: "DateTime", Resolve: messageTime},
+		&postRef,
+	}}
+
+	contentArgs := []graphql.Argument{
+		{Name: "contentType", Type: "String"},
+		{Name: "category", Type: "String"},
+		{Name: "keyword", Type: "String"},
+	}
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.FieldDefinition{
+		{Name: "trending", Type: "[Post!]!", Description: "Top trending posts with content",
+			Args: append([]graphql.Argument{
+				{Name: "limit", Type: "Int", Default: 20},
+				{Name: "creatorTier", Type: "String", Description: "new, emerging, established or star"},
+			}, contentArgs...),
+			Resolve: r.trending},
+		{Name: "post", Type: "Post", Args: []graphql.Argument{{Name: "id", Type: "ID!"}}, Resolve: r.post},
+		{Name: "creator", Type: "Creator", Args: []graphql.Argument{{Name: "id", Type: "ID!"}}, Resolve: r.creator},
+		{Name: "topCreators", Type: "[CreatorMetrics!]!", Description: "Creators with the most engagement",
+			Args: append([]graphql.Argument{
+				{Name: "limit", Type: "Int", Default: 10},
+				{Name: "tier", Type: "String"},
+			}, contentArgs...),
+			Resolve: r.topCreators},
+		{Name: "recommendations", Type: "[Recommendation!]!", Description: "Posts recommended to a user",
+			Args:    []graphql.Argument{{Name: "userId", Type: "ID!"}, {Name: "limit", Type: "Int", Default: 10}},
+			Resolve: r.recommendations},
+	}}
+
+	subscription := &graphql.Object{Name: "Subscription", Fields: []*graphql.FieldDefinition{
+		{Name: "trendingUpdated", Type: "TrendingUpdate!", Description: "Trending score updates, optionally of some posts only",
+			Args: []graphql.Argument{{Name: "postIds", Type: "[ID!]"}}, Subscribe: r.trendingUpdated},
+		{Name: "viralAlert", Type: "ViralAlert!", Description: "Viral alerts, optionally of some tiers only",
+			Args: []graphql.Argument{{Name: "tiers", Type: "[String!]"}}, Subscribe: r.viralAlerts},
+	}}
+
+	schema, err := graphql.NewSchema(query, subscription, post, similarPost, recommendation, creator, creatorPost, creatorMetrics, trendingUpdate, viralAlert)
+	if err != nil {
+		return nil, err
+	}
+	schema.MaxDepth = maxQueryDepth
+	return schema, nil
+}
+
+// Query fields
+
+func (r *resolver) trending(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	limit, err := intArg(args, "limit", 1, 100)
+	if err != nil {
+		return nil, err
+	}
+	creatorTier := stringArg(args, "creatorTier")
+	if creatorTier != "" && !services.IsCreatorTier(creatorTier) {
+		return nil, errors.New("creatorTier must be new, emerging, established or star")
+	}
+
+	filter := contentFilter(args)
+	var posts []models.TrendingScore
+	if !filter.IsEmpty() {
+		posts, err = r.analytics.GetFilteredTrendingPosts(filter, limit, nil, creatorTier)
+	} else {
+		posts, err = r.analytics.GetTrendingPostsWithContent(limit, nil, creatorTier)
+	}
+	if err != nil {
+		return nil, errors.New("failed to fetch trending posts")
+	}
+
+	// Later post fields of the same request reuse the loaded stats
+	l := loaderFrom(ctx)
+	result := make([]*models.TrendingScore, len(posts))
+	for i := range posts {
+		result[i] = &posts[i]
+		l.storePost(result[i])
+	}
+	return result, nil
+}
+
+func (r *resolver) post(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	return r.loadPost(ctx, args["id"].(string))
+}
+
+func (r *resolver) creator(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	return r.loadCreator(ctx, args["id"].(string))
+}
+
+func (r *resolver) topCreators(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	limit, err := intArg(args, "limit", 1, 50)
+	if err != nil {
+		return nil, err
+	}
+	tier := stringArg(args, "tier")
+	if tier != "" && !services.IsCreatorTier(tier) {
+		return nil, errors.New("tier must be new, emerging, established or star")
+	}
+
+	creators, err := r.analytics.GetTopCreators(limit, tier, contentFilter(args), nil)
+	if err != nil {
+		return nil, errors.New("failed to fetch top creators")
+	}
+	return creators, nil
+}
+
+func (r *resolver) recommendations(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	limit, err := intArg(args, "limit", 1, 50)
+	if err != nil {
+		return nil, err
+	}
+	recommendations, err := r.posts.GetUserRecommendations(args["userId"].(string), limit)
+	if err != nil {
+		return nil, errors.New("failed to fetch recommendations")
+	}
+	return recommendations, nil
+}
+
+// Object fields
+
+func (r *resolver) postID(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+	return source.(*models.TrendingScore).PostID, nil
+}
+
+func (r *resolver) postCreator(ctx context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+	creatorID := source.(*models.TrendingScore).CreatorID
+	if creatorID == "" {
+		return nil, nil
+	}
+	return r.loadCreator(ctx, creatorID)
+}
+
+func (r *resolver) similarPosts(_ context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	limit, err := intArg(args, "limit", 1, 50)
+	if err != nil {
+		return nil, err
+	}
+	if r.similar == nil {
+		return nil, errors.New("similar posts require the vertex AI provider")
+	}
+	similar, err := r.similar.FindSimilar(source.(*models.TrendingScore).PostID, limit)
+	if errors.Is(err, services.ErrEmbeddingPending) {
+		return []models.SimilarPost{}, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to find similar posts")
+	}
+	return similar, nil
+}
+
+func (r *resolver) creatorID(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+	return source.(*services.CreatorAnalytics).UserID, nil
+}
+
+func (r *resolver) creatorPosts(_ context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	limit, err := intArg(args, "limit", 1, creatorPostLimit)
+	if err != nil {
+		return nil, err
+	}
+	posts := source.(*services.CreatorAnalytics).Posts
+	return posts[:min(limit, len(posts))], nil
+}
+
+func (r *resolver) metricsCreator(ctx context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+	return r.loadCreator(ctx, source.(services.CreatorMetrics).UserID)
+}
+
+// postOf resolves the post a recommendation, similar post, creator post or broadcast is about
+func (r *resolver) postOf(ctx context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+	var postID string
+	switch source := source.(type) {
+	case models.Recommendation:
+		postID = source.PostID
+	case models.SimilarPost:
+		postID = source.PostID
+	case services.CreatorPostMetrics:
+		postID = source.PostID
+	case services.TrendingUpdateMessage:
+		postID = source.PostID
+	case services.ViralAlertMessage:
+		postID = source.PostID
+	default:
+		return nil, fmt.Errorf("no post for %T", source)
+	}
+	return r.loadPost(ctx, postID)
+}
+
+// messageTime parses the timestamp of a hub broadcast
+func messageTime(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+	var timestamp string
+	switch source := source.(type) {
+	case services.TrendingUpdateMessage:
+		timestamp = source.Timestamp
+	case services.ViralAlertMessage:
+		timestamp = source.Timestamp
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return nil, nil
+	}
+	return t, nil
+}
+
+func (r *resolver) loadPost(ctx context.Context, postID string) (*models.TrendingScore, error) {
+	l := loaderFrom(ctx)
+	if post, ok := l.post(postID); ok {
+		return post, nil
+	}
+	post, err := r.posts.GetPostStats(postID)
+	if err != nil {
+		return nil, errors.New("failed to fetch post stats")
+	}
+	l.storePostID(postID, post)
+	return post, nil
+}
+
+func (r *resolver) loadCreator(ctx context.Context, creatorID string) (*services.CreatorAnalytics, error) {
+	l := loaderFrom(ctx)
+	if creator, ok := l.creator(creatorID); ok {
+		return creator, nil
+	}
+	creator, err := r.analytics.GetCreatorAnalytics(creatorID, creatorPostLimit, creatorHistoryDays)
+	if err != nil {
+		return nil, errors.New("failed to fetch creator analytics")
+	}
+	l.storeCreator(creatorID, creator)
+	return creator, nil
+}
+
+// loader remembers the posts and creators a request loaded, so a creator shared by many
+// trending posts is read once. Subscriptions run without one, so every broadcast reads
+// fresh stats.
+type loader struct {
+	mu       sync.Mutex
+	posts    map[string]*models.TrendingScore
+	creators map[string]*services.CreatorAnalytics
+}
+
+type loaderKey struct{}
+
+// withLoader returns a context whose resolvers share a loader
+func withLoader(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &loader{
+		posts:    map[string]*models.TrendingScore{},
+		creators: map[string]*services.CreatorAnalytics{},
+	})
+}
+
+// loaderFrom returns the loader of a request, nil when it has none. A nil loader remembers
+// nothing.
+func loaderFrom(ctx context.Context) *loader {
+	l, _ := ctx.Value(loaderKey{}).(*loader)
+	return l
+}
+
+func (l *loader) post(postID string) (*models.TrendingScore, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	post, ok := l.posts[postID]
+	return post, ok
+}
+
+func (l *loader) storePost(post *models.TrendingScore) {
+	l.storePostID(post.PostID, post)
+}
+
+func (l *loader) storePostID(postID string, post *models.TrendingScore) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.posts[postID] = post
+}
+
+func (l *loader) creator(creatorID string) (*services.CreatorAnalytics, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	creator, ok := l.creators[creatorID]
+	return creator, ok
+}
+
+func (l *loader) storeCreator(creatorID string, creator *services.CreatorAnalytics) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.creators[creatorID] = creator
+}
+
+// intArg returns an Int argument, which has a default, checked against its range
+func intArg(args map[string]interface{}, name string, lowest, highest int) (int, error) {
+	value, _ := args[name].(int)
+	if value < lowest || value > highest {
+		return 0, fmt.Errorf("%s must be between %d and %d", name, lowest, highest)
+	}
+	return value, nil
+}
+
+// stringArg returns a String argument, "" when it is absent
+func stringArg(args map[string]interface{}, name string) string {
+	value, _ := args[name].(string)
+	return value
+}
+
+func contentFilter(args map[string]interface{}) services.ContentFilter {
+	return services.NewContentFilter(stringArg(args, "contentType"), stringArg(args, "category"), stringArg(args, "keyword"))
+}
@@ -0,0 +1,152 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+
+	"confluent-viral-intelligence/internal/pb/viralv1"
+	"confluent-viral-intelligence/internal/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata key carrying the API key, the X-API-Key header of the HTTP routes
+const apiKeyMetadata = "x-api-key"
+
+// Largest message accepted, the body limit of the HTTP event endpoints
+const maxMessageBytes = 1 << 20
+
+// Access guards the API like the HTTP routes guard theirs: ingestion needs an API key of the
+// ingest role, subscriptions one of the read role when reads are protected, and every call and
+// every ingested event counts against the request rate limits of its peer IP and API key.
+// Without configured keys guarded calls are rejected unless AllowUnauthenticated is set.
+type Access struct {
+	Keys                 *services.APIKeyStore
+	Limiter              *services.RequestLimiter
+	RequireRead          bool
+	AllowUnauthenticated bool
+}
+
+// ServerOptions returns the options enforcing the access rules on a gRPC server
+func (a Access) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(maxMessageBytes),
+		grpc.ChainUnaryInterceptor(a.unary),
+		grpc.ChainStreamInterceptor(a.stream),
+	}
+}
+
+func (a Access) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	key, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.limit(peerIP(ctx), key); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a Access) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	key, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	ip := peerIP(ss.Context())
+	if err := a.limit(ip, key); err != nil {
+		return err
+	}
+
+	// Each message a client streams is a request of its own
+	if info.IsClientStream {
+		ss = &limitedStream{ServerStream: ss, access: a, ip: ip, key: key}
+	}
+	return handler(srv, ss)
+}
+
+// role returns the role an API key needs to call method, "" when the method is open
+func (a Access) role(method string) string {
+	switch {
+	case method == viralv1.ViralIntelligence_IngestEvents_FullMethodName:
+		return services.RoleIngest
+	case a.RequireRead:
+		return services.RoleRead
+	}
+	return ""
+}
+
+// authorize checks the API key in the metadata of a call against the role its method needs. It
+// returns the key, nil when the method is open or no keys are needed.
+func (a Access) authorize(ctx context.Context, method string) (*services.APIKey, error) {
+	role := a.role(method)
+	if role == "" || (a.Keys == nil && a.AllowUnauthenticated) {
+		return nil, nil
+	}
+
+	var secret string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(apiKeyMetadata); len(values) > 0 {
+			secret = values[0]
+		}
+	}
+	key, err := a.Keys.Authorize(secret, role)
+	switch {
+	case errors.Is(err, services.ErrNoAPIKeys):
+		return nil, status.Error(codes.Unavailable, "no API keys are configured")
+	case errors.Is(err, services.ErrInvalidAPIKey):
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid API key")
+	case errors.Is(err, services.ErrAPIKeyRole):
+		return nil, status.Errorf(codes.PermissionDenied, "API key %s lacks the %s role", key.Name, role)
+	case errors.Is(err, services.ErrAPIKeyRateLimited):
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for API key %s", key.Name)
+	}
+	return key, nil
+}
+
+// limit takes a request from the rate limits of the peer IP and, when known, of the API key
+func (a Access) limit(ip string, key *services.APIKey) error {
+	user := ""
+	if key != nil {
+		user = "key:" + key.Name
+	}
+	if allowed, wait := a.Limiter.Allow(ip, user); !allowed {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %ds", int(math.Max(math.Ceil(wait.Seconds()), 1)))
+	}
+	return nil
+}
+
+// limitedStream rate limits every message a client streams, by its peer IP and API key
+type limitedStream struct {
+	grpc.ServerStream
+	access Access
+	ip     string
+	key    *services.APIKey
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.key != nil && !s.access.Keys.Allow(s.key) {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for API key %s", s.key.Name)
+	}
+	return s.access.limit(s.ip, s.key)
+}
+
+// peerIP returns the IP address of the caller, "" when unknown
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
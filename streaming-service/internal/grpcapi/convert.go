@@ -0,0 +1,99 @@
+package grpcapi
+
+import (
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+	"confluent-viral-intelligence/internal/pb/viralv1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// timeFromProto returns the time of a timestamp, zero when it is unset
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func interactionFromProto(event *viralv1.InteractionEvent) models.InteractionEvent {
+	interaction := models.InteractionEvent{
+		PostID:    event.GetPostId(),
+		UserID:    event.GetUserId(),
+		EventType: event.GetEventType(),
+		Timestamp: timeFromProto(event.GetTimestamp()),
+	}
+	if event.GetMetadata() != nil {
+		interaction.Metadata = event.GetMetadata().AsMap()
+	}
+	return interaction
+}
+
+func contentFromProto(event *viralv1.ContentMetadata) models.ContentMetadata {
+	content := models.ContentMetadata{
+		PostID:          event.GetPostId(),
+		UserID:          event.GetUserId(),
+		ContentType:     event.GetContentType(),
+		Prompt:          event.GetPrompt(),
+		CreatedAt:       timeFromProto(event.GetCreatedAt()),
+		Keywords:        event.GetKeywords(),
+		Hashtags:        event.GetHashtags(),
+		Language:        event.GetLanguage(),
+		EnglishKeywords: event.GetEnglishKeywords(),
+		Category:        event.GetCategory(),
+		Style:           event.GetStyle(),
+		OutputURLs:      event.GetOutputUrls(),
+	}
+	if visual := event.GetVisual(); visual != nil {
+		content.Visual = &models.VisualAnalysis{
+			Keywords:       visual.GetKeywords(),
+			Objects:        visual.GetObjects(),
+			DominantColors: visual.GetDominantColors(),
+			NSFWLikelihood: visual.GetNsfwLikelihood(),
+		}
+	}
+	if media := event.GetMedia(); media != nil {
+		content.Media = &models.MediaMetadata{
+			Width:           int(media.GetWidth()),
+			Height:          int(media.GetHeight()),
+			DurationSeconds: media.GetDurationSeconds(),
+			ThumbnailURL:    media.GetThumbnailUrl(),
+			DominantColor:   media.GetDominantColor(),
+			DurationMs:      media.GetDurationMs(),
+			Duration:        media.GetDuration(),
+		}
+	}
+	return content
+}
+
+func viewFromProto(event *viralv1.ViewEvent) models.ViewEvent {
+	return models.ViewEvent{
+		PostID:     event.GetPostId(),
+		UserID:     event.GetUserId(),
+		ViewedAt:   timeFromProto(event.GetViewedAt()),
+		Duration:   int(event.GetDuration()),
+		Platform:   event.GetPlatform(),
+		DeviceType: event.GetDeviceType(),
+		Region:     event.GetRegion(),
+	}
+}
+
+func remixFromProto(event *viralv1.RemixEvent) models.RemixEvent {
+	return models.RemixEvent{
+		OriginalPostID: event.GetOriginalPostId(),
+		RemixPostID:    event.GetRemixPostId(),
+		UserID:         event.GetUserId(),
+		RemixedAt:      timeFromProto(event.GetRemixedAt()),
+		RemixType:      event.GetRemixType(),
+	}
+}
+
+func commentFromProto(event *viralv1.CommentEvent) models.CommentEvent {
+	return models.CommentEvent{
+		CommentID: event.GetCommentId(),
+		PostID:    event.GetPostId(),
+		UserID:    event.GetUserId(),
+		Text:      event.GetText(),
+		CreatedAt: timeFromProto(event.GetCreatedAt()),
+	}
+}
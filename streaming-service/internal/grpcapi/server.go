@@ -0,0 +1,295 @@
+// Package grpcapi serves the ViralIntelligence gRPC API, typed streams of the WebSocket hub's
+// broadcasts and streaming event ingestion, on top of the same hub and event processor as the
+// HTTP and WebSocket endpoints.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/handlers"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/pb/viralv1"
+	"confluent-viral-intelligence/internal/services"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// How long a subscription waits for hub messages before checking whether it is still wanted
+const subscribeWait = 15 * time.Second
+
+type Server struct {
+	viralv1.UnimplementedViralIntelligenceServer
+
+	processor *services.EventProcessor
+	hub       *services.WebSocketHub
+
+	// Whether events are ingested; read replicas only serve subscriptions
+	ingest bool
+
+	// Closed by Drain to end the subscriptions
+	draining  chan struct{}
+	drainOnce sync.Once
+}
+
+func NewServer(processor *services.EventProcessor, hub *services.WebSocketHub, ingest bool) *Server {
+	return &Server{
+		processor: processor,
+		hub:       hub,
+		ingest:    ingest,
+		draining:  make(chan struct{}),
+	}
+}
+
+// Drain ends every subscription with Unavailable, so a graceful stop of the gRPC server only
+// waits for ingestion streams; subscriptions never finish on their own
+func (s *Server) Drain() {
+	s.drainOnce.Do(func() { close(s.draining) })
+}
+
+// hubMessage is the envelope shared by every hub broadcast
+type hubMessage struct {
+	Type string `json:"type"`
+	Seq  uint64 `json:"seq"`
+}
+
+// SubscribeTrending streams the hub's trending updates, optionally only those of some posts
+func (s *Server) SubscribeTrending(req *viralv1.SubscribeTrendingRequest, stream viralv1.ViralIntelligence_SubscribeTrendingServer) error {
+	posts := make(map[string]bool, len(req.PostIds))
+	filters := make([]string, 0, len(req.PostIds))
+	for _, postID := range req.PostIds {
+		posts[postID] = true
+		filters = append(filters, "post:"+postID)
+	}
+
+	return s.subscribe(stream.Context(), req.LastSeq, "trending_update", filters, func(data []byte) error {
+		var update struct {
+			Seq uint64 `json:"seq"`
+			services.TrendingUpdateMessage
+		}
+		if err := json.Unmarshal(data, &update); err != nil {
+			return nil
+		}
+		if len(posts) > 0 && !posts[update.PostID] {
+			return nil
+		}
+		return stream.Send(&viralv1.TrendingUpdate{
+			Seq:       update.Seq,
+			PostId:    update.PostID,
+			Score:     update.Score,
+			ViewCount: update.ViewCount,
+			Timestamp: timestampFromRFC3339(update.Timestamp),
+		})
+	})
+}
+
+// SubscribeViralAlerts streams the hub's viral alerts, optionally only those of some tiers
+func (s *Server) SubscribeViralAlerts(req *viralv1.SubscribeViralAlertsRequest, stream viralv1.ViralIntelligence_SubscribeViralAlertsServer) error {
+	tiers := make(map[string]bool, len(req.Tiers))
+	filters := make([]string, 0, len(req.Tiers))
+	for _, tier := range req.Tiers {
+		tiers[tier] = true
+		filters = append(filters, "tier:"+tier)
+	}
+
+	return s.subscribe(stream.Context(), req.LastSeq, "viral_alert", filters, func(data []byte) error {
+		var alert struct {
+			Seq uint64 `json:"seq"`
+			services.ViralAlertMessage
+		}
+		if err := json.Unmarshal(data, &alert); err != nil {
+			return nil
+		}
+		if len(tiers) > 0 && !tiers[alert.Tier] {
+			return nil
+		}
+		return stream.Send(&viralv1.ViralAlertUpdate{
+			Seq: alert.Seq,
+			Alert: &viralv1.ViralAlert{
+				PostId:           alert.PostID,
+				Tier:             alert.Tier,
+				ViralProbability: alert.ViralProbability,
+				Score:            alert.Score,
+				AlertedAt:        timestampFromRFC3339(alert.Timestamp),
+			},
+			Message: alert.Message,
+		})
+	})
+}
+
+// subscribe registers a hub client for the stream and passes it the broadcasts of one message
+// type until the stream ends. The type and the filters send applies are listed as the client's
+// subscriptions in the admin API. Broadcasts after lastSeq are replayed first; when some can no
+// longer be replayed the stream fails with DataLoss so the caller reloads its state.
+func (s *Server) subscribe(ctx context.Context, lastSeq uint64, messageType string, filters []string, send func(data []byte) error) error {
+	client := services.NewStreamClient(s.hub, "", "grpc")
+	client.SetSubscriptions(append([]string{messageType}, filters...)...)
+	s.hub.RegisterClient(client)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.draining:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if lastSeq > 0 {
+		s.hub.Resume(client, lastSeq)
+	}
+
+	for {
+		messages, ok := client.Next(ctx, subscribeWait)
+		if !ok {
+			select {
+			case <-s.draining:
+				return status.Error(codes.Unavailable, "server is shutting down")
+			default:
+			}
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			return status.Error(codes.Unavailable, "subscription closed by the server")
+		}
+
+		for _, data := range messages {
+			var envelope hubMessage
+			if err := json.Unmarshal(data, &envelope); err != nil {
+				continue
+			}
+			switch envelope.Type {
+			case "replay_gap":
+				return status.Errorf(codes.DataLoss, "broadcasts after seq %d can no longer be replayed; reload and subscribe without last_seq", lastSeq)
+			case messageType:
+				if err := send(data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// IngestEvents processes each event of the stream like the HTTP event endpoints do and answers
+// it with the outcome
+func (s *Server) IngestEvents(stream viralv1.ViralIntelligence_IngestEventsServer) error {
+	if !s.ingest {
+		return status.Error(codes.FailedPrecondition, "event ingestion is not served by read replicas")
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		response := &viralv1.IngestEventResponse{RequestId: req.RequestId, Accepted: true}
+		if err := s.ingestEvent(req); err != nil {
+			response.Accepted = false
+			response.Error = err.Error()
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+// ingestEvent validates and processes one event, defaulting its time to now like the HTTP
+// handlers
+func (s *Server) ingestEvent(req *viralv1.IngestEventRequest) error {
+	defer services.PipelineLatency.ObserveSince(services.StageHandler, time.Now())
+
+	switch event := req.Event.(type) {
+	case *viralv1.IngestEventRequest_Interaction:
+		interaction := interactionFromProto(event.Interaction)
+		if err := handlers.ValidateEvent(&interaction); err != nil {
+			return err
+		}
+		if interaction.Timestamp.IsZero() {
+			interaction.Timestamp = time.Now()
+		}
+		if err := s.processor.ProcessInteraction(interaction, req.RequestId); err != nil {
+			logger.Warnf("gRPC interaction %s failed: %v", req.RequestId, err)
+			return errors.New("failed to process interaction")
+		}
+
+	case *viralv1.IngestEventRequest_Content:
+		content := contentFromProto(event.Content)
+		if err := handlers.ValidateEvent(&content); err != nil {
+			return err
+		}
+		if content.CreatedAt.IsZero() {
+			content.CreatedAt = time.Now()
+		}
+		if _, err := s.processor.ProcessContentMetadata(content, req.RequestId); err != nil {
+			logger.Warnf("gRPC content metadata %s failed: %v", req.RequestId, err)
+			return errors.New("failed to process content metadata")
+		}
+
+	case *viralv1.IngestEventRequest_View:
+		view := viewFromProto(event.View)
+		if err := handlers.ValidateEvent(&view); err != nil {
+			return err
+		}
+		if view.ViewedAt.IsZero() {
+			view.ViewedAt = time.Now()
+		}
+		if err := s.processor.ProcessView(view, req.RequestId); err != nil {
+			logger.Warnf("gRPC view %s failed: %v", req.RequestId, err)
+			return errors.New("failed to process view")
+		}
+
+	case *viralv1.IngestEventRequest_Remix:
+		remix := remixFromProto(event.Remix)
+		if err := handlers.ValidateEvent(&remix); err != nil {
+			return err
+		}
+		if remix.RemixedAt.IsZero() {
+			remix.RemixedAt = time.Now()
+		}
+		if err := s.processor.ProcessRemix(remix, req.RequestId); err != nil {
+			logger.Warnf("gRPC remix %s failed: %v", req.RequestId, err)
+			return errors.New("failed to process remix")
+		}
+
+	case *viralv1.IngestEventRequest_Comment:
+		comment := commentFromProto(event.Comment)
+		if err := handlers.ValidateEvent(&comment); err != nil {
+			return err
+		}
+		// Text of only whitespace passes the required check
+		if strings.TrimSpace(comment.Text) == "" {
+			return errors.New("invalid request: text is required")
+		}
+		if comment.CreatedAt.IsZero() {
+			comment.CreatedAt = time.Now()
+		}
+		if err := s.processor.ProcessComment(comment, req.RequestId); err != nil {
+			logger.Warnf("gRPC comment %s failed: %v", req.RequestId, err)
+			return errors.New("failed to process comment")
+		}
+
+	default:
+		return errors.New("request carries no event")
+	}
+	return nil
+}
+
+// timestampFromRFC3339 converts a hub message timestamp, nil when it does not parse
+func timestampFromRFC3339(value string) *timestamppb.Timestamp {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return timestamppb.New(t)
+}
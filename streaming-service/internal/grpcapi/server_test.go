@@ -0,0 +1,261 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/pb/viralv1"
+	"confluent-viral-intelligence/internal/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// startServer serves the API over an in-memory listener and returns a client for it
+func startServer(t *testing.T, server *Server, opts ...grpc.ServerOption) viralv1.ViralIntelligenceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer(opts...)
+	viralv1.RegisterViralIntelligenceServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return viralv1.NewViralIntelligenceClient(conn)
+}
+
+// waitForClients waits until the hub has registered n clients
+func waitForClients(t *testing.T, hub *services.WebSocketHub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.GetClientCount() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d hub clients, got %d", n, hub.GetClientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSubscribeStreamsHubBroadcasts(t *testing.T) {
+	hub := services.NewWebSocketHub()
+	go hub.Run()
+	client := startServer(t, NewServer(nil, hub, false))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	trending, err := client.SubscribeTrending(ctx, &viralv1.SubscribeTrendingRequest{PostIds: []string{"post-2"}})
+	if err != nil {
+		t.Fatalf("Failed to subscribe to trending updates: %v", err)
+	}
+	alerts, err := client.SubscribeViralAlerts(ctx, &viralv1.SubscribeViralAlertsRequest{Tiers: []string{config.ViralTierViral}})
+	if err != nil {
+		t.Fatalf("Failed to subscribe to viral alerts: %v", err)
+	}
+	waitForClients(t, hub, 2)
+
+	// Updates of other posts and alerts of other tiers are filtered out
+	hub.BroadcastTrendingUpdate("post-1", 10, 1)
+	hub.BroadcastTrendingUpdate("post-2", 42, 7)
+	hub.BroadcastViralAlert("post-1", config.ViralTierWarming, 0.5, 10)
+	hub.BroadcastViralAlert("post-2", config.ViralTierViral, 0.8, 42)
+
+	update, err := trending.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive trending update: %v", err)
+	}
+	if update.PostId != "post-2" || update.Score != 42 || update.ViewCount != 7 || update.Seq != 2 || update.Timestamp == nil {
+		t.Errorf("Unexpected trending update %v", update)
+	}
+
+	alert, err := alerts.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive viral alert: %v", err)
+	}
+	if alert.Alert.GetPostId() != "post-2" || alert.Alert.GetTier() != config.ViralTierViral || alert.Seq != 4 || alert.Message == "" {
+		t.Errorf("Unexpected viral alert %v", alert)
+	}
+
+	// Resuming replays what was missed after the given seq
+	resumed, err := client.SubscribeTrending(ctx, &viralv1.SubscribeTrendingRequest{LastSeq: 1})
+	if err != nil {
+		t.Fatalf("Failed to resume trending updates: %v", err)
+	}
+	if update, err := resumed.Recv(); err != nil || update.Seq != 2 {
+		t.Errorf("Expected the replayed update with seq 2, got %v (%v)", update, err)
+	}
+
+	// Cancelled subscriptions leave the hub
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.GetClientCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hub.GetClientCount() != 0 {
+		t.Errorf("Expected cancelled subscriptions to unregister, got %d clients", hub.GetClientCount())
+	}
+}
+
+func TestIngestEventsOnReadReplica(t *testing.T) {
+	hub := services.NewWebSocketHub()
+	go hub.Run()
+	client := startServer(t, NewServer(nil, hub, false))
+
+	stream, err := client.IngestEvents(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open ingest stream: %v", err)
+	}
+	stream.Send(&viralv1.IngestEventRequest{RequestId: "1"})
+	if _, err := stream.Recv(); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition from a read replica, got %v", err)
+	}
+}
+
+func TestIngestEventRejectsInvalidRequests(t *testing.T) {
+	server := NewServer(nil, services.NewWebSocketHub(), true)
+
+	if err := server.ingestEvent(&viralv1.IngestEventRequest{RequestId: "empty"}); err == nil {
+		t.Error("Expected a request without an event to be rejected")
+	}
+	comment := &viralv1.IngestEventRequest{Event: &viralv1.IngestEventRequest_Comment{Comment: &viralv1.CommentEvent{PostId: "post-1", Text: "  "}}}
+	if err := server.ingestEvent(comment); err == nil {
+		t.Error("Expected a blank comment to be rejected")
+	}
+}
+
+func TestAccessGuardsIngestion(t *testing.T) {
+	keys, err := services.NewAPIKeyStore(&config.Config{
+		APIKeys:              "ingester:ingest:" + services.HashAPIKey("ingest-secret") + ",reader:read:" + services.HashAPIKey("read-secret"),
+		APIKeyRateLimitQPS:   100,
+		APIKeyRateLimitBurst: 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create API key store: %v", err)
+	}
+	hub := services.NewWebSocketHub()
+	go hub.Run()
+
+	// The read replica answers authorized ingestion with FailedPrecondition
+	ingest := func(client viralv1.ViralIntelligenceClient, secret string) codes.Code {
+		ctx := context.Background()
+		if secret != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, apiKeyMetadata, secret)
+		}
+		stream, err := client.IngestEvents(ctx)
+		if err != nil {
+			t.Fatalf("Failed to open ingest stream: %v", err)
+		}
+		stream.Send(&viralv1.IngestEventRequest{RequestId: "1"})
+		_, err = stream.Recv()
+		return status.Code(err)
+	}
+
+	guarded := startServer(t, NewServer(nil, hub, false), Access{Keys: keys}.ServerOptions()...)
+	for secret, want := range map[string]codes.Code{
+		"":              codes.Unauthenticated,
+		"wrong-secret":  codes.Unauthenticated,
+		"read-secret":   codes.PermissionDenied,
+		"ingest-secret": codes.FailedPrecondition,
+	} {
+		if got := ingest(guarded, secret); got != want {
+			t.Errorf("Expected %v for secret %q, got %v", want, secret, got)
+		}
+	}
+
+	// Without keys ingestion is refused unless unauthenticated access is allowed
+	if got := ingest(startServer(t, NewServer(nil, hub, false), Access{}.ServerOptions()...), ""); got != codes.Unavailable {
+		t.Errorf("Expected Unavailable without API keys, got %v", got)
+	}
+	if got := ingest(startServer(t, NewServer(nil, hub, false), Access{AllowUnauthenticated: true}.ServerOptions()...), ""); got != codes.FailedPrecondition {
+		t.Errorf("Expected open ingestion when unauthenticated access is allowed, got %v", got)
+	}
+
+	// Subscriptions stay open unless reads are protected
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	trending, err := guarded.SubscribeTrending(ctx, &viralv1.SubscribeTrendingRequest{})
+	if err != nil {
+		t.Fatalf("Failed to subscribe to trending updates: %v", err)
+	}
+	waitForClients(t, hub, 1)
+	hub.BroadcastTrendingUpdate("post-1", 10, 1)
+	if _, err := trending.Recv(); err != nil {
+		t.Errorf("Expected an open subscription, got %v", err)
+	}
+	protected := startServer(t, NewServer(nil, hub, false), Access{Keys: keys, RequireRead: true}.ServerOptions()...)
+	alerts, err := protected.SubscribeViralAlerts(ctx, &viralv1.SubscribeViralAlertsRequest{})
+	if err == nil {
+		_, err = alerts.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for a protected subscription, got %v", err)
+	}
+}
+
+func TestDrainEndsSubscriptions(t *testing.T) {
+	hub := services.NewWebSocketHub()
+	go hub.Run()
+	server := NewServer(nil, hub, false)
+	client := startServer(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	trending, err := client.SubscribeTrending(ctx, &viralv1.SubscribeTrendingRequest{})
+	if err != nil {
+		t.Fatalf("Failed to subscribe to trending updates: %v", err)
+	}
+	waitForClients(t, hub, 1)
+
+	server.Drain()
+	server.Drain()
+	if _, err := trending.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable once draining, got %v", err)
+	}
+}
+
+func TestIngestEventValidatesLikeHTTP(t *testing.T) {
+	server := NewServer(nil, services.NewWebSocketHub(), true)
+
+	view := &viralv1.IngestEventRequest{Event: &viralv1.IngestEventRequest_View{View: &viralv1.ViewEvent{PostId: "post-1", Platform: "tv"}}}
+	err := server.ingestEvent(view)
+	if err == nil || !strings.Contains(err.Error(), "user_id is required") || !strings.Contains(err.Error(), "platform must be one of") {
+		t.Errorf("Expected the rejected fields to be reported, got %v", err)
+	}
+}
+
+func TestContentFromProto(t *testing.T) {
+	createdAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	content := contentFromProto(&viralv1.ContentMetadata{
+		PostId:      "post-1",
+		ContentType: "video",
+		CreatedAt:   timestamppb.New(createdAt),
+		Keywords:    []string{"sunset"},
+		Media:       &viralv1.MediaMetadata{Width: 1080, Height: 1920, Duration: "1:35"},
+	})
+
+	if content.PostID != "post-1" || !content.CreatedAt.Equal(createdAt) || len(content.Keywords) != 1 {
+		t.Errorf("Unexpected content %+v", content)
+	}
+	if content.Media == nil || content.Media.Width != 1080 || content.Media.Duration != "1:35" {
+		t.Errorf("Unexpected media %+v", content.Media)
+	}
+	if content.Visual != nil {
+		t.Error("Expected no visual analysis when none was sent")
+	}
+	if view := viewFromProto(&viralv1.ViewEvent{PostId: "post-1"}); !view.ViewedAt.IsZero() {
+		t.Error("Expected an unset timestamp to stay zero so it defaults to now")
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Context key of the user a request was identified as
+const userContextKey = "request_user"
+
+// AccessLogOptions configures the access log
+type AccessLogOptions struct {
+	// Route prefixes whose successful requests are logged at SampleRate only, for high-volume
+	// routes such as ingestion
+	SampledPrefixes []string
+	SampleRate      float64
+
+	// Requests slower than this are logged as warnings; 0 disables the warning
+	SlowThreshold time.Duration
+}
+
+// AccessLog logs every request as a structured access log entry once it is served. Failed
+// and slow requests are always logged, successful requests to sampled routes at the sample
+// rate only.
+func AccessLog(opts AccessLogOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		latency := time.Since(start)
+
+		status := c.Writer.Status()
+		slow := opts.SlowThreshold > 0 && latency >= opts.SlowThreshold
+		route := c.FullPath()
+		if status < 400 && !slow && sampled(route, opts.SampledPrefixes) && rand.Float64() >= opts.SampleRate {
+			return
+		}
+
+		bytesIn := c.Request.ContentLength
+		if bytesIn < 0 {
+			bytesIn = 0
+		}
+		logger.Access(logger.AccessEntry{
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Route:     route,
+			Status:    status,
+			Latency:   latency,
+			ClientIP:  c.ClientIP(),
+			User:      loggedUser(c),
+			RequestID: requestID(c),
+			BytesIn:   bytesIn,
+			BytesOut:  max(c.Writer.Size(), 0),
+			Slow:      slow,
+			Errors:    c.Errors.ByType(gin.ErrorTypePrivate).String(),
+		})
+	}
+}
+
+func sampled(route string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(route, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loggedUser returns who a request was made as: the user it was rate limited as, else the API
+// key that authenticated it, else ""
+func loggedUser(c *gin.Context) string {
+	if user := c.GetString(userContextKey); user != "" {
+		return user
+	}
+	if value, ok := c.Get(apiKeyContextKey); ok {
+		if key, ok := value.(*services.APIKey); ok {
+			return "key:" + key.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// accessLogged serves each path once through AccessLog and returns the paths that were logged
+func accessLogged(t *testing.T, opts AccessLogOptions, paths ...string) map[string]bool {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := logger.Logger
+	logger.Logger = zerolog.New(&buf)
+	defer func() { logger.Logger = previous }()
+
+	router := gin.New()
+	router.Use(AccessLog(opts))
+	router.GET("/api/v1/events/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/v1/events/invalid", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+	router.GET("/api/v1/events/failed", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	router.GET("/api/v1/events/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	router.GET("/api/v1/analytics/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range paths {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	logged := make(map[string]bool)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry struct {
+			Type string `json:"type"`
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Failed to decode log line %s: %v", scanner.Text(), err)
+		}
+		if entry.Type == "access" {
+			logged[entry.Path] = true
+		}
+	}
+	return logged
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	paths := []string{
+		"/api/v1/events/ok",
+		"/api/v1/events/invalid",
+		"/api/v1/events/failed",
+		"/api/v1/events/slow",
+		"/api/v1/analytics/ok",
+	}
+
+	// With a sample rate of 0 only the successful, fast requests to sampled routes are dropped
+	logged := accessLogged(t, AccessLogOptions{
+		SampledPrefixes: []string{"/api/v1/events"},
+		SampleRate:      0,
+		SlowThreshold:   10 * time.Millisecond,
+	}, paths...)
+	expected := map[string]bool{
+		"/api/v1/events/ok":      false,
+		"/api/v1/events/invalid": true,
+		"/api/v1/events/failed":  true,
+		"/api/v1/events/slow":    true,
+		"/api/v1/analytics/ok":   true,
+	}
+	for path, want := range expected {
+		if logged[path] != want {
+			t.Errorf("%s: expected logged %v, got %v", path, want, logged[path])
+		}
+	}
+
+	// A sample rate of 1 logs every request
+	logged = accessLogged(t, AccessLogOptions{SampledPrefixes: []string{"/api/v1/events"}, SampleRate: 1}, paths...)
+	for _, path := range paths {
+		if !logged[path] {
+			t.Errorf("%s: expected to be logged at sample rate 1", path)
+		}
+	}
+}
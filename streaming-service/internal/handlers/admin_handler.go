@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"confluent-viral-intelligence/internal/models"
+	"confluent-viral-intelligence/internal/services"
+)
+
+type AdminHandler struct {
+	firestoreClient *services.FirestoreClient
+	processor       *services.EventProcessor
+}
+
+func NewAdminHandler(firestoreClient *services.FirestoreClient, processor *services.EventProcessor) *AdminHandler {
+	return &AdminHandler{firestoreClient: firestoreClient, processor: processor}
+}
+
+// EnablePostTraceRequest is the body of a trace mode request
+type EnablePostTraceRequest struct {
+	DurationMinutes int `json:"duration_minutes"`
+}
+
+// EnablePostTrace turns on audit trail recording for a post
+func (h *AdminHandler) EnablePostTrace(c *gin.Context) {
+	postID := c.Param("id")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	// Body is optional, default to one hour of tracing
+	req := EnablePostTraceRequest{DurationMinutes: 60}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, badRequest(CodeInvalidRequest, err.Error()))
+			return
+		}
+	}
+
+	maxMinutes := int(services.MaxPostTraceDuration / time.Minute)
+	if req.DurationMinutes <= 0 || req.DurationMinutes > maxMinutes {
+		RespondError(c, badRequest("INVALID_DURATION_MINUTES", "Invalid duration_minutes. Must be between 1 and "+strconv.Itoa(maxMinutes)))
+		return
+	}
+
+	trace, err := h.firestoreClient.EnablePostTrace(postID, time.Duration(req.DurationMinutes)*time.Minute)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to enable post trace"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   trace,
+	})
+}
+
+// DisablePostTrace turns off audit trail recording for a post
+func (h *AdminHandler) DisablePostTrace(c *gin.Context) {
+	postID := c.Param("id")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	if err := h.firestoreClient.DisablePostTrace(postID); err != nil {
+		RespondError(c, failed(err, "Failed to disable post trace"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// GetPostTrace returns the recorded audit trail of a post, newest first
+func (h *AdminHandler) GetPostTrace(c *gin.Context) {
+	postID := c.Param("id")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	// Parse limit parameter with default value of 200
+	limitStr := c.DefaultQuery("limit", "200")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 1000 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 1000"))
+		return
+	}
+
+	trace, entries, err := h.firestoreClient.GetPostTrace(postID, limit)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch post trace"))
+		return
+	}
+
+	if trace == nil {
+		RespondError(c, notFound(CodeNotFound, "Trace mode was never enabled for this post"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"trace":  trace,
+		"active": time.Now().Before(trace.ExpiresAt),
+		"count":  len(entries),
+		"data":   entries,
+	})
+}
+
+// RecomputePost recalculates a post's trending score from its post document, re-runs the
+// virality prediction and returns the score before and after
+func (h *AdminHandler) RecomputePost(c *gin.Context) {
+	postID := c.Param("id")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	recomputation, err := h.processor.RecomputePost(postID)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to recompute post"))
+		return
+	}
+	if recomputation == nil {
+		RespondError(c, notFound(CodePostNotFound, "Post not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   recomputation,
+	})
+}
+
+// TrendingOverrideRequest is the body of a trending override request
+type TrendingOverrideRequest struct {
+	Pinned     bool     `json:"pinned"`
+	Excluded   bool     `json:"excluded"`
+	Multiplier *float64 `json:"multiplier"` // defaults to 1
+	Reason     string   `json:"reason"`
+}
+
+// SetTrendingOverride pins a post to the trending feeds, excludes it from them or scales its
+// score, replacing the post's previous override, and returns the rescored score
+func (h *AdminHandler) SetTrendingOverride(c *gin.Context) {
+	postID := c.Param("id")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	var req TrendingOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, badRequest(CodeInvalidRequest, err.Error()))
+		return
+	}
+
+	override := models.TrendingOverride{
+		PostID:     postID,
+		Pinned:     req.Pinned,
+		Excluded:   req.Excluded,
+		Multiplier: 1,
+		Reason:     req.Reason,
+		UpdatedBy:  loggedUser(c),
+	}
+	if req.Multiplier != nil {
+		override.Multiplier = *req.Multiplier
+	}
+	if err := services.ValidateTrendingOverride(override); err != nil {
+		RespondError(c, badRequest(CodeInvalidRequest, err.Error()))
+		return
+	}
+
+	score, err := h.firestoreClient.SetTrendingOverride(override)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to set trending override"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   override,
+		"score":  score,
+	})
+}
+
+// DeleteTrendingOverride returns a post to its calculated trending score
+func (h *AdminHandler) DeleteTrendingOverride(c *gin.Context) {
+	postID := c.Param("id")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	found, err := h.firestoreClient.DeleteTrendingOverride(postID)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to delete trending override"))
+		return
+	}
+	if !found {
+		RespondError(c, notFound(CodeNotFound, "Post has no trending override"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// ListTrendingOverrides returns the trending overrides in effect, most recently updated first
+func (h *AdminHandler) ListTrendingOverrides(c *gin.Context) {
+	overrides := h.firestoreClient.Scoring().TrendingOverrides()
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(overrides),
+		"data":   overrides,
+	})
+}
+
+// CompareScoring compares the rankings of the top posts under the live and shadow scoring
+// formulas
+func (h *AdminHandler) CompareScoring(c *gin.Context) {
+	sample, err := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	if err != nil || sample <= 0 || sample > services.MaxScoringCompareSample {
+		RespondError(c, invalidParam("limit", fmt.Sprintf("Must be between 1 and %d", services.MaxScoringCompareSample)))
+		return
+	}
+	top, err := strconv.Atoi(c.DefaultQuery("top", "20"))
+	if err != nil || top <= 0 || top > services.MaxScoringCompareTop || top > sample {
+		RespondError(c, invalidParam("top", fmt.Sprintf("Must be between 1 and %d, and at most limit", services.MaxScoringCompareTop)))
+		return
+	}
+
+	comparison, err := h.firestoreClient.CompareScoring(sample, top)
+	if errors.Is(err, services.ErrNoShadowFormula) {
+		RespondError(c, NewAPIError(http.StatusConflict, CodeConflict, "No shadow scoring formula is configured in scoring_config/shadow"))
+		return
+	}
+	if err != nil {
+		RespondError(c, failed(err, "Failed to compare scoring formulas"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   comparison,
+	})
+}
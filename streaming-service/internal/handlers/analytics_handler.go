@@ -1,91 +1,281 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
 	"confluent-viral-intelligence/internal/services"
 )
 
 type AnalyticsHandler struct {
 	firestoreClient     *services.FirestoreClient
 	dashboardAnalytics  *services.DashboardAnalytics
+	embeddings          *services.EmbeddingService
+	coach               *services.CreatorCoach
+	reportingLoc        *time.Location
+	config              *config.Config
+	responses           *services.ResponseCache // nil when response caching is disabled
 }
 
-func NewAnalyticsHandler(firestoreClient *services.FirestoreClient) *AnalyticsHandler {
+func NewAnalyticsHandler(firestoreClient *services.FirestoreClient, embeddings *services.EmbeddingService, vertexAI *services.VertexAIClient, cfg *config.Config) *AnalyticsHandler {
+	// The reporting zone was validated at startup
+	reportingLoc, _ := cfg.ReportingLocation()
+
+	var responses *services.ResponseCache
+	if cfg.ResponseCacheTTLSeconds > 0 {
+		responses = services.NewResponseCache(time.Duration(cfg.ResponseCacheTTLSeconds)*time.Second, cfg.ResponseCacheMaxEntries)
+	}
+
 	return &AnalyticsHandler{
 		firestoreClient:    firestoreClient,
 		dashboardAnalytics: services.NewDashboardAnalytics(firestoreClient),
+		embeddings:         embeddings,
+		coach:              services.NewCreatorCoach(firestoreClient, vertexAI, reportingLoc),
+		reportingLoc:       reportingLoc,
+		config:             cfg,
+		responses:          responses,
+	}
+}
+
+// RegisterCaches hands the response cache to the cache maintainer
+func (h *AnalyticsHandler) RegisterCaches(maintainer *services.CacheMaintainer) {
+	if h.responses != nil {
+		maintainer.Register("analytics_responses", h.responses.EvictExpired, h.responses.Len)
 	}
 }
 
+// CacheResponses serves successful responses of the routes it guards from the response cache,
+// keyed by path and query, with an ETag and Last-Modified so polling dashboards revalidate
+// with a 304 instead of downloading the same data again
+func (h *AnalyticsHandler) CacheResponses() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.responses == nil {
+			c.Next()
+			return
+		}
+
+		// Encode sorts the parameters, so their order does not split the cache
+		key := c.Request.URL.Path + "?" + c.Request.URL.Query().Encode()
+		if cached, ok := h.responses.Get(key); ok {
+			h.serveCached(c, cached)
+			c.Abort()
+			return
+		}
+
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.status != http.StatusOK {
+			c.Writer.WriteHeader(writer.status)
+			c.Writer.Write(writer.body.Bytes())
+			return
+		}
+		header := http.Header{}
+		for _, name := range cachedResponseHeaders {
+			if value := c.Writer.Header().Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+		h.serveCached(c, h.responses.Set(key, writer.body.Bytes(), header))
+	}
+}
+
+// Headers of a response kept with it in the response cache; the others, like CORS headers,
+// depend on the request
+var cachedResponseHeaders = []string{"Content-Type", "Content-Disposition"}
+
+// serveCached writes a cached response, or a bare 304 when the client's copy is current
+func (h *AnalyticsHandler) serveCached(c *gin.Context, cached *services.CachedResponse) {
+	header := c.Writer.Header()
+	for name, values := range cached.Header {
+		header[name] = values
+	}
+	header.Set("ETag", cached.ETag)
+	header.Set("Last-Modified", cached.LastModified.Format(http.TimeFormat))
+	header.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(h.responses.TTL().Seconds())))
+
+	if notModified(c.Request, cached) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Writer.Write(cached.Body)
+}
+
+// notModified reports whether the request's validators match the cached response. As in
+// RFC 9110, If-Modified-Since only counts without If-None-Match.
+func notModified(r *http.Request, cached *services.CachedResponse) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == cached.ETag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !cached.LastModified.After(since)
+}
+
+// bufferedResponseWriter holds back a handler's response so it can be cached before it is sent
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) { w.status = code }
+
+func (w *bufferedResponseWriter) WriteHeaderNow() {}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) { return w.body.Write(data) }
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+
+func (w *bufferedResponseWriter) Status() int { return w.status }
+
+func (w *bufferedResponseWriter) Size() int { return w.body.Len() }
+
+func (w *bufferedResponseWriter) Written() bool { return w.body.Len() > 0 }
+
 // GetTrending returns the top trending posts (with content only)
 func (h *AnalyticsHandler) GetTrending(c *gin.Context) {
 	// Parse limit parameter with default value of 20
 	limitStr := c.DefaultQuery("limit", "20")
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 || limit > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter. Must be between 1 and 100"})
+		RespondError(c, invalidParam("limit", "Must be between 1 and 100"))
 		return
 	}
 
-	// Check if content type filter is provided
-	contentType := c.Query("contentType")
-	
-	var trendingPosts []interface{}
-	var count int
+	// Optional field selection, e.g. ?fields=id,score,output_urls
+	fields, err := services.ParseTrendingFields(c.Query("fields"))
+	if err != nil {
+		RespondError(c, badRequest("INVALID_FIELDS", err.Error()))
+		return
+	}
+
+	// Optional spreadsheet export, e.g. ?format=csv
+	format, ok := h.exportFormat(c)
+	if !ok {
+		return
+	}
+
+	// Optional content filters, e.g. ?contentType=image&category=art&keyword=sunset
+	filter := contentFilter(c)
+
+	// Optional creator tier filter, e.g. ?creatorTier=emerging
+	creatorTier := c.Query("creatorTier")
+	if creatorTier != "" && !services.IsCreatorTier(creatorTier) {
+		RespondError(c, invalidParam("creatorTier", "Must be new, emerging, established or star"))
+		return
+	}
 	
-	if contentType != "" {
-		// Filter by content type
-		posts, err := h.dashboardAnalytics.GetTrendingPostsByContentType(contentType, limit)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending posts"})
-			return
-		}
-		count = len(posts)
-		trendingPosts = make([]interface{}, len(posts))
-		for i, post := range posts {
-			trendingPosts[i] = post
-		}
+	// Optional region, e.g. ?region=EU; a category or region reads its own ranking while the
+	// scoped rankings are kept
+	scoped := h.config.TrendingScopeFlushSeconds > 0
+	region := strings.ToUpper(strings.TrimSpace(c.Query("region")))
+	if region != "" && (!scoped || !services.IsTrendingRegion(region, h.config.TrendingRegionGroups)) {
+		RespondError(c, invalidParam("region", "Must be a country code such as DE or a region group such as EU"))
+		return
+	}
+	scope := services.NewTrendingScope(filter.Category, region)
+
+	var posts []models.TrendingScore
+	if scoped && !scope.IsEmpty() {
+		// The scope's ranking holds the category
+		scopeFilter := filter
+		scopeFilter.Category = ""
+		posts, err = h.dashboardAnalytics.GetScopedTrendingPosts(scope, scopeFilter, limit, fields, creatorTier)
+	} else if !filter.IsEmpty() {
+		// Filter by content type, category and keyword
+		posts, err = h.dashboardAnalytics.GetFilteredTrendingPosts(filter, limit, fields, creatorTier)
 	} else {
 		// Use dashboard analytics to get posts with content (same filtering logic as top 3)
-		posts, err := h.dashboardAnalytics.GetTrendingPostsWithContent(limit)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending posts"})
-			return
+		posts, err = h.dashboardAnalytics.GetTrendingPostsWithContent(limit, fields, creatorTier)
+	}
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch trending posts"))
+		return
+	}
+
+	// Pinned posts lead the feed, excluded posts leave it
+	posts, err = h.dashboardAnalytics.ApplyTrendingOverrides(posts, filter, limit, fields, creatorTier)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch trending posts"))
+		return
+	}
+	trendingCount := len(posts)
+
+	// Too few trending posts (new deployment, quiet hours): fill up with recent high-quality posts
+	// so the feed never renders empty
+	if trendingCount < min(h.config.TrendingMinResults, limit) {
+		exclude := make(map[string]bool, len(posts))
+		for _, post := range posts {
+			exclude[post.PostID] = true
+		}
+		for _, override := range h.firestoreClient.Scoring().TrendingOverrides() {
+			if override.Excluded {
+				exclude[override.PostID] = true
+			}
 		}
-		count = len(posts)
-		trendingPosts = make([]interface{}, len(posts))
-		for i, post := range posts {
-			trendingPosts[i] = post
+		since := time.Now().AddDate(0, 0, -h.config.TrendingFallbackDays)
+		fallback, err := h.dashboardAnalytics.GetFallbackPosts(filter, creatorTier, since, exclude, limit-trendingCount, fields)
+		if err != nil {
+			logger.Warnf("Failed to fetch fallback posts for trending feed: %v", err)
+		} else {
+			posts = append(posts, fallback...)
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"count":  count,
-		"data":   trendingPosts,
-	})
+	if format != "" {
+		h.writeExport(c, format, "trending", posts, fields)
+		return
+	}
+	response := gin.H{
+		"status":         "success",
+		"count":          len(posts),
+		"trending_count": trendingCount,
+		"fallback":       len(posts) > trendingCount,
+		"data":           services.ProjectTrendingScores(posts, fields),
+	}
+	if scoped && !scope.IsEmpty() {
+		response["scope"] = scope
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetPostStats returns statistics for a specific post
 func (h *AnalyticsHandler) GetPostStats(c *gin.Context) {
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		RespondError(c, missingID("Post"))
 		return
 	}
 
 	// Get post stats from Firestore
 	stats, err := h.firestoreClient.GetPostStats(postID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post stats"})
+		RespondError(c, failed(err, "Failed to fetch post stats"))
 		return
 	}
 
 	if stats == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		RespondError(c, notFound(CodePostNotFound, "Post not found"))
 		return
 	}
 
@@ -95,11 +285,199 @@ func (h *AnalyticsHandler) GetPostStats(c *gin.Context) {
 	})
 }
 
+// requireKnownPost answers 404 for a post without a trending score, so that the empty history
+// of an unknown post is not mistaken for a post without activity. It returns false once the
+// request is answered.
+func (h *AnalyticsHandler) requireKnownPost(c *gin.Context, postID string) bool {
+	stats, err := h.firestoreClient.GetPostStats(postID)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch post stats"))
+		return false
+	}
+	if stats == nil {
+		RespondError(c, notFound(CodePostNotFound, "Post not found"))
+		return false
+	}
+	return true
+}
+
+// GetPostTimeseries returns a post's engagement per hour or day, oldest first
+func (h *AnalyticsHandler) GetPostTimeseries(c *gin.Context) {
+	postID := c.Param("id")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	// ?interval=hour covers up to a week, ?interval=day up to 90 days in the reporting time zone
+	interval := c.DefaultQuery("interval", services.RollupDay)
+	maxDays := 90
+	switch interval {
+	case services.RollupDay:
+	case services.RollupHour:
+		maxDays = 7
+	default:
+		RespondError(c, invalidParam("interval", "Must be hour or day"))
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > maxDays {
+		RespondError(c, invalidParam("days", "Must be between 1 and "+strconv.Itoa(maxDays)))
+		return
+	}
+	periods := days
+	if interval == services.RollupHour {
+		periods = days * 24
+	}
+
+	rollups, err := h.firestoreClient.GetPostTimeseries(postID, interval, periods, time.Now())
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch post timeseries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"count":    len(rollups),
+		"interval": interval,
+		"timezone": h.reportingLoc.String(),
+		"data":     rollups,
+	})
+}
+
+// GetPostHistory returns a post's trending score over time, downsampled for sparklines
+func (h *AnalyticsHandler) GetPostHistory(c *gin.Context) {
+	postID := c.Param("id")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	// Parse days parameter with default value of 7, bounded by how long snapshots are kept
+	maxDays := h.config.ScoreHistoryRetentionDays
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > maxDays {
+		RespondError(c, invalidParam("days", "Must be between 1 and "+strconv.Itoa(maxDays)))
+		return
+	}
+
+	// Parse points parameter with default value of 48
+	points, err := strconv.Atoi(c.DefaultQuery("points", "48"))
+	if err != nil || points < 2 || points > 500 {
+		RespondError(c, invalidParam("points", "Must be between 2 and 500"))
+		return
+	}
+
+	history, err := h.firestoreClient.GetScoreHistory(postID, time.Now().AddDate(0, 0, -days), points)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch score history"))
+		return
+	}
+	if len(history) == 0 && !h.requireKnownPost(c, postID) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(history),
+		"data":   history,
+	})
+}
+
+// ComparePosts returns posts side by side, aligned by age, e.g. variations of the same prompt
+func (h *AnalyticsHandler) ComparePosts(c *gin.Context) {
+	// ?posts=a,b,c lists the posts in the order they are compared in
+	var postIDs []string
+	seen := make(map[string]bool)
+	for _, postID := range strings.Split(c.Query("posts"), ",") {
+		postID = strings.TrimSpace(postID)
+		if postID != "" && !seen[postID] {
+			seen[postID] = true
+			postIDs = append(postIDs, postID)
+		}
+	}
+	if len(postIDs) < 2 || len(postIDs) > services.MaxComparedPosts {
+		RespondError(c, invalidParam("posts", fmt.Sprintf("Must list between 2 and %d post IDs", services.MaxComparedPosts)))
+		return
+	}
+
+	// Parse hours of age the trajectories cover with default value of 48
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "48"))
+	if err != nil || hours <= 0 || hours > services.MaxComparisonHours {
+		RespondError(c, invalidParam("hours", fmt.Sprintf("Must be between 1 and %d", services.MaxComparisonHours)))
+		return
+	}
+
+	comparison, err := h.dashboardAnalytics.ComparePosts(postIDs, hours)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to compare posts"))
+		return
+	}
+	if comparison == nil {
+		RespondError(c, notFound(CodePostNotFound, "None of the posts has analytics yet"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(comparison.Posts),
+		"data":   comparison,
+	})
+}
+
+// GetSimilarPosts returns the posts most semantically similar to a post ("more like this"),
+// or 202 while the post has no embedding yet
+func (h *AnalyticsHandler) GetSimilarPosts(c *gin.Context) {
+	postID := c.Param("postId")
+	if postID == "" {
+		RespondError(c, missingID("Post"))
+		return
+	}
+
+	// Parse limit parameter with default value of 10
+	limitStr := c.DefaultQuery("limit", "10")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 50 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 50"))
+		return
+	}
+
+	if h.embeddings == nil {
+		RespondError(c, unavailable("Similar posts require the vertex AI provider"))
+		return
+	}
+
+	similar, err := h.embeddings.FindSimilar(postID, limit)
+	if errors.Is(err, services.ErrEmbeddingPending) {
+		// The post indexer embeds posts that missed it on ingestion
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":  "pending",
+			"message": "The post's embedding has not been computed yet, retry later",
+		})
+		return
+	}
+	if err != nil {
+		RespondError(c, failed(err, "Failed to find similar posts"))
+		return
+	}
+
+	if similar == nil {
+		RespondError(c, notFound(CodePostNotFound, "Post not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(similar),
+		"data":   similar,
+	})
+}
+
 // GetRecommendations returns personalized recommendations for a user
 func (h *AnalyticsHandler) GetRecommendations(c *gin.Context) {
 	userID := c.Param("id")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		RespondError(c, missingID("User"))
 		return
 	}
 
@@ -107,14 +485,14 @@ func (h *AnalyticsHandler) GetRecommendations(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "10")
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 || limit > 50 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter. Must be between 1 and 50"})
+		RespondError(c, invalidParam("limit", "Must be between 1 and 50"))
 		return
 	}
 
 	// Get user recommendations from Firestore
 	recommendations, err := h.firestoreClient.GetUserRecommendations(userID, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommendations"})
+		RespondError(c, failed(err, "Failed to fetch recommendations"))
 		return
 	}
 
@@ -125,33 +503,142 @@ func (h *AnalyticsHandler) GetRecommendations(c *gin.Context) {
 	})
 }
 
-// GetDashboardMetrics returns comprehensive dashboard metrics
+// dashboardWindow parses the optional time range of a dashboard request, ?window=7d or
+// ?from=2024-05-01&to=2024-05-08, answering 400 when it is invalid. It returns nil for
+// all-time metrics.
+func (h *AnalyticsHandler) dashboardWindow(c *gin.Context) (*services.DashboardWindow, bool) {
+	window, err := services.ParseDashboardWindow(c.Query("window"), c.Query("from"), c.Query("to"), time.Now(), h.reportingLoc)
+	if err != nil {
+		RespondError(c, badRequest(CodeInvalidTimeRange, "Invalid time range. "+err.Error()))
+		return nil, false
+	}
+	return window, true
+}
+
+// contentFilter reads the optional ?contentType=, ?category= and ?keyword= filters of an
+// analytics request
+func contentFilter(c *gin.Context) services.ContentFilter {
+	return services.NewContentFilter(c.Query("contentType"), c.Query("category"), c.Query("keyword"))
+}
+
+// exportFormat parses the optional ?format=csv|xlsx of an analytics request, answering 400
+// when it is invalid. It returns "" for JSON.
+func (h *AnalyticsHandler) exportFormat(c *gin.Context) (string, bool) {
+	format, err := services.ParseExportFormat(c.Query("format"))
+	if err != nil {
+		RespondError(c, invalidParam("format", "Must be json, csv or xlsx"))
+		return "", false
+	}
+	return format, true
+}
+
+// writeExport streams rows as a CSV or XLSX attachment named after the report, restricted to
+// fields when it is non-nil
+func (h *AnalyticsHandler) writeExport(c *gin.Context, format, report string, rows interface{}, fields services.FieldSet) {
+	filename := fmt.Sprintf("%s-%s.%s", report, time.Now().In(h.reportingLoc).Format("20060102-1504"), format)
+	c.Header("Content-Type", services.ExportContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	if err := services.WriteExport(c.Writer, format, report, rows, fields); err != nil {
+		// Rows may already be on their way, so the status cannot change anymore
+		logger.Errorf("❌ Failed to export %s: %v", report, err)
+	}
+}
+
+// GetDashboardMetrics returns comprehensive dashboard metrics, all-time or over a time range
 func (h *AnalyticsHandler) GetDashboardMetrics(c *gin.Context) {
-	metrics, err := h.dashboardAnalytics.GetDashboardMetrics()
+	window, ok := h.dashboardWindow(c)
+	if !ok {
+		return
+	}
+
+	metrics, err := h.dashboardAnalytics.GetDashboardMetrics(window)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dashboard metrics"})
+		RespondError(c, failed(err, "Failed to fetch dashboard metrics"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"status": "success",
 		"data":   metrics,
-	})
+	}
+	if window != nil {
+		response["window"] = window
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// GetTopCreators returns the top creators
+// GetTopCreators returns the top creators, optionally limited to one tier or a time range
 func (h *AnalyticsHandler) GetTopCreators(c *gin.Context) {
 	// Parse limit parameter with default value of 10
 	limitStr := c.DefaultQuery("limit", "10")
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 || limit > 50 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter. Must be between 1 and 50"})
+		RespondError(c, invalidParam("limit", "Must be between 1 and 50"))
+		return
+	}
+
+	// Optional tier filter, e.g. ?tier=emerging for a rising creators leaderboard
+	tier := c.Query("tier")
+	if tier != "" && !services.IsCreatorTier(tier) {
+		RespondError(c, invalidParam("tier", "Must be new, emerging, established or star"))
+		return
+	}
+
+	window, ok := h.dashboardWindow(c)
+	if !ok {
+		return
+	}
+	format, ok := h.exportFormat(c)
+	if !ok {
+		return
+	}
+
+	// Optional content filters rank creators by their matching posts only
+	filter := contentFilter(c)
+	if window != nil && !filter.IsEmpty() {
+		RespondError(c, badRequest(CodeInvalidRequest, "Content filters cannot be combined with a time range"))
+		return
+	}
+
+	creators, err := h.dashboardAnalytics.GetTopCreators(limit, tier, filter, window)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch top creators"))
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, "top-creators", creators, nil)
+		return
+	}
+
+	response := gin.H{
+		"status": "success",
+		"count":  len(creators),
+		"data":   creators,
+	}
+	if window != nil {
+		response["window"] = window
+	}
+	if !filter.IsEmpty() {
+		response["filter"] = filter
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRisingCreators returns the creators whose engagement grew most week over week
+func (h *AnalyticsHandler) GetRisingCreators(c *gin.Context) {
+	// Parse limit parameter with default value of 10
+	limitStr := c.DefaultQuery("limit", "10")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 50 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 50"))
 		return
 	}
 
-	creators, err := h.dashboardAnalytics.GetTopCreators(limit)
+	creators, err := h.dashboardAnalytics.GetRisingCreators(int64(h.config.RisingCreatorsMinEngagement), limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch top creators"})
+		RespondError(c, failed(err, "Failed to fetch rising creators"))
 		return
 	}
 
@@ -162,39 +649,509 @@ func (h *AnalyticsHandler) GetTopCreators(c *gin.Context) {
 	})
 }
 
-// GetContentTypeBreakdown returns content type breakdown
-func (h *AnalyticsHandler) GetContentTypeBreakdown(c *gin.Context) {
-	breakdown, err := h.dashboardAnalytics.GetContentTypeBreakdown()
+// GetRisingPosts returns the posts whose trending score grew most over the last hours, so
+// breaking content shows up before it tops the absolute ranking
+func (h *AnalyticsHandler) GetRisingPosts(c *gin.Context) {
+	// Parse hours parameter with default value of 6
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "6"))
+	if err != nil || hours <= 0 || hours > services.MaxRisingHours {
+		RespondError(c, invalidParam("hours", fmt.Sprintf("Must be between 1 and %d", services.MaxRisingHours)))
+		return
+	}
+
+	// Parse limit parameter with default value of 20
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 100"))
+		return
+	}
+
+	// Rank by score gained (default) or by score gained per hour, e.g. ?sort=velocity
+	by := c.DefaultQuery("sort", services.RisingByDelta)
+	if by != services.RisingByDelta && by != services.RisingByVelocity {
+		RespondError(c, invalidParam("sort", "Must be delta or velocity"))
+		return
+	}
+
+	posts, err := h.dashboardAnalytics.GetRisingPosts(hours, limit, by)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch content type breakdown"})
+		RespondError(c, failed(err, "Failed to fetch rising posts"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
-		"data":   breakdown,
+		"count":  len(posts),
+		"data":   posts,
+	})
+}
+
+// GetRemixSuggestions returns posts worth remixing for a user, with the reasons for each
+func (h *AnalyticsHandler) GetRemixSuggestions(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		RespondError(c, missingID("User"))
+		return
+	}
+
+	// Parse limit parameter with default value of 10
+	limitStr := c.DefaultQuery("limit", "10")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 50 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 50"))
+		return
+	}
+
+	suggestions, err := h.dashboardAnalytics.GetRemixSuggestions(userID, limit)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch remix suggestions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(suggestions),
+		"data":   suggestions,
+	})
+}
+
+// GetCreatorAnalytics returns a creator's own dashboard: totals, content types, most viewed
+// posts, score history and viral posts
+func (h *AnalyticsHandler) GetCreatorAnalytics(c *gin.Context) {
+	creatorID := c.Param("id")
+	if creatorID == "" {
+		RespondError(c, missingID("Creator"))
+		return
+	}
+
+	// Parse posts parameter with default value of 10
+	postLimit, err := strconv.Atoi(c.DefaultQuery("posts", "10"))
+	if err != nil || postLimit <= 0 || postLimit > 100 {
+		RespondError(c, invalidParam("posts", "Must be between 1 and 100"))
+		return
+	}
+
+	// Parse days parameter of the score history with default value of 30
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 || days > 90 {
+		RespondError(c, invalidParam("days", "Must be between 1 and 90"))
+		return
+	}
+
+	analytics, err := h.dashboardAnalytics.GetCreatorAnalytics(creatorID, postLimit, days)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch creator analytics"))
+		return
+	}
+	if analytics == nil {
+		RespondError(c, notFound(CodeCreatorNotFound, "No analytics for this creator yet"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   analytics,
+	})
+}
+
+// GetAudienceOverlap returns the creators sharing the largest estimated audience with a creator
+func (h *AnalyticsHandler) GetAudienceOverlap(c *gin.Context) {
+	creatorID := c.Param("id")
+	if creatorID == "" {
+		RespondError(c, missingID("Creator"))
+		return
+	}
+
+	// Parse limit parameter with default value of 10
+	limitStr := c.DefaultQuery("limit", "10")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 50 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 50"))
+		return
+	}
+
+	overlap, err := h.dashboardAnalytics.GetAudienceOverlap(creatorID, limit)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to calculate audience overlap"))
+		return
+	}
+	if overlap == nil {
+		RespondError(c, notFound(CodeCreatorNotFound, "No audience tracked for creator"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   overlap,
+	})
+}
+
+// GetCreatorSuggestions returns coaching suggestions for a creator based on how their posts
+// performed per content type, generated once per reporting day
+func (h *AnalyticsHandler) GetCreatorSuggestions(c *gin.Context) {
+	creatorID := c.Param("id")
+	if creatorID == "" {
+		RespondError(c, missingID("Creator"))
+		return
+	}
+
+	coaching, err := h.coach.Suggestions(creatorID)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to generate creator suggestions"))
+		return
+	}
+	if coaching == nil {
+		RespondError(c, notFound(CodeCreatorNotFound, "No posts found for creator"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(coaching.Suggestions),
+		"data":   coaching,
 	})
 }
 
-// GetEngagementTrends returns engagement trends
+// GetPredictionAccuracy returns calibration metrics of the viral predictions resolved recently
+func (h *AnalyticsHandler) GetPredictionAccuracy(c *gin.Context) {
+	// Parse days parameter with default value of 30
+	daysStr := c.DefaultQuery("days", "30")
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days <= 0 || days > 90 {
+		RespondError(c, invalidParam("days", "Must be between 1 and 90"))
+		return
+	}
+
+	// Optional predictor filter: heuristic, gemini or endpoint
+	accuracy, err := h.dashboardAnalytics.GetPredictionAccuracy(days, c.Query("source"), h.config.ViralThreshold())
+	if err != nil {
+		RespondError(c, failed(err, "Failed to calculate prediction accuracy"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   accuracy,
+	})
+}
+
+// GetPredictorComparison returns how the model-based and the heuristic viral predictors compare
+// during their dual run
+func (h *AnalyticsHandler) GetPredictorComparison(c *gin.Context) {
+	// Parse days parameter with default value of 7
+	daysStr := c.DefaultQuery("days", "7")
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days <= 0 || days > 90 {
+		RespondError(c, invalidParam("days", "Must be between 1 and 90"))
+		return
+	}
+
+	// Parse limit parameter with default value of 20
+	limitStr := c.DefaultQuery("limit", "20")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 0 || limit > 100 {
+		RespondError(c, invalidParam("limit", "Must be between 0 and 100"))
+		return
+	}
+
+	report, err := h.dashboardAnalytics.GetPredictorComparison(days, limit, h.config.ViralThreshold())
+	if err != nil {
+		RespondError(c, failed(err, "Failed to compare predictors"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   report,
+	})
+}
+
+// GetTrendingHashtags returns the hashtags rising fastest against the previous window
+func (h *AnalyticsHandler) GetTrendingHashtags(c *gin.Context) {
+	// Window such as 1h, 6h, 24h or 7d, compared with the window before it
+	window, err := services.ParseHashtagWindow(c.DefaultQuery("window", h.config.HashtagTrendWindow))
+	if err != nil {
+		RespondError(c, invalidParam("window", err.Error()))
+		return
+	}
+
+	// Parse limit parameter with default value of 20
+	limitStr := c.DefaultQuery("limit", "20")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 100 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 100"))
+		return
+	}
+
+	hashtags, err := h.dashboardAnalytics.GetTrendingHashtags(window, int64(h.config.HashtagTrendMinPosts), limit)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch trending hashtags"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(hashtags),
+		"window": window.String(),
+		"data":   hashtags,
+	})
+}
+
+// GetKeywordPerformance returns how the posts published in a window performed, grouped by
+// keyword, category or style
+func (h *AnalyticsHandler) GetKeywordPerformance(c *gin.Context) {
+	dimension, err := services.ParseThemeDimension(c.DefaultQuery("by", services.ThemeKeyword))
+	if err != nil {
+		RespondError(c, invalidParam("by", err.Error()))
+		return
+	}
+
+	// Window of publication such as 24h, 7d or 30d
+	window, err := services.ParseKeywordWindow(c.DefaultQuery("window", "30d"))
+	if err != nil {
+		RespondError(c, invalidParam("window", err.Error()))
+		return
+	}
+
+	sortBy, err := services.ParseThemeSort(c.DefaultQuery("sort", services.ThemeSortAvgScore))
+	if err != nil {
+		RespondError(c, invalidParam("sort", err.Error()))
+		return
+	}
+
+	// Themes with fewer posts in the window are left out as noise
+	minPosts, err := strconv.Atoi(c.DefaultQuery("minPosts", "3"))
+	if err != nil || minPosts <= 0 || minPosts > 1000 {
+		RespondError(c, invalidParam("minPosts", "Must be between 1 and 1000"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 100"))
+		return
+	}
+
+	themes, err := h.dashboardAnalytics.GetKeywordPerformance(dimension, window, sortBy, int64(minPosts), limit)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch keyword performance"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(themes),
+		"by":     dimension,
+		"sort":   sortBy,
+		"window": window.String(),
+		"data":   themes,
+	})
+}
+
+// GetContentTypeBreakdown returns content type breakdown, all-time or over a time range
+func (h *AnalyticsHandler) GetContentTypeBreakdown(c *gin.Context) {
+	window, ok := h.dashboardWindow(c)
+	if !ok {
+		return
+	}
+	format, ok := h.exportFormat(c)
+	if !ok {
+		return
+	}
+
+	breakdown, err := h.dashboardAnalytics.GetContentTypeBreakdown(window)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch content type breakdown"))
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, "content-types", services.SortedContentTypes(breakdown), nil)
+		return
+	}
+
+	response := gin.H{
+		"status": "success",
+		"data":   breakdown,
+	}
+	if window != nil {
+		response["window"] = window
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetEngagementTrends returns engagement trends of the last days, or of the days a time range
+// covers, which then takes the place of the days parameter
 func (h *AnalyticsHandler) GetEngagementTrends(c *gin.Context) {
 	// Parse days parameter with default value of 7
 	daysStr := c.DefaultQuery("days", "7")
 	days, err := strconv.Atoi(daysStr)
 	if err != nil || days <= 0 || days > 30 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days parameter. Must be between 1 and 30"})
+		RespondError(c, invalidParam("days", "Must be between 1 and 30"))
 		return
 	}
 
-	trends, err := h.dashboardAnalytics.GetEngagementTrends(days)
+	// Days are bucketed in the reporting time zone unless the request names another, e.g. ?tz=Europe/Istanbul
+	loc := h.reportingLoc
+	if tz := c.Query("tz"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			RespondError(c, invalidParam("tz", "Must be an IANA time zone such as America/New_York"))
+			return
+		}
+	}
+
+	// Dates in from and to fall in the time zone the days are bucketed in
+	window, err := services.ParseDashboardWindow(c.Query("window"), c.Query("from"), c.Query("to"), time.Now(), loc)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch engagement trends"})
+		RespondError(c, badRequest(CodeInvalidTimeRange, "Invalid time range. "+err.Error()))
+		return
+	}
+
+	format, ok := h.exportFormat(c)
+	if !ok {
+		return
+	}
+
+	// Optional content filters, e.g. ?category=art
+	filter := contentFilter(c)
+
+	trends, err := h.dashboardAnalytics.GetEngagementTrends(days, loc, filter, window)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch engagement trends"))
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, "engagement-trends", trends, nil)
+		return
+	}
+
+	response := gin.H{
+		"status":   "success",
+		"count":    len(trends),
+		"timezone": loc.String(),
+		"data":     trends,
+	}
+	if window != nil {
+		response["window"] = window
+	}
+	if !filter.IsEmpty() {
+		response["filter"] = filter
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetActiveUsers returns the daily, weekly and monthly active users and their stickiness
+func (h *AnalyticsHandler) GetActiveUsers(c *gin.Context) {
+	active, err := h.dashboardAnalytics.GetActiveUsers()
+	if err != nil {
+		RespondError(c, failed(err, "Failed to calculate active users"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"timezone": h.reportingLoc.String(),
+		"data":     active,
+	})
+}
+
+// GetViewerRetention returns the daily returning-viewer cohorts of the last days, of every
+// post or of a single post with ?postId=, with their D1 and D7 return rates
+func (h *AnalyticsHandler) GetViewerRetention(c *gin.Context) {
+	// Parse days parameter with default value of 14
+	days, err := strconv.Atoi(c.DefaultQuery("days", "14"))
+	if err != nil || days <= 0 || days > services.MaxRetentionCohorts {
+		RespondError(c, invalidParam("days", "Must be between 1 and 30"))
+		return
+	}
+
+	retention, err := h.dashboardAnalytics.GetViewerRetention(c.Query("postId"), days)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to calculate viewer retention"))
+		return
+	}
+
+	// Cohorts are days in the reporting time zone the viewers were bucketed in
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"count":    len(retention.Cohorts),
+		"timezone": h.reportingLoc.String(),
+		"data":     retention,
+	})
+}
+
+// GetSegmentBreakdown returns views and engagement grouped by platform, device, country or
+// region (?groupBy=), optionally filtered with ?platform=, ?device= and ?region=, over a
+// time range that defaults to the last 7 days
+func (h *AnalyticsHandler) GetSegmentBreakdown(c *gin.Context) {
+	groupBy, err := services.ParseSegmentDimension(c.Query("groupBy"))
+	if err != nil {
+		RespondError(c, invalidParam("groupBy", "Must be platform, device, country or region"))
+		return
+	}
+
+	window, ok := h.dashboardWindow(c)
+	if !ok {
+		return
+	}
+	if window == nil {
+		window, _ = services.ParseDashboardWindow("7d", "", "", time.Now(), h.reportingLoc)
+	}
+
+	filter := services.SegmentFilter{
+		Platform:   c.Query("platform"),
+		DeviceType: c.Query("device"),
+		Region:     c.Query("region"),
+	}
+	breakdown, err := h.dashboardAnalytics.GetSegmentBreakdown(window, groupBy, filter)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch segment breakdown"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
-		"count":  len(trends),
-		"data":   trends,
+		"count":  len(breakdown.Segments),
+		"window": window,
+		"data":   breakdown,
+	})
+}
+
+// GetLeaderboard returns the posts and creators that gained the most trending score in the
+// current day, week or month, or in the one containing ?date=YYYY-MM-DD
+func (h *AnalyticsHandler) GetLeaderboard(c *gin.Context) {
+	period := c.Param("period")
+	if !services.IsLeaderboardPeriod(period) {
+		RespondError(c, badRequest("INVALID_PERIOD", "Invalid period. Must be day, week or month"))
+		return
+	}
+
+	// Parse limit parameter with default value of 10
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 || limit > 100 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 100"))
+		return
+	}
+
+	// Periods are days, weeks and months in the reporting time zone
+	at := time.Now()
+	if date := c.Query("date"); date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, h.reportingLoc)
+		if err != nil || day.After(at) {
+			RespondError(c, invalidParam("date", "Must be a past YYYY-MM-DD date"))
+			return
+		}
+		at = day
+	}
+
+	leaderboard, err := h.dashboardAnalytics.GetLeaderboard(period, at, limit)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch leaderboard"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"timezone": h.reportingLoc.String(),
+		"data":     leaderboard,
 	})
 }
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Context key of the API key that authenticated a request
+const apiKeyContextKey = "api_key"
+
+// RequireRole rejects requests without an X-API-Key of the given role, or of the admin role,
+// and requests over the key's rate limit. Without configured keys every request is rejected,
+// unless allowUnauthenticated lets them all pass.
+func RequireRole(keys *services.APIKeyStore, role string, allowUnauthenticated bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if keys == nil && allowUnauthenticated {
+			c.Next()
+			return
+		}
+
+		key, err := keys.Authorize(c.GetHeader("X-API-Key"), role)
+		switch {
+		case errors.Is(err, services.ErrNoAPIKeys):
+			RespondError(c, unavailable("No API keys are configured"))
+			return
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			RespondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Missing or invalid API key"))
+			return
+		case errors.Is(err, services.ErrAPIKeyRole):
+			RespondError(c, NewAPIError(http.StatusForbidden, CodeForbidden, "API key "+key.Name+" lacks the "+role+" role"))
+			return
+		case errors.Is(err, services.ErrAPIKeyRateLimited):
+			RespondError(c, NewAPIError(http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded for API key "+key.Name))
+			return
+		}
+
+		c.Set(apiKeyContextKey, key)
+		c.Next()
+	}
+}
+
+// ListAPIKeys returns the configured API keys, without their hashes, and the traffic of each
+func ListAPIKeys(keys *services.APIKeyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"enabled": keys != nil,
+				"keys":    keys.Keys(),
+				"traffic": keys.Stats(),
+			},
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// CompressionOptions configures response compression
+type CompressionOptions struct {
+	// Smallest body compressed; smaller bodies cost more to compress than they save
+	MinBytes int
+
+	// Compression level, 1 (fastest) to 9 (smallest); other values use the default level.
+	// Brotli runs at the same level, short of its slowest levels 10 and 11.
+	Level int
+}
+
+// compressor is a brotli, gzip or deflate writer, all of which can be reset and reused
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Encodings offered, in the order preferred when the client weighs them equally; brotli
+// gives the smallest bodies
+var compressionEncodings = []string{"br", "gzip", "deflate"}
+
+// Default brotli level, which compresses JSON about as fast as gzip's default
+const defaultBrotliLevel = 5
+
+// Compress compresses responses of at least MinBytes with brotli, gzip or deflate, whichever
+// the client's Accept-Encoding prefers. Upgrades, event streams and responses that are already
+// encoded are sent as they are.
+func Compress(opts CompressionOptions) gin.HandlerFunc {
+	brotliLevel := opts.Level
+	if opts.Level < gzip.BestSpeed || opts.Level > gzip.BestCompression {
+		opts.Level = gzip.DefaultCompression
+		brotliLevel = defaultBrotliLevel
+	}
+	pools := map[string]*sync.Pool{
+		"br": {New: func() interface{} {
+			return brotli.NewWriterLevel(io.Discard, brotliLevel)
+		}},
+		"gzip": {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, opts.Level)
+			return w
+		}},
+		"deflate": {New: func() interface{} {
+			w, _ := flate.NewWriter(io.Discard, opts.Level)
+			return w
+		}},
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+		// Caches must keep the encodings of a response apart
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, pool: pools[encoding], minBytes: opts.MinBytes}
+		c.Writer = writer
+		defer func() {
+			writer.finish()
+			c.Writer = writer.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding returns the offered encoding the Accept-Encoding header weighs highest,
+// "" when it accepts none of them
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	weights := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range compressionEncodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the start of a response until it knows whether the body reaches
+// minBytes, then sends it compressed or as it is
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	pool     *sync.Pool
+	minBytes int
+
+	pending []byte
+	decided bool
+	encoder compressor // nil when the response is sent as it is
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.pending = append(w.pending, data...)
+		if len(w.pending) < w.minBytes {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers, so the response is sent as it is unless the held back
+// start already reached minBytes
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Written also counts the held back start of the response
+func (w *compressWriter) Written() bool {
+	return len(w.pending) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends what was written so far; a response flushed before reaching minBytes, such as
+// a stream, is sent as it is
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide starts the response, compressed when the held back start reached minBytes and the
+// response can be compressed
+func (w *compressWriter) decide() error {
+	w.decided = true
+	pending := w.pending
+	w.pending = nil
+
+	if len(pending) >= w.minBytes && w.compressible() {
+		header := w.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		// The compressed bytes differ from the ones a strong ETag stands for
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.encoder = w.pool.Get().(compressor)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(pending)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(pending)
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	return !strings.HasPrefix(contentType, "text/event-stream") && !strings.HasPrefix(contentType, "image/")
+}
+
+// finish sends what is still held back and ends the compressed stream
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header   string
+		encoding string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"GZIP", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"deflate, gzip", "gzip"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0.5, gzip;q=0.8, deflate;q=0.9", "deflate"},
+		{"gzip;q=0", ""},
+		{"br;q=0, *", "gzip"},
+		{"*", "br"},
+		{"*;q=0.1, deflate;q=0.5", "deflate"},
+		{"*;q=0", ""},
+		{"br;q=high, gzip;q=0.2", "gzip"},
+		{" gzip ; q=0.3 , br ; q=0.2 ", "gzip"},
+	}
+	for _, test := range tests {
+		if encoding := negotiateEncoding(test.header); encoding != test.encoding {
+			t.Errorf("%q: expected %q, got %q", test.header, test.encoding, encoding)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"post_id":"post-1","score":42}`, 100)
+	router := gin.New()
+	router.Use(Compress(CompressionOptions{MinBytes: 1024}))
+	router.GET("/large", func(c *gin.Context) { c.String(http.StatusOK, large) })
+	router.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, large)
+	})
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"":        func(r io.Reader) (io.Reader, error) { return r, nil },
+		"br":      func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"gzip":    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"deflate": func(r io.Reader) (io.Reader, error) { return flate.NewReader(r), nil },
+	}
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		encoding       string
+		body           string
+	}{
+		{"/large", "gzip, deflate, br", "br", large},
+		{"/large", "br;q=0.5, gzip", "gzip", large},
+		{"/large", "deflate", "deflate", large},
+		{"/large", "", "", large},
+		{"/large", "br;q=0, gzip;q=0", "", large},
+		{"/small", "br", "", "ok"},
+		{"/stream", "br", "", large},
+	}
+	for _, test := range tests {
+		t.Run(test.path+" "+test.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if encoding := w.Header().Get("Content-Encoding"); encoding != test.encoding {
+				t.Fatalf("Expected Content-Encoding %q, got %q", test.encoding, encoding)
+			}
+			// Every response varies by Accept-Encoding, compressed or not, so caches keep them apart
+			if vary := w.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept-Encoding" {
+				t.Errorf("Expected Vary: Accept-Encoding once, got %v", vary)
+			}
+
+			reader, err := decoders[test.encoding](w.Body)
+			if err != nil {
+				t.Fatalf("Failed to open the %s body: %v", test.encoding, err)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Failed to decode the %s body: %v", test.encoding, err)
+			}
+			if string(body) != test.body {
+				t.Errorf("Expected the body to round-trip, got %d bytes", len(body))
+			}
+		})
+	}
+}
+
+func TestCompressWeakensStrongETags(t *testing.T) {
+	router := gin.New()
+	router.Use(Compress(CompressionOptions{MinBytes: 1}))
+	router.GET("/", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.String(http.StatusOK, "body")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if etag := w.Header().Get("ETag"); etag != `W/"v1"` {
+		t.Errorf("Expected the ETag of a compressed body to be weak, got %s", etag)
+	}
+}
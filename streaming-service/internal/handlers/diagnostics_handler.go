@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"confluent-viral-intelligence/internal/services"
+)
+
+type DiagnosticsHandler struct {
+	quotas   *services.QuotaMonitor
+	latency   *services.PipelineMetrics
+	backdated *services.BackdatedMetrics
+	anchors   *services.DecayAnchorReport
+	memory    *services.MemoryGuard
+	caches    *services.CacheMaintainer
+	vertexAI  *services.VertexAIClient // nil with other AI providers
+	ai        services.AIProvider
+}
+
+// aiCallStats is implemented by the remote AI providers, which guard their calls with a
+// circuit breaker and rate limiter
+type aiCallStats interface {
+	BreakerState() services.BreakerState
+	RateLimitStats() []services.RateLimitStats
+}
+
+func NewDiagnosticsHandler(quotas *services.QuotaMonitor, latency *services.PipelineMetrics, backdated *services.BackdatedMetrics, anchors *services.DecayAnchorReport, memory *services.MemoryGuard, caches *services.CacheMaintainer, vertexAI *services.VertexAIClient, ai services.AIProvider) *DiagnosticsHandler {
+	return &DiagnosticsHandler{quotas: quotas, latency: latency, backdated: backdated, anchors: anchors, memory: memory, caches: caches, vertexAI: vertexAI, ai: ai}
+}
+
+// GetDiagnostics returns quota usage, flagging resources past their soft warning threshold,
+// together with pipeline latency, the corrections made for backdated events, the memory held
+// by in-process caches and buffers, the maintenance of TTL caches, AI provider rate limiting
+// and the posts whose trending scores decay without a known creation time
+func (h *DiagnosticsHandler) GetDiagnostics(c *gin.Context) {
+	quotas := h.quotas.States()
+
+	var heap runtime.MemStats
+	runtime.ReadMemStats(&heap)
+
+	warnings := []string{}
+	for _, q := range quotas {
+		if q.Warning {
+			warnings = append(warnings, q.Resource)
+		}
+	}
+
+	var aiCache *services.AICacheStats
+	if h.vertexAI != nil {
+		stats := h.vertexAI.CacheStats()
+		aiCache = &stats
+	}
+	aiRateLimits := []services.RateLimitStats{}
+	if stats, ok := h.ai.(aiCallStats); ok {
+		aiRateLimits = stats.RateLimitStats()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"quota_warning":    len(warnings) > 0,
+			"quota_warnings":   warnings,
+			"quotas":           quotas,
+			"circuit_breakers": h.breakerStates(),
+			"ai_provider":      h.ai.Name(),
+			"ai_cache":         aiCache,
+			"ai_rate_limits":   aiRateLimits,
+			"caches":           h.caches.Stats(),
+			"pipeline_latency": h.latency.Snapshot(),
+			"latency_since":    h.latency.Since().UTC().Format(time.RFC3339),
+			"backdated_events": h.backdated.Snapshot(),
+			"backdated_since":  h.backdated.Since().UTC().Format(time.RFC3339),
+			"memory":           h.memory.States(),
+			"decay_anchors":    h.anchors.Snapshot(),
+			"heap_alloc_bytes": heap.HeapAlloc,
+		},
+	})
+}
+
+// GetCircuitBreakers returns the state of the circuit breakers guarding external dependencies
+func (h *DiagnosticsHandler) GetCircuitBreakers(c *gin.Context) {
+	breakers := h.breakerStates()
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(breakers),
+		"data":   breakers,
+	})
+}
+
+// GetAIUsage returns Gemini token usage and estimated cost per day and operation
+func (h *DiagnosticsHandler) GetAIUsage(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > 30 {
+		RespondError(c, invalidParam("days", "Must be between 1 and 30"))
+		return
+	}
+
+	usage := []services.AIUsageDay{}
+	if h.vertexAI != nil {
+		usage = h.vertexAI.Usage(days)
+	}
+
+	var total services.AIUsage
+	for _, day := range usage {
+		total.Add(day.Total)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"days":  usage,
+			"total": total,
+		},
+	})
+}
+
+func (h *DiagnosticsHandler) breakerStates() []services.BreakerState {
+	if stats, ok := h.ai.(aiCallStats); ok {
+		return []services.BreakerState{stats.BreakerState()}
+	}
+	return []services.BreakerState{}
+}
+
+// TrackAPIKeyUsage counts requests with an X-API-Key header against the API key quota of the
+// configured key by name. Keys that are not configured share one "unknown" count, so secrets
+// never show up in diagnostics or alerts and the counts stay bounded.
+func TrackAPIKeyUsage(quotas *services.QuotaMonitor, keys *services.APIKeyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if secret := c.GetHeader("X-API-Key"); secret != "" {
+			name := "unknown"
+			if key, ok := keys.Authenticate(secret); ok {
+				name = key.Name
+			}
+			quotas.Record(services.QuotaAPIKey+":"+name, 1)
+		}
+		c.Next()
+	}
+}
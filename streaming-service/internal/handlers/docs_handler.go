@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// docsPage renders the spec with Swagger UI
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Viral Intelligence API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "{{SPEC_URL}}", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>`
+
+type DocsHandler struct {
+	spec    map[string]interface{}
+	specURL string
+}
+
+// NewDocsHandler serves spec, an OpenAPI document, and the interactive docs that load it
+// from specURL
+func NewDocsHandler(spec map[string]interface{}, specURL string) *DocsHandler {
+	return &DocsHandler{spec: spec, specURL: specURL}
+}
+
+// GetSpec returns the OpenAPI document of the API
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	c.JSON(http.StatusOK, h.spec)
+}
+
+// GetDocs returns the Swagger UI page of the API
+func (h *DocsHandler) GetDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(strings.Replace(docsPage, "{{SPEC_URL}}", h.specURL, 1)))
+}
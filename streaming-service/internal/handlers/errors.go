@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Machine-readable error codes. Rejected query and path parameters get INVALID_ followed by
+// the parameter name in upper snake case, such as INVALID_LIMIT or INVALID_CREATOR_TIER.
+const (
+	CodeInvalidRequest      = "INVALID_REQUEST"
+	CodeInvalidTimeRange    = "INVALID_TIME_RANGE"
+	CodeMissingID           = "MISSING_ID"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeRateLimited         = "RATE_LIMITED"
+	CodeNotFound            = "NOT_FOUND"
+	CodePostNotFound        = "POST_NOT_FOUND"
+	CodeCreatorNotFound     = "CREATOR_NOT_FOUND"
+	CodeOperationNotFound   = "OPERATION_NOT_FOUND"
+	CodeRouteNotFound       = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	CodeUnsupportedVersion  = "UNSUPPORTED_VERSION"
+	CodeConflict            = "CONFLICT"
+	CodeFeatureUnavailable  = "FEATURE_UNAVAILABLE"
+	CodeQueueFull           = "QUEUE_FULL"
+	CodeUpstreamKafkaDown   = "UPSTREAM_KAFKA_DOWN"
+	CodeUpstreamAIDown      = "UPSTREAM_AI_DOWN"
+	CodeUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	CodeInternalError       = "INTERNAL_ERROR"
+)
+
+// APIError is the error every API route responds with:
+//
+//	{"status": "error", "code": "INVALID_LIMIT", "error": "Invalid limit parameter. ...",
+//	 "details": [...], "request_id": "..."}
+//
+// error stays a plain message so clients reading it keep working.
+type APIError struct {
+	HTTPStatus int
+	Code       string
+	Message    string
+	Details    []FieldError
+
+	// Extra fields of the response body, such as the versions a 406 lists
+	Extra gin.H
+
+	// What went wrong inside the service; logged, never returned
+	Cause error
+}
+
+// NewAPIError returns an error responded with the given status, code and message
+func NewAPIError(status int, code, message string) *APIError {
+	return &APIError{HTTPStatus: status, Code: code, Message: message}
+}
+
+func (e *APIError) Error() string {
+	if e.Cause != nil {
+		return e.Code + ": " + e.Message + ": " + e.Cause.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *APIError) Unwrap() error { return e.Cause }
+
+// body returns the JSON body of the error
+func (e *APIError) body(requestID string) gin.H {
+	body := gin.H{
+		"status": "error",
+		"code":   e.Code,
+		"error":  e.Message,
+	}
+	for key, value := range e.Extra {
+		body[key] = value
+	}
+	if len(e.Details) > 0 {
+		body["details"] = e.Details
+	}
+	if requestID != "" {
+		body["request_id"] = requestID
+	}
+	return body
+}
+
+// RespondError aborts a request with the JSON body of err, mapped to an APIError by
+// ToAPIError. The cause is attached to the request so the access log shows it.
+func RespondError(c *gin.Context, err error) {
+	apiErr := ToAPIError(err)
+	if apiErr.Cause != nil {
+		c.Error(apiErr.Cause)
+	}
+	c.AbortWithStatusJSON(apiErr.HTTPStatus, apiErr.body(requestID(c)))
+}
+
+// ToAPIError maps an error to the APIError a client sees. Errors of unavailable upstreams,
+// Kafka, the AI provider or Firestore, become 503s naming the upstream; any other error
+// becomes a 500 that reveals nothing.
+func ToAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return failed(err, "Internal error")
+}
+
+// failed returns the error of an operation that failed because of cause, with message as
+// the client-facing description unless cause is an unavailable upstream
+func failed(cause error, message string) *APIError {
+	apiErr := &APIError{HTTPStatus: http.StatusInternalServerError, Code: CodeInternalError, Message: message, Cause: cause}
+	switch {
+	case errors.Is(cause, services.ErrKafkaUnavailable):
+		apiErr.HTTPStatus, apiErr.Code = http.StatusServiceUnavailable, CodeUpstreamKafkaDown
+	case errors.Is(cause, services.ErrCircuitOpen), errors.Is(cause, services.ErrRateLimited):
+		apiErr.HTTPStatus, apiErr.Code = http.StatusServiceUnavailable, CodeUpstreamAIDown
+	case services.IsUnavailable(cause):
+		apiErr.HTTPStatus, apiErr.Code = http.StatusServiceUnavailable, CodeUpstreamUnavailable
+	}
+	return apiErr
+}
+
+// invalidParam rejects a query or path parameter, e.g. invalidParam("limit", "Must be between
+// 1 and 100")
+func invalidParam(name, reason string) *APIError {
+	return NewAPIError(http.StatusBadRequest, "INVALID_"+upperSnake(name), fmt.Sprintf("Invalid %s parameter. %s", name, reason))
+}
+
+// badRequest rejects a request with a code of its own
+func badRequest(code, message string) *APIError {
+	return NewAPIError(http.StatusBadRequest, code, message)
+}
+
+// missingID rejects a request whose path lacks the ID of what it is about
+func missingID(what string) *APIError {
+	return NewAPIError(http.StatusBadRequest, CodeMissingID, what+" ID is required")
+}
+
+func notFound(code, message string) *APIError {
+	return NewAPIError(http.StatusNotFound, code, message)
+}
+
+// unavailable rejects a request for a feature this instance does not serve
+func unavailable(message string) *APIError {
+	return NewAPIError(http.StatusServiceUnavailable, CodeFeatureUnavailable, message)
+}
+
+// upperSnake turns a parameter name such as creatorTier into CREATOR_TIER
+func upperSnake(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// HandleErrors answers with an APIError body the requests the handlers cannot: requests that
+// panicked, and requests aborted with an error but no response
+func HandleErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(recovered)
+				}
+				logger.Errorf("❌ [%s] Panic serving %s %s: %v", requestID(c), c.Request.Method, c.Request.URL.Path, recovered)
+				if !c.Writer.Written() {
+					RespondError(c, fmt.Errorf("panic: %v", recovered))
+				}
+				c.Abort()
+			}
+		}()
+
+		c.Next()
+
+		if c.Writer.Written() || !c.IsAborted() || len(c.Errors) == 0 {
+			return
+		}
+		RespondError(c, c.Errors.Last().Err)
+	}
+}
+
+// RouteNotFound answers requests for paths no route serves
+func RouteNotFound(c *gin.Context) {
+	RespondError(c, notFound(CodeRouteNotFound, "No route serves "+c.Request.URL.Path))
+}
+
+// MethodNotAllowed answers requests whose path is served for other methods only
+func MethodNotAllowed(c *gin.Context) {
+	RespondError(c, NewAPIError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path))
+}
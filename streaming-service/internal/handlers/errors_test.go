@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID(), HandleErrors())
+	router.GET("/invalid", func(c *gin.Context) {
+		respondInvalid(c, FieldError{Field: "limit", Message: "must be at most 100"})
+	})
+	router.GET("/unavailable", func(c *gin.Context) {
+		RespondError(c, unavailable("Embeddings are not enabled"))
+	})
+	router.GET("/kafka", func(c *gin.Context) {
+		RespondError(c, failed(fmt.Errorf("publish: %w", services.ErrKafkaUnavailable), "Failed to publish event"))
+	})
+	router.GET("/unexpected", func(c *gin.Context) {
+		c.Error(errors.New("connection string secret://leaked"))
+		c.Abort()
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("secret://leaked")
+	})
+
+	tests := []struct {
+		path    string
+		status  int
+		code    string
+		message string
+		details int
+	}{
+		{"/invalid", http.StatusBadRequest, CodeInvalidRequest, "Invalid request", 1},
+		{"/unavailable", http.StatusServiceUnavailable, CodeFeatureUnavailable, "Embeddings are not enabled", 0},
+		{"/kafka", http.StatusServiceUnavailable, CodeUpstreamKafkaDown, "Failed to publish event", 0},
+		{"/unexpected", http.StatusInternalServerError, CodeInternalError, "Internal error", 0},
+		{"/panic", http.StatusInternalServerError, CodeInternalError, "Internal error", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Header.Set(RequestIDHeader, "req-123")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("Expected %d, got %d: %s", test.status, w.Code, w.Body)
+			}
+			if strings.Contains(w.Body.String(), "secret://") {
+				t.Errorf("Expected the cause to stay out of the response, got %s", w.Body)
+			}
+
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode %s: %v", w.Body, err)
+			}
+			for _, key := range []string{"status", "code", "error", "request_id"} {
+				if _, ok := body[key]; !ok {
+					t.Errorf("Expected %s in the envelope %s", key, w.Body)
+				}
+			}
+			var envelope errorBody
+			json.Unmarshal(w.Body.Bytes(), &envelope)
+			if envelope.Status != "error" || envelope.Code != test.code || envelope.Error != test.message || envelope.RequestID != "req-123" {
+				t.Errorf("Unexpected envelope %+v", envelope)
+			}
+			if len(envelope.Details) != test.details {
+				t.Errorf("Expected %d details, got %+v", test.details, envelope.Details)
+			}
+			if _, ok := body["details"]; !ok && test.details > 0 {
+				t.Error("Expected details in the envelope")
+			} else if ok && test.details == 0 {
+				t.Errorf("Expected no details in the envelope, got %s", body["details"])
+			}
+		})
+	}
+}
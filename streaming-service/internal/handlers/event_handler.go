@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,17 +11,20 @@ import (
 )
 
 type EventHandler struct {
-	processor *services.EventProcessor
+	processor  *services.EventProcessor
+	operations *services.OperationQueue // nil when async ingestion is unavailable
 }
 
-func NewEventHandler(processor *services.EventProcessor) *EventHandler {
-	return &EventHandler{processor: processor}
+func NewEventHandler(processor *services.EventProcessor, operations *services.OperationQueue) *EventHandler {
+	return &EventHandler{processor: processor, operations: operations}
 }
 
 func (h *EventHandler) HandleInteraction(c *gin.Context) {
+	start := time.Now()
+	defer services.PipelineLatency.ObserveSince(services.StageHandler, start)
+
 	var event models.InteractionEvent
-	if err := c.ShouldBindJSON(&event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindStrictJSON(c, &event) {
 		return
 	}
 
@@ -29,8 +33,8 @@ func (h *EventHandler) HandleInteraction(c *gin.Context) {
 		event.Timestamp = time.Now()
 	}
 
-	if err := h.processor.ProcessInteraction(event); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process interaction"})
+	if err := h.processor.ProcessInteraction(event, requestID(c)); err != nil {
+		RespondError(c, failed(err, "Failed to process interaction"))
 		return
 	}
 
@@ -38,9 +42,11 @@ func (h *EventHandler) HandleInteraction(c *gin.Context) {
 }
 
 func (h *EventHandler) HandleContentMetadata(c *gin.Context) {
+	start := time.Now()
+	defer services.PipelineLatency.ObserveSince(services.StageHandler, start)
+
 	var event models.ContentMetadata
-	if err := c.ShouldBindJSON(&event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindStrictJSON(c, &event) {
 		return
 	}
 
@@ -49,23 +55,54 @@ func (h *EventHandler) HandleContentMetadata(c *gin.Context) {
 		event.CreatedAt = time.Now()
 	}
 
-	if err := h.processor.ProcessContentMetadata(event); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process content metadata"})
+	// Keyword extraction can take seconds; async clients get 202 at once and poll the operation
+	if wantsAsync(c) && h.operations != nil {
+		rid := requestID(c)
+		op, err := h.operations.Submit(OperationContentMetadata, rid, func() (interface{}, error) {
+			processed, err := h.processor.ProcessContentMetadata(event, rid)
+			if err != nil {
+				apiErr := failed(err, "Failed to process content metadata")
+				return nil, NewAPIError(apiErr.HTTPStatus, apiErr.Code, apiErr.Message)
+			}
+			return contentMetadataResult(processed), nil
+		})
+		if err != nil {
+			c.Header("Retry-After", "1")
+			RespondError(c, NewAPIError(http.StatusServiceUnavailable, CodeQueueFull, "Too many operations are queued, retry later"))
+			return
+		}
+		respondAccepted(c, op)
+		return
+	}
+
+	processed, err := h.processor.ProcessContentMetadata(event, requestID(c))
+	if err != nil {
+		RespondError(c, failed(err, "Failed to process content metadata"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":   "success",
+	result := contentMetadataResult(processed)
+	result["status"] = "success"
+	c.JSON(http.StatusOK, result)
+}
+
+// contentMetadataResult returns what content ingestion answers with: the keywords, category
+// and style extracted for the post
+func contentMetadataResult(event models.ContentMetadata) gin.H {
+	return gin.H{
+		"post_id":  event.PostID,
 		"keywords": event.Keywords,
 		"category": event.Category,
 		"style":    event.Style,
-	})
+	}
 }
 
 func (h *EventHandler) HandleView(c *gin.Context) {
+	start := time.Now()
+	defer services.PipelineLatency.ObserveSince(services.StageHandler, start)
+
 	var event models.ViewEvent
-	if err := c.ShouldBindJSON(&event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindStrictJSON(c, &event) {
 		return
 	}
 
@@ -74,8 +111,8 @@ func (h *EventHandler) HandleView(c *gin.Context) {
 		event.ViewedAt = time.Now()
 	}
 
-	if err := h.processor.ProcessView(event); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process view"})
+	if err := h.processor.ProcessView(event, requestID(c)); err != nil {
+		RespondError(c, failed(err, "Failed to process view"))
 		return
 	}
 
@@ -83,9 +120,11 @@ func (h *EventHandler) HandleView(c *gin.Context) {
 }
 
 func (h *EventHandler) HandleRemix(c *gin.Context) {
+	start := time.Now()
+	defer services.PipelineLatency.ObserveSince(services.StageHandler, start)
+
 	var event models.RemixEvent
-	if err := c.ShouldBindJSON(&event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindStrictJSON(c, &event) {
 		return
 	}
 
@@ -94,8 +133,58 @@ func (h *EventHandler) HandleRemix(c *gin.Context) {
 		event.RemixedAt = time.Now()
 	}
 
-	if err := h.processor.ProcessRemix(event); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process remix"})
+	if err := h.processor.ProcessRemix(event, requestID(c)); err != nil {
+		RespondError(c, failed(err, "Failed to process remix"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *EventHandler) HandleComment(c *gin.Context) {
+	start := time.Now()
+	defer services.PipelineLatency.ObserveSince(services.StageHandler, start)
+
+	var event models.CommentEvent
+	if !bindStrictJSON(c, &event) {
+		return
+	}
+
+	// Text of only whitespace passes the required check
+	if strings.TrimSpace(event.Text) == "" {
+		respondInvalid(c, FieldError{Field: "text", Message: "is required"})
+		return
+	}
+
+	// Set timestamp if not provided
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	if err := h.processor.ProcessComment(event, requestID(c)); err != nil {
+		RespondError(c, failed(err, "Failed to process comment"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *EventHandler) HandleRecommendationFeedback(c *gin.Context) {
+	start := time.Now()
+	defer services.PipelineLatency.ObserveSince(services.StageHandler, start)
+
+	var event models.RecommendationFeedbackEvent
+	if !bindStrictJSON(c, &event) {
+		return
+	}
+
+	// Set timestamp if not provided
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	if err := h.processor.ProcessRecommendationFeedback(event, requestID(c)); err != nil {
+		RespondError(c, failed(err, "Failed to process recommendation feedback"))
 		return
 	}
 
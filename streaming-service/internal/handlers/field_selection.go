@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SelectFields reduces the data of successful JSON responses to the fields listed in
+// ?fields=, e.g. ?fields=post_id,score,thumbnail_url, so clients on slow connections fetch
+// only what they render. Routes that know their fields, like trending, check the names and
+// skip the content they would not return; here unknown names are left out.
+func SelectFields() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fields := services.ParseFields(c.Query("fields"))
+		if fields == nil {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if writer.status == http.StatusOK && strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "application/json") {
+			projected, err := services.ProjectJSON(body, fields)
+			if err != nil {
+				logger.Warnf("⚠️ [%s] Failed to select fields of %s: %v", requestID(c), c.Request.URL.Path, err)
+			} else {
+				body = projected
+			}
+		}
+		c.Writer.WriteHeader(writer.status)
+		c.Writer.Write(body)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *services.HealthChecker
+	runMode string
+}
+
+func NewHealthHandler(checker *services.HealthChecker, runMode string) *HealthHandler {
+	return &HealthHandler{checker: checker, runMode: runMode}
+}
+
+// Live answers as long as the process serves requests; it checks no dependency, so an outage
+// does not get healthy instances restarted
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive", "run_mode": h.runMode})
+}
+
+// Ready checks the instance's dependencies and answers 503 while a required one is down, with
+// the status and latency of each
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+
+	status, code := "ready", http.StatusOK
+	if !report.Ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{
+		"status":   status,
+		"run_mode": h.runMode,
+		"data":     report,
+	})
+}
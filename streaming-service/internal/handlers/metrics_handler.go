@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"confluent-viral-intelligence/internal/services"
+)
+
+type MetricsHandler struct {
+	latency *services.PipelineMetrics
+	hub     *services.WebSocketHub
+}
+
+func NewMetricsHandler(latency *services.PipelineMetrics, hub *services.WebSocketHub) *MetricsHandler {
+	return &MetricsHandler{latency: latency, hub: hub}
+}
+
+// GetPipelineLatency returns per-stage latency histograms of the event pipeline
+func (h *MetricsHandler) GetPipelineLatency(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"since":  h.latency.Since().UTC().Format(time.RFC3339),
+		"data":   h.latency.Snapshot(),
+	})
+}
+
+// GetWebSocketClients returns the outbound queue metrics of every connected WebSocket and
+// event stream client, those dropping the most messages first
+func (h *MetricsHandler) GetWebSocketClients(c *gin.Context) {
+	stats := h.hub.ClientStats()
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(stats),
+		"data":   stats,
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of operations run in the background
+const OperationContentMetadata = "content_metadata"
+
+type OperationHandler struct {
+	operations *services.OperationQueue
+}
+
+func NewOperationHandler(operations *services.OperationQueue) *OperationHandler {
+	return &OperationHandler{operations: operations}
+}
+
+// GetOperation returns the status of an operation and, once it is done, its result or error
+func (h *OperationHandler) GetOperation(c *gin.Context) {
+	if h.operations == nil {
+		RespondError(c, unavailable("Async operations are not enabled on this instance"))
+		return
+	}
+
+	op, ok := h.operations.Get(c.Param("id"))
+	if !ok {
+		RespondError(c, notFound(CodeOperationNotFound, "Operation not found or expired"))
+		return
+	}
+
+	// Pending operations are polled again shortly
+	if !op.Done() {
+		c.Header("Retry-After", "1")
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   op,
+	})
+}
+
+// wantsAsync reports whether the client asked for the request to run in the background, with
+// ?async=true or a Prefer: respond-async header
+func wantsAsync(c *gin.Context) bool {
+	if c.Query("async") == "true" {
+		return true
+	}
+	for _, preference := range strings.Split(c.GetHeader("Prefer"), ",") {
+		if strings.EqualFold(strings.TrimSpace(preference), "respond-async") {
+			return true
+		}
+	}
+	return false
+}
+
+// respondAccepted answers 202 for a submitted operation, with its status URL in the Location
+// header under the same API prefix as the request
+func respondAccepted(c *gin.Context, op services.Operation) {
+	prefix, _, _ := strings.Cut(c.FullPath(), "/events/")
+	location := prefix + "/operations/" + op.ID
+	c.Header("Location", location)
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":       "accepted",
+		"operation_id": op.ID,
+		"status_url":   location,
+		"data":         op,
+	})
+}
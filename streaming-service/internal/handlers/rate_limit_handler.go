@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// LimitRequests rejects requests over the rate of their client IP or user with 429 and a
+// Retry-After header. Users are identified by the API key that authenticated the request, so
+// it must run after RequireRole, or else by a Firebase ID token in the Authorization header;
+// anonymous requests are limited by IP only. Every request passes when limiter is nil.
+func LimitRequests(limiter *services.RequestLimiter, verifier *services.FirebaseTokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		user := requestUser(c, verifier)
+		c.Set(userContextKey, user)
+		allowed, wait := limiter.Allow(c.ClientIP(), user)
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Max(math.Ceil(wait.Seconds()), 1))))
+			RespondError(c, NewAPIError(http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded, retry later"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestUser returns the user a request is rate limited as, or "" for anonymous requests
+func requestUser(c *gin.Context, verifier *services.FirebaseTokenVerifier) string {
+	if value, ok := c.Get(apiKeyContextKey); ok {
+		if key, ok := value.(*services.APIKey); ok {
+			return "key:" + key.Name
+		}
+	}
+
+	auth := c.GetHeader("Authorization")
+	if verifier == nil || !strings.HasPrefix(auth, "Bearer ") {
+		return ""
+	}
+	userID, err := verifier.VerifyIDToken(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")))
+	if err != nil {
+		return ""
+	}
+	return "uid:" + userID
+}
+
+// GetRateLimits returns the configured request rate limits and the requests they limited
+func GetRateLimits(limiter *services.RequestLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"enabled": limiter != nil,
+				"limits":  limiter.Stats(),
+			},
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID that correlates a request across logs, Kafka and Firestore
+const RequestIDHeader = "X-Request-ID"
+
+// Context key of the request's ID
+const requestIDContextKey = "request_id"
+
+// Longest client-supplied request ID kept; longer or malformed IDs are replaced
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID: the client's X-Request-ID when it sends a usable one,
+// a new random ID otherwise. The ID is echoed in the X-Request-ID response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDContextKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestID returns the ID of a request, "" when RequestID did not run
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so they are safe to put in
+// headers and log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
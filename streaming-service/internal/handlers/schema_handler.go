@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"confluent-viral-intelligence/internal/services"
+)
+
+type SchemaHandler struct{}
+
+func NewSchemaHandler() *SchemaHandler {
+	return &SchemaHandler{}
+}
+
+// GetEventSchemas returns JSON Schemas for every WS message type and webhook payload
+func (h *SchemaHandler) GetEventSchemas(c *gin.Context) {
+	schemas := services.EventSchemas()
+
+	// Optional filter by message type
+	if eventType := c.Query("type"); eventType != "" {
+		schema, ok := schemas[eventType]
+		if !ok {
+			RespondError(c, notFound(CodeNotFound, "Unknown event type"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"version": services.EventSchemaVersion,
+			"data":    schema,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"version": services.EventSchemaVersion,
+		"count":   len(schemas),
+		"data":    schemas,
+	})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Largest request body accepted by bindStrictJSON
+const maxRequestBodyBytes = 1 << 20
+
+func init() {
+	// Report fields by their JSON names, as clients send them
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// FieldError explains why one field of a request was rejected
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// bindStrictJSON decodes a JSON body into obj, rejecting unknown fields, and validates it
+// against its binding tags. On failure it responds with 400 and the errors of each field,
+// and returns false.
+func bindStrictJSON(c *gin.Context, obj interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(obj)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(obj)
+	}
+	if err != nil {
+		respondInvalid(c, validationDetails(err)...)
+		return false
+	}
+	return true
+}
+
+// respondInvalid responds with 400 and the errors of each rejected field
+func respondInvalid(c *gin.Context, details ...FieldError) {
+	RespondError(c, &APIError{HTTPStatus: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "Invalid request", Details: details})
+}
+
+// validationDetails turns a decoding or validation error into messages per field
+func validationDetails(err error) []FieldError {
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		details := make([]FieldError, 0, len(fieldErrs))
+		for _, fieldErr := range fieldErrs {
+			details = append(details, FieldError{Field: fieldPath(fieldErr), Message: fieldMessage(fieldErr)})
+		}
+		return details
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &typeErr):
+		return []FieldError{{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}}
+	case errors.As(err, &syntaxErr):
+		return []FieldError{{Message: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)}}
+	case errors.As(err, &sizeErr):
+		return []FieldError{{Message: fmt.Sprintf("body must be at most %d bytes", sizeErr.Limit)}}
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Message: "body is empty"}}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Message: "body is truncated"}}
+	}
+
+	// The decoder reports unknown fields only by their message
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return []FieldError{{Field: strings.Trim(field, `"`), Message: "is not a known field"}}
+	}
+	return []FieldError{{Message: err.Error()}}
+}
+
+// fieldPath returns the JSON path of a field, without the name of the top-level struct
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".")
+	if !found {
+		return fieldErr.Field()
+	}
+	return path
+}
+
+// fieldMessage describes a failed validation rule
+func fieldMessage(fieldErr validator.FieldError) string {
+	countable := fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "max":
+		switch {
+		case countable:
+			return "must have at most " + fieldErr.Param() + " entries"
+		case fieldErr.Kind() == reflect.String:
+			return "must be at most " + fieldErr.Param() + " characters"
+		}
+		return "must be at most " + fieldErr.Param()
+	case "min":
+		switch {
+		case countable:
+			return "must have at least " + fieldErr.Param() + " entries"
+		case fieldErr.Kind() == reflect.String:
+			return "must be at least " + fieldErr.Param() + " characters"
+		}
+		return "must be at least " + fieldErr.Param()
+	}
+	return "failed the " + fieldErr.Tag() + " check"
+}
+
+// jsonTypeName names a Go type as its JSON counterpart, with its article
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// ValidateEvent validates an event decoded by another transport, such as gRPC, against its
+// binding tags like bindStrictJSON does, describing every rejected field in the error
+func ValidateEvent(obj interface{}) error {
+	err := binding.Validator.ValidateStruct(obj)
+	if err == nil {
+		return nil
+	}
+
+	details := validationDetails(err)
+	messages := make([]string, 0, len(details))
+	for _, detail := range details {
+		messages = append(messages, strings.TrimSpace(detail.Field+" "+detail.Message))
+	}
+	return errors.New("invalid request: " + strings.Join(messages, "; "))
+}
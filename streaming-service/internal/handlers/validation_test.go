@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// boundEvent is a request body with the binding tags the event handlers use
+type boundEvent struct {
+	PostID    string   `json:"post_id" binding:"required"`
+	EventType string   `json:"event_type" binding:"required,oneof=like comment"`
+	Tags      []string `json:"tags" binding:"max=2"`
+	Count     int      `json:"count"`
+}
+
+// errorBody is the JSON body of an APIError
+type errorBody struct {
+	Status    string       `json:"status"`
+	Code      string       `json:"code"`
+	Error     string       `json:"error"`
+	Details   []FieldError `json:"details"`
+	RequestID string       `json:"request_id"`
+}
+
+func TestBindStrictJSON(t *testing.T) {
+	router := gin.New()
+	router.POST("/events", func(c *gin.Context) {
+		var event boundEvent
+		if !bindStrictJSON(c, &event) {
+			return
+		}
+		c.JSON(http.StatusOK, event)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		details []FieldError // nil when the body is accepted
+	}{
+		{
+			name: "valid",
+			body: `{"post_id":"post-1","event_type":"like","tags":["a"]}`,
+		},
+		{
+			name:    "unknown field",
+			body:    `{"post_id":"post-1","event_type":"like","extra":true}`,
+			details: []FieldError{{Field: "extra", Message: "is not a known field"}},
+		},
+		{
+			name: "binding tags",
+			body: `{"event_type":"share","tags":["a","b","c"]}`,
+			details: []FieldError{
+				{Field: "post_id", Message: "is required"},
+				{Field: "event_type", Message: "must be one of: like, comment"},
+				{Field: "tags", Message: "must have at most 2 entries"},
+			},
+		},
+		{
+			name:    "wrong type",
+			body:    `{"post_id":"post-1","event_type":"like","count":"many"}`,
+			details: []FieldError{{Field: "count", Message: "must be an integer"}},
+		},
+		{
+			name:    "malformed JSON",
+			body:    `{"post_id":"post-1",}`,
+			details: []FieldError{{Message: "malformed JSON at offset 21"}},
+		},
+		{
+			name:    "truncated",
+			body:    `{"post_id":"post-1"`,
+			details: []FieldError{{Message: "body is truncated"}},
+		},
+		{
+			name:    "empty",
+			body:    ``,
+			details: []FieldError{{Message: "body is empty"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(test.body)))
+
+			if test.details == nil {
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+				}
+				return
+			}
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected 400, got %d: %s", w.Code, w.Body)
+			}
+			var body errorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode %s: %v", w.Body, err)
+			}
+			if body.Status != "error" || body.Code != CodeInvalidRequest || body.Error != "Invalid request" {
+				t.Errorf("Unexpected envelope %+v", body)
+			}
+			if len(body.Details) != len(test.details) {
+				t.Fatalf("Expected details %+v, got %+v", test.details, body.Details)
+			}
+			for i, detail := range test.details {
+				if body.Details[i] != detail {
+					t.Errorf("Detail %d: expected %+v, got %+v", i, detail, body.Details[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBindStrictJSONRejectsLargeBodies(t *testing.T) {
+	router := gin.New()
+	router.POST("/events", func(c *gin.Context) {
+		var event boundEvent
+		if bindStrictJSON(c, &event) {
+			c.Status(http.StatusOK)
+		}
+	})
+
+	body := `{"post_id":"` + strings.Repeat("x", maxRequestBodyBytes) + `"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "body must be at most") {
+		t.Errorf("Expected an oversized body to be rejected, got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhooks *services.WebhookDispatcher
+}
+
+func NewWebhookHandler(webhooks *services.WebhookDispatcher) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// WebhookRequest is the body of a webhook registration or update
+type WebhookRequest struct {
+	URL            string   `json:"url" binding:"required,max=2048"`
+	Events         []string `json:"events" binding:"required,min=1,max=3,dive,oneof=viral_alert score_threshold new_trending_entry"`
+	ScoreThreshold float64  `json:"score_threshold,omitempty" binding:"min=0"`
+	Active         *bool    `json:"active,omitempty"` // defaults to true
+}
+
+func (r WebhookRequest) webhook() services.Webhook {
+	hook := services.Webhook{URL: r.URL, Events: r.Events, ScoreThreshold: r.ScoreThreshold, Active: true}
+	if r.Active != nil {
+		hook.Active = *r.Active
+	}
+	return hook
+}
+
+// CreateWebhook registers a webhook and returns it with the secret its deliveries are signed
+// with, which is not shown again
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if !bindStrictJSON(c, &req) {
+		return
+	}
+
+	hook, err := h.webhooks.Create(req.webhook())
+	if err != nil {
+		respondWebhookError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   hook,
+	})
+}
+
+// ListWebhooks returns the registered webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.webhooks.List()
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch webhooks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(hooks),
+		"data":   hooks,
+	})
+}
+
+// GetWebhook returns a webhook
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	hook, err := h.webhooks.Get(c.Param("id"))
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch webhook"))
+		return
+	}
+	if hook == nil {
+		RespondError(c, notFound(CodeNotFound, "Webhook not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   hook,
+	})
+}
+
+// UpdateWebhook replaces the URL, events, threshold and state of a webhook
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if !bindStrictJSON(c, &req) {
+		return
+	}
+
+	hook, err := h.webhooks.Update(c.Param("id"), req.webhook())
+	if err != nil {
+		respondWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+	if hook == nil {
+		RespondError(c, notFound(CodeNotFound, "Webhook not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   hook,
+	})
+}
+
+// DeleteWebhook removes a webhook
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	deleted, err := h.webhooks.Delete(c.Param("id"))
+	if err != nil {
+		RespondError(c, failed(err, "Failed to delete webhook"))
+		return
+	}
+	if !deleted {
+		RespondError(c, notFound(CodeNotFound, "Webhook not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, newest first
+func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		RespondError(c, invalidParam("limit", "Must be between 1 and 200"))
+		return
+	}
+
+	deliveries, err := h.webhooks.Deliveries(c.Param("id"), limit)
+	if err != nil {
+		RespondError(c, failed(err, "Failed to fetch webhook deliveries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(deliveries),
+		"data":   deliveries,
+	})
+}
+
+// TestWebhook sends a test event to a webhook at once and returns how the delivery went
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	delivery, err := h.webhooks.Test(c.Param("id"))
+	if err != nil {
+		RespondError(c, failed(err, "Failed to test webhook"))
+		return
+	}
+	if delivery == nil {
+		RespondError(c, notFound(CodeNotFound, "Webhook not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   delivery,
+	})
+}
+
+// respondWebhookError answers 400 for invalid webhooks and a failure otherwise
+func respondWebhookError(c *gin.Context, err error, message string) {
+	var invalid *services.WebhookValidationError
+	if errors.As(err, &invalid) {
+		respondInvalid(c, FieldError{Field: invalid.Field, Message: invalid.Message})
+		return
+	}
+	RespondError(c, failed(err, message))
+}
@@ -1,25 +1,45 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"confluent-viral-intelligence/internal/config"
 	"confluent-viral-intelligence/internal/services"
 )
 
+// Interval of the comments that keep idle Server-Sent Events streams open through proxies
+const sseHeartbeatInterval = 15 * time.Second
+
 type WebSocketHandler struct {
 	hub      *services.WebSocketHub
 	upgrader websocket.Upgrader
+
+	// Authentication mode (config.WebSocketAuth*) and the verifier of Firebase ID tokens
+	authMode string
+	verifier *services.FirebaseTokenVerifier
 }
 
-func NewWebSocketHandler(hub *services.WebSocketHub) *WebSocketHandler {
+func NewWebSocketHandler(hub *services.WebSocketHub, authMode string, verifier *services.FirebaseTokenVerifier, compression bool) *WebSocketHandler {
 	return &WebSocketHandler{
-		hub: hub,
+		hub:      hub,
+		authMode: authMode,
+		verifier: verifier,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			// Clients that offer permessage-deflate get compressed frames
+			EnableCompression: compression,
+			// Clients pick the wire format with the Sec-WebSocket-Protocol header
+			Subprotocols: services.WireFormats,
 			// Allow all origins for WebSocket connections
 			// In production, you should restrict this to specific origins
 			CheckOrigin: func(r *http.Request) bool {
@@ -31,6 +51,11 @@ func NewWebSocketHandler(hub *services.WebSocketHub) *WebSocketHandler {
 
 // HandleWebSocket upgrades HTTP connection to WebSocket and registers the client
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	userID, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -38,8 +63,15 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// The negotiated subprotocol takes precedence over the format query parameter, which
+	// serves clients that cannot set protocols
+	format := conn.Subprotocol()
+	if format == "" {
+		format = c.Query("format")
+	}
+
 	// Create a new WebSocket client
-	client := services.NewWebSocketClient(conn, h.hub)
+	client := services.NewWebSocketClient(conn, h.hub, userID, format)
 
 	// Register the client with the hub
 	h.hub.RegisterClient(client)
@@ -47,5 +79,150 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	// Start the client's read and write pumps
 	client.Start()
 
-	log.Printf("WebSocket client connected from %s", c.Request.RemoteAddr)
+	// A reconnecting client can pass the last sequence ID it saw instead of sending a resume message
+	if lastSeq, err := strconv.ParseUint(c.Query("last_seq"), 10, 64); err == nil {
+		h.hub.Resume(client, lastSeq)
+	}
+
+	if userID != "" {
+		log.Printf("WebSocket client connected from %s as user %s", c.Request.RemoteAddr, userID)
+	} else {
+		log.Printf("WebSocket client connected from %s", c.Request.RemoteAddr)
+	}
+}
+
+// HandleEventStream serves the hub's broadcasts as Server-Sent Events, a fallback for clients
+// on networks that block WebSocket upgrades. Each event is named after the message type and
+// carries the broadcast seq as its id, so browsers resume with Last-Event-ID after reconnecting.
+func (h *WebSocketHandler) HandleEventStream(c *gin.Context) {
+	userID, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	client := services.NewStreamClient(h.hub, userID, "sse")
+	h.hub.RegisterClient(client)
+	defer client.Close()
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_seq")
+	}
+	if lastSeq, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+		h.hub.Resume(client, lastSeq)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Keep reverse proxies such as nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	log.Printf("Event stream client connected from %s", c.Request.RemoteAddr)
+	c.Stream(func(w io.Writer) bool {
+		messages, ok := client.Next(c.Request.Context(), sseHeartbeatInterval)
+		if !ok {
+			return false
+		}
+		if len(messages) == 0 {
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		}
+		for _, message := range messages {
+			if err := writeServerSentEvent(w, message); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// ListClients returns the WebSocket, event stream and gRPC clients connected to this instance
+// with their subscriptions, heartbeat and queue metrics
+func (h *WebSocketHandler) ListClients(c *gin.Context) {
+	clients := h.hub.ClientStats()
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"count":  len(clients),
+		"data":   clients,
+	})
+}
+
+// DisconnectClient closes the connection or stream of a client connected to this instance
+func (h *WebSocketHandler) DisconnectClient(c *gin.Context) {
+	clientID := c.Param("id")
+	if clientID == "" {
+		RespondError(c, missingID("Client"))
+		return
+	}
+
+	if !h.hub.Disconnect(clientID) {
+		RespondError(c, notFound(CodeNotFound, "Client is not connected to this instance"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// writeServerSentEvent writes a hub message as an event named after its type, with its seq as
+// the event id when it has one
+func writeServerSentEvent(w io.Writer, message []byte) error {
+	var envelope struct {
+		Type string `json:"type"`
+		Seq  uint64 `json:"seq"`
+	}
+	// Messages without a type or seq are sent as unnamed events without an id
+	json.Unmarshal(message, &envelope)
+
+	var event strings.Builder
+	if envelope.Seq > 0 {
+		fmt.Fprintf(&event, "id: %d\n", envelope.Seq)
+	}
+	if envelope.Type != "" {
+		fmt.Fprintf(&event, "event: %s\n", envelope.Type)
+	}
+	fmt.Fprintf(&event, "data: %s\n\n", message)
+	_, err := io.WriteString(w, event.String())
+	return err
+}
+
+// authenticate resolves the user of a WebSocket or event stream request from its Firebase ID token. Anonymous
+// requests get an empty user ID unless authentication is required; requests that are rejected
+// have already been answered.
+func (h *WebSocketHandler) authenticate(c *gin.Context) (string, bool) {
+	if h.authMode == config.WebSocketAuthOff {
+		return "", true
+	}
+
+	token := streamToken(c)
+	if token == "" {
+		if h.authMode == config.WebSocketAuthRequired {
+			RespondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Authentication token is required"))
+			return "", false
+		}
+		return "", true
+	}
+
+	userID, err := h.verifier.VerifyIDToken(token)
+	if err != nil {
+		log.Printf("Rejected WebSocket token from %s: %v", c.Request.RemoteAddr, err)
+		RespondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Invalid authentication token"))
+		return "", false
+	}
+	return userID, true
+}
+
+// streamToken returns the ID token of a WebSocket or event stream request from the token query
+// parameter or a bearer Authorization header; browsers cannot set headers on WebSocket or
+// EventSource requests
+func streamToken(c *gin.Context) string {
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
 }
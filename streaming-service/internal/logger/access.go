@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// AccessEntry describes one served HTTP request
+type AccessEntry struct {
+	Method    string
+	Path      string
+	Route     string // matched route pattern, "" when no route matched
+	Status    int
+	Latency   time.Duration
+	ClientIP  string
+	User      string // API key or user the request was made as, "" when anonymous
+	RequestID string
+	BytesIn   int64
+	BytesOut  int
+	Slow      bool
+	Errors    string // errors handlers attached to the request
+}
+
+// Access logs a served request as a structured access log entry: at error level for server
+// errors, at warn level for slow requests and at info level otherwise
+func Access(entry AccessEntry) {
+	var event *zerolog.Event
+	switch {
+	case entry.Status >= 500:
+		event = Logger.Error()
+	case entry.Slow:
+		event = Logger.Warn()
+	default:
+		event = Logger.Info()
+	}
+
+	event.
+		Str("type", "access").
+		Str("method", entry.Method).
+		Str("path", entry.Path).
+		Int("status", entry.Status).
+		Dur("latency_ms", entry.Latency).
+		Str("client_ip", entry.ClientIP).
+		Int64("bytes_in", entry.BytesIn).
+		Int("bytes_out", entry.BytesOut)
+	if entry.Route != "" {
+		event.Str("route", entry.Route)
+	}
+	if entry.User != "" {
+		event.Str("user", entry.User)
+	}
+	if entry.RequestID != "" {
+		event.Str("request_id", entry.RequestID)
+	}
+	if entry.Slow {
+		event.Bool("slow", true)
+	}
+	if entry.Errors != "" {
+		event.Str("errors", entry.Errors)
+	}
+	event.Msg(entry.Method + " " + entry.Path)
+}
@@ -1,45 +1,211 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // InteractionEvent represents a user interaction with content
 type InteractionEvent struct {
-	PostID    string    `json:"post_id"`
-	UserID    string    `json:"user_id"`
-	EventType string    `json:"event_type"` // view, like, comment, share
-	Timestamp time.Time `json:"timestamp"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	PostID    string                 `json:"post_id" binding:"required,max=128"`
+	UserID    string                 `json:"user_id" binding:"required,max=128"`
+	EventType string                 `json:"event_type" binding:"required,oneof=view like comment share"`
+	Timestamp time.Time              `json:"timestamp"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty" binding:"max=20"`
 }
 
 // ContentMetadata represents content information
 type ContentMetadata struct {
-	PostID      string    `json:"post_id"`
-	UserID      string    `json:"user_id"`
-	ContentType string    `json:"content_type"` // image, video, music, voice
-	Prompt      string    `json:"prompt"`
-	CreatedAt   time.Time `json:"created_at"`
-	Keywords    []string  `json:"keywords,omitempty"`
-	Category    string    `json:"category,omitempty"`
-	Style       string    `json:"style,omitempty"`
+	PostID          string    `json:"post_id" binding:"required,max=128"`
+	UserID          string    `json:"user_id" binding:"required,max=128"`
+	ContentType     string    `json:"content_type" binding:"required,oneof=image video music voice"`
+	Prompt          string    `json:"prompt" binding:"required,max=5000"`
+	CreatedAt       time.Time `json:"created_at"`
+	Keywords        []string  `json:"keywords,omitempty" binding:"max=50,dive,max=100"`
+	Hashtags        []string  `json:"hashtags,omitempty" binding:"max=50,dive,max=100"`         // lowercase topics without the leading #
+	Language        string    `json:"language,omitempty" binding:"max=8"`                       // ISO 639-1 code of the prompt
+	EnglishKeywords []string  `json:"english_keywords,omitempty" binding:"max=50,dive,max=100"` // keywords in English, for grouping across languages
+	Category        string    `json:"category,omitempty" binding:"max=100"`
+	Style           string    `json:"style,omitempty" binding:"max=100"`
+	OutputURLs      []string  `json:"output_urls,omitempty" binding:"max=20,dive,max=2048"`
+
+	// What Gemini vision saw in the first output, when media analysis is enabled
+	Visual *VisualAnalysis `json:"visual,omitempty"`
+
+	// Optional preview metadata known to the client; missing values are extracted server-side
+	Media *MediaMetadata `json:"media,omitempty"`
+}
+
+// MediaMetadata describes a post's media for rendering feed placeholders
+type MediaMetadata struct {
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	ThumbnailURL    string  `json:"thumbnail_url,omitempty"`
+	DominantColor   string  `json:"dominant_color,omitempty"` // #rrggbb
+
+	// Alternative duration formats accepted from clients, normalized into DurationSeconds:
+	// milliseconds, or a string such as "95", "1500ms", "1m35s", "1:35" or "PT1M35S"
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Duration   string `json:"duration,omitempty"`
+}
+
+// CommentEvent represents a comment posted on content
+type CommentEvent struct {
+	CommentID string    `json:"comment_id,omitempty" binding:"max=128"`
+	PostID    string    `json:"post_id" binding:"required,max=128"`
+	UserID    string    `json:"user_id" binding:"required,max=128"`
+	Text      string    `json:"text" binding:"required,max=2000"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // ViewEvent represents a content view
 type ViewEvent struct {
-	PostID     string    `json:"post_id"`
-	UserID     string    `json:"user_id"`
+	PostID     string    `json:"post_id" binding:"required,max=128"`
+	UserID     string    `json:"user_id" binding:"required,max=128"`
 	ViewedAt   time.Time `json:"viewed_at"`
-	Duration   int       `json:"duration"` // seconds
-	Platform   string    `json:"platform"` // mobile, web
-	DeviceType string    `json:"device_type,omitempty"`
+	Duration   int       `json:"duration" binding:"min=0,max=86400"` // seconds
+	Platform   string    `json:"platform" binding:"required,oneof=mobile web"`
+	DeviceType string    `json:"device_type,omitempty" binding:"max=64"`
+	Region     string    `json:"region,omitempty" binding:"max=16"` // ISO 3166-1 alpha-2 country, optionally with subdivision: US, US-CA
 }
 
 // RemixEvent represents a content remix
 type RemixEvent struct {
-	OriginalPostID string    `json:"original_post_id"`
-	RemixPostID    string    `json:"remix_post_id"`
-	UserID         string    `json:"user_id"`
+	OriginalPostID string    `json:"original_post_id" binding:"required,max=128"`
+	RemixPostID    string    `json:"remix_post_id" binding:"required,max=128"`
+	UserID         string    `json:"user_id" binding:"required,max=128"`
 	RemixedAt      time.Time `json:"remixed_at"`
-	RemixType      string    `json:"remix_type"` // style_transfer, variation, etc.
+	RemixType      string    `json:"remix_type" binding:"required,max=64"` // style_transfer, variation, etc.
+}
+
+// RemixChainSummary is the cold-storage rollup of archived remixes of a post
+type RemixChainSummary struct {
+	OriginalPostID string              `json:"original_post_id"`
+	RemixCount     int64               `json:"remix_count"`
+	Depth          int                 `json:"depth"` // longest remix-of-remix chain below the post
+	TopRemixes     []RemixSummaryEntry `json:"top_remixes"`
+	OldestRemixAt  time.Time           `json:"oldest_remix_at"`
+	NewestRemixAt  time.Time           `json:"newest_remix_at"`
+	ArchivedAt     time.Time           `json:"archived_at"`
+}
+
+// RemixSummaryEntry is a single top remix kept in a RemixChainSummary
+type RemixSummaryEntry struct {
+	PostID string  `json:"post_id"`
+	Score  float64 `json:"score"`
+}
+
+// PostTrace holds the trace mode settings of a post under debugging
+type PostTrace struct {
+	PostID    string    `json:"post_id"`
+	EnabledAt time.Time `json:"enabled_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PostTraceEntry is a single audit trail record for a traced post
+type PostTraceEntry struct {
+	PostID    string                 `json:"post_id"`
+	Kind      string                 `json:"kind"`   // event, score, ai
+	Source    string                 `json:"source"` // component or operation that produced the entry
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	ExpiresAt time.Time              `json:"expires_at"` // retention cutoff; the Firestore TTL policy deletes the entry after it
+}
+
+// ModerationVerdict is the result of scoring a post's prompt and media for unsafe content
+type ModerationVerdict struct {
+	PostID            string             `json:"post_id"`
+	Flagged           bool               `json:"flagged"`
+	Categories        map[string]float64 `json:"categories"` // harm category -> score in [0, 1]
+	FlaggedCategories []string           `json:"flagged_categories,omitempty"`
+	Blocked           bool               `json:"blocked"` // the safety filter refused the content outright
+	CheckedAt         time.Time          `json:"checked_at"`
+}
+
+// CreatorTierChange announces that a creator moved to another tier (new, emerging, established
+// or star), with the metrics behind the new tier
+type CreatorTierChange struct {
+	UserID         string    `json:"user_id"`
+	PreviousTier   string    `json:"previous_tier"`
+	Tier           string    `json:"tier"`
+	PostCount      int       `json:"post_count"`
+	TotalViews     int64     `json:"total_views"`
+	FollowerCount  int       `json:"follower_count"`
+	ViralPostCount int       `json:"viral_post_count"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// EngagementAnomaly is a post whose engagement velocity departed sharply from its recent
+// baseline: a spike (often a bot ring) or a drop nothing in the pipeline explains
+type EngagementAnomaly struct {
+	PostID           string    `json:"post_id"`
+	Kind             string    `json:"kind"`              // spike or drop
+	Velocity         float64   `json:"velocity"`          // events per minute in the last window
+	ExpectedVelocity float64   `json:"expected_velocity"` // baseline events per minute
+	ZScore           float64   `json:"z_score"`
+	WindowSeconds    int       `json:"window_seconds"`
+	DetectedAt       time.Time `json:"detected_at"`
+}
+
+// PartnerEngagementEvent is the engagement a post received in one window, as shared with a
+// data partner: counts only, rounded down to the stream's bucket size, and no user IDs
+type PartnerEngagementEvent struct {
+	PartnerID   string           `json:"partner_id"`
+	PostID      string           `json:"post_id"`
+	ContentType string           `json:"content_type"`
+	Counts      map[string]int64 `json:"counts"` // views, likes, comments, shares, remixes
+	Bucket      int64            `json:"bucket"` // counts are multiples of this
+	WindowStart time.Time        `json:"window_start"`
+	WindowEnd   time.Time        `json:"window_end"`
+}
+
+// ViralAlert announces that a post's viral probability reached a higher alert tier
+type ViralAlert struct {
+	PostID           string    `json:"post_id"`
+	Tier             string    `json:"tier"`
+	PreviousTier     string    `json:"previous_tier"` // empty when the post had no tier
+	ViralProbability float64   `json:"viral_probability"`
+	Score            float64   `json:"score"`
+	AlertedAt        time.Time `json:"alerted_at"`
+}
+
+// HubBroadcast is a WebSocket hub message relayed through Kafka to every service instance, so
+// clients receive it whichever instance they are connected to
+type HubBroadcast struct {
+	Message json.RawMessage `json:"message"`           // the message as sent to clients
+	Key     string          `json:"key,omitempty"`     // queued messages with the same key are coalesced
+	UserID  string          `json:"user_id,omitempty"` // only this user's connections receive it
+	SentAt  time.Time       `json:"sent_at"`
+}
+
+// PostEmbedding is the text embedding of a post's prompt and keywords
+type PostEmbedding struct {
+	PostID      string    `json:"post_id"`
+	ContentType string    `json:"content_type"`
+	Model       string    `json:"model"`
+	Vector      []float64 `json:"vector"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Original post this post near-duplicates, empty for original content
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+// SimilarPost is a post ranked by semantic similarity to another post
+type SimilarPost struct {
+	PostID      string  `json:"post_id"`
+	ContentType string  `json:"content_type"`
+	Similarity  float64 `json:"similarity"`
+}
+
+// DuplicateMatch links a near-duplicate post to the original it copies. MatchedPostID is the
+// post it was found to resemble, which is the original itself or another duplicate of it.
+type DuplicateMatch struct {
+	PostID        string    `json:"post_id"`
+	OriginalID    string    `json:"original_id"`
+	MatchedPostID string    `json:"matched_post_id"`
+	Similarity    float64   `json:"similarity"`
+	DetectedAt    time.Time `json:"detected_at"`
 }
 
 // TrendingScore represents calculated trending metrics
@@ -56,6 +222,21 @@ type TrendingScore struct {
 	EngagementVelocity float64  `json:"engagement_velocity"` // interactions per minute
 	CalculatedAt      time.Time `json:"calculated_at"`
 	TimeWindow        string    `json:"time_window"` // 1min, 5min, 1hour
+
+	// Highest viral alert tier the post's probability reaches (warming, trending, viral,
+	// mega_viral); empty below every tier
+	ViralTier string `json:"viral_tier,omitempty"`
+
+	// Original post when the post is a near-duplicate (enriched from the posts collection)
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+
+	// Optimistic concurrency bookkeeping, bumped on every versioned write
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// X-Request-ID of the API request behind the latest event-driven update, so a client's
+	// request can be followed to the score it changed
+	LastRequestID string `json:"last_request_id,omitempty"`
 	
 	// Post content fields (enriched from posts collection)
 	ContentType   string   `json:"content_type,omitempty"`
@@ -63,6 +244,73 @@ type TrendingScore struct {
 	Title         string   `json:"title,omitempty"`
 	Description   string   `json:"description,omitempty"`
 	Instructions  string   `json:"instructions,omitempty"`
+
+	// Media preview fields (enriched from the post's media metadata)
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	ThumbnailURL    string  `json:"thumbnail_url,omitempty"`
+	DominantColor   string  `json:"dominant_color,omitempty"`
+
+	// Running mean of comment sentiment in [-1, 1] and the number of comments scored
+	SentimentScore float64 `json:"sentiment_score,omitempty"`
+	SentimentCount int64   `json:"sentiment_count,omitempty"`
+
+	// Running mean of the share of the media each timed view watched, in [0, 1], and the
+	// number of views that reported a watch time while the media's duration was known.
+	// DurationSeconds is kept alongside them.
+	CompletionRate float64 `json:"completion_rate,omitempty"`
+	TimedViewCount int64   `json:"timed_view_count,omitempty"`
+
+	// Approximate number of distinct users who viewed the post, estimated from a HyperLogLog
+	// sketch of its viewers
+	UniqueViewers int64 `json:"unique_viewers,omitempty"`
+
+	// Completion rate relative to what is typical for the media's length (1 = typical);
+	// filled in for post stats, not stored
+	RelativeCompletion float64 `json:"relative_completion,omitempty"`
+
+	// Seconds watched over all views that reported a watch time, whether or not the media's
+	// duration is known, and the number of those views
+	WatchSeconds     float64 `json:"watch_seconds,omitempty"`
+	WatchedViewCount int64   `json:"watched_view_count,omitempty"`
+
+	// Mean seconds watched per view that reported a watch time; filled in for post stats, not
+	// stored
+	AvgWatchSeconds float64 `json:"avg_watch_seconds,omitempty"`
+
+	// Facets the trending feed and dashboards filter on, copied from the post so the filters
+	// run as indexed queries: the category, the lowercase English keywords, the creator and
+	// the lowercase style
+	Category  string   `json:"category,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"`
+	CreatorID string   `json:"creator_id,omitempty"`
+	Style     string   `json:"style,omitempty"`
+
+	// When the post was published, so keyword analytics can select the posts of a window;
+	// stored only
+	PublishedAt time.Time `json:"-"`
+
+	// Score under the experimental shadow formula and the fingerprint of that formula, for
+	// comparing formulas; stored only
+	ShadowScore   float64 `json:"-"`
+	ShadowFormula string  `json:"-"`
+
+	// Manual trending override of the post; filled in for the trending feeds, not stored
+	Override *TrendingOverride `json:"override,omitempty" firestore:"-"`
+}
+
+// TrendingOverride is a manual trending control of a post set by an admin: pinned posts lead
+// the trending feeds, excluded posts are kept out of them, and the multiplier scales the
+// calculated score
+type TrendingOverride struct {
+	PostID     string    `json:"post_id"`
+	Pinned     bool      `json:"pinned"`
+	Excluded   bool      `json:"excluded"`
+	Multiplier float64   `json:"multiplier"` // 1 leaves the score as calculated
+	Reason     string    `json:"reason,omitempty"`
+	UpdatedBy  string    `json:"updated_by,omitempty"` // who set it, as in the access log
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 // Recommendation represents a personalized content recommendation
@@ -73,6 +321,24 @@ type Recommendation struct {
 	Reason       string    `json:"reason"`
 	Category     string    `json:"category"`
 	GeneratedAt  time.Time `json:"generated_at"`
+
+	// Recommender that generated it: empty for the recommendations topic, or one of the
+	// in-service recommenders
+	Source string `json:"source,omitempty"`
+
+	// When it stops being served; zero when it was stored without an expiry
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RecommendationFeedbackEvent is a user's reaction to a recommended post: it was shown,
+// clicked or dismissed, or its creator was hidden. The creator is looked up from the post
+// when CreatorID is not given.
+type RecommendationFeedbackEvent struct {
+	UserID     string    `json:"user_id" binding:"required,max=128"`
+	PostID     string    `json:"post_id" binding:"required,max=128"`
+	CreatorID  string    `json:"creator_id,omitempty" binding:"max=128"`
+	Action     string    `json:"action" binding:"required,oneof=impression click dismiss hide-creator"`
+	OccurredAt time.Time `json:"occurred_at"`
 }
 
 // KeywordExtractionRequest for Vertex AI
@@ -83,10 +349,29 @@ type KeywordExtractionRequest struct {
 
 // KeywordExtractionResponse from Vertex AI
 type KeywordExtractionResponse struct {
-	Keywords []string `json:"keywords"`
-	Category string   `json:"category"`
-	Style    string   `json:"style"`
+	Keywords []string `json:"keywords"` // in the language of the prompt
+	Hashtags []string `json:"hashtags"`
+	Category string   `json:"category"` // always English
+	Style    string   `json:"style"`    // always English
 	Mood     string   `json:"mood"`
+
+	Language        string   `json:"language"`         // ISO 639-1 code of the prompt
+	EnglishKeywords []string `json:"english_keywords"` // keywords translated to English
+}
+
+// VisualAnalysis from Gemini vision, in English
+type VisualAnalysis struct {
+	Keywords       []string `json:"keywords"`
+	Objects        []string `json:"objects"`
+	DominantColors []string `json:"dominant_colors"` // color names, most prominent first
+	NSFWLikelihood float64  `json:"nsfw_likelihood"` // 0 (safe) to 1 (explicit)
+}
+
+// SentimentResult from Vertex AI
+type SentimentResult struct {
+	Score      float64 `json:"score"` // -1 (very negative) to 1 (very positive)
+	Label      string  `json:"label"` // positive, neutral, negative
+	Confidence float64 `json:"confidence"`
 }
 
 // ViralPredictionRequest for Vertex AI
@@ -100,6 +385,14 @@ type ViralPredictionRequest struct {
 	EngagementVelocity float64 `json:"engagement_velocity"`
 	TimeElapsed        int     `json:"time_elapsed"` // minutes since creation
 	ContentType        string  `json:"content_type"`
+
+	// Historical features from the previously stored score, zero when unknown
+	PreviousScore            float64 `json:"previous_score,omitempty"`
+	PreviousViralProbability float64 `json:"previous_viral_probability,omitempty"`
+
+	// Aggregate comment sentiment in [-1, 1] and the number of comments behind it
+	SentimentScore float64 `json:"sentiment_score,omitempty"`
+	SentimentCount int64   `json:"sentiment_count,omitempty"`
 }
 
 // ViralPredictionResponse from Vertex AI
@@ -107,4 +400,18 @@ type ViralPredictionResponse struct {
 	ViralProbability float64 `json:"viral_probability"`
 	Confidence       float64 `json:"confidence"`
 	PredictedPeakTime int    `json:"predicted_peak_time"` // minutes from now
+	Source            string `json:"source,omitempty"`     // heuristic, gemini or endpoint
+}
+
+// PredictionComparison holds the latest outputs of the model-based and the heuristic viral
+// predictors for a post while both run side by side
+type PredictionComparison struct {
+	PostID               string    `json:"post_id"`
+	Model                string    `json:"model"`  // gemini or endpoint
+	Served               string    `json:"served"` // predictor whose output was used
+	ModelProbability     float64   `json:"model_probability"`
+	HeuristicProbability float64   `json:"heuristic_probability"`
+	Difference           float64   `json:"difference"` // model minus heuristic
+	Agree                bool      `json:"agree"`      // both on the same side of the viral threshold
+	ComparedAt           time.Time `json:"compared_at"`
 }
@@ -0,0 +1,277 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: viral/v1/viral.proto
+
+package viralv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ViralIntelligence_SubscribeTrending_FullMethodName    = "/viral.v1.ViralIntelligence/SubscribeTrending"
+	ViralIntelligence_SubscribeViralAlerts_FullMethodName = "/viral.v1.ViralIntelligence/SubscribeViralAlerts"
+	ViralIntelligence_IngestEvents_FullMethodName         = "/viral.v1.ViralIntelligence/IngestEvents"
+)
+
+// ViralIntelligenceClient is the client API for ViralIntelligence service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ViralIntelligenceClient interface {
+	// SubscribeTrending streams trending score updates as they are broadcast
+	SubscribeTrending(ctx context.Context, in *SubscribeTrendingRequest, opts ...grpc.CallOption) (ViralIntelligence_SubscribeTrendingClient, error)
+	// SubscribeViralAlerts streams the alerts of posts reaching a higher viral tier
+	SubscribeViralAlerts(ctx context.Context, in *SubscribeViralAlertsRequest, opts ...grpc.CallOption) (ViralIntelligence_SubscribeViralAlertsClient, error)
+	// IngestEvents processes a stream of events, answering each with the outcome under its
+	// request ID
+	IngestEvents(ctx context.Context, opts ...grpc.CallOption) (ViralIntelligence_IngestEventsClient, error)
+}
+
+type viralIntelligenceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewViralIntelligenceClient(cc grpc.ClientConnInterface) ViralIntelligenceClient {
+	return &viralIntelligenceClient{cc}
+}
+
+func (c *viralIntelligenceClient) SubscribeTrending(ctx context.Context, in *SubscribeTrendingRequest, opts ...grpc.CallOption) (ViralIntelligence_SubscribeTrendingClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ViralIntelligence_ServiceDesc.Streams[0], ViralIntelligence_SubscribeTrending_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &viralIntelligenceSubscribeTrendingClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ViralIntelligence_SubscribeTrendingClient interface {
+	Recv() (*TrendingUpdate, error)
+	grpc.ClientStream
+}
+
+type viralIntelligenceSubscribeTrendingClient struct {
+	grpc.ClientStream
+}
+
+func (x *viralIntelligenceSubscribeTrendingClient) Recv() (*TrendingUpdate, error) {
+	m := new(TrendingUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *viralIntelligenceClient) SubscribeViralAlerts(ctx context.Context, in *SubscribeViralAlertsRequest, opts ...grpc.CallOption) (ViralIntelligence_SubscribeViralAlertsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ViralIntelligence_ServiceDesc.Streams[1], ViralIntelligence_SubscribeViralAlerts_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &viralIntelligenceSubscribeViralAlertsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ViralIntelligence_SubscribeViralAlertsClient interface {
+	Recv() (*ViralAlertUpdate, error)
+	grpc.ClientStream
+}
+
+type viralIntelligenceSubscribeViralAlertsClient struct {
+	grpc.ClientStream
+}
+
+func (x *viralIntelligenceSubscribeViralAlertsClient) Recv() (*ViralAlertUpdate, error) {
+	m := new(ViralAlertUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *viralIntelligenceClient) IngestEvents(ctx context.Context, opts ...grpc.CallOption) (ViralIntelligence_IngestEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ViralIntelligence_ServiceDesc.Streams[2], ViralIntelligence_IngestEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &viralIntelligenceIngestEventsClient{stream}
+	return x, nil
+}
+
+type ViralIntelligence_IngestEventsClient interface {
+	Send(*IngestEventRequest) error
+	Recv() (*IngestEventResponse, error)
+	grpc.ClientStream
+}
+
+type viralIntelligenceIngestEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *viralIntelligenceIngestEventsClient) Send(m *IngestEventRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *viralIntelligenceIngestEventsClient) Recv() (*IngestEventResponse, error) {
+	m := new(IngestEventResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ViralIntelligenceServer is the server API for ViralIntelligence service.
+// All implementations must embed UnimplementedViralIntelligenceServer
+// for forward compatibility
+type ViralIntelligenceServer interface {
+	// SubscribeTrending streams trending score updates as they are broadcast
+	SubscribeTrending(*SubscribeTrendingRequest, ViralIntelligence_SubscribeTrendingServer) error
+	// SubscribeViralAlerts streams the alerts of posts reaching a higher viral tier
+	SubscribeViralAlerts(*SubscribeViralAlertsRequest, ViralIntelligence_SubscribeViralAlertsServer) error
+	// IngestEvents processes a stream of events, answering each with the outcome under its
+	// request ID
+	IngestEvents(ViralIntelligence_IngestEventsServer) error
+	mustEmbedUnimplementedViralIntelligenceServer()
+}
+
+// UnimplementedViralIntelligenceServer must be embedded to have forward compatible implementations.
+type UnimplementedViralIntelligenceServer struct {
+}
+
+func (UnimplementedViralIntelligenceServer) SubscribeTrending(*SubscribeTrendingRequest, ViralIntelligence_SubscribeTrendingServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeTrending not implemented")
+}
+func (UnimplementedViralIntelligenceServer) SubscribeViralAlerts(*SubscribeViralAlertsRequest, ViralIntelligence_SubscribeViralAlertsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeViralAlerts not implemented")
+}
+func (UnimplementedViralIntelligenceServer) IngestEvents(ViralIntelligence_IngestEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method IngestEvents not implemented")
+}
+func (UnimplementedViralIntelligenceServer) mustEmbedUnimplementedViralIntelligenceServer() {}
+
+// UnsafeViralIntelligenceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ViralIntelligenceServer will
+// result in compilation errors.
+type UnsafeViralIntelligenceServer interface {
+	mustEmbedUnimplementedViralIntelligenceServer()
+}
+
+func RegisterViralIntelligenceServer(s grpc.ServiceRegistrar, srv ViralIntelligenceServer) {
+	s.RegisterService(&ViralIntelligence_ServiceDesc, srv)
+}
+
+func _ViralIntelligence_SubscribeTrending_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeTrendingRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ViralIntelligenceServer).SubscribeTrending(m, &viralIntelligenceSubscribeTrendingServer{stream})
+}
+
+type ViralIntelligence_SubscribeTrendingServer interface {
+	Send(*TrendingUpdate) error
+	grpc.ServerStream
+}
+
+type viralIntelligenceSubscribeTrendingServer struct {
+	grpc.ServerStream
+}
+
+func (x *viralIntelligenceSubscribeTrendingServer) Send(m *TrendingUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ViralIntelligence_SubscribeViralAlerts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeViralAlertsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ViralIntelligenceServer).SubscribeViralAlerts(m, &viralIntelligenceSubscribeViralAlertsServer{stream})
+}
+
+type ViralIntelligence_SubscribeViralAlertsServer interface {
+	Send(*ViralAlertUpdate) error
+	grpc.ServerStream
+}
+
+type viralIntelligenceSubscribeViralAlertsServer struct {
+	grpc.ServerStream
+}
+
+func (x *viralIntelligenceSubscribeViralAlertsServer) Send(m *ViralAlertUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ViralIntelligence_IngestEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ViralIntelligenceServer).IngestEvents(&viralIntelligenceIngestEventsServer{stream})
+}
+
+type ViralIntelligence_IngestEventsServer interface {
+	Send(*IngestEventResponse) error
+	Recv() (*IngestEventRequest, error)
+	grpc.ServerStream
+}
+
+type viralIntelligenceIngestEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *viralIntelligenceIngestEventsServer) Send(m *IngestEventResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *viralIntelligenceIngestEventsServer) Recv() (*IngestEventRequest, error) {
+	m := new(IngestEventRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ViralIntelligence_ServiceDesc is the grpc.ServiceDesc for ViralIntelligence service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ViralIntelligence_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "viral.v1.ViralIntelligence",
+	HandlerType: (*ViralIntelligenceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeTrending",
+			Handler:       _ViralIntelligence_SubscribeTrending_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeViralAlerts",
+			Handler:       _ViralIntelligence_SubscribeViralAlerts_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "IngestEvents",
+			Handler:       _ViralIntelligence_IngestEvents_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "viral/v1/viral.proto",
+}
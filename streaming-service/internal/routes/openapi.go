@@ -0,0 +1,537 @@
+package routes
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/handlers"
+	"confluent-viral-intelligence/internal/models"
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Paths of the OpenAPI spec and the interactive docs, outside the versioned API
+const (
+	SpecPath = "/api/openapi.json"
+	DocsPath = "/docs"
+)
+
+// operation documents one API route for the OpenAPI spec
+type operation struct {
+	method  string
+	path    string // under the API root, with {name} path parameters
+	tag     string
+	summary string
+	role    string // API key role the route requires, "" when it is open
+	limited bool   // whether the route is subject to the request rate limits
+	writes  bool   // whether the route is served by processing instances only
+	export  bool   // whether ?format=csv|xlsx exports the rows as a spreadsheet
+	stream  bool   // whether the route answers with Server-Sent Events
+	params  []parameter
+	body    interface{} // the JSON request body, nil when there is none
+	data    interface{} // the "data" field of the response, nil when there is none
+}
+
+// parameter documents a path or query parameter of an operation
+type parameter struct {
+	name        string
+	in          string
+	schemaType  string
+	description string
+	def         string
+	enum        []string
+}
+
+// pathParam is a required path parameter
+func pathParam(name, description string) parameter {
+	return parameter{name: name, in: "path", schemaType: "string", description: description}
+}
+
+// query is an optional query parameter with an optional default
+func query(name, schemaType, description, def string, enum ...string) parameter {
+	return parameter{name: name, in: "query", schemaType: schemaType, description: description, def: def, enum: enum}
+}
+
+// Query parameters shared by the dashboard reports
+var (
+	windowParams = []parameter{
+		query("window", "string", "Time range ending now, such as 24h or 7d. All time when neither window nor from is set.", ""),
+		query("from", "string", "Start of the time range, YYYY-MM-DD or RFC 3339", ""),
+		query("to", "string", "End of the time range, YYYY-MM-DD or RFC 3339. Defaults to now.", ""),
+	}
+	filterParams = []parameter{
+		query("contentType", "string", "Only posts of this content type", "", "image", "video", "music", "voice"),
+		query("category", "string", "Only posts of this category", ""),
+		query("keyword", "string", "Only posts with this keyword", ""),
+	}
+	fieldsParam = query("fields", "string", "Comma-separated fields of the returned items, such as post_id,score,thumbnail_url", "")
+	formatParam = query("format", "string", "Export the rows as a spreadsheet instead of JSON", "json", "json", "csv", "xlsx")
+	tiers       = []string{services.CreatorTierNew, services.CreatorTierEmerging, services.CreatorTierEstablished, services.CreatorTierStar}
+)
+
+func params(groups ...[]parameter) []parameter {
+	var all []parameter
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	return all
+}
+
+// operations documents every API route, in the order they are registered
+var operations = []operation{
+	{method: "GET", path: "/stream", tag: "stream", summary: "Stream the WebSocket broadcasts as Server-Sent Events", stream: true,
+		params: []parameter{
+			query("last_seq", "integer", "Resume after this broadcast seq; browsers send Last-Event-ID instead", ""),
+			query("token", "string", "Firebase ID token, when the Authorization header cannot be set", ""),
+		}},
+
+	// Event ingestion
+	{method: "POST", path: "/events/interaction", tag: "events", summary: "Record a view, like, comment or share", role: services.RoleIngest, limited: true, writes: true, body: models.InteractionEvent{}},
+	{method: "POST", path: "/events/content", tag: "events", summary: "Record a new post and extract its keywords; with ?async=true or Prefer: respond-async, answer 202 with an operation to poll", role: services.RoleIngest, limited: true, writes: true, body: models.ContentMetadata{},
+		params: []parameter{query("async", "boolean", "Queue the keyword extraction and answer 202 with the operation at once", "false")}},
+	{method: "POST", path: "/events/view", tag: "events", summary: "Record a view with its watch time and audience segment", role: services.RoleIngest, limited: true, writes: true, body: models.ViewEvent{}},
+	{method: "POST", path: "/events/remix", tag: "events", summary: "Record a remix of a post", role: services.RoleIngest, limited: true, writes: true, body: models.RemixEvent{}},
+	{method: "POST", path: "/events/comment", tag: "events", summary: "Record a comment on a post", role: services.RoleIngest, limited: true, writes: true, body: models.CommentEvent{}},
+	{method: "POST", path: "/events/recommendation-feedback", tag: "events", summary: "Record an impression, click or dismissal of a recommended post, or hide its creator; re-ranks the user's next recommendations", role: services.RoleIngest, limited: true, writes: true, body: models.RecommendationFeedbackEvent{}},
+	{method: "GET", path: "/operations/{id}", tag: "events", summary: "Status and result of ingestion accepted with 202", role: services.RoleIngest, writes: true,
+		params: []parameter{pathParam("id", "Operation ID")},
+		data:   services.Operation{}},
+
+	// Analytics
+	{method: "GET", path: "/analytics/trending", tag: "analytics", summary: "Trending posts, filled up with recent posts when too few trend", role: services.RoleRead, limited: true, export: true,
+		params: params([]parameter{
+			query("limit", "integer", "Number of posts, 1-100", "20"),
+			query("fields", "string", "Comma-separated fields to return, such as id,score,output_urls", ""),
+			query("creatorTier", "string", "Only posts by creators of this tier", "", tiers...),
+			query("region", "string", "Rank by engagement from a country (DE) or region group (EU)", ""),
+			formatParam,
+		}, filterParams),
+		data: []models.TrendingScore{}},
+	{method: "GET", path: "/analytics/trending-hashtags", tag: "analytics", summary: "Hashtags gaining the most engagement", role: services.RoleRead, limited: true,
+		params: []parameter{
+			query("window", "string", "Time range ending now, such as 6h or 1d", ""),
+			query("limit", "integer", "Number of hashtags, 1-100", "20"),
+		},
+		data: []services.TrendingHashtag{}},
+	{method: "GET", path: "/analytics/keywords", tag: "analytics", summary: "Performance of keywords, categories or styles", role: services.RoleRead, limited: true,
+		params: []parameter{
+			query("by", "string", "Theme to group posts by", services.ThemeKeyword, services.ThemeKeyword, services.ThemeCategory, services.ThemeStyle),
+			query("window", "string", "Time range ending now, up to 90d", "30d"),
+			query("sort", "string", "Ranking of the themes", services.ThemeSortAvgScore),
+			query("minPosts", "integer", "Fewest posts a theme needs to be ranked, 1-1000", "3"),
+			query("limit", "integer", "Number of themes, 1-100", "20"),
+		},
+		data: []services.ThemePerformance{}},
+	{method: "GET", path: "/analytics/rising", tag: "analytics", summary: "Posts whose trending score grew most over the last hours", role: services.RoleRead, limited: true,
+		params: []parameter{
+			query("hours", "integer", "Hours to measure the growth over", "6"),
+			query("limit", "integer", "Number of posts, 1-100", "20"),
+			query("sort", "string", "Rank by absolute growth or growth per hour", services.RisingByDelta, services.RisingByDelta, services.RisingByVelocity),
+		},
+		data: []services.RisingPost{}},
+	{method: "GET", path: "/analytics/post/{id}/stats", tag: "posts", summary: "Trending score and engagement of a post", role: services.RoleRead, limited: true,
+		params: []parameter{pathParam("id", "Post ID")},
+		data:   models.TrendingScore{}},
+	{method: "GET", path: "/analytics/post/{id}/timeseries", tag: "posts", summary: "Engagement of a post per hour or day", role: services.RoleRead, limited: true,
+		params: []parameter{
+			pathParam("id", "Post ID"),
+			query("interval", "string", "Length of each bucket", services.RollupDay, services.RollupHour, services.RollupDay),
+			query("days", "integer", "Days of history", "7"),
+		},
+		data: []services.EngagementRollup{}},
+	{method: "GET", path: "/analytics/post/{id}/history", tag: "posts", summary: "Trending score history of a post", role: services.RoleRead, limited: true,
+		params: []parameter{
+			pathParam("id", "Post ID"),
+			query("days", "integer", "Days of history", "7"),
+			query("points", "integer", "Number of points to downsample to, 2-500", "48"),
+		},
+		data: []services.ScoreHistoryPoint{}},
+	{method: "GET", path: "/analytics/compare", tag: "posts", summary: "Engagement curves of several posts by post age", role: services.RoleRead, limited: true,
+		params: []parameter{
+			query("posts", "string", "Comma-separated post IDs", ""),
+			query("hours", "integer", "Hours after publishing to compare", "48"),
+		},
+		data: services.PostComparison{}},
+	{method: "GET", path: "/analytics/similar/{postId}", tag: "posts", summary: "Posts with similar content; 202 while the post's embedding is pending", role: services.RoleRead, limited: true,
+		params: []parameter{pathParam("postId", "Post ID"), query("limit", "integer", "Number of posts, 1-50", "10")},
+		data:   []models.SimilarPost{}},
+	{method: "GET", path: "/analytics/user/{id}/recommendations", tag: "users", summary: "Posts recommended to a user", role: services.RoleRead, limited: true,
+		params: []parameter{pathParam("id", "User ID"), query("limit", "integer", "Number of posts, 1-50", "10")},
+		data:   []models.Recommendation{}},
+	{method: "GET", path: "/analytics/user/{id}/remix-suggestions", tag: "users", summary: "Trending posts a user could remix", role: services.RoleRead, limited: true,
+		params: []parameter{pathParam("id", "User ID"), query("limit", "integer", "Number of suggestions, 1-50", "10")},
+		data:   []services.RemixSuggestion{}},
+	{method: "GET", path: "/analytics/creator/{id}", tag: "creators", summary: "Performance of a creator and their top posts", role: services.RoleRead, limited: true,
+		params: []parameter{
+			pathParam("id", "Creator ID"),
+			query("posts", "integer", "Number of top posts, 1-100", "10"),
+			query("days", "integer", "Days of score history, 1-90", "30"),
+		},
+		data: services.CreatorAnalytics{}},
+	{method: "GET", path: "/analytics/creator/{id}/audience-overlap", tag: "creators", summary: "Creators sharing the most viewers with a creator", role: services.RoleRead, limited: true,
+		params: []parameter{pathParam("id", "Creator ID"), query("limit", "integer", "Number of creators, 1-50", "10")},
+		data:   services.AudienceOverlap{}},
+	{method: "GET", path: "/analytics/creator/{id}/suggestions", tag: "creators", summary: "Coaching suggestions for a creator", role: services.RoleRead, limited: true,
+		params: []parameter{pathParam("id", "Creator ID")},
+		data:   services.CreatorCoaching{}},
+	{method: "GET", path: "/analytics/creators/rising", tag: "creators", summary: "Creators whose engagement grew most week over week", role: services.RoleRead, limited: true,
+		params: []parameter{query("limit", "integer", "Number of creators, 1-50", "10")},
+		data:   []services.RisingCreator{}},
+	{method: "GET", path: "/analytics/leaderboards/{period}", tag: "creators", summary: "Creator leaderboard of a day, week or month", role: services.RoleRead, limited: true,
+		params: []parameter{
+			{name: "period", in: "path", schemaType: "string", description: "Leaderboard period", enum: []string{"day", "week", "month"}},
+			query("limit", "integer", "Number of creators, 1-100", "10"),
+			query("date", "string", "A past YYYY-MM-DD date within the period. Defaults to the current period.", ""),
+		},
+		data: services.Leaderboard{}},
+	{method: "GET", path: "/analytics/prediction-accuracy", tag: "predictions", summary: "How well viral predictions matched the outcome", role: services.RoleRead, limited: true,
+		params: []parameter{
+			query("days", "integer", "Days of predictions, 1-90", "30"),
+			query("source", "string", "Only predictions of this predictor", ""),
+		},
+		data: services.PredictionAccuracy{}},
+	{method: "GET", path: "/analytics/predictor-comparison", tag: "predictions", summary: "Accuracy of the AI and heuristic predictors side by side", role: services.RoleRead, limited: true,
+		params: []parameter{
+			query("days", "integer", "Days of predictions, 1-90", "7"),
+			query("limit", "integer", "Number of disagreements to list, 0-100", "20"),
+		},
+		data: services.PredictorComparisonReport{}},
+
+	// Dashboard analytics
+	{method: "GET", path: "/analytics/dashboard/metrics", tag: "dashboard", summary: "Totals and engagement of the platform", role: services.RoleRead, limited: true,
+		params: windowParams, data: services.DashboardMetrics{}},
+	{method: "GET", path: "/analytics/dashboard/top-creators", tag: "dashboard", summary: "Creators with the most engagement", role: services.RoleRead, limited: true, export: true,
+		params: params([]parameter{
+			query("limit", "integer", "Number of creators, 1-50", "10"),
+			query("tier", "string", "Only creators of this tier", "", tiers...),
+			formatParam,
+		}, windowParams, filterParams),
+		data: []services.CreatorMetrics{}},
+	{method: "GET", path: "/analytics/dashboard/content-types", tag: "dashboard", summary: "Engagement per content type", role: services.RoleRead, limited: true, export: true,
+		params: params(windowParams, []parameter{formatParam}),
+		data:   map[string]services.ContentTypeMetrics{}},
+	{method: "GET", path: "/analytics/dashboard/trends", tag: "dashboard", summary: "Engagement per day", role: services.RoleRead, limited: true, export: true,
+		params: params([]parameter{
+			query("days", "integer", "Days of history, 1-30", "7"),
+			query("tz", "string", "IANA time zone the days are cut in, such as America/New_York", ""),
+			formatParam,
+		}, windowParams, filterParams),
+		data: []services.EngagementTrend{}},
+	{method: "GET", path: "/analytics/dashboard/retention", tag: "dashboard", summary: "Share of viewers returning on the days after their first view", role: services.RoleRead, limited: true,
+		params: []parameter{
+			query("days", "integer", "Days of cohorts, 1-30", "14"),
+			query("postId", "string", "Only viewers of this post", ""),
+		},
+		data: services.ViewerRetention{}},
+	{method: "GET", path: "/analytics/dashboard/active-users", tag: "dashboard", summary: "Daily, weekly and monthly active users", role: services.RoleRead, limited: true,
+		data: services.ActiveUsers{}},
+	{method: "GET", path: "/analytics/dashboard/segments", tag: "dashboard", summary: "Views and watch time per platform, device, country or region", role: services.RoleRead, limited: true,
+		params: params([]parameter{
+			query("groupBy", "string", "Segment dimension", "", "platform", "device", "country", "region"),
+			query("platform", "string", "Only views on this platform", ""),
+			query("device", "string", "Only views on this device type", ""),
+			query("region", "string", "Only views from this country or region", ""),
+		}, windowParams),
+		data: services.SegmentBreakdown{}},
+
+	// Pipeline metrics
+	{method: "GET", path: "/metrics/pipeline-latency", tag: "metrics", summary: "Latency of each pipeline stage", role: services.RoleRead,
+		data: map[string]services.LatencySnapshot{}},
+	{method: "GET", path: "/metrics/websocket-clients", tag: "metrics", summary: "Clients connected to this instance", role: services.RoleRead,
+		data: []services.WebSocketClientStats{}},
+
+	// Event payload schemas
+	{method: "GET", path: "/schema/events", tag: "schema", summary: "JSON Schemas of the WebSocket and webhook payloads", role: services.RoleRead,
+		params: []parameter{query("type", "string", "Only the schema of this message type", "")}},
+
+	// Admin operations
+	{method: "GET", path: "/admin/api-keys", tag: "admin", summary: "Configured API keys and their traffic", role: services.RoleAdmin,
+		data: struct {
+			Enabled bool                      `json:"enabled"`
+			Keys    []services.APIKey         `json:"keys"`
+			Traffic []services.RateLimitStats `json:"traffic"`
+		}{}},
+	{method: "GET", path: "/admin/rate-limits", tag: "admin", summary: "Request rate limits and the requests they limited", role: services.RoleAdmin,
+		data: struct {
+			Enabled bool                         `json:"enabled"`
+			Limits  services.RequestLimiterStats `json:"limits"`
+		}{}},
+	{method: "GET", path: "/admin/diagnostics", tag: "admin", summary: "Quota usage, caches and pipeline health", role: services.RoleAdmin},
+	{method: "GET", path: "/admin/circuit-breakers", tag: "admin", summary: "State of the circuit breakers guarding external dependencies", role: services.RoleAdmin,
+		data: []services.BreakerState{}},
+	{method: "GET", path: "/admin/ai/usage", tag: "admin", summary: "AI token usage and estimated cost per day", role: services.RoleAdmin,
+		params: []parameter{query("days", "integer", "Days of usage, 1-30", "7")},
+		data: struct {
+			Days  []services.AIUsageDay `json:"days"`
+			Total services.AIUsage      `json:"total"`
+		}{}},
+	{method: "GET", path: "/admin/posts/{id}/trace", tag: "admin", summary: "Recorded audit trail of a post, newest first", role: services.RoleAdmin,
+		params: []parameter{pathParam("id", "Post ID"), query("limit", "integer", "Number of entries, 1-1000", "200")},
+		data:   []models.PostTraceEntry{}},
+	{method: "GET", path: "/admin/trending/overrides", tag: "admin", summary: "Trending overrides in effect, most recently updated first", role: services.RoleAdmin,
+		data: []models.TrendingOverride{}},
+	{method: "GET", path: "/admin/scoring/compare", tag: "admin", summary: "Rank correlation and top list differences of the live and shadow scoring formulas", role: services.RoleAdmin,
+		params: []parameter{query("limit", "integer", "Top posts sampled under either formula, 1-1000", "200"), query("top", "integer", "Size of the top lists compared, 1-100", "20")},
+		data:   services.ScoringComparison{}},
+	{method: "GET", path: "/admin/ws/clients", tag: "admin", summary: "Clients connected to this instance with their subscriptions", role: services.RoleAdmin,
+		data: []services.WebSocketClientStats{}},
+	{method: "DELETE", path: "/admin/ws/clients/{id}", tag: "admin", summary: "Disconnect a client from this instance", role: services.RoleAdmin,
+		params: []parameter{pathParam("id", "Client ID")}},
+	{method: "POST", path: "/webhooks", tag: "webhooks", summary: "Register a webhook; the response carries the secret deliveries are signed with", role: services.RoleAdmin, writes: true,
+		body: handlers.WebhookRequest{},
+		data: services.Webhook{}},
+	{method: "GET", path: "/webhooks", tag: "webhooks", summary: "Registered webhooks", role: services.RoleAdmin, writes: true,
+		data: []services.Webhook{}},
+	{method: "GET", path: "/webhooks/{id}", tag: "webhooks", summary: "A registered webhook", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Webhook ID")},
+		data:   services.Webhook{}},
+	{method: "PUT", path: "/webhooks/{id}", tag: "webhooks", summary: "Change the URL, events, threshold or state of a webhook", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Webhook ID")},
+		body:   handlers.WebhookRequest{},
+		data:   services.Webhook{}},
+	{method: "DELETE", path: "/webhooks/{id}", tag: "webhooks", summary: "Remove a webhook", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Webhook ID")}},
+	{method: "GET", path: "/webhooks/{id}/deliveries", tag: "webhooks", summary: "Latest deliveries of a webhook, newest first", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Webhook ID"), query("limit", "integer", "Number of deliveries, 1-200", "50")},
+		data:   []services.WebhookDelivery{}},
+	{method: "POST", path: "/webhooks/{id}/test", tag: "webhooks", summary: "Send a test event to a webhook and return how the delivery went", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Webhook ID")},
+		data:   services.WebhookDelivery{}},
+	{method: "POST", path: "/admin/index-posts", tag: "admin", summary: "Start indexing every post", role: services.RoleAdmin, writes: true},
+	{method: "POST", path: "/admin/extract-keywords", tag: "admin", summary: "Start extracting keywords for posts without any", role: services.RoleAdmin, writes: true},
+	{method: "GET", path: "/admin/extract-keywords/status", tag: "admin", summary: "Progress of the keyword backfill", role: services.RoleAdmin, writes: true,
+		data: services.KeywordBackfillStatus{}},
+	{method: "POST", path: "/admin/posts/{id}/trace", tag: "admin", summary: "Record the audit trail of a post for a while", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Post ID")},
+		body:   handlers.EnablePostTraceRequest{},
+		data:   models.PostTrace{}},
+	{method: "DELETE", path: "/admin/posts/{id}/trace", tag: "admin", summary: "Stop recording the audit trail of a post", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Post ID")}},
+	{method: "POST", path: "/admin/posts/{id}/recompute", tag: "admin", summary: "Recalculate a post's trending score and viral prediction from its post document", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Post ID")},
+		data:   services.PostRecomputation{}},
+	{method: "PUT", path: "/admin/trending/overrides/{id}", tag: "admin", summary: "Pin a post to the trending feeds, exclude it from them or scale its score", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Post ID")},
+		body:   handlers.TrendingOverrideRequest{},
+		data:   models.TrendingOverride{}},
+	{method: "DELETE", path: "/admin/trending/overrides/{id}", tag: "admin", summary: "Return a post to its calculated trending score", role: services.RoleAdmin, writes: true,
+		params: []parameter{pathParam("id", "Post ID")}},
+	{method: "POST", path: "/admin/archive-remix-chains", tag: "admin", summary: "Start archiving finished remix chains", role: services.RoleAdmin, writes: true},
+	{method: "POST", path: "/admin/classify-creator-tiers", tag: "admin", summary: "Start classifying creators into tiers", role: services.RoleAdmin, writes: true},
+}
+
+// Spec returns the OpenAPI 3.1 document of the API routes the configuration serves
+func Spec(cfg *config.Config) map[string]interface{} {
+	components := map[string]interface{}{
+		"Error": services.JSONSchema(struct {
+			Status    string                `json:"status"`
+			Code      string                `json:"code"`
+			Error     string                `json:"error"`
+			Details   []handlers.FieldError `json:"details,omitempty"`
+			RequestID string                `json:"request_id,omitempty"`
+		}{}),
+	}
+	paths := map[string]interface{}{}
+
+	for _, op := range operations {
+		if op.writes && cfg.IsReadReplica() {
+			continue
+		}
+		item, ok := paths[op.path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = op.spec(cfg, components)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":   "Viral Intelligence API",
+			"version": CurrentVersion,
+			"description": "Real-time engagement analytics and trend detection. The routes are also served " +
+				"under /api as deprecated aliases of the current version.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": "/api/" + versionPrefix + CurrentVersion}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": components,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// spec returns the OpenAPI operation object of op, adding the schemas it refers to to
+// components
+func (op operation) spec(cfg *config.Config, components map[string]interface{}) map[string]interface{} {
+	spec := map[string]interface{}{
+		"operationId": operationID(op.method, op.path),
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+	}
+
+	// Every analytics route selects fields
+	opParams := op.params
+	if strings.HasPrefix(op.path, "/analytics/") && !slices.ContainsFunc(opParams, func(p parameter) bool { return p.name == fieldsParam.name }) {
+		opParams = append(slices.Clip(opParams), fieldsParam)
+	}
+	if len(opParams) > 0 {
+		parameters := make([]interface{}, 0, len(opParams))
+		for _, p := range opParams {
+			schema := map[string]interface{}{"type": p.schemaType}
+			if p.def != "" {
+				schema["default"] = p.def
+			}
+			if len(p.enum) > 0 {
+				schema["enum"] = p.enum
+			}
+			parameters = append(parameters, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"required":    p.in == "path",
+				"schema":      schema,
+			})
+		}
+		spec["parameters"] = parameters
+	}
+
+	if op.body != nil {
+		spec["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(schemaRef(reflect.TypeOf(op.body), components)),
+		}
+	}
+
+	success := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"status"},
+	}
+	if op.data != nil {
+		properties := success["properties"].(map[string]interface{})
+		properties["data"] = schemaRef(reflect.TypeOf(op.data), components)
+		if kind := reflect.TypeOf(op.data).Kind(); kind == reflect.Slice || kind == reflect.Map {
+			properties["count"] = map[string]interface{}{"type": "integer"}
+		}
+	}
+	content := jsonContent(success)
+	switch {
+	case op.stream:
+		content = map[string]interface{}{"text/event-stream": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+	case op.export:
+		for _, format := range []string{services.ExportCSV, services.ExportXLSX} {
+			content[services.ExportContentType(format)] = map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}}
+		}
+	}
+
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"}),
+		}
+	}
+	responses := map[string]interface{}{
+		"200":     map[string]interface{}{"description": "Success", "content": content},
+		"default": errorResponse("Error"),
+	}
+	if len(opParams) > 0 || op.body != nil {
+		responses["400"] = errorResponse("Invalid request")
+	}
+	if op.limited {
+		responses["429"] = errorResponse("Too many requests, retry after the Retry-After header")
+	}
+
+	// Reads stay open unless configured otherwise
+	if op.role != "" && (op.role != services.RoleRead || cfg.APIKeyRequireRead) {
+		spec["security"] = []interface{}{map[string]interface{}{"apiKey": []string{}}}
+		spec["description"] = "Requires an API key with the " + op.role + " role."
+		responses["401"] = errorResponse("Missing or unknown API key")
+		responses["403"] = errorResponse("API key lacks the " + op.role + " role")
+	}
+	spec["responses"] = responses
+	return spec
+}
+
+// schemaRef returns the schema of a type, referring to named structs by their component
+func schemaRef(t reflect.Type, components map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaRef(t.Elem(), components)
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaRef(t.Elem(), components)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaRef(t.Elem(), components)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return services.JSONSchema(reflect.Zero(t).Interface())
+		}
+		if _, ok := components[t.Name()]; !ok {
+			components[t.Name()] = services.JSONSchema(reflect.Zero(t).Interface())
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return services.JSONSchema(reflect.Zero(t).Interface())
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// operationID names an operation after its method and path, e.g. getAnalyticsPostIdStats
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool { return strings.ContainsRune("/{}-", r) }) {
+		id += strings.ToUpper(word[:1]) + word[1:]
+	}
+	return id
+}
+
+// Undocumented returns the API routes of a router that the spec does not describe, as
+// "METHOD /path" under the API root
+func Undocumented(routes gin.RoutesInfo, spec map[string]interface{}) []string {
+	paths, _ := spec["paths"].(map[string]interface{})
+
+	var missing []string
+	for _, route := range routes {
+		path, ok := apiPath(route.Path)
+		if !ok {
+			continue
+		}
+		item, _ := paths[path].(map[string]interface{})
+		if _, documented := item[strings.ToLower(route.Method)]; !documented {
+			missing = append(missing, route.Method+" "+path)
+		}
+	}
+	slices.Sort(missing)
+	return slices.Compact(missing)
+}
+
+// apiPath returns a gin route path relative to the API root in OpenAPI form, or false for
+// routes outside the API
+func apiPath(path string) (string, bool) {
+	if path == SpecPath {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(path, "/api/"+versionPrefix+CurrentVersion+"/")
+	if !ok {
+		if rest, ok = strings.CutPrefix(path, "/api/"); !ok {
+			return "", false
+		}
+	}
+
+	segments := strings.Split(rest, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return "/" + strings.Join(segments, "/"), true
+}
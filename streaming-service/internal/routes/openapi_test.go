@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"confluent-viral-intelligence/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// registeredRoutes registers the API routes of cfg without their services
+func registeredRoutes(cfg *config.Config) gin.RoutesInfo {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	a := &api{cfg: cfg}
+	a.register(router.Group("/api/"+versionPrefix+CurrentVersion, Negotiate(CurrentVersion)))
+	a.register(router.Group("/api", Negotiate("")))
+	router.GET(SpecPath, func(c *gin.Context) {})
+	router.GET("/health", func(c *gin.Context) {})
+	return router.Routes()
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	for _, mode := range []string{"", config.RunModeReadReplica} {
+		cfg := &config.Config{RunMode: mode}
+		if missing := Undocumented(registeredRoutes(cfg), Spec(cfg)); len(missing) > 0 {
+			t.Errorf("Run mode %q: routes missing from the spec: %v", mode, missing)
+		}
+	}
+
+	replica := Spec(&config.Config{RunMode: config.RunModeReadReplica})
+	if _, ok := replica["paths"].(map[string]interface{})["/events/view"]; ok {
+		t.Error("Expected read replicas to leave ingestion out of the spec")
+	}
+}
+
+func TestUndocumented(t *testing.T) {
+	routes := gin.RoutesInfo{
+		{Method: http.MethodGet, Path: "/api/v1/analytics/post/:id/stats"},
+		{Method: http.MethodPut, Path: "/api/analytics/post/:id/stats"},
+		{Method: http.MethodGet, Path: "/api/v1/analytics/unknown"},
+		{Method: http.MethodGet, Path: "/api/analytics/unknown"},
+		{Method: http.MethodGet, Path: "/ws"},
+	}
+	missing := Undocumented(routes, Spec(&config.Config{}))
+	if len(missing) != 2 || missing[0] != "GET /analytics/unknown" || missing[1] != "PUT /analytics/post/{id}/stats" {
+		t.Errorf("Unexpected undocumented routes %v", missing)
+	}
+}
+
+func TestSpecSchemas(t *testing.T) {
+	spec := Spec(&config.Config{APIKeyRequireRead: true})
+	if _, err := json.Marshal(spec); err != nil {
+		t.Fatalf("Spec does not encode: %v", err)
+	}
+
+	paths := spec["paths"].(map[string]interface{})
+	view := paths["/events/view"].(map[string]interface{})["post"].(map[string]interface{})
+	if _, ok := view["security"]; !ok {
+		t.Error("Expected ingestion to require an API key")
+	}
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	viewEvent, ok := schemas["ViewEvent"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected the view event body as a component")
+	}
+	platform := viewEvent["properties"].(map[string]interface{})["platform"].(map[string]interface{})
+	if enum, _ := platform["enum"].([]interface{}); len(enum) != 2 {
+		t.Errorf("Expected the platforms of the binding tag, got %v", platform)
+	}
+	if required := viewEvent["required"].([]string); len(required) != 3 {
+		t.Errorf("Expected post_id, user_id and platform to be required, got %v", required)
+	}
+
+	trending := paths["/analytics/trending"].(map[string]interface{})["get"].(map[string]interface{})
+	if _, ok := trending["security"]; !ok {
+		t.Error("Expected reads to require an API key when configured")
+	}
+}
@@ -0,0 +1,363 @@
+package routes
+
+import (
+	"strings"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/graphqlapi"
+	"confluent-viral-intelligence/internal/handlers"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/services"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// Dependencies are the services behind the HTTP routes. Jobs and caches that are disabled
+// may be nil.
+type Dependencies struct {
+	Processor         *services.EventProcessor
+	WSHub             *services.WebSocketHub
+	PostIndexer       *services.PostIndexer
+	RemixArchiver     *services.RemixArchiver
+	KeywordBackfiller *services.KeywordBackfiller
+	TierClassifier    *services.CreatorTierClassifier
+	CacheMaintainer   *services.CacheMaintainer
+	APIKeys           *services.APIKeyStore
+	RequestLimiter    *services.RequestLimiter
+	Webhooks          *services.WebhookDispatcher
+	Operations        *services.OperationQueue
+	Health            *services.HealthChecker
+}
+
+// New returns the router of the HTTP API. Routes are served under /api/v1 and, as deprecated
+// aliases of the same version, under /api.
+func New(cfg *config.Config, deps Dependencies) *gin.Engine {
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// Access logs go through zerolog like every other log line. Errors, panics included, are
+	// answered with the APIError body.
+	router := gin.New()
+	router.Use(handlers.RequestID(), handlers.AccessLog(handlers.AccessLogOptions{
+		SampledPrefixes: []string{"/api/" + versionPrefix + CurrentVersion + "/events/", "/api/events/"},
+		SampleRate:      cfg.AccessLogIngestSampleRate,
+		SlowThreshold:   time.Duration(cfg.AccessLogSlowMs) * time.Millisecond,
+	}), handlers.HandleErrors())
+	// Bodies large enough to gain from it are compressed for clients that accept gzip or
+	// deflate
+	if cfg.ResponseCompressionMinBytes > 0 {
+		router.Use(handlers.Compress(handlers.CompressionOptions{
+			MinBytes: cfg.ResponseCompressionMinBytes,
+			Level:    cfg.ResponseCompressionLevel,
+		}))
+	}
+	if err := trustProxies(router, cfg.TrustedProxies); err != nil {
+		logger.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(handlers.RouteNotFound)
+	router.NoMethod(handlers.MethodNotAllowed)
+
+	// CORS configuration
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:     cfg.AllowedOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "Prefer", VersionHeader, handlers.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "Location", "Retry-After", VersionHeader, "Deprecation", "Sunset", "Link", handlers.RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// Health check; /health/live is the liveness probe, /health/ready the readiness probe that
+	// checks Kafka, Firestore and Vertex AI
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "healthy", "run_mode": cfg.RunMode})
+	})
+	health := handlers.NewHealthHandler(deps.Health, cfg.RunMode)
+	router.GET("/health/live", health.Live)
+	router.GET("/health/ready", health.Ready)
+
+	api := newAPI(cfg, deps)
+	api.register(router.Group("/api/"+versionPrefix+CurrentVersion, Negotiate(CurrentVersion)))
+	api.register(router.Group("/api", Negotiate(""), Deprecated(cfg.APILegacySunset)))
+
+	// WebSocket endpoint
+	router.GET("/ws", api.ws.HandleWebSocket)
+
+	// GraphQL queries and subscriptions over the analytics services, with the same access
+	// and rate limits as the REST analytics
+	gql := router.Group("/graphql", api.trackKeys, api.read, api.limited)
+	gql.GET("", api.graphql.Serve)
+	gql.POST("", api.graphql.Serve)
+	gql.GET("/schema", api.graphql.GetSchema)
+
+	// OpenAPI spec and interactive docs
+	spec := Spec(cfg)
+	docs := handlers.NewDocsHandler(spec, SpecPath)
+	router.GET(SpecPath, docs.GetSpec)
+	router.GET(DocsPath, docs.GetDocs)
+
+	// Routes added without documenting them show up at startup rather than in a client's
+	// bug report
+	if missing := Undocumented(router.Routes(), spec); len(missing) > 0 {
+		logger.Warnf("⚠️ %d API routes are missing from the OpenAPI spec: %s", len(missing), strings.Join(missing, ", "))
+	}
+
+	return router
+}
+
+// api holds the handlers of the API routes, created once and registered under every prefix
+// so the prefixes share their caches
+type api struct {
+	cfg  *config.Config
+	deps Dependencies
+
+	ws          *handlers.WebSocketHandler
+	events      *handlers.EventHandler
+	operations  *handlers.OperationHandler
+	analytics   *handlers.AnalyticsHandler
+	metrics     *handlers.MetricsHandler
+	schema      *handlers.SchemaHandler
+	diagnostics *handlers.DiagnosticsHandler
+	admin       *handlers.AdminHandler
+	webhooks    *handlers.WebhookHandler
+	graphql     *graphqlapi.Handler
+
+	trackKeys gin.HandlerFunc
+	limited   gin.HandlerFunc
+	read      gin.HandlerFunc
+	ingest    gin.HandlerFunc
+	adminOnly gin.HandlerFunc
+	cached    gin.HandlerFunc
+}
+
+func newAPI(cfg *config.Config, deps Dependencies) *api {
+	processor := deps.Processor
+	verifier := services.NewFirebaseTokenVerifier(cfg.FirebaseProjectID)
+
+	a := &api{
+		cfg:         cfg,
+		deps:        deps,
+		ws:          handlers.NewWebSocketHandler(deps.WSHub, cfg.WebSocketAuthMode(), verifier, cfg.WebSocketCompression),
+		events:      handlers.NewEventHandler(processor, deps.Operations),
+		operations:  handlers.NewOperationHandler(deps.Operations),
+		analytics:   handlers.NewAnalyticsHandler(processor.GetFirestoreClient(), processor.GetEmbeddingService(), processor.GetVertexAIClient(), cfg),
+		metrics:     handlers.NewMetricsHandler(services.PipelineLatency, deps.WSHub),
+		schema:      handlers.NewSchemaHandler(),
+		diagnostics: handlers.NewDiagnosticsHandler(services.Quotas, services.PipelineLatency, services.BackdatedEvents, services.DecayAnchors, services.Memory, deps.CacheMaintainer, processor.GetVertexAIClient(), processor.GetAIProvider()),
+		admin:       handlers.NewAdminHandler(processor.GetFirestoreClient(), processor),
+		webhooks:    handlers.NewWebhookHandler(deps.Webhooks),
+		graphql:     graphqlapi.NewHandler(processor.GetFirestoreClient(), processor.GetEmbeddingService(), deps.WSHub),
+
+		trackKeys: handlers.TrackAPIKeyUsage(services.Quotas, deps.APIKeys),
+		// Clients over their request rate get 429
+		limited:   handlers.LimitRequests(deps.RequestLimiter, verifier),
+		ingest:    handlers.RequireRole(deps.APIKeys, services.RoleIngest, cfg.AllowUnauthenticated),
+		adminOnly: handlers.RequireRole(deps.APIKeys, services.RoleAdmin, cfg.AllowUnauthenticated),
+	}
+	if deps.CacheMaintainer != nil {
+		a.analytics.RegisterCaches(deps.CacheMaintainer)
+	}
+
+	// Dashboard and trending responses absorb polling from the response cache
+	a.cached = a.analytics.CacheResponses()
+
+	// Reads stay open unless configured otherwise
+	a.read = func(c *gin.Context) { c.Next() }
+	if cfg.APIKeyRequireRead {
+		a.read = handlers.RequireRole(deps.APIKeys, services.RoleRead, cfg.AllowUnauthenticated)
+	}
+	return a
+}
+
+// register adds the API routes to a group
+func (a *api) register(api *gin.RouterGroup) {
+	cfg, deps := a.cfg, a.deps
+	api.Use(a.trackKeys)
+
+	// Server-Sent Events fallback for the WebSocket broadcasts
+	api.GET("/stream", a.ws.HandleEventStream)
+
+	// Event ingestion (not served by read replicas)
+	if !cfg.IsReadReplica() {
+		events := api.Group("/events", a.ingest, a.limited)
+		h := a.events
+		events.POST("/interaction", h.HandleInteraction)
+		events.POST("/content", h.HandleContentMetadata)
+		events.POST("/view", h.HandleView)
+		events.POST("/remix", h.HandleRemix)
+		events.POST("/comment", h.HandleComment)
+		events.POST("/recommendation-feedback", h.HandleRecommendationFeedback)
+
+		// Status of ingestion accepted with 202
+		api.GET("/operations/:id", a.ingest, a.operations.GetOperation)
+	}
+
+	// Analytics; ?fields= selects the fields of the returned items
+	analytics := api.Group("/analytics", a.read, a.limited, handlers.SelectFields())
+	{
+		h, cached := a.analytics, a.cached
+		analytics.GET("/trending", cached, h.GetTrending)
+		analytics.GET("/trending-hashtags", h.GetTrendingHashtags)
+		analytics.GET("/keywords", cached, h.GetKeywordPerformance)
+		analytics.GET("/rising", h.GetRisingPosts)
+		analytics.GET("/post/:id/stats", h.GetPostStats)
+		analytics.GET("/post/:id/timeseries", h.GetPostTimeseries)
+		analytics.GET("/post/:id/history", h.GetPostHistory)
+		analytics.GET("/compare", h.ComparePosts)
+		analytics.GET("/similar/:postId", h.GetSimilarPosts)
+		analytics.GET("/user/:id/recommendations", h.GetRecommendations)
+		analytics.GET("/user/:id/remix-suggestions", h.GetRemixSuggestions)
+		analytics.GET("/creator/:id", h.GetCreatorAnalytics)
+		analytics.GET("/creator/:id/audience-overlap", h.GetAudienceOverlap)
+		analytics.GET("/creator/:id/suggestions", h.GetCreatorSuggestions)
+		analytics.GET("/creators/rising", h.GetRisingCreators)
+		analytics.GET("/leaderboards/:period", h.GetLeaderboard)
+		analytics.GET("/prediction-accuracy", h.GetPredictionAccuracy)
+		analytics.GET("/predictor-comparison", h.GetPredictorComparison)
+
+		// Dashboard analytics
+		analytics.GET("/dashboard/metrics", cached, h.GetDashboardMetrics)
+		analytics.GET("/dashboard/top-creators", cached, h.GetTopCreators)
+		analytics.GET("/dashboard/content-types", cached, h.GetContentTypeBreakdown)
+		analytics.GET("/dashboard/trends", cached, h.GetEngagementTrends)
+		analytics.GET("/dashboard/retention", cached, h.GetViewerRetention)
+		analytics.GET("/dashboard/active-users", cached, h.GetActiveUsers)
+		analytics.GET("/dashboard/segments", cached, h.GetSegmentBreakdown)
+	}
+
+	// Pipeline metrics
+	metrics := api.Group("/metrics", a.read)
+	{
+		metrics.GET("/pipeline-latency", a.metrics.GetPipelineLatency)
+		metrics.GET("/websocket-clients", a.metrics.GetWebSocketClients)
+	}
+
+	// Event payload schemas for WS and webhook consumers
+	schema := api.Group("/schema", a.read)
+	{
+		schema.GET("/events", a.schema.GetEventSchemas)
+	}
+
+	// Admin operations
+	admin := api.Group("/admin", a.adminOnly)
+	{
+		// Configured API keys and their traffic
+		admin.GET("/api-keys", handlers.ListAPIKeys(deps.APIKeys))
+		admin.GET("/rate-limits", handlers.GetRateLimits(deps.RequestLimiter))
+
+		// Quota usage and pipeline health
+		admin.GET("/diagnostics", a.diagnostics.GetDiagnostics)
+		admin.GET("/circuit-breakers", a.diagnostics.GetCircuitBreakers)
+		admin.GET("/ai/usage", a.diagnostics.GetAIUsage)
+
+		// Per-post audit trail (trace mode)
+		admin.GET("/posts/:id/trace", a.admin.GetPostTrace)
+
+		// Trending overrides in effect
+		admin.GET("/trending/overrides", a.admin.ListTrendingOverrides)
+
+		// Live and shadow scoring formulas compared
+		admin.GET("/scoring/compare", a.admin.CompareScoring)
+
+		// Clients connected to this instance; replicas serve their own
+		admin.GET("/ws/clients", a.ws.ListClients)
+		admin.DELETE("/ws/clients/:id", a.ws.DisconnectClient)
+	}
+
+	// Webhook subscriptions, delivered by processing instances
+	if !cfg.IsReadReplica() {
+		webhooks := api.Group("/webhooks", a.adminOnly)
+		h := a.webhooks
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.GET("", h.ListWebhooks)
+		webhooks.GET("/:id", h.GetWebhook)
+		webhooks.PUT("/:id", h.UpdateWebhook)
+		webhooks.DELETE("/:id", h.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", h.GetWebhookDeliveries)
+		webhooks.POST("/:id/test", h.TestWebhook)
+	}
+
+	// Admin operations that write or start jobs run on processing instances only
+	if !cfg.IsReadReplica() {
+		// Trigger full post indexing
+		admin.POST("/index-posts", func(c *gin.Context) {
+			go func() {
+				if err := deps.PostIndexer.IndexAllPosts(); err != nil {
+					logger.Errorf("❌ Post indexing failed: %v", err)
+				}
+			}()
+			c.JSON(200, gin.H{"status": "indexing started"})
+		})
+
+		// Backfill keywords for posts that have none
+		admin.POST("/extract-keywords", func(c *gin.Context) {
+			if deps.KeywordBackfiller == nil {
+				handlers.RespondError(c, handlers.NewAPIError(409, handlers.CodeFeatureUnavailable, "keyword backfill requires the vertex AI provider"))
+				return
+			}
+			if err := deps.KeywordBackfiller.Start(); err != nil {
+				conflict := handlers.NewAPIError(409, handlers.CodeConflict, err.Error())
+				conflict.Extra = gin.H{"data": deps.KeywordBackfiller.Status()}
+				handlers.RespondError(c, conflict)
+				return
+			}
+			c.JSON(202, gin.H{"status": "keyword backfill started"})
+		})
+		admin.GET("/extract-keywords/status", func(c *gin.Context) {
+			if deps.KeywordBackfiller == nil {
+				handlers.RespondError(c, handlers.NewAPIError(409, handlers.CodeFeatureUnavailable, "keyword backfill requires the vertex AI provider"))
+				return
+			}
+			c.JSON(200, gin.H{"status": "success", "data": deps.KeywordBackfiller.Status()})
+		})
+
+		// Enable or disable trace mode for a post
+		admin.POST("/posts/:id/trace", a.admin.EnablePostTrace)
+		admin.DELETE("/posts/:id/trace", a.admin.DisablePostTrace)
+
+		// Recalculate a post's score and prediction, showing the values before and after
+		admin.POST("/posts/:id/recompute", a.admin.RecomputePost)
+
+		// Pin, exclude or boost posts in the trending feeds
+		admin.PUT("/trending/overrides/:id", a.admin.SetTrendingOverride)
+		admin.DELETE("/trending/overrides/:id", a.admin.DeleteTrendingOverride)
+
+		// Trigger remix chain archiving
+		admin.POST("/archive-remix-chains", func(c *gin.Context) {
+			go func() {
+				if err := deps.RemixArchiver.ArchiveFinishedChains(); err != nil {
+					logger.Errorf("❌ Remix archiving failed: %v", err)
+				}
+			}()
+			c.JSON(200, gin.H{"status": "archiving started"})
+		})
+
+		// Trigger creator tier classification
+		admin.POST("/classify-creator-tiers", func(c *gin.Context) {
+			if deps.TierClassifier == nil {
+				handlers.RespondError(c, handlers.NewAPIError(409, handlers.CodeFeatureUnavailable, "creator tier classifier is disabled"))
+				return
+			}
+			go func() {
+				if err := deps.TierClassifier.Classify(); err != nil {
+					logger.Errorf("❌ Creator tier classification failed: %v", err)
+				}
+			}()
+			c.JSON(200, gin.H{"status": "classification started"})
+		})
+	}
+}
+
+// trustProxies makes the router take client IPs, which the per-IP rate limits key on, from
+// X-Forwarded-For only when a request comes through one of proxies, so clients cannot pick
+// their own. Without proxies the connection's address is the client IP.
+func trustProxies(router *gin.Engine, proxies []string) error {
+	router.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	if len(proxies) == 0 {
+		return router.SetTrustedProxies(nil)
+	}
+	return router.SetTrustedProxies(proxies)
+}
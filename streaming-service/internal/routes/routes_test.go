@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrustProxies(t *testing.T) {
+	clientIP := func(proxies []string, remoteAddr string) string {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		if err := trustProxies(router, proxies); err != nil {
+			t.Fatalf("Failed to trust proxies %v: %v", proxies, err)
+		}
+		router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.7")
+		req.Header.Set("X-Real-IP", "5.6.7.8")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// Without trusted proxies forwarded headers are ignored
+	if ip := clientIP(nil, "198.51.100.1:1234"); ip != "198.51.100.1" {
+		t.Errorf("Expected the connection's address, got %s", ip)
+	}
+	// Behind a trusted proxy the address it appended counts, not what the client sent
+	if ip := clientIP([]string{"169.254.0.0/16"}, "169.254.1.1:1234"); ip != "203.0.113.7" {
+		t.Errorf("Expected the address appended by the proxy, got %s", ip)
+	}
+	if ip := clientIP([]string{"169.254.0.0/16"}, "198.51.100.1:1234"); ip != "198.51.100.1" {
+		t.Errorf("Expected forwarded headers of untrusted peers to be ignored, got %s", ip)
+	}
+	if err := trustProxies(gin.New(), []string{"not-an-ip"}); err == nil {
+		t.Error("Expected an invalid proxy to be refused")
+	}
+}
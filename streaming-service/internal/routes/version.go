@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"confluent-viral-intelligence/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// Versions of the API. The path selects the version (/api/v1); the unversioned /api paths
+// serve CurrentVersion, or the version a client asks for in the API-Version header or an
+// Accept media type such as application/vnd.viral.v1+json. Every response names the version
+// it was served as in the API-Version header.
+const (
+	CurrentVersion = "1"
+	VersionHeader  = "API-Version"
+
+	versionPrefix = "v"
+)
+
+// SupportedVersions lists the API versions served, oldest first
+var SupportedVersions = []string{"1"}
+
+// versionMediaType matches the version in a vendor media type of the Accept header
+var versionMediaType = regexp.MustCompile(`application/vnd\.viral\.v(\d+)\+json`)
+
+// Negotiate serves the routes of a group as pathVersion, or as the requested version when
+// pathVersion is empty. Requests for a version the group does not serve get 406.
+func Negotiate(pathVersion string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested := RequestedVersion(c.Request)
+		version := pathVersion
+		if version == "" {
+			version = CurrentVersion
+			if requested != "" {
+				version = requested
+			}
+		}
+
+		if (requested != "" && requested != version) || !slices.Contains(SupportedVersions, version) {
+			unsupported := handlers.NewAPIError(http.StatusNotAcceptable, handlers.CodeUnsupportedVersion, "API version "+requested+" is not served here")
+			unsupported.Extra = gin.H{"versions": SupportedVersions}
+			handlers.RespondError(c, unsupported)
+			return
+		}
+
+		c.Header(VersionHeader, version)
+		c.Next()
+	}
+}
+
+// RequestedVersion returns the version a request asks for in its API-Version header or
+// Accept media type, or "" when it asks for none
+func RequestedVersion(r *http.Request) string {
+	if version := strings.TrimPrefix(strings.TrimSpace(r.Header.Get(VersionHeader)), versionPrefix); version != "" {
+		return version
+	}
+	if match := versionMediaType.FindStringSubmatch(r.Header.Get("Accept")); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// Deprecated marks the responses of legacy routes as deprecated (RFC 8594), pointing to the
+// same path under the current version and announcing when the alias goes away when sunset
+// is set
+func Deprecated(sunset time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if successor, ok := strings.CutPrefix(c.Request.URL.Path, "/api/"); ok {
+			c.Header("Link", `</api/`+versionPrefix+CurrentVersion+"/"+successor+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newVersionedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.Group("/api/v1", Negotiate(CurrentVersion)).GET("/analytics/trending", ok)
+	router.Group("/api", Negotiate(""), Deprecated(time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC))).GET("/analytics/trending", ok)
+	return router
+}
+
+func serve(router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	router := newVersionedRouter()
+
+	w := serve(router, "/api/v1/analytics/trending", nil)
+	if w.Code != http.StatusOK || w.Header().Get(VersionHeader) != "1" || w.Header().Get("Deprecation") != "" {
+		t.Errorf("Expected v1 served without deprecation, got %d %v", w.Code, w.Header())
+	}
+
+	w = serve(router, "/api/v1/analytics/trending", map[string]string{"Accept": "application/vnd.viral.v2+json"})
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected v2 to be refused under /api/v1, got %d", w.Code)
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	router := newVersionedRouter()
+
+	w := serve(router, "/api/analytics/trending?limit=5", map[string]string{VersionHeader: "v1"})
+	if w.Code != http.StatusOK || w.Header().Get(VersionHeader) != "1" {
+		t.Fatalf("Expected the legacy alias to serve v1, got %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "Wed, 30 Jun 2027 00:00:00 GMT" {
+		t.Errorf("Expected deprecation and sunset headers, got %v", w.Header())
+	}
+	if link := w.Header().Get("Link"); link != `</api/v1/analytics/trending>; rel="successor-version"` {
+		t.Errorf("Unexpected successor link %q", link)
+	}
+
+	if w := serve(router, "/api/analytics/trending", map[string]string{VersionHeader: "3"}); w.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected an unsupported version to be refused, got %d", w.Code)
+	}
+}
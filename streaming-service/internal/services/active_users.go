@@ -0,0 +1,103 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// Days the active user windows cover, ending with the current day
+const (
+	weeklyActiveDays  = 7
+	monthlyActiveDays = 30
+)
+
+// RecordActivity adds a user who liked, commented on, shared or remixed a post to the active
+// users of the day the interaction happened. Viewers are added by RecordView.
+func (rt *RetentionTracker) RecordActivity(userID string, at time.Time) {
+	if rt == nil || userID == "" {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	rt.add(userID, viewerDayKey{day: rollupStart(RollupDay, at, rt.firestoreClient.reportingLoc), active: true})
+}
+
+// DailyActiveUsers is the number of distinct users acting on posts on one day
+type DailyActiveUsers struct {
+	Date        string `json:"date"`
+	ActiveUsers uint64 `json:"activeUsers"`
+}
+
+// ActiveUsers counts the distinct users who viewed, liked, commented on, shared or remixed a
+// post on the current day (DAU) and over the last 7 (WAU) and 30 days (MAU), the current day
+// included. Counts are HyperLogLog estimates.
+type ActiveUsers struct {
+	Date string `json:"date"`
+	DAU  uint64 `json:"dau"`
+	WAU  uint64 `json:"wau"`
+	MAU  uint64 `json:"mau"`
+
+	// Mean DAU over the last 30 days as a percentage of MAU: how many days of the month an
+	// active user is active on. The mean keeps the current, partial day from skewing it.
+	Stickiness float64 `json:"stickiness"`
+
+	Daily        []DailyActiveUsers `json:"daily"` // oldest first
+	CalculatedAt time.Time          `json:"calculatedAt"`
+}
+
+// GetActiveUsers returns the active users of the current day and the weeks and month ending
+// with it
+func (da *DashboardAnalytics) GetActiveUsers() (*ActiveUsers, error) {
+	logger.Debug("📊 Calculating active users...")
+
+	now := time.Now()
+	loc := da.firestoreClient.reportingLoc
+	days := rollupStarts(RollupDay, StartOfDay(now, loc).AddDate(0, 0, 1-monthlyActiveDays), now, loc)
+	keys := make([]viewerDayKey, len(days))
+	for i, day := range days {
+		keys[i] = viewerDayKey{day: day, active: true}
+	}
+	sketches, err := da.firestoreClient.getDaySketches(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	active := buildActiveUsers(days, sketches, loc)
+	active.CalculatedAt = now
+	logger.Infof("✅ Active users calculated: DAU %d, WAU %d, MAU %d", active.DAU, active.WAU, active.MAU)
+	return active, nil
+}
+
+// buildActiveUsers derives the active user counts from the daily sketches of the last 30
+// days, oldest first
+func buildActiveUsers(days []time.Time, sketches []*HyperLogLog, loc *time.Location) *ActiveUsers {
+	active := &ActiveUsers{Daily: make([]DailyActiveUsers, len(days))}
+	weekly, monthly := NewHyperLogLog(), NewHyperLogLog()
+	var dailySum uint64
+	for i, sketch := range sketches {
+		count := sketch.Count()
+		active.Daily[i] = DailyActiveUsers{Date: ReportingDate(days[i], loc), ActiveUsers: count}
+		dailySum += count
+		monthly.Merge(sketch)
+		if i >= len(sketches)-weeklyActiveDays {
+			weekly.Merge(sketch)
+		}
+	}
+	if len(days) == 0 {
+		return active
+	}
+
+	last := active.Daily[len(days)-1]
+	active.Date, active.DAU = last.Date, last.ActiveUsers
+	active.WAU, active.MAU = weekly.Count(), monthly.Count()
+	if active.MAU > 0 {
+		meanDAU := float64(dailySum) / float64(len(days))
+		// Estimates of single days can add up to slightly more than the month's
+		active.Stickiness = math.Min((meanDAU/float64(active.MAU))*100, 100)
+	}
+	return active
+}
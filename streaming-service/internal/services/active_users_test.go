@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetentionTracker_RecordActivity(t *testing.T) {
+	rt := NewRetentionTracker(&FirestoreClient{reportingLoc: time.UTC}, time.Minute)
+
+	at := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	rt.RecordView("post-1", "viewer-1", at)
+	rt.RecordActivity("viewer-1", at)
+	rt.RecordActivity("liker-1", at)
+	rt.RecordActivity("", at)
+
+	day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	if users := rt.pending[viewerDayKey{day: day, active: true}].Count(); users != 2 {
+		t.Errorf("Expected 2 active users, got %d", users)
+	}
+	if viewers := rt.pending[viewerDayKey{day: day}].Count(); viewers != 1 {
+		t.Errorf("Expected likers to stay out of the viewers, got %d viewers", viewers)
+	}
+	Memory.Release(MemoryPoolRetention, Memory.Used(MemoryPoolRetention))
+
+	var disabled *RetentionTracker
+	disabled.RecordActivity("viewer-1", at)
+}
+
+func TestBuildActiveUsers(t *testing.T) {
+	to := time.Date(2024, 5, 30, 0, 0, 0, 0, time.UTC)
+	days := rollupStarts(RollupDay, to.AddDate(0, 0, 1-monthlyActiveDays), to.Add(time.Hour), time.UTC)
+	if len(days) != monthlyActiveDays {
+		t.Fatalf("Expected %d days, got %d", monthlyActiveDays, len(days))
+	}
+
+	// 100 regulars active every day, plus 50 users of their own each day
+	sketches := make([]*HyperLogLog, len(days))
+	for i := range sketches {
+		sketches[i] = NewHyperLogLog()
+		for u := 0; u < 100; u++ {
+			sketches[i].Add(fmt.Sprintf("regular-%d", u))
+		}
+		for u := 0; u < 50; u++ {
+			sketches[i].Add(fmt.Sprintf("day-%d-user-%d", i, u))
+		}
+	}
+
+	active := buildActiveUsers(days, sketches, time.UTC)
+	if active.Date != "2024-05-30" || len(active.Daily) != monthlyActiveDays {
+		t.Fatalf("Unexpected active users %+v", active)
+	}
+	within(t, "DAU", active.DAU, 150, 0.05)
+	within(t, "WAU", active.WAU, 450, 0.05)
+	within(t, "MAU", active.MAU, 1600, 0.05)
+	if active.Stickiness < 8 || active.Stickiness > 11 {
+		t.Errorf("Expected a stickiness near 9.4%%, got %.2f", active.Stickiness)
+	}
+
+	// The same users every day stick all month
+	for i := range sketches {
+		sketches[i] = NewHyperLogLog()
+		for u := 0; u < 100; u++ {
+			sketches[i].Add(fmt.Sprintf("regular-%d", u))
+		}
+	}
+	if active := buildActiveUsers(days, sketches, time.UTC); active.Stickiness < 99 || active.Stickiness > 100 {
+		t.Errorf("Expected a stickiness near 100%%, got %.2f", active.Stickiness)
+	}
+
+	if empty := buildActiveUsers(nil, nil, time.UTC); empty.DAU != 0 || empty.Stickiness != 0 {
+		t.Errorf("Expected no active users, got %+v", empty)
+	}
+}
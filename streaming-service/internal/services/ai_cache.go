@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"confluent-viral-intelligence/internal/config"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AI cache backends selectable via AI_CACHE_BACKEND
+const (
+	AICacheBackendMemory    = "memory"
+	AICacheBackendFirestore = "firestore"
+)
+
+// Fraction of a full in-memory cache evicted at once, so inserts at capacity stay cheap
+const memoryCacheEvictFraction = 10
+
+// AICache stores serialized AI responses with a TTL and a bound on the number of entries
+type AICache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// EvictExpired removes expired entries and trims the cache to its size bound,
+	// returning the number of entries removed
+	EvictExpired(ctx context.Context) (int, error)
+	// Len returns the number of stored entries, including expired ones not yet evicted
+	Len(ctx context.Context) (int, error)
+	Backend() string
+}
+
+// NewAICache creates the cache backend selected in the configuration. The Firestore
+// backend survives restarts and is shared by all instances.
+func NewAICache(cfg *config.Config, firestoreClient *FirestoreClient) (AICache, error) {
+	switch cfg.AICacheBackend {
+	case "", AICacheBackendMemory:
+		return NewMemoryAICache(cfg.AICacheMaxEntries), nil
+	case AICacheBackendFirestore:
+		if firestoreClient == nil {
+			return nil, fmt.Errorf("firestore client is required for the %s AI cache backend", AICacheBackendFirestore)
+		}
+		return NewFirestoreAICache(firestoreClient, cfg.AICacheMaxEntries), nil
+	default:
+		return nil, fmt.Errorf("unknown AI cache backend %q", cfg.AICacheBackend)
+	}
+}
+
+// AICacheStats reports cache effectiveness since startup
+type AICacheStats struct {
+	Backend   string  `json:"backend"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	HitRate   float64 `json:"hit_rate"`
+	Sets      int64   `json:"sets"`
+	Evictions int64   `json:"evictions"`
+	Errors    int64   `json:"errors"`
+}
+
+// aiCacheMetrics counts cache outcomes
+type aiCacheMetrics struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	sets      atomic.Int64
+	evictions atomic.Int64
+	errors    atomic.Int64
+}
+
+func (m *aiCacheMetrics) stats(backend string) AICacheStats {
+	stats := AICacheStats{
+		Backend:   backend,
+		Hits:      m.hits.Load(),
+		Misses:    m.misses.Load(),
+		Sets:      m.sets.Load(),
+		Evictions: m.evictions.Load(),
+		Errors:    m.errors.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// MemoryAICache keeps entries in process memory, bounded by entry count and by the memory
+// guard's AI cache pool
+type MemoryAICache struct {
+	maxEntries int
+	memory     *MemoryGuard
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	now     func() time.Time
+}
+
+func NewMemoryAICache(maxEntries int) *MemoryAICache {
+	return &MemoryAICache{
+		maxEntries: maxEntries,
+		memory:     Memory,
+		entries:    make(map[string]*cacheEntry),
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryAICache) Backend() string { return AICacheBackendMemory }
+
+func (c *MemoryAICache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().After(entry.expiresAt) {
+		return nil, false, nil
+	}
+	return entry.response.([]byte), true, nil
+}
+
+func (c *MemoryAICache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; exists {
+		c.removeLocked(key)
+	} else if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evictLocked(len(c.entries) - c.maxEntries + 1 + c.maxEntries/memoryCacheEvictFraction)
+	}
+
+	size := int64(len(key) + len(value) + memoryEntryOverhead)
+	if !c.memory.Reserve(MemoryPoolAICache, size) {
+		// The pool is at its ceiling; make room once, otherwise leave the response uncached
+		c.evictLocked(max(len(c.entries)/memoryCacheEvictFraction, 1))
+		if !c.memory.Reserve(MemoryPoolAICache, size) {
+			return nil
+		}
+	}
+	c.entries[key] = &cacheEntry{response: value, expiresAt: c.now().Add(ttl), size: size}
+	return nil
+}
+
+func (c *MemoryAICache) Len(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), nil
+}
+
+func (c *MemoryAICache) EvictExpired(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	excess := 0
+	if c.maxEntries > 0 && len(c.entries) > c.maxEntries {
+		excess = len(c.entries) - c.maxEntries
+	}
+	return c.evictLocked(excess), nil
+}
+
+// evictLocked removes expired entries, then the entries closest to expiry until at least
+// minRemoved entries are gone; callers hold the lock
+func (c *MemoryAICache) evictLocked(minRemoved int) int {
+	now := c.now()
+	removed := 0
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			c.removeLocked(key)
+			removed++
+		}
+	}
+	if removed >= minRemoved {
+		return removed
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].expiresAt.Before(c.entries[keys[j]].expiresAt)
+	})
+	for _, key := range keys[:min(minRemoved-removed, len(keys))] {
+		c.removeLocked(key)
+		removed++
+	}
+	return removed
+}
+
+// removeLocked deletes an entry and releases its memory; callers hold the lock
+func (c *MemoryAICache) removeLocked(key string) {
+	if entry, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.memory.Release(MemoryPoolAICache, entry.size)
+	}
+}
+
+// FirestoreAICache keeps entries in the ai_cache collection
+type FirestoreAICache struct {
+	firestoreClient *FirestoreClient
+	maxEntries      int
+}
+
+// aiCacheDoc is a stored cache entry; documents are keyed by the SHA-256 of the cache key
+type aiCacheDoc struct {
+	Key       string
+	Value     []byte
+	ExpiresAt time.Time
+}
+
+func NewFirestoreAICache(firestoreClient *FirestoreClient, maxEntries int) *FirestoreAICache {
+	return &FirestoreAICache{firestoreClient: firestoreClient, maxEntries: maxEntries}
+}
+
+func (c *FirestoreAICache) Backend() string { return AICacheBackendFirestore }
+
+func (c *FirestoreAICache) collection() *firestore.CollectionRef {
+	return c.firestoreClient.client.Collection("ai_cache")
+}
+
+func aiCacheDocID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *FirestoreAICache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := c.collection().Doc(aiCacheDocID(key)).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry aiCacheDoc
+	if err := doc.DataTo(&entry); err != nil {
+		return nil, false, err
+	}
+	// Guard against hash collisions and entries the eviction loop has not reached yet
+	if entry.Key != key || time.Now().After(entry.ExpiresAt) {
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+func (c *FirestoreAICache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := c.collection().Doc(aiCacheDocID(key)).Set(ctx, aiCacheDoc{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return err
+}
+
+func (c *FirestoreAICache) EvictExpired(ctx context.Context) (int, error) {
+	removed, err := c.deleteAll(ctx, c.collection().Where("ExpiresAt", "<", time.Now()))
+	if err != nil || c.maxEntries <= 0 {
+		return removed, err
+	}
+
+	// Trim to the size bound, dropping the entries closest to expiry
+	count, err := c.Len(ctx)
+	if err != nil {
+		return removed, err
+	}
+	excess := count - c.maxEntries
+	if excess <= 0 {
+		return removed, nil
+	}
+
+	trimmed, err := c.deleteAll(ctx, c.collection().OrderBy("ExpiresAt", firestore.Asc).Limit(excess))
+	return removed + trimmed, err
+}
+
+func (c *FirestoreAICache) Len(ctx context.Context) (int, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	result, err := c.collection().NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count cache entries: %w", err)
+	}
+	count, _ := result["count"].(*firestorepb.Value)
+	return int(count.GetIntegerValue()), nil
+}
+
+// deleteAll deletes every document matched by a query
+func (c *FirestoreAICache) deleteAll(ctx context.Context, query firestore.Query) (int, error) {
+	iter := query.Select().Documents(ctx)
+	defer iter.Stop()
+
+	bw := c.firestoreClient.client.BulkWriter(ctx)
+	defer bw.End()
+
+	removed := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return removed, err
+		}
+		Quotas.Record(QuotaFirestore, 1)
+		if _, err := bw.Delete(doc.Ref); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
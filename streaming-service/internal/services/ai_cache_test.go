@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+func TestMemoryAICacheTTL(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryAICache(0)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Set(ctx, "keywords:a", []byte(`{"category":"art"}`), time.Minute)
+	if value, ok, _ := cache.Get(ctx, "keywords:a"); !ok || string(value) != `{"category":"art"}` {
+		t.Fatalf("expected cached value, got %q (hit=%v)", value, ok)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok, _ := cache.Get(ctx, "keywords:a"); ok {
+		t.Error("expected expired entry to miss")
+	}
+
+	removed, err := cache.EvictExpired(ctx)
+	if err != nil || removed != 1 {
+		t.Errorf("expected 1 expired entry evicted, got %d (%v)", removed, err)
+	}
+}
+
+func TestMemoryAICacheSizeBound(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryAICache(20)
+
+	for i := 0; i < 50; i++ {
+		// Later entries live longer, so the oldest are evicted first
+		cache.Set(ctx, fmt.Sprintf("key-%d", i), []byte("v"), time.Duration(i+1)*time.Minute)
+		if len(cache.entries) > 20 {
+			t.Fatalf("cache grew past its bound: %d entries", len(cache.entries))
+		}
+	}
+
+	if _, ok, _ := cache.Get(ctx, "key-49"); !ok {
+		t.Error("expected newest entry to be kept")
+	}
+	if _, ok, _ := cache.Get(ctx, "key-0"); ok {
+		t.Error("expected oldest entry to be evicted")
+	}
+}
+
+func TestNewAICacheBackends(t *testing.T) {
+	cache, err := NewAICache(&config.Config{AICacheBackend: AICacheBackendMemory}, nil)
+	if err != nil || cache.Backend() != AICacheBackendMemory {
+		t.Errorf("expected memory cache, got %v (%v)", cache, err)
+	}
+
+	if _, err := NewAICache(&config.Config{AICacheBackend: AICacheBackendFirestore}, nil); err == nil {
+		t.Error("expected error for firestore backend without a client")
+	}
+	if _, err := NewAICache(&config.Config{AICacheBackend: "redis"}, nil); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// AI providers selectable via AI_PROVIDER
+const (
+	AIProviderVertex = "vertex"
+	AIProviderOpenAI = "openai"
+	AIProviderLocal  = "local"
+)
+
+// AIProvider is the model backend behind keyword extraction, moderation and viral prediction.
+// Implementations fall back to the local heuristics when their model fails, so only moderation
+// surfaces errors to the caller.
+type AIProvider interface {
+	Name() string
+	ExtractKeywords(prompt string, contentType string) (*models.KeywordExtractionResponse, error)
+	Moderate(postID, prompt string, outputURLs []string) (*models.ModerationVerdict, error)
+	PredictVirality(req models.ViralPredictionRequest) (*models.ViralPredictionResponse, error)
+}
+
+// NewAIProvider returns the provider selected by AI_PROVIDER. The Vertex AI client is created
+// by the caller, which also needs it for the Vertex-only features, and must be set for vertex.
+func NewAIProvider(cfg *config.Config, vertexAI *VertexAIClient) (AIProvider, error) {
+	switch cfg.AIProvider {
+	case AIProviderVertex:
+		if vertexAI == nil {
+			return nil, fmt.Errorf("%s AI provider requires a Vertex AI client", AIProviderVertex)
+		}
+		return vertexAI, nil
+	case AIProviderOpenAI:
+		return NewOpenAIProvider(context.Background(), cfg)
+	case AIProviderLocal:
+		return NewLocalAIProvider(cfg), nil
+	default:
+		return nil, fmt.Errorf("invalid AI_PROVIDER %q: must be %s, %s or %s", cfg.AIProvider, AIProviderVertex, AIProviderOpenAI, AIProviderLocal)
+	}
+}
+
+// newAICallPolicies builds the circuit breaker, retry policy and per-use-case rate limiter of a
+// remote AI provider from the VERTEX_AI_* settings
+func newAICallPolicies(name string, cfg *config.Config) (*CircuitBreaker, RetryPolicy, *KeyedRateLimiter, error) {
+	// Fail fast while the provider is down instead of slowing every request
+	breaker := NewCircuitBreaker(name, cfg.VertexAIBreakerFailureThreshold,
+		time.Duration(cfg.VertexAIBreakerOpenSeconds)*time.Second)
+	retry := RetryPolicy{
+		MaxRetries:  cfg.VertexAIMaxRetries,
+		BaseDelay:   time.Duration(cfg.VertexAIRetryBaseDelayMs) * time.Millisecond,
+		CallTimeout: time.Duration(cfg.VertexAICallTimeoutSeconds) * time.Second,
+	}
+
+	// Pace calls per use case so bursts queue or shed locally instead of hitting quota errors
+	shed := cfg.VertexAIShedPolicy
+	if shed == "" {
+		shed = ShedRejectNew
+	}
+	if shed != ShedRejectNew && shed != ShedDropOldest {
+		return nil, RetryPolicy{}, nil, fmt.Errorf("invalid VERTEX_AI_SHED_POLICY %q: must be %s or %s", cfg.VertexAIShedPolicy, ShedRejectNew, ShedDropOldest)
+	}
+	limiter := NewKeyedRateLimiter(RateLimitPolicy{
+		QPS:          cfg.VertexAIQPS,
+		Burst:        cfg.VertexAIBurst,
+		QueueLength:  cfg.VertexAIQueueLength,
+		QueueTimeout: time.Duration(cfg.VertexAIQueueTimeoutSeconds) * time.Second,
+		Shed:         shed,
+		KeyQPS:       cfg.VertexAIKeyQPS,
+	})
+
+	return breaker, retry, limiter, nil
+}
+
+// Name identifies the Vertex AI provider
+func (v *VertexAIClient) Name() string {
+	return AIProviderVertex
+}
+
+// LocalAIProvider answers every AI call deterministically with the local heuristics, without
+// any network access. It is meant for tests and offline development.
+type LocalAIProvider struct {
+	config *config.Config
+}
+
+func NewLocalAIProvider(cfg *config.Config) *LocalAIProvider {
+	return &LocalAIProvider{config: cfg}
+}
+
+// Name identifies the local provider
+func (l *LocalAIProvider) Name() string {
+	return AIProviderLocal
+}
+
+// ExtractKeywords picks keywords from the words of the prompt
+func (l *LocalAIProvider) ExtractKeywords(prompt string, contentType string) (*models.KeywordExtractionResponse, error) {
+	return fallbackKeywordExtraction(prompt, contentType), nil
+}
+
+// Moderate passes every post; there is no local safety model
+func (l *LocalAIProvider) Moderate(postID, prompt string, outputURLs []string) (*models.ModerationVerdict, error) {
+	return &models.ModerationVerdict{
+		PostID:     postID,
+		Categories: map[string]float64{},
+		CheckedAt:  time.Now(),
+	}, nil
+}
+
+// PredictVirality scores virality with the weighted engagement heuristic
+func (l *LocalAIProvider) PredictVirality(req models.ViralPredictionRequest) (*models.ViralPredictionResponse, error) {
+	return predictViralityHeuristic(req, l.config), nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestNewAIProvider(t *testing.T) {
+	local, err := NewAIProvider(&config.Config{AIProvider: AIProviderLocal}, nil)
+	if err != nil || local.Name() != AIProviderLocal {
+		t.Fatalf("expected local provider, got %v, %v", local, err)
+	}
+	if _, err := NewAIProvider(&config.Config{AIProvider: AIProviderVertex}, nil); err == nil {
+		t.Error("expected an error for vertex without a client")
+	}
+	if _, err := NewAIProvider(&config.Config{AIProvider: "bedrock"}, nil); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+	if _, err := NewAIProvider(&config.Config{AIProvider: AIProviderOpenAI, VertexAIShedPolicy: "drop_newest", OpenAIBaseURL: "http://localhost", OpenAIModel: "m"}, nil); err == nil {
+		t.Error("expected an error for an unknown shed policy")
+	}
+}
+
+func TestLocalAIProviderIsDeterministic(t *testing.T) {
+	provider := NewLocalAIProvider(&config.Config{})
+
+	first, _ := provider.ExtractKeywords("golden sunset over the mountains", "image")
+	second, _ := provider.ExtractKeywords("golden sunset over the mountains", "image")
+	if !reflect.DeepEqual(first, second) || !containsString(first.Keywords, "sunset") {
+		t.Errorf("expected identical keywords from the prompt, got %v and %v", first.Keywords, second.Keywords)
+	}
+
+	verdict, err := provider.Moderate("p1", "anything", nil)
+	if err != nil || verdict.Flagged || verdict.PostID != "p1" {
+		t.Errorf("expected an unflagged verdict, got %+v, %v", verdict, err)
+	}
+
+	prediction, _ := provider.PredictVirality(models.ViralPredictionRequest{ViewCount: 500, LikeCount: 100, EngagementVelocity: 25})
+	if prediction.Source != ViralPredictionModeHeuristic || prediction.ViralProbability < 0.9 {
+		t.Errorf("expected a high heuristic prediction, got %+v", prediction)
+	}
+}
+
+func newTestOpenAIProvider(t *testing.T, handler http.HandlerFunc) *OpenAIProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		OpenAIBaseURL:                   server.URL + "/v1/",
+		OpenAIAPIKey:                    "secret",
+		OpenAIModel:                     "gpt-test",
+		OpenAIModerationModel:           "moderation-test",
+		ViralPredictionMode:             ViralPredictionModeGemini,
+		ModerationThreshold:             0.5,
+		Gemini:                          config.GeminiSettings{Temperature: 0.2, TopP: 0.8, MaxOutputTokens: 256},
+		VertexAIBreakerFailureThreshold: 5,
+	}
+	provider, err := NewOpenAIProvider(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	return provider
+}
+
+func chatCompletion(content string) map[string]interface{} {
+	return map[string]interface{}{
+		"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": content}}},
+	}
+}
+
+func TestOpenAIProviderExtractKeywords(t *testing.T) {
+	provider := newTestOpenAIProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "gpt-test" {
+			t.Errorf("expected configured model, got %v", body["model"])
+		}
+		json.NewEncoder(w).Encode(chatCompletion(`Sure: {"language": "en", "keywords": ["sunset", "mountains", "landscape", "nature", "sky"], "category": "Photography", "style": "landscape", "mood": "calm"}`))
+	})
+
+	result, err := provider.ExtractKeywords("sunset over mountains #goldenhour", "image")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Category != "photography" || !reflect.DeepEqual(result.EnglishKeywords, result.Keywords) {
+		t.Errorf("expected normalized keywords, got %+v", result)
+	}
+	if !containsString(result.Hashtags, "goldenhour") {
+		t.Errorf("expected prompt hashtags, got %v", result.Hashtags)
+	}
+}
+
+func TestOpenAIProviderFallsBack(t *testing.T) {
+	calls := 0
+	provider := newTestOpenAIProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+
+	keywords, err := provider.ExtractKeywords("sunset over mountains", "image")
+	if err != nil || !containsString(keywords.Keywords, "ai-generated") {
+		t.Errorf("expected fallback keywords, got %+v, %v", keywords, err)
+	}
+
+	prediction, err := provider.PredictVirality(models.ViralPredictionRequest{ViewCount: 10})
+	if err != nil || prediction.Source != ViralPredictionModeHeuristic {
+		t.Errorf("expected heuristic prediction, got %+v, %v", prediction, err)
+	}
+
+	if _, err := provider.Moderate("p1", "text", nil); err == nil {
+		t.Error("expected moderation to fail")
+	}
+	if calls != 3 {
+		t.Errorf("expected client errors not to be retried, got %d calls", calls)
+	}
+}
+
+func TestOpenAIProviderPredictVirality(t *testing.T) {
+	provider := newTestOpenAIProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(chatCompletion(`{"viral_probability": 1.4, "confidence": 0.7, "predicted_peak_time": 45}`))
+	})
+
+	prediction, err := provider.PredictVirality(models.ViralPredictionRequest{PostID: "p1", ViewCount: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prediction.Source != AIProviderOpenAI || prediction.ViralProbability != 1 || prediction.PredictedPeakTime != 45 {
+		t.Errorf("expected normalized model prediction, got %+v", prediction)
+	}
+}
+
+func TestOpenAIProviderModerate(t *testing.T) {
+	provider := newTestOpenAIProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string                   `json:"model"`
+			Input []map[string]interface{} `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/v1/moderations" || body.Model != "moderation-test" {
+			t.Errorf("unexpected request %s for model %s", r.URL.Path, body.Model)
+		}
+		// Only the text and the https image reach the API
+		if len(body.Input) != 2 {
+			t.Errorf("expected text and one image, got %v", body.Input)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{
+				"flagged": false,
+				"category_scores": map[string]float64{
+					"hate":             0.1,
+					"violence":         0.2,
+					"self-harm/intent": 0.7,
+					"sexual/minors":    0.01,
+					"unknown/category": 0.99,
+				},
+			}},
+		})
+	})
+
+	verdict, err := provider.Moderate("p1", "a prompt", []string{"https://cdn.example.com/a.png", "gs://bucket/b.png", "https://cdn.example.com/c.mp3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !verdict.Flagged || !reflect.DeepEqual(verdict.FlaggedCategories, []string{"dangerous_content"}) {
+		t.Errorf("expected dangerous content flagged, got %+v", verdict)
+	}
+	if verdict.Categories["dangerous_content"] != 0.7 || verdict.Categories["hate_speech"] != 0.1 {
+		t.Errorf("expected highest score per category, got %v", verdict.Categories)
+	}
+	if _, ok := verdict.Categories["unknown"]; ok {
+		t.Errorf("expected unknown categories to be ignored, got %v", verdict.Categories)
+	}
+}
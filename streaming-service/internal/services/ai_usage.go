@@ -0,0 +1,143 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// Days of usage kept in memory
+const aiUsageRetentionDays = 30
+
+// AIUsage is the token usage and estimated cost of a set of Gemini calls
+type AIUsage struct {
+	Calls        int64   `json:"calls"`
+	Failed       int64   `json:"failed"`
+	PromptTokens int64   `json:"prompt_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Add accumulates another usage into u
+func (u *AIUsage) Add(other AIUsage) {
+	u.Calls += other.Calls
+	u.Failed += other.Failed
+	u.PromptTokens += other.PromptTokens
+	u.OutputTokens += other.OutputTokens
+	u.TotalTokens += other.TotalTokens
+	u.CostUSD += other.CostUSD
+}
+
+// AIUsageDay is the usage of one reporting day, broken down by operation (Gemini use case)
+type AIUsageDay struct {
+	Date       string             `json:"date"`
+	Total      AIUsage            `json:"total"`
+	Operations map[string]AIUsage `json:"operations"`
+}
+
+// AIUsageTracker counts Gemini tokens and estimates their cost per day and operation.
+// Counts are per instance and reset on restart.
+type AIUsageTracker struct {
+	inputCostPerMillion  float64
+	outputCostPerMillion float64
+	loc                  *time.Location // days are bucketed in this zone
+
+	mu   sync.Mutex
+	days map[string]map[string]*AIUsage // date -> operation -> usage
+	now  func() time.Time
+}
+
+// NewAIUsageTracker creates a tracker pricing tokens at the given USD rates per million and
+// bucketing days in loc
+func NewAIUsageTracker(inputCostPerMillion, outputCostPerMillion float64, loc *time.Location) *AIUsageTracker {
+	return &AIUsageTracker{
+		inputCostPerMillion:  inputCostPerMillion,
+		outputCostPerMillion: outputCostPerMillion,
+		loc:                  loc,
+		days:                 make(map[string]map[string]*AIUsage),
+		now:                  time.Now,
+	}
+}
+
+// Record counts a Gemini call and the tokens reported in its response
+func (t *AIUsageTracker) Record(operation string, resp *genai.GenerateContentResponse, err error) {
+	var promptTokens, outputTokens int64
+	if resp != nil && resp.UsageMetadata != nil {
+		promptTokens = int64(resp.UsageMetadata.PromptTokenCount)
+		outputTokens = int64(resp.UsageMetadata.CandidatesTokenCount)
+	}
+	t.RecordTokens(operation, promptTokens, outputTokens, err != nil)
+}
+
+// RecordTokens counts a call with known token counts
+func (t *AIUsageTracker) RecordTokens(operation string, promptTokens, outputTokens int64, failed bool) {
+	if t == nil {
+		return
+	}
+
+	usage := AIUsage{
+		Calls:        1,
+		PromptTokens: promptTokens,
+		OutputTokens: outputTokens,
+		TotalTokens:  promptTokens + outputTokens,
+		CostUSD: (float64(promptTokens)*t.inputCostPerMillion +
+			float64(outputTokens)*t.outputCostPerMillion) / 1e6,
+	}
+	if failed {
+		usage.Failed = 1
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	date := ReportingDate(t.now(), t.loc)
+	operations, ok := t.days[date]
+	if !ok {
+		operations = make(map[string]*AIUsage)
+		t.days[date] = operations
+		t.prune()
+	}
+	if _, ok := operations[operation]; !ok {
+		operations[operation] = &AIUsage{}
+	}
+	operations[operation].Add(usage)
+}
+
+// prune drops days past the retention window; callers hold the lock
+func (t *AIUsageTracker) prune() {
+	cutoff := ReportingDate(StartOfDay(t.now(), t.loc).AddDate(0, 0, -aiUsageRetentionDays), t.loc)
+	for date := range t.days {
+		if date <= cutoff {
+			delete(t.days, date)
+		}
+	}
+}
+
+// Usage returns the usage of the last days (today included) that saw any calls, newest first
+func (t *AIUsageTracker) Usage(days int) []AIUsageDay {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	since := ReportingDate(StartOfDay(t.now(), t.loc).AddDate(0, 0, 1-days), t.loc)
+
+	result := make([]AIUsageDay, 0, len(t.days))
+	for date, operations := range t.days {
+		if date < since {
+			continue
+		}
+		day := AIUsageDay{Date: date, Operations: make(map[string]AIUsage, len(operations))}
+		for operation, usage := range operations {
+			day.Operations[operation] = *usage
+			day.Total.Add(*usage)
+		}
+		result = append(result, day)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date > result[j].Date
+	})
+	return result
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+func TestAIUsageTrackerAggregatesPerDayAndOperation(t *testing.T) {
+	tracker := NewAIUsageTracker(0.5, 1.5, time.UTC)
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	resp := &genai.GenerateContentResponse{UsageMetadata: &genai.UsageMetadata{PromptTokenCount: 1000, CandidatesTokenCount: 200}}
+	tracker.Record("keywords", resp, nil)
+	tracker.Record("keywords", resp, nil)
+	tracker.Record("moderation", nil, errors.New("deadline exceeded"))
+
+	now = now.AddDate(0, 0, -1)
+	tracker.RecordTokens("virality", 500, 100, false)
+	now = now.AddDate(0, 0, 1)
+
+	usage := tracker.Usage(7)
+	if len(usage) != 2 {
+		t.Fatalf("expected 2 days, got %d", len(usage))
+	}
+	if usage[0].Date != "2024-05-02" || usage[1].Date != "2024-05-01" {
+		t.Errorf("expected newest day first, got %s, %s", usage[0].Date, usage[1].Date)
+	}
+
+	today := usage[0]
+	keywords := today.Operations["keywords"]
+	if keywords.Calls != 2 || keywords.PromptTokens != 2000 || keywords.OutputTokens != 400 || keywords.TotalTokens != 2400 {
+		t.Errorf("unexpected keyword usage: %+v", keywords)
+	}
+	// 2000 input tokens at $0.5/M plus 400 output tokens at $1.5/M
+	if math.Abs(keywords.CostUSD-0.0016) > 1e-12 {
+		t.Errorf("expected cost 0.0016, got %f", keywords.CostUSD)
+	}
+	if moderation := today.Operations["moderation"]; moderation.Calls != 1 || moderation.Failed != 1 {
+		t.Errorf("expected one failed moderation call, got %+v", moderation)
+	}
+	if today.Total.Calls != 3 || today.Total.TotalTokens != 2400 {
+		t.Errorf("unexpected daily total: %+v", today.Total)
+	}
+
+	if got := tracker.Usage(1); len(got) != 1 {
+		t.Errorf("expected only today for a 1-day window, got %d days", len(got))
+	}
+}
+
+func TestAIUsageTrackerPrunesOldDays(t *testing.T) {
+	tracker := NewAIUsageTracker(0.5, 1.5, time.UTC)
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	tracker.RecordTokens("keywords", 10, 10, false)
+	now = now.AddDate(0, 0, aiUsageRetentionDays+1)
+	tracker.RecordTokens("keywords", 10, 10, false)
+
+	if len(tracker.days) != 1 {
+		t.Errorf("expected old day to be pruned, have %d days", len(tracker.days))
+	}
+}
+
+func TestAIUsageTrackerBucketsInReportingZone(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	tracker := NewAIUsageTracker(0.5, 1.5, loc)
+	// 03:00 UTC on May 2 is still May 1 in Los Angeles
+	tracker.now = func() time.Time { return time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC) }
+
+	tracker.RecordTokens("keywords", 10, 10, false)
+
+	usage := tracker.Usage(1)
+	if len(usage) != 1 || usage[0].Date != "2024-05-01" {
+		t.Errorf("expected usage on 2024-05-01, got %+v", usage)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// Alert severities
+const (
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// Alert is an operational notification delivered to the configured channels
+type Alert struct {
+	Type      string                 `json:"type"`
+	Severity  string                 `json:"severity"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Alerter delivers operational alerts to a webhook and/or email recipients.
+// Alerts are always logged, even when no channel is configured.
+type Alerter struct {
+	webhookURL string
+	emailTo    []string
+	emailFrom  string
+	smtpAddr   string
+	smtpAuth   smtp.Auth
+	httpClient *http.Client
+}
+
+// NewAlerter creates an alerter from the alerting configuration
+func NewAlerter(cfg *config.Config) *Alerter {
+	a := &Alerter{
+		webhookURL: cfg.AlertWebhookURL,
+		emailTo:    cfg.AlertEmailTo,
+		emailFrom:  cfg.AlertEmailFrom,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if cfg.SMTPHost != "" {
+		a.smtpAddr = cfg.SMTPHost + ":" + cfg.SMTPPort
+		if cfg.SMTPUsername != "" {
+			a.smtpAuth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+		}
+	}
+	return a
+}
+
+// Notify logs the alert and delivers it asynchronously to all configured channels
+func (a *Alerter) Notify(alert Alert) {
+	if alert.Timestamp.IsZero() {
+		alert.Timestamp = time.Now()
+	}
+	logger.Warnf("🚨 [%s] %s: %s", alert.Severity, alert.Type, alert.Message)
+
+	if a == nil {
+		return
+	}
+	if a.webhookURL != "" {
+		go func() {
+			if err := a.sendWebhook(alert); err != nil {
+				logger.Errorf("❌ Failed to deliver alert webhook: %v", err)
+			}
+		}()
+	}
+	if a.smtpAddr != "" && len(a.emailTo) > 0 {
+		go func() {
+			if err := a.sendEmail(alert); err != nil {
+				logger.Errorf("❌ Failed to deliver alert email: %v", err)
+			}
+		}()
+	}
+}
+
+func (a *Alerter) sendWebhook(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	resp, err := a.httpClient.Post(a.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (a *Alerter) sendEmail(alert Alert) error {
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(alert.Severity), alert.Type)
+
+	details, _ := json.MarshalIndent(alert.Details, "", "  ")
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n\r\n%s\r\n",
+		a.emailFrom, strings.Join(a.emailTo, ", "), subject, alert.Message, details)
+
+	return smtp.SendMail(a.smtpAddr, a.smtpAuth, a.emailFrom, a.emailTo, []byte(msg))
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Engagement anomaly kinds
+const (
+	AnomalyKindSpike = "spike"
+	AnomalyKindDrop  = "drop"
+)
+
+const (
+	// Posts whose velocity is followed before new posts are ignored
+	maxTrackedAnomalyPosts = 10000
+
+	// Weight of the latest window in a post's velocity baseline
+	anomalyBaselineAlpha = 0.1
+
+	// Windows without events after which a post stops being followed
+	anomalyIdleWindows = 60
+
+	// Windows after an anomaly before the same post is flagged again
+	anomalyCooldownWindows = 30
+)
+
+// engagementBaseline is the exponentially weighted mean and variance of the events a post
+// receives per window
+type engagementBaseline struct {
+	mean     float64
+	variance float64
+	windows  int
+	idle     int
+	cooldown int
+}
+
+// AnomalyDetector follows the engagement velocity of each post from consumed events and flags
+// windows whose event count lies far outside the post's baseline. Anomalies are stored in
+// Firestore and broadcast to WebSocket clients.
+type AnomalyDetector struct {
+	firestoreClient *FirestoreClient
+	hub             *WebSocketHub
+	ctx             context.Context
+	cancel          context.CancelFunc
+	window          time.Duration
+	zThreshold      float64
+	minEvents       int
+	warmupWindows   int
+
+	mu        sync.Mutex
+	counts    map[string]int // post -> events in the current window
+	baselines map[string]*engagementBaseline
+}
+
+// NewAnomalyDetector creates a detector that counts events per window. A window is anomalous
+// when its count is zThreshold standard deviations from the baseline, once the post has
+// warmupWindows of history; spikes need minEvents in the window and drops a baseline of
+// minEvents.
+func NewAnomalyDetector(firestoreClient *FirestoreClient, hub *WebSocketHub, window time.Duration, zThreshold float64, minEvents, warmupWindows int) *AnomalyDetector {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &AnomalyDetector{
+		firestoreClient: firestoreClient,
+		hub:             hub,
+		ctx:             ctx,
+		cancel:          cancel,
+		window:          window,
+		zThreshold:      zThreshold,
+		minEvents:       minEvents,
+		warmupWindows:   warmupWindows,
+		counts:          make(map[string]int),
+		baselines:       make(map[string]*engagementBaseline),
+	}
+}
+
+// Start begins evaluating a window of events every window interval
+func (ad *AnomalyDetector) Start() {
+	logger.Infof("🕵️ Starting anomaly detector (window %v, z-score threshold %.1f)", ad.window, ad.zThreshold)
+
+	ticker := time.NewTicker(ad.window)
+	go func() {
+		for {
+			select {
+			case <-ad.ctx.Done():
+				ticker.Stop()
+				logger.Info("🛑 Anomaly detector stopped")
+				return
+			case now := <-ticker.C:
+				ad.report(ad.evaluate(now))
+			}
+		}
+	}()
+}
+
+// Stop stops the evaluation loop
+func (ad *AnomalyDetector) Stop() {
+	ad.cancel()
+}
+
+// Record counts a consumed event on a post towards the current window
+func (ad *AnomalyDetector) Record(postID string) {
+	if ad == nil || postID == "" {
+		return
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	if _, ok := ad.counts[postID]; !ok {
+		if _, followed := ad.baselines[postID]; !followed && len(ad.baselines) >= maxTrackedAnomalyPosts {
+			return
+		}
+	}
+	ad.counts[postID]++
+}
+
+// evaluate closes the current window, compares each followed post's count with its baseline
+// and folds the count into the baseline
+func (ad *AnomalyDetector) evaluate(now time.Time) []models.EngagementAnomaly {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	counts := ad.counts
+	ad.counts = make(map[string]int)
+	for postID := range counts {
+		if _, ok := ad.baselines[postID]; !ok {
+			ad.baselines[postID] = &engagementBaseline{}
+		}
+	}
+
+	minutes := ad.window.Minutes()
+	var anomalies []models.EngagementAnomaly
+	for postID, baseline := range ad.baselines {
+		count := float64(counts[postID])
+
+		if baseline.windows >= ad.warmupWindows && baseline.cooldown == 0 {
+			if kind, z, ok := ad.classify(baseline, count); ok {
+				anomalies = append(anomalies, models.EngagementAnomaly{
+					PostID:           postID,
+					Kind:             kind,
+					Velocity:         count / minutes,
+					ExpectedVelocity: baseline.mean / minutes,
+					ZScore:           z,
+					WindowSeconds:    int(ad.window.Seconds()),
+					DetectedAt:       now,
+				})
+				baseline.cooldown = anomalyCooldownWindows + 1
+			}
+		}
+		if baseline.cooldown > 0 {
+			baseline.cooldown--
+		}
+
+		baseline.update(count)
+		if count == 0 {
+			baseline.idle++
+		} else {
+			baseline.idle = 0
+		}
+		if baseline.idle >= anomalyIdleWindows {
+			delete(ad.baselines, postID)
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		return math.Abs(anomalies[i].ZScore) > math.Abs(anomalies[j].ZScore)
+	})
+	return anomalies
+}
+
+// classify reports whether a window count is a spike or a drop against the baseline. The
+// deviation is never taken below the Poisson noise of the baseline, so quiet posts with a
+// near constant trickle of events do not alert on a handful of extra ones.
+func (ad *AnomalyDetector) classify(baseline *engagementBaseline, count float64) (string, float64, bool) {
+	stddev := math.Max(math.Sqrt(baseline.variance), math.Sqrt(math.Max(baseline.mean, 1)))
+	z := (count - baseline.mean) / stddev
+
+	switch {
+	case z >= ad.zThreshold && count >= float64(ad.minEvents):
+		return AnomalyKindSpike, z, true
+	case z <= -ad.zThreshold && baseline.mean >= float64(ad.minEvents):
+		return AnomalyKindDrop, z, true
+	}
+	return "", z, false
+}
+
+// update folds a window count into the exponentially weighted mean and variance
+func (b *engagementBaseline) update(count float64) {
+	if b.windows == 0 {
+		b.mean = count
+	} else {
+		diff := count - b.mean
+		increment := anomalyBaselineAlpha * diff
+		b.mean += increment
+		b.variance = (1 - anomalyBaselineAlpha) * (b.variance + diff*increment)
+	}
+	b.windows++
+}
+
+// report stores and broadcasts detected anomalies
+func (ad *AnomalyDetector) report(anomalies []models.EngagementAnomaly) {
+	for _, anomaly := range anomalies {
+		logger.Warnf("🕵️ Engagement %s on post %s: %.1f events/min against %.1f expected (z=%.1f)",
+			anomaly.Kind, anomaly.PostID, anomaly.Velocity, anomaly.ExpectedVelocity, anomaly.ZScore)
+
+		if err := ad.firestoreClient.SaveEngagementAnomaly(anomaly); err != nil {
+			logger.Errorf("❌ Failed to save engagement anomaly for post %s: %v", anomaly.PostID, err)
+		}
+		if ad.hub != nil {
+			ad.hub.BroadcastAnomalyAlert(anomaly)
+		}
+	}
+}
+
+// SaveEngagementAnomaly writes an anomaly record
+func (fc *FirestoreClient) SaveEngagementAnomaly(anomaly models.EngagementAnomaly) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, _, err := fc.client.Collection("engagement_anomalies").Add(fc.ctx, anomaly)
+	return err
+}
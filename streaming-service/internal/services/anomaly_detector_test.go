@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func recordEvents(ad *AnomalyDetector, postID string, count int) {
+	for i := 0; i < count; i++ {
+		ad.Record(postID)
+	}
+}
+
+func TestAnomalyDetectorFlagsSpikesAndDrops(t *testing.T) {
+	ad := NewAnomalyDetector(nil, nil, time.Minute, 4, 20, 5)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Steady baselines: 10 and 40 events a minute
+	for i := 0; i < 5; i++ {
+		recordEvents(ad, "bots", 10+i%2)
+		recordEvents(ad, "fading", 40-i%2)
+		if anomalies := ad.evaluate(now); len(anomalies) != 0 {
+			t.Fatalf("expected no anomalies while warming up, got %+v", anomalies)
+		}
+	}
+
+	recordEvents(ad, "bots", 200)
+	recordEvents(ad, "fading", 2)
+	anomalies := ad.evaluate(now)
+	if len(anomalies) != 2 {
+		t.Fatalf("expected a spike and a drop, got %+v", anomalies)
+	}
+	if anomalies[0].PostID != "bots" || anomalies[0].Kind != AnomalyKindSpike || anomalies[0].Velocity != 200 || anomalies[0].ZScore < 4 {
+		t.Errorf("expected a spike on bots first, got %+v", anomalies[0])
+	}
+	if anomalies[1].PostID != "fading" || anomalies[1].Kind != AnomalyKindDrop || anomalies[1].ZScore > -4 {
+		t.Errorf("expected a drop on fading, got %+v", anomalies[1])
+	}
+
+	// The same posts are not flagged again during the cooldown
+	recordEvents(ad, "bots", 400)
+	if anomalies := ad.evaluate(now); len(anomalies) != 0 {
+		t.Errorf("expected no repeat alerts during the cooldown, got %+v", anomalies)
+	}
+}
+
+func TestAnomalyDetectorIgnoresQuietPosts(t *testing.T) {
+	ad := NewAnomalyDetector(nil, nil, time.Minute, 4, 20, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		recordEvents(ad, "quiet", 1)
+		ad.evaluate(now)
+	}
+
+	// A jump from one to eight events is many deviations but below the spike floor
+	recordEvents(ad, "quiet", 8)
+	if anomalies := ad.evaluate(now); len(anomalies) != 0 {
+		t.Errorf("expected small posts not to alert, got %+v", anomalies)
+	}
+
+	// Posts without events for long enough are no longer followed
+	for i := 0; i < anomalyIdleWindows; i++ {
+		ad.evaluate(now)
+	}
+	if len(ad.baselines) != 0 {
+		t.Errorf("expected idle posts to be dropped, got %d", len(ad.baselines))
+	}
+}
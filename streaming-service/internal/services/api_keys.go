@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+// Roles an API key can hold. Admin keys may call every protected route.
+const (
+	RoleIngest = "ingest" // event ingestion
+	RoleRead   = "read"   // analytics, metrics and schemas, when reads are protected
+	RoleAdmin  = "admin"  // admin operations and jobs
+)
+
+// APIKey is a configured key, identified by the SHA-256 hash of its secret
+type APIKey struct {
+	Name string  `json:"name"`
+	Role string  `json:"role"`
+	Hash string  `json:"-"`
+	QPS  float64 `json:"qps,omitempty"` // overrides the default rate limit; 0 keeps it
+}
+
+// Allows reports whether the key may call routes that require role
+func (k *APIKey) Allows(role string) bool {
+	return k.Role == RoleAdmin || k.Role == role
+}
+
+// HashAPIKey returns the hex SHA-256 hash a key's secret is configured by
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseAPIKeys parses keys given as comma-separated name:role:sha256 entries, each with an
+// optional :qps suffix
+func ParseAPIKeys(value string) ([]APIKey, error) {
+	var keys []APIKey
+	names := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 || len(parts) > 4 {
+			return nil, fmt.Errorf("API key %q must be name:role:sha256 with an optional :qps", entry)
+		}
+		key := APIKey{Name: parts[0], Role: parts[1], Hash: strings.ToLower(parts[2])}
+		if key.Name == "" || names[key.Name] {
+			return nil, fmt.Errorf("API key %q needs a unique name", entry)
+		}
+		switch key.Role {
+		case RoleIngest, RoleRead, RoleAdmin:
+		default:
+			return nil, fmt.Errorf("API key %s has unknown role %q", key.Name, key.Role)
+		}
+		if hash, err := hex.DecodeString(key.Hash); err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("API key %s must be configured by its hex SHA-256 hash", key.Name)
+		}
+		if len(parts) == 4 {
+			qps, err := strconv.ParseFloat(parts[3], 64)
+			if err != nil || qps <= 0 {
+				return nil, fmt.Errorf("API key %s has invalid rate limit %q", key.Name, parts[3])
+			}
+			key.QPS = qps
+		}
+
+		names[key.Name] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Reasons Authorize turns a request down
+var (
+	ErrNoAPIKeys         = errors.New("no API keys configured")
+	ErrInvalidAPIKey     = errors.New("missing or invalid API key")
+	ErrAPIKeyRole        = errors.New("API key lacks the role")
+	ErrAPIKeyRateLimited = errors.New("rate limit exceeded for API key")
+)
+
+// APIKeyStore authenticates API keys against their configured hashes and rate limits each key.
+// A nil store means no keys are configured: protected routes reject every request unless
+// unauthenticated access is allowed.
+type APIKeyStore struct {
+	keys    []APIKey
+	limiter *KeyedRateLimiter
+}
+
+// NewAPIKeyStore returns the store of the configured API keys, or nil when none are configured
+func NewAPIKeyStore(cfg *config.Config) (*APIKeyStore, error) {
+	keys, err := ParseAPIKeys(cfg.APIKeys)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+
+	// Requests over a key's rate fail at once rather than queue
+	policy := RateLimitPolicy{
+		QPS:    cfg.APIKeyRateLimitQPS,
+		Burst:  cfg.APIKeyRateLimitBurst,
+		Shed:   ShedRejectNew,
+		KeyQPS: make(map[string]float64),
+	}
+	for _, key := range keys {
+		if key.QPS > 0 {
+			policy.KeyQPS[key.Name] = key.QPS
+		}
+	}
+	return &APIKeyStore{keys: keys, limiter: NewKeyedRateLimiter(policy)}, nil
+}
+
+// Authenticate returns the key whose hash matches secret. Every configured hash is compared
+// in constant time, so the time taken does not reveal which keys exist.
+func (s *APIKeyStore) Authenticate(secret string) (*APIKey, bool) {
+	if s == nil || secret == "" {
+		return nil, false
+	}
+
+	hash := []byte(HashAPIKey(secret))
+	var found *APIKey
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(hash, []byte(s.keys[i].Hash)) == 1 {
+			found = &s.keys[i]
+		}
+	}
+	return found, found != nil
+}
+
+// Authorize returns the key of secret when it may call routes that require role and fits
+// within its rate limit. A nil store authorizes nothing.
+func (s *APIKeyStore) Authorize(secret, role string) (*APIKey, error) {
+	if s == nil {
+		return nil, ErrNoAPIKeys
+	}
+	key, ok := s.Authenticate(secret)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	if !key.Allows(role) {
+		return key, ErrAPIKeyRole
+	}
+	if !s.Allow(key) {
+		return key, ErrAPIKeyRateLimited
+	}
+	return key, nil
+}
+
+// Allow reports whether a request of key fits within its rate limit
+func (s *APIKeyStore) Allow(key *APIKey) bool {
+	if s == nil {
+		return true
+	}
+	return s.limiter.Wait(context.Background(), key.Name) == nil
+}
+
+// Keys returns the configured keys, without their hashes
+func (s *APIKeyStore) Keys() []APIKey {
+	if s == nil {
+		return []APIKey{}
+	}
+	return append([]APIKey{}, s.keys...)
+}
+
+// Stats returns the traffic of every key that made requests, for diagnostics
+func (s *APIKeyStore) Stats() []RateLimitStats {
+	if s == nil {
+		return []RateLimitStats{}
+	}
+	return s.limiter.Stats()
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	hash := HashAPIKey("secret")
+	keys, err := ParseAPIKeys(" ingest-bot:ingest:" + hash + ", ops:admin:" + hash + ":2.5,")
+	if err != nil {
+		t.Fatalf("Failed to parse keys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Name != "ingest-bot" || keys[0].QPS != 0 || keys[1].Role != RoleAdmin || keys[1].QPS != 2.5 {
+		t.Errorf("Unexpected keys %+v", keys)
+	}
+
+	for _, value := range []string{
+		"ops:admin",
+		"ops:owner:" + hash,
+		"ops:admin:secret",
+		"ops:admin:" + hash + ":0",
+		"ops:admin:" + hash + ",ops:read:" + hash,
+	} {
+		if _, err := ParseAPIKeys(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestAPIKeyStore(t *testing.T) {
+	store, err := NewAPIKeyStore(&config.Config{
+		APIKeys:              "reader:read:" + HashAPIKey("read-secret") + ",ops:admin:" + HashAPIKey("admin-secret") + ":1",
+		APIKeyRateLimitQPS:   100,
+		APIKeyRateLimitBurst: 1,
+	})
+	if err != nil || store == nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if _, ok := store.Authenticate("wrong"); ok {
+		t.Error("Expected an unknown key to be rejected")
+	}
+	reader, ok := store.Authenticate("read-secret")
+	if !ok || reader.Name != "reader" || !reader.Allows(RoleRead) || reader.Allows(RoleAdmin) || reader.Allows(RoleIngest) {
+		t.Errorf("Unexpected reader %+v", reader)
+	}
+	admin, ok := store.Authenticate("admin-secret")
+	if !ok || !admin.Allows(RoleIngest) || !admin.Allows(RoleRead) {
+		t.Errorf("Expected the admin key to allow every role, got %+v", admin)
+	}
+
+	// One token of burst: the second request in a row is over the limit
+	if !store.Allow(admin) || store.Allow(admin) {
+		t.Error("Expected the second admin request to be rate limited")
+	}
+	if !store.Allow(reader) {
+		t.Error("Expected keys to be limited separately")
+	}
+
+	if empty, err := NewAPIKeyStore(&config.Config{}); empty != nil || err != nil {
+		t.Errorf("Expected no store without keys, got %v (%v)", empty, err)
+	}
+	var off *APIKeyStore
+	if _, ok := off.Authenticate("read-secret"); ok || len(off.Keys()) != 0 {
+		t.Error("Expected a nil store to authenticate nothing")
+	}
+}
+
+func TestAPIKeyStoreAuthorize(t *testing.T) {
+	store, err := NewAPIKeyStore(&config.Config{
+		APIKeys:              "ingester:ingest:" + HashAPIKey("ingest-secret"),
+		APIKeyRateLimitQPS:   100,
+		APIKeyRateLimitBurst: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if key, err := store.Authorize("ingest-secret", RoleIngest); err != nil || key.Name != "ingester" {
+		t.Errorf("Expected the ingest key to be authorized, got %v (%v)", key, err)
+	}
+	if _, err := store.Authorize("ingest-secret", RoleIngest); !errors.Is(err, ErrAPIKeyRateLimited) {
+		t.Errorf("Expected the second request to be rate limited, got %v", err)
+	}
+	if _, err := store.Authorize("ingest-secret", RoleAdmin); !errors.Is(err, ErrAPIKeyRole) {
+		t.Errorf("Expected the ingest key to lack the admin role, got %v", err)
+	}
+	if _, err := store.Authorize("wrong", RoleIngest); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected an unknown key to be rejected, got %v", err)
+	}
+
+	// Without configured keys nothing is authorized
+	var none *APIKeyStore
+	if _, err := none.Authorize("ingest-secret", RoleIngest); !errors.Is(err, ErrNoAPIKeys) {
+		t.Errorf("Expected a nil store to reject every request, got %v", err)
+	}
+}
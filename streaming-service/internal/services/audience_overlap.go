@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Post owners remembered by the audience tracker before the cache is reset
+const maxCachedPostOwners = 10000
+
+// creatorAudience is the stored viewer sketch of a creator
+type creatorAudience struct {
+	UserID    string
+	Sketch    []byte
+	UpdatedAt time.Time
+}
+
+// AudienceTracker collects the viewers of each creator's posts into HyperLogLog sketches.
+// New viewers are buffered in memory and merged into Firestore on every flush; merging is
+// idempotent, so several instances can flush the same creator safely.
+type AudienceTracker struct {
+	firestoreClient *FirestoreClient
+	flusher         *periodicFlusher
+
+	mu      sync.Mutex
+	pending map[string]*HyperLogLog // creator -> viewers since the last flush
+	owners  map[string]string       // post -> creator
+}
+
+// audienceSketchBytes is the memory accounted for a creator's buffered sketch
+func audienceSketchBytes(creatorID string) int64 {
+	return int64(hllRegisters + len(creatorID) + memoryEntryOverhead)
+}
+
+func NewAudienceTracker(firestoreClient *FirestoreClient, flushInterval time.Duration) *AudienceTracker {
+	at := &AudienceTracker{
+		firestoreClient: firestoreClient,
+		pending:         make(map[string]*HyperLogLog),
+		owners:          make(map[string]string),
+	}
+	at.flusher = newPeriodicFlusher("creator audiences", flushInterval, at.Flush)
+	return at
+}
+
+// Start merges the buffered viewer sketches into the stored creator audiences every flush
+// interval
+func (at *AudienceTracker) Start() {
+	logger.Infof("👥 Starting audience tracker (flush interval %v)", at.flusher.interval)
+	at.flusher.start()
+}
+
+// Stop ends the periodic merges and merges the viewers still buffered
+func (at *AudienceTracker) Stop() {
+	at.flusher.stop()
+}
+
+// RecordView adds the viewer of a post to its creator's audience. Creators viewing their
+// own posts are not counted.
+func (at *AudienceTracker) RecordView(postID, viewerID string) {
+	if at == nil || postID == "" || viewerID == "" {
+		return
+	}
+
+	creatorID, err := at.postOwner(postID)
+	if err != nil {
+		logger.Debugf(" Could not resolve creator of post %s: %v", postID, err)
+		return
+	}
+	if creatorID == "" || creatorID == viewerID {
+		return
+	}
+
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	sketch, ok := at.pending[creatorID]
+	if !ok {
+		if !Memory.Reserve(MemoryPoolAudience, audienceSketchBytes(creatorID)) {
+			// Buffered sketches are at their ceiling; write them out early and drop this view
+			at.flusher.flushEarly()
+			return
+		}
+		sketch = NewHyperLogLog()
+		at.pending[creatorID] = sketch
+	}
+	sketch.Add(viewerID)
+}
+
+// postOwner returns the creator of a post, reading the post document on a cache miss
+func (at *AudienceTracker) postOwner(postID string) (string, error) {
+	at.mu.Lock()
+	owner, ok := at.owners[postID]
+	at.mu.Unlock()
+	if ok {
+		return owner, nil
+	}
+
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := at.firestoreClient.client.Collection("posts").Doc(postID).Get(at.firestoreClient.ctx)
+	if err != nil {
+		return "", err
+	}
+	owner, _ = doc.Data()["userId"].(string)
+
+	at.mu.Lock()
+	if len(at.owners) >= maxCachedPostOwners {
+		at.owners = make(map[string]string)
+	}
+	at.owners[postID] = owner
+	at.mu.Unlock()
+	return owner, nil
+}
+
+// Flush merges the buffered viewers into the stored creator sketches
+func (at *AudienceTracker) Flush() error {
+	at.mu.Lock()
+	pending := at.pending
+	at.pending = make(map[string]*HyperLogLog)
+	at.mu.Unlock()
+
+	var lastErr error
+	for creatorID, sketch := range pending {
+		if err := at.firestoreClient.MergeCreatorAudience(creatorID, sketch); err != nil {
+			logger.Infof("Failed to merge audience of creator %s: %v", creatorID, err)
+			lastErr = err
+
+			// Keep the viewers for the next flush
+			at.mu.Lock()
+			if newer, ok := at.pending[creatorID]; ok {
+				sketch.Merge(newer)
+				Memory.Release(MemoryPoolAudience, audienceSketchBytes(creatorID))
+			}
+			at.pending[creatorID] = sketch
+			at.mu.Unlock()
+			continue
+		}
+		Memory.Release(MemoryPoolAudience, audienceSketchBytes(creatorID))
+	}
+
+	if len(pending) > 0 {
+		logger.Debugf("👥 Flushed audiences of %d creators", len(pending))
+	}
+	return lastErr
+}
+
+// MergeCreatorAudience folds a viewer sketch into a creator's stored audience
+func (fc *FirestoreClient) MergeCreatorAudience(creatorID string, sketch *HyperLogLog) error {
+	ref := fc.client.Collection("creator_audiences").Doc(creatorID)
+
+	return fc.client.RunTransaction(fc.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// One read and one write per attempt
+		Quotas.Record(QuotaFirestore, 2)
+
+		merged := NewHyperLogLog()
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var stored creatorAudience
+			if err := doc.DataTo(&stored); err != nil {
+				return fmt.Errorf("failed to parse audience of creator %s: %w", creatorID, err)
+			}
+			if existing, err := HyperLogLogFromBytes(stored.Sketch); err == nil {
+				merged = existing
+			}
+		}
+		merged.Merge(sketch)
+
+		return tx.Set(ref, creatorAudience{
+			UserID:    creatorID,
+			Sketch:    merged.Bytes(),
+			UpdatedAt: time.Now(),
+		})
+	})
+}
+
+// CreatorOverlap is the estimated shared audience of two creators
+type CreatorOverlap struct {
+	CreatorID     string  `json:"creatorId"`
+	AudienceSize  uint64  `json:"audienceSize"`
+	SharedViewers uint64  `json:"sharedViewers"`
+	OverlapRatio  float64 `json:"overlapRatio"` // shared viewers / requesting creator's audience
+	Jaccard       float64 `json:"jaccard"`      // shared viewers / combined audience
+}
+
+// AudienceOverlap lists the creators whose audiences overlap most with a creator's
+type AudienceOverlap struct {
+	CreatorID    string           `json:"creatorId"`
+	AudienceSize uint64           `json:"audienceSize"`
+	Overlaps     []CreatorOverlap `json:"overlaps"`
+	CalculatedAt time.Time        `json:"calculatedAt"`
+}
+
+// GetAudienceOverlap estimates the audience overlap between a creator and every other
+// tracked creator, largest shared audience first. Returns nil when the creator has no
+// tracked audience.
+func (da *DashboardAnalytics) GetAudienceOverlap(creatorID string, limit int) (*AudienceOverlap, error) {
+	logger.Debugf("📊 Calculating audience overlap for creator %s...", creatorID)
+
+	doc, err := da.firestoreClient.client.Collection("creator_audiences").Doc(creatorID).Get(da.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored creatorAudience
+	if err := doc.DataTo(&stored); err != nil {
+		return nil, err
+	}
+	audience, err := HyperLogLogFromBytes(stored.Sketch)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AudienceOverlap{
+		CreatorID:    creatorID,
+		AudienceSize: audience.Count(),
+		Overlaps:     []CreatorOverlap{},
+		CalculatedAt: time.Now(),
+	}
+
+	iter := da.firestoreClient.client.Collection("creator_audiences").Documents(da.ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc.Ref.ID == creatorID {
+			continue
+		}
+
+		var other creatorAudience
+		if err := doc.DataTo(&other); err != nil {
+			continue
+		}
+		otherAudience, err := HyperLogLogFromBytes(other.Sketch)
+		if err != nil {
+			continue
+		}
+
+		if overlap := compareAudiences(audience, otherAudience); overlap.SharedViewers > 0 {
+			overlap.CreatorID = doc.Ref.ID
+			result.Overlaps = append(result.Overlaps, overlap)
+		}
+	}
+
+	sort.Slice(result.Overlaps, func(i, j int) bool {
+		return result.Overlaps[i].SharedViewers > result.Overlaps[j].SharedViewers
+	})
+	if len(result.Overlaps) > limit {
+		result.Overlaps = result.Overlaps[:limit]
+	}
+
+	logger.Infof("✅ Audience overlap calculated for creator %s: %d overlapping creators", creatorID, len(result.Overlaps))
+	return result, nil
+}
+
+// compareAudiences estimates the overlap of another creator's audience with a creator's
+func compareAudiences(audience, other *HyperLogLog) CreatorOverlap {
+	size, otherSize := audience.Count(), other.Count()
+	shared := estimateIntersection(audience, other)
+
+	overlap := CreatorOverlap{
+		AudienceSize:  otherSize,
+		SharedViewers: shared,
+	}
+	if size > 0 {
+		overlap.OverlapRatio = float64(shared) / float64(size)
+	}
+	if combined := size + otherSize - shared; combined > 0 {
+		overlap.Jaccard = float64(shared) / float64(combined)
+	}
+	return overlap
+}
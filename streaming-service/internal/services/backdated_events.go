@@ -0,0 +1,117 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Timing of a consumed event relative to its arrival
+const (
+	EventOnTime        = "on_time"
+	EventBackdated     = "backdated"      // late, but inside the reprocessing window
+	EventOutsideWindow = "outside_window" // older than the reprocessing window
+)
+
+// classifyEventTime tells on-time events from late ones. Events without a timestamp are
+// treated as on time.
+func classifyEventTime(eventTime, now time.Time, lateness, window time.Duration) string {
+	lag := now.Sub(eventTime)
+	switch {
+	case eventTime.IsZero() || lag <= lateness:
+		return EventOnTime
+	case lag <= window:
+		return EventBackdated
+	default:
+		return EventOutsideWindow
+	}
+}
+
+// ApplyBackdatedEvent credits a late event to a post's trending score. count adds the event to
+// the score's counters. When reprocess is set the score is recalculated from the post's age, as
+// the trending updater does, instead of treating the event as fresh engagement; otherwise only
+// the counters change and the next trending update picks them up.
+func (fc *FirestoreClient) ApplyBackdatedEvent(postID, source string, eventTime time.Time, reprocess bool, count func(score *models.TrendingScore)) error {
+	var createdAt time.Time
+	if reprocess {
+		// The post existed at least since the event happened
+		createdAt = fc.PostCreatedAt(postID, eventTime)
+	}
+
+	_, err := fc.ApplyTrendingScore(postID, source+":backdated", func(score *models.TrendingScore, exists bool) {
+		count(score)
+		if !reprocess {
+			return
+		}
+		fc.scoring.Apply(score, createdAt)
+		score.CalculatedAt = time.Now()
+	})
+	return err
+}
+
+// BackdatedEvents is the process-wide record of late events and the corrections made for them
+var BackdatedEvents = NewBackdatedMetrics()
+
+// BackdatedMetrics counts late events per source (view, remix, interaction:<type>)
+type BackdatedMetrics struct {
+	mu      sync.Mutex
+	sources map[string]*BackdatedCounts
+	since   time.Time
+}
+
+// BackdatedCounts counts the late events of one source
+type BackdatedCounts struct {
+	Corrected     int64   `json:"corrected"`      // scores recalculated from the post's age
+	OutsideWindow int64   `json:"outside_window"` // counted without reprocessing
+	Failed        int64   `json:"failed"`
+	MaxLagSeconds float64 `json:"max_lag_seconds"`
+}
+
+func NewBackdatedMetrics() *BackdatedMetrics {
+	return &BackdatedMetrics{
+		sources: make(map[string]*BackdatedCounts),
+		since:   time.Now(),
+	}
+}
+
+// Record counts a late event of the given timing that arrived lag after it happened
+func (m *BackdatedMetrics) Record(source, timing string, lag time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts, ok := m.sources[source]
+	if !ok {
+		counts = &BackdatedCounts{}
+		m.sources[source] = counts
+	}
+
+	switch {
+	case err != nil:
+		counts.Failed++
+	case timing == EventBackdated:
+		counts.Corrected++
+	case timing == EventOutsideWindow:
+		counts.OutsideWindow++
+	}
+	if seconds := lag.Seconds(); seconds > counts.MaxLagSeconds {
+		counts.MaxLagSeconds = seconds
+	}
+}
+
+// Snapshot returns the counts per source
+func (m *BackdatedMetrics) Snapshot() map[string]BackdatedCounts {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]BackdatedCounts, len(m.sources))
+	for source, counts := range m.sources {
+		snapshot[source] = *counts
+	}
+	return snapshot
+}
+
+// Since returns when counting started
+func (m *BackdatedMetrics) Since() time.Time {
+	return m.since
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClassifyEventTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	lateness, window := 10*time.Minute, 72*time.Hour
+
+	cases := []struct {
+		name      string
+		eventTime time.Time
+		want      string
+	}{
+		{"no timestamp", time.Time{}, EventOnTime},
+		{"just now", now.Add(-time.Minute), EventOnTime},
+		{"clock skew", now.Add(time.Minute), EventOnTime},
+		{"late", now.Add(-2 * time.Hour), EventBackdated},
+		{"window edge", now.Add(-window), EventBackdated},
+		{"imported", now.AddDate(0, 0, -30), EventOutsideWindow},
+	}
+	for _, tc := range cases {
+		if got := classifyEventTime(tc.eventTime, now, lateness, window); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestBackdatedMetricsRecord(t *testing.T) {
+	metrics := NewBackdatedMetrics()
+	metrics.Record("view", EventBackdated, 2*time.Hour, nil)
+	metrics.Record("view", EventBackdated, time.Hour, nil)
+	metrics.Record("view", EventOutsideWindow, 30*24*time.Hour, nil)
+	metrics.Record("remix", EventBackdated, time.Hour, errors.New("aborted"))
+
+	snapshot := metrics.Snapshot()
+	view := snapshot["view"]
+	if view.Corrected != 2 || view.OutsideWindow != 1 || view.Failed != 0 {
+		t.Errorf("unexpected view counts: %+v", view)
+	}
+	if view.MaxLagSeconds != (30 * 24 * time.Hour).Seconds() {
+		t.Errorf("expected max lag of 30 days, got %fs", view.MaxLagSeconds)
+	}
+	if remix := snapshot["remix"]; remix.Failed != 1 || remix.Corrected != 0 {
+		t.Errorf("expected one failed remix correction, got %+v", remix)
+	}
+}
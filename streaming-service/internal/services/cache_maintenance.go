@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// CacheStats reports the maintenance of one TTL cache
+type CacheStats struct {
+	Name        string    `json:"name"`
+	Size        int       `json:"size"`         // entries after the last run
+	Evicted     int64     `json:"evicted"`      // entries evicted since startup
+	LastEvicted int       `json:"last_evicted"` // entries evicted by the last run
+	Runs        int64     `json:"runs"`
+	Errors      int64     `json:"errors"`
+	LastRunAt   time.Time `json:"last_run_at,omitempty"`
+}
+
+// maintainedCache is a registered cache with its eviction and size functions
+type maintainedCache struct {
+	evict func(ctx context.Context) (int, error)
+	size  func(ctx context.Context) (int, error)
+	stats CacheStats
+}
+
+// CacheMaintainer evicts expired entries from every registered TTL cache on a single schedule
+// and keeps eviction counts and sizes per cache
+type CacheMaintainer struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	interval time.Duration
+
+	mu     sync.Mutex
+	caches []*maintainedCache
+}
+
+func NewCacheMaintainer(interval time.Duration) *CacheMaintainer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &CacheMaintainer{
+		ctx:      ctx,
+		cancel:   cancel,
+		interval: interval,
+	}
+}
+
+// Register adds a cache to maintain. evict removes expired entries and returns how many it
+// removed; size returns the number of entries left.
+func (cm *CacheMaintainer) Register(name string, evict, size func(ctx context.Context) (int, error)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.caches = append(cm.caches, &maintainedCache{
+		evict: evict,
+		size:  size,
+		stats: CacheStats{Name: name},
+	})
+}
+
+// Start begins the periodic maintenance loop
+func (cm *CacheMaintainer) Start() {
+	logger.Infof("🧹 Starting cache maintenance (interval %v)", cm.interval)
+
+	ticker := time.NewTicker(cm.interval)
+	go func() {
+		for {
+			select {
+			case <-cm.ctx.Done():
+				ticker.Stop()
+				logger.Info("🛑 Cache maintenance stopped")
+				return
+			case <-ticker.C:
+				cm.Run()
+			}
+		}
+	}()
+}
+
+// Stop stops the maintenance loop
+func (cm *CacheMaintainer) Stop() {
+	cm.cancel()
+}
+
+// Run evicts expired entries from every registered cache once
+func (cm *CacheMaintainer) Run() {
+	cm.mu.Lock()
+	caches := append([]*maintainedCache(nil), cm.caches...)
+	cm.mu.Unlock()
+
+	for _, cache := range caches {
+		removed, err := cache.evict(cm.ctx)
+		size, sizeErr := cache.size(cm.ctx)
+
+		cm.mu.Lock()
+		stats := &cache.stats
+		stats.Runs++
+		stats.LastRunAt = time.Now()
+		stats.LastEvicted = removed
+		stats.Evicted += int64(removed)
+		if err != nil {
+			stats.Errors++
+		}
+		if sizeErr == nil {
+			stats.Size = size
+		}
+		cm.mu.Unlock()
+
+		if err != nil {
+			logger.Infof("Eviction from cache %s failed: %v", stats.Name, err)
+		} else if removed > 0 {
+			logger.Debugf("🧹 Evicted %d entries from cache %s, %d left", removed, stats.Name, size)
+		}
+	}
+}
+
+// Stats returns the maintenance stats of every registered cache, in registration order
+func (cm *CacheMaintainer) Stats() []CacheStats {
+	if cm == nil {
+		return []CacheStats{}
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	stats := make([]CacheStats, 0, len(cm.caches))
+	for _, cache := range cm.caches {
+		stats = append(stats, cache.stats)
+	}
+	return stats
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheMaintainerRun(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryAICache(0)
+	cache.memory = NewMemoryGuard()
+	cache.Set(ctx, "fresh", []byte("v"), time.Hour)
+	cache.Set(ctx, "stale", []byte("v"), time.Hour)
+	cache.entries["stale"].expiresAt = time.Now().Add(-time.Minute)
+
+	cm := NewCacheMaintainer(time.Minute)
+	cm.Register("ai_cache", cache.EvictExpired, cache.Len)
+	cm.Register("broken", func(context.Context) (int, error) {
+		return 0, errors.New("unavailable")
+	}, func(context.Context) (int, error) {
+		return 3, nil
+	})
+
+	cm.Run()
+	cm.Run()
+
+	stats := cm.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 caches, got %d", len(stats))
+	}
+	if ai := stats[0]; ai.Name != "ai_cache" || ai.Evicted != 1 || ai.LastEvicted != 0 || ai.Size != 1 || ai.Runs != 2 {
+		t.Errorf("unexpected ai_cache stats: %+v", ai)
+	}
+	if broken := stats[1]; broken.Errors != 2 || broken.Size != 3 {
+		t.Errorf("unexpected broken cache stats: %+v", broken)
+	}
+
+	var nilMaintainer *CacheMaintainer
+	if stats := nilMaintainer.Stats(); len(stats) != 0 {
+		t.Errorf("expected no stats without a maintainer, got %v", stats)
+	}
+}
+
+func TestPredictorDualRunEvictExpired(t *testing.T) {
+	d := &predictorDualRun{lastCompared: map[string]time.Time{
+		"recent": time.Now(),
+		"old":    time.Now().Add(-dualRunCompareInterval - time.Second),
+	}}
+
+	removed, _ := d.evictExpired(context.Background())
+	size, _ := d.len(context.Background())
+	if removed != 1 || size != 1 {
+		t.Errorf("expected 1 entry evicted and 1 left, got %d and %d", removed, size)
+	}
+	if _, ok := d.lastCompared["recent"]; !ok {
+		t.Error("expected recently compared post to be kept")
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen is returned without calling the dependency while the breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is a point-in-time view of a circuit breaker
+type BreakerState struct {
+	Name                string    `json:"name"`
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	FailureThreshold    int       `json:"failure_threshold"`
+	OpenDuration        string    `json:"open_duration"`
+	OpenedAt            time.Time `json:"opened_at,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	TotalFailures       int64     `json:"total_failures"`
+	TotalRejected       int64     `json:"total_rejected"`
+}
+
+// CircuitBreaker stops calling a failing dependency after a run of consecutive failures.
+// After the open duration a single trial call is let through; its outcome closes or re-opens
+// the breaker.
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	openDuration     time.Duration
+
+	mu            sync.Mutex
+	state         string
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+	lastError     string
+	totalFailures int64
+	totalRejected int64
+}
+
+func NewCircuitBreaker(name string, failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		state:            BreakerClosed,
+	}
+}
+
+// Execute runs fn if the breaker allows it and records the outcome. Permanent errors mean
+// the dependency answered, so they do not count as failures.
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	if err := cb.allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	if err != nil && isRetryable(err) {
+		cb.recordFailure(err)
+	} else {
+		cb.recordSuccess()
+	}
+	return err
+}
+
+func (cb *CircuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		if time.Since(cb.openedAt) < cb.openDuration {
+			cb.totalRejected++
+			return ErrCircuitOpen
+		}
+		cb.state = BreakerHalfOpen
+		cb.trialInFlight = true
+		return nil
+	case BreakerHalfOpen:
+		// Only one trial call at a time
+		if cb.trialInFlight {
+			cb.totalRejected++
+			return ErrCircuitOpen
+		}
+		cb.trialInFlight = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (cb *CircuitBreaker) recordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.state = BreakerClosed
+	cb.failures = 0
+	cb.trialInFlight = false
+}
+
+func (cb *CircuitBreaker) recordFailure(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.totalFailures++
+	cb.lastError = err.Error()
+	cb.trialInFlight = false
+
+	if cb.state == BreakerHalfOpen || cb.failures >= cb.failureThreshold {
+		cb.state = BreakerOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// State returns the current breaker state
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	state := cb.state
+	if state == BreakerOpen && time.Since(cb.openedAt) >= cb.openDuration {
+		state = BreakerHalfOpen
+	}
+
+	bs := BreakerState{
+		Name:                cb.name,
+		State:               state,
+		ConsecutiveFailures: cb.failures,
+		FailureThreshold:    cb.failureThreshold,
+		OpenDuration:        cb.openDuration.String(),
+		LastError:           cb.lastError,
+		TotalFailures:       cb.totalFailures,
+		TotalRejected:       cb.totalRejected,
+	}
+	if state != BreakerClosed {
+		bs.OpenedAt = cb.openedAt
+	}
+	return bs
+}
+
+// RetryPolicy retries failed calls with exponential backoff and jitter, bounding each attempt
+// with a timeout
+type RetryPolicy struct {
+	MaxRetries  int
+	BaseDelay   time.Duration
+	CallTimeout time.Duration
+}
+
+// Do runs fn until it succeeds, returns a non-retryable error, or retries are exhausted
+func (rp RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; attempt <= rp.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(rp.backoff(attempt)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if rp.CallTimeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, rp.CallTimeout)
+		}
+		err = fn(callCtx)
+		cancel()
+
+		if err == nil || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// backoff returns the delay before a retry: half of the exponential step plus random jitter
+// up to the other half, so concurrent callers do not retry in lockstep
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	step := rp.BaseDelay << uint(attempt-1)
+	if step <= 0 {
+		return 0
+	}
+	return step/2 + time.Duration(rand.Int63n(int64(step/2)+1))
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error so RetryPolicy gives up immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+func isRetryable(err error) bool {
+	var permanent permanentError
+	return !errors.As(err, &permanent) && !errors.Is(err, ErrCircuitOpen)
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	cb := NewCircuitBreaker("test", 2, 20*time.Millisecond)
+	failure := errors.New("unavailable")
+
+	cb.Execute(func() error { return failure })
+	if cb.State().State != BreakerClosed {
+		t.Fatal("Expected breaker to stay closed below the threshold")
+	}
+	cb.Execute(func() error { return failure })
+	if cb.State().State != BreakerOpen {
+		t.Fatal("Expected breaker to open at the threshold")
+	}
+
+	called := false
+	if err := cb.Execute(func() error { called = true; return nil }); err != ErrCircuitOpen || called {
+		t.Fatalf("Expected open breaker to reject the call, got %v (called=%v)", err, called)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err := cb.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("Expected trial call to pass, got %v", err)
+	}
+	if state := cb.State(); state.State != BreakerClosed || state.TotalRejected != 1 || state.TotalFailures != 2 {
+		t.Errorf("Unexpected state after recovery: %+v", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresPermanentErrors(t *testing.T) {
+	cb := NewCircuitBreaker("test", 1, time.Minute)
+	cb.Execute(func() error { return Permanent(errors.New("bad request")) })
+	if cb.State().State != BreakerClosed {
+		t.Error("Expected permanent errors not to open the breaker")
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	rp := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, CallTimeout: time.Second}
+
+	attempts := 0
+	err := rp.Do(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Expected success on third attempt, got %v after %d attempts", err, attempts)
+	}
+
+	attempts = 0
+	err = rp.Do(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return Permanent(errors.New("bad request"))
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected permanent error without retries, got %v after %d attempts", err, attempts)
+	}
+
+	rp.CallTimeout = 5 * time.Millisecond
+	rp.MaxRetries = 0
+	err = rp.Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected per-call timeout, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Keywords stored on a trending score for filtering; every one of them adds an index entry
+const maxFacetKeywords = 10
+
+// ContentFilter limits analytics to posts of one content type, category and keyword. Empty
+// fields match every post. The filters run against the facets stored on trending scores,
+// which need the composite indexes in firestore.indexes.json.
+type ContentFilter struct {
+	ContentType string `json:"contentType,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Keyword     string `json:"keyword,omitempty"`
+}
+
+// NewContentFilter normalizes filter values as given in a request. Categories and keywords
+// are stored lowercase.
+func NewContentFilter(contentType, category, keyword string) ContentFilter {
+	return ContentFilter{
+		ContentType: strings.TrimSpace(contentType),
+		Category:    strings.ToLower(strings.TrimSpace(category)),
+		Keyword:     strings.ToLower(strings.TrimSpace(keyword)),
+	}
+}
+
+// IsEmpty reports whether the filter matches every post
+func (f ContentFilter) IsEmpty() bool {
+	return f == ContentFilter{}
+}
+
+// query narrows a query of trending scores to the posts matching the filter
+func (f ContentFilter) query(q firestore.Query) firestore.Query {
+	if f.ContentType != "" {
+		q = q.Where("ContentType", "==", f.ContentType)
+	}
+	if f.Category != "" {
+		q = q.Where("Category", "==", f.Category)
+	}
+	if f.Keyword != "" {
+		q = q.Where("Keywords", "array-contains", f.Keyword)
+	}
+	return q
+}
+
+// matchesPost reports whether a post document matches the filter, for scans of the posts
+// collection
+func (f ContentFilter) matchesPost(postData map[string]interface{}) bool {
+	facets := postFacets(postData)
+	if f.ContentType != "" && facets.ContentType != f.ContentType {
+		return false
+	}
+	if f.Category != "" && facets.Category != f.Category {
+		return false
+	}
+	return f.Keyword == "" || slices.Contains(facets.Keywords, f.Keyword)
+}
+
+// matchesScore reports whether the facets stored on a trending score match the filter, for
+// rankings the filter does not run against as a query
+func (f ContentFilter) matchesScore(score models.TrendingScore) bool {
+	if f.ContentType != "" && score.ContentType != f.ContentType {
+		return false
+	}
+	if f.Category != "" && score.Category != f.Category {
+		return false
+	}
+	return f.Keyword == "" || slices.Contains(score.Keywords, f.Keyword)
+}
+
+// trendingScoresQuery returns the trending scores of the posts matching a filter
+func (fc *FirestoreClient) trendingScoresQuery(filter ContentFilter) firestore.Query {
+	return filter.query(fc.client.Collection("trending_scores").Query)
+}
+
+// ContentFacets are the facets of a post stored on its trending score
+type ContentFacets struct {
+	ContentType string
+	Category    string
+	Keywords    []string
+	CreatorID   string
+	Style       string
+	PublishedAt time.Time
+}
+
+// NewContentFacets returns the facets of a post from its content type, creator, publication
+// time and extracted keywords
+func NewContentFacets(contentType, creatorID string, publishedAt time.Time, extracted *models.KeywordExtractionResponse) ContentFacets {
+	facets := ContentFacets{ContentType: contentType, CreatorID: creatorID, PublishedAt: publishedAt}
+	if extracted != nil {
+		facets.Category = strings.ToLower(strings.TrimSpace(extracted.Category))
+		facets.Keywords = facetKeywords(extracted.EnglishKeywords)
+		facets.Style = strings.ToLower(strings.TrimSpace(extracted.Style))
+	}
+	return facets
+}
+
+// postFacets returns the facets of a post document
+func postFacets(postData map[string]interface{}) ContentFacets {
+	facets := ContentFacets{}
+	facets.ContentType, _ = postData["contentType"].(string)
+	facets.CreatorID, _ = postData["userId"].(string)
+	category, _ := postData["category"].(string)
+	facets.Category = strings.ToLower(strings.TrimSpace(category))
+	style, _ := postData["style"].(string)
+	facets.Style = strings.ToLower(strings.TrimSpace(style))
+	facets.PublishedAt, _ = postCreationTime(postData)
+
+	keywords, _ := postData["english_keywords"].([]interface{})
+	values := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if value, ok := keyword.(string); ok {
+			values = append(values, value)
+		}
+	}
+	facets.Keywords = facetKeywords(values)
+	return facets
+}
+
+// facetKeywords lowercases and deduplicates keywords, keeping the first maxFacetKeywords
+func facetKeywords(keywords []string) []string {
+	facets := make([]string, 0, min(len(keywords), maxFacetKeywords))
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword == "" || slices.Contains(facets, keyword) {
+			continue
+		}
+		facets = append(facets, keyword)
+		if len(facets) == maxFacetKeywords {
+			break
+		}
+	}
+	return facets
+}
+
+// apply copies the known facets onto a trending score, keeping stored ones that are unknown
+func (f ContentFacets) apply(score *models.TrendingScore) {
+	if f.ContentType != "" {
+		score.ContentType = f.ContentType
+	}
+	if f.Category != "" {
+		score.Category = f.Category
+	}
+	if len(f.Keywords) > 0 {
+		score.Keywords = f.Keywords
+	}
+	if f.CreatorID != "" {
+		score.CreatorID = f.CreatorID
+	}
+	if f.Style != "" {
+		score.Style = f.Style
+	}
+	if !f.PublishedAt.IsZero() {
+		score.PublishedAt = f.PublishedAt
+	}
+}
+
+// SetContentFacets stores the facets of a post on its trending score, creating the score when
+// the post has none yet
+func (fc *FirestoreClient) SetContentFacets(postID string, facets ContentFacets) error {
+	_, err := fc.ApplyTrendingScore(postID, "content_facets", func(score *models.TrendingScore, exists bool) {
+		facets.apply(score)
+	})
+	return err
+}
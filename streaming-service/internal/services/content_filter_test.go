@@ -0,0 +1,61 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestNewContentFilter(t *testing.T) {
+	filter := NewContentFilter(" image", " Art ", "Sunset ")
+	if filter != (ContentFilter{ContentType: "image", Category: "art", Keyword: "sunset"}) {
+		t.Errorf("Unexpected filter %+v", filter)
+	}
+	if filter.IsEmpty() || !NewContentFilter("", " ", "").IsEmpty() {
+		t.Error("Expected only a filter without values to be empty")
+	}
+}
+
+func TestContentFilter_MatchesPost(t *testing.T) {
+	post := map[string]interface{}{
+		"contentType":      "image",
+		"category":         "Photography",
+		"english_keywords": []interface{}{"Sunset", "mountains"},
+	}
+
+	for _, tc := range []struct {
+		filter ContentFilter
+		want   bool
+	}{
+		{ContentFilter{}, true},
+		{NewContentFilter("image", "photography", "sunset"), true},
+		{NewContentFilter("video", "", ""), false},
+		{NewContentFilter("", "art", ""), false},
+		{NewContentFilter("", "", "beach"), false},
+	} {
+		if got := tc.filter.matchesPost(post); got != tc.want {
+			t.Errorf("%+v: expected %v, got %v", tc.filter, tc.want, got)
+		}
+	}
+}
+
+func TestContentFacets(t *testing.T) {
+	keywords := []string{" Sunset", "sunset", "", "Mountains"}
+	for i := 0; i < 20; i++ {
+		keywords = append(keywords, string(rune('a'+i)))
+	}
+	publishedAt := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	facets := NewContentFacets("image", "user-1", publishedAt, &models.KeywordExtractionResponse{Category: " Art", Style: "Watercolor ", EnglishKeywords: keywords})
+	if facets.Category != "art" || facets.Style != "watercolor" || !facets.PublishedAt.Equal(publishedAt) || len(facets.Keywords) != maxFacetKeywords || facets.Keywords[0] != "sunset" || facets.Keywords[1] != "mountains" {
+		t.Errorf("Unexpected facets %+v", facets)
+	}
+
+	score := models.TrendingScore{ContentType: "video", Category: "music", CreatorID: "user-2"}
+	ContentFacets{ContentType: "image", Keywords: []string{"sunset"}}.apply(&score)
+	want := models.TrendingScore{ContentType: "image", Category: "music", Keywords: []string{"sunset"}, CreatorID: "user-2"}
+	if !reflect.DeepEqual(score, want) {
+		t.Errorf("Expected unknown facets to be kept, got %+v", score)
+	}
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Recommender modes: collaborative filtering, content matching, or both side by side
+const (
+	RecommendationModeCollaborative = "collaborative"
+	RecommendationModeContent       = "content"
+	RecommendationModeHybrid        = "hybrid"
+)
+
+// Source of the recommendations written by content matching
+const RecommendationSourceContent = "content"
+
+// Bounds and weights of content matching
+const (
+	maxContentCandidates       = 1000  // top trending posts matched against the profiles
+	maxProfilePosts            = 10000 // most engaged posts whose keywords and category profiles are built from
+	maxProfileEmbeddingPosts   = 2000  // most engaged posts whose embeddings profiles are built from
+	maxProfileKeywords         = 20    // strongest keywords kept per profile
+	maxDocumentsPerRead        = 500   // documents read per batched get
+	contentCategoryWeight      = 0.5   // weight of a liked category relative to the liked keywords
+	contentEmbeddingWeight     = 1     // weight of the embedding similarity
+	minContentEmbeddingSimilar = 0.5   // embedding similarity below which a post does not resemble a profile
+)
+
+// ValidateRecommendationMode reports a recommender mode the engine does not know
+func ValidateRecommendationMode(mode string) error {
+	switch mode {
+	case RecommendationModeCollaborative, RecommendationModeContent, RecommendationModeHybrid:
+		return nil
+	}
+	return fmt.Errorf("invalid RECOMMENDATION_MODE %q: must be %s, %s or %s", mode, RecommendationModeCollaborative, RecommendationModeContent, RecommendationModeHybrid)
+}
+
+// contentPost is what content matching knows of a post
+type contentPost struct {
+	PostID   string
+	Category string
+	Keywords []string
+	Vector   []float64 // embedding, nil when the post has none
+}
+
+// affinityProfile is a user's taste: the keywords and categories of the posts they engaged
+// with, weighted by their engagement and normalized to sum to 1, and the engagement-weighted
+// mean embedding of those posts
+type affinityProfile struct {
+	keywords   map[string]float64
+	categories map[string]float64
+	vector     []float64
+}
+
+// buildAffinityProfile builds a user's profile from their engagement with posts
+func buildAffinityProfile(engaged map[string]float64, posts map[string]contentPost) affinityProfile {
+	profile := affinityProfile{keywords: make(map[string]float64), categories: make(map[string]float64)}
+	var vectorWeight float64
+	for postID, weight := range engaged {
+		post, ok := posts[postID]
+		if !ok {
+			continue
+		}
+		for _, keyword := range post.Keywords {
+			profile.keywords[keyword] += weight
+		}
+		if post.Category != "" {
+			profile.categories[post.Category] += weight
+		}
+		if len(post.Vector) == 0 || (profile.vector != nil && len(post.Vector) != len(profile.vector)) {
+			continue
+		}
+		if profile.vector == nil {
+			profile.vector = make([]float64, len(post.Vector))
+		}
+		for i, v := range post.Vector {
+			profile.vector[i] += weight * v
+		}
+		vectorWeight += weight
+	}
+	for i := range profile.vector {
+		profile.vector[i] /= vectorWeight
+	}
+
+	profile.keywords = strongestTerms(profile.keywords, maxProfileKeywords)
+	normalizeTerms(profile.keywords)
+	normalizeTerms(profile.categories)
+	return profile
+}
+
+// strongestTerms keeps the limit heaviest terms, ties by name
+func strongestTerms(terms map[string]float64, limit int) map[string]float64 {
+	if len(terms) <= limit {
+		return terms
+	}
+	names := make([]string, 0, len(terms))
+	for name := range terms {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if terms[names[i]] != terms[names[j]] {
+			return terms[names[i]] > terms[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	kept := make(map[string]float64, limit)
+	for _, name := range names[:limit] {
+		kept[name] = terms[name]
+	}
+	return kept
+}
+
+// normalizeTerms scales term weights to sum to 1
+func normalizeTerms(terms map[string]float64) {
+	var total float64
+	for _, weight := range terms {
+		total += weight
+	}
+	if total == 0 {
+		return
+	}
+	for name := range terms {
+		terms[name] /= total
+	}
+}
+
+// recommendFromContent scores candidate posts the user has not engaged with by the liked
+// keywords and category they carry and the similarity of their embedding to the user's, and
+// returns the topN with the taste they match as the reason
+func recommendFromContent(userID string, engaged map[string]float64, profile affinityProfile, candidates []contentPost, topN int, now time.Time) []models.Recommendation {
+	recs := make([]models.Recommendation, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, seen := engaged[candidate.PostID]; seen {
+			continue
+		}
+
+		var score, strongest float64
+		var keyword string
+		for _, k := range candidate.Keywords {
+			weight := profile.keywords[k]
+			score += weight
+			if weight > strongest || (weight == strongest && weight > 0 && k < keyword) {
+				strongest, keyword = weight, k
+			}
+		}
+		category := ""
+		if weight := profile.categories[candidate.Category]; weight > 0 {
+			score += weight * contentCategoryWeight
+			category = candidate.Category
+		}
+		resembles := false
+		if len(profile.vector) > 0 && len(candidate.Vector) == len(profile.vector) {
+			if similarity := cosineSimilarity(profile.vector, candidate.Vector); similarity >= minContentEmbeddingSimilar {
+				score += similarity * contentEmbeddingWeight
+				resembles = true
+			}
+		}
+		if keyword == "" && category == "" && !resembles {
+			continue
+		}
+
+		recs = append(recs, models.Recommendation{
+			UserID:      userID,
+			PostID:      candidate.PostID,
+			Score:       score,
+			Reason:      contentReason(keyword, category),
+			Category:    candidate.Category,
+			GeneratedAt: now,
+			Source:      RecommendationSourceContent,
+		})
+	}
+
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].PostID < recs[j].PostID
+	})
+	if len(recs) > topN {
+		recs = recs[:topN]
+	}
+	return recs
+}
+
+// contentReason names the taste a recommended post matches, such as "Because you liked sunset
+// photography" for the keyword sunset in the category photography
+func contentReason(keyword, category string) string {
+	switch {
+	case keyword != "" && category != "" && keyword != category:
+		return "Because you liked " + keyword + " " + category
+	case keyword != "":
+		return "Because you liked " + keyword
+	case category != "":
+		return "Because you liked " + category
+	default:
+		return "Because it resembles posts you liked"
+	}
+}
+
+// contentRecommendations matches the profiles of the active users, built from the posts they
+// engaged with, against the top trending posts
+func (re *RecommendationEngine) contentRecommendations(matrix map[string]map[string]float64, now time.Time) (map[string][]models.Recommendation, error) {
+	candidates, err := re.firestoreClient.ContentCandidates(maxContentCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Profiles draw on the posts engaged with most across the users
+	totals := make(map[string]float64)
+	for _, engaged := range matrix {
+		for postID, weight := range engaged {
+			totals[postID] += weight
+		}
+	}
+	profilePosts := rankedKeys(totals)
+	if len(profilePosts) > maxProfilePosts {
+		profilePosts = profilePosts[:maxProfilePosts]
+	}
+	embedded := profilePosts
+	if len(embedded) > maxProfileEmbeddingPosts {
+		embedded = embedded[:maxProfileEmbeddingPosts]
+	}
+	posts, err := re.firestoreClient.ContentPosts(profilePosts, embedded)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := make(map[string][]models.Recommendation, len(matrix))
+	for userID, engaged := range matrix {
+		profile := buildAffinityProfile(engaged, posts)
+		recommendations[userID] = recommendFromContent(userID, engaged, profile, candidates, re.topN*recommendationHeadroom, now)
+	}
+	return recommendations, nil
+}
+
+// rankedKeys returns the keys of a weight map, heaviest first, ties by key
+func rankedKeys(weights map[string]float64) []string {
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if weights[keys[i]] != weights[keys[j]] {
+			return weights[keys[i]] > weights[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// ContentCandidates returns the keywords, category and embedding of the limit top trending
+// posts
+func (fc *FirestoreClient) ContentCandidates(limit int) ([]contentPost, error) {
+	docs, err := fc.client.Collection("trending_scores").
+		OrderBy("Score", firestore.Desc).
+		Select("Category", "Keywords").
+		Limit(limit).
+		Documents(fc.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]contentPost, 0, len(docs))
+	postIDs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		post := contentPostOf(doc)
+		candidates = append(candidates, post)
+		postIDs = append(postIDs, post.PostID)
+	}
+	vectors, err := fc.postVectors(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		candidates[i].Vector = vectors[candidates[i].PostID]
+	}
+	return candidates, nil
+}
+
+// ContentPosts returns the keywords and category of posts, with the embeddings of those in
+// embedded
+func (fc *FirestoreClient) ContentPosts(postIDs, embedded []string) (map[string]contentPost, error) {
+	posts := make(map[string]contentPost, len(postIDs))
+	err := fc.getAllInBatches("trending_scores", postIDs, func(doc *firestore.DocumentSnapshot) {
+		posts[doc.Ref.ID] = contentPostOf(doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vectors, err := fc.postVectors(embedded)
+	if err != nil {
+		return nil, err
+	}
+	for postID, vector := range vectors {
+		if post, ok := posts[postID]; ok {
+			post.Vector = vector
+			posts[postID] = post
+		}
+	}
+	return posts, nil
+}
+
+// contentPostOf reads the facets of a trending score document
+func contentPostOf(doc *firestore.DocumentSnapshot) contentPost {
+	post := contentPost{PostID: doc.Ref.ID}
+	data := doc.Data()
+	post.Category, _ = data["Category"].(string)
+	if keywords, ok := data["Keywords"].([]interface{}); ok {
+		for _, keyword := range keywords {
+			if k, ok := keyword.(string); ok && k != "" {
+				post.Keywords = append(post.Keywords, k)
+			}
+		}
+	}
+	return post
+}
+
+// postVectors returns the stored embeddings of posts, by post ID
+func (fc *FirestoreClient) postVectors(postIDs []string) (map[string][]float64, error) {
+	vectors := make(map[string][]float64, len(postIDs))
+	err := fc.getAllInBatches("post_embeddings", postIDs, func(doc *firestore.DocumentSnapshot) {
+		var embedding models.PostEmbedding
+		if err := doc.DataTo(&embedding); err == nil && len(embedding.Vector) > 0 {
+			vectors[doc.Ref.ID] = embedding.Vector
+		}
+	})
+	return vectors, err
+}
+
+// getAllInBatches reads the existing documents of a collection with the given IDs
+func (fc *FirestoreClient) getAllInBatches(collection string, ids []string, read func(doc *firestore.DocumentSnapshot)) error {
+	for start := 0; start < len(ids); start += maxDocumentsPerRead {
+		end := min(start+maxDocumentsPerRead, len(ids))
+		refs := make([]*firestore.DocumentRef, 0, end-start)
+		for _, id := range ids[start:end] {
+			refs = append(refs, fc.client.Collection(collection).Doc(id))
+		}
+		docs, err := fc.client.GetAll(fc.ctx, refs)
+		Quotas.Record(QuotaFirestore, int64(len(refs)))
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if doc.Exists() {
+				read(doc)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBuildAffinityProfile(t *testing.T) {
+	posts := map[string]contentPost{
+		"a": {PostID: "a", Category: "photography", Keywords: []string{"sunset", "beach"}, Vector: []float64{1, 0}},
+		"b": {PostID: "b", Category: "photography", Keywords: []string{"sunset"}, Vector: []float64{0, 1}},
+		"c": {PostID: "c", Category: "music", Keywords: []string{"jazz"}},
+	}
+	profile := buildAffinityProfile(map[string]float64{"a": 3, "b": 1, "c": 4, "unknown": 10}, posts)
+
+	// sunset 4, beach 3, jazz 4 of 11
+	if math.Abs(profile.keywords["sunset"]-4.0/11) > 0.0001 || math.Abs(profile.keywords["beach"]-3.0/11) > 0.0001 {
+		t.Errorf("Unexpected keyword weights %+v", profile.keywords)
+	}
+	if profile.categories["photography"] != 0.5 || profile.categories["music"] != 0.5 {
+		t.Errorf("Unexpected category weights %+v", profile.categories)
+	}
+	if len(profile.vector) != 2 || profile.vector[0] != 0.75 || profile.vector[1] != 0.25 {
+		t.Errorf("Expected the engagement-weighted mean embedding, got %v", profile.vector)
+	}
+}
+
+func TestRecommendFromContent(t *testing.T) {
+	profile := affinityProfile{
+		keywords:   map[string]float64{"sunset": 0.6, "beach": 0.4},
+		categories: map[string]float64{"photography": 1},
+		vector:     []float64{1, 0},
+	}
+	candidates := []contentPost{
+		{PostID: "seen", Category: "photography", Keywords: []string{"sunset"}},
+		{PostID: "sunset-photo", Category: "photography", Keywords: []string{"sunset", "beach"}},
+		{PostID: "beach-video", Category: "travel", Keywords: []string{"beach"}},
+		{PostID: "landscape", Category: "photography"},
+		{PostID: "lookalike", Category: "art", Vector: []float64{0.9, 0.1}},
+		{PostID: "unrelated", Category: "music", Keywords: []string{"jazz"}, Vector: []float64{0, 1}},
+	}
+
+	recs := recommendFromContent("user-1", map[string]float64{"seen": 1}, profile, candidates, 10, time.Now())
+	if len(recs) != 4 {
+		t.Fatalf("Expected 4 recommendations, got %+v", recs)
+	}
+	expected := []struct{ postID, reason string }{
+		{"sunset-photo", "Because you liked sunset photography"},
+		{"lookalike", "Because it resembles posts you liked"},
+		{"landscape", "Because you liked photography"},
+		{"beach-video", "Because you liked beach"},
+	}
+	for i, e := range expected {
+		if recs[i].PostID != e.postID || recs[i].Reason != e.reason {
+			t.Errorf("Recommendation %d: expected %s (%q), got %s (%q)", i, e.postID, e.reason, recs[i].PostID, recs[i].Reason)
+		}
+	}
+	if recs[0].Category != "photography" || recs[0].Source != RecommendationSourceContent || math.Abs(recs[0].Score-1.5) > 0.0001 {
+		t.Errorf("Unexpected recommendation %+v", recs[0])
+	}
+
+	if recs := recommendFromContent("user-1", nil, profile, candidates, 1, time.Now()); len(recs) != 1 {
+		t.Errorf("Expected the recommendations cut to 1, got %d", len(recs))
+	}
+}
+
+func TestValidateRecommendationMode(t *testing.T) {
+	for _, mode := range []string{RecommendationModeCollaborative, RecommendationModeContent, RecommendationModeHybrid} {
+		if err := ValidateRecommendationMode(mode); err != nil {
+			t.Errorf("Expected %s to be valid: %v", mode, err)
+		}
+	}
+	if err := ValidateRecommendationMode("random"); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
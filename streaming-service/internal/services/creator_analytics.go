@@ -0,0 +1,406 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Viral posts listed in a creator's analytics
+const creatorViralPostsListed = 20
+
+// creatorAggregate is the engagement of all of a creator's posts, kept in
+// creator_analytics/{creator}
+type creatorAggregate struct {
+	UserID     string
+	Views      int64
+	Likes      int64
+	Comments   int64
+	Shares     int64
+	Remixes    int64
+	Posts      int64
+	ViralPosts int64
+	Score      float64 // trending score gained
+
+	ContentTypes map[string]dashboardContentCounts
+	ScoreByDay   map[string]float64 // trending score gained per calendar day in the reporting zone
+
+	UpdatedAt time.Time
+}
+
+// creatorPostAggregate is the engagement of one of a creator's posts, kept in
+// creator_analytics/{creator}/posts/{post}
+type creatorPostAggregate struct {
+	PostID      string
+	ContentType string
+	Views       int64
+	Likes       int64
+	Comments    int64
+	Shares      int64
+	Remixes     int64
+	Score       float64   // latest trending score
+	ViralAt     time.Time // when the post went viral, unset until it does
+	UpdatedAt   time.Time
+}
+
+// creatorDelta is the engagement a creator's posts received since the last flush
+type creatorDelta struct {
+	counts       dashboardCreatorCounts
+	contentTypes map[string]dashboardContentCounts
+	scoreByDay   map[string]float64
+	posts        map[string]*creatorPostDelta
+}
+
+// creatorPostDelta is the engagement a post received since the last flush
+type creatorPostDelta struct {
+	contentType string
+	counts      dashboardCreatorCounts
+	score       *float64 // latest trending score, nil when it did not change
+	viralAt     time.Time
+}
+
+func newCreatorDelta() *creatorDelta {
+	return &creatorDelta{
+		contentTypes: make(map[string]dashboardContentCounts),
+		scoreByDay:   make(map[string]float64),
+		posts:        make(map[string]*creatorPostDelta),
+	}
+}
+
+// post returns the delta of one of the creator's posts
+func (d *creatorDelta) post(postID string) *creatorPostDelta {
+	post, ok := d.posts[postID]
+	if !ok {
+		post = &creatorPostDelta{}
+		d.posts[postID] = post
+	}
+	return post
+}
+
+// add adds an older delta of the same creator; latest scores already recorded are kept
+func (d *creatorDelta) add(older *creatorDelta) {
+	d.counts.add(older.counts)
+	for contentType, counts := range older.contentTypes {
+		d.contentTypes[contentType] = d.contentTypes[contentType].add(counts)
+	}
+	for day, score := range older.scoreByDay {
+		d.scoreByDay[day] += score
+	}
+	for postID, older := range older.posts {
+		post := d.post(postID)
+		post.counts.add(older.counts)
+		if post.contentType == "" {
+			post.contentType = older.contentType
+		}
+		if post.score == nil {
+			post.score = older.score
+		}
+		if post.viralAt.IsZero() {
+			post.viralAt = older.viralAt
+		}
+	}
+}
+
+// CreatorAnalytics is a creator's own dashboard
+type CreatorAnalytics struct {
+	UserID          string                        `json:"userId"`
+	TotalViews      int64                         `json:"totalViews"`
+	TotalLikes      int64                         `json:"totalLikes"`
+	TotalComments   int64                         `json:"totalComments"`
+	TotalShares     int64                         `json:"totalShares"`
+	TotalRemixes    int64                         `json:"totalRemixes"`
+	PostCount       int64                         `json:"postCount"`
+	ViralPostCount  int64                         `json:"viralPostCount"`
+	TotalScore      float64                       `json:"totalScore"`
+	EngagementRate  float64                       `json:"engagementRate"`
+	BestContentType string                        `json:"bestContentType,omitempty"` // most views per post
+	ContentTypes    map[string]ContentTypeMetrics `json:"contentTypes"`
+	Posts           []CreatorPostMetrics          `json:"posts"` // most viewed first
+	ScoreHistory    []CreatorScorePoint           `json:"scoreHistory"`
+	ViralPosts      []CreatorPostMetrics          `json:"viralPosts"` // latest first
+	UpdatedAt       time.Time                     `json:"updatedAt"`
+}
+
+// CreatorPostMetrics is the engagement of one of a creator's posts
+type CreatorPostMetrics struct {
+	PostID      string     `json:"postId"`
+	ContentType string     `json:"contentType,omitempty"`
+	Views       int64      `json:"views"`
+	Likes       int64      `json:"likes"`
+	Comments    int64      `json:"comments"`
+	Shares      int64      `json:"shares"`
+	Remixes     int64      `json:"remixes"`
+	Score       float64    `json:"score"`
+	ViralAt     *time.Time `json:"viralAt,omitempty"`
+}
+
+// CreatorScorePoint is the trending score a creator's posts gained during a day and the total
+// they ended the day with
+type CreatorScorePoint struct {
+	Date   string  `json:"date"`
+	Gained float64 `json:"gained"`
+	Total  float64 `json:"total"`
+}
+
+// GetCreatorAnalytics returns a creator's dashboard from the aggregates the metrics aggregator
+// maintains, with their postLimit most viewed posts and their score over the last days. It
+// returns nil when no event of the creator was aggregated yet.
+func (da *DashboardAnalytics) GetCreatorAnalytics(creatorID string, postLimit, days int) (*CreatorAnalytics, error) {
+	logger.Debugf("📊 Loading analytics of creator %s...", creatorID)
+
+	aggregate, err := da.firestoreClient.LoadCreatorAggregate(creatorID)
+	if err != nil || aggregate == nil {
+		return nil, err
+	}
+	posts, err := da.firestoreClient.CreatorPostAggregates(creatorID, "Views", postLimit)
+	if err != nil {
+		return nil, err
+	}
+	viral, err := da.firestoreClient.CreatorPostAggregates(creatorID, "ViralAt", creatorViralPostsListed)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCreatorAnalytics(aggregate, posts, viral, days, time.Now(), da.firestoreClient.reportingLoc), nil
+}
+
+// buildCreatorAnalytics assembles a creator's dashboard from their aggregates
+func buildCreatorAnalytics(aggregate *creatorAggregate, posts, viral []creatorPostAggregate, days int, now time.Time, loc *time.Location) *CreatorAnalytics {
+	analytics := &CreatorAnalytics{
+		UserID:         aggregate.UserID,
+		TotalViews:     aggregate.Views,
+		TotalLikes:     aggregate.Likes,
+		TotalComments:  aggregate.Comments,
+		TotalShares:    aggregate.Shares,
+		TotalRemixes:   aggregate.Remixes,
+		PostCount:      aggregate.Posts,
+		ViralPostCount: aggregate.ViralPosts,
+		TotalScore:     aggregate.Score,
+		ContentTypes:   windowContentTypes(&dashboardBucket{ContentTypes: aggregate.ContentTypes}),
+		Posts:          make([]CreatorPostMetrics, 0, len(posts)),
+		ViralPosts:     make([]CreatorPostMetrics, 0, len(viral)),
+		UpdatedAt:      aggregate.UpdatedAt,
+	}
+	if analytics.TotalViews > 0 {
+		engagement := analytics.TotalLikes + analytics.TotalComments + analytics.TotalShares
+		analytics.EngagementRate = (float64(engagement) / float64(analytics.TotalViews)) * 100
+	}
+
+	bestViews := -1.0
+	for contentType, metrics := range analytics.ContentTypes {
+		if metrics.Count == 0 {
+			continue
+		}
+		if metrics.AvgViews > bestViews || (metrics.AvgViews == bestViews && contentType < analytics.BestContentType) {
+			analytics.BestContentType, bestViews = contentType, metrics.AvgViews
+		}
+	}
+
+	for _, post := range posts {
+		analytics.Posts = append(analytics.Posts, post.metrics())
+	}
+	for _, post := range viral {
+		analytics.ViralPosts = append(analytics.ViralPosts, post.metrics())
+	}
+
+	// The total at the end of each day is the current total less what was gained after it
+	today := StartOfDay(now, loc)
+	analytics.ScoreHistory = make([]CreatorScorePoint, days)
+	total := aggregate.Score
+	for i := days - 1; i >= 0; i-- {
+		date := ReportingDate(today.AddDate(0, 0, i-days+1), loc)
+		analytics.ScoreHistory[i] = CreatorScorePoint{Date: date, Gained: aggregate.ScoreByDay[date], Total: total}
+		total -= aggregate.ScoreByDay[date]
+	}
+	return analytics
+}
+
+// metrics returns the API view of a post aggregate
+func (p creatorPostAggregate) metrics() CreatorPostMetrics {
+	metrics := CreatorPostMetrics{
+		PostID:      p.PostID,
+		ContentType: p.ContentType,
+		Views:       p.Views,
+		Likes:       p.Likes,
+		Comments:    p.Comments,
+		Shares:      p.Shares,
+		Remixes:     p.Remixes,
+		Score:       p.Score,
+	}
+	if !p.ViralAt.IsZero() {
+		viralAt := p.ViralAt
+		metrics.ViralAt = &viralAt
+	}
+	return metrics
+}
+
+// creatorAnalyticsRef is the document holding a creator's aggregate
+func (fc *FirestoreClient) creatorAnalyticsRef(creatorID string) *firestore.DocumentRef {
+	return fc.client.Collection("creator_analytics").Doc(creatorID)
+}
+
+// IncrementCreatorAggregates adds the deltas of creators to their stored aggregates and those
+// of their posts, creating the missing ones. It returns the parts of the deltas whose write
+// failed with the last error.
+func (fc *FirestoreClient) IncrementCreatorAggregates(deltas map[string]*creatorDelta) (map[string]*creatorDelta, error) {
+	now := time.Now()
+	bw := fc.client.BulkWriter(fc.ctx)
+
+	// Writes are identified by creator and post, empty for the creator's own aggregate
+	type write struct {
+		creatorID string
+		postID    string
+		job       *firestore.BulkWriterJob
+	}
+	var writes []write
+	failed := make(map[string]*creatorDelta)
+	var lastErr error
+	keepFailed := func(creatorID, postID string, err error) {
+		delta, ok := failed[creatorID]
+		if !ok {
+			delta = newCreatorDelta()
+			failed[creatorID] = delta
+		}
+		source := deltas[creatorID]
+		if postID == "" {
+			delta.counts, delta.contentTypes, delta.scoreByDay = source.counts, source.contentTypes, source.scoreByDay
+		} else {
+			delta.posts[postID] = source.posts[postID]
+		}
+		lastErr = err
+	}
+	set := func(creatorID, postID string, ref *firestore.DocumentRef, data map[string]interface{}) {
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(ref, data, firestore.MergeAll)
+		if err != nil {
+			keepFailed(creatorID, postID, err)
+			return
+		}
+		writes = append(writes, write{creatorID: creatorID, postID: postID, job: job})
+	}
+
+	for creatorID, delta := range deltas {
+		data := map[string]interface{}{"UserID": creatorID, "UpdatedAt": now}
+		incrementCounts(data, delta.counts)
+		if len(delta.contentTypes) > 0 {
+			contentTypes := make(map[string]interface{}, len(delta.contentTypes))
+			for contentType, counts := range delta.contentTypes {
+				contentTypes[contentType] = map[string]interface{}{
+					"Posts": firestore.Increment(counts.Posts),
+					"Views": firestore.Increment(counts.Views),
+					"Likes": firestore.Increment(counts.Likes),
+				}
+			}
+			data["ContentTypes"] = contentTypes
+		}
+		if len(delta.scoreByDay) > 0 {
+			scoreByDay := make(map[string]interface{}, len(delta.scoreByDay))
+			for day, score := range delta.scoreByDay {
+				scoreByDay[day] = firestore.Increment(score)
+			}
+			data["ScoreByDay"] = scoreByDay
+		}
+		ref := fc.creatorAnalyticsRef(creatorID)
+		set(creatorID, "", ref, data)
+
+		for postID, post := range delta.posts {
+			data := map[string]interface{}{"PostID": postID, "UpdatedAt": now}
+			// Posts and score gains count towards the creator; the post keeps its latest score
+			counts := post.counts
+			counts.Posts, counts.ViralPosts, counts.Score = 0, 0, 0
+			incrementCounts(data, counts)
+			// Every post has views, so posts not yet viewed are listed too
+			data["Views"] = firestore.Increment(counts.Views)
+			if post.contentType != "" {
+				data["ContentType"] = post.contentType
+			}
+			if post.score != nil {
+				data["Score"] = *post.score
+			}
+			if !post.viralAt.IsZero() {
+				data["ViralAt"] = post.viralAt
+			}
+			set(creatorID, postID, ref.Collection("posts").Doc(postID), data)
+		}
+	}
+	bw.End()
+
+	for _, w := range writes {
+		if _, err := w.job.Results(); err != nil {
+			keepFailed(w.creatorID, w.postID, err)
+		}
+	}
+	return failed, lastErr
+}
+
+// incrementCounts adds increments of the non-zero counts to document data
+func incrementCounts(data map[string]interface{}, counts dashboardCreatorCounts) {
+	for field, n := range map[string]int64{
+		"Views":      counts.Views,
+		"Likes":      counts.Likes,
+		"Comments":   counts.Comments,
+		"Shares":     counts.Shares,
+		"Remixes":    counts.Remixes,
+		"Posts":      counts.Posts,
+		"ViralPosts": counts.ViralPosts,
+	} {
+		if n != 0 {
+			data[field] = firestore.Increment(n)
+		}
+	}
+	if counts.Score != 0 {
+		data["Score"] = firestore.Increment(counts.Score)
+	}
+}
+
+// LoadCreatorAggregate returns a creator's stored aggregate, nil when none was written yet
+func (fc *FirestoreClient) LoadCreatorAggregate(creatorID string) (*creatorAggregate, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := fc.creatorAnalyticsRef(creatorID).Get(fc.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var aggregate creatorAggregate
+	if err := doc.DataTo(&aggregate); err != nil {
+		return nil, fmt.Errorf("failed to parse creator analytics: %w", err)
+	}
+	return &aggregate, nil
+}
+
+// CreatorPostAggregates returns up to limit of a creator's post aggregates ordered by a field,
+// highest first. Posts without the field are left out.
+func (fc *FirestoreClient) CreatorPostAggregates(creatorID, orderBy string, limit int) ([]creatorPostAggregate, error) {
+	iter := fc.creatorAnalyticsRef(creatorID).Collection("posts").
+		OrderBy(orderBy, firestore.Desc).
+		Limit(limit).
+		Documents(fc.ctx)
+	defer iter.Stop()
+
+	posts := []creatorPostAggregate{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		Quotas.Record(QuotaFirestore, 1)
+
+		var post creatorPostAggregate
+		if err := doc.DataTo(&post); err != nil {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
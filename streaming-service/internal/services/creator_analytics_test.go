@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildCreatorAnalytics(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	aggregate := &creatorAggregate{
+		UserID: "creator-1",
+		Views:  200, Likes: 30, Comments: 10, Posts: 3, Score: 100,
+		ContentTypes: map[string]dashboardContentCounts{
+			"video": {Posts: 1, Views: 150},
+			"image": {Posts: 2, Views: 50},
+			"music": {Views: 500}, // remixed, never posted
+		},
+		ScoreByDay: map[string]float64{"2024-05-10": 20, "2024-05-09": 30, "2024-04-01": 50},
+	}
+	viralAt := now.Add(-time.Hour)
+	posts := []creatorPostAggregate{{PostID: "post-1", Views: 150, Score: 80, ViralAt: viralAt}, {PostID: "post-2", Views: 30}}
+
+	analytics := buildCreatorAnalytics(aggregate, posts, posts[:1], 3, now, time.UTC)
+	if analytics.EngagementRate != 20 || analytics.BestContentType != "video" {
+		t.Errorf("Unexpected engagement %.1f or best content type %q", analytics.EngagementRate, analytics.BestContentType)
+	}
+	if len(analytics.Posts) != 2 || analytics.Posts[1].ViralAt != nil || len(analytics.ViralPosts) != 1 || !analytics.ViralPosts[0].ViralAt.Equal(viralAt) {
+		t.Errorf("Unexpected posts %+v and viral posts %+v", analytics.Posts, analytics.ViralPosts)
+	}
+
+	// Totals run back from the current score
+	expected := []CreatorScorePoint{{"2024-05-08", 0, 50}, {"2024-05-09", 30, 80}, {"2024-05-10", 20, 100}}
+	for i, point := range analytics.ScoreHistory {
+		if point != expected[i] {
+			t.Errorf("Score history day %d: expected %+v, got %+v", i, expected[i], point)
+		}
+	}
+}
+
+func TestCreatorDelta_Add(t *testing.T) {
+	older, newer := newCreatorDelta(), newCreatorDelta()
+	oldScore, newScore := 10.0, 20.0
+	older.counts.Views = 3
+	older.contentTypes["video"] = dashboardContentCounts{Views: 3}
+	older.post("post-1").score = &oldScore
+	older.post("post-1").counts.Views = 3
+	newer.counts.Views = 1
+	newer.post("post-1").score = &newScore
+
+	newer.add(older)
+	if newer.counts.Views != 4 || newer.contentTypes["video"].Views != 3 || newer.posts["post-1"].counts.Views != 3 {
+		t.Errorf("Unexpected merged delta %+v", newer)
+	}
+	if *newer.posts["post-1"].score != 20 {
+		t.Errorf("Expected the newer score to be kept, got %v", *newer.posts["post-1"].score)
+	}
+}
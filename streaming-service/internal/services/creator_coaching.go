@@ -0,0 +1,352 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Creator posts read to build the performance history
+	coachingHistoryPosts = 200
+
+	// Posts a content type needs before its timing or prompt length is commented on
+	coachingMinPosts = 3
+
+	// Suggestions returned per creator
+	maxCoachingSuggestions = 5
+)
+
+// Where creator suggestions came from
+const (
+	CoachingSourceGemini    = "gemini"
+	CoachingSourceHeuristic = "heuristic"
+)
+
+// ContentTypePerformance summarizes how a creator's posts of one content type performed
+type ContentTypePerformance struct {
+	ContentType        string  `json:"contentType"`
+	PostCount          int     `json:"postCount"`
+	AverageViews       float64 `json:"averageViews"`
+	AverageEngagement  float64 `json:"averageEngagement"` // likes, comments, shares and remixes per post
+	EngagementRate     float64 `json:"engagementRate"`    // engagement per 100 views
+	BestHour           int     `json:"bestHour"`          // hour of day (reporting zone) with the most engagement per post; -1 without engagement
+	AveragePromptWords float64 `json:"averagePromptWords"`
+	TopPromptWords     float64 `json:"topPromptWords"` // average prompt length of the better performing half
+}
+
+// CreatorCoaching holds actionable suggestions for a creator and the history they are based on
+type CreatorCoaching struct {
+	UserID      string                   `json:"userId"`
+	Date        string                   `json:"date"` // reporting day the suggestions were generated for
+	Performance []ContentTypePerformance `json:"performance"`
+	Suggestions []string                 `json:"suggestions"`
+	Source      string                   `json:"source"`
+	GeneratedAt time.Time                `json:"generatedAt"`
+}
+
+// coachingPost is the part of a post the performance history is built from
+type coachingPost struct {
+	contentType string
+	createdAt   time.Time
+	promptWords int
+	views       int64
+	engagement  int64
+}
+
+// CreatorCoach generates suggestions from a creator's per-content-type history with Gemini,
+// falling back to rule-based suggestions, and caches them per creator per reporting day
+type CreatorCoach struct {
+	analytics *DashboardAnalytics
+	firestore *FirestoreClient
+	vertexAI  *VertexAIClient // nil uses the rule-based suggestions only
+	loc       *time.Location
+}
+
+func NewCreatorCoach(firestoreClient *FirestoreClient, vertexAI *VertexAIClient, loc *time.Location) *CreatorCoach {
+	return &CreatorCoach{
+		analytics: NewDashboardAnalytics(firestoreClient),
+		firestore: firestoreClient,
+		vertexAI:  vertexAI,
+		loc:       loc,
+	}
+}
+
+// Suggestions returns today's suggestions for a creator, generating them on the first request
+// of the day. It returns nil when the creator has no posts.
+func (cc *CreatorCoach) Suggestions(userID string) (*CreatorCoaching, error) {
+	now := time.Now()
+	date := ReportingDate(now, cc.loc)
+
+	cached, err := cc.firestore.getCreatorCoaching(userID)
+	if err != nil {
+		logger.Debugf(" Failed to read cached suggestions of creator %s: %v", userID, err)
+	} else if cached != nil && cached.Date == date {
+		return cached, nil
+	}
+
+	posts, err := cc.analytics.creatorPosts(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read creator posts: %w", err)
+	}
+	if len(posts) == 0 {
+		return nil, nil
+	}
+
+	coaching := &CreatorCoaching{
+		UserID:      userID,
+		Date:        date,
+		Performance: summarizePerformance(posts, cc.loc),
+		GeneratedAt: now,
+	}
+
+	if cc.vertexAI != nil {
+		suggestions, err := cc.vertexAI.CoachCreator(coaching.Performance)
+		if err != nil {
+			logger.Infof("Failed to generate suggestions for creator %s, using rules: %v", userID, err)
+		} else {
+			coaching.Suggestions, coaching.Source = suggestions, CoachingSourceGemini
+		}
+	}
+	if coaching.Source == "" {
+		coaching.Suggestions, coaching.Source = heuristicSuggestions(coaching.Performance), CoachingSourceHeuristic
+	}
+
+	if err := cc.firestore.saveCreatorCoaching(coaching); err != nil {
+		logger.Infof("Failed to cache suggestions of creator %s: %v", userID, err)
+	}
+	return coaching, nil
+}
+
+// creatorPosts reads the most recent posts of a creator
+func (da *DashboardAnalytics) creatorPosts(userID string) ([]coachingPost, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	iter := da.firestoreClient.client.Collection("posts").
+		Where("userId", "==", userID).
+		OrderBy("createdAt", firestore.Desc).
+		Limit(coachingHistoryPosts).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	posts := []coachingPost{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data := doc.Data()
+		contentType, _ := data["contentType"].(string)
+		createdAt, field := postCreationTime(data)
+		if contentType == "" || field == "" {
+			continue
+		}
+		prompt, _ := data["prompt"].(string)
+
+		posts = append(posts, coachingPost{
+			contentType: contentType,
+			createdAt:   createdAt,
+			promptWords: len(strings.Fields(prompt)),
+			views:       getInt64(data, "view_count"),
+			engagement: getInt64(data, "like_count") + getInt64(data, "comment_count") +
+				getInt64(data, "share_count") + getInt64(data, "remix_count"),
+		})
+	}
+	return posts, nil
+}
+
+// summarizePerformance aggregates posts per content type, best performing type first
+func summarizePerformance(posts []coachingPost, loc *time.Location) []ContentTypePerformance {
+	byType := make(map[string][]coachingPost)
+	for _, post := range posts {
+		byType[post.contentType] = append(byType[post.contentType], post)
+	}
+
+	performance := make([]ContentTypePerformance, 0, len(byType))
+	for contentType, typePosts := range byType {
+		perf := ContentTypePerformance{ContentType: contentType, PostCount: len(typePosts), BestHour: -1}
+
+		var views, engagement, words int64
+		hourEngagement := make(map[int][2]int64) // hour -> engagement, posts
+		for _, post := range typePosts {
+			views += post.views
+			engagement += post.engagement
+			words += int64(post.promptWords)
+
+			hour := post.createdAt.In(loc).Hour()
+			h := hourEngagement[hour]
+			hourEngagement[hour] = [2]int64{h[0] + post.engagement, h[1] + 1}
+		}
+
+		n := float64(len(typePosts))
+		perf.AverageViews = float64(views) / n
+		perf.AverageEngagement = float64(engagement) / n
+		perf.AveragePromptWords = float64(words) / n
+		if views > 0 {
+			perf.EngagementRate = float64(engagement) / float64(views) * 100
+		}
+
+		bestAverage := 0.0
+		for hour, h := range hourEngagement {
+			average := float64(h[0]) / float64(h[1])
+			if average > bestAverage || (average == bestAverage && perf.BestHour >= 0 && hour < perf.BestHour) {
+				bestAverage, perf.BestHour = average, hour
+			}
+		}
+
+		// Prompt length of the better performing half
+		sort.SliceStable(typePosts, func(i, j int) bool {
+			return typePosts[i].engagement > typePosts[j].engagement
+		})
+		top := typePosts[:(len(typePosts)+1)/2]
+		words = 0
+		for _, post := range top {
+			words += int64(post.promptWords)
+		}
+		perf.TopPromptWords = float64(words) / float64(len(top))
+
+		performance = append(performance, perf)
+	}
+
+	sort.Slice(performance, func(i, j int) bool {
+		if performance[i].AverageEngagement != performance[j].AverageEngagement {
+			return performance[i].AverageEngagement > performance[j].AverageEngagement
+		}
+		return performance[i].ContentType < performance[j].ContentType
+	})
+	return performance
+}
+
+// heuristicSuggestions derives suggestions from the performance history with fixed rules
+func heuristicSuggestions(performance []ContentTypePerformance) []string {
+	suggestions := []string{}
+	if len(performance) == 0 {
+		return suggestions
+	}
+
+	best := performance[0]
+	if len(performance) > 1 && best.AverageEngagement > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Your %s posts get the most engagement, %.1f per post; consider posting more of them", best.ContentType, best.AverageEngagement))
+	}
+
+	for _, perf := range performance {
+		if perf.PostCount < coachingMinPosts {
+			continue
+		}
+		if perf.BestHour >= 0 && perf.AverageEngagement > 0 {
+			suggestions = append(suggestions, fmt.Sprintf("Your %s posts peak at %s; try publishing around then", perf.ContentType, formatHour(perf.BestHour)))
+		}
+		switch {
+		case perf.TopPromptWords > 0 && perf.TopPromptWords < perf.AveragePromptWords*0.8:
+			suggestions = append(suggestions, fmt.Sprintf("Your best %s posts use shorter prompts (about %.0f words); try shorter prompts", perf.ContentType, perf.TopPromptWords))
+		case perf.TopPromptWords > perf.AveragePromptWords*1.2:
+			suggestions = append(suggestions, fmt.Sprintf("Your best %s posts use more detailed prompts (about %.0f words); try adding detail", perf.ContentType, perf.TopPromptWords))
+		}
+	}
+
+	if worst := performance[len(performance)-1]; len(performance) > 1 && worst.PostCount >= coachingMinPosts && worst.AverageEngagement < best.AverageEngagement/2 {
+		suggestions = append(suggestions, fmt.Sprintf("Your %s posts get less than half the engagement of your %s posts; try remixing trending %s posts for ideas", worst.ContentType, best.ContentType, worst.ContentType))
+	}
+
+	if len(suggestions) == 0 {
+		suggestions = append(suggestions, "Keep posting regularly; suggestions get more specific as your posts collect engagement")
+	}
+	if len(suggestions) > maxCoachingSuggestions {
+		suggestions = suggestions[:maxCoachingSuggestions]
+	}
+	return suggestions
+}
+
+// formatHour formats an hour of day as 8am or 8pm
+func formatHour(hour int) string {
+	return time.Date(2000, 1, 1, hour, 0, 0, 0, time.UTC).Format("3pm")
+}
+
+// CoachCreator asks Gemini for actionable suggestions based on a creator's performance history
+func (v *VertexAIClient) CoachCreator(performance []ContentTypePerformance) ([]string, error) {
+	systemPrompt := `You are a coach for creators of AI-generated content on a social platform.
+Given a creator's performance per content type, give short, specific and actionable suggestions
+grounded in the numbers, such as the best hour to post or whether shorter prompts perform better.
+Hours are in 24-hour format. Return ONLY a valid JSON object with this exact field:
+- suggestions: array of 3-5 suggestions (strings), each one sentence
+
+Example response:
+{
+  "suggestions": ["Your music posts peak at 8pm; schedule new tracks for the evening", "Your best images use short prompts of about 8 words; try shorter prompts"]
+}
+
+Do not include any explanation, only return the JSON object.`
+
+	history, err := json.MarshalIndent(performance, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode performance: %w", err)
+	}
+	userPrompt := fmt.Sprintf("Performance per content type:\n%s\n\nSuggest how this creator can improve.", history)
+
+	response, err := v.callGemini(config.GeminiUseCaseCoaching, systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tolerate extra text around the JSON object
+	jsonStart := strings.Index(response, "{")
+	jsonEnd := strings.LastIndex(response, "}")
+	if jsonStart < 0 || jsonEnd <= jsonStart {
+		return nil, fmt.Errorf("no JSON object in gemini response")
+	}
+
+	var result struct {
+		Suggestions []string `json:"suggestions"`
+	}
+	if err := json.Unmarshal([]byte(response[jsonStart:jsonEnd+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse suggestions: %w", err)
+	}
+
+	suggestions := make([]string, 0, len(result.Suggestions))
+	for _, suggestion := range result.Suggestions {
+		if suggestion = strings.TrimSpace(suggestion); suggestion != "" && len(suggestions) < maxCoachingSuggestions {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	if len(suggestions) == 0 {
+		return nil, fmt.Errorf("no suggestions in gemini response")
+	}
+	return suggestions, nil
+}
+
+// getCreatorCoaching returns the cached suggestions of a creator, nil when there are none
+func (fc *FirestoreClient) getCreatorCoaching(userID string) (*CreatorCoaching, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := fc.client.Collection("creator_coaching").Doc(userID).Get(fc.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var coaching CreatorCoaching
+	if err := doc.DataTo(&coaching); err != nil {
+		return nil, err
+	}
+	return &coaching, nil
+}
+
+// saveCreatorCoaching caches the suggestions of a creator for the rest of the reporting day
+func (fc *FirestoreClient) saveCreatorCoaching(coaching *CreatorCoaching) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("creator_coaching").Doc(coaching.UserID).Set(fc.ctx, coaching)
+	return err
+}
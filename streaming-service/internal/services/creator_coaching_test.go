@@ -0,0 +1,64 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizePerformance(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	at := func(hour int) time.Time {
+		return time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+	}
+	posts := []coachingPost{
+		{contentType: "music", createdAt: at(17), promptWords: 4, views: 100, engagement: 40},
+		{contentType: "music", createdAt: at(17), promptWords: 6, views: 100, engagement: 20},
+		{contentType: "music", createdAt: at(9), promptWords: 20, views: 100, engagement: 5},
+		{contentType: "music", createdAt: at(9), promptWords: 18, views: 100, engagement: 5},
+		{contentType: "image", createdAt: at(12), promptWords: 10, views: 0, engagement: 0},
+	}
+
+	performance := summarizePerformance(posts, loc)
+	if len(performance) != 2 || performance[0].ContentType != "music" {
+		t.Fatalf("expected music first, got %+v", performance)
+	}
+
+	music := performance[0]
+	if music.PostCount != 4 || music.AverageEngagement != 17.5 || music.EngagementRate != 17.5 {
+		t.Errorf("unexpected music averages: %+v", music)
+	}
+	// 17:00 UTC is 20:00 in the reporting zone
+	if music.BestHour != 20 {
+		t.Errorf("expected best hour 20, got %d", music.BestHour)
+	}
+	if music.AveragePromptWords != 12 || music.TopPromptWords != 5 {
+		t.Errorf("expected 12 average and 5 top prompt words, got %v and %v", music.AveragePromptWords, music.TopPromptWords)
+	}
+
+	if image := performance[1]; image.BestHour != -1 || image.EngagementRate != 0 {
+		t.Errorf("expected no best hour without engagement, got %+v", image)
+	}
+}
+
+func TestHeuristicSuggestions(t *testing.T) {
+	performance := []ContentTypePerformance{
+		{ContentType: "music", PostCount: 4, AverageEngagement: 17.5, BestHour: 20, AveragePromptWords: 12, TopPromptWords: 5},
+		{ContentType: "image", PostCount: 3, AverageEngagement: 2, BestHour: 9, AveragePromptWords: 10, TopPromptWords: 10},
+	}
+
+	suggestions := heuristicSuggestions(performance)
+	joined := strings.Join(suggestions, "\n")
+	for _, want := range []string{"music posts get the most engagement", "music posts peak at 8pm", "shorter prompts", "image posts peak at 9am", "image posts get less than half"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected a suggestion containing %q, got %v", want, suggestions)
+		}
+	}
+	if len(suggestions) > maxCoachingSuggestions {
+		t.Errorf("expected at most %d suggestions, got %d", maxCoachingSuggestions, len(suggestions))
+	}
+
+	if fresh := heuristicSuggestions([]ContentTypePerformance{{ContentType: "text", PostCount: 1, BestHour: -1}}); len(fresh) != 1 {
+		t.Errorf("expected a generic suggestion for a new creator, got %v", fresh)
+	}
+}
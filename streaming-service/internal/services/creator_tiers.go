@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+// Creator tiers, lowest first
+const (
+	CreatorTierNew         = "new"
+	CreatorTierEmerging    = "emerging"
+	CreatorTierEstablished = "established"
+	CreatorTierStar        = "star"
+)
+
+// Every tier, lowest first
+var creatorTierOrder = []string{CreatorTierNew, CreatorTierEmerging, CreatorTierEstablished, CreatorTierStar}
+
+// A creator is demoted only once their metrics drop below this share of their tier's thresholds,
+// so creators on a boundary do not flip between tiers on every run
+const creatorTierDemotionMargin = 0.8
+
+// creatorTierRule is what a creator needs to reach a tier: enough posts, and enough views,
+// followers or viral posts
+type creatorTierRule struct {
+	tier          string
+	minPosts      int
+	minViews      int64
+	minFollowers  int
+	minViralPosts int
+}
+
+// Tier rules, highest tier first; creators matching none are new
+var creatorTierRules = []creatorTierRule{
+	{tier: CreatorTierStar, minPosts: 10, minViews: 100000, minFollowers: 10000, minViralPosts: 10},
+	{tier: CreatorTierEstablished, minPosts: 5, minViews: 10000, minFollowers: 1000, minViralPosts: 3},
+	{tier: CreatorTierEmerging, minPosts: 2, minViews: 500, minFollowers: 100, minViralPosts: 1},
+}
+
+// IsCreatorTier reports whether tier is a known creator tier
+func IsCreatorTier(tier string) bool {
+	return creatorTierRank(tier) >= 0
+}
+
+func creatorTierRank(tier string) int {
+	for i, t := range creatorTierOrder {
+		if t == tier {
+			return i
+		}
+	}
+	return -1
+}
+
+// matches reports whether a creator reaches the rule with every threshold scaled by scale
+func (r creatorTierRule) matches(m CreatorMetrics, scale float64) bool {
+	if float64(m.PostCount) < float64(r.minPosts)*scale {
+		return false
+	}
+	return float64(m.TotalViews) >= float64(r.minViews)*scale ||
+		float64(m.FollowerCount) >= float64(r.minFollowers)*scale ||
+		float64(m.ViralPostCount) >= float64(r.minViralPosts)*scale
+}
+
+// classifyCreatorTier returns the tier a creator's metrics reach. A creator already in a higher
+// tier keeps it while they stay within the demotion margin of its thresholds.
+func classifyCreatorTier(m CreatorMetrics, previous string) string {
+	tier := CreatorTierNew
+	for _, rule := range creatorTierRules {
+		if rule.matches(m, 1) {
+			tier = rule.tier
+			break
+		}
+	}
+
+	if creatorTierRank(previous) > creatorTierRank(tier) {
+		for _, rule := range creatorTierRules {
+			if rule.tier == previous && rule.matches(m, creatorTierDemotionMargin) {
+				return previous
+			}
+		}
+	}
+	return tier
+}
+
+// creatorTierOf returns the stored tier of a creator; creators never classified are new
+func creatorTierOf(tiers map[string]string, userID string) string {
+	if tier, ok := tiers[userID]; ok {
+		return tier
+	}
+	return CreatorTierNew
+}
+
+// CreatorTiers returns the stored tier of every classified creator
+func (fc *FirestoreClient) CreatorTiers() (map[string]string, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	iter := fc.client.Collection("creator_metrics").Select("tier").Documents(fc.ctx)
+	defer iter.Stop()
+
+	tiers := make(map[string]string)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tier, ok := doc.Data()["tier"].(string); ok {
+			tiers[doc.Ref.ID] = tier
+		}
+	}
+	return tiers, nil
+}
+
+// CreatorTierClassifier periodically classifies creators into tiers from their historical
+// metrics, stores the metrics and tier in creator_metrics, snapshots their engagement for the
+// rising creators feed and publishes tier changes for the notification system
+type CreatorTierClassifier struct {
+	firestoreClient *FirestoreClient
+	analytics       *DashboardAnalytics
+	producer        *KafkaProducer
+	ctx             context.Context
+	cancel          context.CancelFunc
+	runInterval     time.Duration
+	leader          *LeaderElector
+}
+
+func NewCreatorTierClassifier(firestoreClient *FirestoreClient, producer *KafkaProducer, runInterval time.Duration) *CreatorTierClassifier {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &CreatorTierClassifier{
+		firestoreClient: firestoreClient,
+		analytics:       NewDashboardAnalytics(firestoreClient),
+		producer:        producer,
+		ctx:             ctx,
+		cancel:          cancel,
+		runInterval:     runInterval,
+	}
+}
+
+// UseLeaderElector classifies on the elected instance only, so each tier change is announced
+// once
+func (ct *CreatorTierClassifier) UseLeaderElector(leader *LeaderElector) {
+	ct.leader = leader
+}
+
+// Start begins the periodic classification loop
+func (ct *CreatorTierClassifier) Start() {
+	logger.Infof("🏅 Starting creator tier classifier (interval %v)", ct.runInterval)
+
+	ticker := time.NewTicker(ct.runInterval)
+	go func() {
+		for {
+			select {
+			case <-ct.ctx.Done():
+				ticker.Stop()
+				logger.Info("🛑 Creator tier classifier stopped")
+				return
+			case <-ticker.C:
+				if !ct.leader.IsLeader() {
+					continue
+				}
+				if err := ct.Classify(); err != nil {
+					logger.Errorf("❌ Creator tier classification failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the classification loop
+func (ct *CreatorTierClassifier) Stop() {
+	ct.cancel()
+}
+
+// Classify recomputes the tier of every creator with a scored post. Creators classified for
+// the first time get their tier silently; later changes are published as events.
+func (ct *CreatorTierClassifier) Classify() error {
+	startTime := time.Now()
+	logger.Debug("🏅 Classifying creator tiers...")
+
+	creators, err := ct.analytics.creatorMetrics(ContentFilter{})
+	if err != nil {
+		return err
+	}
+	tiers, err := ct.firestoreClient.CreatorTiers()
+	if err != nil {
+		return err
+	}
+
+	changed, errorCount := 0, 0
+	for _, creator := range creators {
+		previous, classified := tiers[creator.UserID]
+		creator.Tier = classifyCreatorTier(creator, previous)
+
+		if err := ct.firestoreClient.SaveCreatorMetrics(creator, previous); err != nil {
+			errorCount++
+			continue
+		}
+		if err := ct.firestoreClient.SaveCreatorEngagementSnapshot(creator); err != nil {
+			logger.Infof("Failed to snapshot engagement of creator %s: %v", creator.UserID, err)
+		}
+		if !classified || creator.Tier == previous {
+			continue
+		}
+
+		changed++
+		logger.Infof("🏅 Creator %s moved from %s to %s", creator.UserID, previous, creator.Tier)
+		if ct.producer == nil {
+			continue
+		}
+		err := ct.producer.PublishCreatorTierChange(models.CreatorTierChange{
+			UserID:         creator.UserID,
+			PreviousTier:   previous,
+			Tier:           creator.Tier,
+			PostCount:      creator.PostCount,
+			TotalViews:     creator.TotalViews,
+			FollowerCount:  creator.FollowerCount,
+			ViralPostCount: creator.ViralPostCount,
+			ChangedAt:      creator.CalculatedAt,
+		}, startTime)
+		if err != nil {
+			logger.Infof("Failed to publish tier change for creator %s: %v", creator.UserID, err)
+		}
+	}
+
+	logger.Infof("✅ Creator tiers classified: creators=%d, changed=%d, errors=%d, duration=%v",
+		len(creators), changed, errorCount, time.Since(startTime))
+	return nil
+}
+
+// SaveCreatorMetrics stores a creator's metrics and tier, recording when the tier last changed
+func (fc *FirestoreClient) SaveCreatorMetrics(creator CreatorMetrics, previousTier string) error {
+	data := map[string]interface{}{
+		"userId":         creator.UserID,
+		"tier":           creator.Tier,
+		"totalScore":     creator.TotalScore,
+		"totalViews":     creator.TotalViews,
+		"totalLikes":     creator.TotalLikes,
+		"totalComments":  creator.TotalComments,
+		"postCount":      creator.PostCount,
+		"viralPostCount": creator.ViralPostCount,
+		"followerCount":  creator.FollowerCount,
+		"engagementRate": creator.EngagementRate,
+		"averageScore":   creator.AverageScore,
+		"calculatedAt":   creator.CalculatedAt,
+	}
+	if creator.Tier != previousTier {
+		data["previousTier"] = previousTier
+		data["tierChangedAt"] = creator.CalculatedAt
+	}
+
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("creator_metrics").Doc(creator.UserID).Set(fc.ctx, data, firestore.MergeAll)
+	return err
+}
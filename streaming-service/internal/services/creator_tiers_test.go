@@ -0,0 +1,43 @@
+package services
+
+import "testing"
+
+func TestClassifyCreatorTier(t *testing.T) {
+	cases := []struct {
+		name     string
+		metrics  CreatorMetrics
+		previous string
+		want     string
+	}{
+		{"first post", CreatorMetrics{PostCount: 1, TotalViews: 5000}, "", CreatorTierNew},
+		{"a few viewed posts", CreatorMetrics{PostCount: 3, TotalViews: 800}, "", CreatorTierEmerging},
+		{"followers without posts", CreatorMetrics{PostCount: 1, FollowerCount: 50000}, "", CreatorTierNew},
+		{"viral hits", CreatorMetrics{PostCount: 6, TotalViews: 2000, ViralPostCount: 3}, "", CreatorTierEstablished},
+		{"large audience", CreatorMetrics{PostCount: 40, TotalViews: 250000}, "", CreatorTierStar},
+		{"star within margin", CreatorMetrics{PostCount: 40, TotalViews: 85000}, CreatorTierStar, CreatorTierStar},
+		{"star past margin", CreatorMetrics{PostCount: 40, TotalViews: 60000}, CreatorTierStar, CreatorTierEstablished},
+		{"promotion ignores margin", CreatorMetrics{PostCount: 5, TotalViews: 9000}, CreatorTierEmerging, CreatorTierEmerging},
+	}
+	for _, tc := range cases {
+		if got := classifyCreatorTier(tc.metrics, tc.previous); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestInCreatorTier(t *testing.T) {
+	tiers := map[string]string{"user_star": CreatorTierStar}
+
+	if !inCreatorTier(tiers, CreatorTierStar, map[string]interface{}{"userId": "user_star"}) {
+		t.Error("expected post by star creator to match star tier")
+	}
+	if !inCreatorTier(tiers, CreatorTierNew, map[string]interface{}{"userId": "user_unclassified"}) {
+		t.Error("expected unclassified creator to count as new")
+	}
+	if inCreatorTier(tiers, CreatorTierNew, map[string]interface{}{"userId": "user_star"}) {
+		t.Error("expected star creator not to match new tier")
+	}
+	if !inCreatorTier(nil, "", map[string]interface{}{}) {
+		t.Error("expected every post to match without a tier filter")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"time"
 
+	"cloud.google.com/go/firestore"
 	"confluent-viral-intelligence/internal/logger"
 	"confluent-viral-intelligence/internal/models"
 	"google.golang.org/api/iterator"
@@ -28,6 +29,7 @@ func NewDashboardAnalytics(firestoreClient *FirestoreClient) *DashboardAnalytics
 type DashboardMetrics struct {
 	TotalViews        int64                `json:"totalViews"`
 	TotalInteractions int64                `json:"totalInteractions"`
+	InteractionCounts map[string]int64     `json:"interactionCounts"` // views, likes, comments, shares and remixes
 	ViralPosts        int                  `json:"viralPosts"`
 	TotalPosts        int                  `json:"totalPosts"`
 	ActiveUsers       int                  `json:"activeUsers"`
@@ -54,85 +56,134 @@ type CreatorMetrics struct {
 	FollowerCount      int       `json:"followerCount"`
 	EngagementRate     float64   `json:"engagementRate"`
 	AverageScore       float64   `json:"averageScore"`
+	Tier               string    `json:"tier,omitempty"` // new, emerging, established or star
 	CalculatedAt       time.Time `json:"calculatedAt"`
 }
 
-// GetDashboardMetrics returns comprehensive metrics for the dashboard
-func (da *DashboardAnalytics) GetDashboardMetrics() (*DashboardMetrics, error) {
-	logger.Debug("📊 Calculating dashboard metrics...")
-	
+// Trending scores read to find the top posts with content
+const dashboardTopPostCandidates = 25
+
+// Highest-scoring posts matching a content filter read for a filtered trending feed, and whose
+// rollups are summed into filtered engagement trends
+const (
+	filteredTrendingCandidates = 200
+	filteredTrendPosts         = 200
+)
+
+// GetDashboardMetrics returns comprehensive metrics for the dashboard from the totals the
+// metrics aggregator maintains. When they were never computed, they are computed from a full
+// scan and stored first. A non-nil window replaces the all-time counts with those of the
+// window, summed from its hourly buckets.
+func (da *DashboardAnalytics) GetDashboardMetrics(window *DashboardWindow) (*DashboardMetrics, error) {
+	totals, err := da.firestoreClient.LoadDashboardTotals()
+	if err != nil {
+		return nil, err
+	}
+	if totals == nil {
+		logger.Info("📊 No dashboard metrics stored yet, computing them from a full scan...")
+		if totals, err = da.scanDashboardTotals(); err != nil {
+			return nil, err
+		}
+		if err := da.firestoreClient.SaveDashboardTotals(totals); err != nil {
+			logger.Warnf("Failed to store dashboard metrics: %v", err)
+		}
+	}
+
 	metrics := &DashboardMetrics{
-		TopContentTypes: make(map[string]int),
+		TotalViews:        totals.Views,
+		TotalInteractions: totals.Likes + totals.Comments + totals.Shares,
+		InteractionCounts: map[string]int64{
+			"views":    totals.Views,
+			"likes":    totals.Likes,
+			"comments": totals.Comments,
+			"shares":   totals.Shares,
+			"remixes":  totals.Remixes,
+		},
+		ViralPosts:      int(totals.ViralPosts),
+		TotalPosts:      int(totals.Posts),
+		TopContentTypes: make(map[string]int, len(totals.ContentTypes)),
 		TopPosts:        []models.TrendingScore{},
 		TopCreators:     []CreatorMetrics{},
-		CalculatedAt:    time.Now(),
+		CalculatedAt:    totals.UpdatedAt,
 	}
-	
-	// Get all trending scores
-	iter := da.firestoreClient.client.Collection("trending_scores").Documents(da.ctx)
-	
-	var allScores []models.TrendingScore
-	totalScore := 0.0
-	
+	for contentType, count := range totals.ContentTypes {
+		metrics.TopContentTypes[contentType] = int(count)
+	}
+	if users, err := HyperLogLogFromBytes(totals.ActiveUsers); err == nil {
+		metrics.ActiveUsers = int(users.Count())
+	}
+	if metrics.TotalPosts > 0 {
+		metrics.AverageScore = totals.ScoreSum / float64(metrics.TotalPosts)
+	}
+	if metrics.TotalViews > 0 {
+		metrics.EngagementRate = (float64(metrics.TotalInteractions) / float64(metrics.TotalViews)) * 100
+	}
+	if window != nil {
+		sum, err := da.loadWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		applyWindow(metrics, sum)
+	}
+
+	if metrics.TopPosts, err = da.topContentPosts(3); err != nil {
+		logger.Warnf("Failed to fetch top posts for dashboard metrics: %v", err)
+		metrics.TopPosts = []models.TrendingScore{}
+	}
+	return metrics, nil
+}
+
+// topContentPosts returns the highest scored posts that have content and are not flagged by
+// moderation, enriched with their post data
+func (da *DashboardAnalytics) topContentPosts(limit int) ([]models.TrendingScore, error) {
+	iter := da.firestoreClient.client.Collection("trending_scores").
+		OrderBy("Score", firestore.Desc).
+		Limit(dashboardTopPostCandidates).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	var topScores []models.TrendingScore
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
-		if err != nil {			continue
+		if err != nil {
+			return nil, err
 		}
-		
+
 		var score models.TrendingScore
 		if err := doc.DataTo(&score); err != nil {
 			continue
 		}
-		
-		allScores = append(allScores, score)
-		
-		// Aggregate metrics
-		metrics.TotalViews += score.ViewCount
-		metrics.TotalInteractions += score.LikeCount + score.CommentCount + score.ShareCount
-		totalScore += score.Score
-		
-		// Count viral posts (score > 100 or viral_probability > 0.7)
-		if score.Score > 100 || score.ViralProbability > 0.7 {
-			metrics.ViralPosts++
-		}
+		topScores = append(topScores, score)
 	}
-	
-	metrics.TotalPosts = len(allScores)
-	
-	// Calculate average score
-	if metrics.TotalPosts > 0 {
-		metrics.AverageScore = totalScore / float64(metrics.TotalPosts)
-	}
-	
-	// Calculate engagement rate
-	if metrics.TotalViews > 0 {
-		metrics.EngagementRate = (float64(metrics.TotalInteractions) / float64(metrics.TotalViews)) * 100
-	}
-	
-	// Get top posts (top 3) and enrich with post data
-	sort.Slice(allScores, func(i, j int) bool {
-		return allScores[i].Score > allScores[j].Score
-	})
-	
+
 	// Enrich posts with actual post data and filter out test/invalid posts
 	enrichedPosts := []models.TrendingScore{}
 	
-	for _, score := range allScores {
-		// Skip if we already have 3 posts
-		if len(enrichedPosts) >= 3 {
+	for _, score := range topScores {
+		if len(enrichedPosts) >= limit {
 			break
 		}
 		
 		// Get post details
 		postDoc, err := da.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(da.ctx)
-		if err != nil {			continue // Skip posts that don't exist in posts collection
+		if IsNotFound(err) {
+			continue // Skip posts that don't exist in posts collection
+		}
+		if err != nil {
+			return nil, err
 		}
 		
 		var postData map[string]interface{}
-		if err := postDoc.DataTo(&postData); err != nil {			continue
+		if err := postDoc.DataTo(&postData); err != nil {
+			continue
+		}
+		
+		// Keep posts flagged by moderation out of the top posts
+		if isModerationFlagged(postData) {
+			continue
 		}
 		
 		// Add post data to the score
@@ -167,51 +218,144 @@ func (da *DashboardAnalytics) GetDashboardMetrics() (*DashboardMetrics, error) {
 		}
 	}
 	
-	metrics.TopPosts = enrichedPosts
-	logger.Debugf("📊 Top posts with content: %d", len(enrichedPosts))
-	
-	// Get content type distribution and active users
-	contentTypes := make(map[string]int)
-	activeUsers := make(map[string]bool)
-	
-	for _, score := range allScores {
-		// Get post details for content type and user
-		postDoc, err := da.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(da.ctx)
+	return enrichedPosts, nil
+}
+
+// scanDashboardTotals computes the dashboard totals from every trending score and post
+func (da *DashboardAnalytics) scanDashboardTotals() (*dashboardTotals, error) {
+	logger.Debug("📊 Scanning trending scores for dashboard metrics...")
+
+	totals := &dashboardTotals{ContentTypes: make(map[string]int64)}
+	creators := NewHyperLogLog()
+
+	iter := da.firestoreClient.client.Collection("trending_scores").Documents(da.ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
 		if err != nil {
+			return nil, err
+		}
+
+		var score models.TrendingScore
+		if err := doc.DataTo(&score); err != nil {
 			continue
 		}
-		
+
+		totals.Posts++
+		totals.Views += score.ViewCount
+		totals.Likes += score.LikeCount
+		totals.Comments += score.CommentCount
+		totals.Shares += score.ShareCount
+		totals.Remixes += score.RemixCount
+		totals.ScoreSum += score.Score
+		if isDashboardViral(&score) {
+			totals.ViralPosts++
+		}
+
+		// Content type and creator come from the post
+		postDoc, err := da.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(da.ctx)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
 		var postData map[string]interface{}
 		if err := postDoc.DataTo(&postData); err != nil {
 			continue
 		}
-		
-		// Track content type
 		if contentType, ok := postData["contentType"].(string); ok {
-			contentTypes[contentType]++
+			totals.ContentTypes[contentType]++
 		}
-		
-		// Track active users
 		if userID, ok := postData["userId"].(string); ok {
-			activeUsers[userID] = true
+			creators.Add(userID)
 		}
 	}
+	totals.ActiveUsers = creators.Bytes()
+
+	logger.Infof("✅ Dashboard metrics calculated: posts=%d, views=%d, viral=%d", totals.Posts, totals.Views, totals.ViralPosts)
+	return totals, nil
+}
+
+// GetTopCreators returns the top creators based on their content performance, with their
+// stored tier. A non-empty tier limits the leaderboard to creators in that tier, and filter to
+// their posts matching it. A non-nil window ranks creators by their performance within it and
+// cannot be combined with a filter.
+func (da *DashboardAnalytics) GetTopCreators(limit int, tier string, filter ContentFilter, window *DashboardWindow) ([]CreatorMetrics, error) {
+	logger.Debugf("📊 Calculating top %d creators...", limit)
+	
+	if window != nil {
+		return da.topCreatorsInWindow(limit, tier, window)
+	}
 	
-	metrics.TopContentTypes = contentTypes
-	metrics.ActiveUsers = len(activeUsers)
+	all, err := da.creatorMetrics(filter)
+	if err != nil {
+		return nil, err
+	}
 	
-	logger.Infof("✅ Dashboard metrics calculated: posts=%d, views=%d, interactions=%d, viral=%d",
-		metrics.TotalPosts, metrics.TotalViews, metrics.TotalInteractions, metrics.ViralPosts)
+	tiers, err := da.firestoreClient.CreatorTiers()
+	if err != nil {
+		return nil, err
+	}
 	
-	return metrics, nil
+	creators := make([]CreatorMetrics, 0, len(all))
+	for _, creator := range all {
+		creator.Tier = creatorTierOf(tiers, creator.UserID)
+		if tier != "" && creator.Tier != tier {
+			continue
+		}
+		creators = append(creators, creator)
+	}
+	
+	// Sort by total score
+	sort.Slice(creators, func(i, j int) bool {
+		return creators[i].TotalScore > creators[j].TotalScore
+	})
+	
+	// Limit results
+	if len(creators) > limit {
+		creators = creators[:limit]
+	}
+	
+	logger.Infof("✅ Top creators calculated: %d creators", len(creators))
+	return creators, nil
 }
 
-// GetTopCreators returns the top creators based on their content performance
-func (da *DashboardAnalytics) GetTopCreators(limit int) ([]CreatorMetrics, error) {
-	logger.Debugf("📊 Calculating top %d creators...", limit)
-	
-	// Get all trending scores
-	iter := da.firestoreClient.client.Collection("trending_scores").Documents(da.ctx)
+// topCreatorsInWindow returns the creators whose posts gained the most trending score within
+// a window
+func (da *DashboardAnalytics) topCreatorsInWindow(limit int, tier string, window *DashboardWindow) ([]CreatorMetrics, error) {
+	sum, err := da.loadWindow(window)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := da.firestoreClient.CreatorTiers()
+	if err != nil {
+		return nil, err
+	}
+
+	creators := make([]CreatorMetrics, 0, limit)
+	for _, creator := range windowCreators(sum, tiers, tier) {
+		if len(creators) >= limit {
+			break
+		}
+		if !da.enrichCreator(&creator) {
+			continue
+		}
+		creators = append(creators, creator)
+	}
+
+	logger.Infof("✅ Top creators calculated: %d creators", len(creators))
+	return creators, nil
+}
+
+// creatorMetrics aggregates the content performance of every creator with a scored post
+// matching filter
+func (da *DashboardAnalytics) creatorMetrics(filter ContentFilter) ([]CreatorMetrics, error) {
+	// Get the trending scores of matching posts
+	iter := da.firestoreClient.trendingScoresQuery(filter).Documents(da.ctx)
 	
 	// Aggregate by user
 	creatorMap := make(map[string]*CreatorMetrics)
@@ -230,19 +374,25 @@ func (da *DashboardAnalytics) GetTopCreators(limit int) ([]CreatorMetrics, error
 			continue
 		}
 		
-		// Get post details to find user
-		postDoc, err := da.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(da.ctx)
-		if err != nil {
-			continue
-		}
-		
-		var postData map[string]interface{}
-		if err := postDoc.DataTo(&postData); err != nil {
-			continue
+		// Scores without the creator facet need the post to find user
+		userID := score.CreatorID
+		if userID == "" {
+			postDoc, err := da.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(da.ctx)
+			if IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			
+			var postData map[string]interface{}
+			if err := postDoc.DataTo(&postData); err != nil {
+				continue
+			}
+			
+			userID, _ = postData["userId"].(string)
 		}
-		
-		userID, ok := postData["userId"].(string)
-		if !ok || userID == "" {
+		if userID == "" {
 			continue
 		}
 		
@@ -269,31 +419,11 @@ func (da *DashboardAnalytics) GetTopCreators(limit int) ([]CreatorMetrics, error
 	
 	// Enrich with user data and calculate averages
 	creators := make([]CreatorMetrics, 0, len(creatorMap))
-	for userID, creator := range creatorMap {
-		// Get user details
-		userDoc, err := da.firestoreClient.client.Collection("users").Doc(userID).Get(da.ctx)
-		if err != nil {			continue
-		}
-		
-		var userData map[string]interface{}
-		if err := userDoc.DataTo(&userData); err != nil {
+	for _, creator := range creatorMap {
+		if !da.enrichCreator(creator) {
 			continue
 		}
 		
-		// Set user details
-		if username, ok := userData["username"].(string); ok {
-			creator.Username = username
-		}
-		if displayName, ok := userData["displayName"].(string); ok {
-			creator.DisplayName = displayName
-		}
-		if photoURL, ok := userData["photoURL"].(string); ok {
-			creator.PhotoURL = photoURL
-		}
-		if followerCount, ok := userData["followerCount"].(int64); ok {
-			creator.FollowerCount = int(followerCount)
-		}
-		
 		// Calculate averages
 		if creator.PostCount > 0 {
 			creator.AverageScore = creator.TotalScore / float64(creator.PostCount)
@@ -306,24 +436,52 @@ func (da *DashboardAnalytics) GetTopCreators(limit int) ([]CreatorMetrics, error
 		creators = append(creators, *creator)
 	}
 	
-	// Sort by total score
-	sort.Slice(creators, func(i, j int) bool {
-		return creators[i].TotalScore > creators[j].TotalScore
-	})
+	return creators, nil
+}
+
+// enrichCreator copies a creator's user details onto their metrics, false when the user
+// cannot be read
+func (da *DashboardAnalytics) enrichCreator(creator *CreatorMetrics) bool {
+	userDoc, err := da.firestoreClient.client.Collection("users").Doc(creator.UserID).Get(da.ctx)
+	if err != nil {
+		return false
+	}
 	
-	// Limit results
-	if len(creators) > limit {
-		creators = creators[:limit]
+	var userData map[string]interface{}
+	if err := userDoc.DataTo(&userData); err != nil {
+		return false
 	}
 	
-	logger.Infof("✅ Top creators calculated: %d creators", len(creators))
-	return creators, nil
+	if username, ok := userData["username"].(string); ok {
+		creator.Username = username
+	}
+	if displayName, ok := userData["displayName"].(string); ok {
+		creator.DisplayName = displayName
+	}
+	if photoURL, ok := userData["photoURL"].(string); ok {
+		creator.PhotoURL = photoURL
+	}
+	if followerCount, ok := userData["followerCount"].(int64); ok {
+		creator.FollowerCount = int(followerCount)
+	}
+	return true
 }
 
-// GetContentTypeBreakdown returns breakdown of content by type
-func (da *DashboardAnalytics) GetContentTypeBreakdown() (map[string]ContentTypeMetrics, error) {
+// GetContentTypeBreakdown returns breakdown of content by type. A non-nil window breaks down
+// the posts created and the engagement received within it.
+func (da *DashboardAnalytics) GetContentTypeBreakdown(window *DashboardWindow) (map[string]ContentTypeMetrics, error) {
 	logger.Debug("📊 Calculating content type breakdown...")
 	
+	if window != nil {
+		sum, err := da.loadWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		breakdown := windowContentTypes(sum)
+		logger.Infof("✅ Content type breakdown calculated: %d types", len(breakdown))
+		return breakdown, nil
+	}
+	
 	breakdown := make(map[string]ContentTypeMetrics)
 	
 	// Get all posts
@@ -388,71 +546,91 @@ type ContentTypeMetrics struct {
 	AvgLikes    float64 `json:"avgLikes"`
 }
 
-// GetEngagementTrends returns engagement trends over time
-func (da *DashboardAnalytics) GetEngagementTrends(days int) ([]EngagementTrend, error) {
-	logger.Debugf("📊 Calculating engagement trends for last %d days...", days)
-	
-	trends := make([]EngagementTrend, 0, days)
-	
-	for i := 0; i < days; i++ {
-		date := time.Now().AddDate(0, 0, -i)
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		endOfDay := startOfDay.Add(24 * time.Hour)
-		
-		trend := EngagementTrend{
-			Date: startOfDay,
-		}
-		
-		// Query posts created on this day
-		iter := da.firestoreClient.client.Collection("posts").
-			Where("isPublic", "==", true).
-			Where("createdAt", ">=", startOfDay).
-			Where("createdAt", "<", endOfDay).
-			Documents(da.ctx)
-		
-		for {
-			doc, err := iter.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				continue
-			}
-			
-			var postData map[string]interface{}
-			if err := doc.DataTo(&postData); err != nil {
-				continue
-			}
-			
-			trend.PostCount++
-			
-			if viewCount, ok := postData["viewCount"].(int64); ok {
-				trend.Views += viewCount
-			}
-			if likeCount, ok := postData["likeCount"].(int64); ok {
-				trend.Likes += likeCount
-			}
-			if commentCount, ok := postData["commentCount"].(int64); ok {
-				trend.Comments += commentCount
-			}
+// SortedContentTypes returns the entries of a content type breakdown, most posts first
+func SortedContentTypes(breakdown map[string]ContentTypeMetrics) []ContentTypeMetrics {
+	sorted := make([]ContentTypeMetrics, 0, len(breakdown))
+	for _, metrics := range breakdown {
+		sorted = append(sorted, metrics)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
 		}
-		
-		trends = append(trends, trend)
+		return sorted[i].ContentType < sorted[j].ContentType
+	})
+	return sorted
+}
+
+// GetEngagementTrends returns engagement trends over time, one entry per calendar day in loc
+// with the posts created and the views, likes and comments received that day. They are read
+// from the daily rollups, or from the hourly ones when loc is not the reporting time zone. A
+// non-nil window replaces the last days with the days it covers, summing its hours only. A
+// non-empty filter sums the rollups of the highest-scoring matching posts instead, which do
+// not count new posts.
+func (da *DashboardAnalytics) GetEngagementTrends(days int, loc *time.Location, filter ContentFilter, window *DashboardWindow) ([]EngagementTrend, error) {
+	today := StartOfDay(time.Now(), loc)
+	from, to := today.AddDate(0, 0, 1-days), today.AddDate(0, 0, 1)
+	interval := RollupDay
+	if window != nil {
+		from, to = window.From, window.To
+		interval = RollupHour
 	}
-	
-	// Reverse to get chronological order
-	for i, j := 0, len(trends)-1; i < j; i, j = i+1, j-1 {
-		trends[i], trends[j] = trends[j], trends[i]
+	if loc.String() != da.firestoreClient.reportingLoc.String() {
+		interval = RollupHour
 	}
+	logger.Debugf("📊 Calculating engagement trends from %s to %s (%s, %s rollups)...", from.Format(time.RFC3339), to.Format(time.RFC3339), loc, interval)
+	
+	starts := rollupStarts(interval, from, to, loc)
+	var rollups []EngagementRollup
+	var err error
+	if filter.IsEmpty() {
+		rollups, err = da.firestoreClient.GetEngagementRollups("", interval, starts)
+	} else {
+		rollups, err = da.filteredRollups(filter, interval, starts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	trends := engagementTrends(rollups, from, to, loc)
 	
 	logger.Infof("✅ Engagement trends calculated: %d days", len(trends))
 	return trends, nil
 }
 
-// GetTrendingPostsWithContent returns trending posts that have actual content (for trending feed)
-func (da *DashboardAnalytics) GetTrendingPostsWithContent(limit int) ([]models.TrendingScore, error) {
+// filteredRollups returns the rollups of the highest-scoring posts matching a filter for the
+// periods of an interval starting at starts
+func (da *DashboardAnalytics) filteredRollups(filter ContentFilter, interval string, starts []time.Time) ([]EngagementRollup, error) {
+	docs, err := da.firestoreClient.trendingScoresQuery(filter).
+		OrderBy("Score", firestore.Desc).
+		Limit(filteredTrendPosts).
+		Documents(da.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	var rollups []EngagementRollup
+	for _, doc := range docs {
+		postRollups, err := da.firestoreClient.GetEngagementRollups(doc.Ref.ID, interval, starts)
+		if err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, postRollups...)
+	}
+	return rollups, nil
+}
+
+// GetTrendingPostsWithContent returns trending posts that have actual content (for trending feed).
+// Only the post fields in fields are copied from the posts collection; nil copies all of them.
+// A non-empty creatorTier keeps only posts by creators in that tier.
+func (da *DashboardAnalytics) GetTrendingPostsWithContent(limit int, fields FieldSet, creatorTier string) ([]models.TrendingScore, error) {
 	logger.Debugf("📊 Getting trending posts with content (limit: %d)...", limit)
 	
+	tiers, err := da.tiersFor(creatorTier)
+	if err != nil {
+		return nil, err
+	}
+	
 	// Get all trending scores
 	iter := da.firestoreClient.client.Collection("trending_scores").Documents(da.ctx)
 	
@@ -481,6 +659,9 @@ func (da *DashboardAnalytics) GetTrendingPostsWithContent(limit int) ([]models.T
 	
 	// Enrich posts with actual post data and filter out posts without content
 	enrichedPosts := []models.TrendingScore{}
+	var duplicates []models.TrendingScore
+	diversity := da.firestoreClient.diversity
+	perCreator := make(map[string]int)
 	
 	for _, score := range allScores {
 		// Skip if we already have enough posts
@@ -490,135 +671,231 @@ func (da *DashboardAnalytics) GetTrendingPostsWithContent(limit int) ([]models.T
 		
 		// Get post details
 		postDoc, err := da.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(da.ctx)
-		if err != nil {			continue // Skip posts that don't exist in posts collection
+		if IsNotFound(err) {			continue // Skip posts that don't exist in posts collection
+		}
+		if err != nil {
+			return nil, err
 		}
 		
 		var postData map[string]interface{}
 		if err := postDoc.DataTo(&postData); err != nil {			continue
 		}
 		
-		// Add post data to the score
-		if contentType, ok := postData["contentType"].(string); ok {
-			score.ContentType = contentType
-		}
-		if outputUrls, ok := postData["outputUrls"].([]interface{}); ok && len(outputUrls) > 0 {
-			urls := make([]string, 0, len(outputUrls))
-			for _, url := range outputUrls {
-				if urlStr, ok := url.(string); ok {
-					urls = append(urls, urlStr)
-				}
-			}
-			score.OutputURLs = urls
+		// Keep posts flagged by moderation out of trending
+		if isModerationFlagged(postData) {
+			continue
 		}
-		if title, ok := postData["title"].(string); ok {
-			score.Title = title
+		
+		if !inCreatorTier(tiers, creatorTier, postData) {
+			continue
 		}
-		if description, ok := postData["description"].(string); ok {
-			score.Description = description
+		
+		if !da.clearsEngagementFloor(&score, postData) {
+			continue
 		}
-		if instructions, ok := postData["instructions"].(string); ok {
-			score.Instructions = instructions
+		
+		// Near-duplicates compete with their down-weighted score
+		keep, lowered := weighDuplicate(&score, postData, da.firestoreClient.duplicateWeight)
+		if !keep {
+			continue
 		}
 		
-		// Only add posts that have actual content
-		if score.ContentType != "" && len(score.OutputURLs) > 0 {
+		// Add requested post data to the score
+		urlCount := enrichTrendingScore(&score, postData, fields)
+		
+		// Only add posts that have actual content, up to the creator's share of the feed
+		if score.ContentType != "" && urlCount > 0 {
+			if !diversity.admit(perCreator, creatorOf(score, postData)) {
+				continue
+			}
+			if lowered {
+				duplicates = append(duplicates, score)
+				continue
+			}
 			enrichedPosts = append(enrichedPosts, score)
-			logger.Infof("✅ Enriched post %s: type=%s, urls=%d", score.PostID, score.ContentType, len(score.OutputURLs))
+			logger.Infof("✅ Enriched post %s: type=%s, urls=%d", score.PostID, score.ContentType, urlCount)
 		} else {
-			logger.Debugf(" Skipping post %s: no content (type=%s, urls=%d)", score.PostID, score.ContentType, len(score.OutputURLs))
+			logger.Debugf(" Skipping post %s: no content (type=%s, urls=%d)", score.PostID, score.ContentType, urlCount)
 		}
 	}
+	enrichedPosts = diversity.interleave(mergeDuplicates(enrichedPosts, duplicates, limit))
 	
 	logger.Debugf("📊 Trending posts with content: %d", len(enrichedPosts))
 	return enrichedPosts, nil
 }
 
-// GetTrendingPostsByContentType returns trending posts filtered by content type
-func (da *DashboardAnalytics) GetTrendingPostsByContentType(contentType string, limit int) ([]models.TrendingScore, error) {
-	logger.Debugf("📊 Getting trending posts for content type '%s' (limit: %d)...", contentType, limit)
-	
-	// Get all trending scores
-	iter := da.firestoreClient.client.Collection("trending_scores").Documents(da.ctx)
-	
-	var allScores []models.TrendingScore
-	
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {			continue
-		}
-		
-		var score models.TrendingScore
-		if err := doc.DataTo(&score); err != nil {
-			continue
+// GetFilteredTrendingPosts returns the trending posts matching a content filter and, when
+// creatorTier is set, by creators in that tier. The filter runs as an indexed query for the
+// highest scores, so only the top candidates are read.
+func (da *DashboardAnalytics) GetFilteredTrendingPosts(filter ContentFilter, limit int, fields FieldSet, creatorTier string) ([]models.TrendingScore, error) {
+	logger.Debugf("📊 Getting trending posts for %+v (limit: %d)...", filter, limit)
+
+	iter := da.firestoreClient.trendingScoresQuery(filter).
+		OrderBy("Score", firestore.Desc).
+		Limit(filteredTrendingCandidates).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	enrichedPosts, err := da.enrichTrendingCandidates(func() (models.TrendingScore, bool, error) {
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				return models.TrendingScore{}, false, nil
+			}
+			if err != nil {
+				return models.TrendingScore{}, false, err
+			}
+			var score models.TrendingScore
+			if err := doc.DataTo(&score); err == nil {
+				return score, true, nil
+			}
 		}
-		
-		allScores = append(allScores, score)
+	}, limit, fields, creatorTier)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Sort by score
-	sort.Slice(allScores, func(i, j int) bool {
-		return allScores[i].Score > allScores[j].Score
-	})
-	
-	// Enrich posts with actual post data and filter by content type
+
+	logger.Debugf("📊 Trending posts for %+v: %d", filter, len(enrichedPosts))
+	return enrichedPosts, nil
+}
+
+// enrichTrendingCandidates enriches candidate scores, taken in score order from next until it
+// reports no more, with post data until limit posts with content are found. Moderated posts,
+// posts of other creator tiers, posts below the engagement floor, posts without content and
+// posts past their creator's share of the feed are skipped, near-duplicates compete with their
+// down-weighted score, and runs of one content type are interleaved with others.
+func (da *DashboardAnalytics) enrichTrendingCandidates(next func() (models.TrendingScore, bool, error), limit int, fields FieldSet, creatorTier string) ([]models.TrendingScore, error) {
+	tiers, err := da.tiersFor(creatorTier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enrich posts with actual post data, in score order
 	enrichedPosts := []models.TrendingScore{}
-	
-	for _, score := range allScores {
-		// Skip if we already have enough posts
-		if len(enrichedPosts) >= limit {
+	var duplicates []models.TrendingScore
+	diversity := da.firestoreClient.diversity
+	perCreator := make(map[string]int)
+	for len(enrichedPosts) < limit {
+		score, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
 			break
 		}
-		
+
 		// Get post details
 		postDoc, err := da.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(da.ctx)
-		if err != nil {			continue
-		}
-		
-		var postData map[string]interface{}
-		if err := postDoc.DataTo(&postData); err != nil {			continue
+		if IsNotFound(err) {
+			continue
 		}
-		
-		// Add post data to the score
-		if ct, ok := postData["contentType"].(string); ok {
-			score.ContentType = ct
+		if err != nil {
+			return nil, err
 		}
-		
-		// Skip if content type doesn't match
-		if score.ContentType != contentType {
+
+		var postData map[string]interface{}
+		if err := postDoc.DataTo(&postData); err != nil {
 			continue
 		}
-		
-		if outputUrls, ok := postData["outputUrls"].([]interface{}); ok && len(outputUrls) > 0 {
-			urls := make([]string, 0, len(outputUrls))
-			for _, url := range outputUrls {
-				if urlStr, ok := url.(string); ok {
-					urls = append(urls, urlStr)
-				}
-			}
-			score.OutputURLs = urls
+
+		// Keep posts flagged by moderation out of trending
+		if isModerationFlagged(postData) {
+			continue
 		}
-		if title, ok := postData["title"].(string); ok {
-			score.Title = title
+
+		if !inCreatorTier(tiers, creatorTier, postData) {
+			continue
 		}
-		if description, ok := postData["description"].(string); ok {
-			score.Description = description
+
+		if !da.clearsEngagementFloor(&score, postData) {
+			continue
 		}
-		if instructions, ok := postData["instructions"].(string); ok {
-			score.Instructions = instructions
+
+		// Near-duplicates compete with their down-weighted score
+		keep, lowered := weighDuplicate(&score, postData, da.firestoreClient.duplicateWeight)
+		if !keep {
+			continue
 		}
-		
-		// Only add posts that have actual content
-		if len(score.OutputURLs) > 0 {
+
+		// Add requested post data to the score
+		urlCount := enrichTrendingScore(&score, postData, fields)
+
+		// Only add posts that have actual content, up to the creator's share of the feed
+		if urlCount > 0 {
+			if !diversity.admit(perCreator, creatorOf(score, postData)) {
+				continue
+			}
+			if lowered {
+				duplicates = append(duplicates, score)
+				continue
+			}
 			enrichedPosts = append(enrichedPosts, score)
-			logger.Infof("✅ Enriched post %s: type=%s, urls=%d", score.PostID, score.ContentType, len(score.OutputURLs))
+			logger.Infof("✅ Enriched post %s: type=%s, urls=%d", score.PostID, score.ContentType, urlCount)
 		}
 	}
-	
-	logger.Debugf("📊 Trending posts for type '%s': %d", contentType, len(enrichedPosts))
-	return enrichedPosts, nil
+	return diversity.interleave(mergeDuplicates(enrichedPosts, duplicates, limit)), nil
+}
+
+// clearsEngagementFloor reports whether a trending candidate has the engagement the feeds
+// require; pinned posts always do
+func (da *DashboardAnalytics) clearsEngagementFloor(score *models.TrendingScore, postData map[string]interface{}) bool {
+	if override, ok := da.firestoreClient.scoring.Override(score.PostID); ok && override.Pinned {
+		return true
+	}
+	if score.CreatorID == "" {
+		score.CreatorID, _ = postData["userId"].(string)
+	}
+	return da.firestoreClient.floor.Allows(*score)
+}
+
+// tiersFor returns the stored creator tiers when a feed is filtered by tier, nil otherwise
+func (da *DashboardAnalytics) tiersFor(creatorTier string) (map[string]string, error) {
+	if creatorTier == "" {
+		return nil, nil
+	}
+	return da.firestoreClient.CreatorTiers()
+}
+
+// inCreatorTier reports whether a post's creator is in the requested tier; every post matches
+// when no tier is requested
+func inCreatorTier(tiers map[string]string, creatorTier string, postData map[string]interface{}) bool {
+	if creatorTier == "" {
+		return true
+	}
+	userID, _ := postData["userId"].(string)
+	return creatorTierOf(tiers, userID) == creatorTier
+}
+
+// enrichTrendingScore copies the requested content fields of a post document onto its score and
+// returns the number of output URLs the post has. The content type is always copied since the
+// trending feeds filter on it.
+func enrichTrendingScore(score *models.TrendingScore, postData map[string]interface{}, fields FieldSet) int {
+	if contentType, ok := postData["contentType"].(string); ok {
+		score.ContentType = contentType
+	}
+
+	outputUrls, _ := postData["outputUrls"].([]interface{})
+	if fields.Has("output_urls") && len(outputUrls) > 0 {
+		urls := make([]string, 0, len(outputUrls))
+		for _, url := range outputUrls {
+			if urlStr, ok := url.(string); ok {
+				urls = append(urls, urlStr)
+			}
+		}
+		score.OutputURLs = urls
+	}
+	if fields.Has("title") {
+		score.Title, _ = postData["title"].(string)
+	}
+	if fields.Has("description") {
+		score.Description, _ = postData["description"].(string)
+	}
+	if fields.Has("instructions") {
+		score.Instructions, _ = postData["instructions"].(string)
+	}
+	applyMediaMetadata(score, postData, fields)
+
+	return len(outputUrls)
 }
 
 // EngagementTrend represents engagement metrics for a specific day
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dimensions views and engagement are broken down by. Countries are the first part of regions.
+const (
+	SegmentPlatform = "platform"
+	SegmentDevice   = "device"
+	SegmentCountry  = "country"
+	SegmentRegion   = "region"
+)
+
+// SegmentDimensions lists the dimensions a segment breakdown can be grouped by
+var SegmentDimensions = []string{SegmentPlatform, SegmentDevice, SegmentCountry, SegmentRegion}
+
+const (
+	// Label of segments whose platform, device or region is not known
+	segmentUnknown = "unknown"
+
+	// Segments kept per hourly bucket; views from further segments are counted under a
+	// segment of other values so client-supplied labels cannot grow the bucket unbounded
+	maxSegmentsPerBucket = 1000
+	segmentOther         = "other"
+
+	maxSegmentLabelLength = 32
+)
+
+// Region codes: an ISO 3166-1 alpha-2 country, optionally with an ISO 3166-2 subdivision
+var regionPattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// dashboardSegmentCounts are the counts of views from one platform, device and region in a
+// bucket. Interactions are counted under the segment their user last viewed from.
+type dashboardSegmentCounts struct {
+	Views        int64
+	WatchSeconds int64
+	ViralViews   int64 // views of posts that were viral when viewed
+	Likes        int64
+	Comments     int64
+	Shares       int64
+}
+
+// add returns the sum of two segment counts
+func (c dashboardSegmentCounts) add(other dashboardSegmentCounts) dashboardSegmentCounts {
+	c.Views += other.Views
+	c.WatchSeconds += other.WatchSeconds
+	c.ViralViews += other.ViralViews
+	c.Likes += other.Likes
+	c.Comments += other.Comments
+	c.Shares += other.Shares
+	return c
+}
+
+// viewSegment is the platform, device and region a view came from
+type viewSegment struct {
+	platform string
+	device   string
+	region   string
+}
+
+// newViewSegment normalizes the platform, device type and region of a view event. Platforms
+// and devices are lowercased; regions that are not valid codes are unknown.
+func newViewSegment(platform, device, region string) viewSegment {
+	segment := viewSegment{
+		platform: segmentLabel(strings.ToLower(platform)),
+		device:   segmentLabel(strings.ToLower(device)),
+		region:   strings.ToUpper(strings.TrimSpace(region)),
+	}
+	if !regionPattern.MatchString(segment.region) {
+		segment.region = segmentUnknown
+	}
+	return segment
+}
+
+// segmentLabel trims a label to its stored form, unknown when empty
+func segmentLabel(label string) string {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return segmentUnknown
+	}
+	if len(label) > maxSegmentLabelLength {
+		label = label[:maxSegmentLabelLength]
+	}
+	return label
+}
+
+// key returns the key a segment is stored under in a bucket
+func (s viewSegment) key() string {
+	return s.platform + "|" + s.device + "|" + s.region
+}
+
+// parseViewSegment reverses key
+func parseViewSegment(key string) viewSegment {
+	parts := strings.SplitN(key, "|", 3)
+	for len(parts) < 3 {
+		parts = append(parts, segmentUnknown)
+	}
+	return viewSegment{platform: parts[0], device: parts[1], region: parts[2]}
+}
+
+// value returns the value of the segment in a dimension
+func (s viewSegment) value(dimension string) string {
+	switch dimension {
+	case SegmentPlatform:
+		return s.platform
+	case SegmentDevice:
+		return s.device
+	case SegmentCountry:
+		country, _, _ := strings.Cut(s.region, "-")
+		return country
+	default:
+		return s.region
+	}
+}
+
+// addSegment adds counts to a segment of the bucket, or to the overflow segment once the
+// bucket holds maxSegmentsPerBucket segments
+func (b *dashboardBucket) addSegment(key string, counts dashboardSegmentCounts) {
+	if _, ok := b.Segments[key]; !ok && len(b.Segments) >= maxSegmentsPerBucket {
+		key = viewSegment{platform: segmentOther, device: segmentOther, region: segmentOther}.key()
+	}
+	b.Segments[key] = b.Segments[key].add(counts)
+}
+
+// SegmentFilter restricts a segment breakdown to views from a platform, device type and
+// region; empty fields match every segment. A country also matches its subdivisions.
+type SegmentFilter struct {
+	Platform   string `json:"platform,omitempty"`
+	DeviceType string `json:"deviceType,omitempty"`
+	Region     string `json:"region,omitempty"`
+}
+
+// normalized returns the filter with its values in the form segments are stored in
+func (f SegmentFilter) normalized() SegmentFilter {
+	if f.Platform != "" {
+		f.Platform = segmentLabel(strings.ToLower(f.Platform))
+	}
+	if f.DeviceType != "" {
+		f.DeviceType = segmentLabel(strings.ToLower(f.DeviceType))
+	}
+	f.Region = strings.ToUpper(strings.TrimSpace(f.Region))
+	return f
+}
+
+// matches reports whether a segment passes the filter
+func (f SegmentFilter) matches(segment viewSegment) bool {
+	if f.Platform != "" && segment.platform != f.Platform {
+		return false
+	}
+	if f.DeviceType != "" && segment.device != f.DeviceType {
+		return false
+	}
+	if f.Region != "" && segment.region != f.Region && !strings.HasPrefix(segment.region, f.Region+"-") {
+		return false
+	}
+	return true
+}
+
+// SegmentMetrics are the views and engagement of one segment. Shares are percentages.
+type SegmentMetrics struct {
+	Segment         string  `json:"segment"`
+	Views           int64   `json:"views"`
+	ViewShare       float64 `json:"viewShare"` // of the views matching the filter
+	WatchSeconds    int64   `json:"watchSeconds"`
+	AvgWatchSeconds float64 `json:"avgWatchSeconds"`
+	ViralViews      int64   `json:"viralViews"`
+	ViralViewShare  float64 `json:"viralViewShare"` // of the segment's views
+	Likes           int64   `json:"likes"`
+	Comments        int64   `json:"comments"`
+	Shares          int64   `json:"shares"`
+	EngagementRate  float64 `json:"engagementRate"`
+}
+
+// SegmentBreakdown is the views and engagement of a time range grouped by one dimension, most
+// viewed segment first
+type SegmentBreakdown struct {
+	GroupBy  string           `json:"groupBy"`
+	Filter   SegmentFilter    `json:"filter"`
+	Total    SegmentMetrics   `json:"total"`
+	Segments []SegmentMetrics `json:"segments"`
+}
+
+// GetSegmentBreakdown returns the views and engagement of a time range by platform, device,
+// country or region, restricted to the segments matching a filter
+func (da *DashboardAnalytics) GetSegmentBreakdown(window *DashboardWindow, groupBy string, filter SegmentFilter) (*SegmentBreakdown, error) {
+	sum, err := da.loadWindow(window)
+	if err != nil {
+		return nil, err
+	}
+	return segmentBreakdown(sum, groupBy, filter), nil
+}
+
+// segmentBreakdown groups the segments of summed buckets by a dimension
+func segmentBreakdown(sum *dashboardBucket, groupBy string, filter SegmentFilter) *SegmentBreakdown {
+	filter = filter.normalized()
+	groups := make(map[string]dashboardSegmentCounts)
+	var total dashboardSegmentCounts
+	for key, counts := range sum.Segments {
+		segment := parseViewSegment(key)
+		if !filter.matches(segment) {
+			continue
+		}
+		value := segment.value(groupBy)
+		groups[value] = groups[value].add(counts)
+		total = total.add(counts)
+	}
+
+	breakdown := &SegmentBreakdown{
+		GroupBy:  groupBy,
+		Filter:   filter,
+		Total:    segmentMetrics("all", total, total.Views),
+		Segments: make([]SegmentMetrics, 0, len(groups)),
+	}
+	for value, counts := range groups {
+		breakdown.Segments = append(breakdown.Segments, segmentMetrics(value, counts, total.Views))
+	}
+	sort.Slice(breakdown.Segments, func(i, j int) bool {
+		if breakdown.Segments[i].Views != breakdown.Segments[j].Views {
+			return breakdown.Segments[i].Views > breakdown.Segments[j].Views
+		}
+		return breakdown.Segments[i].Segment < breakdown.Segments[j].Segment
+	})
+	return breakdown
+}
+
+// segmentMetrics derives the metrics of a segment from its counts and the total views
+func segmentMetrics(segment string, counts dashboardSegmentCounts, totalViews int64) SegmentMetrics {
+	metrics := SegmentMetrics{
+		Segment:      segment,
+		Views:        counts.Views,
+		WatchSeconds: counts.WatchSeconds,
+		ViralViews:   counts.ViralViews,
+		Likes:        counts.Likes,
+		Comments:     counts.Comments,
+		Shares:       counts.Shares,
+	}
+	if totalViews > 0 {
+		metrics.ViewShare = (float64(counts.Views) / float64(totalViews)) * 100
+	}
+	if counts.Views > 0 {
+		metrics.AvgWatchSeconds = float64(counts.WatchSeconds) / float64(counts.Views)
+		metrics.ViralViewShare = (float64(counts.ViralViews) / float64(counts.Views)) * 100
+		metrics.EngagementRate = (float64(counts.Likes+counts.Comments+counts.Shares) / float64(counts.Views)) * 100
+	}
+	return metrics
+}
+
+// ParseSegmentDimension validates the dimension of a segment breakdown, platform by default
+func ParseSegmentDimension(groupBy string) (string, error) {
+	if groupBy == "" {
+		return SegmentPlatform, nil
+	}
+	for _, dimension := range SegmentDimensions {
+		if groupBy == dimension {
+			return dimension, nil
+		}
+	}
+	return "", fmt.Errorf("invalid groupBy %q, expected one of %s", groupBy, strings.Join(SegmentDimensions, ", "))
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewViewSegment(t *testing.T) {
+	tests := []struct {
+		platform, device, region string
+		expected                 string
+	}{
+		{"Mobile", " iOS ", "us-ca", "mobile|ios|US-CA"},
+		{"web", "", "TR", "web|unknown|TR"},
+		{"", "android", "Turkey", "unknown|android|unknown"},
+	}
+	for _, test := range tests {
+		if key := newViewSegment(test.platform, test.device, test.region).key(); key != test.expected {
+			t.Errorf("newViewSegment(%q, %q, %q) = %q, expected %q", test.platform, test.device, test.region, key, test.expected)
+		}
+	}
+}
+
+func TestSegmentBreakdown(t *testing.T) {
+	sum := newDashboardBucket(time.Time{})
+	sum.addSegment("mobile|ios|US-CA", dashboardSegmentCounts{Views: 60, WatchSeconds: 600, ViralViews: 30, Likes: 6})
+	sum.addSegment("mobile|android|US-NY", dashboardSegmentCounts{Views: 20, Likes: 4})
+	sum.addSegment("web|unknown|TR", dashboardSegmentCounts{Views: 20, Shares: 2})
+
+	byCountry := segmentBreakdown(sum, SegmentCountry, SegmentFilter{})
+	if len(byCountry.Segments) != 2 || byCountry.Segments[0].Segment != "US" || byCountry.Segments[0].Views != 80 || byCountry.Segments[0].ViewShare != 80 {
+		t.Errorf("Unexpected country breakdown %+v", byCountry.Segments)
+	}
+	if byCountry.Total.Views != 100 || byCountry.Total.EngagementRate != 12 {
+		t.Errorf("Unexpected total %+v", byCountry.Total)
+	}
+
+	// A country filter matches its subdivisions; filter values are normalized like the segments
+	mobileUS := segmentBreakdown(sum, SegmentDevice, SegmentFilter{Platform: "Mobile", Region: "us"})
+	if len(mobileUS.Segments) != 2 || mobileUS.Total.Views != 80 {
+		t.Fatalf("Unexpected filtered breakdown %+v", mobileUS)
+	}
+	if ios := mobileUS.Segments[0]; ios.Segment != "ios" || ios.AvgWatchSeconds != 10 || ios.ViralViewShare != 50 || ios.ViewShare != 75 {
+		t.Errorf("Unexpected ios segment %+v", ios)
+	}
+	if california := segmentBreakdown(sum, SegmentRegion, SegmentFilter{Region: "US-CA"}); len(california.Segments) != 1 {
+		t.Errorf("Expected a subdivision filter to match only itself, got %+v", california.Segments)
+	}
+}
+
+func TestDashboardBucket_SegmentOverflow(t *testing.T) {
+	bucket := newDashboardBucket(time.Time{})
+	for i := 0; i < maxSegmentsPerBucket+5; i++ {
+		bucket.addSegment(fmt.Sprintf("device-%d|unknown|unknown", i), dashboardSegmentCounts{Views: 1})
+	}
+	if len(bucket.Segments) != maxSegmentsPerBucket+1 || bucket.Segments["other|other|other"].Views != 5 {
+		t.Errorf("Expected 5 views in the overflow segment, got %d segments", len(bucket.Segments))
+	}
+}
+
+func TestParseSegmentDimension(t *testing.T) {
+	if dimension, err := ParseSegmentDimension(""); err != nil || dimension != SegmentPlatform {
+		t.Errorf("Expected platform by default, got %q (%v)", dimension, err)
+	}
+	if _, err := ParseSegmentDimension("city"); err == nil {
+		t.Error("Expected city to be rejected")
+	}
+}
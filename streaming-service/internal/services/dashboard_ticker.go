@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+)
+
+const (
+	// Posts whose latest broadcast score is remembered for the dashboard ticks
+	maxDashboardPosts = 1000
+
+	// Posts without a new score for this long leave the ticks' top posts
+	dashboardScoreTTL = time.Hour
+
+	// Top posts carried by each tick
+	dashboardTopPosts = 5
+)
+
+// Dashboard is the process-wide source of the dashboard_tick aggregates
+var Dashboard = NewDashboardCounters()
+
+// DashboardCounters keeps the live dashboard aggregates in memory: the events ingested and the
+// latest broadcast score of each post
+type DashboardCounters struct {
+	events atomic.Uint64
+
+	mu     sync.Mutex
+	scores map[string]dashboardScore
+}
+
+// dashboardScore is the latest broadcast score of a post
+type dashboardScore struct {
+	score     float64
+	updatedAt time.Time
+}
+
+// DashboardPost is a top post of a dashboard tick
+type DashboardPost struct {
+	PostID string  `json:"post_id"`
+	Score  float64 `json:"score"`
+}
+
+// DashboardTickMessage carries the live dashboard aggregates of this instance
+type DashboardTickMessage struct {
+	Type            string          `json:"type"`
+	EventsPerSecond float64         `json:"events_per_second"`
+	ActiveClients   int             `json:"active_clients"`
+	TopPosts        []DashboardPost `json:"top_posts"`
+	Timestamp       string          `json:"timestamp"`
+}
+
+// NewDashboardCounters creates empty dashboard aggregates
+func NewDashboardCounters() *DashboardCounters {
+	return &DashboardCounters{scores: make(map[string]dashboardScore)}
+}
+
+// RecordEvent counts an ingested event
+func (dc *DashboardCounters) RecordEvent() {
+	dc.events.Add(1)
+}
+
+// Events returns the number of events ingested since the process started
+func (dc *DashboardCounters) Events() uint64 {
+	return dc.events.Load()
+}
+
+// RecordScore remembers the latest score of a post. Beyond maxDashboardPosts, the post with
+// the lowest score is forgotten.
+func (dc *DashboardCounters) RecordScore(postID string, score float64, now time.Time) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if _, ok := dc.scores[postID]; !ok && len(dc.scores) >= maxDashboardPosts {
+		lowestID, lowest := "", 0.0
+		for id, entry := range dc.scores {
+			if lowestID == "" || entry.score < lowest {
+				lowestID, lowest = id, entry.score
+			}
+		}
+		if score <= lowest {
+			return
+		}
+		delete(dc.scores, lowestID)
+	}
+	dc.scores[postID] = dashboardScore{score: score, updatedAt: now}
+}
+
+// Top returns the n posts with the highest recent scores, highest first
+func (dc *DashboardCounters) Top(n int, now time.Time) []DashboardPost {
+	dc.mu.Lock()
+	posts := make([]DashboardPost, 0, len(dc.scores))
+	for postID, entry := range dc.scores {
+		if now.Sub(entry.updatedAt) > dashboardScoreTTL {
+			delete(dc.scores, postID)
+			continue
+		}
+		posts = append(posts, DashboardPost{PostID: postID, Score: entry.score})
+	}
+	dc.mu.Unlock()
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].Score != posts[j].Score {
+			return posts[i].Score > posts[j].Score
+		}
+		return posts[i].PostID < posts[j].PostID
+	})
+	if len(posts) > n {
+		posts = posts[:n]
+	}
+	return posts
+}
+
+// DashboardTicker pushes a dashboard_tick message with the live aggregates to this instance's
+// WebSocket and stream clients every interval. Ticks are not relayed to other instances: each
+// reports its own ingest rate and clients.
+type DashboardTicker struct {
+	hub      *WebSocketHub
+	counters *DashboardCounters
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	// Event count and time of the previous tick, only touched by the tick loop
+	lastEvents uint64
+	lastTick   time.Time
+}
+
+// NewDashboardTicker creates a ticker of the given counters
+func NewDashboardTicker(hub *WebSocketHub, counters *DashboardCounters, interval time.Duration) *DashboardTicker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &DashboardTicker{
+		hub:      hub,
+		counters: counters,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start begins sending ticks
+func (dt *DashboardTicker) Start() {
+	logger.Infof("📊 Sending dashboard ticks every %v", dt.interval)
+
+	dt.lastEvents, dt.lastTick = dt.counters.Events(), time.Now()
+	ticker := time.NewTicker(dt.interval)
+	go func() {
+		for {
+			select {
+			case <-dt.ctx.Done():
+				ticker.Stop()
+				return
+			case now := <-ticker.C:
+				dt.tick(now)
+			}
+		}
+	}()
+}
+
+// Stop stops sending ticks
+func (dt *DashboardTicker) Stop() {
+	dt.cancel()
+}
+
+// tick measures the ingest rate since the previous tick and broadcasts the aggregates when
+// clients are connected
+func (dt *DashboardTicker) tick(now time.Time) {
+	events := dt.counters.Events()
+	message := DashboardTickMessage{
+		Type:          "dashboard_tick",
+		ActiveClients: dt.hub.GetClientCount(),
+		TopPosts:      dt.counters.Top(dashboardTopPosts, now),
+		Timestamp:     now.UTC().Format(time.RFC3339),
+	}
+	if elapsed := now.Sub(dt.lastTick).Seconds(); elapsed > 0 {
+		message.EventsPerSecond = float64(events-dt.lastEvents) / elapsed
+	}
+	dt.lastEvents, dt.lastTick = events, now
+
+	if message.ActiveClients > 0 {
+		dt.hub.BroadcastDashboardTick(message)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardCounters_Top(t *testing.T) {
+	counters := NewDashboardCounters()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	counters.RecordScore("stale", 500, now.Add(-2*time.Hour))
+	for i := 1; i <= 7; i++ {
+		counters.RecordScore(fmt.Sprintf("post-%d", i), float64(i*10), now)
+	}
+	// A newer score replaces the previous one
+	counters.RecordScore("post-1", 100, now)
+
+	top := counters.Top(dashboardTopPosts, now)
+	if len(top) != dashboardTopPosts {
+		t.Fatalf("Expected %d top posts, got %v", dashboardTopPosts, top)
+	}
+	if top[0].PostID != "post-1" || top[0].Score != 100 || top[1].PostID != "post-7" {
+		t.Errorf("Unexpected top posts %v", top)
+	}
+	for _, post := range top {
+		if post.PostID == "stale" {
+			t.Error("Expected posts without recent scores to leave the top posts")
+		}
+	}
+}
+
+func TestDashboardCounters_BoundedPosts(t *testing.T) {
+	counters := NewDashboardCounters()
+	now := time.Now()
+	for i := 0; i < maxDashboardPosts; i++ {
+		counters.RecordScore(fmt.Sprintf("post-%d", i), float64(i+1), now)
+	}
+
+	counters.RecordScore("low", 0.5, now)
+	counters.RecordScore("high", 5000, now)
+	if len(counters.scores) != maxDashboardPosts {
+		t.Errorf("Expected at most %d posts, got %d", maxDashboardPosts, len(counters.scores))
+	}
+	if _, ok := counters.scores["low"]; ok {
+		t.Error("Expected a score below every remembered one to be ignored")
+	}
+	if _, ok := counters.scores["post-0"]; ok {
+		t.Error("Expected the lowest score to make room for a higher one")
+	}
+}
+
+func TestDashboardTicker_Tick(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+	client := NewStreamClient(hub, "", "sse")
+	hub.RegisterClient(client)
+	time.Sleep(50 * time.Millisecond)
+
+	counters := NewDashboardCounters()
+	counters.RecordScore("post-1", 42, time.Now())
+	ticker := NewDashboardTicker(hub, counters, time.Second)
+	start := time.Now()
+	ticker.lastTick = start
+	for i := 0; i < 20; i++ {
+		counters.RecordEvent()
+	}
+	ticker.tick(start.Add(2 * time.Second))
+
+	messages, ok := client.Next(context.Background(), time.Second)
+	if !ok || len(messages) != 1 {
+		t.Fatalf("Expected a dashboard tick, got %d messages (%v)", len(messages), ok)
+	}
+	if strings.Contains(string(messages[0]), `"seq"`) {
+		t.Errorf("Expected ticks to carry no seq, got %s", messages[0])
+	}
+	var tick DashboardTickMessage
+	if err := json.Unmarshal(messages[0], &tick); err != nil {
+		t.Fatalf("Invalid tick %s: %v", messages[0], err)
+	}
+	if tick.Type != "dashboard_tick" || tick.EventsPerSecond != 10 || tick.ActiveClients != 1 || len(tick.TopPosts) != 1 {
+		t.Errorf("Unexpected tick %+v", tick)
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DashboardWindow is the time range dashboard metrics are computed over. Metrics are summed
+// from hourly buckets, so the range starts at the beginning of the hour of From.
+type DashboardWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// ParseDashboardWindow parses the time range of a dashboard request: either a window ending
+// now, such as 24h, 7d or 30d, or from and to given as RFC 3339 times or YYYY-MM-DD dates in
+// loc, to defaulting to now. Ranges may span at most MaxDashboardWindow and must start within
+// the bucket retention. Without any of them, it returns nil for all-time metrics.
+func ParseDashboardWindow(window, from, to string, now time.Time, loc *time.Location) (*DashboardWindow, error) {
+	if window == "" && from == "" && to == "" {
+		return nil, nil
+	}
+	if window != "" && (from != "" || to != "") {
+		return nil, errors.New("use either window or from and to")
+	}
+
+	var parsed DashboardWindow
+	if window != "" {
+		length, err := parseWindowDuration(window)
+		if err != nil {
+			return nil, err
+		}
+		parsed = DashboardWindow{From: now.Add(-length), To: now}
+	} else {
+		if from == "" {
+			return nil, errors.New("from is required with to")
+		}
+		var err error
+		if parsed.From, err = parseDashboardTime(from, loc); err != nil {
+			return nil, err
+		}
+		parsed.To = now
+		if to != "" {
+			if parsed.To, err = parseDashboardTime(to, loc); err != nil {
+				return nil, err
+			}
+			if parsed.To.After(now) {
+				parsed.To = now
+			}
+		}
+	}
+
+	if !parsed.From.Before(parsed.To) {
+		return nil, errors.New("from must be before to")
+	}
+	if parsed.To.Sub(parsed.From) > MaxDashboardWindow {
+		return nil, fmt.Errorf("time range must not exceed %dd", int(MaxDashboardWindow.Hours()/24))
+	}
+	if parsed.From.Before(now.Add(-dashboardBucketRetention)) {
+		return nil, fmt.Errorf("from must be within the last %dd", int(dashboardBucketRetention.Hours()/24))
+	}
+	return &parsed, nil
+}
+
+// parseDashboardTime parses an RFC 3339 time or a YYYY-MM-DD date, which starts the day in loc
+func parseDashboardTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", value)
+}
+
+// loadWindow returns the sum of the hourly buckets of a window
+func (da *DashboardAnalytics) loadWindow(window *DashboardWindow) (*dashboardBucket, error) {
+	buckets, err := da.firestoreClient.LoadDashboardBuckets(window.From, window.To)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := newDashboardBucket(window.From.UTC().Truncate(time.Hour))
+	for i := range buckets {
+		sum.add(&buckets[i])
+	}
+	return sum, nil
+}
+
+// applyWindow replaces the all-time counts of dashboard metrics with those of a window. The
+// average score and top posts describe the current scores and are kept.
+func applyWindow(metrics *DashboardMetrics, sum *dashboardBucket) {
+	metrics.TotalViews = sum.Views
+	metrics.TotalInteractions = sum.Likes + sum.Comments + sum.Shares
+	metrics.InteractionCounts = map[string]int64{
+		"views":    sum.Views,
+		"likes":    sum.Likes,
+		"comments": sum.Comments,
+		"shares":   sum.Shares,
+		"remixes":  sum.Remixes,
+	}
+	metrics.ViralPosts = int(sum.ViralPosts)
+	metrics.TotalPosts = int(sum.Posts)
+
+	// Active users are the creators who posted in the window
+	metrics.ActiveUsers = 0
+	for _, creator := range sum.Creators {
+		if creator.Posts > 0 {
+			metrics.ActiveUsers++
+		}
+	}
+	metrics.TopContentTypes = make(map[string]int, len(sum.ContentTypes))
+	for contentType, counts := range sum.ContentTypes {
+		if counts.Posts > 0 {
+			metrics.TopContentTypes[contentType] = int(counts.Posts)
+		}
+	}
+
+	metrics.EngagementRate = 0
+	if metrics.TotalViews > 0 {
+		metrics.EngagementRate = (float64(metrics.TotalInteractions) / float64(metrics.TotalViews)) * 100
+	}
+}
+
+// windowCreators returns the creators active in a window, ranked by the trending score their
+// posts gained in it, without their user details. A non-empty tier keeps only creators in
+// that tier.
+func windowCreators(sum *dashboardBucket, tiers map[string]string, tier string) []CreatorMetrics {
+	now := time.Now()
+	creators := make([]CreatorMetrics, 0, len(sum.Creators))
+	for userID, counts := range sum.Creators {
+		creator := CreatorMetrics{
+			UserID:         userID,
+			TotalScore:     counts.Score,
+			TotalViews:     counts.Views,
+			TotalLikes:     counts.Likes,
+			TotalComments:  counts.Comments,
+			PostCount:      int(counts.Posts),
+			ViralPostCount: int(counts.ViralPosts),
+			Tier:           creatorTierOf(tiers, userID),
+			CalculatedAt:   now,
+		}
+		if tier != "" && creator.Tier != tier {
+			continue
+		}
+		if creator.PostCount > 0 {
+			creator.AverageScore = creator.TotalScore / float64(creator.PostCount)
+		}
+		if creator.TotalViews > 0 {
+			creator.EngagementRate = (float64(creator.TotalLikes+creator.TotalComments) / float64(creator.TotalViews)) * 100
+		}
+		creators = append(creators, creator)
+	}
+
+	sort.Slice(creators, func(i, j int) bool {
+		if creators[i].TotalScore != creators[j].TotalScore {
+			return creators[i].TotalScore > creators[j].TotalScore
+		}
+		return creators[i].UserID < creators[j].UserID
+	})
+	return creators
+}
+
+// windowContentTypes returns the content type breakdown of a window: the posts created in it
+// and the views and likes their content type received in it
+func windowContentTypes(sum *dashboardBucket) map[string]ContentTypeMetrics {
+	breakdown := make(map[string]ContentTypeMetrics, len(sum.ContentTypes))
+	for contentType, counts := range sum.ContentTypes {
+		metrics := ContentTypeMetrics{
+			ContentType: contentType,
+			Count:       int(counts.Posts),
+			TotalViews:  counts.Views,
+			TotalLikes:  counts.Likes,
+		}
+		if metrics.Count > 0 {
+			metrics.AvgViews = float64(metrics.TotalViews) / float64(metrics.Count)
+			metrics.AvgLikes = float64(metrics.TotalLikes) / float64(metrics.Count)
+		}
+		breakdown[contentType] = metrics
+	}
+	return breakdown
+}
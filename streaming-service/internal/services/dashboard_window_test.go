@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDashboardWindow(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+
+	if window, err := ParseDashboardWindow("", "", "", now, time.UTC); window != nil || err != nil {
+		t.Errorf("Expected all-time metrics without parameters, got %v (%v)", window, err)
+	}
+
+	window, err := ParseDashboardWindow("7d", "", "", now, time.UTC)
+	if err != nil || !window.From.Equal(now.AddDate(0, 0, -7)) || !window.To.Equal(now) {
+		t.Errorf("Unexpected 7d window %+v (%v)", window, err)
+	}
+
+	// Dates start the day in the given time zone; to defaults to now and never passes it
+	window, err = ParseDashboardWindow("", "2024-05-08", "", now, istanbul)
+	if err != nil || !window.From.Equal(time.Date(2024, 5, 7, 21, 0, 0, 0, time.UTC)) || !window.To.Equal(now) {
+		t.Errorf("Unexpected from window %+v (%v)", window, err)
+	}
+	window, err = ParseDashboardWindow("", "2024-05-09T00:00:00Z", "2024-06-01", now, time.UTC)
+	if err != nil || !window.To.Equal(now) {
+		t.Errorf("Expected to to be clamped to now, got %+v (%v)", window, err)
+	}
+
+	for _, invalid := range [][3]string{
+		{"7d", "2024-05-01", ""},
+		{"", "", "2024-05-01"},
+		{"soon", "", ""},
+		{"", "yesterday", ""},
+		{"", "2024-05-09", "2024-05-08"},
+		{"45d", "", ""},
+		{"", "2024-01-01", "2024-01-02"},
+	} {
+		if _, err := ParseDashboardWindow(invalid[0], invalid[1], invalid[2], now, time.UTC); err == nil {
+			t.Errorf("Expected window=%q from=%q to=%q to be rejected", invalid[0], invalid[1], invalid[2])
+		}
+	}
+}
+
+func TestDashboardWindowAggregates(t *testing.T) {
+	day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	first := newDashboardBucket(day.Add(9 * time.Hour))
+	first.Views, first.Likes, first.Comments, first.Posts = 100, 10, 5, 2
+	first.ContentTypes["video"] = dashboardContentCounts{Posts: 2, Views: 80, Likes: 8}
+	first.Creators["alice"] = dashboardCreatorCounts{Posts: 2, Views: 80, Likes: 8, Score: 30}
+	first.Creators["bob"] = dashboardCreatorCounts{Views: 20, Likes: 2, Score: 50}
+	second := newDashboardBucket(day.Add(33 * time.Hour))
+	second.Views, second.Likes, second.ViralPosts, second.Posts = 50, 5, 1, 1
+	second.ContentTypes["video"] = dashboardContentCounts{Views: 50, Likes: 5}
+	second.Creators["alice"] = dashboardCreatorCounts{Views: 50, Likes: 5, Score: 40, ViralPosts: 1}
+	second.Creators["carol"] = dashboardCreatorCounts{Posts: 1}
+
+	sum := newDashboardBucket(day)
+	sum.add(first)
+	sum.add(second)
+
+	metrics := &DashboardMetrics{}
+	applyWindow(metrics, sum)
+	if metrics.TotalViews != 150 || metrics.TotalInteractions != 20 || metrics.TotalPosts != 3 || metrics.ViralPosts != 1 {
+		t.Errorf("Unexpected window metrics %+v", metrics)
+	}
+	if metrics.ActiveUsers != 2 || metrics.TopContentTypes["video"] != 2 {
+		t.Errorf("Expected two posting creators and two videos, got %d and %v", metrics.ActiveUsers, metrics.TopContentTypes)
+	}
+
+	creators := windowCreators(sum, map[string]string{"bob": CreatorTierStar}, "")
+	if len(creators) != 3 || creators[0].UserID != "alice" || creators[0].TotalScore != 70 || creators[0].TotalViews != 130 || creators[1].UserID != "bob" {
+		t.Errorf("Unexpected window creators %+v", creators)
+	}
+	if creators[1].Tier != CreatorTierStar {
+		t.Errorf("Expected bob's tier to be kept, got %q", creators[1].Tier)
+	}
+	if stars := windowCreators(sum, map[string]string{"bob": CreatorTierStar}, CreatorTierStar); len(stars) != 1 {
+		t.Errorf("Expected the tier filter to keep only bob, got %+v", stars)
+	}
+
+	if video := windowContentTypes(sum)["video"]; video.Count != 2 || video.TotalViews != 130 || video.AvgViews != 65 {
+		t.Errorf("Unexpected video breakdown %+v", video)
+	}
+
+}
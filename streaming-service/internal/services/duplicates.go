@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+// DuplicateDetector compares the embeddings of new posts with those of recent posts and marks
+// reposted or lightly altered content as a duplicate of the original it copies
+type DuplicateDetector struct {
+	firestoreClient *FirestoreClient
+	threshold       float64
+	window          time.Duration
+}
+
+func NewDuplicateDetector(firestoreClient *FirestoreClient, threshold float64, window time.Duration) *DuplicateDetector {
+	return &DuplicateDetector{
+		firestoreClient: firestoreClient,
+		threshold:       threshold,
+		window:          window,
+	}
+}
+
+// Check compares a freshly stored embedding with the posts embedded during the window before it
+// and records the post as a duplicate when one is similar enough. It returns the match, or nil
+// when the post is original.
+func (dd *DuplicateDetector) Check(embedding models.PostEmbedding) (*models.DuplicateMatch, error) {
+	if dd == nil {
+		return nil, nil
+	}
+
+	candidates, err := dd.firestoreClient.GetRecentPostEmbeddings(embedding.UpdatedAt.Add(-dd.window), maxSimilarityCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recent embeddings: %w", err)
+	}
+
+	match := findDuplicate(embedding, candidates, dd.threshold)
+	if match == nil {
+		return nil, nil
+	}
+	match.DetectedAt = time.Now()
+
+	if err := dd.firestoreClient.SaveDuplicateMatch(*match); err != nil {
+		return nil, fmt.Errorf("failed to mark post %s as duplicate: %w", match.PostID, err)
+	}
+
+	logger.Infof("♻️ Post %s is a near-duplicate of %s (similarity %.3f to %s)", match.PostID, match.OriginalID, match.Similarity, match.MatchedPostID)
+	return match, nil
+}
+
+// findDuplicate returns the closest earlier post of the same content type whose similarity
+// reaches the threshold, linked to the original that post copies, or nil if there is none
+func findDuplicate(target models.PostEmbedding, candidates []models.PostEmbedding, threshold float64) *models.DuplicateMatch {
+	var best *models.PostEmbedding
+	bestSimilarity := threshold
+	for i, candidate := range candidates {
+		if candidate.PostID == target.PostID || candidate.ContentType != target.ContentType ||
+			len(candidate.Vector) != len(target.Vector) || !candidate.UpdatedAt.Before(target.UpdatedAt) {
+			continue
+		}
+		if similarity := cosineSimilarity(target.Vector, candidate.Vector); similarity >= bestSimilarity {
+			best = &candidates[i]
+			bestSimilarity = similarity
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	originalID := best.PostID
+	if best.DuplicateOf != "" {
+		originalID = best.DuplicateOf
+	}
+	return &models.DuplicateMatch{
+		PostID:        target.PostID,
+		OriginalID:    originalID,
+		MatchedPostID: best.PostID,
+		Similarity:    bestSimilarity,
+	}
+}
+
+// GetRecentPostEmbeddings returns up to limit embeddings stored since the given time, newest first
+func (fc *FirestoreClient) GetRecentPostEmbeddings(since time.Time, limit int) ([]models.PostEmbedding, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	iter := fc.client.Collection("post_embeddings").
+		Where("UpdatedAt", ">=", since).
+		OrderBy("UpdatedAt", firestore.Desc).
+		Limit(limit).
+		Documents(fc.ctx)
+
+	var embeddings []models.PostEmbedding
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var embedding models.PostEmbedding
+		if err := doc.DataTo(&embedding); err != nil {
+			continue
+		}
+		embeddings = append(embeddings, embedding)
+	}
+
+	return embeddings, nil
+}
+
+// SaveDuplicateMatch links a duplicate post and its embedding to the original and counts the
+// duplicate on the original post
+func (fc *FirestoreClient) SaveDuplicateMatch(match models.DuplicateMatch) error {
+	Quotas.Record(QuotaFirestore, 3)
+	batch := fc.client.Batch()
+	batch.Set(fc.client.Collection("posts").Doc(match.PostID), map[string]interface{}{
+		"duplicate": map[string]interface{}{
+			"of":          match.OriginalID,
+			"matched":     match.MatchedPostID,
+			"similarity":  match.Similarity,
+			"detected_at": match.DetectedAt,
+		},
+	}, firestore.MergeAll)
+	batch.Set(fc.client.Collection("post_embeddings").Doc(match.PostID), map[string]interface{}{
+		"DuplicateOf": match.OriginalID,
+	}, firestore.MergeAll)
+	batch.Set(fc.client.Collection("posts").Doc(match.OriginalID), map[string]interface{}{
+		"duplicate_count": firestore.Increment(1),
+	}, firestore.MergeAll)
+	_, err := batch.Commit(fc.ctx)
+	return err
+}
+
+// duplicateOf returns the original a post document is marked as duplicating, or ""
+func duplicateOf(postData map[string]interface{}) string {
+	duplicate, ok := postData["duplicate"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	original, _ := duplicate["of"].(string)
+	return original
+}
+
+// weighDuplicate links a near-duplicate's score to its original and applies the duplicate
+// weight to it. It reports whether the post stays in the feed and whether its score was
+// lowered, in which case it has to be ranked again.
+func weighDuplicate(score *models.TrendingScore, postData map[string]interface{}, weight float64) (keep, lowered bool) {
+	original := duplicateOf(postData)
+	if original == "" {
+		return true, false
+	}
+	score.DuplicateOf = original
+	if weight >= 1 {
+		return true, false
+	}
+	if weight <= 0 {
+		return false, false
+	}
+	score.Score *= weight
+	return true, true
+}
+
+// mergeDuplicates ranks down-weighted duplicates among the posts of a feed by score and keeps
+// the top limit
+func mergeDuplicates(posts, duplicates []models.TrendingScore, limit int) []models.TrendingScore {
+	if len(duplicates) == 0 {
+		return posts
+	}
+	return rankFallbackPosts(append(posts, duplicates...), limit)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestFindDuplicate(t *testing.T) {
+	now := time.Now()
+	target := models.PostEmbedding{PostID: "post-new", ContentType: "image", Vector: []float64{1, 0.2, 0}, UpdatedAt: now}
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		candidates []models.PostEmbedding
+		original   string // "" when the post must not be flagged
+		matched    string
+	}{
+		{
+			name:       "exact duplicate",
+			candidates: []models.PostEmbedding{{PostID: "post-1", ContentType: "image", Vector: []float64{1, 0.2, 0}, UpdatedAt: earlier}},
+			original:   "post-1",
+			matched:    "post-1",
+		},
+		{
+			name:       "near duplicate",
+			candidates: []models.PostEmbedding{{PostID: "post-1", ContentType: "image", Vector: []float64{1, 0.25, 0.02}, UpdatedAt: earlier}},
+			original:   "post-1",
+			matched:    "post-1",
+		},
+		{
+			name:       "copy of a duplicate links to the original",
+			candidates: []models.PostEmbedding{{PostID: "post-2", ContentType: "image", Vector: []float64{1, 0.2, 0}, UpdatedAt: earlier, DuplicateOf: "post-1"}},
+			original:   "post-1",
+			matched:    "post-2",
+		},
+		{
+			name: "closest of several",
+			candidates: []models.PostEmbedding{
+				{PostID: "post-1", ContentType: "image", Vector: []float64{1, 0.3, 0.05}, UpdatedAt: earlier},
+				{PostID: "post-2", ContentType: "image", Vector: []float64{1, 0.2, 0.01}, UpdatedAt: earlier},
+			},
+			original: "post-2",
+			matched:  "post-2",
+		},
+		{
+			name:       "distinct post",
+			candidates: []models.PostEmbedding{{PostID: "post-1", ContentType: "image", Vector: []float64{0, 0.1, 1}, UpdatedAt: earlier}},
+		},
+		{
+			name:       "similar post of another content type",
+			candidates: []models.PostEmbedding{{PostID: "post-1", ContentType: "video", Vector: []float64{1, 0.2, 0}, UpdatedAt: earlier}},
+		},
+		{
+			name:       "similar post embedded later",
+			candidates: []models.PostEmbedding{{PostID: "post-1", ContentType: "image", Vector: []float64{1, 0.2, 0}, UpdatedAt: now.Add(time.Minute)}},
+		},
+		{
+			name:       "embedding of another model size",
+			candidates: []models.PostEmbedding{{PostID: "post-1", ContentType: "image", Vector: []float64{1, 0.2}, UpdatedAt: earlier}},
+		},
+		{
+			name:       "the post itself",
+			candidates: []models.PostEmbedding{{PostID: "post-new", ContentType: "image", Vector: []float64{1, 0.2, 0}, UpdatedAt: earlier}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match := findDuplicate(target, test.candidates, 0.98)
+			if test.original == "" {
+				if match != nil {
+					t.Fatalf("Expected no duplicate, got %+v", match)
+				}
+				return
+			}
+			if match == nil {
+				t.Fatal("Expected a duplicate")
+			}
+			if match.PostID != target.PostID || match.OriginalID != test.original || match.MatchedPostID != test.matched {
+				t.Errorf("Expected a duplicate of %s matched on %s, got %+v", test.original, test.matched, match)
+			}
+			if match.Similarity < 0.98 {
+				t.Errorf("Expected a similarity of at least the threshold, got %v", match.Similarity)
+			}
+		})
+	}
+}
+
+func TestWeighDuplicate(t *testing.T) {
+	duplicate := map[string]interface{}{"duplicate": map[string]interface{}{"of": "original"}}
+
+	score := models.TrendingScore{PostID: "repost", Score: 80}
+	if keep, lowered := weighDuplicate(&score, duplicate, 0.5); !keep || !lowered || score.Score != 40 || score.DuplicateOf != "original" {
+		t.Errorf("expected a halved, linked score, got %+v (keep %v, lowered %v)", score, keep, lowered)
+	}
+	if keep, _ := weighDuplicate(&models.TrendingScore{Score: 80}, duplicate, 0); keep {
+		t.Error("expected a zero weight to hide duplicates")
+	}
+	score = models.TrendingScore{Score: 80}
+	if keep, lowered := weighDuplicate(&score, map[string]interface{}{}, 0.5); !keep || lowered || score.Score != 80 {
+		t.Errorf("expected original posts to keep their score, got %+v", score)
+	}
+
+	posts := []models.TrendingScore{{PostID: "a", Score: 90}, {PostID: "b", Score: 30}}
+	merged := mergeDuplicates(posts, []models.TrendingScore{{PostID: "repost", Score: 40}}, 2)
+	if len(merged) != 2 || merged[1].PostID != "repost" {
+		t.Errorf("expected the duplicate to rank between the posts, got %+v", merged)
+	}
+}
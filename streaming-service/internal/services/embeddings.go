@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Number of stored embeddings scanned per duplicate check
+const maxSimilarityCandidates = 1000
+
+// Stored embeddings read per page of a similarity search
+const similarityPageSize = 500
+
+// ErrEmbeddingPending is returned for a post that exists but has no embedding of the configured
+// model yet
+var ErrEmbeddingPending = errors.New("post embedding not computed yet")
+
+// EmbeddingService embeds post prompts and keywords with a Vertex AI text-embedding model
+// and answers "more like this" queries over the stored vectors
+type EmbeddingService struct {
+	client    *aiplatform.PredictionClient
+	firestore *FirestoreClient
+	config    *config.Config
+	ctx       context.Context
+
+	// Marks new posts that near-duplicate recent ones, nil when detection is disabled
+	duplicates *DuplicateDetector
+}
+
+func NewEmbeddingService(ctx context.Context, cfg *config.Config, firestore *FirestoreClient) (*EmbeddingService, error) {
+	client, err := aiplatform.NewPredictionClient(ctx, option.WithEndpoint(fmt.Sprintf("%s-aiplatform.googleapis.com:443", cfg.VertexAILocation)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding client: %w", err)
+	}
+
+	var duplicates *DuplicateDetector
+	if cfg.DuplicateSimilarityThreshold > 0 {
+		duplicates = NewDuplicateDetector(firestore, cfg.DuplicateSimilarityThreshold, time.Duration(cfg.DuplicateWindowDays)*24*time.Hour)
+	}
+
+	return &EmbeddingService{
+		client:     client,
+		firestore:  firestore,
+		config:     cfg,
+		ctx:        ctx,
+		duplicates: duplicates,
+	}, nil
+}
+
+// Embed returns the embedding vector of a text
+func (e *EmbeddingService) Embed(text string) ([]float64, error) {
+	instance, err := structpb.NewStruct(map[string]interface{}{
+		"content":   text,
+		"task_type": "SEMANTIC_SIMILARITY",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode embedding instance: %w", err)
+	}
+
+	Quotas.Record(QuotaVertexAI, 1)
+	resp, err := e.client.Predict(e.ctx, &aiplatformpb.PredictRequest{
+		Endpoint: fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s",
+			e.config.GoogleCloudProject, e.config.VertexAILocation, e.config.EmbeddingModel),
+		Instances: []*structpb.Value{structpb.NewStructValue(instance)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	if len(resp.Predictions) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+
+	return parseEmbedding(resp.Predictions[0])
+}
+
+// EmbedPost embeds a post's prompt and keywords and stores the vector in post_embeddings
+func (e *EmbeddingService) EmbedPost(postID, contentType, prompt string, keywords []string) (*models.PostEmbedding, error) {
+	text := embeddingText(prompt, keywords)
+	if text == "" {
+		return nil, fmt.Errorf("post %s has no text to embed", postID)
+	}
+
+	vector, err := e.Embed(text)
+	if err != nil {
+		return nil, err
+	}
+
+	embedding := models.PostEmbedding{
+		PostID:      postID,
+		ContentType: contentType,
+		Model:       e.config.EmbeddingModel,
+		Vector:      vector,
+		UpdatedAt:   time.Now(),
+	}
+	if err := e.firestore.SavePostEmbedding(embedding); err != nil {
+		return nil, fmt.Errorf("failed to save embedding for post %s: %w", postID, err)
+	}
+
+	logger.Debugf(" Stored %d-dim embedding for post %s", len(vector), postID)
+	return &embedding, nil
+}
+
+// FindSimilar returns the posts whose embeddings are closest to the given post's, searching
+// every stored embedding of the configured model. It returns nil for posts that do not exist
+// and ErrEmbeddingPending for posts not embedded yet: embeddings are computed on ingestion and
+// by the post indexer, never on read.
+func (e *EmbeddingService) FindSimilar(postID string, limit int) ([]models.SimilarPost, error) {
+	target, err := e.firestore.GetPostEmbedding(postID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil || target.Model != e.config.EmbeddingModel {
+		post, err := e.firestore.GetPostDocument(postID)
+		if err != nil {
+			return nil, err
+		}
+		if post == nil {
+			return nil, nil
+		}
+		return nil, ErrEmbeddingPending
+	}
+
+	similar := []models.SimilarPost{}
+	err = e.firestore.ScanPostEmbeddings(e.config.EmbeddingModel, func(page []models.PostEmbedding) {
+		similar = topSimilar(append(similar, rankSimilar(*target, page, limit)...), limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return similar, nil
+}
+
+// EmbedPostDocument embeds a post from the fields of its Firestore document
+func (e *EmbeddingService) EmbedPostDocument(postID string, data map[string]interface{}) (*models.PostEmbedding, error) {
+	contentType, _ := data["contentType"].(string)
+
+	var promptParts []string
+	for _, field := range []string{"title", "description", "instructions"} {
+		if value, ok := data[field].(string); ok && value != "" {
+			promptParts = append(promptParts, value)
+		}
+	}
+
+	var keywords []string
+	if values, ok := data["keywords"].([]interface{}); ok {
+		for _, v := range values {
+			if keyword, ok := v.(string); ok {
+				keywords = append(keywords, keyword)
+			}
+		}
+	}
+
+	return e.EmbedPost(postID, contentType, strings.Join(promptParts, "\n"), keywords)
+}
+
+// Embedded reports whether a post has an embedding of the configured model
+func (e *EmbeddingService) Embedded(postID string) (bool, error) {
+	embedding, err := e.firestore.GetPostEmbedding(postID)
+	if err != nil {
+		return false, err
+	}
+	return embedding != nil && embedding.Model == e.config.EmbeddingModel, nil
+}
+
+// Close closes the embedding client
+func (e *EmbeddingService) Close() error {
+	return e.client.Close()
+}
+
+// SavePostEmbedding stores a post embedding
+func (fc *FirestoreClient) SavePostEmbedding(embedding models.PostEmbedding) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("post_embeddings").Doc(embedding.PostID).Set(fc.ctx, embedding)
+	return err
+}
+
+// GetPostEmbedding returns the stored embedding of a post, or nil if it has none
+func (fc *FirestoreClient) GetPostEmbedding(postID string) (*models.PostEmbedding, error) {
+	doc, err := fc.client.Collection("post_embeddings").Doc(postID).Get(fc.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var embedding models.PostEmbedding
+	if err := doc.DataTo(&embedding); err != nil {
+		return nil, err
+	}
+	return &embedding, nil
+}
+
+// ScanPostEmbeddings passes every stored embedding of a model to visit, a page at a time
+func (fc *FirestoreClient) ScanPostEmbeddings(model string, visit func(page []models.PostEmbedding)) error {
+	query := fc.client.Collection("post_embeddings").
+		Where("Model", "==", model).
+		OrderBy(firestore.DocumentID, firestore.Asc).
+		Limit(similarityPageSize)
+
+	var last *firestore.DocumentSnapshot
+	for {
+		pageQuery := query
+		if last != nil {
+			pageQuery = query.StartAfter(last)
+		}
+
+		docs, err := pageQuery.Documents(fc.ctx).GetAll()
+		if err != nil {
+			return err
+		}
+		Quotas.Record(QuotaFirestore, int64(len(docs)))
+
+		page := make([]models.PostEmbedding, 0, len(docs))
+		for _, doc := range docs {
+			var embedding models.PostEmbedding
+			if err := doc.DataTo(&embedding); err != nil {
+				continue
+			}
+			page = append(page, embedding)
+		}
+		visit(page)
+
+		if len(docs) < similarityPageSize {
+			return nil
+		}
+		last = docs[len(docs)-1]
+	}
+}
+
+// embeddingText combines a prompt and its keywords into the text that gets embedded
+func embeddingText(prompt string, keywords []string) string {
+	prompt = strings.TrimSpace(prompt)
+	if len(keywords) == 0 {
+		return prompt
+	}
+	return strings.TrimSpace(prompt + "\nKeywords: " + strings.Join(keywords, ", "))
+}
+
+// parseEmbedding extracts the vector from a text-embedding model prediction
+// ({"embeddings": {"values": [...]}})
+func parseEmbedding(prediction *structpb.Value) ([]float64, error) {
+	embeddings := prediction.GetStructValue().GetFields()["embeddings"]
+	values := embeddings.GetStructValue().GetFields()["values"].GetListValue().GetValues()
+	if len(values) == 0 {
+		return nil, fmt.Errorf("embedding prediction has no values")
+	}
+
+	vector := make([]float64, len(values))
+	for i, v := range values {
+		vector[i] = v.GetNumberValue()
+	}
+	return vector, nil
+}
+
+// rankSimilar orders candidates by cosine similarity to the target, excluding the target itself
+func rankSimilar(target models.PostEmbedding, candidates []models.PostEmbedding, limit int) []models.SimilarPost {
+	similar := make([]models.SimilarPost, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.PostID == target.PostID || candidate.Model != target.Model || len(candidate.Vector) != len(target.Vector) {
+			continue
+		}
+		similar = append(similar, models.SimilarPost{
+			PostID:      candidate.PostID,
+			ContentType: candidate.ContentType,
+			Similarity:  cosineSimilarity(target.Vector, candidate.Vector),
+		})
+	}
+
+	return topSimilar(similar, limit)
+}
+
+// topSimilar keeps the limit most similar posts, most similar first
+func topSimilar(similar []models.SimilarPost, limit int) []models.SimilarPost {
+	sort.Slice(similar, func(i, j int) bool {
+		return similar[i].Similarity > similar[j].Similarity
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRankSimilar(t *testing.T) {
+	target := models.PostEmbedding{PostID: "p1", Vector: []float64{1, 0, 0}}
+	candidates := []models.PostEmbedding{
+		{PostID: "p1", Vector: []float64{1, 0, 0}},
+		{PostID: "p2", Vector: []float64{0, 1, 0}},
+		{PostID: "p3", Vector: []float64{0.9, 0.1, 0}},
+		{PostID: "p4", Vector: []float64{1, 1}}, // different dimension
+		{PostID: "p5", Vector: []float64{0.5, 0.5, 0}},
+	}
+
+	similar := rankSimilar(target, candidates, 2)
+
+	if len(similar) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(similar))
+	}
+	if similar[0].PostID != "p3" || similar[1].PostID != "p5" {
+		t.Errorf("Unexpected order: %+v", similar)
+	}
+	if math.Abs(similar[1].Similarity-math.Sqrt(0.5)) > 1e-9 {
+		t.Errorf("Similarity = %v, want %v", similar[1].Similarity, math.Sqrt(0.5))
+	}
+}
+
+func TestRankSimilarAcrossPages(t *testing.T) {
+	target := models.PostEmbedding{PostID: "p1", Model: "m2", Vector: []float64{1, 0}}
+	pages := [][]models.PostEmbedding{
+		{{PostID: "p2", Model: "m2", Vector: []float64{0, 1}}, {PostID: "p3", Model: "m2", Vector: []float64{1, 0.1}}},
+		{{PostID: "p4", Model: "m1", Vector: []float64{1, 0}}, {PostID: "p5", Model: "m2", Vector: []float64{1, 0.5}}},
+	}
+
+	similar := []models.SimilarPost{}
+	for _, page := range pages {
+		similar = topSimilar(append(similar, rankSimilar(target, page, 2)...), 2)
+	}
+
+	// The embedding of another model is never compared
+	if len(similar) != 2 || similar[0].PostID != "p3" || similar[1].PostID != "p5" {
+		t.Errorf("Unexpected similar posts %+v", similar)
+	}
+}
+
+func TestParseEmbedding(t *testing.T) {
+	prediction, err := structpb.NewValue(map[string]interface{}{
+		"embeddings": map[string]interface{}{
+			"values":     []interface{}{0.1, -0.2, 0.3},
+			"statistics": map[string]interface{}{"token_count": 4},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vector, err := parseEmbedding(prediction)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vector) != 3 || vector[1] != -0.2 {
+		t.Errorf("Vector = %v, want [0.1 -0.2 0.3]", vector)
+	}
+
+	if _, err := parseEmbedding(structpb.NewNumberValue(1)); err == nil {
+		t.Error("Expected error for prediction without embeddings")
+	}
+}
+
+func TestEmbeddingText(t *testing.T) {
+	if got := embeddingText(" a cat ", []string{"cat", "pet"}); got != "a cat\nKeywords: cat, pet" {
+		t.Errorf("embeddingText = %q", got)
+	}
+	if got := embeddingText("a cat", nil); got != "a cat" {
+		t.Errorf("embeddingText = %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Creator accounts whose creation time is remembered; the cache starts over when full
+const maxFloorAccounts = 50000
+
+// EngagementFloor keeps posts with too little engagement out of the trending feeds and viral
+// alerts: a post needs a minimum of views and of unique viewers, and its creator's account a
+// minimum age. Posts whose viewers are not tracked have no unique viewers; creators whose
+// account creation time is unknown pass the account age check.
+type EngagementFloor struct {
+	firestoreClient  *FirestoreClient
+	minViews         int64
+	minUniqueViewers int64
+	minAccountAge    time.Duration
+
+	mu       sync.Mutex
+	accounts map[string]time.Time // creation time of creator accounts, zero when unknown
+}
+
+func NewEngagementFloor(firestoreClient *FirestoreClient, minViews, minUniqueViewers int64, minAccountAge time.Duration) *EngagementFloor {
+	return &EngagementFloor{
+		firestoreClient:  firestoreClient,
+		minViews:         minViews,
+		minUniqueViewers: minUniqueViewers,
+		minAccountAge:    minAccountAge,
+		accounts:         make(map[string]time.Time),
+	}
+}
+
+// EngagementFloorFrom returns the engagement floor configured by the environment
+func EngagementFloorFrom(firestoreClient *FirestoreClient, cfg *config.Config) *EngagementFloor {
+	return NewEngagementFloor(firestoreClient, int64(cfg.TrendingMinViews), int64(cfg.TrendingMinUniqueViewers), time.Duration(cfg.TrendingMinAccountAgeHours)*time.Hour)
+}
+
+// Allows reports whether a post clears the engagement floor; every post does without one. The
+// creator is the score's, else the post's owner.
+func (ef *EngagementFloor) Allows(score models.TrendingScore) bool {
+	if ef == nil {
+		return true
+	}
+	if !ef.meetsCounts(score) {
+		return false
+	}
+	if ef.minAccountAge <= 0 {
+		return true
+	}
+
+	creatorID := score.CreatorID
+	if creatorID == "" {
+		owner, err := ef.firestoreClient.PostOwner(score.PostID)
+		if err != nil || owner == "" {
+			return true
+		}
+		creatorID = owner
+	}
+	created := ef.accountCreatedAt(creatorID)
+	return created.IsZero() || time.Since(created) >= ef.minAccountAge
+}
+
+// meetsCounts reports whether a score reaches the view and unique viewer minimums
+func (ef *EngagementFloor) meetsCounts(score models.TrendingScore) bool {
+	return score.ViewCount >= ef.minViews && score.UniqueViewers >= ef.minUniqueViewers
+}
+
+// accountCreatedAt returns when a creator's account was created, zero when unknown
+func (ef *EngagementFloor) accountCreatedAt(userID string) time.Time {
+	ef.mu.Lock()
+	created, ok := ef.accounts[userID]
+	ef.mu.Unlock()
+	if ok {
+		return created
+	}
+
+	created, err := ef.firestoreClient.AccountCreatedAt(userID)
+	if err != nil {
+		// Let the post through and try again next time
+		return time.Time{}
+	}
+	ef.mu.Lock()
+	defer ef.mu.Unlock()
+	if len(ef.accounts) >= maxFloorAccounts {
+		ef.accounts = make(map[string]time.Time)
+	}
+	ef.accounts[userID] = created
+	return created
+}
+
+// AccountCreatedAt returns when a user's account was created, zero when the user or the time
+// is unknown
+func (fc *FirestoreClient) AccountCreatedAt(userID string) (time.Time, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	userDoc, err := fc.client.Collection("users").Doc(userID).Get(fc.ctx)
+	if IsNotFound(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	userData := userDoc.Data()
+	if created, ok := userData["createdAt"].(time.Time); ok {
+		return created, nil
+	}
+	created, _ := userData["created_at"].(time.Time)
+	return created, nil
+}
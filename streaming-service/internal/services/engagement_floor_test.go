@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestEngagementFloorAllows(t *testing.T) {
+	var unset *EngagementFloor
+	if !unset.Allows(models.TrendingScore{}) {
+		t.Error("Expected every post to pass without a floor")
+	}
+
+	floor := NewEngagementFloor(nil, 20, 5, 0)
+	tests := []struct {
+		name     string
+		score    models.TrendingScore
+		expected bool
+	}{
+		{"quiet post", models.TrendingScore{ViewCount: 3, LikeCount: 1, UniqueViewers: 3}, false},
+		{"few viewers", models.TrendingScore{ViewCount: 50, UniqueViewers: 2}, false},
+		{"untracked viewers", models.TrendingScore{ViewCount: 50}, false},
+		{"enough engagement", models.TrendingScore{ViewCount: 20, UniqueViewers: 5}, true},
+	}
+	for _, test := range tests {
+		if got := floor.Allows(test.score); got != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestExpectedCounts(t *testing.T) {
+	score := models.TrendingScore{PostID: "post-1", ViewCount: 30, LikeCount: 2}
+	if got := expectedCounts(nil, score); got.ViewCount != 30 {
+		t.Errorf("Expected the new counts without a stored score, got %+v", got)
+	}
+
+	previous := &models.TrendingScore{PostID: "post-1", ViewCount: 40, LikeCount: 1, UniqueViewers: 12, CreatorID: "creator-1"}
+	got := expectedCounts(previous, score)
+	if got.ViewCount != 40 || got.LikeCount != 2 || got.UniqueViewers != 12 || got.CreatorID != "creator-1" {
+		t.Errorf("Expected the stored score merged with the new counts, got %+v", got)
+	}
+}
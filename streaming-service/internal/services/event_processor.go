@@ -2,6 +2,7 @@ package services
 
 import (
 	"confluent-viral-intelligence/internal/logger"
+	"strings"
 	"time"
 
 	"confluent-viral-intelligence/internal/config"
@@ -9,157 +10,538 @@ import (
 )
 
 type EventProcessor struct {
-	producer  *KafkaProducer
-	firestore *FirestoreClient
-	vertexAI  *VertexAIClient
-	config    *config.Config
+	producer   *KafkaProducer
+	firestore  *FirestoreClient
+	vertexAI   *VertexAIClient // only set with the vertex AI provider
+	ai         AIProvider
+	embeddings *EmbeddingService
+	moderation *ModerationService
+	audience    *AudienceTracker
+	predictions *PredictionTracker
+	anomalies   *AnomalyDetector
+	partners    *PartnerStreamer
+	alerts      *ViralAlertCooldown
+	metrics     *MetricsAggregator
+	rollups     *RollupService
+	retention   *RetentionTracker
+	hub         *WebSocketHub
+	dualRun     *predictorDualRun
+	webhooks    *WebhookDispatcher
+	scopes      *ScopedTrending
+	recommendations *RecommendationEngine
+	seen        *SeenPostTracker
+	config      *config.Config
 }
 
-func NewEventProcessor(producer *KafkaProducer, firestore *FirestoreClient, vertexAI *VertexAIClient, cfg *config.Config) *EventProcessor {
+// NewEventProcessor creates a processor of the consumed events. Moderation, analytics and
+// alerting are optional and added with the Use setters; without them events are only scored.
+func NewEventProcessor(producer *KafkaProducer, firestore *FirestoreClient, vertexAI *VertexAIClient, ai AIProvider, embeddings *EmbeddingService, hub *WebSocketHub, cfg *config.Config) *EventProcessor {
+	// The predictors compared while the heuristic is retired are the Vertex AI ones
+	var dualRun *predictorDualRun
+	if vertexAI != nil {
+		dualRun = newPredictorDualRun(vertexAI, firestore, cfg)
+	}
+
 	return &EventProcessor{
-		producer:  producer,
-		firestore: firestore,
-		vertexAI:  vertexAI,
-		config:    cfg,
+		producer:    producer,
+		firestore:   firestore,
+		vertexAI:    vertexAI,
+		ai:          ai,
+		embeddings:  embeddings,
+		hub:         hub,
+		dualRun:     dualRun,
+		config:      cfg,
 	}
 }
 
+// UseWebhooks delivers viral alerts and crossed score thresholds to the registered webhooks
+func (ep *EventProcessor) UseWebhooks(webhooks *WebhookDispatcher) {
+	ep.webhooks = webhooks
+}
+
+// UseModeration scores the prompts of created posts with the AI provider's safety checks
+func (ep *EventProcessor) UseModeration(moderation *ModerationService) {
+	ep.moderation = moderation
+}
+
+// UseAudience adds the viewers of posts to their creators' audience sketches
+func (ep *EventProcessor) UseAudience(audience *AudienceTracker) {
+	ep.audience = audience
+}
+
+// UsePredictions stores viral predictions so their outcomes can be checked
+func (ep *EventProcessor) UsePredictions(predictions *PredictionTracker) {
+	ep.predictions = predictions
+}
+
+// UseAnomalies follows the engagement velocity of posts to flag anomalies
+func (ep *EventProcessor) UseAnomalies(anomalies *AnomalyDetector) {
+	ep.anomalies = anomalies
+}
+
+// UsePartners aggregates engagement into the counts published to partners
+func (ep *EventProcessor) UsePartners(partners *PartnerStreamer) {
+	ep.partners = partners
+}
+
+// UseAlertCooldown keeps a post from being alerted at the same tier over and over
+func (ep *EventProcessor) UseAlertCooldown(alerts *ViralAlertCooldown) {
+	ep.alerts = alerts
+}
+
+// UseMetrics counts events into the dashboard, creator and leaderboard metrics
+func (ep *EventProcessor) UseMetrics(metrics *MetricsAggregator) {
+	ep.metrics = metrics
+}
+
+// UseRollups counts events into the hourly and daily engagement rollups
+func (ep *EventProcessor) UseRollups(rollups *RollupService) {
+	ep.rollups = rollups
+}
+
+// UseRetention adds viewers to the daily viewer sketches behind the retention cohorts
+func (ep *EventProcessor) UseRetention(retention *RetentionTracker) {
+	ep.retention = retention
+}
+
+// GetAIProvider returns the AI provider behind keywords, moderation and viral prediction
+func (ep *EventProcessor) GetAIProvider() AIProvider {
+	return ep.ai
+}
+
 // GetFirestoreClient returns the Firestore client
 func (ep *EventProcessor) GetFirestoreClient() *FirestoreClient {
 	return ep.firestore
 }
 
-// ProcessInteraction handles user interaction events
-func (ep *EventProcessor) ProcessInteraction(event models.InteractionEvent) error {
+// GetVertexAIClient returns the Vertex AI client, nil with other AI providers
+func (ep *EventProcessor) GetVertexAIClient() *VertexAIClient {
+	return ep.vertexAI
+}
+
+// RegisterCaches hands the processor's TTL caches to the cache maintainer
+func (ep *EventProcessor) RegisterCaches(maintainer *CacheMaintainer) {
+	if ep.vertexAI != nil {
+		maintainer.Register("ai_cache", ep.vertexAI.EvictExpiredCache, ep.vertexAI.CacheSize)
+	}
+	if ep.dualRun != nil {
+		maintainer.Register("predictor_dual_run", ep.dualRun.evictExpired, ep.dualRun.len)
+	}
+}
+
+// GetEmbeddingService returns the embedding service
+func (ep *EventProcessor) GetEmbeddingService() *EmbeddingService {
+	return ep.embeddings
+}
+
+// ProcessInteraction handles user interaction events. requestID, the API request that
+// ingested the event, travels with it through Kafka to the score update.
+func (ep *EventProcessor) ProcessInteraction(event models.InteractionEvent, requestID string) error {
+	ingestedAt := time.Now()
+
 	// Publish to Kafka
-	if err := ep.producer.PublishInteraction(event); err != nil {
-		logger.Infof("Failed to publish interaction: %v", err)
+	if err := ep.producer.PublishInteraction(event, EventTrace{IngestedAt: ingestedAt, RequestID: requestID}); err != nil {
+		logger.Infof("[%s] Failed to publish interaction: %v", requestID, err)
 		return err
 	}
+	PipelineLatency.ObserveSince(StageProduce, ingestedAt)
+	Dashboard.RecordEvent()
 
-	logger.Infof("Processed interaction: %s on post %s", event.EventType, event.PostID)
+	logger.Infof("[%s] Processed interaction: %s on post %s", requestID, event.EventType, event.PostID)
 	return nil
 }
 
 // ProcessInteractionForAnalytics updates analytics when consuming from Kafka
-func (ep *EventProcessor) ProcessInteractionForAnalytics(event models.InteractionEvent) {
+func (ep *EventProcessor) ProcessInteractionForAnalytics(event models.InteractionEvent, requestID string) {
+	ep.firestore.RecordAudit(event.PostID, AuditKindEvent, "consumer:interaction", map[string]interface{}{
+		"event_type": event.EventType,
+		"user_id":    event.UserID,
+		"timestamp":  event.Timestamp,
+		"request_id": requestID,
+	})
+
+	firestoreStart := time.Now()
+
 	// Update Firestore analytics based on interaction type
-	if err := ep.firestore.UpdatePostAnalytics(event.PostID, event.EventType); err != nil {
+	if timing, lag := ep.eventTiming(event.Timestamp); timing == EventOnTime {
+		if score, err := ep.firestore.UpdatePostAnalytics(event.PostID, event.EventType, requestID); err != nil {
+			logger.Infof("Failed to update analytics for interaction: %v", err)
+		} else {
+			ep.broadcastScore(score)
+		}
+		ep.anomalies.Record(event.PostID)
+	} else if counted, err := ep.firestore.IncrementPostCounter(event.PostID, event.EventType); err != nil {
 		logger.Infof("Failed to update analytics for interaction: %v", err)
+	} else if counted {
+		ep.correctLateEvent("interaction:"+event.EventType, event.PostID, event.Timestamp, timing, lag, func(score *models.TrendingScore) {
+			countInteraction(score, event.EventType)
+		})
+	}
+	PipelineLatency.ObserveSince(StageFirestore, firestoreStart)
+	ep.partners.RecordInteraction(event.PostID, event.EventType)
+	ep.metrics.RecordInteraction(event.PostID, event.UserID, event.EventType)
+	ep.scopes.RecordInteraction(event.PostID, event.UserID, event.EventType)
+	ep.recommendations.RecordEngagement(event.UserID, event.PostID, event.EventType)
+	if event.EventType == "view" {
+		ep.seen.RecordView(event.UserID, event.PostID, event.Timestamp)
 	}
-	logger.Infof("Updated analytics for %s on post %s", event.EventType, event.PostID)
+	ep.rollups.RecordInteraction(event.PostID, event.EventType, event.Timestamp)
+	ep.retention.RecordActivity(event.UserID, event.Timestamp)
+	logger.Infof("[%s] Updated analytics for %s on post %s", requestID, event.EventType, event.PostID)
 }
 
 // ProcessViewForAnalytics updates analytics when consuming view events from Kafka
-func (ep *EventProcessor) ProcessViewForAnalytics(event models.ViewEvent) {
+func (ep *EventProcessor) ProcessViewForAnalytics(event models.ViewEvent, requestID string) {
+	ep.firestore.RecordAudit(event.PostID, AuditKindEvent, "consumer:view", map[string]interface{}{
+		"user_id":    event.UserID,
+		"duration":   event.Duration,
+		"platform":   event.Platform,
+		"viewed_at":  event.ViewedAt,
+		"request_id": requestID,
+	})
+
+	firestoreStart := time.Now()
+
 	// Increment view count
 	if err := ep.firestore.IncrementViewCount(event.PostID); err != nil {
 		logger.Infof("Failed to increment view count: %v", err)
 	}
 	
-	// Update trending score
-	if err := ep.firestore.UpdateTrendingScoreFromView(event.PostID); err != nil {
-		logger.Infof("Failed to update trending score: %v", err)
+	// Update trending score; late views are credited to when they happened, not to now
+	viral := false
+	if timing, lag := ep.eventTiming(event.ViewedAt); timing == EventOnTime {
+		if score, err := ep.firestore.UpdateTrendingScoreFromView(event.PostID, requestID); err != nil {
+			logger.Infof("Failed to update trending score: %v", err)
+		} else {
+			ep.broadcastScore(score)
+			viral = isDashboardViral(score)
+		}
+		ep.anomalies.Record(event.PostID)
+	} else {
+		ep.correctLateEvent("view", event.PostID, event.ViewedAt, timing, lag, func(score *models.TrendingScore) {
+			score.ViewCount++
+		})
+	}
+
+	// Fold the watch time into the post's completion rate
+	if event.Duration > 0 {
+		if err := ep.firestore.RecordViewCompletion(event.PostID, float64(event.Duration)); err != nil {
+			logger.Infof("Failed to record view completion: %v", err)
+		}
 	}
+	PipelineLatency.ObserveSince(StageFirestore, firestoreStart)
+
+	// Add the viewer to the creator's audience for overlap analytics
+	ep.audience.RecordView(event.PostID, event.UserID)
+	ep.partners.RecordView(event.PostID)
+	ep.metrics.RecordView(event, viral)
+	ep.scopes.RecordView(event)
+	ep.recommendations.RecordEngagement(event.UserID, event.PostID, "view")
+	ep.seen.RecordView(event.UserID, event.PostID, event.ViewedAt)
+	ep.rollups.RecordView(event.PostID, event.ViewedAt)
+	ep.retention.RecordView(event.PostID, event.UserID, event.ViewedAt)
 	
-	logger.Infof("Updated analytics for view on post %s", event.PostID)
+	logger.Infof("[%s] Updated analytics for view on post %s", requestID, event.PostID)
 }
 
 // ProcessRemixForAnalytics updates analytics when consuming remix events from Kafka
-func (ep *EventProcessor) ProcessRemixForAnalytics(event models.RemixEvent) {
+func (ep *EventProcessor) ProcessRemixForAnalytics(event models.RemixEvent, requestID string) {
+	ep.firestore.RecordAudit(event.OriginalPostID, AuditKindEvent, "consumer:remix", map[string]interface{}{
+		"remix_post_id": event.RemixPostID,
+		"user_id":       event.UserID,
+		"remix_type":    event.RemixType,
+		"remixed_at":    event.RemixedAt,
+		"request_id":    requestID,
+	})
+
+	firestoreStart := time.Now()
+
 	// Track remix chain
 	if err := ep.firestore.TrackRemixChain(event.OriginalPostID, event.RemixPostID); err != nil {
 		logger.Infof("Failed to track remix chain: %v", err)
 	}
 	
 	// Update trending score for original post
-	if err := ep.firestore.UpdateTrendingScoreFromRemix(event.OriginalPostID); err != nil {
-		logger.Infof("Failed to update trending score: %v", err)
+	if timing, lag := ep.eventTiming(event.RemixedAt); timing == EventOnTime {
+		if score, err := ep.firestore.UpdateTrendingScoreFromRemix(event.OriginalPostID, requestID); err != nil {
+			logger.Infof("Failed to update trending score: %v", err)
+		} else {
+			ep.broadcastScore(score)
+		}
+		ep.anomalies.Record(event.OriginalPostID)
+	} else {
+		ep.correctLateEvent("remix", event.OriginalPostID, event.RemixedAt, timing, lag, func(score *models.TrendingScore) {
+			score.RemixCount++
+		})
 	}
+	PipelineLatency.ObserveSince(StageFirestore, firestoreStart)
+	ep.partners.RecordRemix(event.OriginalPostID)
+	ep.metrics.RecordRemix(event.OriginalPostID)
+	ep.rollups.RecordRemix(event.OriginalPostID, event.RemixedAt)
+	ep.recommendations.RecordEngagement(event.UserID, event.OriginalPostID, "remix")
+	ep.retention.RecordActivity(event.UserID, event.RemixedAt)
 	
-	logger.Infof("Updated analytics for remix: %s -> %s", event.OriginalPostID, event.RemixPostID)
+	logger.Infof("[%s] Updated analytics for remix: %s -> %s", requestID, event.OriginalPostID, event.RemixPostID)
 }
 
-// ProcessContentMetadata handles content metadata and generates keywords
-func (ep *EventProcessor) ProcessContentMetadata(event models.ContentMetadata) error {
-	// Extract keywords using Vertex AI
-	keywords, err := ep.vertexAI.ExtractKeywords(event.Prompt, event.ContentType)
+// eventTiming classifies a consumed event by how long after it happened it arrived
+func (ep *EventProcessor) eventTiming(eventTime time.Time) (string, time.Duration) {
+	now := time.Now()
+	timing := classifyEventTime(eventTime, now,
+		time.Duration(ep.config.BackdatedEventLatenessMinutes)*time.Minute,
+		time.Duration(ep.config.BackdatedReprocessWindowHours)*time.Hour)
+	return timing, now.Sub(eventTime)
+}
+
+// correctLateEvent applies a late event to the post's trending score, reprocessing the score
+// only inside the reprocessing window
+func (ep *EventProcessor) correctLateEvent(source, postID string, eventTime time.Time, timing string, lag time.Duration, count func(score *models.TrendingScore)) {
+	err := ep.firestore.ApplyBackdatedEvent(postID, source, eventTime, timing == EventBackdated, count)
+	BackdatedEvents.Record(source, timing, lag, err)
+	if err != nil {
+		logger.Infof("Failed to apply backdated %s on post %s: %v", source, postID, err)
+		return
+	}
+	logger.Debugf("⏪ Applied %s %s on post %s from %v ago", timing, source, postID, lag.Round(time.Second))
+}
+
+// ProcessContentMetadata handles content metadata and generates keywords, returning the event
+// with the keywords, category and style it was published with
+func (ep *EventProcessor) ProcessContentMetadata(event models.ContentMetadata, requestID string) (models.ContentMetadata, error) {
+	ingestedAt := time.Now()
+
+	// Extract keywords using the AI provider
+	keywords, err := ep.ai.ExtractKeywords(event.Prompt, event.ContentType)
+	PipelineLatency.ObserveSince(StageAI, ingestedAt)
+	ep.recordAICall(event.PostID, "extract_keywords", keywords, err)
 	if err != nil {
 		logger.Infof("Failed to extract keywords: %v", err)
 		// Continue with empty keywords
 		keywords = &models.KeywordExtractionResponse{
-			Keywords: []string{},
-			Category: event.ContentType,
+			Keywords:        []string{},
+			EnglishKeywords: []string{},
+			Hashtags:        normalizeHashtags(hashtagsInText(event.Prompt)),
+			Category:        event.ContentType,
+			Language:        detectLanguage(event.Prompt),
+		}
+	}
+
+	// Merge what the first output shows with the keywords of the prompt; vision needs Vertex AI
+	var visual *models.VisualAnalysis
+	if ep.config.AnalyzeOutputMedia && ep.vertexAI != nil && len(event.OutputURLs) > 0 {
+		aiStart := time.Now()
+		visual, err = ep.vertexAI.AnalyzeMedia(event.OutputURLs[0])
+		PipelineLatency.ObserveSince(StageAI, aiStart)
+		ep.recordAICall(event.PostID, "analyze_media", visual, err)
+		if err != nil {
+			logger.Infof("Failed to analyze media of post %s: %v", event.PostID, err)
+			visual = nil
+		} else {
+			mergeVisualKeywords(keywords, visual)
+			event.Visual = visual
 		}
 	}
 
 	// Update event with keywords
 	event.Keywords = keywords.Keywords
+	event.EnglishKeywords = keywords.EnglishKeywords
+	event.Hashtags = keywords.Hashtags
+	event.Language = keywords.Language
 	event.Category = keywords.Category
 	event.Style = keywords.Style
 
 	// Publish to Kafka
-	if err := ep.producer.PublishContentMetadata(event); err != nil {
-		logger.Infof("Failed to publish content metadata: %v", err)
-		return err
+	produceStart := time.Now()
+	if err := ep.producer.PublishContentMetadata(event, EventTrace{IngestedAt: ingestedAt, RequestID: requestID}); err != nil {
+		logger.Infof("[%s] Failed to publish content metadata: %v", requestID, err)
+		return event, err
 	}
+	PipelineLatency.ObserveSince(StageProduce, produceStart)
+	Dashboard.RecordEvent()
+	ep.metrics.RecordPost(event.PostID, event.UserID, event.ContentType)
+	ep.rollups.RecordPost(event.CreatedAt)
 
 	// Update Firestore
-	if err := ep.firestore.UpdateContentMetadata(event.PostID, keywords.Keywords, keywords.Category, keywords.Style); err != nil {
+	firestoreStart := time.Now()
+	if err := ep.firestore.UpdateContentMetadata(event.PostID, keywords); err != nil {
 		logger.Infof("Failed to update content metadata in Firestore: %v", err)
 	}
+	if err := ep.firestore.SetContentFacets(event.PostID, NewContentFacets(event.ContentType, event.UserID, event.CreatedAt, keywords)); err != nil {
+		logger.Infof("Failed to store content facets of post %s: %v", event.PostID, err)
+	}
+	if visual != nil {
+		if err := ep.firestore.SaveVisualAnalysis(event.PostID, visual); err != nil {
+			logger.Infof("Failed to save visual analysis of post %s: %v", event.PostID, err)
+		}
+	}
+	if err := ep.firestore.RecordHashtags(keywords.Hashtags, event.CreatedAt); err != nil {
+		logger.Infof("Failed to record hashtags of post %s: %v", event.PostID, err)
+	}
+	PipelineLatency.ObserveSince(StageFirestore, firestoreStart)
+
+	// Score the prompt for unsafe content; flagged posts are kept out of trending
+	if ep.moderation != nil {
+		aiStart := time.Now()
+		verdict, err := ep.moderation.ModeratePost(event)
+		PipelineLatency.ObserveSince(StageAI, aiStart)
+		ep.recordAICall(event.PostID, "moderate_content", verdict, err)
+		if err != nil {
+			logger.Infof("Failed to moderate post %s: %v", event.PostID, err)
+		}
+	}
 
-	logger.Infof("Processed content metadata for post %s with %d keywords", event.PostID, len(keywords.Keywords))
-	return nil
+	// Extract preview metadata (dimensions, thumbnail, dominant color) for feed placeholders
+	go func() {
+		media, err := ExtractMediaMetadata(event)
+		if err != nil {
+			logger.Debugf(" Partial media metadata for post %s: %v", event.PostID, err)
+		}
+		if media == (models.MediaMetadata{}) {
+			return
+		}
+		if err := ep.firestore.UpdateMediaMetadata(event.PostID, media); err != nil {
+			logger.Infof("Failed to save media metadata for post %s: %v", event.PostID, err)
+		}
+	}()
+
+	// Embed the prompt and keywords for similarity search and duplicate detection without
+	// holding up ingestion
+	if ep.embeddings != nil {
+		go func() {
+			embedding, err := ep.embeddings.EmbedPost(event.PostID, event.ContentType, event.Prompt, event.Keywords)
+			if err != nil {
+				logger.Infof("Failed to embed post %s: %v", event.PostID, err)
+				return
+			}
+			if _, err := ep.embeddings.duplicates.Check(*embedding); err != nil {
+				logger.Infof("Failed to check post %s for duplicates: %v", event.PostID, err)
+			}
+		}()
+	}
+
+	logger.Infof("[%s] Processed content metadata for post %s with %d keywords", requestID, event.PostID, len(keywords.Keywords))
+	return event, nil
 }
 
 // ProcessView handles view events
-func (ep *EventProcessor) ProcessView(event models.ViewEvent) error {
+func (ep *EventProcessor) ProcessView(event models.ViewEvent, requestID string) error {
+	ingestedAt := time.Now()
+
 	// Publish to Kafka
-	if err := ep.producer.PublishView(event); err != nil {
-		logger.Infof("Failed to publish view: %v", err)
+	if err := ep.producer.PublishView(event, EventTrace{IngestedAt: ingestedAt, RequestID: requestID}); err != nil {
+		logger.Infof("[%s] Failed to publish view: %v", requestID, err)
 		return err
 	}
+	PipelineLatency.ObserveSince(StageProduce, ingestedAt)
+	Dashboard.RecordEvent()
 
 	// Increment view count in Firestore
 	if err := ep.firestore.IncrementViewCount(event.PostID); err != nil {
 		logger.Infof("Failed to increment view count: %v", err)
 	}
 
-	logger.Infof("Processed view for post %s by user %s", event.PostID, event.UserID)
+	logger.Infof("[%s] Processed view for post %s by user %s", requestID, event.PostID, event.UserID)
 	return nil
 }
 
 // ProcessRemix handles remix events
-func (ep *EventProcessor) ProcessRemix(event models.RemixEvent) error {
+func (ep *EventProcessor) ProcessRemix(event models.RemixEvent, requestID string) error {
+	ingestedAt := time.Now()
+
 	// Publish to Kafka
-	if err := ep.producer.PublishRemix(event); err != nil {
-		logger.Infof("Failed to publish remix: %v", err)
+	if err := ep.producer.PublishRemix(event, EventTrace{IngestedAt: ingestedAt, RequestID: requestID}); err != nil {
+		logger.Infof("[%s] Failed to publish remix: %v", requestID, err)
 		return err
 	}
+	PipelineLatency.ObserveSince(StageProduce, ingestedAt)
+	Dashboard.RecordEvent()
 
 	// Track remix chain in Firestore
 	if err := ep.firestore.TrackRemixChain(event.OriginalPostID, event.RemixPostID); err != nil {
 		logger.Infof("Failed to track remix chain: %v", err)
 	}
 
-	logger.Infof("Processed remix: %s -> %s", event.OriginalPostID, event.RemixPostID)
+	logger.Infof("[%s] Processed remix: %s -> %s", requestID, event.OriginalPostID, event.RemixPostID)
+	return nil
+}
+
+// ProcessComment handles comment events. The comment is scored for sentiment in the
+// background; comment counts keep coming from "comment" interaction events.
+func (ep *EventProcessor) ProcessComment(event models.CommentEvent, requestID string) error {
+	ingestedAt := time.Now()
+
+	// Publish to Kafka
+	if err := ep.producer.PublishComment(event, EventTrace{IngestedAt: ingestedAt, RequestID: requestID}); err != nil {
+		logger.Infof("[%s] Failed to publish comment: %v", requestID, err)
+		return err
+	}
+	PipelineLatency.ObserveSince(StageProduce, ingestedAt)
+	Dashboard.RecordEvent()
+
+	// Sentiment analysis needs Vertex AI
+	if ep.vertexAI != nil {
+		go func() {
+			aiStart := time.Now()
+			sentiment, err := ep.vertexAI.AnalyzeSentiment(event.Text)
+			PipelineLatency.ObserveSince(StageAI, aiStart)
+			ep.recordAICall(event.PostID, "analyze_sentiment", sentiment, err)
+			if err != nil {
+				logger.Infof("Failed to analyze sentiment of comment on post %s: %v", event.PostID, err)
+				return
+			}
+
+			if _, err := ep.firestore.ApplyCommentSentiment(event.PostID, sentiment.Score); err != nil {
+				logger.Infof("Failed to update sentiment of post %s: %v", event.PostID, err)
+			}
+		}()
+	}
+
+	logger.Infof("[%s] Processed comment on post %s by user %s", requestID, event.PostID, event.UserID)
+	return nil
+}
+
+// ProcessRecommendationFeedback handles a user's feedback on a recommended post
+func (ep *EventProcessor) ProcessRecommendationFeedback(event models.RecommendationFeedbackEvent, requestID string) error {
+	ingestedAt := time.Now()
+
+	// Publish to Kafka
+	if err := ep.producer.PublishRecommendationFeedback(event, EventTrace{IngestedAt: ingestedAt, RequestID: requestID}); err != nil {
+		logger.Infof("[%s] Failed to publish recommendation feedback: %v", requestID, err)
+		return err
+	}
+	PipelineLatency.ObserveSince(StageProduce, ingestedAt)
+
+	logger.Infof("[%s] Processed %s feedback on post %s by user %s", requestID, event.Action, event.PostID, event.UserID)
 	return nil
 }
 
+// ProcessRecommendationFeedbackForAnalytics stores the feedback consumed from Kafka, re-ranking
+// the user's next recommendations
+func (ep *EventProcessor) ProcessRecommendationFeedbackForAnalytics(event models.RecommendationFeedbackEvent, requestID string) {
+	if err := ep.firestore.ApplyRecommendationFeedback(event); err != nil {
+		logger.Infof("[%s] Failed to store %s feedback on post %s: %v", requestID, event.Action, event.PostID, err)
+		return
+	}
+	logger.Debugf("[%s] Stored %s feedback on post %s by user %s", requestID, event.Action, event.PostID, event.UserID)
+}
+
 // ProcessTrendingScore handles trending score calculations from Flink
 func (ep *EventProcessor) ProcessTrendingScore(score models.TrendingScore) {
-	// Predict virality using Vertex AI
-	prediction, err := ep.vertexAI.PredictVirality(models.ViralPredictionRequest{
-		PostID:             score.PostID,
-		ViewCount:          score.ViewCount,
-		LikeCount:          score.LikeCount,
-		CommentCount:       score.CommentCount,
-		ShareCount:         score.ShareCount,
-		RemixCount:         score.RemixCount,
-		EngagementVelocity: score.EngagementVelocity,
-		TimeElapsed:        int(time.Since(score.CalculatedAt).Minutes()),
-	})
+	previousTier := ""
+	previous, previousErr := ep.firestore.GetPostStats(score.PostID)
+	if previousErr != nil {
+		previous = nil
+	}
+	if previous != nil {
+		previousTier = previous.ViralTier
+	}
+	predictionReq := ep.predictionRequest(score, previous, time.Since(score.CalculatedAt))
+
+	// Predict virality using the AI provider
+	aiStart := time.Now()
+	prediction, err := ep.ai.PredictVirality(predictionReq)
+	PipelineLatency.ObserveSince(StageAI, aiStart)
+	ep.recordAICall(score.PostID, "predict_virality", prediction, err)
 
 	if err != nil {
 		logger.Infof("Failed to predict virality: %v", err)
@@ -167,25 +549,147 @@ func (ep *EventProcessor) ProcessTrendingScore(score models.TrendingScore) {
 			ViralProbability: 0.5,
 			Confidence:       0.5,
 		}
+	} else {
+		// Keep the prediction to compare with the engagement the post actually reaches, and as
+		// the post's viral probability for this hour of its trajectory
+		ep.predictions.Record(score.PostID, prediction)
+		if err := ep.firestore.RecordViralSample(score.PostID, prediction.ViralProbability, time.Now()); err != nil {
+			logger.Infof("Failed to record viral sample: %v", err)
+		}
+
+		// While the heuristic is being retired, compare it with the model in the background
+		if ep.dualRun.active(time.Now()) {
+			go ep.dualRun.Compare(predictionReq, prediction)
+		}
 	}
 
-	// Update score with prediction
+	// Update score with prediction and the alert tier it reaches. Posts below the engagement
+	// floor reach no tier, so they are alerted once they clear it.
 	score.ViralProbability = prediction.ViralProbability
+	if tier, ok := ep.config.ViralAlertTierFor(score.ViralProbability); ok && ep.firestore.floor.Allows(expectedCounts(previous, score)) {
+		score.ViralTier = tier.Name
+	}
 
-	// Save to Firestore
-	if err := ep.firestore.SaveTrendingScore(score); err != nil {
+	// Save to Firestore, merging with counts written concurrently by other paths
+	firestoreStart := time.Now()
+	stored, err := ep.firestore.ApplyTrendingScore(score.PostID, "flink_trending_score", func(latest *models.TrendingScore, exists bool) {
+		mergeScoreCounts(latest, score)
+		latest.Score = score.Score
+		latest.ViralProbability = score.ViralProbability
+		latest.ViralTier = score.ViralTier
+		latest.EngagementRate = score.EngagementRate
+		latest.EngagementVelocity = score.EngagementVelocity
+		latest.TimeWindow = score.TimeWindow
+		latest.CalculatedAt = score.CalculatedAt
+	})
+	if err != nil {
 		logger.Infof("Failed to save trending score: %v", err)
 	}
+	PipelineLatency.ObserveSince(StageFirestore, firestoreStart)
+	ep.broadcastScore(stored)
+	// Dashboard totals and webhook thresholds follow the score only when the previous one is
+	// known
+	if previousErr == nil {
+		ep.metrics.RecordScore(previous, stored)
+		ep.webhooks.ScoreChanged(previous, stored)
+	}
 
 	logger.Infof("Processed trending score for post %s: score=%.2f, viral_prob=%.2f", 
 		score.PostID, score.Score, score.ViralProbability)
 
-	// If viral probability is high, trigger notifications
-	if score.ViralProbability > 0.7 {
-		logger.Infof("🔥 VIRAL ALERT: Post %s has %.0f%% viral probability!", 
-			score.PostID, score.ViralProbability*100)
-		// TODO: Send push notifications
+	// Alert when the post reaches a higher tier than before, unless it was alerted at that
+	// tier recently and only dipped below it since
+	if ep.config.ViralAlertTierRank(score.ViralTier) > ep.config.ViralAlertTierRank(previousTier) &&
+		ep.alerts.Allow(score.PostID, score.ViralTier, time.Now()) {
+		ep.alertViralTier(score, previousTier)
+	}
+}
+
+// expectedCounts returns a score with the counts it is about to be stored with: the stored
+// score's merged with the new one's
+func expectedCounts(previous *models.TrendingScore, score models.TrendingScore) models.TrendingScore {
+	if previous == nil {
+		return score
+	}
+	expected := *previous
+	mergeScoreCounts(&expected, score)
+	return expected
+}
+
+// predictionRequest returns the virality prediction request for a score. Model-backed
+// predictors also look at the previously stored score, nil when unknown; every predictor uses
+// the comment sentiment aggregated on it.
+func (ep *EventProcessor) predictionRequest(score models.TrendingScore, previous *models.TrendingScore, elapsed time.Duration) models.ViralPredictionRequest {
+	req := models.ViralPredictionRequest{
+		PostID:             score.PostID,
+		ViewCount:          score.ViewCount,
+		LikeCount:          score.LikeCount,
+		CommentCount:       score.CommentCount,
+		ShareCount:         score.ShareCount,
+		RemixCount:         score.RemixCount,
+		EngagementVelocity: score.EngagementVelocity,
+		TimeElapsed:        int(elapsed.Minutes()),
+		ContentType:        score.ContentType,
+	}
+	if previous != nil {
+		if ep.config.ViralPredictionMode != ViralPredictionModeHeuristic {
+			req.PreviousScore = previous.Score
+			req.PreviousViralProbability = previous.ViralProbability
+		}
+		req.SentimentScore = previous.SentimentScore
+		req.SentimentCount = previous.SentimentCount
+	}
+	return req
+}
+
+// broadcastScore pushes an updated trending score to real-time clients
+func (ep *EventProcessor) broadcastScore(score *models.TrendingScore) {
+	if ep.hub == nil || score == nil {
+		return
+	}
+	ep.hub.BroadcastTrendingUpdate(score.PostID, score.Score, score.ViewCount)
+}
+
+// alertViralTier announces a post's new viral tier to WebSocket clients, webhooks and, through
+// Kafka, to the notification system
+func (ep *EventProcessor) alertViralTier(score models.TrendingScore, previousTier string) {
+	logger.Infof("🔥 %s ALERT: Post %s has %.0f%% viral probability!",
+		strings.ToUpper(score.ViralTier), score.PostID, score.ViralProbability*100)
+
+	if ep.hub != nil {
+		ep.hub.BroadcastViralAlert(score.PostID, score.ViralTier, score.ViralProbability, score.Score)
+		if ep.firestore != nil {
+			// Let the creator know their own post is taking off
+			if owner, err := ep.firestore.PostOwner(score.PostID); err != nil {
+				logger.Debugf(" Could not resolve creator of post %s: %v", score.PostID, err)
+			} else {
+				ep.hub.SendCreatorViralAlert(owner, score.PostID, score.ViralTier, score.ViralProbability)
+			}
+		}
+	}
+	alert := models.ViralAlert{
+		PostID:           score.PostID,
+		Tier:             score.ViralTier,
+		PreviousTier:     previousTier,
+		ViralProbability: score.ViralProbability,
+		Score:            score.Score,
+		AlertedAt:        time.Now(),
+	}
+	ep.webhooks.ViralAlert(alert)
+	if ep.producer != nil {
+		if err := ep.producer.PublishViralAlert(alert, alert.AlertedAt); err != nil {
+			logger.Infof("Failed to publish viral alert for post %s: %v", score.PostID, err)
+		}
+	}
+}
+
+// recordAICall adds an AI call and its result to the post's audit trail
+func (ep *EventProcessor) recordAICall(postID, operation string, result interface{}, err error) {
+	details := map[string]interface{}{"result": result}
+	if err != nil {
+		details["error"] = err.Error()
 	}
+	ep.firestore.RecordAudit(postID, AuditKindAI, operation, details)
 }
 
 // ProcessRecommendation handles personalized recommendations
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -21,7 +22,7 @@ func TestEventProcessorCreation(t *testing.T) {
 
 	// Note: In a real test, we would use mocks for these dependencies
 	// For now, we just test the struct creation
-	ep := NewEventProcessor(nil, nil, nil, cfg)
+	ep := NewEventProcessor(nil, nil, nil, nil, nil, nil, cfg)
 	
 	if ep == nil {
 		t.Fatal("Expected EventProcessor to be created, got nil")
@@ -197,7 +198,7 @@ func TestRecommendationStructure(t *testing.T) {
 // TestEventProcessorMethods tests that all required methods exist
 func TestEventProcessorMethods(t *testing.T) {
 	cfg := &config.Config{}
-	ep := NewEventProcessor(nil, nil, nil, cfg)
+	ep := NewEventProcessor(nil, nil, nil, nil, nil, nil, cfg)
 
 	// Test that methods exist (will panic if they don't)
 	_ = ep.ProcessInteraction
@@ -210,3 +211,27 @@ func TestEventProcessorMethods(t *testing.T) {
 	_ = ep.GetPostStats
 	_ = ep.GetUserRecommendations
 }
+
+// TestBroadcastScore tests that updated scores reach the WebSocket hub
+func TestBroadcastScore(t *testing.T) {
+	hub := NewWebSocketHub()
+	ep := NewEventProcessor(nil, nil, nil, nil, nil, hub, &config.Config{})
+
+	ep.broadcastScore(&models.TrendingScore{PostID: "post-1", Score: 12.5, ViewCount: 40})
+	ep.broadcastScore(nil)
+
+	select {
+	case message := <-hub.broadcast:
+		if message.key != "trending_update:post-1" || !strings.Contains(string(message.data), `"view_count":40`) {
+			t.Errorf("Unexpected broadcast %s (key %q)", message.data, message.key)
+		}
+	default:
+		t.Fatal("Expected a trending update broadcast")
+	}
+	if len(hub.broadcast) != 0 {
+		t.Error("Expected no broadcast for a missing score")
+	}
+
+	// Without a hub nothing is broadcast
+	NewEventProcessor(nil, nil, nil, nil, nil, nil, &config.Config{}).broadcastScore(&models.TrendingScore{PostID: "post-1"})
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+// EventSchemaVersion is the version of the published WS/webhook payload contract.
+// Bump it whenever a field is removed or its type changes.
+const EventSchemaVersion = "2.0.0"
+
+// eventSchemaTypes maps every message type the WebSocket hub sends to the struct that carries
+// it
+var eventSchemaTypes = []struct {
+	name        string
+	description string
+	payload     interface{}
+}{
+	{"trending_update", "Trending score update for a post", TrendingUpdateMessage{}},
+	{"viral_alert", "Post predicted to go viral", ViralAlertMessage{}},
+	{"creator_viral_alert", "One of the creator's posts reached a higher viral tier, sent to the creator only", CreatorViralAlertMessage{}},
+	{"anomaly_alert", "Post whose engagement velocity departed from its baseline", AnomalyAlertMessage{}},
+	{"dashboard_tick", "Periodic live dashboard aggregates", DashboardTickMessage{}},
+	{"post_stats", "Changed stats of a watched post", PostStatsMessage{}},
+	{"command_result", "Answer to a client command", CommandResultMessage{}},
+	{"command_error", "Why a client command failed", CommandErrorMessage{}},
+	{"replay_gap", "Broadcasts missed since the resumed seq can no longer be replayed", ReplayGapMessage{}},
+}
+
+// webhookSchemaTypes maps every webhook event to the data its deliveries carry. Their schemas
+// are keyed by "webhook." and the event.
+var webhookSchemaTypes = []struct {
+	event       string
+	description string
+	data        interface{}
+}{
+	{WebhookEventViralAlert, "A post reached a higher viral tier", models.ViralAlert{}},
+	{WebhookEventScoreThreshold, "A post's trending score crossed the webhook's threshold", ScoreThresholdData{}},
+	{WebhookEventNewTrendingEntry, "A post entered the top trending posts", TrendingEntryData{}},
+	{WebhookEventTest, "Delivery sent on request by the test endpoint", WebhookTestData{}},
+}
+
+// EventSchemas returns a JSON Schema (draft 2020-12) for every WS message and webhook payload,
+// keyed by message type
+func EventSchemas() map[string]map[string]interface{} {
+	schemas := make(map[string]map[string]interface{}, len(eventSchemaTypes)+len(webhookSchemaTypes))
+	for _, t := range eventSchemaTypes {
+		// Pin the discriminator so consumers can switch on it
+		schemas[t.name] = eventSchema(t.name, t.description, structSchema(reflect.TypeOf(t.payload)), "type", t.name)
+	}
+	for _, t := range webhookSchemaTypes {
+		schema := structSchema(reflect.TypeOf(WebhookPayload{}))
+		schema["properties"].(map[string]interface{})["data"] = typeSchema(reflect.TypeOf(t.data))
+		name := "webhook." + t.event
+		schemas[name] = eventSchema(name, t.description, schema, "event", t.event)
+	}
+	return schemas
+}
+
+// eventSchema completes the schema of a published payload, pinning its discriminator property
+// to value
+func eventSchema(name, description string, schema map[string]interface{}, discriminator, value string) map[string]interface{} {
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = fmt.Sprintf("/api/schema/events/%s/v%s", name, EventSchemaVersion)
+	schema["title"] = name
+	schema["description"] = description
+
+	if props, ok := schema["properties"].(map[string]interface{}); ok {
+		if prop, ok := props[discriminator].(map[string]interface{}); ok {
+			prop["const"] = value
+		}
+	}
+	return schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of the JSON encoding of v
+func JSONSchema(v interface{}) map[string]interface{} {
+	return typeSchema(reflect.TypeOf(v))
+}
+
+// structSchema builds an object schema from a struct's json tags. Request structs that carry
+// binding tags are described by them instead: their required fields, allowed values and sizes.
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	validated := hasBindingTags(t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		// Embedded structs are flattened into their parent, as encoding/json does
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			embedded := structSchema(field.Type)
+			for name, prop := range embedded["properties"].(map[string]interface{}) {
+				properties[name] = prop
+			}
+			required = append(required, embedded["required"].([]string)...)
+			continue
+		}
+
+		name := field.Name
+		omitEmpty := false
+		if tag := field.Tag.Get("json"); tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					omitEmpty = true
+				}
+			}
+		}
+
+		schema := typeSchema(field.Type)
+		if validated {
+			if applyBindingTag(schema, field.Tag.Get("binding")) {
+				required = append(required, name)
+			}
+		} else if !omitEmpty {
+			required = append(required, name)
+		}
+		properties[name] = schema
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// hasBindingTags reports whether any field of a struct is validated by a binding tag
+func hasBindingTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("binding"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBindingTag adds the oneof, min and max rules of a binding tag to a field's schema,
+// applying the rules after dive to the items of a list. It reports whether the field is
+// required.
+func applyBindingTag(schema map[string]interface{}, tag string) bool {
+	required, dived := false, false
+	target := schema
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = !dived
+		case "dive":
+			if items, ok := target["items"].(map[string]interface{}); ok {
+				target, dived = items, true
+			}
+		case "oneof":
+			values := []interface{}{}
+			for _, value := range strings.Fields(param) {
+				values = append(values, value)
+			}
+			target["enum"] = values
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			target[sizeKeyword(name, target["type"])] = n
+		}
+	}
+	return required
+}
+
+// sizeKeyword returns the JSON Schema keyword of a min or max rule on a value of a type:
+// its length, number of items or entries, or its value
+func sizeKeyword(rule string, schemaType interface{}) string {
+	switch schemaType {
+	case "string":
+		return rule + "Length"
+	case "array":
+		return rule + "Items"
+	case "object":
+		return rule + "Properties"
+	}
+	if rule == "min" {
+		return "minimum"
+	}
+	return "maximum"
+}
+
+// typeSchema maps a Go type to its JSON Schema representation
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEventSchemas(t *testing.T) {
+	schemas := EventSchemas()
+
+	for name, discriminator := range map[string][2]string{
+		"trending_update":         {"type", "trending_update"},
+		"anomaly_alert":           {"type", "anomaly_alert"},
+		"replay_gap":              {"type", "replay_gap"},
+		"webhook.viral_alert":     {"event", "viral_alert"},
+		"webhook.score_threshold": {"event", "score_threshold"},
+	} {
+		schema, ok := schemas[name]
+		if !ok {
+			t.Errorf("Expected schema for %s", name)
+			continue
+		}
+
+		props, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Schema %s has no properties", name)
+		}
+
+		prop, ok := props[discriminator[0]].(map[string]interface{})
+		if !ok || prop["const"] != discriminator[1] {
+			t.Errorf("Schema %s %s const = %v, want %s", name, discriminator[0], prop["const"], discriminator[1])
+		}
+	}
+
+	// Webhook schemas describe the data of their event
+	data := schemas["webhook.score_threshold"]["properties"].(map[string]interface{})["data"].(map[string]interface{})
+	if _, ok := data["properties"].(map[string]interface{})["threshold"]; !ok {
+		t.Errorf("Expected the score_threshold data in its webhook schema, got %v", data)
+	}
+}
+
+// TestEventSchemasCoverSentTypes fails when a message the package sends, or a webhook event it
+// delivers, has no schema, or when a schema describes a message that is never sent
+func TestEventSchemasCoverSentTypes(t *testing.T) {
+	sent := map[string]bool{}
+	fset := token.NewFileSet()
+	files, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatalf("Failed to list package files: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".go") || strings.HasSuffix(file.Name(), "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file.Name(), nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", file.Name(), err)
+		}
+
+		// Messages are built as <Name>Message{Type: "<type>", ...}
+		ast.Inspect(f, func(node ast.Node) bool {
+			message, ok := node.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			if ident, ok := message.Type.(*ast.Ident); !ok || !strings.HasSuffix(ident.Name, "Message") {
+				return true
+			}
+			for _, elt := range message.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Type" {
+					if lit, ok := kv.Value.(*ast.BasicLit); ok {
+						value, _ := strconv.Unquote(lit.Value)
+						sent[value] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	if len(sent) == 0 {
+		t.Fatal("Found no sent message types")
+	}
+	sent["webhook."+WebhookEventTest] = true
+	for _, event := range WebhookEvents {
+		sent["webhook."+event] = true
+	}
+	schemas := EventSchemas()
+	for name := range sent {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("Message type %s is sent but has no schema", name)
+		}
+	}
+	for name := range schemas {
+		if !sent[name] {
+			t.Errorf("Schema %s describes a message type that is never sent", name)
+		}
+	}
+}
+
+func TestStructSchemaRequiredFields(t *testing.T) {
+	schema := structSchema(reflect.TypeOf(CommandResultMessage{}))
+
+	required, ok := schema["required"].([]string)
+	if !ok {
+		t.Fatal("Expected required list")
+	}
+
+	for _, field := range required {
+		if field == "request_id" {
+			t.Error("omitempty field request_id should not be required")
+		}
+	}
+
+	props := structSchema(reflect.TypeOf(DashboardTickMessage{}))["properties"].(map[string]interface{})
+	topPosts := props["top_posts"].(map[string]interface{})
+	if topPosts["type"] != "array" {
+		t.Errorf("top_posts type = %v, want array", topPosts["type"])
+	}
+}
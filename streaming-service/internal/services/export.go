@@ -0,0 +1,274 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats analytics endpoints export their rows in besides JSON
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// Rows written between flushes of the response, so large exports reach the client as they
+// are written instead of in one piece
+const exportFlushRows = 500
+
+// ParseExportFormat parses a ?format= value. It returns "" for JSON, the default.
+func ParseExportFormat(raw string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "json":
+		return "", nil
+	case ExportCSV:
+		return ExportCSV, nil
+	case ExportXLSX:
+		return ExportXLSX, nil
+	default:
+		return "", fmt.Errorf("invalid format %q, expected json, csv or xlsx", raw)
+	}
+}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	if format == ExportXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// exportColumn is a struct field exported as a column
+type exportColumn struct {
+	name  string
+	index int
+}
+
+// exportColumns returns the columns of a struct type: its scalar, time and string slice
+// fields by json name in declaration order, restricted to fields when it is non-nil
+func exportColumns(t reflect.Type, fields FieldSet) []exportColumn {
+	index := jsonFieldIndex(t)
+	columns := make([]exportColumn, 0, len(index))
+	for name, i := range index {
+		if !fields.Has(name) || !isExportable(t.Field(i).Type) {
+			continue
+		}
+		columns = append(columns, exportColumn{name: name, index: i})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].index < columns[j].index })
+	return columns
+}
+
+// isExportable reports whether values of a type fit in one cell
+func isExportable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	default:
+		return t == timeType
+	}
+}
+
+// exportCell returns the cell value of a field: numbers stay numbers, times are RFC 3339 and
+// string slices are joined with spaces
+func exportCell(value reflect.Value) interface{} {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return value.Int()
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Slice:
+		return strings.Join(value.Interface().([]string), " ")
+	case reflect.String:
+		return value.String()
+	}
+	if t, ok := value.Interface().(time.Time); ok && !t.IsZero() {
+		return t.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// tableWriter writes the header and rows of an export
+type tableWriter interface {
+	WriteRow(cells []interface{}) error
+	Flush() error
+	Close() error
+}
+
+// WriteExport writes items, a slice of structs, as a table in an export format: a header of
+// json field names, restricted to fields when it is non-nil, then one row per item. Rows are
+// flushed to w as they are written when it is an http.Flusher. sheet names the worksheet of
+// XLSX exports.
+func WriteExport(w io.Writer, format, sheet string, items interface{}, fields FieldSet) error {
+	value := reflect.ValueOf(items)
+	if value.Kind() != reflect.Slice || value.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot export %T, expected a slice of structs", items)
+	}
+	columns := exportColumns(value.Type().Elem(), fields)
+
+	var table tableWriter
+	if format == ExportXLSX {
+		xlsx, err := newXLSXWriter(w, sheet)
+		if err != nil {
+			return err
+		}
+		table = xlsx
+	} else {
+		table = &csvWriter{writer: csv.NewWriter(w)}
+	}
+	flusher, _ := w.(http.Flusher)
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	if err := table.WriteRow(header); err != nil {
+		return err
+	}
+	for row := 0; row < value.Len(); row++ {
+		item := value.Index(row)
+		cells := make([]interface{}, len(columns))
+		for i, column := range columns {
+			cells[i] = exportCell(item.Field(column.index))
+		}
+		if err := table.WriteRow(cells); err != nil {
+			return err
+		}
+
+		if (row+1)%exportFlushRows == 0 {
+			if err := table.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	return table.Close()
+}
+
+// csvWriter writes exports as CSV
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (cw *csvWriter) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch v := cell.(type) {
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return cw.writer.Write(record)
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	return cw.Flush()
+}
+
+// Static parts of a workbook with a single worksheet
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes exports as an XLSX workbook. The worksheet is the last part of the zip
+// and is streamed row by row, with strings inline so no shared string table is kept.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	rows    int
+}
+
+func newXLSXWriter(w io.Writer, sheet string) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheet))
+	parts := []struct{ path, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+	}
+	for _, part := range parts {
+		writer, err := archive.Create(part.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(writer, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheetWriter, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheetWriter, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{archive: archive, sheet: sheetWriter}, nil
+}
+
+func (xw *xlsxWriter) WriteRow(cells []interface{}) error {
+	xw.rows++
+	var row strings.Builder
+	fmt.Fprintf(&row, `<row r="%d">`, xw.rows)
+	for _, cell := range cells {
+		switch v := cell.(type) {
+		case int64:
+			fmt.Fprintf(&row, `<c><v>%d</v></c>`, v)
+		case float64:
+			fmt.Fprintf(&row, `<c><v>%s</v></c>`, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			row.WriteString(`<c t="inlineStr"><is><t>`)
+			xml.EscapeText(&row, []byte(fmt.Sprint(v)))
+			row.WriteString(`</t></is></c>`)
+		}
+	}
+	row.WriteString(`</row>`)
+	_, err := io.WriteString(xw.sheet, row.String())
+	return err
+}
+
+func (xw *xlsxWriter) Flush() error {
+	return xw.archive.Flush()
+}
+
+func (xw *xlsxWriter) Close() error {
+	if _, err := io.WriteString(xw.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return xw.archive.Close()
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestParseExportFormat(t *testing.T) {
+	for raw, expected := range map[string]string{"": "", "json": "", "CSV": ExportCSV, "xlsx": ExportXLSX} {
+		if format, err := ParseExportFormat(raw); err != nil || format != expected {
+			t.Errorf("ParseExportFormat(%q) = %q (%v), expected %q", raw, format, err, expected)
+		}
+	}
+	if _, err := ParseExportFormat("pdf"); err == nil {
+		t.Error("Expected pdf to be rejected")
+	}
+}
+
+func TestWriteExport_CSV(t *testing.T) {
+	scores := []models.TrendingScore{
+		{PostID: "post-1", Score: 12.5, ViewCount: 300, OutputURLs: []string{"a.png", "b.png"}, Title: `Say "hi", world`},
+		{PostID: "post-2", CalculatedAt: time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)},
+	}
+	fields, _ := ParseTrendingFields("id,score,view_count,output_urls,title,calculated_at")
+
+	var out bytes.Buffer
+	if err := WriteExport(&out, ExportCSV, "trending", scores, fields); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	expected := "post_id,score,view_count,calculated_at,output_urls,title\n" +
+		"post-1,12.5,300,,a.png b.png,\"Say \"\"hi\"\", world\"\n" +
+		"post-2,0,0,2024-05-10T12:00:00Z,,\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+
+	if err := WriteExport(&out, ExportCSV, "trending", "not rows", nil); err == nil {
+		t.Error("Expected exporting a non-slice to fail")
+	}
+}
+
+func TestWriteExport_XLSX(t *testing.T) {
+	trends := []EngagementTrend{{Date: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), PostCount: 3, Views: 120}}
+
+	var out bytes.Buffer
+	if err := WriteExport(&out, ExportXLSX, "engagement-trends", trends, nil); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("Export is not a zip archive: %v", err)
+	}
+
+	parts := make(map[string]string)
+	for _, file := range archive.File {
+		reader, _ := file.Open()
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		parts[file.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if parts[name] == "" {
+			t.Errorf("Missing workbook part %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(sheet, `<row r="1"><c t="inlineStr"><is><t>date</t></is></c>`) || !strings.Contains(sheet, `<c><v>120</v></c>`) || !strings.HasSuffix(sheet, "</sheetData></worksheet>") {
+		t.Errorf("Unexpected worksheet %s", sheet)
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="engagement-trends"`) {
+		t.Errorf("Expected the sheet to be named after the report, got %s", parts["xl/workbook.xml"])
+	}
+}
+
+func TestSortedContentTypes(t *testing.T) {
+	sorted := SortedContentTypes(map[string]ContentTypeMetrics{
+		"image": {ContentType: "image", Count: 2},
+		"video": {ContentType: "video", Count: 5},
+		"music": {ContentType: "music", Count: 2},
+	})
+	if len(sorted) != 3 || sorted[0].ContentType != "video" || sorted[1].ContentType != "image" {
+		t.Errorf("Unexpected order %+v", sorted)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+// trendingFieldAliases maps short client-facing names onto TrendingScore json fields
+var trendingFieldAliases = map[string]string{
+	"id":        "post_id",
+	"thumbnail": "thumbnail_url",
+}
+
+// trendingFieldIndex maps TrendingScore json field names to their struct field index
+var trendingFieldIndex = jsonFieldIndex(reflect.TypeOf(models.TrendingScore{}))
+
+// FieldSet is the set of json fields a client asked for via ?fields=.
+// A nil FieldSet selects every field.
+type FieldSet map[string]bool
+
+// ParseTrendingFields parses a comma separated ?fields= value for trending responses.
+// An empty value selects every field.
+func ParseTrendingFields(raw string) (FieldSet, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	fields := make(FieldSet)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if alias, ok := trendingFieldAliases[name]; ok {
+			name = alias
+		}
+		if _, ok := trendingFieldIndex[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q, valid fields: %s", name, strings.Join(TrendingFieldNames(), ", "))
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// ParseFields parses a comma separated ?fields= value for any JSON response. Names are not
+// checked, since the fields of an item depend on the response; the trending aliases id and
+// thumbnail select post_id and thumbnail_url as well. An empty value selects every field.
+func ParseFields(raw string) FieldSet {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	fields := make(FieldSet)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		fields[name] = true
+		if alias, ok := trendingFieldAliases[name]; ok {
+			fields[alias] = true
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// ProjectJSON reduces the data of an API response body to the requested fields: the fields of
+// each object when data is a list, the fields of data itself when it is an object. Field names
+// are matched case-insensitively, so camelCase fields are selected by their lowercase name.
+// Bodies without data are returned unchanged.
+func ProjectJSON(body []byte, fields FieldSet) ([]byte, error) {
+	if fields == nil {
+		return body, nil
+	}
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	raw, ok := response["data"]
+	if !ok {
+		return body, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber() // keep numbers exactly as the handler wrote them
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	switch value := data.(type) {
+	case []interface{}:
+		for i, item := range value {
+			if object, ok := item.(map[string]interface{}); ok {
+				value[i] = projectObject(object, fields)
+			}
+		}
+	case map[string]interface{}:
+		data = projectObject(value, fields)
+	default:
+		return body, nil
+	}
+
+	projected, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	response["data"] = projected
+	return json.Marshal(response)
+}
+
+func projectObject(object map[string]interface{}, fields FieldSet) map[string]interface{} {
+	projected := make(map[string]interface{}, len(fields))
+	for name, value := range object {
+		if fields[strings.ToLower(name)] {
+			projected[name] = value
+		}
+	}
+	return projected
+}
+
+// TrendingFieldNames returns the selectable trending fields in sorted order
+func TrendingFieldNames() []string {
+	names := make([]string, 0, len(trendingFieldIndex))
+	for name := range trendingFieldIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a field was requested
+func (fs FieldSet) Has(name string) bool {
+	return fs == nil || fs[name]
+}
+
+// ProjectTrendingScores reduces scores to the requested fields. With a nil FieldSet the
+// scores are returned unchanged so the full response shape is preserved.
+func ProjectTrendingScores(scores []models.TrendingScore, fields FieldSet) []interface{} {
+	projected := make([]interface{}, len(scores))
+	for i, score := range scores {
+		if fields == nil {
+			projected[i] = score
+			continue
+		}
+
+		value := reflect.ValueOf(score)
+		item := make(map[string]interface{}, len(fields))
+		for name := range fields {
+			item[name] = value.Field(trendingFieldIndex[name]).Interface()
+		}
+		// Manual overrides stay flagged whatever fields are selected
+		if score.Override != nil {
+			item["override"] = score.Override
+		}
+		projected[i] = item
+	}
+	return projected
+}
+
+// jsonFieldIndex maps the json names of a struct's exported fields to their index
+func jsonFieldIndex(t reflect.Type) map[string]int {
+	index := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		index[name] = i
+	}
+	return index
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestParseTrendingFields(t *testing.T) {
+	fields, err := ParseTrendingFields("id, score ,output_urls,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(fields) != 3 || !fields.Has("post_id") || !fields.Has("score") || fields.Has("title") {
+		t.Errorf("Unexpected field set: %v", fields)
+	}
+
+	if fields, err := ParseTrendingFields(""); err != nil || fields != nil || !fields.Has("title") {
+		t.Errorf("Expected empty fields to select everything, got %v, %v", fields, err)
+	}
+
+	if _, err := ParseTrendingFields("score,bogus"); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
+
+func TestProjectTrendingScores(t *testing.T) {
+	scores := []models.TrendingScore{{PostID: "p1", Score: 42, Title: "hello"}}
+
+	fields, _ := ParseTrendingFields("id,score")
+	projected := ProjectTrendingScores(scores, fields)
+
+	item, ok := projected[0].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected projected map, got %T", projected[0])
+	}
+	if len(item) != 2 || item["post_id"] != "p1" || item["score"] != 42.0 {
+		t.Errorf("Unexpected projection: %v", item)
+	}
+
+	if _, ok := ProjectTrendingScores(scores, nil)[0].(models.TrendingScore); !ok {
+		t.Error("Expected full scores without field selection")
+	}
+}
+
+func TestEnrichTrendingScoreSkipsUnrequestedFields(t *testing.T) {
+	postData := map[string]interface{}{
+		"contentType": "image",
+		"outputUrls":  []interface{}{"a.png", "b.png"},
+		"title":       "Sunset",
+	}
+
+	fields, _ := ParseTrendingFields("id,title")
+	var score models.TrendingScore
+	if count := enrichTrendingScore(&score, postData, fields); count != 2 {
+		t.Errorf("URL count = %d, want 2", count)
+	}
+	if score.OutputURLs != nil || score.Title != "Sunset" || score.ContentType != "image" {
+		t.Errorf("Unexpected enrichment: %+v", score)
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	fields := ParseFields(" post_id,Score, thumbnail,")
+	if len(fields) != 4 || !fields["post_id"] || !fields["score"] || !fields["thumbnail"] || !fields["thumbnail_url"] {
+		t.Errorf("Unexpected field set: %v", fields)
+	}
+	if fields := ParseFields(" , "); fields != nil {
+		t.Errorf("Expected no field selection, got %v", fields)
+	}
+}
+
+func TestProjectJSON(t *testing.T) {
+	fields := ParseFields("id,score,creatorid")
+
+	list := []byte(`{"status":"success","count":2,"data":[{"post_id":"p1","score":12345678901234567,"title":"a"},{"postId":"p2","creatorId":"c1","views":3}]}`)
+	projected, err := ProjectJSON(list, fields)
+	if err != nil {
+		t.Fatalf("ProjectJSON failed: %v", err)
+	}
+	want := `{"count":2,"data":[{"post_id":"p1","score":12345678901234567},{"creatorId":"c1"}],"status":"success"}`
+	if string(projected) != want {
+		t.Errorf("ProjectJSON() = %s, want %s", projected, want)
+	}
+
+	object := []byte(`{"status":"success","data":{"score":1.5,"views":10}}`)
+	if projected, err := ProjectJSON(object, fields); err != nil || string(projected) != `{"data":{"score":1.5},"status":"success"}` {
+		t.Errorf("Unexpected projection of an object: %s, %v", projected, err)
+	}
+
+	noData := []byte(`{"status":"indexing started"}`)
+	if projected, err := ProjectJSON(noData, fields); err != nil || string(projected) != string(noData) {
+		t.Errorf("Expected a body without data unchanged, got %s, %v", projected, err)
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Google's public certificates for Firebase ID token signatures, keyed by key ID
+const firebaseCertsURL = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+
+const (
+	// How long fetched certificates are used when the response does not say
+	defaultFirebaseCertsTTL = time.Hour
+
+	// Clock skew tolerated on token timestamps
+	firebaseTokenLeeway = 5 * time.Minute
+)
+
+var maxAgePattern = regexp.MustCompile(`max-age=(\d+)`)
+
+// FirebaseTokenVerifier verifies Firebase ID tokens, the RS256-signed JWTs Firebase
+// Authentication issues to signed-in users, against Google's rotating public certificates
+type FirebaseTokenVerifier struct {
+	projectID  string
+	certsURL   string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	expiresAt time.Time
+}
+
+func NewFirebaseTokenVerifier(projectID string) *FirebaseTokenVerifier {
+	return &FirebaseTokenVerifier{
+		projectID:  projectID,
+		certsURL:   firebaseCertsURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// firebaseClaims are the ID token claims the verifier checks
+type firebaseClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// VerifyIDToken checks the signature and claims of an ID token and returns the user ID it
+// was issued to
+func (v *FirebaseTokenVerifier) VerifyIDToken(token string) (string, error) {
+	keys, err := v.publicKeys()
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := verifyFirebaseToken(token, keys, v.projectID, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+// publicKeys returns the signing keys, fetching them again once the cached set expires
+func (v *FirebaseTokenVerifier) publicKeys() (map[string]*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.keys != nil && time.Now().Before(v.expiresAt) {
+		return v.keys, nil
+	}
+
+	resp, err := v.httpClient.Get(v.certsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Firebase certificates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch Firebase certificates: status %d", resp.StatusCode)
+	}
+
+	var certs map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+		return nil, fmt.Errorf("invalid Firebase certificates: %w", err)
+	}
+	keys, err := parseFirebaseCerts(certs)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := defaultFirebaseCertsTTL
+	if match := maxAgePattern.FindStringSubmatch(resp.Header.Get("Cache-Control")); match != nil {
+		if seconds, err := strconv.Atoi(match[1]); err == nil {
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+	v.keys = keys
+	v.expiresAt = time.Now().Add(ttl)
+	return keys, nil
+}
+
+// parseFirebaseCerts extracts the RSA public keys from PEM certificates keyed by key ID
+func parseFirebaseCerts(certs map[string]string) (map[string]*rsa.PublicKey, error) {
+	keys := make(map[string]*rsa.PublicKey, len(certs))
+	for kid, certPEM := range certs {
+		block, _ := pem.Decode([]byte(certPEM))
+		if block == nil {
+			return nil, fmt.Errorf("invalid Firebase certificate %s", kid)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Firebase certificate %s: %w", kid, err)
+		}
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("certificate %s has no RSA key", kid)
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
+
+// verifyFirebaseToken checks an ID token's RS256 signature against the key it names and its
+// audience, issuer, subject and lifetime
+func verifyFirebaseToken(token string, keys map[string]*rsa.PublicKey, projectID string, now time.Time) (*firebaseClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("unexpected signing algorithm %q", header.Algorithm)
+	}
+	key, ok := keys[header.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", header.KeyID)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	var claims firebaseClaims
+	if err := decodeTokenPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	switch {
+	case claims.Audience != projectID:
+		return nil, fmt.Errorf("token issued for project %q", claims.Audience)
+	case claims.Issuer != "https://securetoken.google.com/"+projectID:
+		return nil, fmt.Errorf("unexpected token issuer %q", claims.Issuer)
+	case claims.Subject == "" || len(claims.Subject) > 128:
+		return nil, fmt.Errorf("invalid token subject")
+	case now.Add(-firebaseTokenLeeway).After(time.Unix(claims.ExpiresAt, 0)):
+		return nil, fmt.Errorf("token expired")
+	case now.Add(firebaseTokenLeeway).Before(time.Unix(claims.IssuedAt, 0)):
+		return nil, fmt.Errorf("token issued in the future")
+	}
+	return &claims, nil
+}
+
+func decodeTokenPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
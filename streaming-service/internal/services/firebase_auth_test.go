@@ -0,0 +1,83 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signTestToken builds an RS256 JWT with the given key ID and claims
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode token part: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyFirebaseToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keys := map[string]*rsa.PublicKey{"key-1": &key.PublicKey}
+	now := time.Now()
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss": "https://securetoken.google.com/yarimai",
+			"aud": "yarimai",
+			"sub": "user-42",
+			"iat": now.Add(-time.Minute).Unix(),
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	verified, err := verifyFirebaseToken(signTestToken(t, key, "key-1", claims(nil)), keys, "yarimai", now)
+	if err != nil || verified.Subject != "user-42" {
+		t.Fatalf("expected a valid token for user-42, got %+v, %v", verified, err)
+	}
+
+	valid := signTestToken(t, key, "key-1", claims(nil))
+	parts := strings.Split(valid, ".")
+	rejected := map[string]string{
+		"wrong project": signTestToken(t, key, "key-1", claims(map[string]interface{}{"aud": "other"})),
+		"wrong issuer":  signTestToken(t, key, "key-1", claims(map[string]interface{}{"iss": "https://example.com"})),
+		"no subject":    signTestToken(t, key, "key-1", claims(map[string]interface{}{"sub": ""})),
+		"expired":       signTestToken(t, key, "key-1", claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})),
+		"unknown key":   signTestToken(t, key, "key-2", claims(nil)),
+		"wrong key":     signTestToken(t, otherKey, "key-1", claims(nil)),
+		"tampered":      parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`)) + "." + parts[2],
+		"not a jwt":     "abc",
+	}
+	for name, token := range rejected {
+		if _, err := verifyFirebaseToken(token, keys, "yarimai", now); err == nil {
+			t.Errorf("expected %s token to be rejected", name)
+		}
+	}
+}
@@ -2,19 +2,65 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
 	"confluent-viral-intelligence/internal/models"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// maxScoreWriteAttempts bounds how often a conflicting trending score write is retried
+const maxScoreWriteAttempts = 5
+
 type FirestoreClient struct {
 	client *firestore.Client
 	ctx    context.Context
+	audit  *postAuditor
+
+	// Multiplier for near-duplicate posts' scores in the trending feeds
+	duplicateWeight float64
+
+	// Share of the trending feeds one creator or content type may take
+	diversity TrendingDiversity
+
+	// Time zone of the calendar days daily rollups cover
+	reportingLoc *time.Location
+
+	// Trending score formula shared by every code path that writes scores
+	scoring *ScoringEngine
+
+	// Hourly history of the scores written, nil when not kept
+	history *ScoreHistory
+
+	// Category rankings the scores written are copied into, nil when not kept
+	scopes *ScopedTrending
+
+	// Updater recalculating the posts whose scores were written, nil when not running
+	updater *TrendingUpdater
+
+	// Engagement posts need for the trending feeds and viral alerts
+	floor *EngagementFloor
+
+	// How long a viewed post stays out of the viewer's recommendations, 0 when views are not
+	// tracked
+	seenTTL time.Duration
+
+	// How long feedback on recommendations re-ranks them, 0 when feedback is ignored
+	feedbackTTL time.Duration
+
+	// How long a stored recommendation is served, 0 when recommendations do not expire; the
+	// share of each response filled with trending posts; and how recently a trending post's
+	// score must have been updated to count as recent
+	recommendationTTL time.Duration
+	trendingBlend     float64
+	trendingRecent    time.Duration
 }
 
 func NewFirestoreClient(ctx context.Context, cfg *config.Config) (*FirestoreClient, error) {
@@ -23,34 +69,65 @@ func NewFirestoreClient(ctx context.Context, cfg *config.Config) (*FirestoreClie
 		return nil, fmt.Errorf("failed to create firestore client: %w", err)
 	}
 
-	return &FirestoreClient{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	// The reporting zone was validated at startup
+	reportingLoc, _ := cfg.ReportingLocation()
+
+	fc := &FirestoreClient{
+		client:            client,
+		ctx:               ctx,
+		audit:             newPostAuditor(),
+		duplicateWeight:   cfg.DuplicateTrendingWeight,
+		diversity:         TrendingDiversityFrom(cfg),
+		reportingLoc:      reportingLoc,
+		seenTTL:           time.Duration(cfg.SeenPostsTTLHours) * time.Hour,
+		feedbackTTL:       time.Duration(cfg.RecommendationFeedbackTTLDays) * 24 * time.Hour,
+		recommendationTTL: time.Duration(cfg.RecommendationTTLHours) * time.Hour,
+		trendingBlend:     cfg.RecommendationTrendingBlend,
+		trendingRecent:    time.Duration(cfg.RecommendationTrendingRecentHours) * time.Hour,
+	}
+	fc.scoring = NewScoringEngine(ScoringConfigFrom(cfg), fc, time.Duration(cfg.ScoringConfigReloadSeconds)*time.Second)
+	fc.floor = EngagementFloorFrom(fc, cfg)
+	return fc, nil
+}
+
+// EngagementFloor returns the engagement posts need for the trending feeds and viral alerts
+func (fc *FirestoreClient) EngagementFloor() *EngagementFloor {
+	return fc.floor
+}
+
+// Scoring returns the trending score formula
+func (fc *FirestoreClient) Scoring() *ScoringEngine {
+	return fc.scoring
 }
 
 // SaveTrendingScore saves trending score to Firestore
 func (fc *FirestoreClient) SaveTrendingScore(score models.TrendingScore) error {
+	Quotas.Record(QuotaFirestore, 1)
 	_, err := fc.client.Collection("trending_scores").Doc(score.PostID).Set(fc.ctx, score)
 	return err
 }
 
-// SaveRecommendation saves recommendation to Firestore
+// SaveRecommendation saves recommendation to Firestore, expiring after the recommendation TTL
+// unless it carries its own expiry
 func (fc *FirestoreClient) SaveRecommendation(rec models.Recommendation) error {
 	_, err := fc.client.Collection("recommendations").
 		Doc(rec.UserID).
 		Collection("items").
 		Doc(rec.PostID).
-		Set(fc.ctx, rec)
+		Set(fc.ctx, fc.withExpiry(rec))
 	return err
 }
 
-// UpdateContentMetadata updates content with keywords and category
-func (fc *FirestoreClient) UpdateContentMetadata(postID string, keywords []string, category, style string) error {
+// UpdateContentMetadata updates content with the extracted keywords, hashtags, language and category
+func (fc *FirestoreClient) UpdateContentMetadata(postID string, extracted *models.KeywordExtractionResponse) error {
+	Quotas.Record(QuotaFirestore, 1)
 	_, err := fc.client.Collection("posts").Doc(postID).Update(fc.ctx, []firestore.Update{
-		{Path: "keywords", Value: keywords},
-		{Path: "category", Value: category},
-		{Path: "style", Value: style},
+		{Path: "keywords", Value: extracted.Keywords},
+		{Path: "english_keywords", Value: extracted.EnglishKeywords},
+		{Path: "hashtags", Value: extracted.Hashtags},
+		{Path: "language", Value: extracted.Language},
+		{Path: "category", Value: extracted.Category},
+		{Path: "style", Value: extracted.Style},
 		{Path: "updated_at", Value: time.Now()},
 	})
 	return err
@@ -58,6 +135,7 @@ func (fc *FirestoreClient) UpdateContentMetadata(postID string, keywords []strin
 
 // IncrementViewCount increments view count for a post
 func (fc *FirestoreClient) IncrementViewCount(postID string) error {
+	Quotas.Record(QuotaFirestore, 1)
 	_, err := fc.client.Collection("posts").Doc(postID).Update(fc.ctx, []firestore.Update{
 		{Path: "view_count", Value: firestore.Increment(1)},
 		{Path: "last_viewed_at", Value: time.Now()},
@@ -105,9 +183,28 @@ func (fc *FirestoreClient) GetTrendingPosts(limit int) ([]models.TrendingScore,
 	return scores, nil
 }
 
-// GetPostStats retrieves statistics for a specific post
+// IsNotFound reports whether err is Firestore's answer for a document that does not exist
+func IsNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+// IsUnavailable reports whether err means Firestore could not be reached or did not answer in
+// time, rather than that the read itself failed
+func IsUnavailable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// GetPostStats retrieves statistics for a specific post, or nil if the post has no trending
+// score yet
 func (fc *FirestoreClient) GetPostStats(postID string) (*models.TrendingScore, error) {
 	doc, err := fc.client.Collection("trending_scores").Doc(postID).Get(fc.ctx)
+	if IsNotFound(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -116,19 +213,24 @@ func (fc *FirestoreClient) GetPostStats(postID string) (*models.TrendingScore, e
 	if err := doc.DataTo(&score); err != nil {
 		return nil, err
 	}
+	score.RelativeCompletion = relativeCompletion(score)
+	score.AvgWatchSeconds = avgWatchSeconds(score)
 
 	return &score, nil
 }
 
-// GetUserRecommendations retrieves recommendations for a user
+// GetUserRecommendations retrieves the unexpired recommendations for a user, without the posts
+// the user recently viewed or created, re-ranked by the user's feedback on their
+// recommendations, with a share of recent trending posts blended in
 func (fc *FirestoreClient) GetUserRecommendations(userID string, limit int) ([]models.Recommendation, error) {
 	iter := fc.client.Collection("recommendations").
 		Doc(userID).
 		Collection("items").
-		OrderBy("score", firestore.Desc).
-		Limit(limit).
+		OrderBy("Score", firestore.Desc).
+		Limit(limit * recommendationHeadroom).
 		Documents(fc.ctx)
 
+	now := time.Now()
 	var recs []models.Recommendation
 	for {
 		doc, err := iter.Next()
@@ -143,14 +245,42 @@ func (fc *FirestoreClient) GetUserRecommendations(userID string, limit int) ([]m
 		if err := doc.DataTo(&rec); err != nil {
 			continue
 		}
+		// Expired recommendations are hidden until the sweep reaches them
+		if fc.recommendationExpired(rec, now) {
+			continue
+		}
 		recs = append(recs, rec)
 	}
 
-	return recs, nil
+	// Recent trending posts the user is not recommended already, filtered alongside
+	var trending []models.Recommendation
+	if fc.trendingBlend > 0 {
+		candidates, err := fc.RecentTrendingRecommendations(userID, limit*recommendationHeadroom, now)
+		if err != nil {
+			logger.Warnf("Serving the recommendations of user %s without trending posts: %v", userID, err)
+		}
+		trending = withoutRecommended(candidates, recs)
+	}
+
+	all := append(recs, trending...)
+	recommendations := map[string][]models.Recommendation{userID: all}
+	owners, err := fc.excludeSeenAndOwn(recommendations, len(all))
+	if err != nil {
+		return nil, err
+	}
+	feedback, err := fc.RecommendationFeedback(userID)
+	if err != nil {
+		return nil, err
+	}
+	personal, trending := splitTrending(recommendations[userID])
+	personal = rerankWithFeedback(personal, feedback, owners)
+	trending = rerankWithFeedback(trending, feedback, owners)
+	return blendTrending(personal, trending, limit, fc.trendingBlend), nil
 }
 
 // TrackRemixChain tracks remix relationships
 func (fc *FirestoreClient) TrackRemixChain(originalPostID, remixPostID string) error {
+	Quotas.Record(QuotaFirestore, 1)
 	_, err := fc.client.Collection("remix_chains").Doc(originalPostID).Collection("remixes").Doc(remixPostID).Set(fc.ctx, map[string]interface{}{
 		"remix_post_id": remixPostID,
 		"created_at":    time.Now(),
@@ -158,25 +288,80 @@ func (fc *FirestoreClient) TrackRemixChain(originalPostID, remixPostID string) e
 	return err
 }
 
-// GetRemixCount gets the number of remixes for a post
+// GetRemixCount gets the number of remixes for a post, including archived remixes. The hot
+// remixes and the archive summary are read in one read-only transaction, so remixes being
+// archived are never counted twice or missed.
 func (fc *FirestoreClient) GetRemixCount(postID string) (int, error) {
-	iter := fc.client.Collection("remix_chains").Doc(postID).Collection("remixes").Documents(fc.ctx)
 	count := 0
-	for {
-		_, err := iter.Next()
-		if err == iterator.Done {
-			break
+	err := fc.client.RunTransaction(fc.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		count = 0
+		refs := tx.DocumentRefs(fc.client.Collection("remix_chains").Doc(postID).Collection("remixes"))
+		for {
+			_, err := refs.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			count++
+		}
+
+		doc, err := tx.Get(fc.client.Collection("remix_chain_summaries").Doc(postID))
+		if status.Code(err) == codes.NotFound {
+			return nil
 		}
 		if err != nil {
-			return 0, err
+			return err
+		}
+		var summary models.RemixChainSummary
+		if err := doc.DataTo(&summary); err != nil {
+			return err
 		}
-		count++
+		count += int(summary.RemixCount)
+		return nil
+	}, firestore.ReadOnly)
+	if err != nil {
+		return 0, err
 	}
 	return count, nil
 }
 
-// UpdatePostAnalytics updates post analytics based on interaction type
-func (fc *FirestoreClient) UpdatePostAnalytics(postID string, eventType string) error {
+// GetRemixChainSummary returns the archived summary of a remix chain, or nil if none exists
+func (fc *FirestoreClient) GetRemixChainSummary(originalPostID string) (*models.RemixChainSummary, error) {
+	doc, err := fc.client.Collection("remix_chain_summaries").Doc(originalPostID).Get(fc.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var summary models.RemixChainSummary
+	if err := doc.DataTo(&summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// UpdatePostAnalytics updates post analytics based on interaction type and returns the updated
+// trending score, or nil when the interaction type has no counter or the score could not be
+// updated
+func (fc *FirestoreClient) UpdatePostAnalytics(postID string, eventType string, requestID string) (*models.TrendingScore, error) {
+	counted, err := fc.IncrementPostCounter(postID, eventType)
+	
+	// Also update or create trending score
+	if err == nil && counted {
+		score, _ := fc.UpdateTrendingScoreFromInteraction(postID, eventType, requestID)
+		return score, nil
+	}
+	
+	return nil, err
+}
+
+// IncrementPostCounter increments the post's counter for an interaction type, reporting
+// whether the type has a counter at all
+func (fc *FirestoreClient) IncrementPostCounter(postID string, eventType string) (bool, error) {
 	var field string
 	switch eventType {
 	case "like":
@@ -186,82 +371,50 @@ func (fc *FirestoreClient) UpdatePostAnalytics(postID string, eventType string)
 	case "share":
 		field = "share_count"
 	default:
-		return nil
+		return false, nil
 	}
 
 	// Update the post document
+	Quotas.Record(QuotaFirestore, 1)
 	_, err := fc.client.Collection("posts").Doc(postID).Update(fc.ctx, []firestore.Update{
 		{Path: field, Value: firestore.Increment(1)},
 		{Path: "updated_at", Value: time.Now()},
 	})
-	
-	// Also update or create trending score
-	if err == nil {
-		fc.UpdateTrendingScoreFromInteraction(postID, eventType)
-	}
-	
-	return err
+	return true, err
 }
 
-// UpdateTrendingScoreFromView updates trending score when a view occurs
-func (fc *FirestoreClient) UpdateTrendingScoreFromView(postID string) error {
-	scoreRef := fc.client.Collection("trending_scores").Doc(postID)
-	
-	// Get or create the score document
-	doc, err := scoreRef.Get(fc.ctx)
-	if err != nil {
-		// Create new score document
-		score := models.TrendingScore{
-			PostID:       postID,
-			ViewCount:    1,
-			Score:        0.1,
-			CalculatedAt: time.Now(),
-		}
-		_, err = scoreRef.Set(fc.ctx, score)
-		return err
-	}
-	
-	// Update existing score
-	var score models.TrendingScore
-	doc.DataTo(&score)
-	
-	score.ViewCount++
-	score.Score = fc.calculateScore(score)
-	score.CalculatedAt = time.Now()
-	
-	_, err = scoreRef.Set(fc.ctx, score)
-	return err
+// UpdateTrendingScoreFromView updates trending score when a view occurs; requestID is the
+// API request that ingested the view
+func (fc *FirestoreClient) UpdateTrendingScoreFromView(postID string, requestID string) (*models.TrendingScore, error) {
+	return fc.ApplyTrendingScore(postID, "view", func(score *models.TrendingScore, exists bool) {
+		score.LastRequestID = requestID
+		score.ViewCount++
+		fc.rescore(score, exists)
+	})
 }
 
-// UpdateTrendingScoreFromInteraction updates trending score when an interaction occurs
-func (fc *FirestoreClient) UpdateTrendingScoreFromInteraction(postID string, eventType string) error {
-	scoreRef := fc.client.Collection("trending_scores").Doc(postID)
-	
-	// Get or create the score document
-	doc, err := scoreRef.Get(fc.ctx)
-	if err != nil {
-		// Create new score document
-		score := models.TrendingScore{
-			PostID:       postID,
-			Score:        1.0,
-			CalculatedAt: time.Now(),
-		}
-		switch eventType {
-		case "like":
-			score.LikeCount = 1
-		case "comment":
-			score.CommentCount = 1
-		case "share":
-			score.ShareCount = 1
-		}
-		_, err = scoreRef.Set(fc.ctx, score)
-		return err
-	}
-	
-	// Update existing score
-	var score models.TrendingScore
-	doc.DataTo(&score)
-	
+// UpdateTrendingScoreFromInteraction updates trending score when an interaction occurs;
+// requestID is the API request that ingested the interaction
+func (fc *FirestoreClient) UpdateTrendingScoreFromInteraction(postID string, eventType string, requestID string) (*models.TrendingScore, error) {
+	return fc.ApplyTrendingScore(postID, "interaction:"+eventType, func(score *models.TrendingScore, exists bool) {
+		score.LastRequestID = requestID
+		countInteraction(score, eventType)
+		fc.rescore(score, exists)
+	})
+}
+
+// UpdateTrendingScoreFromRemix updates trending score when a remix occurs; requestID is the
+// API request that ingested the remix
+func (fc *FirestoreClient) UpdateTrendingScoreFromRemix(postID string, requestID string) (*models.TrendingScore, error) {
+	return fc.ApplyTrendingScore(postID, "remix", func(score *models.TrendingScore, exists bool) {
+		score.LastRequestID = requestID
+		score.RemixCount++
+		fc.rescore(score, exists)
+	})
+}
+
+// countInteraction adds an interaction to the matching counter of a score
+func countInteraction(score *models.TrendingScore, eventType string) {
 	switch eventType {
 	case "like":
 		score.LikeCount++
@@ -270,81 +423,128 @@ func (fc *FirestoreClient) UpdateTrendingScoreFromInteraction(postID string, eve
 	case "share":
 		score.ShareCount++
 	}
+}
+
+// PostCreatedAt returns the creation time of a post, or fallback when it cannot be read or the
+// post has none
+func (fc *FirestoreClient) PostCreatedAt(postID string, fallback time.Time) time.Time {
+	Quotas.Record(QuotaFirestore, 1)
+	postDoc, err := fc.client.Collection("posts").Doc(postID).Get(fc.ctx)
+	if err != nil {
+		return fallback
+	}
 	
-	score.Score = fc.calculateScore(score)
-	score.CalculatedAt = time.Now()
+	var postData map[string]interface{}
+	if err := postDoc.DataTo(&postData); err != nil {
+		return fallback
+	}
 	
-	_, err = scoreRef.Set(fc.ctx, score)
-	return err
+	if createdAt, ok := decayAnchor(postID, postData); ok {
+		return createdAt
+	}
+	return fallback
 }
 
-// UpdateTrendingScoreFromRemix updates trending score when a remix occurs
-func (fc *FirestoreClient) UpdateTrendingScoreFromRemix(postID string) error {
-	scoreRef := fc.client.Collection("trending_scores").Doc(postID)
-	
-	// Get or create the score document
-	doc, err := scoreRef.Get(fc.ctx)
+// PostOwner returns the user ID of a post's creator
+func (fc *FirestoreClient) PostOwner(postID string) (string, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	postDoc, err := fc.client.Collection("posts").Doc(postID).Get(fc.ctx)
 	if err != nil {
-		// Create new score document
-		score := models.TrendingScore{
-			PostID:       postID,
-			RemixCount:   1,
-			Score:        2.0,
-			CalculatedAt: time.Now(),
+		return "", err
+	}
+	owner, _ := postDoc.Data()["userId"].(string)
+	return owner, nil
+}
+
+// ApplyTrendingScore reads the trending score of a post, applies mutate and writes it back
+// inside a transaction guarded by the document version. When another writer (consumer,
+// TrendingUpdater or PostIndexer) commits first, the transaction is retried against the fresh
+// document so mutate re-applies its change on top of theirs instead of overwriting it.
+// source names the writer in the post's audit trail.
+func (fc *FirestoreClient) ApplyTrendingScore(postID, source string, mutate func(score *models.TrendingScore, exists bool)) (*models.TrendingScore, error) {
+	scoreRef := fc.client.Collection("trending_scores").Doc(postID)
+
+	var previous, result models.TrendingScore
+	err := fc.client.RunTransaction(fc.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// One read and one write per attempt
+		Quotas.Record(QuotaFirestore, 2)
+
+		score := models.TrendingScore{PostID: postID}
+		exists := false
+
+		doc, err := tx.Get(scoreRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(&score); err != nil {
+				return fmt.Errorf("failed to parse trending score %s: %w", postID, err)
+			}
+			exists = true
 		}
-		_, err = scoreRef.Set(fc.ctx, score)
-		return err
+
+		previous = score
+		expectedVersion := score.Version
+		mutate(&score, exists)
+
+		score.PostID = postID
+		score.Version = expectedVersion + 1
+		score.UpdatedAt = time.Now()
+
+		result = score
+		return tx.Set(scoreRef, score)
+	}, firestore.MaxAttempts(maxScoreWriteAttempts))
+	if err != nil {
+		return nil, fmt.Errorf("versioned write of trending score %s failed: %w", postID, err)
 	}
-	
-	// Update existing score
-	var score models.TrendingScore
-	doc.DataTo(&score)
-	
-	score.RemixCount++
-	score.Score = fc.calculateScore(score)
-	score.CalculatedAt = time.Now()
-	
-	_, err = scoreRef.Set(fc.ctx, score)
-	return err
+	fc.history.Record(&previous, &result, result.UpdatedAt)
+	fc.scopes.RecordScore(&result)
+	if source != trendingUpdaterSource {
+		fc.updater.MarkDirty(postID)
+	}
+
+	fc.RecordAudit(postID, AuditKindScore, source, map[string]interface{}{
+		"previous_score": previous.Score,
+		"score":          result.Score,
+		"version":        result.Version,
+		"view_count":     result.ViewCount,
+		"like_count":     result.LikeCount,
+		"comment_count":  result.CommentCount,
+		"share_count":    result.ShareCount,
+		"remix_count":    result.RemixCount,
+	})
+
+	return &result, nil
 }
 
-// calculateScore calculates trending score based on engagement metrics with time decay
-func (fc *FirestoreClient) calculateScore(score models.TrendingScore) float64 {
-	// Calculate hours since the score was calculated (post age)
-	hoursSinceCalculation := time.Since(score.CalculatedAt).Hours()
-	
-	// Avoid division by zero for very new posts
-	if hoursSinceCalculation < 0.1 {
-		hoursSinceCalculation = 0.1
+// mergeScoreCounts folds engagement counts from an external source (posts collection, Flink)
+// into the stored score. Counts only ever grow, so the larger value wins and increments made
+// concurrently by the consumer are never lost.
+func mergeScoreCounts(dst *models.TrendingScore, src models.TrendingScore) {
+	dst.ViewCount = max64(dst.ViewCount, src.ViewCount)
+	dst.LikeCount = max64(dst.LikeCount, src.LikeCount)
+	dst.CommentCount = max64(dst.CommentCount, src.CommentCount)
+	dst.ShareCount = max64(dst.ShareCount, src.ShareCount)
+	dst.RemixCount = max64(dst.RemixCount, src.RemixCount)
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
 	}
-	
-	// Weighted scoring algorithm
-	// Views: 0.1, Likes: 1.0, Comments: 2.0, Shares: 3.0, Remixes: 5.0
-	baseScore := float64(score.ViewCount)*0.1 +
-		float64(score.LikeCount)*1.0 +
-		float64(score.CommentCount)*2.0 +
-		float64(score.ShareCount)*3.0 +
-		float64(score.RemixCount)*5.0
-	
-	// Calculate engagement velocity (engagement per hour)
-	totalEngagement := float64(score.LikeCount + score.CommentCount + score.ShareCount + score.RemixCount)
-	engagementVelocity := totalEngagement / hoursSinceCalculation
-	
-	// Apply time decay factor (exponential decay)
-	// λ = 0.02 gives half-life of ~35 hours
-	lambda := 0.02
-	timeDecayFactor := 1.0 / (1.0 + lambda*hoursSinceCalculation)
-	
-	// Recency bonus for new posts (last 24 hours)
-	recencyBonus := 0.0
-	if hoursSinceCalculation < 24 {
-		recencyBonus = 10.0 * (1.0 - hoursSinceCalculation/24.0)
+	return b
+}
+
+// rescore recalculates a score after one of its counts changed, decaying from when it was
+// last calculated
+func (fc *FirestoreClient) rescore(score *models.TrendingScore, exists bool) {
+	now := time.Now()
+	anchor := score.CalculatedAt
+	if !exists {
+		anchor = now
 	}
-	
-	// Calculate final trending score with time decay and velocity
-	trendingScore := (baseScore * timeDecayFactor) + (engagementVelocity * 5.0) + recencyBonus
-	
-	return trendingScore
+	fc.scoring.Apply(score, anchor)
+	score.CalculatedAt = now
 }
 
 func (fc *FirestoreClient) Close() error {
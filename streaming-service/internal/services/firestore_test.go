@@ -2,11 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"confluent-viral-intelligence/internal/config"
 	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // TestFirestoreOperations tests all Firestore operations
@@ -69,8 +73,14 @@ func TestFirestoreOperations(t *testing.T) {
 
 	// Test UpdateContentMetadata
 	t.Run("UpdateContentMetadata", func(t *testing.T) {
-		keywords := []string{"abstract", "colorful", "modern"}
-		err := client.UpdateContentMetadata("test-post-1", keywords, "art", "abstract")
+		err := client.UpdateContentMetadata("test-post-1", &models.KeywordExtractionResponse{
+			Keywords:        []string{"abstract", "colorful", "modern"},
+			EnglishKeywords: []string{"abstract", "colorful", "modern"},
+			Hashtags:        []string{"abstractart"},
+			Language:        "en",
+			Category:        "art",
+			Style:           "abstract",
+		})
 		if err != nil {
 			t.Logf("UpdateContentMetadata failed (expected if post doesn't exist): %v", err)
 		}
@@ -97,7 +107,9 @@ func TestFirestoreOperations(t *testing.T) {
 	t.Run("GetPostStats", func(t *testing.T) {
 		stats, err := client.GetPostStats("test-post-1")
 		if err != nil {
-			t.Logf("GetPostStats failed (expected if post doesn't exist): %v", err)
+			t.Logf("GetPostStats failed: %v", err)
+		} else if stats == nil {
+			t.Logf("Post test-post-1 has no stats yet")
 		} else {
 			t.Logf("Post stats: Score=%.2f, Views=%d", stats.Score, stats.ViewCount)
 		}
@@ -191,6 +203,9 @@ func TestSaveTrendingScoreValidation(t *testing.T) {
 	if err != nil {
 		t.Errorf("Failed to retrieve saved trending score: %v", err)
 	}
+	if retrieved == nil {
+		t.Fatal("Saved trending score was not found")
+	}
 
 	if retrieved.PostID != score.PostID {
 		t.Errorf("Retrieved PostID mismatch: got %s, want %s", retrieved.PostID, score.PostID)
@@ -281,3 +296,72 @@ func TestRemixChainTracking(t *testing.T) {
 		t.Logf("Remix count: got %d, expected at least %d", count, len(remixes))
 	}
 }
+
+// TestMergeScoreCounts tests that merged counts never go backwards
+func TestMergeScoreCounts(t *testing.T) {
+	stored := models.TrendingScore{
+		PostID:       "merge-test",
+		ViewCount:    120,
+		LikeCount:    10,
+		CommentCount: 4,
+		ShareCount:   1,
+		RemixCount:   0,
+	}
+	incoming := models.TrendingScore{
+		ViewCount:    100,
+		LikeCount:    12,
+		CommentCount: 4,
+		ShareCount:   0,
+		RemixCount:   2,
+	}
+
+	mergeScoreCounts(&stored, incoming)
+
+	if stored.ViewCount != 120 {
+		t.Errorf("ViewCount = %d, want 120", stored.ViewCount)
+	}
+	if stored.LikeCount != 12 {
+		t.Errorf("LikeCount = %d, want 12", stored.LikeCount)
+	}
+	if stored.CommentCount != 4 {
+		t.Errorf("CommentCount = %d, want 4", stored.CommentCount)
+	}
+	if stored.ShareCount != 1 {
+		t.Errorf("ShareCount = %d, want 1", stored.ShareCount)
+	}
+	if stored.RemixCount != 2 {
+		t.Errorf("RemixCount = %d, want 2", stored.RemixCount)
+	}
+	if stored.PostID != "merge-test" {
+		t.Errorf("PostID = %s, want merge-test", stored.PostID)
+	}
+}
+
+func TestFirestoreErrorClasses(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		notFound    bool
+		unavailable bool
+	}{
+		{"nil", nil, false, false},
+		{"not found", status.Error(codes.NotFound, "no document"), true, false},
+		{"wrapped not found", fmt.Errorf("load post: %w", status.Error(codes.NotFound, "no document")), true, false},
+		{"unavailable", status.Error(codes.Unavailable, "connection refused"), false, true},
+		{"deadline", status.Error(codes.DeadlineExceeded, "timeout"), false, true},
+		{"context deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), false, true},
+		{"permission denied", status.Error(codes.PermissionDenied, "denied"), false, false},
+		{"plain error", errors.New("unreadable document"), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNotFound(tt.err); got != tt.notFound {
+				t.Errorf("IsNotFound() = %v, want %v", got, tt.notFound)
+			}
+			if got := IsUnavailable(tt.err); got != tt.unavailable {
+				t.Errorf("IsUnavailable() = %v, want %v", got, tt.unavailable)
+			}
+		})
+	}
+}
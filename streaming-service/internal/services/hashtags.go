@@ -0,0 +1,216 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"google.golang.org/api/iterator"
+)
+
+// Hashtags kept per post
+const maxHashtagsPerPost = 5
+
+// Longest trending window served; hourly buckets are kept for two of them so the previous
+// window can be compared against
+const (
+	MaxHashtagTrendWindow  = 7 * 24 * time.Hour
+	hashtagBucketRetention = 2*MaxHashtagTrendWindow + 24*time.Hour
+)
+
+// hashtagPattern matches hashtags written into a prompt
+var hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+
+// hashtagBucket counts the new posts tagged with a hashtag during one hour
+type hashtagBucket struct {
+	Hashtag   string
+	Hour      time.Time
+	Count     int64
+	ExpiresAt time.Time // retention cutoff for the bucket
+}
+
+// hashtagsInText returns the hashtags written into a text, without the leading #
+func hashtagsInText(text string) []string {
+	var hashtags []string
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		hashtags = append(hashtags, match[1])
+	}
+	return hashtags
+}
+
+// normalizeHashtags lowercases hashtags, strips the # sign and anything but letters, digits
+// and underscores, and drops duplicates, keeping at most maxHashtagsPerPost
+func normalizeHashtags(hashtags []string) []string {
+	seen := make(map[string]bool, len(hashtags))
+	result := []string{}
+	for _, hashtag := range hashtags {
+		normalized := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, hashtag)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		result = append(result, normalized)
+		if len(result) == maxHashtagsPerPost {
+			break
+		}
+	}
+	return result
+}
+
+// RecordHashtags counts a new post's hashtags in the hourly bucket of when it was created
+func (fc *FirestoreClient) RecordHashtags(hashtags []string, createdAt time.Time) error {
+	if len(hashtags) == 0 {
+		return nil
+	}
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	hour := createdAt.UTC().Truncate(time.Hour)
+
+	var lastErr error
+	for _, hashtag := range hashtags {
+		Quotas.Record(QuotaFirestore, 1)
+		_, err := fc.client.Collection("hashtag_trends").Doc(fmt.Sprintf("%s_%s", hashtag, hour.Format("2006010215"))).Set(fc.ctx, map[string]interface{}{
+			"Hashtag":   hashtag,
+			"Hour":      hour,
+			"Count":     firestore.Increment(1),
+			"ExpiresAt": hour.Add(hashtagBucketRetention),
+		}, firestore.MergeAll)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// ParseHashtagWindow parses a trending window such as 6h or 7d, which must lie between one hour
+// and MaxHashtagTrendWindow
+func ParseHashtagWindow(value string) (time.Duration, error) {
+	window, err := parseWindowDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if window < time.Hour || window > MaxHashtagTrendWindow {
+		return 0, fmt.Errorf("window must be between 1h and %dd", int(MaxHashtagTrendWindow.Hours()/24))
+	}
+	return window, nil
+}
+
+// parseWindowDuration parses a duration that may also be given in days, such as 7d
+func parseWindowDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %q", value)
+	}
+	return window, nil
+}
+
+// TrendingHashtag is a hashtag's post count in the current window against the previous one
+type TrendingHashtag struct {
+	Hashtag       string  `json:"hashtag"`
+	Count         int64   `json:"count"`
+	PreviousCount int64   `json:"previousCount"`
+	Growth        float64 `json:"growth"` // relative change against the previous window
+}
+
+// GetTrendingHashtags returns the fastest-rising hashtags of the last window, comparing each
+// hashtag's post count with the window before it. Hashtags used by fewer than minPosts posts
+// in the window are left out as noise.
+func (da *DashboardAnalytics) GetTrendingHashtags(window time.Duration, minPosts int64, limit int) ([]TrendingHashtag, error) {
+	logger.Debugf("📊 Calculating trending hashtags over %v...", window)
+
+	now := time.Now()
+	iter := da.firestoreClient.client.Collection("hashtag_trends").
+		Where("Hour", ">=", now.Add(-2*window).Truncate(time.Hour)).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	var buckets []hashtagBucket
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var bucket hashtagBucket
+		if err := doc.DataTo(&bucket); err != nil {
+			continue
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	trending := rankHashtags(buckets, now, window, minPosts, limit)
+	logger.Infof("✅ Trending hashtags calculated: %d hashtags", len(trending))
+	return trending, nil
+}
+
+// rankHashtags sums hourly buckets into the current and previous window and orders hashtags by
+// growth, then by count
+func rankHashtags(buckets []hashtagBucket, now time.Time, window time.Duration, minPosts int64, limit int) []TrendingHashtag {
+	currentStart := now.Add(-window).Truncate(time.Hour)
+	previousStart := currentStart.Add(-window)
+
+	byHashtag := make(map[string]*TrendingHashtag)
+	for _, bucket := range buckets {
+		if bucket.Hour.Before(previousStart) {
+			continue
+		}
+		hashtag, ok := byHashtag[bucket.Hashtag]
+		if !ok {
+			hashtag = &TrendingHashtag{Hashtag: bucket.Hashtag}
+			byHashtag[bucket.Hashtag] = hashtag
+		}
+		if bucket.Hour.Before(currentStart) {
+			hashtag.PreviousCount += bucket.Count
+		} else {
+			hashtag.Count += bucket.Count
+		}
+	}
+
+	trending := []TrendingHashtag{}
+	for _, hashtag := range byHashtag {
+		if hashtag.Count < minPosts || hashtag.Count <= hashtag.PreviousCount {
+			continue
+		}
+		// Hashtags new in this window grow from a baseline of one post
+		hashtag.Growth = float64(hashtag.Count-hashtag.PreviousCount) / math.Max(float64(hashtag.PreviousCount), 1)
+		trending = append(trending, *hashtag)
+	}
+
+	sort.Slice(trending, func(i, j int) bool {
+		if trending[i].Growth != trending[j].Growth {
+			return trending[i].Growth > trending[j].Growth
+		}
+		if trending[i].Count != trending[j].Count {
+			return trending[i].Count > trending[j].Count
+		}
+		return trending[i].Hashtag < trending[j].Hashtag
+	})
+	if len(trending) > limit {
+		trending = trending[:limit]
+	}
+	return trending
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeHashtags(t *testing.T) {
+	got := normalizeHashtags(append(
+		hashtagsInText("Neon city at night #CyberPunk #neon_art"),
+		"#cyberpunk", "Golden Hour", "", "a", "b", "c", "d",
+	))
+	want := []string{"cyberpunk", "neon_art", "goldenhour", "a", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseHashtagWindow(t *testing.T) {
+	cases := map[string]time.Duration{
+		"1h":  time.Hour,
+		"6h":  6 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+		"90m": 90 * time.Minute,
+	}
+	for value, want := range cases {
+		if got, err := ParseHashtagWindow(value); err != nil || got != want {
+			t.Errorf("%s: expected %v, got %v (%v)", value, want, got, err)
+		}
+	}
+	for _, value := range []string{"", "30m", "8d", "xd", "soon"} {
+		if _, err := ParseHashtagWindow(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestRankHashtags(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+	hour := func(hoursAgo int) time.Time {
+		return now.Truncate(time.Hour).Add(-time.Duration(hoursAgo) * time.Hour)
+	}
+	buckets := []hashtagBucket{
+		// rising: 2 -> 8
+		{Hashtag: "rising", Hour: hour(30), Count: 2},
+		{Hashtag: "rising", Hour: hour(3), Count: 5},
+		{Hashtag: "rising", Hour: hour(1), Count: 3},
+		// new: 0 -> 4
+		{Hashtag: "new", Hour: hour(2), Count: 4},
+		// steady: 10 -> 10
+		{Hashtag: "steady", Hour: hour(40), Count: 10},
+		{Hashtag: "steady", Hour: hour(5), Count: 10},
+		// rare: below the minimum
+		{Hashtag: "rare", Hour: hour(1), Count: 2},
+		// old buckets outside both windows are ignored
+		{Hashtag: "new", Hour: hour(60), Count: 50},
+	}
+
+	trending := rankHashtags(buckets, now, 24*time.Hour, 3, 10)
+
+	if len(trending) != 2 {
+		t.Fatalf("expected 2 trending hashtags, got %+v", trending)
+	}
+	if trending[0].Hashtag != "new" || trending[0].Growth != 4 {
+		t.Errorf("expected the new hashtag first with growth 4, got %+v", trending[0])
+	}
+	if trending[1].Hashtag != "rising" || trending[1].Count != 8 || trending[1].PreviousCount != 2 || trending[1].Growth != 3 {
+		t.Errorf("unexpected rising hashtag: %+v", trending[1])
+	}
+
+	if limited := rankHashtags(buckets, now, 24*time.Hour, 3, 1); len(limited) != 1 {
+		t.Errorf("expected the limit to apply, got %d hashtags", len(limited))
+	}
+}
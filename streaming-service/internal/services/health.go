@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Dependency check outcomes
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// DependencyHealth is the outcome of checking one dependency
+type DependencyHealth struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+
+	// Whether the instance is not ready while the dependency is down; optional dependencies
+	// are reported only
+	Required bool `json:"required"`
+}
+
+// HealthReport is the outcome of a readiness check
+type HealthReport struct {
+	Ready     bool                        `json:"ready"`
+	Checks    map[string]DependencyHealth `json:"checks"`
+	CheckedAt time.Time                   `json:"checked_at"`
+}
+
+// healthCheck is a registered dependency check
+type healthCheck struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+}
+
+// HealthChecker checks the dependencies an instance needs to serve traffic. Checks run
+// concurrently, each bounded by the timeout.
+type HealthChecker struct {
+	timeout time.Duration
+	checks  []healthCheck
+}
+
+func NewHealthChecker(timeout time.Duration) *HealthChecker {
+	return &HealthChecker{timeout: timeout}
+}
+
+// Register adds a dependency check. A failing required check makes the instance not ready.
+func (hc *HealthChecker) Register(name string, required bool, check func(ctx context.Context) error) {
+	hc.checks = append(hc.checks, healthCheck{name: name, required: required, check: check})
+}
+
+// Check runs every registered check and reports whether all required ones passed
+func (hc *HealthChecker) Check(ctx context.Context) HealthReport {
+	report := HealthReport{Ready: true, Checks: map[string]DependencyHealth{}, CheckedAt: time.Now()}
+	if hc == nil {
+		return report
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range hc.checks {
+		wg.Add(1)
+		go func(check healthCheck) {
+			defer wg.Done()
+			result := hc.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.name] = result
+			if result.Status != HealthUp && check.required {
+				report.Ready = false
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+func (hc *HealthChecker) run(ctx context.Context, check healthCheck) DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.check(ctx)
+	result := DependencyHealth{
+		Status:    HealthUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Required:  check.required,
+	}
+	if err != nil {
+		result.Status = HealthDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// timeoutMs returns the time left until the context's deadline in milliseconds, for clients
+// that take a timeout rather than a context
+func timeoutMs(ctx context.Context, fallback time.Duration) int {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return int(fallback.Milliseconds())
+	}
+	return max(int(time.Until(deadline).Milliseconds()), 1)
+}
+
+// Ping checks that the producer reaches the Kafka brokers
+func (kp *KafkaProducer) Ping(ctx context.Context) error {
+	metadata, err := kp.producer.GetMetadata(nil, false, timeoutMs(ctx, 5*time.Second))
+	if err != nil {
+		return err
+	}
+	if len(metadata.Brokers) == 0 {
+		return errors.New("no brokers available")
+	}
+	return nil
+}
+
+// CheckAssignment checks that the consumer has partitions assigned. Instances beyond the
+// number of partitions legitimately have none, so this is an optional check.
+func (kc *KafkaConsumer) CheckAssignment(ctx context.Context) error {
+	partitions, err := kc.consumer.Assignment()
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 {
+		return errors.New("no partitions assigned")
+	}
+	return nil
+}
+
+// Ping checks that Firestore answers a read; a missing document is an answer
+func (fc *FirestoreClient) Ping(ctx context.Context) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("health").Doc("ping").Get(ctx)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Ping checks that the Vertex AI endpoint accepts connections and that the circuit breaker
+// lets calls through, without spending a model call
+func (v *VertexAIClient) Ping(ctx context.Context) error {
+	if state := v.breaker.State(); state.State == BreakerOpen {
+		return fmt.Errorf("circuit breaker open: %s", state.LastError)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s-aiplatform.googleapis.com:443", v.config.GeminiLocation))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
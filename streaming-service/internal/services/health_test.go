@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckerReadiness(t *testing.T) {
+	hc := NewHealthChecker(50 * time.Millisecond)
+	hc.Register("firestore", true, func(ctx context.Context) error { return nil })
+	hc.Register("vertex_ai", false, func(ctx context.Context) error { return errors.New("unreachable") })
+
+	report := hc.Check(context.Background())
+	if !report.Ready {
+		t.Errorf("Expected a failing optional check to keep the instance ready, got %+v", report)
+	}
+	if check := report.Checks["vertex_ai"]; check.Status != HealthDown || check.Error != "unreachable" || check.Required {
+		t.Errorf("Unexpected vertex_ai check: %+v", check)
+	}
+	if check := report.Checks["firestore"]; check.Status != HealthUp || !check.Required {
+		t.Errorf("Unexpected firestore check: %+v", check)
+	}
+
+	// A required check that hangs is cut off by the timeout
+	hc.Register("kafka_producer", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	start := time.Now()
+	report = hc.Check(context.Background())
+	if report.Ready || report.Checks["kafka_producer"].Status != HealthDown {
+		t.Errorf("Expected a hanging required check to make the instance not ready, got %+v", report)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the check to time out, took %v", elapsed)
+	}
+}
+
+func TestHealthCheckerWithoutChecks(t *testing.T) {
+	var hc *HealthChecker
+	if report := hc.Check(context.Background()); !report.Ready || len(report.Checks) != 0 {
+		t.Errorf("Expected a ready report without checks, got %+v", report)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog precision: 2^12 registers, about 1.6% standard error in 4 KB
+const (
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+)
+
+// HyperLogLog estimates the number of distinct values added to it in constant space.
+// Sketches merge losslessly, which makes the union of two audiences exact at sketch level.
+type HyperLogLog struct {
+	registers []uint8
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{registers: make([]uint8, hllRegisters)}
+}
+
+// HyperLogLogFromBytes restores a sketch serialized with Bytes
+func HyperLogLogFromBytes(data []byte) (*HyperLogLog, error) {
+	if len(data) != hllRegisters {
+		return nil, fmt.Errorf("invalid sketch size %d, expected %d", len(data), hllRegisters)
+	}
+	registers := make([]uint8, hllRegisters)
+	copy(registers, data)
+	return &HyperLogLog{registers: registers}, nil
+}
+
+// Add records a value
+func (h *HyperLogLog) Add(value string) {
+	hash := hllHash(value)
+	index := hash >> (64 - hllPrecision)
+	// Rank of the first set bit in the remaining bits; the sentinel bit caps it
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Merge folds another sketch into this one
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+}
+
+// Count returns the estimated number of distinct values
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(hllRegisters)
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+
+	// Linear counting is more accurate while many registers are still empty
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Bytes serializes the sketch
+func (h *HyperLogLog) Bytes() []byte {
+	data := make([]byte, hllRegisters)
+	copy(data, h.registers)
+	return data
+}
+
+// estimateIntersection estimates |A ∩ B| by inclusion-exclusion over the merged sketch
+func estimateIntersection(a, b *HyperLogLog) uint64 {
+	union := NewHyperLogLog()
+	union.Merge(a)
+	union.Merge(b)
+
+	countA, countB, countUnion := a.Count(), b.Count(), union.Count()
+	if countA+countB <= countUnion {
+		return 0
+	}
+	shared := countA + countB - countUnion
+	// Estimation error can push the difference past the smaller set
+	if shared > countA {
+		shared = countA
+	}
+	if shared > countB {
+		shared = countB
+	}
+	return shared
+}
+
+// hllHash hashes a value with FNV-1a followed by a 64-bit finalizer so the high bits used
+// for the register index are well mixed
+func hllHash(value string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	x := hasher.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func within(t *testing.T, name string, got uint64, want float64, tolerance float64) {
+	t.Helper()
+	if math.Abs(float64(got)-want) > want*tolerance {
+		t.Errorf("%s: expected about %.0f, got %d", name, want, got)
+	}
+}
+
+func TestHyperLogLogCount(t *testing.T) {
+	for _, n := range []int{100, 10000, 200000} {
+		h := NewHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.Add(fmt.Sprintf("user-%d", i))
+			h.Add(fmt.Sprintf("user-%d", i)) // duplicates do not count
+		}
+		within(t, fmt.Sprintf("n=%d", n), h.Count(), float64(n), 0.05)
+	}
+
+	if got := NewHyperLogLog().Count(); got != 0 {
+		t.Errorf("expected empty sketch to count 0, got %d", got)
+	}
+}
+
+func TestHyperLogLogIntersection(t *testing.T) {
+	a, b := NewHyperLogLog(), NewHyperLogLog()
+	// 20000 viewers each, 5000 in common
+	for i := 0; i < 20000; i++ {
+		a.Add(fmt.Sprintf("user-%d", i))
+		b.Add(fmt.Sprintf("user-%d", i+15000))
+	}
+
+	overlap := compareAudiences(a, b)
+	within(t, "shared viewers", overlap.SharedViewers, 5000, 0.25)
+	if overlap.OverlapRatio < 0.15 || overlap.OverlapRatio > 0.35 {
+		t.Errorf("expected overlap ratio near 0.25, got %f", overlap.OverlapRatio)
+	}
+	if overlap.Jaccard < 0.1 || overlap.Jaccard > 0.2 {
+		t.Errorf("expected jaccard near 0.14, got %f", overlap.Jaccard)
+	}
+
+	disjoint := NewHyperLogLog()
+	for i := 0; i < 100; i++ {
+		disjoint.Add(fmt.Sprintf("other-%d", i))
+	}
+	if shared := estimateIntersection(NewHyperLogLog(), disjoint); shared != 0 {
+		t.Errorf("expected no overlap with an empty audience, got %d", shared)
+	}
+}
+
+func TestHyperLogLogBytesRoundTrip(t *testing.T) {
+	h := NewHyperLogLog()
+	for i := 0; i < 1000; i++ {
+		h.Add(fmt.Sprintf("user-%d", i))
+	}
+
+	restored, err := HyperLogLogFromBytes(h.Bytes())
+	if err != nil {
+		t.Fatalf("failed to restore sketch: %v", err)
+	}
+	if restored.Count() != h.Count() {
+		t.Errorf("expected %d after round trip, got %d", h.Count(), restored.Count())
+	}
+
+	if _, err := HyperLogLogFromBytes([]byte{1, 2, 3}); err == nil {
+		t.Error("expected error for truncated sketch")
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/contracts"
 	"confluent-viral-intelligence/internal/models"
 )
 
@@ -56,6 +57,7 @@ func (kc *KafkaConsumer) Start() error {
 		kc.config.TopicRemixEvents,
 		kc.config.TopicTrendingScores,
 		kc.config.TopicRecommendations,
+		kc.config.TopicRecommendationFeedback,
 	}
 
 	err := kc.consumer.SubscribeTopics(topics, nil)
@@ -92,72 +94,136 @@ func (kc *KafkaConsumer) processMessages() {
 			}
 
 			// Process the message
-			if err := kc.handleMessage(msg); err != nil {
+			trace := traceFromHeaders(msg.Headers)
+			consumedAt := time.Now()
+			if !trace.ProducedAt.IsZero() {
+				PipelineLatency.Observe(StageKafka, consumedAt.Sub(trace.ProducedAt))
+			}
+
+			if err := kc.handleMessage(msg, trace.RequestID); err != nil {
 				logger.Infof("Failed to handle message from topic %s: %v", *msg.TopicPartition.Topic, err)
 			}
+
+			if !trace.IngestedAt.IsZero() {
+				endToEnd := time.Since(trace.IngestedAt)
+				PipelineLatency.Observe(StageEndToEnd, endToEnd)
+				logger.Debugf("⏱️ Pipeline latency for %s: kafka=%v, processing=%v, end_to_end=%v",
+					*msg.TopicPartition.Topic, consumedAt.Sub(trace.ProducedAt), time.Since(consumedAt), endToEnd)
+			}
 		}
 	}
 }
 
-// handleMessage processes a single Kafka message
-func (kc *KafkaConsumer) handleMessage(msg *kafka.Message) error {
+// handleMessage processes a single Kafka message ingested by the API request requestID
+func (kc *KafkaConsumer) handleMessage(msg *kafka.Message, requestID string) error {
 	topic := *msg.TopicPartition.Topic
 	
-	logger.Infof("Received message from topic %s, partition %d, offset %d",
-		topic, msg.TopicPartition.Partition, msg.TopicPartition.Offset)
+	logger.Infof("[%s] Received message from topic %s, partition %d, offset %d",
+		requestID, topic, msg.TopicPartition.Partition, msg.TopicPartition.Offset)
+
+	if kc.config.StrictContractValidation {
+		if err := kc.validateContract(topic, msg.Value); err != nil {
+			return kc.deadLetter(msg, err)
+		}
+	}
 
 	switch topic {
 	case kc.config.TopicUserInteractions:
-		return kc.handleUserInteraction(msg.Value)
+		return kc.handleUserInteraction(msg.Value, requestID)
 	case kc.config.TopicViewEvents:
-		return kc.handleViewEvent(msg.Value)
+		return kc.handleViewEvent(msg.Value, requestID)
 	case kc.config.TopicRemixEvents:
-		return kc.handleRemixEvent(msg.Value)
+		return kc.handleRemixEvent(msg.Value, requestID)
 	case kc.config.TopicTrendingScores:
 		return kc.handleTrendingScore(msg.Value)
 	case kc.config.TopicRecommendations:
 		return kc.handleRecommendation(msg.Value)
+	case kc.config.TopicRecommendationFeedback:
+		return kc.handleRecommendationFeedback(msg.Value, requestID)
 	default:
 		logger.Infof("Unknown topic: %s", topic)
 		return nil
 	}
 }
 
+// contractFor maps a configured topic name to the contract of its payloads
+func (kc *KafkaConsumer) contractFor(topic string) (string, bool) {
+	switch topic {
+	case kc.config.TopicUserInteractions:
+		return contracts.UserInteractions, true
+	case kc.config.TopicViewEvents:
+		return contracts.ViewEvents, true
+	case kc.config.TopicRemixEvents:
+		return contracts.RemixEvents, true
+	case kc.config.TopicTrendingScores:
+		return contracts.TrendingScores, true
+	case kc.config.TopicRecommendations:
+		return contracts.Recommendations, true
+	case kc.config.TopicRecommendationFeedback:
+		return contracts.RecommendationFeedback, true
+	default:
+		return "", false
+	}
+}
+
+// validateContract checks a payload against its topic's contract; topics without a contract pass
+func (kc *KafkaConsumer) validateContract(topic string, data []byte) error {
+	name, ok := kc.contractFor(topic)
+	if !ok {
+		return nil
+	}
+	return contracts.Validate(name, data)
+}
+
+// deadLetter routes a rejected message to the dead letter topic instead of processing it
+func (kc *KafkaConsumer) deadLetter(msg *kafka.Message, reason error) error {
+	logger.Warnf("☠️ Rejecting message from topic %s, offset %d: %v",
+		*msg.TopicPartition.Topic, msg.TopicPartition.Offset, reason)
+
+	if kc.eventProcessor.producer == nil {
+		return fmt.Errorf("no producer for dead letter topic: %w", reason)
+	}
+	if err := kc.eventProcessor.producer.PublishDeadLetter(msg, reason); err != nil {
+		return fmt.Errorf("%v (dead letter failed: %w)", reason, err)
+	}
+	return nil
+}
+
 // handleUserInteraction deserializes and processes a user interaction event
-func (kc *KafkaConsumer) handleUserInteraction(data []byte) error {
+func (kc *KafkaConsumer) handleUserInteraction(data []byte, requestID string) error {
 	var event models.InteractionEvent
 	if err := json.Unmarshal(data, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal interaction event: %w", err)
 	}
 
 	// Update analytics in Firestore
-	kc.eventProcessor.ProcessInteractionForAnalytics(event)
+	kc.eventProcessor.ProcessInteractionForAnalytics(event, requestID)
 	
 	return nil
 }
 
 // handleViewEvent deserializes and processes a view event
-func (kc *KafkaConsumer) handleViewEvent(data []byte) error {
+func (kc *KafkaConsumer) handleViewEvent(data []byte, requestID string) error {
 	var event models.ViewEvent
 	if err := json.Unmarshal(data, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal view event: %w", err)
 	}
 
 	// Update analytics in Firestore
-	kc.eventProcessor.ProcessViewForAnalytics(event)
+	kc.eventProcessor.ProcessViewForAnalytics(event, requestID)
 	
 	return nil
 }
 
 // handleRemixEvent deserializes and processes a remix event
-func (kc *KafkaConsumer) handleRemixEvent(data []byte) error {
+func (kc *KafkaConsumer) handleRemixEvent(data []byte, requestID string) error {
 	var event models.RemixEvent
 	if err := json.Unmarshal(data, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal remix event: %w", err)
 	}
 
 	// Update analytics in Firestore
-	kc.eventProcessor.ProcessRemixForAnalytics(event)
+	kc.eventProcessor.ProcessRemixForAnalytics(event, requestID)
 	
 	return nil
 }
@@ -188,6 +254,19 @@ func (kc *KafkaConsumer) handleRecommendation(data []byte) error {
 	return nil
 }
 
+// handleRecommendationFeedback deserializes and processes a user's feedback on a recommendation
+func (kc *KafkaConsumer) handleRecommendationFeedback(data []byte, requestID string) error {
+	var event models.RecommendationFeedbackEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal recommendation feedback: %w", err)
+	}
+
+	// Re-rank the user's recommendations
+	kc.eventProcessor.ProcessRecommendationFeedbackForAnalytics(event, requestID)
+
+	return nil
+}
+
 // Close gracefully shuts down the consumer
 func (kc *KafkaConsumer) Close() error {
 	logger.Info("Closing Kafka consumer")
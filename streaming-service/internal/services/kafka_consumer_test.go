@@ -0,0 +1,35 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/contracts"
+)
+
+func TestValidateContractUsesConfiguredTopics(t *testing.T) {
+	cfg := &config.Config{
+		TopicUserInteractions: "prod.user-interactions",
+		TopicTrendingScores:   "prod.trending-scores",
+	}
+	kc := &KafkaConsumer{config: cfg}
+
+	fixture, err := contracts.Fixture(contracts.TrendingScores)
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	if err := kc.validateContract("prod.trending-scores", fixture); err != nil {
+		t.Errorf("expected canonical trending score to pass, got %v", err)
+	}
+
+	// A trending score published on the interactions topic violates that topic's contract
+	if err := kc.validateContract("prod.user-interactions", fixture); !errors.Is(err, contracts.ErrContractViolation) {
+		t.Errorf("expected contract violation, got %v", err)
+	}
+
+	// Topics without a contract are not validated
+	if err := kc.validateContract("some-other-topic", []byte("not json")); err != nil {
+		t.Errorf("expected unknown topic to pass, got %v", err)
+	}
+}
@@ -2,14 +2,30 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 	"confluent-viral-intelligence/internal/logger"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/contracts"
 	"confluent-viral-intelligence/internal/models"
 )
 
+// Headers added to dead-lettered messages
+const (
+	headerDLQOriginalTopic     = "x-dlq-original-topic"
+	headerDLQOriginalPartition = "x-dlq-original-partition"
+	headerDLQOriginalOffset    = "x-dlq-original-offset"
+	headerDLQReason            = "x-dlq-reason"
+	headerDLQContractVersion   = "x-dlq-contract-version"
+)
+
+// ErrKafkaUnavailable wraps errors of messages the producer could not hand to Kafka
+var ErrKafkaUnavailable = errors.New("kafka unavailable")
+
 type KafkaProducer struct {
 	producer *kafka.Producer
 	config   *config.Config
@@ -50,44 +66,109 @@ func NewKafkaProducer(cfg *config.Config) (*KafkaProducer, error) {
 	}, nil
 }
 
-func (kp *KafkaProducer) PublishInteraction(event models.InteractionEvent) error {
-	return kp.publish(kp.config.TopicUserInteractions, event.PostID, event)
+// PublishInteraction publishes an interaction event; its trace is carried as headers
+func (kp *KafkaProducer) PublishInteraction(event models.InteractionEvent, trace EventTrace) error {
+	return kp.publish(kp.config.TopicUserInteractions, event.PostID, event, trace)
+}
+
+func (kp *KafkaProducer) PublishContentMetadata(event models.ContentMetadata, trace EventTrace) error {
+	return kp.publish(kp.config.TopicContentMetadata, event.PostID, event, trace)
+}
+
+func (kp *KafkaProducer) PublishView(event models.ViewEvent, trace EventTrace) error {
+	return kp.publish(kp.config.TopicViewEvents, event.PostID, event, trace)
+}
+
+func (kp *KafkaProducer) PublishComment(event models.CommentEvent, trace EventTrace) error {
+	return kp.publish(kp.config.TopicCommentEvents, event.PostID, event, trace)
+}
+
+// PublishRecommendationFeedback publishes a user's reaction to a recommendation, keyed by user
+// so each user's feedback stays in order
+func (kp *KafkaProducer) PublishRecommendationFeedback(event models.RecommendationFeedbackEvent, trace EventTrace) error {
+	return kp.publish(kp.config.TopicRecommendationFeedback, event.UserID, event, trace)
+}
+
+func (kp *KafkaProducer) PublishRemix(event models.RemixEvent, trace EventTrace) error {
+	return kp.publish(kp.config.TopicRemixEvents, event.OriginalPostID, event, trace)
 }
 
-func (kp *KafkaProducer) PublishContentMetadata(event models.ContentMetadata) error {
-	return kp.publish(kp.config.TopicContentMetadata, event.PostID, event)
+func (kp *KafkaProducer) PublishTrendingScore(score models.TrendingScore, ingestedAt time.Time) error {
+	return kp.publish(kp.config.TopicTrendingScores, score.PostID, score, EventTrace{IngestedAt: ingestedAt})
 }
 
-func (kp *KafkaProducer) PublishView(event models.ViewEvent) error {
-	return kp.publish(kp.config.TopicViewEvents, event.PostID, event)
+func (kp *KafkaProducer) PublishRecommendation(rec models.Recommendation, ingestedAt time.Time) error {
+	return kp.publish(kp.config.TopicRecommendations, rec.UserID, rec, EventTrace{IngestedAt: ingestedAt})
 }
 
-func (kp *KafkaProducer) PublishRemix(event models.RemixEvent) error {
-	return kp.publish(kp.config.TopicRemixEvents, event.OriginalPostID, event)
+// PublishModerationVerdict sends a flagged post to the moderation queue for human review
+func (kp *KafkaProducer) PublishModerationVerdict(verdict models.ModerationVerdict, ingestedAt time.Time) error {
+	return kp.publish(kp.config.TopicModerationQueue, verdict.PostID, verdict, EventTrace{IngestedAt: ingestedAt})
 }
 
-func (kp *KafkaProducer) PublishTrendingScore(score models.TrendingScore) error {
-	return kp.publish(kp.config.TopicTrendingScores, score.PostID, score)
+// PublishCreatorTierChange announces a creator's new tier to the notification system
+func (kp *KafkaProducer) PublishCreatorTierChange(change models.CreatorTierChange, ingestedAt time.Time) error {
+	return kp.publish(kp.config.TopicCreatorTiers, change.UserID, change, EventTrace{IngestedAt: ingestedAt})
 }
 
-func (kp *KafkaProducer) PublishRecommendation(rec models.Recommendation) error {
-	return kp.publish(kp.config.TopicRecommendations, rec.UserID, rec)
+// PublishViralAlert announces a post reaching a higher viral tier to the notification system
+func (kp *KafkaProducer) PublishViralAlert(alert models.ViralAlert, ingestedAt time.Time) error {
+	return kp.publish(kp.config.TopicViralAlerts, alert.PostID, alert, EventTrace{IngestedAt: ingestedAt})
 }
 
-func (kp *KafkaProducer) publish(topic string, key string, value interface{}) error {
+// PublishHubBroadcast relays a WebSocket hub message to the hubs of every service instance
+func (kp *KafkaProducer) PublishHubBroadcast(broadcast models.HubBroadcast) error {
+	return kp.publish(kp.config.TopicHubBroadcasts, broadcast.Key, broadcast, EventTrace{IngestedAt: broadcast.SentAt})
+}
+
+// PublishPartnerEngagement publishes aggregated engagement to the partner topic, keyed by
+// partner so each partner's events stay in order
+func (kp *KafkaProducer) PublishPartnerEngagement(event models.PartnerEngagementEvent, ingestedAt time.Time) error {
+	return kp.publish(kp.config.TopicPartnerEvents, event.PartnerID, event, EventTrace{IngestedAt: ingestedAt})
+}
+
+// PublishDeadLetter forwards a rejected message unchanged to the dead letter topic, keeping its
+// key and headers and recording where it came from and why it was rejected
+func (kp *KafkaProducer) PublishDeadLetter(msg *kafka.Message, reason error) error {
+	topic := kp.config.TopicDeadLetter
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: headerDLQOriginalTopic, Value: []byte(*msg.TopicPartition.Topic)},
+		kafka.Header{Key: headerDLQOriginalPartition, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: headerDLQOriginalOffset, Value: []byte(msg.TopicPartition.Offset.String())},
+		kafka.Header{Key: headerDLQReason, Value: []byte(reason.Error())},
+		kafka.Header{Key: headerDLQContractVersion, Value: []byte(contracts.Version)},
+	)
+
+	err := kp.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("%w: failed to produce dead letter: %w", ErrKafkaUnavailable, err)
+	}
+	return nil
+}
+
+func (kp *KafkaProducer) publish(topic string, key string, value interface{}, trace EventTrace) error {
 	data, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
+	trace.ProducedAt = time.Now()
+
 	err = kp.producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
 		Key:            []byte(key),
 		Value:          data,
+		Headers:        trace.headers(),
 	}, nil)
 
 	if err != nil {
-		return fmt.Errorf("failed to produce message: %w", err)
+		return fmt.Errorf("%w: failed to produce message: %w", ErrKafkaUnavailable, err)
 	}
 
 	return nil
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/iterator"
+)
+
+// ErrBackfillRunning is returned when a keyword backfill is started while one is in progress
+var ErrBackfillRunning = errors.New("keyword backfill already running")
+
+// KeywordBatchItem is a single post in a batched keyword extraction request
+type KeywordBatchItem struct {
+	PostID      string `json:"id"`
+	ContentType string `json:"content_type"`
+	Prompt      string `json:"prompt"`
+
+	// Publication time of the post, stored with its facets; not sent to the model
+	CreatedAt time.Time `json:"-"`
+}
+
+// ExtractKeywordsBatch extracts keywords for several posts with a single Gemini request.
+// Posts missing from the response are left out of the result.
+func (v *VertexAIClient) ExtractKeywordsBatch(items []KeywordBatchItem) (map[string]*models.KeywordExtractionResponse, error) {
+	systemPrompt := `You are an AI content analyzer. For each content prompt, extract relevant keywords, hashtags, category, style, and mood.
+Return ONLY a valid JSON array with one object per input item and these exact fields:
+- id: the id of the input item
+- language: ISO 639-1 code of the language the prompt is written in (en, tr, es, ja, ...)
+- keywords: array of 5-10 relevant keywords (strings), in the language of the prompt
+- english_keywords: the same keywords translated to English, in the same order
+- hashtags: array of 1-5 topic hashtags people would follow, lowercase, single words without the # sign
+- category: main category (art, photography, music, voice, video, text)
+- style: artistic style or genre (string), in English
+- mood: emotional tone (string), in English
+
+Example response:
+[
+  {"id": "abc", "language": "es", "keywords": ["atardecer", "montañas", "paisaje", "naturaleza", "hora dorada"], "english_keywords": ["sunset", "mountains", "landscape", "nature", "golden hour"], "hashtags": ["horadorada", "montañas"], "category": "photography", "style": "landscape", "mood": "peaceful"}
+]
+
+Do not include any explanation, only return the JSON array.`
+
+	input, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+	userPrompt := fmt.Sprintf("Items:\n%s\n\nExtract keywords, hashtags, category, style, and mood for every item.", input)
+
+	response, err := v.callGemini(config.GeminiUseCaseKeywords, systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tolerate extra text around the JSON array
+	jsonStart := strings.Index(response, "[")
+	jsonEnd := strings.LastIndex(response, "]")
+	if jsonStart < 0 || jsonEnd <= jsonStart {
+		return nil, fmt.Errorf("no JSON array in gemini response")
+	}
+
+	var parsed []struct {
+		ID string `json:"id"`
+		models.KeywordExtractionResponse
+	}
+	if err := json.Unmarshal([]byte(response[jsonStart:jsonEnd+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse batch keywords: %w", err)
+	}
+
+	contentTypes := make(map[string]string, len(items))
+	prompts := make(map[string]string, len(items))
+	for _, item := range items {
+		contentTypes[item.PostID] = item.ContentType
+		prompts[item.PostID] = item.Prompt
+	}
+
+	results := make(map[string]*models.KeywordExtractionResponse, len(parsed))
+	for _, p := range parsed {
+		contentType, ok := contentTypes[p.ID]
+		if !ok {
+			continue
+		}
+		result := p.KeywordExtractionResponse
+		normalizeKeywords(&result, contentType)
+		normalizeLanguage(&result, detectLanguage(prompts[p.ID]))
+		result.Hashtags = normalizeHashtags(append(hashtagsInText(prompts[p.ID]), result.Hashtags...))
+		results[p.ID] = &result
+	}
+	return results, nil
+}
+
+// KeywordBackfillStatus reports the progress of a keyword backfill run
+type KeywordBackfillStatus struct {
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Scanned    int        `json:"scanned"`   // posts read from Firestore
+	Queued     int        `json:"queued"`    // posts without keywords
+	Processed  int        `json:"processed"` // queued posts handled so far
+	Updated    int        `json:"updated"`
+	Fallback   int        `json:"fallback"` // posts that got heuristic keywords because Gemini failed
+	Failed     int        `json:"failed"`
+	Batches    int        `json:"batches"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// KeywordBackfiller walks the posts collection and fills in keywords, category and style for
+// posts that never went through content metadata processing
+type KeywordBackfiller struct {
+	firestore *FirestoreClient
+	vertexAI  *VertexAIClient
+	config    *config.Config
+
+	mu     sync.Mutex
+	status KeywordBackfillStatus
+}
+
+func NewKeywordBackfiller(firestore *FirestoreClient, vertexAI *VertexAIClient, cfg *config.Config) *KeywordBackfiller {
+	return &KeywordBackfiller{
+		firestore: firestore,
+		vertexAI:  vertexAI,
+		config:    cfg,
+	}
+}
+
+// Start begins a backfill in the background
+func (kb *KeywordBackfiller) Start() error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	if kb.status.Running {
+		return ErrBackfillRunning
+	}
+
+	now := time.Now()
+	kb.status = KeywordBackfillStatus{Running: true, StartedAt: &now}
+
+	go kb.run()
+	return nil
+}
+
+// Status returns the progress of the current or last backfill
+func (kb *KeywordBackfiller) Status() KeywordBackfillStatus {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	return kb.status
+}
+
+func (kb *KeywordBackfiller) run() {
+	logger.Info("🔄 Starting keyword backfill...")
+
+	batchSize := kb.config.KeywordBackfillBatchSize
+	if batchSize <= 0 {
+		batchSize = 10
+	}
+	concurrency := kb.config.KeywordBackfillConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	perMinute := kb.config.KeywordBackfillRequestsPerMinute
+	if perMinute <= 0 {
+		perMinute = 60
+	}
+	limiter := rate.NewLimiter(rate.Limit(float64(perMinute)/60), 1)
+
+	batches := make(chan []KeywordBatchItem)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				if err := limiter.Wait(kb.vertexAI.ctx); err != nil {
+					kb.recordFailure(len(batch), err)
+					continue
+				}
+				kb.processBatch(batch)
+			}
+		}()
+	}
+
+	scanErr := kb.scanPosts(batchSize, batches)
+	close(batches)
+	wg.Wait()
+
+	kb.mu.Lock()
+	now := time.Now()
+	kb.status.Running = false
+	kb.status.FinishedAt = &now
+	if scanErr != nil {
+		kb.status.LastError = scanErr.Error()
+	}
+	status := kb.status
+	kb.mu.Unlock()
+
+	logger.Infof("✅ Keyword backfill finished: scanned=%d, updated=%d, fallback=%d, failed=%d",
+		status.Scanned, status.Updated, status.Fallback, status.Failed)
+}
+
+// scanPosts reads every post and sends the ones without keywords to the workers in batches
+func (kb *KeywordBackfiller) scanPosts(batchSize int, batches chan<- []KeywordBatchItem) error {
+	iter := kb.firestore.client.Collection("posts").Documents(kb.firestore.ctx)
+	defer iter.Stop()
+
+	batch := make([]KeywordBatchItem, 0, batchSize)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to scan posts: %w", err)
+		}
+
+		data := doc.Data()
+		kb.mu.Lock()
+		kb.status.Scanned++
+		kb.mu.Unlock()
+
+		if len(stringSlice(data["keywords"])) > 0 {
+			continue
+		}
+		prompt := postPrompt(data)
+		if prompt == "" {
+			continue
+		}
+
+		contentType, _ := data["contentType"].(string)
+		createdAt, _ := postCreationTime(data)
+		batch = append(batch, KeywordBatchItem{PostID: doc.Ref.ID, ContentType: contentType, Prompt: prompt, CreatedAt: createdAt})
+
+		kb.mu.Lock()
+		kb.status.Queued++
+		kb.mu.Unlock()
+
+		if len(batch) == batchSize {
+			batches <- batch
+			batch = make([]KeywordBatchItem, 0, batchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		batches <- batch
+	}
+	return nil
+}
+
+// processBatch extracts keywords for a batch and writes them back to the posts
+func (kb *KeywordBackfiller) processBatch(batch []KeywordBatchItem) {
+	results, err := kb.vertexAI.ExtractKeywordsBatch(batch)
+	if err != nil {
+		logger.Infof("Batch keyword extraction failed, using fallback: %v", err)
+	}
+
+	updated, fallback, failed := 0, 0, 0
+	var lastErr error
+	for _, item := range batch {
+		result, ok := results[item.PostID]
+		if !ok {
+			result = fallbackKeywordExtraction(item.Prompt, item.ContentType)
+			fallback++
+		}
+
+		// Backfilled posts are old, so their hashtags are stored but not counted as trending
+		if err := kb.firestore.UpdateContentMetadata(item.PostID, result); err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+		if err := kb.firestore.SetContentFacets(item.PostID, NewContentFacets(item.ContentType, "", item.CreatedAt, result)); err != nil {
+			logger.Debugf(" Failed to store content facets of post %s: %v", item.PostID, err)
+		}
+		updated++
+	}
+
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	kb.status.Batches++
+	kb.status.Processed += len(batch)
+	kb.status.Updated += updated
+	kb.status.Fallback += fallback
+	kb.status.Failed += failed
+	if lastErr != nil {
+		kb.status.LastError = lastErr.Error()
+	}
+}
+
+func (kb *KeywordBackfiller) recordFailure(count int, err error) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	kb.status.Processed += count
+	kb.status.Failed += count
+	kb.status.LastError = err.Error()
+}
+
+// postPrompt returns the text that best describes what a post was generated from
+func postPrompt(data map[string]interface{}) string {
+	for _, field := range []string{"prompt", "instructions", "description", "title"} {
+		if value, ok := data[field].(string); ok && strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package services
+
+import "testing"
+
+func TestPostPrompt(t *testing.T) {
+	if got := postPrompt(map[string]interface{}{"title": "Title", "description": "  "}); got != "Title" {
+		t.Errorf("postPrompt = %q, want Title", got)
+	}
+	if got := postPrompt(map[string]interface{}{"prompt": "a red fox", "title": "Fox"}); got != "a red fox" {
+		t.Errorf("postPrompt = %q, want the generation prompt", got)
+	}
+	if got := postPrompt(map[string]interface{}{}); got != "" {
+		t.Errorf("postPrompt = %q, want empty", got)
+	}
+}
+
+func TestKeywordBackfillerRejectsConcurrentRuns(t *testing.T) {
+	kb := NewKeywordBackfiller(nil, nil, nil)
+	kb.status.Running = true
+
+	if err := kb.Start(); err != ErrBackfillRunning {
+		t.Errorf("Start = %v, want ErrBackfillRunning", err)
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+// Dimensions keyword performance can be grouped by
+const (
+	ThemeKeyword  = "keyword"
+	ThemeCategory = "category"
+	ThemeStyle    = "style"
+)
+
+// Orders keyword performance can be sorted by
+const (
+	ThemeSortViews          = "views"
+	ThemeSortAvgScore       = "avg_score"
+	ThemeSortViralRate      = "viral_rate"
+	ThemeSortEngagementRate = "engagement_rate"
+	ThemeSortPosts          = "posts"
+)
+
+// Longest window of published posts keyword performance is computed over
+const MaxKeywordWindow = 90 * 24 * time.Hour
+
+// ThemePerformance is the engagement of the posts published in a window that share a keyword,
+// category or style
+type ThemePerformance struct {
+	Theme          string  `json:"theme"`
+	Posts          int64   `json:"posts"`
+	Views          int64   `json:"views"`
+	Likes          int64   `json:"likes"`
+	Comments       int64   `json:"comments"`
+	Shares         int64   `json:"shares"`
+	AvgViews       float64 `json:"avgViews"`
+	AvgScore       float64 `json:"avgScore"`
+	ViralPosts     int64   `json:"viralPosts"`
+	ViralRate      float64 `json:"viralRate"`      // share of the posts that went viral
+	EngagementRate float64 `json:"engagementRate"` // likes, comments and shares per view
+}
+
+// ParseThemeDimension validates the dimension keyword performance is grouped by
+func ParseThemeDimension(value string) (string, error) {
+	switch value {
+	case ThemeKeyword, ThemeCategory, ThemeStyle:
+		return value, nil
+	}
+	return "", fmt.Errorf("dimension must be %s, %s or %s", ThemeKeyword, ThemeCategory, ThemeStyle)
+}
+
+// ParseThemeSort validates the order keyword performance is sorted by
+func ParseThemeSort(value string) (string, error) {
+	switch value {
+	case ThemeSortViews, ThemeSortAvgScore, ThemeSortViralRate, ThemeSortEngagementRate, ThemeSortPosts:
+		return value, nil
+	}
+	return "", fmt.Errorf("sort must be %s, %s, %s, %s or %s",
+		ThemeSortViews, ThemeSortAvgScore, ThemeSortViralRate, ThemeSortEngagementRate, ThemeSortPosts)
+}
+
+// ParseKeywordWindow parses a window of published posts such as 24h or 30d, which must lie
+// between one hour and MaxKeywordWindow
+func ParseKeywordWindow(value string) (time.Duration, error) {
+	window, err := parseWindowDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if window < time.Hour || window > MaxKeywordWindow {
+		return 0, fmt.Errorf("window must be between 1h and %dd", int(MaxKeywordWindow.Hours()/24))
+	}
+	return window, nil
+}
+
+// GetKeywordPerformance returns how the posts published in the last window performed, grouped
+// by keyword, category or style. Themes of fewer than minPosts posts are left out as noise.
+// Posts are selected by the publication time stored with their facets, so posts whose
+// keywords were never extracted are not counted.
+func (da *DashboardAnalytics) GetKeywordPerformance(dimension string, window time.Duration, sortBy string, minPosts int64, limit int) ([]ThemePerformance, error) {
+	logger.Debugf("📊 Calculating %s performance over %v...", dimension, window)
+
+	iter := da.firestoreClient.client.Collection("trending_scores").
+		Where("PublishedAt", ">=", time.Now().Add(-window)).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	var scores []models.TrendingScore
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		Quotas.Record(QuotaFirestore, 1)
+
+		var score models.TrendingScore
+		if err := doc.DataTo(&score); err != nil {
+			continue
+		}
+		scores = append(scores, score)
+	}
+
+	themes := rankThemes(scores, dimension, sortBy, minPosts, limit)
+	logger.Infof("✅ %s performance calculated: %d themes from %d posts", dimension, len(themes), len(scores))
+	return themes, nil
+}
+
+// scoreThemes returns the themes of a post along a dimension
+func scoreThemes(score *models.TrendingScore, dimension string) []string {
+	switch dimension {
+	case ThemeCategory:
+		if score.Category != "" {
+			return []string{score.Category}
+		}
+	case ThemeStyle:
+		if score.Style != "" {
+			return []string{score.Style}
+		}
+	case ThemeKeyword:
+		return score.Keywords
+	}
+	return nil
+}
+
+// rankThemes sums the engagement of posts by theme and orders the themes by sortBy, then by
+// views
+func rankThemes(scores []models.TrendingScore, dimension, sortBy string, minPosts int64, limit int) []ThemePerformance {
+	byTheme := make(map[string]*ThemePerformance)
+	scoreSums := make(map[string]float64)
+	for i := range scores {
+		score := &scores[i]
+		for _, name := range scoreThemes(score, dimension) {
+			theme, ok := byTheme[name]
+			if !ok {
+				theme = &ThemePerformance{Theme: name}
+				byTheme[name] = theme
+			}
+			theme.Posts++
+			theme.Views += score.ViewCount
+			theme.Likes += score.LikeCount
+			theme.Comments += score.CommentCount
+			theme.Shares += score.ShareCount
+			if isDashboardViral(score) {
+				theme.ViralPosts++
+			}
+			scoreSums[name] += score.Score
+		}
+	}
+
+	themes := []ThemePerformance{}
+	for name, theme := range byTheme {
+		if theme.Posts < minPosts {
+			continue
+		}
+		theme.AvgViews = float64(theme.Views) / float64(theme.Posts)
+		theme.AvgScore = scoreSums[name] / float64(theme.Posts)
+		theme.ViralRate = float64(theme.ViralPosts) / float64(theme.Posts)
+		if theme.Views > 0 {
+			theme.EngagementRate = float64(theme.Likes+theme.Comments+theme.Shares) / float64(theme.Views)
+		}
+		themes = append(themes, *theme)
+	}
+
+	sort.Slice(themes, func(i, j int) bool {
+		a, b := themeSortValue(&themes[i], sortBy), themeSortValue(&themes[j], sortBy)
+		if a != b {
+			return a > b
+		}
+		if themes[i].Views != themes[j].Views {
+			return themes[i].Views > themes[j].Views
+		}
+		return themes[i].Theme < themes[j].Theme
+	})
+	if len(themes) > limit {
+		themes = themes[:limit]
+	}
+	return themes
+}
+
+// themeSortValue returns the value a theme is ordered by
+func themeSortValue(theme *ThemePerformance, sortBy string) float64 {
+	switch sortBy {
+	case ThemeSortAvgScore:
+		return theme.AvgScore
+	case ThemeSortViralRate:
+		return theme.ViralRate
+	case ThemeSortEngagementRate:
+		return theme.EngagementRate
+	case ThemeSortPosts:
+		return float64(theme.Posts)
+	}
+	return float64(theme.Views)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestParseKeywordWindow(t *testing.T) {
+	if got, err := ParseKeywordWindow("30d"); err != nil || got != 30*24*time.Hour {
+		t.Errorf("expected 30 days, got %v (%v)", got, err)
+	}
+	for _, value := range []string{"", "30m", "91d", "soon"} {
+		if _, err := ParseKeywordWindow(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+	if _, err := ParseThemeDimension("mood"); err == nil {
+		t.Error("expected an unknown dimension to be rejected")
+	}
+	if _, err := ParseThemeSort("likes"); err == nil {
+		t.Error("expected an unknown sort to be rejected")
+	}
+}
+
+func TestRankThemes(t *testing.T) {
+	scores := []models.TrendingScore{
+		{PostID: "a", Keywords: []string{"sunset", "beach"}, Category: "photography", Style: "landscape", ViewCount: 100, LikeCount: 10, Score: 120},
+		{PostID: "b", Keywords: []string{"sunset"}, Category: "photography", ViewCount: 50, ShareCount: 5, Score: 20},
+		{PostID: "c", Keywords: []string{"neon"}, Category: "art", Style: "cyberpunk", ViewCount: 400, Score: 10},
+		{PostID: "d", Keywords: []string{"neon"}, Category: "art", ViewCount: 10, Score: 5, ViralProbability: 0.9},
+	}
+
+	keywords := rankThemes(scores, ThemeKeyword, ThemeSortViews, 2, 10)
+	if len(keywords) != 2 || keywords[0].Theme != "neon" || keywords[1].Theme != "sunset" {
+		t.Fatalf("expected neon then sunset with beach left out, got %+v", keywords)
+	}
+	sunset := keywords[1]
+	if sunset.Posts != 2 || sunset.Views != 150 || sunset.AvgScore != 70 || sunset.ViralRate != 0.5 || sunset.EngagementRate != 0.1 {
+		t.Errorf("unexpected sunset performance %+v", sunset)
+	}
+
+	categories := rankThemes(scores, ThemeCategory, ThemeSortAvgScore, 1, 1)
+	if len(categories) != 1 || categories[0].Theme != "photography" {
+		t.Errorf("expected photography to lead on average score, got %+v", categories)
+	}
+
+	styles := rankThemes(scores, ThemeStyle, ThemeSortViralRate, 1, 10)
+	if len(styles) != 2 || styles[0].Theme != "landscape" || styles[1].Theme != "cyberpunk" {
+		t.Errorf("expected the styled posts ordered by viral rate, got %+v", styles)
+	}
+}
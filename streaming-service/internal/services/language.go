@@ -0,0 +1,128 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Prompt languages recognized by keyword extraction
+const (
+	LanguageEnglish  = "en"
+	LanguageTurkish  = "tr"
+	LanguageSpanish  = "es"
+	LanguageJapanese = "ja"
+)
+
+// languageNames names each language in the extraction prompts
+var languageNames = map[string]string{
+	LanguageEnglish:  "English",
+	LanguageTurkish:  "Turkish",
+	LanguageSpanish:  "Spanish",
+	LanguageJapanese: "Japanese",
+}
+
+// stopwords are skipped by the fallback extractor; they also identify the language of prompts
+// written without language-specific letters
+var stopwords = map[string]map[string]bool{
+	LanguageEnglish: wordSet("the", "and", "with", "for", "from", "that", "this", "into", "over", "under", "of", "in", "on", "at", "a", "an"),
+	LanguageTurkish: wordSet("ve", "ile", "bir", "bu", "şu", "için", "gibi", "çok", "daha", "olan", "de", "da", "ki", "ya", "veya", "üzerinde", "altında"),
+	LanguageSpanish: wordSet("el", "la", "los", "las", "un", "una", "unos", "unas", "y", "con", "para", "por", "del", "de", "en", "que", "sobre", "bajo"),
+}
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+// detectLanguage guesses the language of a prompt: Japanese from its script, Turkish and
+// Spanish from their letters and common words, English otherwise
+func detectLanguage(text string) string {
+	turkishLetters, spanishLetters := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han):
+			return LanguageJapanese
+		case strings.ContainsRune("ğĞıİşŞ", r):
+			turkishLetters++
+		case strings.ContainsRune("ñÑ¿¡áÁéÉíÍóÓúÚ", r):
+			spanishLetters++
+		}
+	}
+
+	votes := map[string]int{
+		LanguageTurkish: 2 * turkishLetters,
+		LanguageSpanish: 2 * spanishLetters,
+	}
+	for _, word := range promptWords(text) {
+		for _, language := range []string{LanguageEnglish, LanguageTurkish, LanguageSpanish} {
+			if stopwords[language][word] {
+				votes[language]++
+			}
+		}
+	}
+
+	detected, best := LanguageEnglish, votes[LanguageEnglish]
+	for _, language := range []string{LanguageTurkish, LanguageSpanish} {
+		if votes[language] > best {
+			detected, best = language, votes[language]
+		}
+	}
+	return detected
+}
+
+// promptWords splits a prompt into lowercase words. Japanese has no spaces, so runs of kanji and
+// of katakana are taken as words and hiragana, mostly particles and endings, separates them.
+func promptWords(text string) []string {
+	var words []string
+	var word []rune
+	var script *unicode.RangeTable
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		var current *unicode.RangeTable
+		switch {
+		case unicode.Is(unicode.Han, r):
+			current = unicode.Han
+		case unicode.Is(unicode.Katakana, r) || r == 'ー':
+			current = unicode.Katakana
+		case unicode.Is(unicode.Hiragana, r):
+			flush()
+			script = nil
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current = unicode.Latin
+		default:
+			flush()
+			script = nil
+			continue
+		}
+		if current != script {
+			flush()
+			script = current
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// meaningfulWord reports whether the fallback extractor keeps a prompt word as a keyword
+func meaningfulWord(word, language string) bool {
+	if stopwords[language][word] {
+		return false
+	}
+	runes := []rune(word)
+	if unicode.In(runes[0], unicode.Han, unicode.Katakana) {
+		return len(runes) >= 2
+	}
+	return len(runes) > 3
+}
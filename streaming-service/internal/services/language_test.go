@@ -0,0 +1,98 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"beautiful sunset over the mountains": LanguageEnglish,
+		"dağların üzerinde güneş batışı":      LanguageTurkish,
+		"deniz kenarında bir kedi ve köpek":   LanguageTurkish,
+		"un gato con sombrero en la playa":    LanguageSpanish,
+		"atardecer sobre las montañas":        LanguageSpanish,
+		"夕焼けの山の風景":                            LanguageJapanese,
+		"サイバーパンクな街":                           LanguageJapanese,
+		"neon cyberpunk city":                 LanguageEnglish,
+		"":                                    LanguageEnglish,
+	}
+	for prompt, want := range cases {
+		if got := detectLanguage(prompt); got != want {
+			t.Errorf("%q: expected %s, got %s", prompt, want, got)
+		}
+	}
+}
+
+func TestPromptWords(t *testing.T) {
+	got := promptWords("夕焼けの山とサイバーパンクな街")
+	want := []string{"夕焼", "山", "サイバーパンク", "街"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got = promptWords("Sunset, over the MOUNTAINS!")
+	want = []string{"sunset", "over", "the", "mountains"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFallbackKeywordExtractionLanguages(t *testing.T) {
+	tr := fallbackKeywordExtraction("dağların üzerinde güneş batışı ve bulutlar", "image")
+	if tr.Language != LanguageTurkish {
+		t.Errorf("expected turkish, got %s", tr.Language)
+	}
+	if !containsString(tr.Keywords, "dağların") || containsString(tr.Keywords, "üzerinde") {
+		t.Errorf("expected turkish words without stopwords, got %v", tr.Keywords)
+	}
+	if containsString(tr.EnglishKeywords, "dağların") || len(tr.EnglishKeywords) < 5 {
+		t.Errorf("expected only language-neutral english keywords, got %v", tr.EnglishKeywords)
+	}
+
+	ja := fallbackKeywordExtraction("夕焼けの山の風景", "image")
+	if ja.Language != LanguageJapanese || !containsString(ja.Keywords, "風景") {
+		t.Errorf("expected japanese keywords, got %s %v", ja.Language, ja.Keywords)
+	}
+
+	en := fallbackKeywordExtraction("golden sunset with mountains", "image")
+	if !reflect.DeepEqual(en.Keywords, en.EnglishKeywords) {
+		t.Errorf("expected english keywords to match keywords, got %v and %v", en.Keywords, en.EnglishKeywords)
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	result := &models.KeywordExtractionResponse{
+		Keywords: []string{"sunset", "mountains"},
+		Category: " Photography",
+		Style:    "Landscape",
+	}
+	normalizeLanguage(result, LanguageEnglish)
+	if result.Language != LanguageEnglish || !reflect.DeepEqual(result.EnglishKeywords, result.Keywords) {
+		t.Errorf("expected english keywords copied from keywords, got %+v", result)
+	}
+	if result.Category != "photography" || result.Style != "landscape" {
+		t.Errorf("expected lowercase category and style, got %q and %q", result.Category, result.Style)
+	}
+
+	result = &models.KeywordExtractionResponse{
+		Language:        "Spanish",
+		Keywords:        []string{"atardecer"},
+		EnglishKeywords: []string{" Sunset ", ""},
+	}
+	normalizeLanguage(result, LanguageSpanish)
+	if result.Language != LanguageSpanish || !reflect.DeepEqual(result.EnglishKeywords, []string{"sunset"}) {
+		t.Errorf("expected detected language and cleaned english keywords, got %+v", result)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// leaderLease is the stored lease of an elected instance
+type leaderLease struct {
+	Holder     string
+	AcquiredAt time.Time
+	RenewedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// LeaderElector elects one instance to run the background jobs that must not run twice, by
+// holding a lease on the leases/{name} Firestore document. The leader renews the lease every
+// third of its TTL; once a lease has not been renewed for its TTL another instance takes it
+// over, so a crashed leader is replaced within about one TTL. An instance that cannot renew
+// stops considering itself the leader when its lease runs out.
+type LeaderElector struct {
+	firestoreClient *FirestoreClient
+	name            string
+	holder          string
+	ttl             time.Duration
+	ctx             context.Context
+	cancel          context.CancelFunc
+
+	mu        sync.Mutex
+	leader    bool
+	expiresAt time.Time // end of the lease held, unless renewed
+	onElected []func()
+}
+
+func NewLeaderElector(firestoreClient *FirestoreClient, name string, ttl time.Duration) *LeaderElector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LeaderElector{
+		firestoreClient: firestoreClient,
+		name:            name,
+		holder:          instanceID(),
+		ttl:             ttl,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// instanceID identifies this process among the instances competing for a lease
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// OnElected runs fn in the background every time this instance becomes the leader, and right
+// away when it is the leader already
+func (le *LeaderElector) OnElected(fn func()) {
+	le.mu.Lock()
+	le.onElected = append(le.onElected, fn)
+	elected := le.leader && time.Now().Before(le.expiresAt)
+	le.mu.Unlock()
+	if elected {
+		go fn()
+	}
+}
+
+// IsLeader reports whether this instance holds the lease. Without an elector every instance
+// runs the jobs, as with a single instance.
+func (le *LeaderElector) IsLeader() bool {
+	if le == nil {
+		return true
+	}
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.leader && time.Now().Before(le.expiresAt)
+}
+
+// Start campaigns for the lease now, so IsLeader is settled when Start returns, and then every
+// third of the TTL
+func (le *LeaderElector) Start() {
+	logger.Infof("👑 Campaigning for the %s lease as %s (TTL %v)", le.name, le.holder, le.ttl)
+	le.campaign()
+
+	ticker := time.NewTicker(le.ttl / 3)
+	go func() {
+		for {
+			select {
+			case <-le.ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				le.campaign()
+			}
+		}
+	}()
+}
+
+// Stop stops campaigning and releases the lease, so another instance takes over right away
+func (le *LeaderElector) Stop() {
+	le.cancel()
+	if !le.IsLeader() {
+		return
+	}
+	if err := le.release(); err != nil {
+		logger.Errorf("❌ Failed to release the %s lease: %v", le.name, err)
+		return
+	}
+	logger.Infof("👑 Released the %s lease", le.name)
+}
+
+// campaign acquires or renews the lease and announces changes of leadership
+func (le *LeaderElector) campaign() {
+	wasLeader := le.IsLeader()
+	expiresAt, acquired, err := le.acquire()
+	if err != nil {
+		logger.Errorf("❌ Failed to renew the %s lease: %v", le.name, err)
+	}
+
+	le.mu.Lock()
+	if err == nil {
+		le.leader = acquired
+		le.expiresAt = expiresAt
+	}
+	elected := le.leader && time.Now().Before(le.expiresAt)
+	callbacks := le.onElected
+	le.mu.Unlock()
+
+	switch {
+	case elected && !wasLeader:
+		logger.Infof("👑 Elected leader of %s", le.name)
+		for _, fn := range callbacks {
+			go fn()
+		}
+	case !elected && wasLeader:
+		logger.Warnf("👑 Lost the %s lease", le.name)
+	}
+}
+
+// acquire takes the lease when it is free or expired, or renews it when this instance holds
+// it, returning when the lease held ends
+func (le *LeaderElector) acquire() (time.Time, bool, error) {
+	ref := le.firestoreClient.client.Collection("leases").Doc(le.name)
+	var expiresAt time.Time
+	acquired := false
+	err := le.firestoreClient.client.RunTransaction(le.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		Quotas.Record(QuotaFirestore, 2)
+		now := time.Now()
+		acquired = false
+
+		var current leaderLease
+		doc, err := tx.Get(ref)
+		if err != nil && !IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(&current); err != nil {
+				return err
+			}
+		}
+		if !leaseAvailable(current, le.holder, now) {
+			return nil
+		}
+
+		next := leaderLease{Holder: le.holder, AcquiredAt: current.AcquiredAt, RenewedAt: now, ExpiresAt: now.Add(le.ttl)}
+		if current.Holder != le.holder {
+			next.AcquiredAt = now
+		}
+		acquired, expiresAt = true, next.ExpiresAt
+		return tx.Set(ref, next)
+	})
+	return expiresAt, acquired, err
+}
+
+// leaseAvailable reports whether holder may take or renew a lease: when nobody holds it, when
+// it expired, or when holder holds it already
+func leaseAvailable(current leaderLease, holder string, now time.Time) bool {
+	return current.Holder == "" || current.Holder == holder || !now.Before(current.ExpiresAt)
+}
+
+// release expires the lease if this instance still holds it
+func (le *LeaderElector) release() error {
+	ref := le.firestoreClient.client.Collection("leases").Doc(le.name)
+	return le.firestoreClient.client.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		Quotas.Record(QuotaFirestore, 2)
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		var current leaderLease
+		if err := doc.DataTo(&current); err != nil {
+			return err
+		}
+		if current.Holder != le.holder {
+			return nil
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "ExpiresAt", Value: time.Now()}})
+	})
+}
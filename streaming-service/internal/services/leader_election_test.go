@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaseAvailable(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		lease    leaderLease
+		expected bool
+	}{
+		{"free", leaderLease{}, true},
+		{"held by this instance", leaderLease{Holder: "a", ExpiresAt: now.Add(time.Minute)}, true},
+		{"held by another instance", leaderLease{Holder: "b", ExpiresAt: now.Add(time.Minute)}, false},
+		{"expired", leaderLease{Holder: "b", ExpiresAt: now.Add(-time.Second)}, true},
+	}
+	for _, test := range tests {
+		if got := leaseAvailable(test.lease, "a", now); got != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestLeaderElectorIsLeader(t *testing.T) {
+	var unset *LeaderElector
+	if !unset.IsLeader() {
+		t.Error("Expected every instance to lead without an elector")
+	}
+
+	le := NewLeaderElector(nil, "jobs", time.Minute)
+	if le.IsLeader() {
+		t.Error("Expected no leadership before the lease is acquired")
+	}
+	le.leader, le.expiresAt = true, time.Now().Add(time.Minute)
+	if !le.IsLeader() {
+		t.Error("Expected leadership while the lease lasts")
+	}
+	le.expiresAt = time.Now().Add(-time.Second)
+	if le.IsLeader() {
+		t.Error("Expected leadership to end with an unrenewed lease")
+	}
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// Periods leaderboards are kept for: calendar days, ISO weeks starting on Monday and calendar
+// months in the reporting time zone
+const (
+	LeaderboardDay   = "day"
+	LeaderboardWeek  = "week"
+	LeaderboardMonth = "month"
+)
+
+// LeaderboardPeriods lists the leaderboard periods
+var LeaderboardPeriods = []string{LeaderboardDay, LeaderboardWeek, LeaderboardMonth}
+
+// How long leaderboard entries are kept after their period ends
+var leaderboardRetention = map[string]time.Duration{
+	LeaderboardDay:   35 * 24 * time.Hour,
+	LeaderboardWeek:  180 * 24 * time.Hour,
+	LeaderboardMonth: 400 * 24 * time.Hour,
+}
+
+// Subcollections of a leaderboard document holding the entries of posts and creators
+const (
+	leaderboardPosts    = "post_scores"
+	leaderboardCreators = "creator_scores"
+)
+
+// leaderboardKey identifies the entry of a post or creator on the leaderboard of one period
+type leaderboardKey struct {
+	board string // period and period ID, e.g. week_2024W19
+	kind  string // leaderboardPosts or leaderboardCreators
+	id    string
+}
+
+// leaderboardDelta is the score a post or creator gained and the interactions they received in
+// a period since the last flush
+type leaderboardDelta struct {
+	start        time.Time
+	expiresAt    time.Time
+	creatorID    string // posts only
+	contentType  string // posts only
+	score        float64
+	interactions int64
+}
+
+// leaderboardScore is a stored leaderboard entry
+type leaderboardScore struct {
+	ID           string
+	CreatorID    string
+	ContentType  string
+	Score        float64 // trending score gained in the period
+	Interactions int64   // likes, comments and shares received in the period
+	PeriodStart  time.Time
+	UpdatedAt    time.Time
+	ExpiresAt    time.Time // retention cutoff for the entry
+}
+
+// LeaderboardEntry is a post or creator ranked on a leaderboard. Ties in score are broken by
+// the engagement velocity over the period, the interactions per minute since it started.
+type LeaderboardEntry struct {
+	Rank               int     `json:"rank"`
+	ID                 string  `json:"id"`
+	CreatorID          string  `json:"creatorId,omitempty"`
+	ContentType        string  `json:"contentType,omitempty"`
+	Username           string  `json:"username,omitempty"`
+	DisplayName        string  `json:"displayName,omitempty"`
+	PhotoURL           string  `json:"photoUrl,omitempty"`
+	Score              float64 `json:"score"`
+	Interactions       int64   `json:"interactions"`
+	EngagementVelocity float64 `json:"engagementVelocity"`
+}
+
+// Leaderboard ranks the posts and creators by the trending score they gained in one period
+type Leaderboard struct {
+	Period       string             `json:"period"`
+	Start        time.Time          `json:"start"`
+	End          time.Time          `json:"end"`
+	Posts        []LeaderboardEntry `json:"posts"`
+	Creators     []LeaderboardEntry `json:"creators"`
+	CalculatedAt time.Time          `json:"calculatedAt"`
+}
+
+// IsLeaderboardPeriod reports whether period names a leaderboard period
+func IsLeaderboardPeriod(period string) bool {
+	for _, p := range LeaderboardPeriods {
+		if period == p {
+			return true
+		}
+	}
+	return false
+}
+
+// leaderboardPeriod returns the start and end of the period t falls in, in loc, and the ID of
+// its leaderboard
+func leaderboardPeriod(period string, t time.Time, loc *time.Location) (time.Time, time.Time, string) {
+	day := StartOfDay(t, loc)
+	switch period {
+	case LeaderboardWeek:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		year, week := start.ISOWeek()
+		return start, start.AddDate(0, 0, 7), fmt.Sprintf("%s_%04dW%02d", period, year, week)
+	case LeaderboardMonth:
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0), period + "_" + start.Format("200601")
+	default:
+		return day, day.AddDate(0, 0, 1), LeaderboardDay + "_" + day.Format("20060102")
+	}
+}
+
+// rankOnLeaderboards adds score gained and interactions received at now to the entries of a
+// post, and of its creator when known, on the leaderboards of every period; the caller holds
+// ma.mu
+func (ma *MetricsAggregator) rankOnLeaderboards(postID string, origin postOrigin, score float64, interactions int64, now time.Time) {
+	if postID == "" || (score == 0 && interactions == 0) {
+		return
+	}
+	for _, period := range LeaderboardPeriods {
+		start, end, board := leaderboardPeriod(period, now, ma.firestoreClient.reportingLoc)
+		expiresAt := end.Add(leaderboardRetention[period])
+		post := ma.leaderboardDelta(leaderboardKey{board: board, kind: leaderboardPosts, id: postID}, start, expiresAt)
+		if origin.creatorID != "" {
+			post.creatorID = origin.creatorID
+		}
+		if origin.contentType != "" {
+			post.contentType = origin.contentType
+		}
+		post.score += score
+		post.interactions += interactions
+		if origin.creatorID == "" {
+			continue
+		}
+		creator := ma.leaderboardDelta(leaderboardKey{board: board, kind: leaderboardCreators, id: origin.creatorID}, start, expiresAt)
+		creator.score += score
+		creator.interactions += interactions
+	}
+}
+
+// leaderboardDelta returns the pending delta of a leaderboard entry; the caller holds ma.mu
+func (ma *MetricsAggregator) leaderboardDelta(key leaderboardKey, start, expiresAt time.Time) *leaderboardDelta {
+	delta, ok := ma.leaderboards[key]
+	if !ok {
+		delta = &leaderboardDelta{start: start, expiresAt: expiresAt}
+		ma.leaderboards[key] = delta
+	}
+	return delta
+}
+
+// leaderboardRef returns the document of a leaderboard entry
+func (fc *FirestoreClient) leaderboardRef(key leaderboardKey) *firestore.DocumentRef {
+	return fc.client.Collection("leaderboards").Doc(key.board).Collection(key.kind).Doc(key.id)
+}
+
+// IncrementLeaderboards adds deltas to the stored leaderboard entries, creating the missing
+// ones. It returns the entries whose write failed with the last error.
+func (fc *FirestoreClient) IncrementLeaderboards(deltas map[leaderboardKey]*leaderboardDelta) ([]leaderboardKey, error) {
+	bw := fc.client.BulkWriter(fc.ctx)
+	keys := make([]leaderboardKey, 0, len(deltas))
+	jobs := make([]*firestore.BulkWriterJob, 0, len(deltas))
+	var failed []leaderboardKey
+	var lastErr error
+	for key, delta := range deltas {
+		data := map[string]interface{}{
+			"ID":           key.id,
+			"PeriodStart":  delta.start,
+			"Score":        firestore.Increment(delta.score),
+			"Interactions": firestore.Increment(delta.interactions),
+			"UpdatedAt":    time.Now(),
+			"ExpiresAt":    delta.expiresAt,
+		}
+		if delta.creatorID != "" {
+			data["CreatorID"] = delta.creatorID
+		}
+		if delta.contentType != "" {
+			data["ContentType"] = delta.contentType
+		}
+
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(fc.leaderboardRef(key), data, firestore.MergeAll)
+		if err != nil {
+			failed, lastErr = append(failed, key), err
+			continue
+		}
+		keys = append(keys, key)
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			failed, lastErr = append(failed, keys[i]), err
+		}
+	}
+	return failed, lastErr
+}
+
+// TopLeaderboardScores returns the entries of a leaderboard with the highest scores: at least
+// limit of them when there are as many, plus those tied with the last, which may rank above
+// it on engagement velocity
+func (fc *FirestoreClient) TopLeaderboardScores(board, kind string, limit int) ([]leaderboardScore, error) {
+	collection := fc.client.Collection("leaderboards").Doc(board).Collection(kind)
+	docs, err := collection.OrderBy("Score", firestore.Desc).Limit(limit).Documents(fc.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]leaderboardScore, 0, len(docs))
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		var score leaderboardScore
+		if err := doc.DataTo(&score); err != nil {
+			logger.Debugf(" Skipping unreadable leaderboard entry %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		scores = append(scores, score)
+		seen[score.ID] = true
+	}
+	if len(docs) < limit || len(scores) == 0 {
+		return scores, nil
+	}
+
+	ties, err := collection.Where("Score", "==", scores[len(scores)-1].Score).Documents(fc.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(ties)+1))
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range ties {
+		var score leaderboardScore
+		if err := doc.DataTo(&score); err == nil && !seen[score.ID] {
+			scores = append(scores, score)
+		}
+	}
+	return scores, nil
+}
+
+// GetLeaderboard returns the top posts and creators of the period at falls in
+func (da *DashboardAnalytics) GetLeaderboard(period string, at time.Time, limit int) (*Leaderboard, error) {
+	logger.Debugf("📊 Calculating %s leaderboard...", period)
+
+	now := time.Now()
+	start, end, board := leaderboardPeriod(period, at, da.firestoreClient.reportingLoc)
+	elapsed := end.Sub(start)
+	if now.Before(end) {
+		elapsed = now.Sub(start)
+	}
+
+	posts, err := da.firestoreClient.TopLeaderboardScores(board, leaderboardPosts, limit)
+	if err != nil {
+		return nil, err
+	}
+	creators, err := da.firestoreClient.TopLeaderboardScores(board, leaderboardCreators, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderboard := &Leaderboard{
+		Period:       period,
+		Start:        start,
+		End:          end,
+		Posts:        rankLeaderboard(posts, limit, elapsed),
+		Creators:     rankLeaderboard(creators, limit, elapsed),
+		CalculatedAt: now,
+	}
+	for i := range leaderboard.Creators {
+		entry := &leaderboard.Creators[i]
+		creator := CreatorMetrics{UserID: entry.ID}
+		if da.enrichCreator(&creator) {
+			entry.Username, entry.DisplayName, entry.PhotoURL = creator.Username, creator.DisplayName, creator.PhotoURL
+		}
+	}
+
+	logger.Infof("✅ %s leaderboard %s calculated: %d posts, %d creators", period, board, len(leaderboard.Posts), len(leaderboard.Creators))
+	return leaderboard, nil
+}
+
+// rankLeaderboard orders leaderboard entries by score, then by engagement velocity over the
+// elapsed part of their period, and keeps the first limit
+func rankLeaderboard(scores []leaderboardScore, limit int, elapsed time.Duration) []LeaderboardEntry {
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		// Over the same period, more interactions mean a higher velocity
+		if scores[i].Interactions != scores[j].Interactions {
+			return scores[i].Interactions > scores[j].Interactions
+		}
+		return scores[i].ID < scores[j].ID
+	})
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+
+	minutes := elapsed.Minutes()
+	if minutes < 1 {
+		minutes = 1
+	}
+	entries := make([]LeaderboardEntry, len(scores))
+	for i, score := range scores {
+		entries[i] = LeaderboardEntry{
+			Rank:               i + 1,
+			ID:                 score.ID,
+			CreatorID:          score.CreatorID,
+			ContentType:        score.ContentType,
+			Score:              score.Score,
+			Interactions:       score.Interactions,
+			EngagementVelocity: float64(score.Interactions) / minutes,
+		}
+	}
+	return entries
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestLeaderboardPeriod(t *testing.T) {
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+
+	// 22:30 UTC on Sunday 2024-05-12 is already Monday in Istanbul, the start of ISO week 20
+	at := time.Date(2024, 5, 12, 22, 30, 0, 0, time.UTC)
+	tests := []struct {
+		period string
+		start  time.Time
+		end    time.Time
+		board  string
+	}{
+		{LeaderboardDay, time.Date(2024, 5, 13, 0, 0, 0, 0, istanbul), time.Date(2024, 5, 14, 0, 0, 0, 0, istanbul), "day_20240513"},
+		{LeaderboardWeek, time.Date(2024, 5, 13, 0, 0, 0, 0, istanbul), time.Date(2024, 5, 20, 0, 0, 0, 0, istanbul), "week_2024W20"},
+		{LeaderboardMonth, time.Date(2024, 5, 1, 0, 0, 0, 0, istanbul), time.Date(2024, 6, 1, 0, 0, 0, 0, istanbul), "month_202405"},
+	}
+	for _, test := range tests {
+		start, end, board := leaderboardPeriod(test.period, at, istanbul)
+		if !start.Equal(test.start) || !end.Equal(test.end) || board != test.board {
+			t.Errorf("%s: got %v - %v %s, expected %v - %v %s", test.period, start, end, board, test.start, test.end, test.board)
+		}
+	}
+
+	// The first days of January can belong to the last ISO week of the previous year
+	if _, _, board := leaderboardPeriod(LeaderboardWeek, time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC), time.UTC); board != "week_2020W53" {
+		t.Errorf("Expected week_2020W53, got %s", board)
+	}
+}
+
+func TestRankLeaderboard(t *testing.T) {
+	scores := []leaderboardScore{
+		{ID: "slow", Score: 50, Interactions: 60},
+		{ID: "fast", Score: 50, Interactions: 120},
+		{ID: "top", Score: 80, Interactions: 10},
+		{ID: "low", Score: 5},
+	}
+	entries := rankLeaderboard(scores, 3, time.Hour)
+	if len(entries) != 3 || entries[0].ID != "top" || entries[1].ID != "fast" || entries[2].ID != "slow" {
+		t.Fatalf("Unexpected ranking %+v", entries)
+	}
+	if entries[1].Rank != 2 || entries[1].EngagementVelocity != 2 {
+		t.Errorf("Expected fast to rank second at 2 interactions per minute, got %+v", entries[1])
+	}
+}
+
+func TestMetricsAggregator_Leaderboards(t *testing.T) {
+	ma := NewMetricsAggregator(&FirestoreClient{reportingLoc: time.UTC}, 0, 0)
+	ma.RecordPost("post-1", "creator-1", "video")
+	ma.RecordInteraction("post-1", "viewer-1", "like")
+	ma.RecordInteraction("post-1", "viewer-1", "view")
+	ma.RecordScore(nil, &models.TrendingScore{PostID: "post-1", Score: 40})
+	ma.RecordScore(&models.TrendingScore{Score: 40}, &models.TrendingScore{PostID: "post-1", Score: 30})
+
+	if len(ma.leaderboards) != 2*len(LeaderboardPeriods) {
+		t.Fatalf("Expected post and creator entries for every period, got %d", len(ma.leaderboards))
+	}
+	_, _, board := leaderboardPeriod(LeaderboardWeek, time.Now(), time.UTC)
+	post := ma.leaderboards[leaderboardKey{board: board, kind: leaderboardPosts, id: "post-1"}]
+	if post == nil || post.score != 30 || post.interactions != 1 || post.creatorID != "creator-1" || post.contentType != "video" {
+		t.Errorf("Unexpected weekly post entry %+v", post)
+	}
+	creator := ma.leaderboards[leaderboardKey{board: board, kind: leaderboardCreators, id: "creator-1"}]
+	if creator == nil || creator.score != 30 || creator.interactions != 1 {
+		t.Errorf("Unexpected weekly creator entry %+v", creator)
+	}
+}
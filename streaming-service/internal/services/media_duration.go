@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+const (
+	// Longest media accepted; anything longer is almost certainly sent in the wrong unit
+	maxMediaDurationSeconds = 6 * 60 * 60
+
+	// Timed views a post needs before completion affects its trending score
+	minTimedViewsForCompletion = 5
+
+	// Bounds of the completion factor applied to the weight of views
+	minCompletionFactor = 0.5
+	maxCompletionFactor = 2.0
+)
+
+var isoDurationPattern = regexp.MustCompile(`^pt(?:(\d+(?:\.\d+)?)h)?(?:(\d+(?:\.\d+)?)m)?(?:(\d+(?:\.\d+)?)s)?$`)
+
+// normalizeMediaDuration folds the duration formats clients send into DurationSeconds.
+// duration_seconds wins over duration_ms, which wins over the duration string. An invalid
+// duration is dropped and reported.
+func normalizeMediaDuration(media *models.MediaMetadata) error {
+	seconds := media.DurationSeconds
+	var err error
+	switch {
+	case seconds > 0:
+	case media.DurationMs > 0:
+		seconds = float64(media.DurationMs) / 1000
+	case media.Duration != "":
+		seconds, err = parseMediaDuration(media.Duration)
+	}
+
+	media.DurationMs = 0
+	media.Duration = ""
+	media.DurationSeconds = 0
+	if err != nil {
+		return err
+	}
+	if seconds < 0 || seconds > maxMediaDurationSeconds {
+		return fmt.Errorf("media duration %.1fs out of range", seconds)
+	}
+	media.DurationSeconds = math.Round(seconds*1000) / 1000
+	return nil
+}
+
+// parseMediaDuration parses a duration given as plain seconds ("95.5"), a Go duration
+// ("1500ms", "1m35s"), a clock ("1:35", "1:01:35") or ISO 8601 ("PT1M35S") into seconds
+func parseMediaDuration(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return seconds, nil
+	}
+
+	if match := isoDurationPattern.FindStringSubmatch(value); match != nil && value != "pt" {
+		var seconds float64
+		for i, unit := range []float64{3600, 60, 1} {
+			if match[i+1] != "" {
+				n, _ := strconv.ParseFloat(match[i+1], 64)
+				seconds += n * unit
+			}
+		}
+		return seconds, nil
+	}
+
+	if parts := strings.Split(value, ":"); len(parts) > 1 && len(parts) <= 3 {
+		var seconds float64
+		for i, part := range parts {
+			n, err := strconv.ParseFloat(part, 64)
+			if err != nil || n < 0 || (i > 0 && n >= 60) {
+				return 0, fmt.Errorf("invalid media duration %q", value)
+			}
+			seconds = seconds*60 + n
+		}
+		return seconds, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid media duration %q", value)
+	}
+	return d.Seconds(), nil
+}
+
+// addWatchTime folds the watch time of one view into a post's totals and, when the duration of
+// its media is known, into its completion rate. Watch times longer than any media are capped.
+func addWatchTime(score *models.TrendingScore, watchedSeconds, durationSeconds float64) {
+	score.WatchSeconds += math.Min(watchedSeconds, maxMediaDurationSeconds)
+	score.WatchedViewCount++
+	if durationSeconds > 0 {
+		addViewCompletion(score, watchedSeconds, durationSeconds)
+	}
+}
+
+// avgWatchSeconds returns the mean watch time of a post's views that reported one, 0 when none
+// did
+func avgWatchSeconds(score models.TrendingScore) float64 {
+	if score.WatchedViewCount == 0 {
+		return 0
+	}
+	return score.WatchSeconds / float64(score.WatchedViewCount)
+}
+
+// addViewCompletion folds how much of the media one view watched into a post's running
+// completion rate. Replays of short loops count as a full view, not more.
+func addViewCompletion(score *models.TrendingScore, watchedSeconds, durationSeconds float64) {
+	completion := math.Min(watchedSeconds/durationSeconds, 1)
+
+	score.DurationSeconds = durationSeconds
+	score.TimedViewCount++
+	score.CompletionRate += (completion - score.CompletionRate) / float64(score.TimedViewCount)
+}
+
+// expectedCompletion is the completion rate typical for media of the given length: nearly
+// everyone finishes a 3-second loop, about a quarter of viewers finish a 3-minute song
+func expectedCompletion(durationSeconds float64) float64 {
+	return 1 / (1 + durationSeconds/60)
+}
+
+// relativeCompletion compares a post's completion rate with what is typical for its length,
+// returning 0 when the post has no timed views or no known duration
+func relativeCompletion(score models.TrendingScore) float64 {
+	if score.TimedViewCount == 0 || score.DurationSeconds <= 0 {
+		return 0
+	}
+	return score.CompletionRate / expectedCompletion(score.DurationSeconds)
+}
+
+// scoringOptions selects the optional inputs of the trending score formula
+type scoringOptions struct {
+	uniqueViewers bool // weigh unique viewers instead of raw views
+	completion    bool // scale the weight of views by duration-adjusted completion
+}
+
+// scoredViews returns the views the scoring formula weighs: the post's unique viewers when
+// enabled and known, its raw views otherwise, scaled by its completion factor when enabled.
+// Unique viewers are an estimate, so they never count for more than the raw views.
+func scoredViews(score models.TrendingScore, scoring scoringOptions) float64 {
+	views := float64(score.ViewCount)
+	if scoring.uniqueViewers && score.UniqueViewers > 0 && score.UniqueViewers < score.ViewCount {
+		views = float64(score.UniqueViewers)
+	}
+	if scoring.completion {
+		views *= completionFactor(score)
+	}
+	return views
+}
+
+// completionFactor scales the weight of a post's views by its duration-adjusted completion,
+// so a 3-minute song that is mostly watched gains on a 3-second loop that is always finished
+func completionFactor(score models.TrendingScore) float64 {
+	if score.TimedViewCount < minTimedViewsForCompletion {
+		return 1
+	}
+	relative := relativeCompletion(score)
+	if relative == 0 {
+		return 1
+	}
+	return math.Max(minCompletionFactor, math.Min(relative, maxCompletionFactor))
+}
+
+// PostDurationSeconds returns the normalized media duration of a post, or 0 when unknown
+func (fc *FirestoreClient) PostDurationSeconds(postID string) float64 {
+	Quotas.Record(QuotaFirestore, 1)
+	postDoc, err := fc.client.Collection("posts").Doc(postID).Get(fc.ctx)
+	if err != nil {
+		return 0
+	}
+
+	media, _ := postDoc.Data()["media"].(map[string]interface{})
+	return getFloat64(media, "duration_seconds")
+}
+
+// RecordViewCompletion adds the watch time of a view to the post's watch time and, when the
+// duration of its media is known, to its completion rate
+func (fc *FirestoreClient) RecordViewCompletion(postID string, watchedSeconds float64) error {
+	if watchedSeconds <= 0 {
+		return nil
+	}
+	duration := fc.PostDurationSeconds(postID)
+
+	_, err := fc.ApplyTrendingScore(postID, "view_completion", func(score *models.TrendingScore, exists bool) {
+		addWatchTime(score, watchedSeconds, duration)
+	})
+	return err
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestParseMediaDuration(t *testing.T) {
+	cases := map[string]float64{
+		"95":       95,
+		"95.5":     95.5,
+		"1500ms":   1.5,
+		"1m35s":    95,
+		"1:35":     95,
+		"1:01:35":  3695,
+		"PT1M35S":  95,
+		"pt2h":     7200,
+		" PT3.5S ": 3.5,
+	}
+	for value, want := range cases {
+		got, err := parseMediaDuration(value)
+		if err != nil || got != want {
+			t.Errorf("%q: expected %v, got %v (%v)", value, want, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "PT", "1:75", "three minutes", "1:2:3:4"} {
+		if _, err := parseMediaDuration(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestNormalizeMediaDuration(t *testing.T) {
+	media := models.MediaMetadata{DurationMs: 3250, Duration: "PT10S"}
+	if err := normalizeMediaDuration(&media); err != nil || media.DurationSeconds != 3.25 {
+		t.Errorf("expected milliseconds to win, got %+v (%v)", media, err)
+	}
+	if media.DurationMs != 0 || media.Duration != "" {
+		t.Errorf("expected alternative formats cleared, got %+v", media)
+	}
+
+	media = models.MediaMetadata{Duration: "3:05"}
+	if err := normalizeMediaDuration(&media); err != nil || media.DurationSeconds != 185 {
+		t.Errorf("expected 185s, got %+v (%v)", media, err)
+	}
+
+	// A duration sent in milliseconds as seconds is out of range and dropped
+	media = models.MediaMetadata{DurationSeconds: 185000}
+	if err := normalizeMediaDuration(&media); err == nil || media.DurationSeconds != 0 {
+		t.Errorf("expected out of range duration to be dropped, got %+v (%v)", media, err)
+	}
+}
+
+func TestCompletionFactor(t *testing.T) {
+	loop := models.TrendingScore{}
+	song := models.TrendingScore{}
+	for i := 0; i < 10; i++ {
+		addViewCompletion(&loop, 12, 3) // replayed four times
+		addViewCompletion(&song, 90, 180)
+	}
+
+	if loop.CompletionRate != 1 || loop.TimedViewCount != 10 || loop.DurationSeconds != 3 {
+		t.Errorf("expected replays capped at full completion, got %+v", loop)
+	}
+	if song.CompletionRate != 0.5 {
+		t.Errorf("expected half completion, got %v", song.CompletionRate)
+	}
+	if completionFactor(song) <= completionFactor(loop) {
+		t.Errorf("expected half-watched song to outweigh finished loop, got %v and %v",
+			completionFactor(song), completionFactor(loop))
+	}
+	if factor := completionFactor(song); factor > maxCompletionFactor {
+		t.Errorf("expected factor capped at %v, got %v", maxCompletionFactor, factor)
+	}
+
+	few := models.TrendingScore{}
+	addViewCompletion(&few, 1, 180)
+	if factor := completionFactor(few); factor != 1 {
+		t.Errorf("expected no adjustment below %d timed views, got %v", minTimedViewsForCompletion, factor)
+	}
+}
+
+func TestAddWatchTime(t *testing.T) {
+	score := models.TrendingScore{}
+	addWatchTime(&score, 30, 0) // duration unknown
+	addWatchTime(&score, 90, 180)
+	addWatchTime(&score, 2*maxMediaDurationSeconds, 180)
+
+	if score.WatchedViewCount != 3 || score.WatchSeconds != 120+maxMediaDurationSeconds {
+		t.Errorf("expected every timed view in the watch time, capped, got %+v", score)
+	}
+	if score.TimedViewCount != 2 || score.CompletionRate != 0.75 {
+		t.Errorf("expected only views of known duration in the completion rate, got %+v", score)
+	}
+	if avg := avgWatchSeconds(score); avg != score.WatchSeconds/3 {
+		t.Errorf("expected mean watch time, got %v", avg)
+	}
+	if avg := avgWatchSeconds(models.TrendingScore{}); avg != 0 {
+		t.Errorf("expected no mean without timed views, got %v", avg)
+	}
+}
+
+func TestScoredViews_Completion(t *testing.T) {
+	song := models.TrendingScore{ViewCount: 100}
+	for i := 0; i < 10; i++ {
+		addViewCompletion(&song, 90, 180)
+	}
+
+	if views := scoredViews(song, scoringOptions{}); views != 100 {
+		t.Errorf("expected raw views without completion weighting, got %v", views)
+	}
+	if views := scoredViews(song, scoringOptions{completion: true}); views != 100*completionFactor(song) {
+		t.Errorf("expected views scaled by the completion factor, got %v", views)
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF decoder
+	_ "image/jpeg" // register JPEG decoder
+	_ "image/png"  // register PNG decoder
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Largest image downloaded for metadata extraction
+const maxMediaDownloadBytes = 20 << 20
+
+var mediaHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// ExtractMediaMetadata builds the preview metadata of a post at metadata processing time.
+// Values supplied by the client win; missing dimensions and dominant color are filled by
+// decoding the thumbnail, which for images defaults to the first output URL. The duration is
+// normalized to seconds whatever format the client sent it in.
+func ExtractMediaMetadata(event models.ContentMetadata) (models.MediaMetadata, error) {
+	var media models.MediaMetadata
+	if event.Media != nil {
+		media = *event.Media
+	}
+	durationErr := normalizeMediaDuration(&media)
+
+	isImage := event.ContentType == "image" || event.ContentType == "art" || event.ContentType == "photography"
+	if media.ThumbnailURL == "" && isImage && len(event.OutputURLs) > 0 {
+		media.ThumbnailURL = event.OutputURLs[0]
+	}
+
+	needsDimensions := isImage && (media.Width == 0 || media.Height == 0)
+	if media.ThumbnailURL == "" || (!needsDimensions && media.DominantColor != "") {
+		return media, durationErr
+	}
+
+	img, err := fetchImage(media.ThumbnailURL)
+	if err != nil {
+		return media, err
+	}
+
+	if needsDimensions {
+		bounds := img.Bounds()
+		media.Width = bounds.Dx()
+		media.Height = bounds.Dy()
+	}
+	if media.DominantColor == "" {
+		media.DominantColor = dominantColor(img)
+	}
+	return media, durationErr
+}
+
+func fetchImage(url string) (image.Image, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("unsupported thumbnail URL %q", url)
+	}
+
+	resp, err := mediaHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("thumbnail download returned status %d", resp.StatusCode)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, maxMediaDownloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+	return img, nil
+}
+
+// dominantColor returns the most common color of an image as #rrggbb. Pixels are sampled on
+// a grid and bucketed by their top four bits per channel; the winning bucket is averaged.
+func dominantColor(img image.Image) string {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return ""
+	}
+
+	// Sample roughly 64x64 pixels regardless of image size
+	stepX := bounds.Dx()/64 + 1
+	stepY := bounds.Dy()/64 + 1
+
+	type bucket struct {
+		count   int
+		r, g, b uint64
+	}
+	buckets := make(map[uint16]*bucket)
+	var best *bucket
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a < 0x8000 {
+				continue // ignore mostly transparent pixels
+			}
+			r8, g8, b8 := r>>8, g>>8, b>>8
+			key := uint16(r8>>4)<<8 | uint16(g8>>4)<<4 | uint16(b8>>4)
+
+			bk, ok := buckets[key]
+			if !ok {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.count++
+			bk.r += uint64(r8)
+			bk.g += uint64(g8)
+			bk.b += uint64(b8)
+
+			if best == nil || bk.count > best.count {
+				best = bk
+			}
+		}
+	}
+
+	if best == nil {
+		return ""
+	}
+	n := uint64(best.count)
+	return fmt.Sprintf("#%02x%02x%02x", best.r/n, best.g/n, best.b/n)
+}
+
+// UpdateMediaMetadata stores preview metadata on the post document
+func (fc *FirestoreClient) UpdateMediaMetadata(postID string, media models.MediaMetadata) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("posts").Doc(postID).Set(fc.ctx, map[string]interface{}{
+		"media": map[string]interface{}{
+			"width":            media.Width,
+			"height":           media.Height,
+			"duration_seconds": media.DurationSeconds,
+			"thumbnail_url":    media.ThumbnailURL,
+			"dominant_color":   media.DominantColor,
+		},
+	}, firestore.MergeAll)
+	return err
+}
+
+// applyMediaMetadata copies the requested preview fields of a post document onto its score
+func applyMediaMetadata(score *models.TrendingScore, postData map[string]interface{}, fields FieldSet) {
+	media, ok := postData["media"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if fields.Has("width") {
+		score.Width = int(getInt64(media, "width"))
+	}
+	if fields.Has("height") {
+		score.Height = int(getInt64(media, "height"))
+	}
+	if fields.Has("duration_seconds") {
+		score.DurationSeconds = getFloat64(media, "duration_seconds")
+	}
+	if fields.Has("thumbnail_url") {
+		score.ThumbnailURL, _ = media["thumbnail_url"].(string)
+	}
+	if fields.Has("dominant_color") {
+		score.DominantColor, _ = media["dominant_color"].(string)
+	}
+}
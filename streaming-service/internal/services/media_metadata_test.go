@@ -0,0 +1,66 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestDominantColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			c := color.RGBA{R: 200, G: 30, B: 30, A: 255}
+			if x < 30 {
+				c = color.RGBA{R: 10, G: 10, B: 250, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	if got := dominantColor(img); got != "#c81e1e" {
+		t.Errorf("dominantColor = %s, want #c81e1e", got)
+	}
+	if got := dominantColor(image.NewRGBA(image.Rect(0, 0, 4, 4))); got != "" {
+		t.Errorf("Expected no color for fully transparent image, got %s", got)
+	}
+}
+
+func TestExtractMediaMetadataKeepsClientValues(t *testing.T) {
+	media, err := ExtractMediaMetadata(models.ContentMetadata{
+		ContentType: "video",
+		OutputURLs:  []string{"https://example.com/video.mp4"},
+		Media: &models.MediaMetadata{
+			DurationSeconds: 12.5,
+			ThumbnailURL:    "https://example.com/thumb.jpg",
+			DominantColor:   "#000000",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if media.DurationSeconds != 12.5 || media.ThumbnailURL != "https://example.com/thumb.jpg" {
+		t.Errorf("Unexpected media metadata: %+v", media)
+	}
+}
+
+func TestApplyMediaMetadata(t *testing.T) {
+	postData := map[string]interface{}{
+		"media": map[string]interface{}{
+			"width":          int64(1024),
+			"height":         int64(768),
+			"thumbnail_url":  "https://example.com/a.png",
+			"dominant_color": "#112233",
+		},
+	}
+
+	fields, _ := ParseTrendingFields("id,thumbnail,width")
+	var score models.TrendingScore
+	applyMediaMetadata(&score, postData, fields)
+
+	if score.Width != 1024 || score.Height != 0 || score.ThumbnailURL != "https://example.com/a.png" || score.DominantColor != "" {
+		t.Errorf("Unexpected enrichment: %+v", score)
+	}
+}
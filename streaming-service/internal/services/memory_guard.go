@@ -0,0 +1,191 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+// Memory pools accounted by the guard
+const (
+	MemoryPoolAICache   = "ai_cache"          // in-memory AI response cache
+	MemoryPoolWebSocket = "websocket_buffers" // messages queued for WebSocket clients
+	MemoryPoolAudience  = "audience_sketches" // creator viewer sketches awaiting a flush
+	MemoryPoolRetention = "viewer_sketches"   // daily viewer sketches awaiting a flush
+)
+
+// Rough per-entry bookkeeping cost (map slot, struct, string headers) added to payload sizes
+const memoryEntryOverhead = 64
+
+// Memory is the process-wide accounting of memory held by in-memory caches and buffers
+var Memory = NewMemoryGuard()
+
+// MemoryPoolState is the accounted usage of one pool
+type MemoryPoolState struct {
+	Pool       string  `json:"pool"`
+	UsedBytes  int64   `json:"used_bytes"`
+	LimitBytes int64   `json:"limit_bytes"` // 0 when unbounded
+	PeakBytes  int64   `json:"peak_bytes"`
+	Ratio      float64 `json:"ratio"`
+	Rejections int64   `json:"rejections"` // reservations refused at the ceiling
+	Warning    bool    `json:"warning"`
+}
+
+type memoryPool struct {
+	used       int64
+	limit      int64
+	peak       int64
+	rejections int64
+	warned     bool
+}
+
+// MemoryGuard tracks bytes reserved per pool against configurable ceilings. Owners reserve
+// before growing and evict or drop data when a reservation is refused. A warning is emitted
+// once a pool crosses the warning ratio of its ceiling, and again after it has recovered.
+type MemoryGuard struct {
+	mu           sync.Mutex
+	pools        map[string]*memoryPool
+	warningRatio float64
+	alerter      *Alerter
+}
+
+// NewMemoryGuard creates a guard with no ceilings configured
+func NewMemoryGuard() *MemoryGuard {
+	return &MemoryGuard{
+		pools:        make(map[string]*memoryPool),
+		warningRatio: 0.9,
+	}
+}
+
+// Configure sets the pool ceilings, warning ratio and alert channel from configuration
+func (m *MemoryGuard) Configure(cfg *config.Config, alerter *Alerter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pool(MemoryPoolAICache).limit = int64(cfg.MemoryLimitAICacheMB) << 20
+	m.pool(MemoryPoolWebSocket).limit = int64(cfg.MemoryLimitWebSocketMB) << 20
+	m.pool(MemoryPoolAudience).limit = int64(cfg.MemoryLimitAudienceMB) << 20
+	m.pool(MemoryPoolRetention).limit = int64(cfg.MemoryLimitRetentionMB) << 20
+	if cfg.MemoryWarningRatio > 0 && cfg.MemoryWarningRatio < 1 {
+		m.warningRatio = cfg.MemoryWarningRatio
+	}
+	m.alerter = alerter
+}
+
+// SetLimit sets the ceiling of a pool in bytes; 0 leaves it unbounded
+func (m *MemoryGuard) SetLimit(pool string, limit int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pool(pool).limit = limit
+}
+
+// pool returns a pool, creating it on first use; callers hold the lock
+func (m *MemoryGuard) pool(name string) *memoryPool {
+	p, ok := m.pools[name]
+	if !ok {
+		p = &memoryPool{}
+		m.pools[name] = p
+	}
+	return p
+}
+
+// Reserve accounts n more bytes to a pool, refusing when that would pass its ceiling
+func (m *MemoryGuard) Reserve(pool string, n int64) bool {
+	m.mu.Lock()
+
+	p := m.pool(pool)
+	if p.limit > 0 && p.used+n > p.limit {
+		p.rejections++
+		shouldWarn := !p.warned
+		p.warned = true
+		used, limit, ratio := p.used, p.limit, m.warningRatio
+		m.mu.Unlock()
+
+		if shouldWarn {
+			m.warn(pool, used, limit, ratio)
+		}
+		return false
+	}
+
+	p.used += n
+	if p.used > p.peak {
+		p.peak = p.used
+	}
+	shouldWarn := p.limit > 0 && !p.warned && float64(p.used) >= m.warningRatio*float64(p.limit)
+	if shouldWarn {
+		p.warned = true
+	}
+	used, limit, ratio := p.used, p.limit, m.warningRatio
+	m.mu.Unlock()
+
+	if shouldWarn {
+		m.warn(pool, used, limit, ratio)
+	}
+	return true
+}
+
+// Release returns n bytes to a pool
+func (m *MemoryGuard) Release(pool string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.pool(pool)
+	p.used -= n
+	if p.used < 0 {
+		p.used = 0
+	}
+	// Re-arm the warning once the pool has recovered
+	if p.warned && float64(p.used) < m.warningRatio*float64(p.limit) {
+		p.warned = false
+	}
+}
+
+// Used returns the bytes accounted to a pool
+func (m *MemoryGuard) Used(pool string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pool(pool).used
+}
+
+func (m *MemoryGuard) warn(pool string, used, limit int64, ratio float64) {
+	m.alerter.Notify(Alert{
+		Type:     "memory_warning",
+		Severity: AlertSeverityWarning,
+		Message:  fmt.Sprintf("%s memory reached %.0f%% of its ceiling (%d/%d bytes)", pool, float64(used)/float64(limit)*100, used, limit),
+		Details: map[string]interface{}{
+			"pool":          pool,
+			"used_bytes":    used,
+			"limit_bytes":   limit,
+			"warning_ratio": ratio,
+		},
+	})
+}
+
+// States returns the usage of every pool, sorted by pool name
+func (m *MemoryGuard) States() []MemoryPoolState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := make([]MemoryPoolState, 0, len(m.pools))
+	for name, p := range m.pools {
+		state := MemoryPoolState{
+			Pool:       name,
+			UsedBytes:  p.used,
+			LimitBytes: p.limit,
+			PeakBytes:  p.peak,
+			Rejections: p.rejections,
+		}
+		if p.limit > 0 {
+			state.Ratio = float64(p.used) / float64(p.limit)
+			state.Warning = state.Ratio >= m.warningRatio
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Pool < states[j].Pool
+	})
+	return states
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+func TestMemoryGuardCeiling(t *testing.T) {
+	mg := NewMemoryGuard()
+	mg.Configure(&config.Config{MemoryLimitWebSocketMB: 1, MemoryWarningRatio: 0.5}, nil)
+
+	if !mg.Reserve(MemoryPoolWebSocket, 600<<10) {
+		t.Fatal("Expected reservation below the ceiling to succeed")
+	}
+	if mg.Reserve(MemoryPoolWebSocket, 600<<10) {
+		t.Fatal("Expected reservation past the ceiling to be refused")
+	}
+	if !mg.Reserve(MemoryPoolAICache, 10<<20) {
+		t.Error("Expected unbounded pool to accept any reservation")
+	}
+
+	states := map[string]MemoryPoolState{}
+	for _, s := range mg.States() {
+		states[s.Pool] = s
+	}
+	ws := states[MemoryPoolWebSocket]
+	if ws.UsedBytes != 600<<10 || ws.Rejections != 1 || !ws.Warning {
+		t.Errorf("Unexpected websocket pool state: %+v", ws)
+	}
+
+	mg.Release(MemoryPoolWebSocket, 600<<10)
+	if used := mg.Used(MemoryPoolWebSocket); used != 0 {
+		t.Errorf("Expected pool to be empty after release, got %d bytes", used)
+	}
+	if mg.pools[MemoryPoolWebSocket].warned {
+		t.Error("Expected warning to re-arm after usage dropped below the ratio")
+	}
+	if peak := mg.pools[MemoryPoolWebSocket].peak; peak != 600<<10 {
+		t.Errorf("Expected peak of %d bytes, got %d", 600<<10, peak)
+	}
+}
+
+func TestMemoryAICacheAccounting(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryAICache(0)
+	cache.memory = NewMemoryGuard()
+	entrySize := int64(len("key-00") + len("value") + memoryEntryOverhead)
+	cache.memory.SetLimit(MemoryPoolAICache, 10*entrySize)
+
+	for i := 0; i < 30; i++ {
+		// Later entries live longer, so the oldest are evicted first
+		cache.Set(ctx, fmt.Sprintf("key-%02d", i), []byte("value"), time.Duration(i+1)*time.Minute)
+		if used := cache.memory.Used(MemoryPoolAICache); used > 10*entrySize {
+			t.Fatalf("cache grew past its memory ceiling: %d bytes", used)
+		}
+	}
+
+	if _, ok, _ := cache.Get(ctx, "key-29"); !ok {
+		t.Error("expected newest entry to be kept")
+	}
+	if _, ok, _ := cache.Get(ctx, "key-00"); ok {
+		t.Error("expected oldest entry to be evicted")
+	}
+	if used := cache.memory.Used(MemoryPoolAICache); used != int64(len(cache.entries))*entrySize {
+		t.Errorf("expected %d entries to account %d bytes, got %d", len(cache.entries), int64(len(cache.entries))*entrySize, used)
+	}
+}
@@ -0,0 +1,719 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Longest time range the dashboard is computed over, and how long the hourly buckets backing
+// it are kept
+const (
+	MaxDashboardWindow       = 30 * 24 * time.Hour
+	dashboardBucketRetention = 90 * 24 * time.Hour
+)
+
+// dashboardTotals are the running dashboard aggregates kept in dashboard_metrics/current
+type dashboardTotals struct {
+	Views      int64
+	Likes      int64
+	Comments   int64
+	Shares     int64
+	Remixes    int64
+	Posts      int64
+	ViralPosts int64
+	ScoreSum   float64
+
+	// Posts per content type
+	ContentTypes map[string]int64
+
+	// HyperLogLog sketch of the creators with posts
+	ActiveUsers []byte
+
+	UpdatedAt time.Time
+	RebuiltAt time.Time
+}
+
+// dashboardBucket holds the dashboard counts of the events consumed during one hour
+type dashboardBucket struct {
+	Hour       time.Time
+	Views      int64
+	Likes      int64
+	Comments   int64
+	Shares     int64
+	Remixes    int64
+	Posts      int64
+	ViralPosts int64 // posts that went viral
+
+	ContentTypes map[string]dashboardContentCounts
+	Creators     map[string]dashboardCreatorCounts
+	Segments     map[string]dashboardSegmentCounts // by "platform|device|region" of the views
+
+	ExpiresAt time.Time // retention cutoff for the bucket
+}
+
+// dashboardContentCounts are the counts of one content type in a bucket
+type dashboardContentCounts struct {
+	Posts int64
+	Views int64
+	Likes int64
+}
+
+// dashboardCreatorCounts are the counts of one creator's posts in a bucket
+type dashboardCreatorCounts struct {
+	Posts      int64
+	ViralPosts int64
+	Views      int64
+	Likes      int64
+	Comments   int64
+	Shares     int64
+	Remixes    int64
+	Score      float64 // trending score gained
+}
+
+// add returns the sum of two content type counts
+func (c dashboardContentCounts) add(other dashboardContentCounts) dashboardContentCounts {
+	c.Posts += other.Posts
+	c.Views += other.Views
+	c.Likes += other.Likes
+	return c
+}
+
+// add adds other creator counts
+func (c *dashboardCreatorCounts) add(other dashboardCreatorCounts) {
+	c.Posts += other.Posts
+	c.ViralPosts += other.ViralPosts
+	c.Views += other.Views
+	c.Likes += other.Likes
+	c.Comments += other.Comments
+	c.Shares += other.Shares
+	c.Remixes += other.Remixes
+	c.Score += other.Score
+}
+
+// postOrigin is the creator and content type a post's events are counted under
+type postOrigin struct {
+	creatorID   string
+	contentType string
+}
+
+// isDashboardViral reports whether a post counts as viral on the dashboard
+func isDashboardViral(score *models.TrendingScore) bool {
+	return score != nil && (score.Score > 100 || score.ViralProbability > 0.7)
+}
+
+// MetricsAggregator maintains the dashboard metrics incrementally. Consumed events are counted
+// in memory and added to the stored totals on every flush, in a transaction, so several
+// instances can flush safely. Score changes are only known where the previous score is read
+// (Flink trending scores), so the totals are rebuilt from a full scan every rebuild interval
+// to correct the drift. The same events are also counted in hourly buckets, by creator, content
+// type and the platform, device and region of the views, which the dashboard sums to compute
+// its metrics over a time range, in the aggregates of each creator and their posts behind the
+// creator dashboards, and on the daily, weekly and monthly leaderboards.
+type MetricsAggregator struct {
+	firestoreClient *FirestoreClient
+	analytics       *DashboardAnalytics
+	ctx             context.Context
+	cancel          context.CancelFunc
+	flushInterval   time.Duration
+	rebuildInterval time.Duration
+
+	mu      sync.Mutex
+	pending  dashboardTotals
+	users    *HyperLogLog                   // creators seen since the last flush
+	hours    map[time.Time]*dashboardBucket // hourly buckets since the last flush
+	creators map[string]*creatorDelta       // creator aggregates since the last flush
+	origins  map[string]postOrigin          // post -> creator and content type
+	viewers  map[string]string              // user -> segment key of their last view
+
+	leaderboards map[leaderboardKey]*leaderboardDelta // leaderboard entries since the last flush
+}
+
+// NewMetricsAggregator creates an aggregator that flushes every flushInterval and rebuilds the
+// totals every rebuildInterval (never when 0)
+func NewMetricsAggregator(firestoreClient *FirestoreClient, flushInterval, rebuildInterval time.Duration) *MetricsAggregator {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &MetricsAggregator{
+		firestoreClient: firestoreClient,
+		analytics:       NewDashboardAnalytics(firestoreClient),
+		ctx:             ctx,
+		cancel:          cancel,
+		flushInterval:   flushInterval,
+		rebuildInterval: rebuildInterval,
+		pending:         dashboardTotals{ContentTypes: make(map[string]int64)},
+		users:           NewHyperLogLog(),
+		hours:           make(map[time.Time]*dashboardBucket),
+		creators:        make(map[string]*creatorDelta),
+		origins:         make(map[string]postOrigin),
+		viewers:         make(map[string]string),
+		leaderboards:    make(map[leaderboardKey]*leaderboardDelta),
+	}
+}
+
+// Start begins the periodic flush and rebuild loops
+func (ma *MetricsAggregator) Start() {
+	logger.Infof("📊 Starting dashboard metrics aggregator (flush interval %v, rebuild interval %v)", ma.flushInterval, ma.rebuildInterval)
+
+	flush := time.NewTicker(ma.flushInterval)
+	var rebuild *time.Ticker
+	var rebuildC <-chan time.Time
+	if ma.rebuildInterval > 0 {
+		rebuild = time.NewTicker(ma.rebuildInterval)
+		rebuildC = rebuild.C
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ma.ctx.Done():
+				flush.Stop()
+				if rebuild != nil {
+					rebuild.Stop()
+				}
+				logger.Info("🛑 Dashboard metrics aggregator stopped")
+				return
+			case <-flush.C:
+				if err := ma.Flush(); err != nil {
+					logger.Errorf("❌ Dashboard metrics flush failed: %v", err)
+				}
+			case <-rebuildC:
+				if err := ma.Rebuild(); err != nil {
+					logger.Errorf("❌ Dashboard metrics rebuild failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the loops and writes out the counts still buffered
+func (ma *MetricsAggregator) Stop() {
+	ma.cancel()
+	if err := ma.Flush(); err != nil {
+		logger.Errorf("❌ Final dashboard metrics flush failed: %v", err)
+	}
+}
+
+// RecordInteraction counts a consumed interaction of a user with a post by its type
+func (ma *MetricsAggregator) RecordInteraction(postID, userID, eventType string) {
+	if ma == nil {
+		return
+	}
+
+	var counts dashboardCreatorCounts
+	switch eventType {
+	case "view":
+		counts.Views = 1
+	case "like":
+		counts.Likes = 1
+	case "comment":
+		counts.Comments = 1
+	case "share":
+		counts.Shares = 1
+	default:
+		return
+	}
+	ma.countEngagement(postID, counts)
+
+	// Interactions carry no platform or region; they count under the user's last view
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	key, ok := ma.viewers[userID]
+	if !ok {
+		key = newViewSegment("", "", "").key()
+	}
+	ma.bucket(time.Now()).addSegment(key, dashboardSegmentCounts{
+		Views:    counts.Views,
+		Likes:    counts.Likes,
+		Comments: counts.Comments,
+		Shares:   counts.Shares,
+	})
+}
+
+// RecordView counts a consumed view under its post and the platform, device and region it
+// came from. viral tells whether the post was viral when viewed.
+func (ma *MetricsAggregator) RecordView(event models.ViewEvent, viral bool) {
+	if ma == nil {
+		return
+	}
+	ma.countEngagement(event.PostID, dashboardCreatorCounts{Views: 1})
+
+	counts := dashboardSegmentCounts{Views: 1, WatchSeconds: int64(event.Duration)}
+	if viral {
+		counts.ViralViews = 1
+	}
+	key := newViewSegment(event.Platform, event.DeviceType, event.Region).key()
+
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	ma.bucket(time.Now()).addSegment(key, counts)
+	if event.UserID != "" {
+		if len(ma.viewers) >= maxCachedPostOwners {
+			ma.viewers = make(map[string]string)
+		}
+		ma.viewers[event.UserID] = key
+	}
+}
+
+// RecordRemix counts a consumed remix of a post
+func (ma *MetricsAggregator) RecordRemix(postID string) {
+	if ma == nil {
+		return
+	}
+	ma.countEngagement(postID, dashboardCreatorCounts{Remixes: 1})
+}
+
+// countEngagement adds engagement with a post to the totals, the bucket of the current hour
+// and the aggregates of the post's creator
+func (ma *MetricsAggregator) countEngagement(postID string, counts dashboardCreatorCounts) {
+	origin := ma.postOrigin(postID)
+	content := dashboardContentCounts{Views: counts.Views, Likes: counts.Likes}
+
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	ma.pending.Views += counts.Views
+	ma.pending.Likes += counts.Likes
+	ma.pending.Comments += counts.Comments
+	ma.pending.Shares += counts.Shares
+	ma.pending.Remixes += counts.Remixes
+
+	now := time.Now()
+	ma.rankOnLeaderboards(postID, origin, 0, counts.Likes+counts.Comments+counts.Shares, now)
+	bucket := ma.bucket(now)
+	bucket.Views += counts.Views
+	bucket.Likes += counts.Likes
+	bucket.Comments += counts.Comments
+	bucket.Shares += counts.Shares
+	bucket.Remixes += counts.Remixes
+	if origin.contentType != "" {
+		bucket.ContentTypes[origin.contentType] = bucket.ContentTypes[origin.contentType].add(content)
+	}
+	if origin.creatorID == "" {
+		return
+	}
+	creatorCounts := bucket.Creators[origin.creatorID]
+	creatorCounts.add(counts)
+	bucket.Creators[origin.creatorID] = creatorCounts
+
+	creator := ma.creator(origin.creatorID)
+	creator.counts.add(counts)
+	post := creator.post(postID)
+	post.counts.add(counts)
+	if origin.contentType != "" {
+		creator.contentTypes[origin.contentType] = creator.contentTypes[origin.contentType].add(content)
+		post.contentType = origin.contentType
+	}
+}
+
+// RecordPost counts a new post of a creator
+func (ma *MetricsAggregator) RecordPost(postID, userID, contentType string) {
+	if ma == nil {
+		return
+	}
+
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	ma.rememberOrigin(postID, postOrigin{creatorID: userID, contentType: contentType})
+	bucket := ma.bucket(time.Now())
+	ma.pending.Posts++
+	bucket.Posts++
+	if contentType != "" {
+		ma.pending.ContentTypes[contentType]++
+		bucket.ContentTypes[contentType] = bucket.ContentTypes[contentType].add(dashboardContentCounts{Posts: 1})
+	}
+	if userID == "" {
+		return
+	}
+	ma.users.Add(userID)
+	creatorCounts := bucket.Creators[userID]
+	creatorCounts.Posts++
+	bucket.Creators[userID] = creatorCounts
+
+	creator := ma.creator(userID)
+	creator.counts.Posts++
+	if contentType != "" {
+		creator.contentTypes[contentType] = creator.contentTypes[contentType].add(dashboardContentCounts{Posts: 1})
+		creator.post(postID).contentType = contentType
+	}
+}
+
+// RecordScore applies the change from a post's previous trending score, nil for a post
+// without one, to its current score
+func (ma *MetricsAggregator) RecordScore(previous, current *models.TrendingScore) {
+	if ma == nil || current == nil {
+		return
+	}
+	origin := ma.postOrigin(current.PostID)
+	now := time.Now()
+
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	gained := current.Score
+	if previous != nil {
+		gained -= previous.Score
+	}
+	ma.pending.ScoreSum += gained
+	ma.rankOnLeaderboards(current.PostID, origin, gained, 0, now)
+
+	wentViral := isDashboardViral(current) && !isDashboardViral(previous)
+	if wentViral {
+		ma.pending.ViralPosts++
+	} else if isDashboardViral(previous) && !isDashboardViral(current) {
+		ma.pending.ViralPosts--
+	}
+	bucket := ma.bucket(now)
+	if wentViral {
+		bucket.ViralPosts++
+	}
+	if origin.creatorID == "" {
+		return
+	}
+	counts := dashboardCreatorCounts{Score: gained}
+	if wentViral {
+		counts.ViralPosts = 1
+	}
+	creatorCounts := bucket.Creators[origin.creatorID]
+	creatorCounts.add(counts)
+	bucket.Creators[origin.creatorID] = creatorCounts
+
+	creator := ma.creator(origin.creatorID)
+	creator.counts.add(counts)
+	creator.scoreByDay[ReportingDate(now, ma.firestoreClient.reportingLoc)] += gained
+	post := creator.post(current.PostID)
+	score := current.Score
+	post.score = &score
+	if wentViral {
+		post.viralAt = now
+	}
+}
+
+// creator returns the pending aggregate delta of a creator; the caller holds ma.mu
+func (ma *MetricsAggregator) creator(creatorID string) *creatorDelta {
+	delta, ok := ma.creators[creatorID]
+	if !ok {
+		delta = newCreatorDelta()
+		ma.creators[creatorID] = delta
+	}
+	return delta
+}
+
+// bucket returns the pending bucket of the hour of t; the caller holds ma.mu
+func (ma *MetricsAggregator) bucket(t time.Time) *dashboardBucket {
+	hour := t.UTC().Truncate(time.Hour)
+	bucket, ok := ma.hours[hour]
+	if !ok {
+		bucket = newDashboardBucket(hour)
+		ma.hours[hour] = bucket
+	}
+	return bucket
+}
+
+// postOrigin returns the creator and content type of a post, reading the post document on a
+// cache miss. Posts that cannot be read are counted in the totals only.
+func (ma *MetricsAggregator) postOrigin(postID string) postOrigin {
+	ma.mu.Lock()
+	origin, ok := ma.origins[postID]
+	ma.mu.Unlock()
+	if ok || postID == "" {
+		return origin
+	}
+
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := ma.firestoreClient.client.Collection("posts").Doc(postID).Get(ma.firestoreClient.ctx)
+	if err != nil {
+		logger.Debugf(" Could not resolve creator of post %s for dashboard metrics: %v", postID, err)
+		return origin
+	}
+	origin.creatorID, _ = doc.Data()["userId"].(string)
+	origin.contentType, _ = doc.Data()["contentType"].(string)
+
+	ma.mu.Lock()
+	ma.rememberOrigin(postID, origin)
+	ma.mu.Unlock()
+	return origin
+}
+
+// rememberOrigin caches the origin of a post; the caller holds ma.mu
+func (ma *MetricsAggregator) rememberOrigin(postID string, origin postOrigin) {
+	if postID == "" {
+		return
+	}
+	if len(ma.origins) >= maxCachedPostOwners {
+		ma.origins = make(map[string]postOrigin)
+	}
+	ma.origins[postID] = origin
+}
+
+// Flush adds the counts recorded since the last flush to the stored totals, hourly buckets and
+// creator aggregates
+func (ma *MetricsAggregator) Flush() error {
+	ma.mu.Lock()
+	delta, users, hours, creators, leaderboards := ma.pending, ma.users, ma.hours, ma.creators, ma.leaderboards
+	ma.pending = dashboardTotals{ContentTypes: make(map[string]int64)}
+	ma.users = NewHyperLogLog()
+	ma.hours = make(map[time.Time]*dashboardBucket)
+	ma.creators = make(map[string]*creatorDelta)
+	ma.leaderboards = make(map[leaderboardKey]*leaderboardDelta)
+	ma.mu.Unlock()
+
+	var lastErr error
+	if !delta.isZero() || users.Count() > 0 {
+		if err := ma.firestoreClient.MergeDashboardTotals(delta, users); err != nil {
+			// Keep the counts for the next flush
+			ma.mu.Lock()
+			ma.pending.add(delta)
+			ma.users.Merge(users)
+			ma.mu.Unlock()
+			lastErr = err
+		}
+	}
+	for _, bucket := range hours {
+		if err := ma.firestoreClient.MergeDashboardBucket(bucket); err != nil {
+			ma.mu.Lock()
+			ma.bucket(bucket.Hour).add(bucket)
+			ma.mu.Unlock()
+			lastErr = err
+		}
+	}
+	if len(creators) > 0 {
+		failed, err := ma.firestoreClient.IncrementCreatorAggregates(creators)
+		ma.mu.Lock()
+		for creatorID, delta := range failed {
+			ma.creator(creatorID).add(delta)
+		}
+		ma.mu.Unlock()
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if len(leaderboards) > 0 {
+		failed, err := ma.firestoreClient.IncrementLeaderboards(leaderboards)
+		ma.mu.Lock()
+		for _, key := range failed {
+			pending := leaderboards[key]
+			retry := ma.leaderboardDelta(key, pending.start, pending.expiresAt)
+			retry.creatorID, retry.contentType = pending.creatorID, pending.contentType
+			retry.score += pending.score
+			retry.interactions += pending.interactions
+		}
+		ma.mu.Unlock()
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Rebuild recomputes the stored totals from every trending score and post
+func (ma *MetricsAggregator) Rebuild() error {
+	start := time.Now()
+
+	// Counts recorded from here on are part of the scan or flushed on top of it. Hourly
+	// buckets are not part of the scan and are kept.
+	ma.mu.Lock()
+	ma.pending = dashboardTotals{ContentTypes: make(map[string]int64)}
+	ma.users = NewHyperLogLog()
+	ma.mu.Unlock()
+
+	totals, err := ma.analytics.scanDashboardTotals()
+	if err != nil {
+		return err
+	}
+	if err := ma.firestoreClient.SaveDashboardTotals(totals); err != nil {
+		return err
+	}
+	logger.Infof("📊 Rebuilt dashboard metrics from %d posts in %v", totals.Posts, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// isZero reports whether a delta changes nothing
+func (t *dashboardTotals) isZero() bool {
+	return t.Views == 0 && t.Likes == 0 && t.Comments == 0 && t.Shares == 0 && t.Remixes == 0 &&
+		t.Posts == 0 && t.ViralPosts == 0 && t.ScoreSum == 0 && len(t.ContentTypes) == 0
+}
+
+// add adds the counts of a delta
+func (t *dashboardTotals) add(delta dashboardTotals) {
+	t.Views += delta.Views
+	t.Likes += delta.Likes
+	t.Comments += delta.Comments
+	t.Shares += delta.Shares
+	t.Remixes += delta.Remixes
+	t.Posts += delta.Posts
+	t.ViralPosts += delta.ViralPosts
+	t.ScoreSum += delta.ScoreSum
+	if t.ContentTypes == nil {
+		t.ContentTypes = make(map[string]int64)
+	}
+	for contentType, count := range delta.ContentTypes {
+		t.ContentTypes[contentType] += count
+	}
+}
+
+// newDashboardBucket creates an empty bucket of an hour
+func newDashboardBucket(hour time.Time) *dashboardBucket {
+	return &dashboardBucket{
+		Hour:         hour,
+		ContentTypes: make(map[string]dashboardContentCounts),
+		Creators:     make(map[string]dashboardCreatorCounts),
+		Segments:     make(map[string]dashboardSegmentCounts),
+		ExpiresAt:    hour.Add(dashboardBucketRetention),
+	}
+}
+
+// add adds the counts of another bucket
+func (b *dashboardBucket) add(other *dashboardBucket) {
+	b.Views += other.Views
+	b.Likes += other.Likes
+	b.Comments += other.Comments
+	b.Shares += other.Shares
+	b.Remixes += other.Remixes
+	b.Posts += other.Posts
+	b.ViralPosts += other.ViralPosts
+	if b.ContentTypes == nil {
+		b.ContentTypes = make(map[string]dashboardContentCounts)
+	}
+	for contentType, counts := range other.ContentTypes {
+		b.ContentTypes[contentType] = b.ContentTypes[contentType].add(counts)
+	}
+	if b.Creators == nil {
+		b.Creators = make(map[string]dashboardCreatorCounts)
+	}
+	for creatorID, counts := range other.Creators {
+		sum := b.Creators[creatorID]
+		sum.add(counts)
+		b.Creators[creatorID] = sum
+	}
+	if b.Segments == nil {
+		b.Segments = make(map[string]dashboardSegmentCounts)
+	}
+	for key, counts := range other.Segments {
+		b.addSegment(key, counts)
+	}
+}
+
+// dashboardMetricsRef is the document holding the dashboard totals
+func (fc *FirestoreClient) dashboardMetricsRef() *firestore.DocumentRef {
+	return fc.client.Collection("dashboard_metrics").Doc("current")
+}
+
+// LoadDashboardTotals returns the stored dashboard totals, nil when they were never computed
+func (fc *FirestoreClient) LoadDashboardTotals() (*dashboardTotals, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := fc.dashboardMetricsRef().Get(fc.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var totals dashboardTotals
+	if err := doc.DataTo(&totals); err != nil {
+		return nil, fmt.Errorf("failed to parse dashboard metrics: %w", err)
+	}
+	return &totals, nil
+}
+
+// SaveDashboardTotals replaces the stored dashboard totals
+func (fc *FirestoreClient) SaveDashboardTotals(totals *dashboardTotals) error {
+	Quotas.Record(QuotaFirestore, 1)
+	totals.UpdatedAt = time.Now()
+	totals.RebuiltAt = totals.UpdatedAt
+	_, err := fc.dashboardMetricsRef().Set(fc.ctx, totals)
+	return err
+}
+
+// MergeDashboardTotals adds a delta and the creators seen with it to the stored totals. Without
+// stored totals the delta is not applied: the first request computes them from a full scan.
+func (fc *FirestoreClient) MergeDashboardTotals(delta dashboardTotals, users *HyperLogLog) error {
+	ref := fc.dashboardMetricsRef()
+
+	return fc.client.RunTransaction(fc.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// One read and one write per attempt
+		Quotas.Record(QuotaFirestore, 2)
+
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var totals dashboardTotals
+		if err := doc.DataTo(&totals); err != nil {
+			return fmt.Errorf("failed to parse dashboard metrics: %w", err)
+		}
+
+		totals.add(delta)
+		merged := NewHyperLogLog()
+		if existing, err := HyperLogLogFromBytes(totals.ActiveUsers); err == nil {
+			merged = existing
+		}
+		merged.Merge(users)
+		totals.ActiveUsers = merged.Bytes()
+		totals.UpdatedAt = time.Now()
+		return tx.Set(ref, totals)
+	})
+}
+
+// MergeDashboardBucket adds the counts of a bucket to the stored bucket of the same hour
+func (fc *FirestoreClient) MergeDashboardBucket(bucket *dashboardBucket) error {
+	ref := fc.client.Collection("dashboard_metrics_hourly").Doc(bucket.Hour.Format("2006010215"))
+
+	return fc.client.RunTransaction(fc.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// One read and one write per attempt
+		Quotas.Record(QuotaFirestore, 2)
+
+		stored := newDashboardBucket(bucket.Hour)
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(stored); err != nil {
+				return fmt.Errorf("failed to parse dashboard bucket: %w", err)
+			}
+		}
+
+		stored.add(bucket)
+		stored.ExpiresAt = bucket.Hour.Add(dashboardBucketRetention)
+		return tx.Set(ref, stored)
+	})
+}
+
+// LoadDashboardBuckets returns the hourly dashboard buckets from the hour of from up to to
+func (fc *FirestoreClient) LoadDashboardBuckets(from, to time.Time) ([]dashboardBucket, error) {
+	iter := fc.client.Collection("dashboard_metrics_hourly").
+		Where("Hour", ">=", from.UTC().Truncate(time.Hour)).
+		Where("Hour", "<", to).
+		Documents(fc.ctx)
+	defer iter.Stop()
+
+	var buckets []dashboardBucket
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		Quotas.Record(QuotaFirestore, 1)
+
+		var bucket dashboardBucket
+		if err := doc.DataTo(&bucket); err != nil {
+			continue
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestMetricsAggregator_Record(t *testing.T) {
+	ma := NewMetricsAggregator(&FirestoreClient{reportingLoc: time.UTC}, 0, 0)
+
+	// Posts counted here are known, so their interactions need no post lookup
+	ma.RecordPost("post-1", "creator-1", "video")
+	ma.RecordPost("post-2", "creator-1", "image")
+	ma.RecordView(models.ViewEvent{PostID: "post-2", UserID: "viewer-1", Duration: 30, Platform: "Mobile", DeviceType: "ios", Region: "us-ca"}, true)
+	for _, eventType := range []string{"view", "like", "like", "comment", "share", "unknown"} {
+		ma.RecordInteraction("post-1", "viewer-1", eventType)
+	}
+	ma.RecordRemix("post-1")
+
+	// A new post, then the same post crossing the viral line and falling back
+	ma.RecordScore(nil, &models.TrendingScore{PostID: "post-1", Score: 40})
+	ma.RecordScore(&models.TrendingScore{Score: 40}, &models.TrendingScore{PostID: "post-1", Score: 120})
+	ma.RecordScore(&models.TrendingScore{Score: 120}, &models.TrendingScore{PostID: "post-1", Score: 150})
+
+	p := ma.pending
+	if p.Views != 2 || p.Likes != 2 || p.Comments != 1 || p.Shares != 1 || p.Remixes != 1 {
+		t.Errorf("Unexpected interaction counts %+v", p)
+	}
+	if p.Posts != 2 || p.ContentTypes["video"] != 1 || p.ContentTypes["image"] != 1 {
+		t.Errorf("Unexpected post counts %+v", p)
+	}
+	if p.ScoreSum != 150 || p.ViralPosts != 1 {
+		t.Errorf("Expected score sum 150 and one viral post, got %.0f and %d", p.ScoreSum, p.ViralPosts)
+	}
+	if ma.users.Count() != 1 {
+		t.Errorf("Expected one active creator, got %d", ma.users.Count())
+	}
+
+	// The hourly buckets hold the same counts, by creator and content type
+	sum := newDashboardBucket(time.Time{})
+	for _, bucket := range ma.hours {
+		sum.add(bucket)
+	}
+	if sum.Views != 2 || sum.Likes != 2 || sum.Posts != 2 || sum.ViralPosts != 1 || sum.Remixes != 1 {
+		t.Errorf("Unexpected bucket counts %+v", sum)
+	}
+	if creator := sum.Creators["creator-1"]; creator.Posts != 2 || creator.Views != 2 || creator.Likes != 2 || creator.Comments != 1 || creator.Score != 150 || creator.ViralPosts != 1 {
+		t.Errorf("Unexpected creator counts %+v", creator)
+	}
+	if video := sum.ContentTypes["video"]; video.Posts != 1 || video.Views != 1 || video.Likes != 2 {
+		t.Errorf("Unexpected video counts %+v", video)
+	}
+
+	// Interactions count under the segment their user last viewed from
+	if segment := sum.Segments["mobile|ios|US-CA"]; segment.Views != 2 || segment.WatchSeconds != 30 || segment.ViralViews != 1 || segment.Likes != 2 || segment.Shares != 1 {
+		t.Errorf("Unexpected segment counts %+v", segment)
+	}
+
+	// The creator's aggregates keep the engagement of each post and its latest score
+	creator := ma.creators["creator-1"]
+	if creator.counts.Views != 2 || creator.counts.Remixes != 1 || creator.counts.Score != 150 || creator.scoreByDay[ReportingDate(time.Now(), time.UTC)] != 150 {
+		t.Errorf("Unexpected creator delta %+v", creator)
+	}
+	post := creator.posts["post-1"]
+	if post.counts.Likes != 2 || post.counts.Shares != 1 || post.contentType != "video" || *post.score != 150 || post.viralAt.IsZero() {
+		t.Errorf("Unexpected post delta %+v", post)
+	}
+
+	ma.RecordScore(&models.TrendingScore{Score: 150}, &models.TrendingScore{PostID: "post-1", Score: 20})
+	if ma.pending.ViralPosts != 0 {
+		t.Errorf("Expected a post falling below the viral line to be uncounted, got %d", ma.pending.ViralPosts)
+	}
+
+	var disabled *MetricsAggregator
+	disabled.RecordInteraction("post-1", "viewer-1", "like")
+	disabled.RecordScore(nil, &models.TrendingScore{Score: 1})
+}
+
+func TestDashboardTotals_Add(t *testing.T) {
+	totals := dashboardTotals{Views: 10, ContentTypes: map[string]int64{"video": 2}}
+	totals.add(dashboardTotals{Views: 5, ViralPosts: -1, ScoreSum: 2.5, ContentTypes: map[string]int64{"video": 1, "music": 1}})
+
+	if totals.Views != 15 || totals.ViralPosts != -1 || totals.ScoreSum != 2.5 {
+		t.Errorf("Unexpected totals %+v", totals)
+	}
+	if totals.ContentTypes["video"] != 3 || totals.ContentTypes["music"] != 1 {
+		t.Errorf("Unexpected content types %v", totals.ContentTypes)
+	}
+	if totals.isZero() || !(&dashboardTotals{}).isZero() {
+		t.Error("Unexpected isZero result")
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/vertexai/genai"
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Legacy text-only models; media moderation switches them to their vision variant
+var textOnlyGeminiModels = map[string]string{
+	"gemini-pro":     "gemini-pro-vision",
+	"gemini-1.0-pro": "gemini-1.0-pro-vision",
+}
+
+// harmProbabilityScores maps Vertex AI safety probabilities onto a [0, 1] score
+var harmProbabilityScores = map[genai.HarmProbability]float64{
+	genai.HarmProbabilityNegligible: 0.1,
+	genai.HarmProbabilityLow:        0.3,
+	genai.HarmProbabilityMedium:     0.6,
+	genai.HarmProbabilityHigh:       0.9,
+}
+
+var harmCategoryNames = map[genai.HarmCategory]string{
+	genai.HarmCategoryHateSpeech:       "hate_speech",
+	genai.HarmCategoryDangerousContent: "dangerous_content",
+	genai.HarmCategoryHarassment:       "harassment",
+	genai.HarmCategorySexuallyExplicit: "sexually_explicit",
+}
+
+// ModerationService scores post prompts (and optionally output media) with the AI provider's
+// safety checks, stores the verdict on the post and routes flagged posts to human review
+type ModerationService struct {
+	ai        AIProvider
+	producer  *KafkaProducer
+	firestore *FirestoreClient
+	config    *config.Config
+}
+
+func NewModerationService(ai AIProvider, producer *KafkaProducer, firestore *FirestoreClient, cfg *config.Config) *ModerationService {
+	return &ModerationService{
+		ai:        ai,
+		producer:  producer,
+		firestore: firestore,
+		config:    cfg,
+	}
+}
+
+// ModeratePost scores a post, writes the verdict to the post document and publishes flagged
+// posts to the moderation queue
+func (m *ModerationService) ModeratePost(event models.ContentMetadata) (*models.ModerationVerdict, error) {
+	var outputURLs []string
+	if m.config.ModerateOutputURLs {
+		outputURLs = event.OutputURLs
+	}
+
+	verdict, err := m.ai.Moderate(event.PostID, event.Prompt, outputURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.firestore.SaveModerationVerdict(*verdict); err != nil {
+		logger.Infof("Failed to save moderation verdict for post %s: %v", event.PostID, err)
+	}
+
+	if verdict.Flagged {
+		logger.Warnf("🚫 Post %s flagged by moderation: %v", event.PostID, verdict.FlaggedCategories)
+		if err := m.producer.PublishModerationVerdict(*verdict, time.Now()); err != nil {
+			logger.Infof("Failed to publish moderation verdict: %v", err)
+		}
+	}
+
+	return verdict, nil
+}
+
+// Moderate scores a prompt and media URLs with the Vertex AI safety filters
+func (v *VertexAIClient) Moderate(postID, prompt string, outputURLs []string) (*models.ModerationVerdict, error) {
+	parts := []genai.Part{genai.Text("Describe this content in one sentence.\n\n" + prompt)}
+	for _, url := range outputURLs {
+		if part, ok := mediaPart(url); ok {
+			parts = append(parts, part)
+		}
+	}
+
+	settings := v.config.GeminiFor(config.GeminiUseCaseModeration)
+	modelName := settings.Model
+	if vision, ok := textOnlyGeminiModels[modelName]; ok && len(parts) > 1 {
+		modelName = vision
+	}
+
+	model := v.genaiClient.GenerativeModel(modelName)
+	model.Temperature = float32(settings.Temperature)
+	model.MaxOutputTokens = int32(settings.MaxOutputTokens)
+
+	// Disable blocking so every category comes back with a rating instead of an error
+	for category := range harmCategoryNames {
+		model.SafetySettings = append(model.SafetySettings, &genai.SafetySetting{
+			Category:  category,
+			Threshold: genai.HarmBlockNone,
+		})
+	}
+
+	var resp *genai.GenerateContentResponse
+	err := v.guard(config.GeminiUseCaseModeration, func(ctx context.Context) error {
+		Quotas.Record(QuotaVertexAI, 1)
+		var err error
+		resp, err = model.GenerateContent(ctx, parts...)
+		return err
+	})
+
+	// A blocked response still used tokens, it just carries no usage metadata
+	var blockedErr *genai.BlockedError
+	if errors.As(err, &blockedErr) {
+		v.usage.Record(config.GeminiUseCaseModeration, nil, nil)
+	} else {
+		v.usage.Record(config.GeminiUseCaseModeration, resp, err)
+	}
+
+	var ratings []*genai.SafetyRating
+	blocked := false
+	switch {
+	case errors.As(err, &blockedErr):
+		blocked = true
+		if blockedErr.PromptFeedback != nil {
+			ratings = blockedErr.PromptFeedback.SafetyRatings
+		}
+		if blockedErr.Candidate != nil {
+			ratings = append(ratings, blockedErr.Candidate.SafetyRatings...)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	default:
+		for _, candidate := range resp.Candidates {
+			ratings = append(ratings, candidate.SafetyRatings...)
+		}
+	}
+
+	return buildVerdict(postID, ratings, blocked, v.config.ModerationThreshold), nil
+}
+
+// buildVerdict keeps the highest score per category and flags the post when any category
+// reaches the threshold or the content was blocked outright
+func buildVerdict(postID string, ratings []*genai.SafetyRating, blocked bool, threshold float64) *models.ModerationVerdict {
+	verdict := &models.ModerationVerdict{
+		PostID:     postID,
+		Categories: make(map[string]float64),
+		Blocked:    blocked,
+		CheckedAt:  time.Now(),
+	}
+
+	for _, rating := range ratings {
+		name, ok := harmCategoryNames[rating.Category]
+		if !ok {
+			continue
+		}
+		score := harmProbabilityScores[rating.Probability]
+		if rating.Blocked && score < threshold {
+			score = threshold
+		}
+		if score > verdict.Categories[name] {
+			verdict.Categories[name] = score
+		}
+	}
+
+	for name, score := range verdict.Categories {
+		if score >= threshold {
+			verdict.FlaggedCategories = append(verdict.FlaggedCategories, name)
+		}
+	}
+	sort.Strings(verdict.FlaggedCategories)
+
+	verdict.Flagged = blocked || len(verdict.FlaggedCategories) > 0
+	return verdict
+}
+
+// mediaPart turns an output URL into a file part the safety model can inspect.
+// Only Cloud Storage URIs of image and video files are supported.
+func mediaPart(url string) (genai.Part, bool) {
+	if !strings.HasPrefix(url, "gs://") {
+		return nil, false
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(path.Ext(url)))
+	if !strings.HasPrefix(mimeType, "image/") && !strings.HasPrefix(mimeType, "video/") {
+		return nil, false
+	}
+	return genai.FileData{MIMEType: mimeType, FileURI: url}, true
+}
+
+// SaveModerationVerdict writes a moderation verdict to the post document
+func (fc *FirestoreClient) SaveModerationVerdict(verdict models.ModerationVerdict) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("posts").Doc(verdict.PostID).Set(fc.ctx, map[string]interface{}{
+		"moderation": map[string]interface{}{
+			"flagged":            verdict.Flagged,
+			"blocked":            verdict.Blocked,
+			"categories":         verdict.Categories,
+			"flagged_categories": verdict.FlaggedCategories,
+			"checked_at":         verdict.CheckedAt,
+		},
+	}, firestore.MergeAll)
+	return err
+}
+
+// isModerationFlagged reports whether a post document carries a flagged moderation verdict
+func isModerationFlagged(postData map[string]interface{}) bool {
+	moderation, ok := postData["moderation"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	flagged, _ := moderation["flagged"].(bool)
+	return flagged
+}
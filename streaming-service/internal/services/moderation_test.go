@@ -0,0 +1,59 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+func TestBuildVerdict(t *testing.T) {
+	ratings := []*genai.SafetyRating{
+		{Category: genai.HarmCategoryHateSpeech, Probability: genai.HarmProbabilityNegligible},
+		{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityLow},
+		{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityMedium},
+		{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityLow, Blocked: true},
+	}
+
+	verdict := buildVerdict("p1", ratings, false, 0.6)
+
+	if !verdict.Flagged {
+		t.Error("Expected post to be flagged")
+	}
+	if want := []string{"dangerous_content", "harassment"}; !reflect.DeepEqual(verdict.FlaggedCategories, want) {
+		t.Errorf("FlaggedCategories = %v, want %v", verdict.FlaggedCategories, want)
+	}
+	if verdict.Categories["hate_speech"] != 0.1 || verdict.Categories["harassment"] != 0.6 {
+		t.Errorf("Unexpected category scores: %v", verdict.Categories)
+	}
+
+	clean := buildVerdict("p2", ratings[:2], false, 0.6)
+	if clean.Flagged || len(clean.FlaggedCategories) != 0 {
+		t.Errorf("Expected clean verdict, got %+v", clean)
+	}
+
+	if blocked := buildVerdict("p3", nil, true, 0.6); !blocked.Flagged {
+		t.Error("Expected blocked content to be flagged")
+	}
+}
+
+func TestMediaPart(t *testing.T) {
+	if _, ok := mediaPart("gs://bucket/image.PNG"); !ok {
+		t.Error("Expected gs:// image to be supported")
+	}
+	if _, ok := mediaPart("https://example.com/image.png"); ok {
+		t.Error("Expected non-GCS URL to be skipped")
+	}
+	if _, ok := mediaPart("gs://bucket/track.mp3"); ok {
+		t.Error("Expected audio to be skipped")
+	}
+}
+
+func TestIsModerationFlagged(t *testing.T) {
+	if !isModerationFlagged(map[string]interface{}{"moderation": map[string]interface{}{"flagged": true}}) {
+		t.Error("Expected flagged post")
+	}
+	if isModerationFlagged(map[string]interface{}{"title": "x"}) {
+		t.Error("Expected post without verdict to pass")
+	}
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Rate limit key of calls to the moderation endpoint of an OpenAI-compatible provider
+const openAIRateKeyModeration = "openai_moderation"
+
+// openAICategoryNames maps OpenAI moderation categories onto the harm categories of the Vertex
+// AI safety filters; subcategories such as "hate/threatening" use the name of their parent
+var openAICategoryNames = map[string]string{
+	"hate":       "hate_speech",
+	"harassment": "harassment",
+	"sexual":     "sexually_explicit",
+	"violence":   "dangerous_content",
+	"self-harm":  "dangerous_content",
+	"illicit":    "dangerous_content",
+}
+
+// OpenAIProvider extracts keywords, moderates posts and predicts virality with any endpoint
+// implementing the OpenAI chat completions and moderations APIs
+type OpenAIProvider struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	breaker    *CircuitBreaker
+	retry      RetryPolicy
+	limiter    *KeyedRateLimiter
+	config     *config.Config
+	ctx        context.Context
+}
+
+func NewOpenAIProvider(ctx context.Context, cfg *config.Config) (*OpenAIProvider, error) {
+	if cfg.OpenAIBaseURL == "" || cfg.OpenAIModel == "" {
+		return nil, fmt.Errorf("OPENAI_BASE_URL and OPENAI_MODEL are required for the %s AI provider", AIProviderOpenAI)
+	}
+
+	breaker, retry, limiter, err := newAICallPolicies("openai", cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAIProvider{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimRight(cfg.OpenAIBaseURL, "/"),
+		apiKey:     cfg.OpenAIAPIKey,
+		breaker:    breaker,
+		retry:      retry,
+		limiter:    limiter,
+		config:     cfg,
+		ctx:        ctx,
+	}, nil
+}
+
+// Name identifies the OpenAI-compatible provider
+func (o *OpenAIProvider) Name() string {
+	return AIProviderOpenAI
+}
+
+// ExtractKeywords asks the chat model for keywords, falling back to the local extraction
+func (o *OpenAIProvider) ExtractKeywords(prompt string, contentType string) (*models.KeywordExtractionResponse, error) {
+	language := detectLanguage(prompt)
+
+	response, err := o.chat(config.GeminiUseCaseKeywords, keywordSystemPrompt, keywordUserPrompt(prompt, contentType, language))
+	if err != nil {
+		logger.Debugf(" OpenAI keyword extraction failed, using fallback: %v", err)
+		return fallbackKeywordExtraction(prompt, contentType), nil
+	}
+
+	result, err := parseKeywordResponse(response, prompt, contentType, language)
+	if err != nil {
+		return fallbackKeywordExtraction(prompt, contentType), nil
+	}
+	return result, nil
+}
+
+// PredictVirality asks the chat model when VIRAL_PREDICTION_MODE selects a model-based
+// predictor and uses the heuristic otherwise, or whenever the model fails
+func (o *OpenAIProvider) PredictVirality(req models.ViralPredictionRequest) (*models.ViralPredictionResponse, error) {
+	if o.config.ViralPredictionMode != ViralPredictionModeHeuristic {
+		resp, err := o.predictWithChat(req)
+		if err == nil {
+			return resp, nil
+		}
+		logger.Debugf(" OpenAI virality prediction failed for %s, using heuristic: %v", req.PostID, err)
+	}
+
+	return predictViralityHeuristic(req, o.config), nil
+}
+
+func (o *OpenAIProvider) predictWithChat(req models.ViralPredictionRequest) (*models.ViralPredictionResponse, error) {
+	userPrompt, err := viralityUserPrompt(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := o.chat(config.GeminiUseCaseVirality, viralitySystemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseViralityResponse(response)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Source = AIProviderOpenAI
+	return result, nil
+}
+
+// Moderate scores a prompt and http(s) image URLs with the moderations API
+func (o *OpenAIProvider) Moderate(postID, prompt string, outputURLs []string) (*models.ModerationVerdict, error) {
+	input := []map[string]interface{}{{"type": "text", "text": prompt}}
+	for _, url := range outputURLs {
+		if openAIImageURL(url) {
+			input = append(input, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]string{"url": url},
+			})
+		}
+	}
+
+	var resp struct {
+		Results []struct {
+			Flagged        bool               `json:"flagged"`
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	err := o.post(openAIRateKeyModeration, "/moderations", map[string]interface{}{
+		"model": o.config.OpenAIModerationModel,
+		"input": input,
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+
+	scores := make(map[string]float64)
+	flagged := false
+	for _, result := range resp.Results {
+		flagged = flagged || result.Flagged
+		for category, score := range result.CategoryScores {
+			scores[category] = max(scores[category], score)
+		}
+	}
+	return buildOpenAIVerdict(postID, scores, flagged, o.config.ModerationThreshold), nil
+}
+
+// buildOpenAIVerdict keeps the highest score per harm category and flags the post when any
+// category reaches the threshold or the provider flagged it
+func buildOpenAIVerdict(postID string, scores map[string]float64, flagged bool, threshold float64) *models.ModerationVerdict {
+	verdict := &models.ModerationVerdict{
+		PostID:     postID,
+		Categories: make(map[string]float64),
+		CheckedAt:  time.Now(),
+	}
+
+	for category, score := range scores {
+		parent, _, _ := strings.Cut(category, "/")
+		name, ok := openAICategoryNames[parent]
+		if !ok {
+			continue
+		}
+		verdict.Categories[name] = max(verdict.Categories[name], clamp01(score))
+	}
+
+	for name, score := range verdict.Categories {
+		if score >= threshold {
+			verdict.FlaggedCategories = append(verdict.FlaggedCategories, name)
+		}
+	}
+	sort.Strings(verdict.FlaggedCategories)
+
+	verdict.Flagged = flagged || len(verdict.FlaggedCategories) > 0
+	return verdict
+}
+
+// openAIImageURL reports whether a URL is an image the moderations API can fetch itself
+func openAIImageURL(url string) bool {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return false
+	}
+	return strings.HasPrefix(mime.TypeByExtension(strings.ToLower(path.Ext(url))), "image/")
+}
+
+// chat sends a system and user message to the chat model with the generation parameters
+// configured for the use case and returns the text of the answer
+func (o *OpenAIProvider) chat(useCase, systemPrompt, userPrompt string) (string, error) {
+	settings := o.config.GeminiFor(useCase)
+
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	err := o.post(useCase, "/chat/completions", map[string]interface{}{
+		"model": o.config.OpenAIModel,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": userPrompt},
+		},
+		"temperature": settings.Temperature,
+		"top_p":       settings.TopP,
+		"max_tokens":  settings.MaxOutputTokens,
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("openai chat completion failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("no content in openai response")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// post sends a JSON request behind the rate limiter of its key and the circuit breaker,
+// retrying rate limited and server errors, and decodes the response into dest
+func (o *OpenAIProvider) post(key, endpoint string, body interface{}, dest interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	if err := o.limiter.Wait(o.ctx, key); err != nil {
+		return err
+	}
+
+	return o.breaker.Execute(func() error {
+		return o.retry.Do(o.ctx, func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+endpoint, bytes.NewReader(payload))
+			if err != nil {
+				return Permanent(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if o.apiKey != "" {
+				req.Header.Set("Authorization", "Bearer "+o.apiKey)
+			}
+
+			resp, err := o.httpClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+				err := fmt.Errorf("%s returned status %d: %s", endpoint, resp.StatusCode, strings.TrimSpace(string(detail)))
+				if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+					return err
+				}
+				return Permanent(err)
+			}
+
+			if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+				return Permanent(fmt.Errorf("failed to decode %s response: %w", endpoint, err))
+			}
+			return nil
+		})
+	})
+}
+
+// BreakerState returns the state of the OpenAI circuit breaker
+func (o *OpenAIProvider) BreakerState() BreakerState {
+	return o.breaker.State()
+}
+
+// RateLimitStats returns the queued, delayed and dropped OpenAI requests per use case
+func (o *OpenAIProvider) RateLimitStats() []RateLimitStats {
+	return o.limiter.Stats()
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// Operation states
+const (
+	OperationPending   = "pending"
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+)
+
+// ErrQueueFull is returned for work submitted while the operation queue is full or stopped
+var ErrQueueFull = errors.New("operation queue is full")
+
+// Operation is work accepted by the API and run in the background, polled by its ID until it
+// succeeds or fails
+type Operation struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Status     string      `json:"status"`
+	RequestID  string      `json:"request_id,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the operation succeeded or failed
+func (op Operation) Done() bool {
+	return op.Status == OperationSucceeded || op.Status == OperationFailed
+}
+
+// operationJob is an operation waiting for a worker
+type operationJob struct {
+	id  string
+	run func() (interface{}, error)
+}
+
+// OperationQueue runs submitted work on a fixed number of workers from a bounded queue, so a
+// burst of slow requests cannot pile up goroutines. Operations are kept in memory for
+// retention after they finish, so they are polled on the instance that accepted them.
+type OperationQueue struct {
+	jobs      chan operationJob
+	workers   int
+	retention time.Duration
+	wg        sync.WaitGroup
+
+	mu         sync.Mutex
+	operations map[string]*Operation
+	stopped    bool
+}
+
+func NewOperationQueue(size, workers int, retention time.Duration) *OperationQueue {
+	return &OperationQueue{
+		jobs:       make(chan operationJob, max(size, 1)),
+		workers:    max(workers, 1),
+		retention:  retention,
+		operations: make(map[string]*Operation),
+	}
+}
+
+// Start launches the workers
+func (q *OperationQueue) Start() {
+	logger.Infof("📥 Starting operation queue (%d workers, %d slots)", q.workers, cap(q.jobs))
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+}
+
+// Stop rejects new work and waits for the queued operations to finish
+func (q *OperationQueue) Stop() {
+	q.mu.Lock()
+	if q.stopped {
+		q.mu.Unlock()
+		return
+	}
+	q.stopped = true
+	close(q.jobs)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+// Submit queues run as an operation of the given kind and returns it pending, or ErrQueueFull
+// when no slot is free
+func (q *OperationQueue) Submit(kind, requestID string, run func() (interface{}, error)) (Operation, error) {
+	op := &Operation{
+		ID:        newOperationID(),
+		Kind:      kind,
+		Status:    OperationPending,
+		RequestID: requestID,
+		CreatedAt: time.Now(),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return Operation{}, ErrQueueFull
+	}
+	select {
+	case q.jobs <- operationJob{id: op.ID, run: run}:
+	default:
+		return Operation{}, ErrQueueFull
+	}
+	q.operations[op.ID] = op
+	return *op, nil
+}
+
+// Get returns an operation, false when it is unknown or expired
+func (q *OperationQueue) Get(id string) (Operation, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	op, ok := q.operations[id]
+	if !ok {
+		return Operation{}, false
+	}
+	return *op, true
+}
+
+// Pending returns the number of operations waiting for a worker
+func (q *OperationQueue) Pending() int {
+	return len(q.jobs)
+}
+
+func (q *OperationQueue) work() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		q.update(job.id, func(op *Operation) {
+			now := time.Now()
+			op.Status = OperationRunning
+			op.StartedAt = &now
+		})
+
+		result, err := runOperation(job.run)
+
+		q.update(job.id, func(op *Operation) {
+			now := time.Now()
+			op.FinishedAt = &now
+			if err != nil {
+				op.Status = OperationFailed
+				op.Error = err.Error()
+				return
+			}
+			op.Status = OperationSucceeded
+			op.Result = result
+		})
+	}
+}
+
+// runOperation runs an operation's work, turning a panic into its error
+func runOperation(run func() (interface{}, error)) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.Errorf("❌ Operation panicked: %v", recovered)
+			result, err = nil, fmt.Errorf("operation panicked")
+		}
+	}()
+	return run()
+}
+
+func (q *OperationQueue) update(id string, apply func(op *Operation)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if op, ok := q.operations[id]; ok {
+		apply(op)
+	}
+}
+
+// RegisterCaches hands the finished operations to the cache maintainer, which drops them once
+// their retention passes
+func (q *OperationQueue) RegisterCaches(maintainer *CacheMaintainer) {
+	maintainer.Register("operations", q.evictExpired, q.len)
+}
+
+// evictExpired removes operations that finished more than retention ago
+func (q *OperationQueue) evictExpired(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-q.retention)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	removed := 0
+	for id, op := range q.operations {
+		if op.FinishedAt != nil && op.FinishedAt.Before(cutoff) {
+			delete(q.operations, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (q *OperationQueue) len(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.operations), nil
+}
+
+func newOperationID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "op_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForOperation polls an operation until it is done
+func waitForOperation(t *testing.T, q *OperationQueue, id string) Operation {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if op, ok := q.Get(id); ok && op.Done() {
+			return op
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Operation %s did not finish", id)
+	return Operation{}
+}
+
+func TestOperationQueueRunsOperations(t *testing.T) {
+	q := NewOperationQueue(10, 2, time.Hour)
+	q.Start()
+	defer q.Stop()
+
+	succeeded, err := q.Submit("content_metadata", "req-1", func() (interface{}, error) { return "done", nil })
+	if err != nil || succeeded.Status != OperationPending || succeeded.RequestID != "req-1" {
+		t.Fatalf("Unexpected submitted operation %+v, %v", succeeded, err)
+	}
+	failed, _ := q.Submit("content_metadata", "", func() (interface{}, error) { return nil, errors.New("boom") })
+	panicked, _ := q.Submit("content_metadata", "", func() (interface{}, error) { panic("oops") })
+
+	if op := waitForOperation(t, q, succeeded.ID); op.Status != OperationSucceeded || op.Result != "done" || op.StartedAt == nil || op.FinishedAt == nil {
+		t.Errorf("Expected a succeeded operation, got %+v", op)
+	}
+	if op := waitForOperation(t, q, failed.ID); op.Status != OperationFailed || op.Error != "boom" {
+		t.Errorf("Expected a failed operation, got %+v", op)
+	}
+	if op := waitForOperation(t, q, panicked.ID); op.Status != OperationFailed {
+		t.Errorf("Expected a panicking operation to fail, got %+v", op)
+	}
+	if _, ok := q.Get("op_unknown"); ok {
+		t.Error("Expected an unknown operation to be missing")
+	}
+}
+
+func TestOperationQueueRejectsWhenFull(t *testing.T) {
+	// Not started, so nothing leaves the queue
+	q := NewOperationQueue(1, 1, time.Hour)
+	noop := func() (interface{}, error) { return nil, nil }
+
+	if _, err := q.Submit("test", "", noop); err != nil {
+		t.Fatalf("Expected the first operation to be queued, got %v", err)
+	}
+	if _, err := q.Submit("test", "", noop); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	q.Start()
+	q.Stop()
+	if _, err := q.Submit("test", "", noop); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected a stopped queue to reject work, got %v", err)
+	}
+}
+
+func TestOperationQueueEvictsFinishedOperations(t *testing.T) {
+	q := NewOperationQueue(10, 1, time.Minute)
+	finished := time.Now().Add(-2 * time.Minute)
+	q.operations["op_old"] = &Operation{ID: "op_old", Status: OperationSucceeded, FinishedAt: &finished}
+	q.operations["op_pending"] = &Operation{ID: "op_pending", Status: OperationPending}
+
+	if removed, _ := q.evictExpired(context.Background()); removed != 1 {
+		t.Errorf("Expected 1 operation evicted, got %d", removed)
+	}
+	if _, ok := q.Get("op_pending"); !ok {
+		t.Error("Expected the pending operation to be kept")
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Engagement metrics shared with partners
+const (
+	PartnerMetricViews    = "views"
+	PartnerMetricLikes    = "likes"
+	PartnerMetricComments = "comments"
+	PartnerMetricShares   = "shares"
+	PartnerMetricRemixes  = "remixes"
+)
+
+var partnerMetrics = []string{PartnerMetricViews, PartnerMetricLikes, PartnerMetricComments, PartnerMetricShares, PartnerMetricRemixes}
+
+// partnerMetricByInteraction maps interaction event types to partner metrics
+var partnerMetricByInteraction = map[string]string{
+	"view":    PartnerMetricViews,
+	"like":    PartnerMetricLikes,
+	"comment": PartnerMetricComments,
+	"share":   PartnerMetricShares,
+}
+
+// Post content types remembered by the partner stream before the cache is reset
+const maxCachedPostContentTypes = 10000
+
+// PartnerStream is a data partner and the slice of engagement it receives. Empty lists mean
+// every content type or metric.
+type PartnerStream struct {
+	ID           string   `json:"id"`
+	ContentTypes []string `json:"content_types"`
+	Metrics      []string `json:"metrics"`
+}
+
+// ParsePartnerStreams reads the PARTNER_STREAMS JSON list
+func ParsePartnerStreams(raw string) ([]PartnerStream, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var partners []PartnerStream
+	if err := json.Unmarshal([]byte(raw), &partners); err != nil {
+		return nil, fmt.Errorf("invalid PARTNER_STREAMS: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, partner := range partners {
+		if partner.ID == "" {
+			return nil, fmt.Errorf("invalid PARTNER_STREAMS: partner without id")
+		}
+		if seen[partner.ID] {
+			return nil, fmt.Errorf("invalid PARTNER_STREAMS: duplicate partner %q", partner.ID)
+		}
+		seen[partner.ID] = true
+		for _, metric := range partner.Metrics {
+			if !slices.Contains(partnerMetrics, metric) {
+				return nil, fmt.Errorf("invalid PARTNER_STREAMS: unknown metric %q for partner %q", metric, partner.ID)
+			}
+		}
+	}
+	return partners, nil
+}
+
+// PartnerStreamer aggregates consumed engagement into per-post counts and, every window,
+// publishes to each partner the counts it is allowed to see. Only counts leave the service:
+// they are rounded down to the bucket size, so engagement from one or a few users is never
+// visible, and events carry no user IDs.
+type PartnerStreamer struct {
+	producer        *KafkaProducer
+	firestoreClient *FirestoreClient
+	partners        []PartnerStream
+	ctx             context.Context
+	cancel          context.CancelFunc
+	window          time.Duration
+	bucket          int64
+
+	mu           sync.Mutex
+	counts       map[string]map[string]int64 // post -> metric -> events in the current window
+	windowStart  time.Time
+	contentTypes map[string]string // post -> content type
+}
+
+func NewPartnerStreamer(producer *KafkaProducer, firestoreClient *FirestoreClient, partners []PartnerStream, window time.Duration, bucket int) *PartnerStreamer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &PartnerStreamer{
+		producer:        producer,
+		firestoreClient: firestoreClient,
+		partners:        partners,
+		ctx:             ctx,
+		cancel:          cancel,
+		window:          window,
+		bucket:          int64(max(bucket, 1)),
+		counts:          make(map[string]map[string]int64),
+		windowStart:     time.Now(),
+		contentTypes:    make(map[string]string),
+	}
+}
+
+// Start begins publishing a window of engagement every window interval
+func (ps *PartnerStreamer) Start() {
+	logger.Infof("🤝 Starting partner stream for %d partners (window %v, bucket %d)", len(ps.partners), ps.window, ps.bucket)
+
+	ticker := time.NewTicker(ps.window)
+	go func() {
+		for {
+			select {
+			case <-ps.ctx.Done():
+				ticker.Stop()
+				logger.Info("🛑 Partner stream stopped")
+				return
+			case now := <-ticker.C:
+				if err := ps.Flush(now); err != nil {
+					logger.Errorf("❌ Partner stream flush failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the publishing loop and publishes the window in progress
+func (ps *PartnerStreamer) Stop() {
+	ps.cancel()
+	if err := ps.Flush(time.Now()); err != nil {
+		logger.Errorf("❌ Final partner stream flush failed: %v", err)
+	}
+}
+
+// RecordInteraction counts a consumed interaction towards the current window
+func (ps *PartnerStreamer) RecordInteraction(postID, eventType string) {
+	if metric, ok := partnerMetricByInteraction[eventType]; ok {
+		ps.record(postID, metric)
+	}
+}
+
+// RecordView counts a consumed view towards the current window
+func (ps *PartnerStreamer) RecordView(postID string) {
+	ps.record(postID, PartnerMetricViews)
+}
+
+// RecordRemix counts a consumed remix of a post towards the current window
+func (ps *PartnerStreamer) RecordRemix(postID string) {
+	ps.record(postID, PartnerMetricRemixes)
+}
+
+func (ps *PartnerStreamer) record(postID, metric string) {
+	if ps == nil || postID == "" {
+		return
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	counts, ok := ps.counts[postID]
+	if !ok {
+		counts = make(map[string]int64)
+		ps.counts[postID] = counts
+	}
+	counts[metric]++
+}
+
+// Flush closes the current window and publishes its engagement to every partner
+func (ps *PartnerStreamer) Flush(now time.Time) error {
+	ps.mu.Lock()
+	counts := ps.counts
+	windowStart := ps.windowStart
+	ps.counts = make(map[string]map[string]int64)
+	ps.windowStart = now
+	ps.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	contentTypes := make(map[string]string, len(counts))
+	for postID := range counts {
+		contentType, err := ps.postContentType(postID)
+		if err != nil {
+			logger.Debugf(" Could not resolve content type of post %s: %v", postID, err)
+			continue
+		}
+		contentTypes[postID] = contentType
+	}
+
+	events := buildPartnerEvents(ps.partners, counts, contentTypes, ps.bucket, windowStart, now)
+	var lastErr error
+	for _, event := range events {
+		if err := ps.producer.PublishPartnerEngagement(event, now); err != nil {
+			lastErr = err
+		}
+	}
+
+	logger.Debugf("🤝 Published %d partner events for %d posts", len(events), len(counts))
+	return lastErr
+}
+
+// postContentType returns the content type of a post, reading the post document on a cache miss
+func (ps *PartnerStreamer) postContentType(postID string) (string, error) {
+	ps.mu.Lock()
+	contentType, ok := ps.contentTypes[postID]
+	ps.mu.Unlock()
+	if ok {
+		return contentType, nil
+	}
+
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := ps.firestoreClient.client.Collection("posts").Doc(postID).Get(ps.firestoreClient.ctx)
+	if err != nil {
+		return "", err
+	}
+	contentType, _ = doc.Data()["contentType"].(string)
+
+	ps.mu.Lock()
+	if len(ps.contentTypes) >= maxCachedPostContentTypes {
+		ps.contentTypes = make(map[string]string)
+	}
+	ps.contentTypes[postID] = contentType
+	ps.mu.Unlock()
+	return contentType, nil
+}
+
+// buildPartnerEvents turns a window's counts into the events each partner receives. Counts are
+// rounded down to the bucket; metrics below one bucket and posts left without metrics are
+// dropped, as are posts whose content type is unknown.
+func buildPartnerEvents(partners []PartnerStream, counts map[string]map[string]int64, contentTypes map[string]string, bucket int64, windowStart, windowEnd time.Time) []models.PartnerEngagementEvent {
+	postIDs := make([]string, 0, len(counts))
+	for postID := range counts {
+		postIDs = append(postIDs, postID)
+	}
+	sort.Strings(postIDs)
+
+	var events []models.PartnerEngagementEvent
+	for _, partner := range partners {
+		for _, postID := range postIDs {
+			contentType, ok := contentTypes[postID]
+			if !ok || (len(partner.ContentTypes) > 0 && !slices.Contains(partner.ContentTypes, contentType)) {
+				continue
+			}
+
+			shared := make(map[string]int64)
+			for metric, count := range counts[postID] {
+				if len(partner.Metrics) > 0 && !slices.Contains(partner.Metrics, metric) {
+					continue
+				}
+				if bucketed := count / bucket * bucket; bucketed > 0 {
+					shared[metric] = bucketed
+				}
+			}
+			if len(shared) == 0 {
+				continue
+			}
+
+			events = append(events, models.PartnerEngagementEvent{
+				PartnerID:   partner.ID,
+				PostID:      postID,
+				ContentType: contentType,
+				Counts:      shared,
+				Bucket:      bucket,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+			})
+		}
+	}
+	return events
+}
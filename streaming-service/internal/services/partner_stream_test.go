@@ -0,0 +1,65 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePartnerStreams(t *testing.T) {
+	partners, err := ParsePartnerStreams(`[{"id":"acme","content_types":["music"],"metrics":["views","likes"]},{"id":"globex"}]`)
+	if err != nil || len(partners) != 2 || partners[0].Metrics[1] != PartnerMetricLikes {
+		t.Fatalf("expected two partners, got %+v, %v", partners, err)
+	}
+	if partners, err := ParsePartnerStreams(""); err != nil || partners != nil {
+		t.Errorf("expected no partners when unset, got %+v, %v", partners, err)
+	}
+
+	for _, raw := range []string{
+		`{"id":"acme"}`,
+		`[{"content_types":["music"]}]`,
+		`[{"id":"acme"},{"id":"acme"}]`,
+		`[{"id":"acme","metrics":["user_ids"]}]`,
+	} {
+		if _, err := ParsePartnerStreams(raw); err == nil {
+			t.Errorf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestBuildPartnerEvents(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(5 * time.Minute)
+	partners := []PartnerStream{
+		{ID: "acme", ContentTypes: []string{"music"}, Metrics: []string{PartnerMetricViews, PartnerMetricLikes}},
+		{ID: "globex"},
+	}
+	counts := map[string]map[string]int64{
+		"song":    {PartnerMetricViews: 147, PartnerMetricLikes: 32, PartnerMetricShares: 25},
+		"picture": {PartnerMetricViews: 58, PartnerMetricComments: 3},
+		"quiet":   {PartnerMetricViews: 4},
+		"deleted": {PartnerMetricViews: 500},
+	}
+	contentTypes := map[string]string{"song": "music", "picture": "image", "quiet": "music"}
+
+	events := buildPartnerEvents(partners, counts, contentTypes, 10, start, end)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %+v", events)
+	}
+
+	acme := events[0]
+	if acme.PartnerID != "acme" || acme.PostID != "song" || !reflect.DeepEqual(acme.Counts, map[string]int64{"views": 140, "likes": 30}) {
+		t.Errorf("expected acme to see bucketed views and likes of the song, got %+v", acme)
+	}
+	if acme.Bucket != 10 || !acme.WindowStart.Equal(start) || !acme.WindowEnd.Equal(end) {
+		t.Errorf("expected the window and bucket on the event, got %+v", acme)
+	}
+
+	// Counts under one bucket are left out, and posts with nothing left are not sent
+	if picture := events[1]; picture.PartnerID != "globex" || picture.PostID != "picture" || !reflect.DeepEqual(picture.Counts, map[string]int64{"views": 50}) {
+		t.Errorf("expected globex to see picture views only, got %+v", picture)
+	}
+	if song := events[2]; song.PartnerID != "globex" || song.Counts[PartnerMetricShares] != 20 {
+		t.Errorf("expected globex to see every metric of the song, got %+v", song)
+	}
+}
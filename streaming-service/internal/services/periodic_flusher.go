@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// periodicFlusher drives the flush of a service that buffers writes in memory: it flushes on
+// a ticker, once more when stopped, and early in the background when the buffer is full
+type periodicFlusher struct {
+	name     string // what is flushed, for the logs
+	interval time.Duration
+	flush    func() error
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	flushing atomic.Bool // an early flush is running
+}
+
+func newPeriodicFlusher(name string, interval time.Duration, flush func() error) *periodicFlusher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &periodicFlusher{
+		name:     name,
+		interval: interval,
+		flush:    flush,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// start flushes every interval until stop is called
+func (f *periodicFlusher) start() {
+	ticker := time.NewTicker(f.interval)
+	go func() {
+		for {
+			select {
+			case <-f.ctx.Done():
+				ticker.Stop()
+				logger.Infof("🛑 Stopped flushing %s", f.name)
+				return
+			case <-ticker.C:
+				if err := f.flush(); err != nil {
+					logger.Errorf("❌ Flushing %s failed: %v", f.name, err)
+				}
+			}
+		}
+	}()
+}
+
+// stop ends the ticker and flushes what is still buffered
+func (f *periodicFlusher) stop() {
+	f.cancel()
+	if err := f.flush(); err != nil {
+		logger.Errorf("❌ Final flush of %s failed: %v", f.name, err)
+	}
+}
+
+// flushEarly starts a flush in the background unless one is already running
+func (f *periodicFlusher) flushEarly() {
+	if !f.flushing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer f.flushing.Store(false)
+		if err := f.flush(); err != nil {
+			logger.Errorf("❌ Early flush of %s failed: %v", f.name, err)
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPeriodicFlusher(t *testing.T) {
+	var flushes atomic.Int32
+	release := make(chan struct{})
+	f := newPeriodicFlusher("test", time.Hour, func() error {
+		flushes.Add(1)
+		<-release
+		return nil
+	})
+
+	// Only one early flush runs at a time
+	f.flushEarly()
+	for flushes.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	f.flushEarly()
+	close(release)
+	for f.flushing.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	if got := flushes.Load(); got != 1 {
+		t.Errorf("Early flushes = %d, want 1", got)
+	}
+
+	// Stopping flushes what is still buffered
+	f.start()
+	f.stop()
+	if got := flushes.Load(); got != 2 {
+		t.Errorf("Flushes after stop = %d, want 2", got)
+	}
+}
+
+func TestPeriodicFlusherTicks(t *testing.T) {
+	flushed := make(chan struct{}, 1)
+	f := newPeriodicFlusher("test", 10*time.Millisecond, func() error {
+		select {
+		case flushed <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	f.start()
+	defer f.stop()
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a flush within the interval")
+	}
+}
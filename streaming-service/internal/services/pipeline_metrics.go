@@ -0,0 +1,208 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Pipeline stages timed along the event path
+const (
+	StageHandler   = "handler"       // HTTP bind + processing in the ingest handler
+	StageProduce   = "produce"       // enqueueing the event with the Kafka producer
+	StageKafka     = "kafka_transit" // produced -> picked up by the consumer
+	StageFirestore = "firestore"     // analytics writes for a consumed event
+	StageAI        = "ai"            // Vertex AI keyword extraction / virality prediction
+	StageBroadcast = "broadcast"     // WebSocket fan-out of an update
+	StageEndToEnd  = "end_to_end"    // ingested -> consumer finished processing
+)
+
+// Kafka headers carrying per-event trace timestamps (unix nanoseconds) and the ingesting
+// request's ID
+const (
+	headerIngestedAt = "x-ingested-at"
+	headerProducedAt = "x-produced-at"
+	headerRequestID  = "x-request-id"
+)
+
+// latencyBucketsMs are the upper bounds of the latency histogram buckets in milliseconds
+var latencyBucketsMs = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// PipelineLatency is the process-wide latency recorder for the event pipeline
+var PipelineLatency = NewPipelineMetrics()
+
+// PipelineMetrics aggregates stage latencies into histograms
+type PipelineMetrics struct {
+	mu     sync.Mutex
+	stages map[string]*latencyHistogram
+	since  time.Time
+}
+
+type latencyHistogram struct {
+	counts []int64 // one per bucket plus the +Inf bucket
+	count  int64
+	sumMs  float64
+	maxMs  float64
+}
+
+// LatencySnapshot is a point-in-time view of one stage histogram
+type LatencySnapshot struct {
+	Count   int64            `json:"count"`
+	AvgMs   float64          `json:"avg_ms"`
+	P50Ms   float64          `json:"p50_ms"`
+	P95Ms   float64          `json:"p95_ms"`
+	P99Ms   float64          `json:"p99_ms"`
+	MaxMs   float64          `json:"max_ms"`
+	Buckets map[string]int64 `json:"buckets"` // cumulative counts keyed by upper bound ("le")
+}
+
+// NewPipelineMetrics creates an empty latency recorder
+func NewPipelineMetrics() *PipelineMetrics {
+	return &PipelineMetrics{
+		stages: make(map[string]*latencyHistogram),
+		since:  time.Now(),
+	}
+}
+
+// Observe records a latency for a stage
+func (pm *PipelineMetrics) Observe(stage string, d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	ms := float64(d) / float64(time.Millisecond)
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	h, ok := pm.stages[stage]
+	if !ok {
+		h = &latencyHistogram{counts: make([]int64, len(latencyBucketsMs)+1)}
+		pm.stages[stage] = h
+	}
+
+	idx := len(latencyBucketsMs)
+	for i, bound := range latencyBucketsMs {
+		if ms <= bound {
+			idx = i
+			break
+		}
+	}
+	h.counts[idx]++
+	h.count++
+	h.sumMs += ms
+	if ms > h.maxMs {
+		h.maxMs = ms
+	}
+}
+
+// ObserveSince records the time elapsed since start for a stage
+func (pm *PipelineMetrics) ObserveSince(stage string, start time.Time) {
+	pm.Observe(stage, time.Since(start))
+}
+
+// Snapshot returns the current histograms for all stages
+func (pm *PipelineMetrics) Snapshot() map[string]LatencySnapshot {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	result := make(map[string]LatencySnapshot, len(pm.stages))
+	for stage, h := range pm.stages {
+		snap := LatencySnapshot{
+			Count:   h.count,
+			MaxMs:   h.maxMs,
+			Buckets: make(map[string]int64, len(h.counts)),
+		}
+		if h.count > 0 {
+			snap.AvgMs = h.sumMs / float64(h.count)
+		}
+
+		cumulative := int64(0)
+		for i, c := range h.counts {
+			cumulative += c
+			le := "+Inf"
+			if i < len(latencyBucketsMs) {
+				le = strconv.FormatFloat(latencyBucketsMs[i], 'f', -1, 64)
+			}
+			snap.Buckets[le] = cumulative
+		}
+
+		snap.P50Ms = h.quantile(0.50)
+		snap.P95Ms = h.quantile(0.95)
+		snap.P99Ms = h.quantile(0.99)
+		result[stage] = snap
+	}
+	return result
+}
+
+// Since returns when recording started
+func (pm *PipelineMetrics) Since() time.Time {
+	return pm.since
+}
+
+// quantile estimates a quantile as the upper bound of the bucket containing it,
+// capped at the observed maximum
+func (h *latencyHistogram) quantile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.count)))
+	cumulative := int64(0)
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative >= rank {
+			if i < len(latencyBucketsMs) && latencyBucketsMs[i] < h.maxMs {
+				return latencyBucketsMs[i]
+			}
+			return h.maxMs
+		}
+	}
+	return h.maxMs
+}
+
+// EventTrace carries the pipeline timestamps of a single event across Kafka, and the ID of
+// the API request that ingested it
+type EventTrace struct {
+	IngestedAt time.Time
+	ProducedAt time.Time
+	RequestID  string
+}
+
+// headers encodes the trace as Kafka message headers
+func (t EventTrace) headers() []kafka.Header {
+	var headers []kafka.Header
+	if !t.IngestedAt.IsZero() {
+		headers = append(headers, kafka.Header{Key: headerIngestedAt, Value: []byte(strconv.FormatInt(t.IngestedAt.UnixNano(), 10))})
+	}
+	if !t.ProducedAt.IsZero() {
+		headers = append(headers, kafka.Header{Key: headerProducedAt, Value: []byte(strconv.FormatInt(t.ProducedAt.UnixNano(), 10))})
+	}
+	if t.RequestID != "" {
+		headers = append(headers, kafka.Header{Key: headerRequestID, Value: []byte(t.RequestID)})
+	}
+	return headers
+}
+
+// traceFromHeaders decodes a trace from Kafka message headers; missing values stay zero
+func traceFromHeaders(headers []kafka.Header) EventTrace {
+	var trace EventTrace
+	for _, h := range headers {
+		if h.Key == headerRequestID {
+			trace.RequestID = string(h.Value)
+			continue
+		}
+		nanos, err := strconv.ParseInt(string(h.Value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch h.Key {
+		case headerIngestedAt:
+			trace.IngestedAt = time.Unix(0, nanos)
+		case headerProducedAt:
+			trace.ProducedAt = time.Unix(0, nanos)
+		}
+	}
+	return trace
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipelineMetricsObserve(t *testing.T) {
+	pm := NewPipelineMetrics()
+
+	for i := 0; i < 98; i++ {
+		pm.Observe(StageFirestore, 8*time.Millisecond)
+	}
+	pm.Observe(StageFirestore, 400*time.Millisecond)
+	pm.Observe(StageFirestore, 30*time.Second)
+
+	snap, ok := pm.Snapshot()[StageFirestore]
+	if !ok {
+		t.Fatal("Expected firestore stage in snapshot")
+	}
+
+	if snap.Count != 100 {
+		t.Errorf("Count = %d, want 100", snap.Count)
+	}
+	if snap.P50Ms != 10 {
+		t.Errorf("P50Ms = %v, want 10", snap.P50Ms)
+	}
+	if snap.P99Ms != 500 {
+		t.Errorf("P99Ms = %v, want 500", snap.P99Ms)
+	}
+	if snap.MaxMs != 30000 {
+		t.Errorf("MaxMs = %v, want 30000", snap.MaxMs)
+	}
+	if snap.Buckets["+Inf"] != 100 {
+		t.Errorf("+Inf bucket = %d, want 100", snap.Buckets["+Inf"])
+	}
+	if snap.Buckets["10"] != 98 {
+		t.Errorf("le=10 bucket = %d, want 98", snap.Buckets["10"])
+	}
+}
+
+func TestEventTraceHeadersRoundTrip(t *testing.T) {
+	trace := EventTrace{
+		IngestedAt: time.Unix(1700000000, 123),
+		ProducedAt: time.Unix(1700000000, 456),
+		RequestID:  "req-42",
+	}
+
+	decoded := traceFromHeaders(trace.headers())
+
+	if !decoded.IngestedAt.Equal(trace.IngestedAt) {
+		t.Errorf("IngestedAt = %v, want %v", decoded.IngestedAt, trace.IngestedAt)
+	}
+	if !decoded.ProducedAt.Equal(trace.ProducedAt) {
+		t.Errorf("ProducedAt = %v, want %v", decoded.ProducedAt, trace.ProducedAt)
+	}
+	if decoded.RequestID != trace.RequestID {
+		t.Errorf("RequestID = %q, want %q", decoded.RequestID, trace.RequestID)
+	}
+
+	// Missing headers leave zero timestamps
+	empty := traceFromHeaders(nil)
+	if !empty.IngestedAt.IsZero() || !empty.ProducedAt.IsZero() || empty.RequestID != "" {
+		t.Error("Expected zero trace for missing headers")
+	}
+}
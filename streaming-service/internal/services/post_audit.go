@@ -0,0 +1,208 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Audit entry kinds recorded for traced posts
+const (
+	AuditKindEvent = "event"
+	AuditKindScore = "score"
+	AuditKindAI    = "ai"
+)
+
+const (
+	// How long the set of traced posts is trusted before re-reading it from Firestore
+	auditRefreshInterval = time.Minute
+
+	// Upper bound for how long a single post can be traced
+	MaxPostTraceDuration = 24 * time.Hour
+)
+
+// postAuditor keeps track of posts with trace mode enabled and records their audit trail
+// to post_traces/{postID}/entries. Firestore's TTL policy on the entries' ExpiresAt field,
+// declared in firestore.indexes.json, deletes them once they expire.
+type postAuditor struct {
+	mu          sync.RWMutex
+	traced      map[string]time.Time // post ID -> trace expiry
+	refreshedAt time.Time
+
+	// Held while the traced set is re-read, so one caller refreshes it at a time
+	refreshing sync.Mutex
+}
+
+func newPostAuditor() *postAuditor {
+	return &postAuditor{traced: make(map[string]time.Time)}
+}
+
+// EnablePostTrace turns on trace mode for a post for the given duration
+func (fc *FirestoreClient) EnablePostTrace(postID string, duration time.Duration) (*models.PostTrace, error) {
+	if duration <= 0 || duration > MaxPostTraceDuration {
+		return nil, fmt.Errorf("trace duration must be between 1s and %v", MaxPostTraceDuration)
+	}
+
+	now := time.Now()
+	trace := models.PostTrace{
+		PostID:    postID,
+		EnabledAt: now,
+		ExpiresAt: now.Add(duration),
+	}
+	if _, err := fc.client.Collection("post_traces").Doc(postID).Set(fc.ctx, trace); err != nil {
+		return nil, err
+	}
+
+	fc.audit.mu.Lock()
+	fc.audit.traced[postID] = trace.ExpiresAt
+	fc.audit.mu.Unlock()
+
+	logger.Infof("🔍 Trace mode enabled for post %s until %s", postID, trace.ExpiresAt.Format(time.RFC3339))
+	return &trace, nil
+}
+
+// DisablePostTrace turns off trace mode for a post; recorded entries are kept until they expire
+func (fc *FirestoreClient) DisablePostTrace(postID string) error {
+	_, err := fc.client.Collection("post_traces").Doc(postID).Update(fc.ctx, []firestore.Update{
+		{Path: "ExpiresAt", Value: time.Now()},
+	})
+
+	fc.audit.mu.Lock()
+	delete(fc.audit.traced, postID)
+	fc.audit.mu.Unlock()
+
+	return err
+}
+
+// IsPostTraced reports whether trace mode is currently enabled for a post
+func (fc *FirestoreClient) IsPostTraced(postID string) bool {
+	fc.audit.mu.RLock()
+	stale := time.Since(fc.audit.refreshedAt) > auditRefreshInterval
+	expiresAt, ok := fc.audit.traced[postID]
+	fc.audit.mu.RUnlock()
+
+	// Callers arriving while another refreshes go on with the current set
+	if stale && fc.audit.refreshing.TryLock() {
+		fc.refreshTracedPosts()
+		fc.audit.refreshing.Unlock()
+		fc.audit.mu.RLock()
+		expiresAt, ok = fc.audit.traced[postID]
+		fc.audit.mu.RUnlock()
+	}
+
+	return ok && time.Now().Before(expiresAt)
+}
+
+// refreshTracedPosts reloads the traced post set so traces enabled on other instances are picked up
+func (fc *FirestoreClient) refreshTracedPosts() {
+	iter := fc.client.Collection("post_traces").
+		Where("ExpiresAt", ">", time.Now()).
+		Documents(fc.ctx)
+
+	traced := make(map[string]time.Time)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// Keep the current set and retry after the next interval
+			logger.Debugf(" Failed to refresh traced posts: %v", err)
+			fc.audit.mu.Lock()
+			fc.audit.refreshedAt = time.Now()
+			fc.audit.mu.Unlock()
+			return
+		}
+
+		var trace models.PostTrace
+		if err := doc.DataTo(&trace); err != nil {
+			continue
+		}
+		traced[trace.PostID] = trace.ExpiresAt
+	}
+
+	fc.audit.mu.Lock()
+	fc.audit.traced = traced
+	fc.audit.refreshedAt = time.Now()
+	fc.audit.mu.Unlock()
+}
+
+// RecordAudit appends an entry to a post's audit trail when trace mode is enabled for it
+func (fc *FirestoreClient) RecordAudit(postID, kind, source string, details map[string]interface{}) {
+	if postID == "" || !fc.IsPostTraced(postID) {
+		return
+	}
+
+	fc.audit.mu.RLock()
+	expiresAt := fc.audit.traced[postID]
+	fc.audit.mu.RUnlock()
+
+	entry := newTraceEntry(postID, kind, source, details, expiresAt, time.Now())
+	_, _, err := fc.client.Collection("post_traces").Doc(postID).Collection("entries").Add(fc.ctx, entry)
+	if err != nil {
+		logger.Debugf(" Failed to record audit entry for post %s: %v", postID, err)
+	}
+}
+
+// newTraceEntry returns an audit entry recorded at now for a post traced until traceExpiresAt.
+// Entries outlive the trace by MaxPostTraceDuration, so the last ones recorded can still be
+// read after the trace ends.
+func newTraceEntry(postID, kind, source string, details map[string]interface{}, traceExpiresAt, now time.Time) models.PostTraceEntry {
+	return models.PostTraceEntry{
+		PostID:    postID,
+		Kind:      kind,
+		Source:    source,
+		Details:   details,
+		Timestamp: now,
+		ExpiresAt: traceExpiresAt.Add(MaxPostTraceDuration),
+	}
+}
+
+// GetPostTrace returns the trace settings and the latest recorded audit entries of a post,
+// newest first.
+// The trace is nil when trace mode was never enabled for the post.
+func (fc *FirestoreClient) GetPostTrace(postID string, limit int) (*models.PostTrace, []models.PostTraceEntry, error) {
+	doc, err := fc.client.Collection("post_traces").Doc(postID).Get(fc.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var trace models.PostTrace
+	if err := doc.DataTo(&trace); err != nil {
+		return nil, nil, err
+	}
+
+	iter := fc.client.Collection("post_traces").Doc(postID).Collection("entries").
+		OrderBy("Timestamp", firestore.Desc).
+		Limit(limit).
+		Documents(fc.ctx)
+
+	entries := []models.PostTraceEntry{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var entry models.PostTraceEntry
+		if err := doc.DataTo(&entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return &trace, entries, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIsPostTracedExpires(t *testing.T) {
+	now := time.Now()
+	fc := &FirestoreClient{audit: newPostAuditor()}
+	fc.audit.refreshedAt = now
+	fc.audit.traced["post-active"] = now.Add(time.Hour)
+	fc.audit.traced["post-expired"] = now.Add(-time.Second)
+
+	if !fc.IsPostTraced("post-active") {
+		t.Error("Expected a post traced until later to be traced")
+	}
+	if fc.IsPostTraced("post-expired") {
+		t.Error("Expected a post whose trace expired not to be traced")
+	}
+	if fc.IsPostTraced("post-unknown") {
+		t.Error("Expected a post never traced not to be traced")
+	}
+}
+
+func TestTraceEntryExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	traceExpiresAt := now.Add(time.Hour)
+
+	entry := newTraceEntry("post-1", AuditKindEvent, "view", nil, traceExpiresAt, now)
+	if !entry.Timestamp.Equal(now) {
+		t.Errorf("Expected the entry recorded at %v, got %v", now, entry.Timestamp)
+	}
+	if expected := traceExpiresAt.Add(MaxPostTraceDuration); !entry.ExpiresAt.Equal(expected) {
+		t.Errorf("Expected the entry to expire at %v, got %v", expected, entry.ExpiresAt)
+	}
+}
+
+// Trace entries are deleted by the TTL policy declared on their ExpiresAt field
+func TestTraceEntriesHaveTTLPolicy(t *testing.T) {
+	data, err := os.ReadFile("../../firestore.indexes.json")
+	if err != nil {
+		t.Fatalf("Failed to read firestore.indexes.json: %v", err)
+	}
+	var indexes struct {
+		FieldOverrides []struct {
+			CollectionGroup string `json:"collectionGroup"`
+			FieldPath       string `json:"fieldPath"`
+			TTL             bool   `json:"ttl"`
+		} `json:"fieldOverrides"`
+	}
+	if err := json.Unmarshal(data, &indexes); err != nil {
+		t.Fatalf("Failed to decode firestore.indexes.json: %v", err)
+	}
+	for _, override := range indexes.FieldOverrides {
+		if override.CollectionGroup == "entries" && override.FieldPath == "ExpiresAt" && override.TTL {
+			return
+		}
+	}
+	t.Error("Expected a TTL policy on the ExpiresAt field of the entries collection group")
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Posts compared side by side at most, and the longest age their trajectories cover. Hourly
+// samples of single posts are kept as long as their hourly rollups.
+const (
+	MaxComparedPosts     = 5
+	MaxComparisonHours   = 7 * 24
+	viralSampleRetention = postHourlyRollupRetention
+)
+
+// viralSample is the last viral probability predicted for a post during one hour
+type viralSample struct {
+	PostID           string
+	Hour             time.Time
+	ViralProbability float64
+	ExpiresAt        time.Time // retention cutoff for the sample
+}
+
+// viralSampleRef returns the document of a post's viral probability sample for an hour
+func (fc *FirestoreClient) viralSampleRef(postID string, hour time.Time) *firestore.DocumentRef {
+	return fc.client.Collection("post_viral_hourly").Doc(postID + "_" + hour.UTC().Format("2006010215"))
+}
+
+// RecordViralSample stores the viral probability predicted for a post at a time as the
+// sample of its hour, replacing earlier predictions of the same hour
+func (fc *FirestoreClient) RecordViralSample(postID string, probability float64, at time.Time) error {
+	hour := rollupStart(RollupHour, at, fc.reportingLoc)
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.viralSampleRef(postID, hour).Set(fc.ctx, viralSample{
+		PostID:           postID,
+		Hour:             hour,
+		ViralProbability: probability,
+		ExpiresAt:        hour.Add(viralSampleRetention),
+	})
+	return err
+}
+
+// GetViralSamples returns the viral probability samples of a post for the hours starting at
+// hours; hours without a prediction are nil
+func (fc *FirestoreClient) GetViralSamples(postID string, hours []time.Time) ([]*float64, error) {
+	refs := make([]*firestore.DocumentRef, len(hours))
+	for i, hour := range hours {
+		refs[i] = fc.viralSampleRef(postID, hour)
+	}
+	Quotas.Record(QuotaFirestore, int64(len(refs)))
+	docs, err := fc.client.GetAll(fc.ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]*float64, len(hours))
+	for i, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		var sample viralSample
+		if err := doc.DataTo(&sample); err != nil {
+			logger.Debugf(" Skipping unreadable viral sample %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		samples[i] = &sample.ViralProbability
+	}
+	return samples, nil
+}
+
+// PostAgePoint is a post's engagement during one hour of its life. Hour 0 is the clock hour
+// the post was published in.
+type PostAgePoint struct {
+	AgeHours        int     `json:"ageHours"`
+	Views           int64   `json:"views"`
+	CumulativeViews int64   `json:"cumulativeViews"`
+	ViewsPerHour    float64 `json:"viewsPerHour"` // cumulative views per hour of age
+	Likes           int64   `json:"likes"`
+	Comments        int64   `json:"comments"`
+	Shares          int64   `json:"shares"`
+	Remixes         int64   `json:"remixes"`
+	EngagementRate  float64 `json:"engagementRate"` // of the hour's views
+
+	// Latest viral probability predicted up to the end of the hour; null before the first
+	ViralProbability *float64 `json:"viralProbability"`
+}
+
+// ComparedPost is one post of a comparison: its current metrics normalized by age and views,
+// and its trajectory by age
+type ComparedPost struct {
+	PostID           string         `json:"postId"`
+	ContentType      string         `json:"contentType,omitempty"`
+	Title            string         `json:"title,omitempty"`
+	PublishedAt      time.Time      `json:"publishedAt"`
+	AgeHours         float64        `json:"ageHours"`
+	Views            int64          `json:"views"`
+	ViewsPerHour     float64        `json:"viewsPerHour"` // since publish
+	EngagementRate   float64        `json:"engagementRate"`
+	ViralProbability float64        `json:"viralProbability"`
+	Score            float64        `json:"score"`
+	Trajectory       []PostAgePoint `json:"trajectory"`
+}
+
+// PostComparison lines up posts by age so variations of the same prompt published at
+// different times can be compared
+type PostComparison struct {
+	Hours        int            `json:"hours"`
+	Posts        []ComparedPost `json:"posts"`
+	Missing      []string       `json:"missing,omitempty"` // posts without analytics yet
+	CalculatedAt time.Time      `json:"calculatedAt"`
+}
+
+// ComparePosts compares posts over their first hours of age. Posts without a trending score
+// are listed as missing; the comparison is nil when none of the posts has one.
+func (da *DashboardAnalytics) ComparePosts(postIDs []string, hours int) (*PostComparison, error) {
+	logger.Debugf("📊 Comparing %d posts...", len(postIDs))
+
+	now := time.Now()
+	comparison := &PostComparison{Hours: hours, Posts: []ComparedPost{}, CalculatedAt: now}
+	for _, postID := range postIDs {
+		score, err := da.firestoreClient.GetPostStats(postID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load stats of post %s: %w", postID, err)
+		}
+		if score == nil {
+			comparison.Missing = append(comparison.Missing, postID)
+			continue
+		}
+
+		publishedAt := da.firestoreClient.PostCreatedAt(postID, score.CalculatedAt)
+		end := publishedAt.Add(time.Duration(hours) * time.Hour)
+		if now.Before(end) {
+			end = now
+		}
+		starts := rollupStarts(RollupHour, publishedAt, end, da.firestoreClient.reportingLoc)
+		rollups, err := da.firestoreClient.GetEngagementRollups(postID, RollupHour, starts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rollups of post %s: %w", postID, err)
+		}
+		samples, err := da.firestoreClient.GetViralSamples(postID, starts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load viral samples of post %s: %w", postID, err)
+		}
+
+		comparison.Posts = append(comparison.Posts, comparePost(*score, publishedAt, rollups, samples, now))
+	}
+	if len(comparison.Posts) == 0 {
+		return nil, nil
+	}
+	return comparison, nil
+}
+
+// comparePost normalizes a post's metrics by its age and views and lines up its hourly rollups
+// and viral samples by age
+func comparePost(score models.TrendingScore, publishedAt time.Time, rollups []EngagementRollup, samples []*float64, now time.Time) ComparedPost {
+	age := now.Sub(publishedAt).Hours()
+	post := ComparedPost{
+		PostID:           score.PostID,
+		ContentType:      score.ContentType,
+		Title:            score.Title,
+		PublishedAt:      publishedAt,
+		AgeHours:         age,
+		Views:            score.ViewCount,
+		ViewsPerHour:     float64(score.ViewCount) / max(age, 1),
+		EngagementRate:   engagementRate(score.ViewCount, score.LikeCount, score.CommentCount, score.ShareCount),
+		ViralProbability: score.ViralProbability,
+		Score:            score.Score,
+		Trajectory:       make([]PostAgePoint, len(rollups)),
+	}
+
+	var cumulative int64
+	var probability *float64
+	for i, rollup := range rollups {
+		cumulative += rollup.Views
+		if i < len(samples) && samples[i] != nil {
+			probability = samples[i]
+		}
+		post.Trajectory[i] = PostAgePoint{
+			AgeHours:         i,
+			Views:            rollup.Views,
+			CumulativeViews:  cumulative,
+			ViewsPerHour:     float64(cumulative) / float64(i+1),
+			Likes:            rollup.Likes,
+			Comments:         rollup.Comments,
+			Shares:           rollup.Shares,
+			Remixes:          rollup.Remixes,
+			EngagementRate:   engagementRate(rollup.Views, rollup.Likes, rollup.Comments, rollup.Shares),
+			ViralProbability: probability,
+		}
+	}
+	return post
+}
+
+// engagementRate returns likes, comments and shares as a percentage of views
+func engagementRate(views, likes, comments, shares int64) float64 {
+	if views == 0 {
+		return 0
+	}
+	return (float64(likes+comments+shares) / float64(views)) * 100
+}
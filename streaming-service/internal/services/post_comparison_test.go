@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestComparePost(t *testing.T) {
+	publishedAt := time.Date(2024, 5, 10, 9, 40, 0, 0, time.UTC)
+	now := publishedAt.Add(4 * time.Hour)
+	score := models.TrendingScore{PostID: "post-1", ViewCount: 200, LikeCount: 10, CommentCount: 6, ShareCount: 4, ViralProbability: 0.6}
+	rollups := []EngagementRollup{
+		{Views: 20, Likes: 2},
+		{Views: 100, Likes: 5, Comments: 5},
+		{Views: 0},
+		{Views: 80, Shares: 4},
+	}
+	early, later := 0.2, 0.6
+	samples := []*float64{nil, &early, nil, &later}
+
+	post := comparePost(score, publishedAt, rollups, samples, now)
+	if post.ViewsPerHour != 50 || post.EngagementRate != 10 || post.AgeHours != 4 {
+		t.Errorf("Unexpected normalized metrics %+v", post)
+	}
+	if len(post.Trajectory) != 4 {
+		t.Fatalf("Expected 4 hours of trajectory, got %d", len(post.Trajectory))
+	}
+
+	second := post.Trajectory[1]
+	if second.AgeHours != 1 || second.CumulativeViews != 120 || second.ViewsPerHour != 60 || second.EngagementRate != 10 {
+		t.Errorf("Unexpected second hour %+v", second)
+	}
+	if post.Trajectory[0].ViralProbability != nil {
+		t.Error("Expected no viral probability before the first prediction")
+	}
+	if p := post.Trajectory[2].ViralProbability; p == nil || *p != early {
+		t.Errorf("Expected the last prediction carried forward, got %v", p)
+	}
+	if p := post.Trajectory[3].ViralProbability; p == nil || *p != later {
+		t.Errorf("Expected the hour's prediction, got %v", p)
+	}
+	if post.Trajectory[2].EngagementRate != 0 {
+		t.Errorf("Expected no engagement rate without views, got %v", post.Trajectory[2].EngagementRate)
+	}
+}
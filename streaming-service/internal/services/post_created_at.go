@@ -0,0 +1,158 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// Field the creation time of posts is normalized to; the app writes createdAt, which the
+// fallback and coaching queries order by, while older pipeline writers used created_at
+const postCreatedAtField = "createdAt"
+
+// Fields a post's creation time is read from, canonical first
+var postCreatedAtFields = []string{postCreatedAtField, "created_at"}
+
+// Posts without a creation time listed in the decay anchor report
+const maxUnanchoredPosts = 100
+
+// postCreationTime resolves a post's creation time from the first of its creation fields
+// holding a timestamp, an RFC 3339 string or Unix seconds or milliseconds. field is the field
+// the time was read from, "" when none holds one.
+func postCreationTime(postData map[string]interface{}) (time.Time, string) {
+	for _, field := range postCreatedAtFields {
+		if createdAt, ok := parseCreationTime(postData[field]); ok {
+			return createdAt, field
+		}
+	}
+	return time.Time{}, ""
+}
+
+// parseCreationTime reads a creation time stored in any of the forms writers have used
+func parseCreationTime(value interface{}) (time.Time, bool) {
+	var unix float64
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		return parsed, err == nil
+	case int64:
+		unix = float64(v)
+	case float64:
+		unix = v
+	default:
+		return time.Time{}, false
+	}
+	if unix <= 0 {
+		return time.Time{}, false
+	}
+	// Seconds reach 1e12 only in the year 33658
+	if unix >= 1e12 {
+		return time.UnixMilli(int64(unix)), true
+	}
+	return time.Unix(int64(unix), 0), true
+}
+
+// needsCreatedAtBackfill reports whether a post's creation time is resolvable but not stored
+// as a timestamp in the canonical field
+func needsCreatedAtBackfill(postData map[string]interface{}) bool {
+	createdAt, field := postCreationTime(postData)
+	if field == "" {
+		return false
+	}
+	stored, ok := postData[postCreatedAtField].(time.Time)
+	return !ok || !stored.Equal(createdAt)
+}
+
+// decayAnchor resolves the creation time a post's score decays from and records how it was
+// resolved in the decay anchor report
+func decayAnchor(postID string, postData map[string]interface{}) (time.Time, bool) {
+	createdAt, field := postCreationTime(postData)
+	DecayAnchors.Record(postID, field)
+	return createdAt, field != ""
+}
+
+// DecayAnchors is the process-wide report of how the decay anchors of posts were resolved
+var DecayAnchors = NewDecayAnchorReport()
+
+// DecayAnchorReport counts the creation fields decay anchors were resolved from and keeps the
+// latest posts none could be resolved for, whose scores decay from a fallback time instead
+type DecayAnchorReport struct {
+	mu         sync.Mutex
+	fields     map[string]int64
+	unanchored map[string]time.Time // post ID to when it was last seen without an anchor
+	since      time.Time
+}
+
+// DecayAnchorSnapshot is the decay anchor report at one point in time
+type DecayAnchorSnapshot struct {
+	Fields     map[string]int64 `json:"fields"` // anchors resolved per creation field
+	Unresolved int64            `json:"unresolved"`
+	Unanchored []string         `json:"unanchored_posts"` // latest first
+	Since      time.Time        `json:"since"`
+	Canonical  string           `json:"canonical_field"`
+}
+
+func NewDecayAnchorReport() *DecayAnchorReport {
+	return &DecayAnchorReport{
+		fields:     make(map[string]int64),
+		unanchored: make(map[string]time.Time),
+		since:      time.Now(),
+	}
+}
+
+// Record counts a decay anchor resolved from field, "" when none could be. A post is logged
+// the first time it turns up without an anchor.
+func (r *DecayAnchorReport) Record(postID, field string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if field != "" {
+		r.fields[field]++
+		delete(r.unanchored, postID)
+		return
+	}
+
+	r.fields[""]++
+	if _, seen := r.unanchored[postID]; !seen {
+		logger.Warnf("⏳ Post %s has no creation time in %v; its score decays from a fallback time", postID, postCreatedAtFields)
+	}
+	r.unanchored[postID] = time.Now()
+	if len(r.unanchored) > maxUnanchoredPosts {
+		oldest, oldestAt := "", time.Now()
+		for id, at := range r.unanchored {
+			if at.Before(oldestAt) {
+				oldest, oldestAt = id, at
+			}
+		}
+		delete(r.unanchored, oldest)
+	}
+}
+
+// Snapshot returns the report
+func (r *DecayAnchorReport) Snapshot() DecayAnchorSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := DecayAnchorSnapshot{
+		Fields:     make(map[string]int64, len(r.fields)),
+		Unresolved: r.fields[""],
+		Unanchored: make([]string, 0, len(r.unanchored)),
+		Since:      r.since,
+		Canonical:  postCreatedAtField,
+	}
+	for field, count := range r.fields {
+		if field != "" {
+			snapshot.Fields[field] = count
+		}
+	}
+	for postID := range r.unanchored {
+		snapshot.Unanchored = append(snapshot.Unanchored, postID)
+	}
+	sort.Slice(snapshot.Unanchored, func(i, j int) bool {
+		return r.unanchored[snapshot.Unanchored[i]].After(r.unanchored[snapshot.Unanchored[j]])
+	})
+	return snapshot
+}
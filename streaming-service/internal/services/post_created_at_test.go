@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPostCreationTime(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		postData      map[string]interface{}
+		expectedField string
+	}{
+		{"canonical timestamp", map[string]interface{}{"createdAt": created}, "createdAt"},
+		{"pipeline timestamp", map[string]interface{}{"created_at": created}, "created_at"},
+		{"canonical first", map[string]interface{}{"createdAt": created, "created_at": created.Add(time.Hour)}, "createdAt"},
+		{"RFC 3339 string", map[string]interface{}{"created_at": "2024-05-01T12:00:00Z"}, "created_at"},
+		{"Unix seconds", map[string]interface{}{"createdAt": created.Unix()}, "createdAt"},
+		{"Unix milliseconds", map[string]interface{}{"createdAt": float64(created.UnixMilli())}, "createdAt"},
+		{"unreadable canonical", map[string]interface{}{"createdAt": "yesterday", "created_at": created}, "created_at"},
+		{"missing", map[string]interface{}{"title": "post"}, ""},
+	}
+	for _, test := range tests {
+		got, field := postCreationTime(test.postData)
+		if field != test.expectedField {
+			t.Errorf("%s: expected field %q, got %q", test.name, test.expectedField, field)
+			continue
+		}
+		if field != "" && !got.Equal(created) {
+			t.Errorf("%s: expected %v, got %v", test.name, created, got)
+		}
+	}
+}
+
+func TestNeedsCreatedAtBackfill(t *testing.T) {
+	created := time.Now()
+	if needsCreatedAtBackfill(map[string]interface{}{"createdAt": created}) {
+		t.Error("Expected a canonical timestamp to need no backfill")
+	}
+	if !needsCreatedAtBackfill(map[string]interface{}{"created_at": created}) {
+		t.Error("Expected a pipeline timestamp to be backfilled")
+	}
+	if !needsCreatedAtBackfill(map[string]interface{}{"createdAt": created.Unix()}) {
+		t.Error("Expected a Unix time to be backfilled as a timestamp")
+	}
+	if needsCreatedAtBackfill(map[string]interface{}{}) {
+		t.Error("Expected a post without creation time to have nothing to backfill")
+	}
+}
+
+func TestDecayAnchorReport(t *testing.T) {
+	report := NewDecayAnchorReport()
+	report.Record("post-1", "createdAt")
+	report.Record("post-2", "created_at")
+	report.Record("post-3", "")
+	report.Record("post-4", "")
+	report.Record("post-3", "createdAt") // backfilled since
+
+	snapshot := report.Snapshot()
+	if snapshot.Fields["createdAt"] != 2 || snapshot.Fields["created_at"] != 1 || snapshot.Unresolved != 2 {
+		t.Errorf("Expected the resolutions counted per field, got %+v", snapshot)
+	}
+	if len(snapshot.Unanchored) != 1 || snapshot.Unanchored[0] != "post-4" {
+		t.Errorf("Expected only post-4 without an anchor, got %v", snapshot.Unanchored)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"confluent-viral-intelligence/internal/logger"
 	"time"
 
+	"cloud.google.com/go/firestore"
 	"confluent-viral-intelligence/internal/models"
 	"google.golang.org/api/iterator"
 )
@@ -13,6 +14,9 @@ import (
 type PostIndexer struct {
 	firestoreClient *FirestoreClient
 	ctx             context.Context
+
+	// Embeds posts that have no embedding of the configured model, nil to skip them
+	embeddings *EmbeddingService
 }
 
 // NewPostIndexer creates a new post indexer
@@ -23,7 +27,14 @@ func NewPostIndexer(firestoreClient *FirestoreClient) *PostIndexer {
 	}
 }
 
-// IndexAllPosts indexes all posts from the posts collection into trending_scores
+// UseEmbeddings makes indexing embed every post that has no embedding of the configured model
+// yet, for similarity search. Nil leaves embeddings alone.
+func (pi *PostIndexer) UseEmbeddings(embeddings *EmbeddingService) {
+	pi.embeddings = embeddings
+}
+
+// IndexAllPosts indexes all posts from the posts collection into trending_scores, storing the
+// creation time of posts that keep it elsewhere in the canonical createdAt field
 func (pi *PostIndexer) IndexAllPosts() error {
 	startTime := time.Now()
 	logger.Debug("📊 Starting full post indexing...")
@@ -34,6 +45,8 @@ func (pi *PostIndexer) IndexAllPosts() error {
 	indexedCount := 0
 	updatedCount := 0
 	errorCount := 0
+	backfilledCount := 0
+	embeddedCount := 0
 	
 	for {
 		doc, err := iter.Next()
@@ -55,6 +68,18 @@ func (pi *PostIndexer) IndexAllPosts() error {
 		
 		postID := doc.Ref.ID
 		
+		if needsCreatedAtBackfill(postData) {
+			if err := pi.backfillCreatedAt(doc.Ref, postData); err != nil {
+				logger.Debugf(" Failed to backfill creation time of %s: %v", postID, err)
+			} else {
+				backfilledCount++
+			}
+		}
+		
+		if pi.embedMissing(postID, postData) {
+			embeddedCount++
+		}
+
 		// Check if trending score already exists
 		existingScore, err := pi.firestoreClient.GetPostStats(postID)
 		if err == nil && existingScore != nil {
@@ -78,101 +103,82 @@ func (pi *PostIndexer) IndexAllPosts() error {
 	}
 	
 	duration := time.Since(startTime)
-	logger.Infof("✅ Post indexing complete: indexed=%d, updated=%d, errors=%d, duration=%v", 
-		indexedCount, updatedCount, errorCount, duration)
+	logger.Infof("✅ Post indexing complete: indexed=%d, updated=%d, errors=%d, created_at_backfilled=%d, embedded=%d, duration=%v", 
+		indexedCount, updatedCount, errorCount, backfilledCount, embeddedCount, duration)
 	
 	return nil
 }
 
+// embedMissing embeds a post that has no embedding of the configured model yet and reports
+// whether it did
+func (pi *PostIndexer) embedMissing(postID string, postData map[string]interface{}) bool {
+	if pi.embeddings == nil {
+		return false
+	}
+	embedded, err := pi.embeddings.Embedded(postID)
+	if err != nil || embedded {
+		return false
+	}
+	if _, err := pi.embeddings.EmbedPostDocument(postID, postData); err != nil {
+		logger.Debugf(" Failed to embed post %s: %v", postID, err)
+		return false
+	}
+	return true
+}
+
+// backfillCreatedAt stores a post's creation time, resolved from any of its creation fields, as
+// a timestamp in the canonical field
+func (pi *PostIndexer) backfillCreatedAt(ref *firestore.DocumentRef, postData map[string]interface{}) error {
+	createdAt, _ := postCreationTime(postData)
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := ref.Update(pi.ctx, []firestore.Update{{Path: postCreatedAtField, Value: createdAt}})
+	return err
+}
+
 // createTrendingScoreFromPost creates a new trending score from post data
 func (pi *PostIndexer) createTrendingScoreFromPost(postID string, postData map[string]interface{}) error {
-	score := models.TrendingScore{
-		PostID:       postID,
-		ViewCount:    getInt64(postData, "view_count"),
-		LikeCount:    getInt64(postData, "like_count"),
-		CommentCount: getInt64(postData, "comment_count"),
-		ShareCount:   getInt64(postData, "share_count"),
-		RemixCount:   getInt64(postData, "remix_count"),
-		CalculatedAt: time.Now(),
-	}
-	
 	// Get creation time for time decay calculation
-	var createdAt time.Time
-	if createdAtVal, ok := postData["created_at"].(time.Time); ok {
-		createdAt = createdAtVal
-	} else {
+	createdAt, ok := decayAnchor(postID, postData)
+	if !ok {
 		createdAt = time.Now()
 	}
 	
-	// Calculate score with time decay
-	score.Score = pi.calculateScoreWithAge(score, createdAt)
-	
-	// Save to Firestore
-	return pi.firestoreClient.SaveTrendingScore(score)
+	return pi.applyPostCounts(postID, postData, createdAt)
 }
 
 // updateTrendingScoreFromPost updates an existing trending score with latest post data
 func (pi *PostIndexer) updateTrendingScoreFromPost(postID string, postData map[string]interface{}, existingScore *models.TrendingScore) error {
-	// Update counts from post data
-	existingScore.ViewCount = getInt64(postData, "view_count")
-	existingScore.LikeCount = getInt64(postData, "like_count")
-	existingScore.CommentCount = getInt64(postData, "comment_count")
-	existingScore.ShareCount = getInt64(postData, "share_count")
-	existingScore.RemixCount = getInt64(postData, "remix_count")
-	
 	// Get creation time for time decay calculation
-	var createdAt time.Time
-	if createdAtVal, ok := postData["created_at"].(time.Time); ok {
-		createdAt = createdAtVal
-	} else {
+	createdAt, ok := decayAnchor(postID, postData)
+	if !ok {
 		createdAt = existingScore.CalculatedAt
 	}
 	
-	// Recalculate score with time decay
-	existingScore.Score = pi.calculateScoreWithAge(*existingScore, createdAt)
-	existingScore.CalculatedAt = time.Now()
-	
-	// Save to Firestore
-	return pi.firestoreClient.SaveTrendingScore(*existingScore)
+	return pi.applyPostCounts(postID, postData, createdAt)
 }
 
-// calculateScoreWithAge calculates score with time decay from a specific creation time
-func (pi *PostIndexer) calculateScoreWithAge(score models.TrendingScore, createdAt time.Time) float64 {
-	// Calculate hours since post creation
-	hoursSinceCreation := time.Since(createdAt).Hours()
-	
-	// Avoid division by zero for very new posts
-	if hoursSinceCreation < 0.1 {
-		hoursSinceCreation = 0.1
-	}
-	
-	// Weighted scoring algorithm
-	// Views: 0.1, Likes: 1.0, Comments: 2.0, Shares: 3.0, Remixes: 5.0
-	baseScore := float64(score.ViewCount)*0.1 +
-		float64(score.LikeCount)*1.0 +
-		float64(score.CommentCount)*2.0 +
-		float64(score.ShareCount)*3.0 +
-		float64(score.RemixCount)*5.0
-	
-	// Calculate engagement velocity (engagement per hour)
-	totalEngagement := float64(score.LikeCount + score.CommentCount + score.ShareCount + score.RemixCount)
-	engagementVelocity := totalEngagement / hoursSinceCreation
-	
-	// Apply time decay factor (exponential decay)
-	// λ = 0.03 gives half-life of ~23 hours (faster decay for more dynamic trending)
-	lambda := 0.03
-	timeDecayFactor := 1.0 / (1.0 + lambda*hoursSinceCreation)
-	
-	// Recency bonus for new posts (last 24 hours)
-	recencyBonus := 0.0
-	if hoursSinceCreation < 24 {
-		recencyBonus = 10.0 * (1.0 - hoursSinceCreation/24.0)
+// applyPostCounts merges the post's counts and facets into its trending score and recalculates
+// it. The write is versioned so increments made by the consumer while indexing are not lost.
+func (pi *PostIndexer) applyPostCounts(postID string, postData map[string]interface{}, createdAt time.Time) error {
+	postCounts := models.TrendingScore{
+		ViewCount:    getInt64(postData, "view_count"),
+		LikeCount:    getInt64(postData, "like_count"),
+		CommentCount: getInt64(postData, "comment_count"),
+		ShareCount:   getInt64(postData, "share_count"),
+		RemixCount:   getInt64(postData, "remix_count"),
 	}
 	
-	// Calculate final trending score with time decay and velocity
-	trendingScore := (baseScore * timeDecayFactor) + (engagementVelocity * 5.0) + recencyBonus
+	facets := postFacets(postData)
 	
-	return trendingScore
+	_, err := pi.firestoreClient.ApplyTrendingScore(postID, "post_indexer", func(score *models.TrendingScore, exists bool) {
+		mergeScoreCounts(score, postCounts)
+		facets.apply(score)
+		
+		// Recalculate score with time decay
+		pi.firestoreClient.scoring.Apply(score, createdAt)
+		score.CalculatedAt = time.Now()
+	})
+	return err
 }
 
 // getInt64 safely extracts an int64 value from a map
@@ -189,3 +195,18 @@ func getInt64(data map[string]interface{}, key string) int64 {
 	}
 	return 0
 }
+
+// getFloat64 safely extracts a float64 value from a map
+func getFloat64(data map[string]interface{}, key string) float64 {
+	if val, ok := data[key]; ok {
+		switch v := val.(type) {
+		case float64:
+			return v
+		case int64:
+			return float64(v)
+		case int:
+			return float64(v)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// PostCounts are the engagement counts stored on a post document
+type PostCounts struct {
+	ViewCount    int64 `json:"view_count"`
+	LikeCount    int64 `json:"like_count"`
+	CommentCount int64 `json:"comment_count"`
+	ShareCount   int64 `json:"share_count"`
+	RemixCount   int64 `json:"remix_count"`
+}
+
+// PostRecomputation is the outcome of recomputing a post's trending score and viral
+// prediction: the stored score before and after, and what the recomputation was based on
+type PostRecomputation struct {
+	PostID     string                `json:"post_id"`
+	PostCounts PostCounts            `json:"post_counts"`
+	CreatedAt  time.Time             `json:"created_at"`
+	Before     *models.TrendingScore `json:"before"` // nil when the post had no trending score
+	After      *models.TrendingScore `json:"after"`
+
+	Prediction      *models.ViralPredictionResponse `json:"prediction,omitempty"`
+	PredictionError string                          `json:"prediction_error,omitempty"`
+
+	ScoreDelta            float64 `json:"score_delta"`
+	ViralProbabilityDelta float64 `json:"viral_probability_delta"`
+}
+
+// RecomputePost re-reads a post's counts from its post document, recalculates its trending
+// score and re-runs the virality prediction, returning the score before and after. Counts
+// the consumer has already counted past are kept, as when posts are indexed. When the
+// prediction fails the stored viral probability and tier are kept. It returns nil, nil for
+// a post that does not exist.
+func (ep *EventProcessor) RecomputePost(postID string) (*PostRecomputation, error) {
+	postData, err := ep.firestore.GetPostDocument(postID)
+	if err != nil || postData == nil {
+		return nil, err
+	}
+	previous, err := ep.firestore.GetPostStats(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := PostCounts{
+		ViewCount:    getInt64(postData, "view_count"),
+		LikeCount:    getInt64(postData, "like_count"),
+		CommentCount: getInt64(postData, "comment_count"),
+		ShareCount:   getInt64(postData, "share_count"),
+		RemixCount:   getInt64(postData, "remix_count"),
+	}
+	createdAt, ok := decayAnchor(postID, postData)
+	if !ok {
+		createdAt = time.Now()
+		if previous != nil {
+			createdAt = previous.CalculatedAt
+		}
+	}
+	facets := postFacets(postData)
+
+	// Predict from the counts and facets the score is about to be written with
+	expected := models.TrendingScore{PostID: postID}
+	if previous != nil {
+		expected = *previous
+	}
+	mergePostCounts(&expected, counts, facets)
+	expected.Score = ep.firestore.scoring.Score(expected, createdAt)
+
+	result := &PostRecomputation{PostID: postID, PostCounts: counts, CreatedAt: createdAt, Before: previous}
+	prediction, err := ep.ai.PredictVirality(ep.predictionRequest(expected, previous, time.Since(createdAt)))
+	ep.recordAICall(postID, "predict_virality", prediction, err)
+	if err != nil {
+		logger.Infof("Failed to predict virality of recomputed post %s: %v", postID, err)
+		result.PredictionError = err.Error()
+		prediction = nil
+	}
+	result.Prediction = prediction
+
+	eligible := ep.firestore.floor.Allows(expected)
+	stored, err := ep.firestore.ApplyTrendingScore(postID, "admin_recompute", func(score *models.TrendingScore, exists bool) {
+		mergePostCounts(score, counts, facets)
+		ep.firestore.scoring.Apply(score, createdAt)
+		score.CalculatedAt = time.Now()
+		if prediction != nil {
+			score.ViralProbability = prediction.ViralProbability
+			score.ViralTier = ""
+			if tier, ok := ep.config.ViralAlertTierFor(prediction.ViralProbability); ok && eligible {
+				score.ViralTier = tier.Name
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	ep.broadcastScore(stored)
+
+	result.After = stored
+	if previous != nil {
+		result.ScoreDelta = stored.Score - previous.Score
+		result.ViralProbabilityDelta = stored.ViralProbability - previous.ViralProbability
+	} else {
+		result.ScoreDelta = stored.Score
+		result.ViralProbabilityDelta = stored.ViralProbability
+	}
+	logger.Infof("🔁 Recomputed post %s: score %.2f (%+.2f), viral_prob %.2f", postID, stored.Score, result.ScoreDelta, stored.ViralProbability)
+	return result, nil
+}
+
+// mergePostCounts merges a post document's counts and facets into a trending score
+func mergePostCounts(score *models.TrendingScore, counts PostCounts, facets ContentFacets) {
+	mergeScoreCounts(score, models.TrendingScore{
+		ViewCount:    counts.ViewCount,
+		LikeCount:    counts.LikeCount,
+		CommentCount: counts.CommentCount,
+		ShareCount:   counts.ShareCount,
+		RemixCount:   counts.RemixCount,
+	})
+	facets.apply(score)
+}
+
+// GetPostDocument returns the fields of a post document, nil when the post does not exist
+func (fc *FirestoreClient) GetPostDocument(postID string) (map[string]interface{}, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := fc.client.Collection("posts").Doc(postID).Get(fc.ctx)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return doc.Data(), nil
+}
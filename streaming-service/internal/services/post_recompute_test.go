@@ -0,0 +1,22 @@
+package services
+
+import (
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestMergePostCounts(t *testing.T) {
+	score := models.TrendingScore{PostID: "p1", ViewCount: 120, LikeCount: 4, ContentType: "image"}
+	counts := PostCounts{ViewCount: 100, LikeCount: 9, CommentCount: 2}
+
+	mergePostCounts(&score, counts, ContentFacets{Category: "art"})
+
+	// The consumer's higher view count is kept, the post's higher counts are taken
+	if score.ViewCount != 120 || score.LikeCount != 9 || score.CommentCount != 2 {
+		t.Errorf("Unexpected counts: %+v", score)
+	}
+	if score.ContentType != "image" || score.Category != "art" {
+		t.Errorf("Expected known facets applied and unknown ones kept, got %+v", score)
+	}
+}
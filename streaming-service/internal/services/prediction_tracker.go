@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A prediction is judged on the post's peak engagement between these ages of the prediction
+const (
+	predictionOutcomeFrom  = 24 * time.Hour
+	predictionOutcomeUntil = 48 * time.Hour
+)
+
+// Posts remembered as already tracked before the cache is reset
+const maxCachedTrackedPosts = 10000
+
+// Number of equal-width probability bins in the calibration curve
+const calibrationBuckets = 10
+
+// trackedPrediction is the stored first prediction of a post and its observed outcome
+type trackedPrediction struct {
+	PostID           string
+	ViralProbability float64
+	Confidence       float64
+	Source           string
+	PredictedAt      time.Time
+
+	// Peak trending score and interactions seen while the outcome window is open
+	PeakScore   float64
+	Engagement  int64
+	Resolved    bool
+	ActualViral bool
+	ResolvedAt  time.Time
+}
+
+// PredictionTracker records viral predictions and, 24–48h later, the engagement the post
+// actually reached, so the predictor's accuracy can be measured
+type PredictionTracker struct {
+	firestoreClient     *FirestoreClient
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	checkInterval       time.Duration
+	viralScoreThreshold float64
+	leader              *LeaderElector // nil when every instance checks outcomes
+
+	mu      sync.Mutex
+	tracked map[string]bool // posts whose prediction is already stored
+}
+
+// NewPredictionTracker creates a tracker that counts a post as having gone viral when its
+// trending score peaks at or above viralScoreThreshold
+func NewPredictionTracker(firestoreClient *FirestoreClient, viralScoreThreshold float64, checkInterval time.Duration) *PredictionTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &PredictionTracker{
+		firestoreClient:     firestoreClient,
+		ctx:                 ctx,
+		cancel:              cancel,
+		checkInterval:       checkInterval,
+		viralScoreThreshold: viralScoreThreshold,
+		tracked:             make(map[string]bool),
+	}
+}
+
+// UseLeaderElector checks outcomes on the elected instance only; every instance keeps recording
+// predictions
+func (pt *PredictionTracker) UseLeaderElector(leader *LeaderElector) {
+	pt.leader = leader
+}
+
+// Start begins the periodic outcome check
+func (pt *PredictionTracker) Start() {
+	logger.Infof("🎯 Starting prediction tracker (check interval %v)", pt.checkInterval)
+
+	ticker := time.NewTicker(pt.checkInterval)
+	go func() {
+		for {
+			select {
+			case <-pt.ctx.Done():
+				ticker.Stop()
+				logger.Info("🛑 Prediction tracker stopped")
+				return
+			case <-ticker.C:
+				if !pt.leader.IsLeader() {
+					continue
+				}
+				if err := pt.CheckOutcomes(); err != nil {
+					logger.Errorf("❌ Prediction outcome check failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the outcome check loop
+func (pt *PredictionTracker) Stop() {
+	pt.cancel()
+}
+
+// Record stores the first prediction made for a post. Later predictions of the same post are
+// ignored so every post is judged on what was predicted while it was still early.
+func (pt *PredictionTracker) Record(postID string, prediction *models.ViralPredictionResponse) {
+	if pt == nil || postID == "" || prediction == nil {
+		return
+	}
+
+	pt.mu.Lock()
+	if pt.tracked[postID] {
+		pt.mu.Unlock()
+		return
+	}
+	if len(pt.tracked) >= maxCachedTrackedPosts {
+		pt.tracked = make(map[string]bool)
+	}
+	pt.tracked[postID] = true
+	pt.mu.Unlock()
+
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := pt.firestoreClient.client.Collection("prediction_outcomes").Doc(postID).Create(pt.ctx, trackedPrediction{
+		PostID:           postID,
+		ViralProbability: prediction.ViralProbability,
+		Confidence:       prediction.Confidence,
+		Source:           prediction.Source,
+		PredictedAt:      time.Now(),
+	})
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		logger.Infof("Failed to track prediction for post %s: %v", postID, err)
+	}
+}
+
+// CheckOutcomes samples the engagement of predictions inside their outcome window and resolves
+// the ones whose window has closed
+func (pt *PredictionTracker) CheckOutcomes() error {
+	now := time.Now()
+	iter := pt.firestoreClient.client.Collection("prediction_outcomes").
+		Where("Resolved", "==", false).
+		Where("PredictedAt", "<=", now.Add(-predictionOutcomeFrom)).
+		Documents(pt.ctx)
+	defer iter.Stop()
+
+	sampled, resolved := 0, 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		Quotas.Record(QuotaFirestore, 1)
+
+		var prediction trackedPrediction
+		if err := doc.DataTo(&prediction); err != nil {
+			continue
+		}
+
+		score, err := pt.firestoreClient.GetPostStats(prediction.PostID)
+		if err != nil {
+			logger.Debugf(" Could not read score of predicted post %s: %v", prediction.PostID, err)
+		} else if score != nil {
+			observeOutcome(&prediction, score)
+			sampled++
+		}
+
+		if now.Sub(prediction.PredictedAt) >= predictionOutcomeUntil {
+			prediction.Resolved = true
+			prediction.ActualViral = prediction.PeakScore >= pt.viralScoreThreshold
+			prediction.ResolvedAt = now
+			resolved++
+		}
+
+		Quotas.Record(QuotaFirestore, 1)
+		if _, err := doc.Ref.Set(pt.ctx, prediction); err != nil {
+			logger.Infof("Failed to update prediction outcome of post %s: %v", prediction.PostID, err)
+		}
+	}
+
+	if sampled > 0 || resolved > 0 {
+		logger.Infof("🎯 Prediction outcomes checked: sampled=%d, resolved=%d", sampled, resolved)
+	}
+	return nil
+}
+
+// observeOutcome folds the post's current trending score into the outcome seen so far
+func observeOutcome(prediction *trackedPrediction, score *models.TrendingScore) {
+	prediction.PeakScore = math.Max(prediction.PeakScore, score.Score)
+	prediction.Engagement = max64(prediction.Engagement,
+		score.LikeCount+score.CommentCount+score.ShareCount+score.RemixCount)
+}
+
+// PredictionAccuracy summarizes how well resolved viral predictions matched what happened
+type PredictionAccuracy struct {
+	Threshold      float64             `json:"threshold"`
+	Source         string              `json:"source,omitempty"` // predictor, all when empty
+	Resolved       int                 `json:"resolved"`
+	TruePositives  int                 `json:"truePositives"`
+	FalsePositives int                 `json:"falsePositives"`
+	FalseNegatives int                 `json:"falseNegatives"`
+	TrueNegatives  int                 `json:"trueNegatives"`
+	Precision      float64             `json:"precision"`
+	Recall         float64             `json:"recall"`
+	Accuracy       float64             `json:"accuracy"`
+	BrierScore     float64             `json:"brierScore"` // mean squared error of the probabilities
+	Calibration    []CalibrationBucket `json:"calibration"`
+	Since          time.Time           `json:"since"`
+	CalculatedAt   time.Time           `json:"calculatedAt"`
+}
+
+// CalibrationBucket compares predicted probabilities in a range with the observed viral rate
+type CalibrationBucket struct {
+	MinProbability float64 `json:"minProbability"`
+	MaxProbability float64 `json:"maxProbability"`
+	Count          int     `json:"count"`
+	MeanPredicted  float64 `json:"meanPredicted"`
+	ActualRate     float64 `json:"actualRate"`
+}
+
+// GetPredictionAccuracy computes calibration metrics over the predictions resolved in the last
+// days, optionally only for one predictor source, counting probabilities at or above threshold
+// as predicted viral
+func (da *DashboardAnalytics) GetPredictionAccuracy(days int, source string, threshold float64) (*PredictionAccuracy, error) {
+	logger.Debugf("📊 Calculating prediction accuracy for last %d days...", days)
+
+	since := time.Now().AddDate(0, 0, -days)
+	iter := da.firestoreClient.client.Collection("prediction_outcomes").
+		Where("Resolved", "==", true).
+		Where("ResolvedAt", ">=", since).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	var predictions []trackedPrediction
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var prediction trackedPrediction
+		if err := doc.DataTo(&prediction); err != nil {
+			continue
+		}
+		if source != "" && prediction.Source != source {
+			continue
+		}
+		predictions = append(predictions, prediction)
+	}
+
+	accuracy := scorePredictions(predictions, threshold)
+	accuracy.Source = source
+	accuracy.Since = since
+	accuracy.CalculatedAt = time.Now()
+
+	logger.Infof("✅ Prediction accuracy calculated: %d resolved predictions", accuracy.Resolved)
+	return accuracy, nil
+}
+
+// scorePredictions computes precision and recall at threshold, the Brier score and the
+// calibration curve of resolved predictions
+func scorePredictions(predictions []trackedPrediction, threshold float64) *PredictionAccuracy {
+	accuracy := &PredictionAccuracy{
+		Threshold:   threshold,
+		Resolved:    len(predictions),
+		Calibration: make([]CalibrationBucket, calibrationBuckets),
+	}
+	for i := range accuracy.Calibration {
+		accuracy.Calibration[i].MinProbability = float64(i) / calibrationBuckets
+		accuracy.Calibration[i].MaxProbability = float64(i+1) / calibrationBuckets
+	}
+
+	squaredError := 0.0
+	for _, p := range predictions {
+		predictedViral := p.ViralProbability >= threshold
+		switch {
+		case predictedViral && p.ActualViral:
+			accuracy.TruePositives++
+		case predictedViral:
+			accuracy.FalsePositives++
+		case p.ActualViral:
+			accuracy.FalseNegatives++
+		default:
+			accuracy.TrueNegatives++
+		}
+
+		actual := 0.0
+		if p.ActualViral {
+			actual = 1
+		}
+		squaredError += (p.ViralProbability - actual) * (p.ViralProbability - actual)
+
+		bucket := &accuracy.Calibration[min(int(clamp01(p.ViralProbability)*calibrationBuckets), calibrationBuckets-1)]
+		bucket.Count++
+		bucket.MeanPredicted += p.ViralProbability
+		bucket.ActualRate += actual
+	}
+
+	for i := range accuracy.Calibration {
+		if bucket := &accuracy.Calibration[i]; bucket.Count > 0 {
+			bucket.MeanPredicted /= float64(bucket.Count)
+			bucket.ActualRate /= float64(bucket.Count)
+		}
+	}
+	if predicted := accuracy.TruePositives + accuracy.FalsePositives; predicted > 0 {
+		accuracy.Precision = float64(accuracy.TruePositives) / float64(predicted)
+	}
+	if viral := accuracy.TruePositives + accuracy.FalseNegatives; viral > 0 {
+		accuracy.Recall = float64(accuracy.TruePositives) / float64(viral)
+	}
+	if accuracy.Resolved > 0 {
+		accuracy.Accuracy = float64(accuracy.TruePositives+accuracy.TrueNegatives) / float64(accuracy.Resolved)
+		accuracy.BrierScore = squaredError / float64(accuracy.Resolved)
+	}
+	return accuracy
+}
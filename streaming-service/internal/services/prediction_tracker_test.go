@@ -0,0 +1,72 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestScorePredictions(t *testing.T) {
+	predictions := []trackedPrediction{
+		{ViralProbability: 0.9, ActualViral: true},   // true positive
+		{ViralProbability: 0.75, ActualViral: false}, // false positive
+		{ViralProbability: 0.4, ActualViral: true},   // false negative
+		{ViralProbability: 0.1, ActualViral: false},  // true negative
+		{ViralProbability: 1.0, ActualViral: true},   // true positive in the top bucket
+	}
+
+	accuracy := scorePredictions(predictions, config.DefaultViralProbabilityThreshold)
+
+	if accuracy.Resolved != 5 || accuracy.TruePositives != 2 || accuracy.FalsePositives != 1 ||
+		accuracy.FalseNegatives != 1 || accuracy.TrueNegatives != 1 {
+		t.Fatalf("unexpected confusion matrix: %+v", accuracy)
+	}
+	if math.Abs(accuracy.Precision-2.0/3) > 1e-9 || math.Abs(accuracy.Recall-2.0/3) > 1e-9 {
+		t.Errorf("expected precision and recall of 2/3, got %f and %f", accuracy.Precision, accuracy.Recall)
+	}
+	if math.Abs(accuracy.Accuracy-0.6) > 1e-9 {
+		t.Errorf("expected accuracy 0.6, got %f", accuracy.Accuracy)
+	}
+	// (0.01 + 0.5625 + 0.36 + 0.01 + 0) / 5
+	if math.Abs(accuracy.BrierScore-0.18850) > 1e-9 {
+		t.Errorf("expected Brier score 0.1885, got %f", accuracy.BrierScore)
+	}
+
+	if len(accuracy.Calibration) != calibrationBuckets {
+		t.Fatalf("expected %d calibration buckets, got %d", calibrationBuckets, len(accuracy.Calibration))
+	}
+	top := accuracy.Calibration[calibrationBuckets-1]
+	if top.Count != 2 || math.Abs(top.MeanPredicted-0.95) > 1e-9 || top.ActualRate != 1 {
+		t.Errorf("unexpected top calibration bucket: %+v", top)
+	}
+	if low := accuracy.Calibration[1]; low.Count != 1 || low.ActualRate != 0 {
+		t.Errorf("unexpected calibration bucket for 0.1: %+v", low)
+	}
+}
+
+func TestScorePredictionsEmpty(t *testing.T) {
+	accuracy := scorePredictions(nil, config.DefaultViralProbabilityThreshold)
+	if accuracy.Resolved != 0 || accuracy.Precision != 0 || accuracy.Recall != 0 || accuracy.BrierScore != 0 {
+		t.Errorf("expected zero metrics without predictions, got %+v", accuracy)
+	}
+}
+
+func TestObserveOutcomeKeepsPeak(t *testing.T) {
+	prediction := trackedPrediction{}
+	observeOutcome(&prediction, &models.TrendingScore{Score: 80, LikeCount: 10, ShareCount: 2})
+	observeOutcome(&prediction, &models.TrendingScore{Score: 60, LikeCount: 12, ShareCount: 2})
+
+	if prediction.PeakScore != 80 {
+		t.Errorf("expected peak score 80, got %f", prediction.PeakScore)
+	}
+	if prediction.Engagement != 14 {
+		t.Errorf("expected engagement 14, got %d", prediction.Engagement)
+	}
+}
+
+func TestPredictionTrackerRecordIsNilSafe(t *testing.T) {
+	var tracker *PredictionTracker
+	tracker.Record("post-1", &models.ViralPredictionResponse{ViralProbability: 0.8})
+}
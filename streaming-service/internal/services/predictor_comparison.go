@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+// A post is compared at most this often, so the extra model calls stay bounded while scores
+// for the same post keep arriving from every Flink window
+const dualRunCompareInterval = 15 * time.Minute
+
+// predictorDualRun runs the heuristic and a model-based viral predictor side by side while the
+// heuristic is being retired, keeping both outputs per post for the comparison report. Whichever
+// predictor VIRAL_PREDICTION_MODE selects is still the one served.
+type predictorDualRun struct {
+	vertexAI  *VertexAIClient
+	firestore *FirestoreClient
+	until     time.Time
+	mode      string // served predictor
+	model     string // model compared with the heuristic
+	threshold float64
+
+	mu           sync.Mutex
+	lastCompared map[string]time.Time
+}
+
+func newPredictorDualRun(vertexAI *VertexAIClient, firestore *FirestoreClient, cfg *config.Config) *predictorDualRun {
+	model := cfg.ViralPredictionMode
+	if model == ViralPredictionModeHeuristic {
+		model = cfg.ViralPredictionDualRunModel
+	}
+
+	return &predictorDualRun{
+		vertexAI:     vertexAI,
+		firestore:    firestore,
+		until:        cfg.ViralPredictionDualRunUntil,
+		mode:         cfg.ViralPredictionMode,
+		model:        model,
+		threshold:    cfg.ViralThreshold(),
+		lastCompared: make(map[string]time.Time),
+	}
+}
+
+// active reports whether the dual-run period is still open
+func (d *predictorDualRun) active(now time.Time) bool {
+	return d != nil && now.Before(d.until)
+}
+
+// due reports whether a post is due for another comparison and marks it compared
+func (d *predictorDualRun) due(postID string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if last, ok := d.lastCompared[postID]; ok && now.Sub(last) < dualRunCompareInterval {
+		return false
+	}
+	if len(d.lastCompared) >= maxCachedTrackedPosts {
+		d.lastCompared = make(map[string]time.Time)
+	}
+	d.lastCompared[postID] = now
+	return true
+}
+
+// evictExpired forgets posts whose comparison throttle has lapsed
+func (d *predictorDualRun) evictExpired(ctx context.Context) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for postID, last := range d.lastCompared {
+		if now.Sub(last) >= dualRunCompareInterval {
+			delete(d.lastCompared, postID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (d *predictorDualRun) len(ctx context.Context) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.lastCompared), nil
+}
+
+// Compare runs the predictor that was not served for a request and stores both outputs
+func (d *predictorDualRun) Compare(req models.ViralPredictionRequest, served *models.ViralPredictionResponse) {
+	now := time.Now()
+	if !d.active(now) || !d.due(req.PostID, now) {
+		return
+	}
+
+	var modelPrediction, heuristicPrediction *models.ViralPredictionResponse
+	switch {
+	case d.mode == ViralPredictionModeHeuristic:
+		prediction, err := d.vertexAI.predictWithModel(d.model, req)
+		if err != nil {
+			logger.Debugf(" Dual-run %s prediction failed for %s: %v", d.model, req.PostID, err)
+			return
+		}
+		modelPrediction, heuristicPrediction = prediction, served
+	case served.Source == ViralPredictionModeHeuristic:
+		// The model failed and the heuristic stood in; there is nothing to compare
+		return
+	default:
+		modelPrediction, heuristicPrediction = served, predictViralityHeuristic(req, d.vertexAI.config)
+	}
+
+	comparison := comparePredictions(req.PostID, d.model, modelPrediction, heuristicPrediction, d.threshold)
+	comparison.Served = served.Source
+	comparison.ComparedAt = now
+	if err := d.firestore.SavePredictionComparison(comparison); err != nil {
+		logger.Infof("Failed to save predictor comparison for post %s: %v", req.PostID, err)
+	}
+}
+
+// comparePredictions pairs the outputs of the model and the heuristic for a post; they agree
+// when both or neither reach the viral threshold
+func comparePredictions(postID, model string, modelPrediction, heuristicPrediction *models.ViralPredictionResponse, threshold float64) models.PredictionComparison {
+	return models.PredictionComparison{
+		PostID:               postID,
+		Model:                model,
+		ModelProbability:     modelPrediction.ViralProbability,
+		HeuristicProbability: heuristicPrediction.ViralProbability,
+		Difference:           modelPrediction.ViralProbability - heuristicPrediction.ViralProbability,
+		Agree: (modelPrediction.ViralProbability >= threshold) ==
+			(heuristicPrediction.ViralProbability >= threshold),
+	}
+}
+
+// predictWithModel runs a model-based predictor without falling back to the heuristic
+func (v *VertexAIClient) predictWithModel(mode string, req models.ViralPredictionRequest) (*models.ViralPredictionResponse, error) {
+	switch mode {
+	case ViralPredictionModeGemini:
+		return v.predictViralityWithGemini(req)
+	case ViralPredictionModeEndpoint:
+		return v.predictViralityWithEndpoint(req)
+	default:
+		return nil, fmt.Errorf("unknown viral prediction model %q", mode)
+	}
+}
+
+// SavePredictionComparison stores the latest predictor comparison of a post
+func (fc *FirestoreClient) SavePredictionComparison(comparison models.PredictionComparison) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("prediction_comparisons").Doc(comparison.PostID).Set(fc.ctx, comparison)
+	return err
+}
+
+// PredictorComparisonReport summarizes how often the model-based and the heuristic predictors
+// agree on which posts will go viral
+type PredictorComparisonReport struct {
+	Threshold              float64                       `json:"threshold"`
+	Compared               int                           `json:"compared"`
+	Agreements             int                           `json:"agreements"`
+	AgreementRate          float64                       `json:"agreementRate"`
+	MeanAbsoluteDifference float64                       `json:"meanAbsoluteDifference"`
+	BothViral              int                           `json:"bothViral"`
+	ModelOnlyViral         int                           `json:"modelOnlyViral"`
+	HeuristicOnlyViral     int                           `json:"heuristicOnlyViral"`
+	NeitherViral           int                           `json:"neitherViral"`
+	Divergent              []models.PredictionComparison `json:"divergent"` // largest disagreements first
+	Since                  time.Time                     `json:"since"`
+	CalculatedAt           time.Time                     `json:"calculatedAt"`
+}
+
+// GetPredictorComparison reports on the predictor comparisons of the last days, listing up to
+// limit posts where the predictors disagree about reaching threshold
+func (da *DashboardAnalytics) GetPredictorComparison(days, limit int, threshold float64) (*PredictorComparisonReport, error) {
+	logger.Debugf("📊 Comparing viral predictors over last %d days...", days)
+
+	since := time.Now().AddDate(0, 0, -days)
+	iter := da.firestoreClient.client.Collection("prediction_comparisons").
+		Where("ComparedAt", ">=", since).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	var comparisons []models.PredictionComparison
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var comparison models.PredictionComparison
+		if err := doc.DataTo(&comparison); err != nil {
+			continue
+		}
+		comparisons = append(comparisons, comparison)
+	}
+
+	report := summarizeComparisons(comparisons, limit, threshold)
+	report.Since = since
+	report.CalculatedAt = time.Now()
+
+	logger.Infof("✅ Predictor comparison calculated: %d posts, %.0f%% agreement", report.Compared, report.AgreementRate*100)
+	return report, nil
+}
+
+// summarizeComparisons computes agreement statistics and picks the most divergent posts
+func summarizeComparisons(comparisons []models.PredictionComparison, limit int, threshold float64) *PredictorComparisonReport {
+	report := &PredictorComparisonReport{
+		Threshold: threshold,
+		Compared:  len(comparisons),
+		Divergent: []models.PredictionComparison{},
+	}
+
+	totalDifference := 0.0
+	for _, c := range comparisons {
+		totalDifference += math.Abs(c.Difference)
+
+		modelViral := c.ModelProbability >= threshold
+		heuristicViral := c.HeuristicProbability >= threshold
+		switch {
+		case modelViral && heuristicViral:
+			report.BothViral++
+		case modelViral:
+			report.ModelOnlyViral++
+		case heuristicViral:
+			report.HeuristicOnlyViral++
+		default:
+			report.NeitherViral++
+		}
+
+		if c.Agree {
+			report.Agreements++
+		} else {
+			report.Divergent = append(report.Divergent, c)
+		}
+	}
+
+	if report.Compared > 0 {
+		report.AgreementRate = float64(report.Agreements) / float64(report.Compared)
+		report.MeanAbsoluteDifference = totalDifference / float64(report.Compared)
+	}
+
+	sort.Slice(report.Divergent, func(i, j int) bool {
+		return math.Abs(report.Divergent[i].Difference) > math.Abs(report.Divergent[j].Difference)
+	})
+	if len(report.Divergent) > limit {
+		report.Divergent = report.Divergent[:limit]
+	}
+	return report
+}
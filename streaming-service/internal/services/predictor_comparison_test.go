@@ -0,0 +1,83 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestComparePredictions(t *testing.T) {
+	model := &models.ViralPredictionResponse{ViralProbability: 0.8}
+	heuristic := &models.ViralPredictionResponse{ViralProbability: 0.55}
+
+	comparison := comparePredictions("post-1", ViralPredictionModeGemini, model, heuristic, config.DefaultViralProbabilityThreshold)
+	if comparison.Agree {
+		t.Error("expected predictions on opposite sides of the threshold to disagree")
+	}
+	if math.Abs(comparison.Difference-0.25) > 1e-9 {
+		t.Errorf("expected difference 0.25, got %f", comparison.Difference)
+	}
+
+	heuristic.ViralProbability = 0.95
+	if comparison := comparePredictions("post-1", ViralPredictionModeGemini, model, heuristic, config.DefaultViralProbabilityThreshold); !comparison.Agree {
+		t.Error("expected predictions both above the threshold to agree")
+	}
+}
+
+func TestSummarizeComparisons(t *testing.T) {
+	comparisons := []models.PredictionComparison{
+		{PostID: "both", ModelProbability: 0.9, HeuristicProbability: 0.8, Difference: 0.1, Agree: true},
+		{PostID: "neither", ModelProbability: 0.2, HeuristicProbability: 0.3, Difference: -0.1, Agree: true},
+		{PostID: "model", ModelProbability: 0.75, HeuristicProbability: 0.55, Difference: 0.2, Agree: false},
+		{PostID: "heuristic", ModelProbability: 0.1, HeuristicProbability: 0.85, Difference: -0.75, Agree: false},
+	}
+
+	report := summarizeComparisons(comparisons, 1, config.DefaultViralProbabilityThreshold)
+
+	if report.Compared != 4 || report.Agreements != 2 || report.AgreementRate != 0.5 {
+		t.Errorf("unexpected agreement: %+v", report)
+	}
+	if report.BothViral != 1 || report.NeitherViral != 1 || report.ModelOnlyViral != 1 || report.HeuristicOnlyViral != 1 {
+		t.Errorf("unexpected quadrant counts: %+v", report)
+	}
+	if math.Abs(report.MeanAbsoluteDifference-0.2875) > 1e-9 {
+		t.Errorf("expected mean absolute difference 0.2875, got %f", report.MeanAbsoluteDifference)
+	}
+	if len(report.Divergent) != 1 || report.Divergent[0].PostID != "heuristic" {
+		t.Errorf("expected the largest disagreement first, got %+v", report.Divergent)
+	}
+}
+
+func TestPredictorDualRunSchedule(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{
+		ViralPredictionMode:         ViralPredictionModeHeuristic,
+		ViralPredictionDualRunModel: ViralPredictionModeEndpoint,
+		ViralPredictionDualRunUntil: now.Add(time.Hour),
+	}
+	dualRun := newPredictorDualRun(nil, nil, cfg)
+
+	if dualRun.model != ViralPredictionModeEndpoint {
+		t.Errorf("expected the dual-run model to be compared with the served heuristic, got %s", dualRun.model)
+	}
+	if !dualRun.active(now) || dualRun.active(now.Add(2*time.Hour)) {
+		t.Error("expected the dual run to end at the configured time")
+	}
+	if !dualRun.due("post-1", now) {
+		t.Error("expected the first comparison of a post to be due")
+	}
+	if dualRun.due("post-1", now.Add(time.Minute)) {
+		t.Error("expected a recently compared post not to be due")
+	}
+	if !dualRun.due("post-1", now.Add(dualRunCompareInterval)) {
+		t.Error("expected the post to be due again after the interval")
+	}
+
+	var disabled *predictorDualRun
+	if disabled.active(now) {
+		t.Error("expected a nil dual run to be inactive")
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+// Quota resources tracked by the monitor
+const (
+	QuotaVertexAI  = "vertex_ai"
+	QuotaFirestore = "firestore"
+	QuotaAPIKey    = "api_key" // tracked per configured key as "api_key:<name>", others as "api_key:unknown"
+)
+
+// Quotas is the process-wide usage tracker for soft quota warnings
+var Quotas = NewQuotaMonitor()
+
+// QuotaState is the usage of a single resource in the current daily window
+type QuotaState struct {
+	Resource    string    `json:"resource"`
+	Used        int64     `json:"used"`
+	Limit       int64     `json:"limit"`
+	Ratio       float64   `json:"ratio"`
+	Warning     bool      `json:"warning"`
+	WindowStart time.Time `json:"window_start"`
+}
+
+// QuotaMonitor counts daily usage of metered resources and emits a single soft warning per
+// resource and day once usage crosses the warning ratio of its configured quota
+type QuotaMonitor struct {
+	mu           sync.Mutex
+	limits       map[string]int64 // by resource kind
+	usage        map[string]int64 // by resource
+	warned       map[string]bool
+	warningRatio float64
+	windowStart  time.Time
+	alerter      *Alerter
+}
+
+// NewQuotaMonitor creates a monitor with no quotas configured
+func NewQuotaMonitor() *QuotaMonitor {
+	return &QuotaMonitor{
+		limits:       make(map[string]int64),
+		usage:        make(map[string]int64),
+		warned:       make(map[string]bool),
+		warningRatio: 0.8,
+		windowStart:  utcDayStart(time.Now()),
+	}
+}
+
+// Configure sets the quotas, warning ratio and alert channel from configuration
+func (q *QuotaMonitor) Configure(cfg *config.Config, alerter *Alerter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.limits = map[string]int64{
+		QuotaVertexAI:  int64(cfg.QuotaVertexAIDailyCalls),
+		QuotaFirestore: int64(cfg.QuotaFirestoreDailyOps),
+		QuotaAPIKey:    int64(cfg.QuotaAPIKeyDailyRequests),
+	}
+	if cfg.QuotaWarningRatio > 0 && cfg.QuotaWarningRatio < 1 {
+		q.warningRatio = cfg.QuotaWarningRatio
+	}
+	q.alerter = alerter
+}
+
+// Record adds n units of usage for a resource
+func (q *QuotaMonitor) Record(resource string, n int64) {
+	q.mu.Lock()
+
+	q.rollWindow(time.Now())
+	q.usage[resource] += n
+
+	limit := q.limitFor(resource)
+	used := q.usage[resource]
+	shouldWarn := limit > 0 && !q.warned[resource] && float64(used) >= q.warningRatio*float64(limit)
+	if shouldWarn {
+		q.warned[resource] = true
+	}
+	alerter := q.alerter
+	ratio := q.warningRatio
+
+	q.mu.Unlock()
+
+	if shouldWarn {
+		alerter.Notify(Alert{
+			Type:     "quota_warning",
+			Severity: AlertSeverityWarning,
+			Message: fmt.Sprintf("%s usage reached %.0f%% of its daily quota (%d/%d)",
+				resource, float64(used)/float64(limit)*100, used, limit),
+			Details: map[string]interface{}{
+				"resource":      resource,
+				"used":          used,
+				"limit":         limit,
+				"warning_ratio": ratio,
+			},
+		})
+	}
+}
+
+// States returns the current usage of every tracked resource, sorted by resource name
+func (q *QuotaMonitor) States() []QuotaState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollWindow(time.Now())
+
+	states := make([]QuotaState, 0, len(q.usage))
+	for resource, used := range q.usage {
+		state := QuotaState{
+			Resource:    resource,
+			Used:        used,
+			Limit:       q.limitFor(resource),
+			WindowStart: q.windowStart,
+		}
+		if state.Limit > 0 {
+			state.Ratio = float64(used) / float64(state.Limit)
+			state.Warning = state.Ratio >= q.warningRatio
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Resource < states[j].Resource
+	})
+	return states
+}
+
+// limitFor returns the quota of a resource; per-key API usage shares the api_key quota
+func (q *QuotaMonitor) limitFor(resource string) int64 {
+	if strings.HasPrefix(resource, QuotaAPIKey+":") {
+		return q.limits[QuotaAPIKey]
+	}
+	return q.limits[resource]
+}
+
+// rollWindow resets usage when a new UTC day starts; callers must hold the lock
+func (q *QuotaMonitor) rollWindow(now time.Time) {
+	dayStart := utcDayStart(now)
+	if dayStart.After(q.windowStart) {
+		q.usage = make(map[string]int64)
+		q.warned = make(map[string]bool)
+		q.windowStart = dayStart
+	}
+}
+
+func utcDayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+func TestQuotaMonitorWarning(t *testing.T) {
+	qm := NewQuotaMonitor()
+	qm.Configure(&config.Config{
+		QuotaVertexAIDailyCalls:  10,
+		QuotaAPIKeyDailyRequests: 100,
+		QuotaWarningRatio:        0.8,
+	}, nil)
+
+	qm.Record(QuotaVertexAI, 7)
+	qm.Record(QuotaAPIKey+":abc", 5)
+	qm.Record(QuotaFirestore, 1000)
+
+	states := map[string]QuotaState{}
+	for _, s := range qm.States() {
+		states[s.Resource] = s
+	}
+
+	if states[QuotaVertexAI].Warning {
+		t.Error("Expected no warning below the ratio")
+	}
+
+	qm.Record(QuotaVertexAI, 1)
+	for _, s := range qm.States() {
+		states[s.Resource] = s
+	}
+
+	vertex := states[QuotaVertexAI]
+	if !vertex.Warning || vertex.Ratio != 0.8 {
+		t.Errorf("vertex_ai state = %+v, want warning at ratio 0.8", vertex)
+	}
+	if apiKey := states[QuotaAPIKey+":abc"]; apiKey.Limit != 100 || apiKey.Warning {
+		t.Errorf("api key state = %+v, want limit 100 without warning", apiKey)
+	}
+	if firestore := states[QuotaFirestore]; firestore.Limit != 0 || firestore.Warning {
+		t.Errorf("firestore state = %+v, want unlimited without warning", firestore)
+	}
+	if !qm.warned[QuotaVertexAI] {
+		t.Error("Expected the warning to be recorded as sent")
+	}
+}
+
+func TestQuotaMonitorRollsDailyWindow(t *testing.T) {
+	qm := NewQuotaMonitor()
+	qm.Record(QuotaFirestore, 5)
+
+	qm.windowStart = qm.windowStart.Add(-24 * time.Hour)
+	if states := qm.States(); len(states) != 0 {
+		t.Errorf("Expected usage reset for new day, got %+v", states)
+	}
+}
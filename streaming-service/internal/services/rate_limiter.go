@@ -0,0 +1,201 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Shed policies applied when a key's request queue is full
+const (
+	ShedRejectNew  = "reject_new"  // the incoming request fails
+	ShedDropOldest = "drop_oldest" // the longest waiting request fails and the incoming one queues
+)
+
+// ErrRateLimited is returned for requests shed by the rate limiter instead of being sent
+var ErrRateLimited = errors.New("request shed by rate limiter")
+
+// RateLimitPolicy configures a KeyedRateLimiter
+type RateLimitPolicy struct {
+	QPS          float64 // requests per second per key; 0 disables limiting
+	Burst        int
+	QueueLength  int           // requests allowed to wait per key
+	QueueTimeout time.Duration // longest a request waits for its turn
+	Shed         string        // ShedRejectNew or ShedDropOldest
+
+	// Per-key overrides of QPS
+	KeyQPS map[string]float64
+}
+
+// RateLimitStats reports the traffic of one rate limited key
+type RateLimitStats struct {
+	Key        string  `json:"key"`
+	QPS        float64 `json:"qps"`
+	Queued     int     `json:"queued"` // requests waiting right now
+	PeakQueued int     `json:"peak_queued"`
+	Admitted   int64   `json:"admitted"`
+	Delayed    int64   `json:"delayed"` // admitted after waiting in the queue
+	Dropped    int64   `json:"dropped"` // shed because the queue was full or the wait timed out
+	AvgWaitMs  float64 `json:"avg_wait_ms"`
+}
+
+// keyLimiter is the token bucket and wait queue of one key
+type keyLimiter struct {
+	limiter   *rate.Limiter
+	waiting   *list.List // of *rateWaiter, oldest first
+	stats     RateLimitStats
+	totalWait time.Duration
+}
+
+type rateWaiter struct {
+	cancel  context.CancelFunc
+	dropped bool
+}
+
+// KeyedRateLimiter paces requests per key (e.g. per Gemini use case) with a token bucket.
+// Requests over the rate wait in a bounded queue; when it is full the shed policy decides
+// which request fails, so bursts degrade into fast fallbacks instead of quota errors.
+type KeyedRateLimiter struct {
+	policy RateLimitPolicy
+
+	mu   sync.Mutex
+	keys map[string]*keyLimiter
+}
+
+func NewKeyedRateLimiter(policy RateLimitPolicy) *KeyedRateLimiter {
+	if policy.Burst < 1 {
+		policy.Burst = 1
+	}
+	if policy.Shed != ShedDropOldest {
+		policy.Shed = ShedRejectNew
+	}
+
+	return &KeyedRateLimiter{
+		policy: policy,
+		keys:   make(map[string]*keyLimiter),
+	}
+}
+
+// Wait blocks until a request for key may be sent. It returns an error wrapping
+// ErrRateLimited when the request is shed.
+func (l *KeyedRateLimiter) Wait(ctx context.Context, key string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	k := l.keyLocked(key)
+	if k == nil {
+		l.mu.Unlock()
+		return nil
+	}
+
+	// Nobody queued ahead and a token is free: go straight through
+	if k.waiting.Len() == 0 && k.limiter.Allow() {
+		k.stats.Admitted++
+		l.mu.Unlock()
+		return nil
+	}
+
+	if k.waiting.Len() >= l.policy.QueueLength {
+		if l.policy.Shed != ShedDropOldest || k.waiting.Len() == 0 {
+			k.stats.Dropped++
+			l.mu.Unlock()
+			return fmt.Errorf("%w: %s queue full", ErrRateLimited, key)
+		}
+		oldest := k.waiting.Remove(k.waiting.Front()).(*rateWaiter)
+		oldest.dropped = true
+		oldest.cancel()
+		k.stats.Dropped++
+	}
+
+	waitCtx, cancel := ctx, context.CancelFunc(func() {})
+	if l.policy.QueueTimeout > 0 {
+		waitCtx, cancel = context.WithTimeout(ctx, l.policy.QueueTimeout)
+	}
+	waitCtx, cancelWait := context.WithCancel(waitCtx)
+	waiter := &rateWaiter{cancel: cancelWait}
+	elem := k.waiting.PushBack(waiter)
+	k.stats.Queued = k.waiting.Len()
+	k.stats.PeakQueued = max(k.stats.PeakQueued, k.stats.Queued)
+	l.mu.Unlock()
+
+	start := time.Now()
+	err := k.limiter.Wait(waitCtx)
+	cancelWait()
+	cancel()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !waiter.dropped {
+		k.waiting.Remove(elem)
+	}
+	k.stats.Queued = k.waiting.Len()
+
+	switch {
+	case waiter.dropped:
+		return fmt.Errorf("%w: %s dropped for newer request", ErrRateLimited, key)
+	case err != nil:
+		k.stats.Dropped++
+		return fmt.Errorf("%w: %s: %v", ErrRateLimited, key, err)
+	}
+
+	k.stats.Admitted++
+	k.stats.Delayed++
+	k.totalWait += time.Since(start)
+	return nil
+}
+
+// keyLocked returns the limiter of key, creating it on first use; nil means the key is
+// not limited
+func (l *KeyedRateLimiter) keyLocked(key string) *keyLimiter {
+	if k, ok := l.keys[key]; ok {
+		return k
+	}
+
+	qps := l.policy.QPS
+	if override, ok := l.policy.KeyQPS[key]; ok {
+		qps = override
+	}
+	if qps <= 0 {
+		return nil
+	}
+
+	k := &keyLimiter{
+		limiter: rate.NewLimiter(rate.Limit(qps), l.policy.Burst),
+		waiting: list.New(),
+		stats:   RateLimitStats{Key: key, QPS: qps},
+	}
+	l.keys[key] = k
+	return k
+}
+
+// Stats returns the traffic of every key seen so far, sorted by key
+func (l *KeyedRateLimiter) Stats() []RateLimitStats {
+	if l == nil {
+		return []RateLimitStats{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]RateLimitStats, 0, len(l.keys))
+	for _, k := range l.keys {
+		s := k.stats
+		if s.Delayed > 0 {
+			s.AvgWaitMs = float64(k.totalWait.Milliseconds()) / float64(s.Delayed)
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Key < stats[j].Key
+	})
+	return stats
+}
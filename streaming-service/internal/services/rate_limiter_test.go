@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyedRateLimiterRejectNew(t *testing.T) {
+	l := NewKeyedRateLimiter(RateLimitPolicy{QPS: 1, Burst: 1, QueueLength: 0, Shed: ShedRejectNew})
+	ctx := context.Background()
+
+	if err := l.Wait(ctx, "keywords"); err != nil {
+		t.Fatalf("expected first request admitted, got %v", err)
+	}
+	if err := l.Wait(ctx, "keywords"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected second request shed, got %v", err)
+	}
+
+	// Keys have their own buckets
+	if err := l.Wait(ctx, "sentiment"); err != nil {
+		t.Fatalf("expected other key admitted, got %v", err)
+	}
+
+	stats := l.Stats()
+	if len(stats) != 2 || stats[0].Key != "keywords" || stats[0].Admitted != 1 || stats[0].Dropped != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestKeyedRateLimiterQueues(t *testing.T) {
+	l := NewKeyedRateLimiter(RateLimitPolicy{QPS: 50, Burst: 1, QueueLength: 5, QueueTimeout: time.Second})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx, "vision"); err != nil {
+			t.Fatalf("request %d: expected queued request admitted, got %v", i, err)
+		}
+	}
+
+	stats := l.Stats()[0]
+	if stats.Admitted != 3 || stats.Delayed != 2 || stats.Queued != 0 || stats.PeakQueued != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestKeyedRateLimiterDropOldest(t *testing.T) {
+	l := NewKeyedRateLimiter(RateLimitPolicy{QPS: 5, Burst: 1, QueueLength: 1, QueueTimeout: time.Minute, Shed: ShedDropOldest})
+	ctx := context.Background()
+	l.Wait(ctx, "moderation")
+
+	oldest := make(chan error, 1)
+	go func() { oldest <- l.Wait(ctx, "moderation") }()
+	for l.Stats()[0].Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	newest := make(chan error, 1)
+	go func() { newest <- l.Wait(ctx, "moderation") }()
+
+	if err := <-oldest; !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected oldest request dropped, got %v", err)
+	}
+	if err := <-newest; err != nil {
+		t.Fatalf("expected newest request admitted, got %v", err)
+	}
+	if stats := l.Stats()[0]; stats.Dropped != 1 || stats.Admitted != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestKeyedRateLimiterDisabled(t *testing.T) {
+	l := NewKeyedRateLimiter(RateLimitPolicy{QPS: 1, KeyQPS: map[string]float64{"prediction": 0}})
+	for i := 0; i < 5; i++ {
+		if err := l.Wait(context.Background(), "prediction"); err != nil {
+			t.Fatalf("expected unlimited key, got %v", err)
+		}
+	}
+
+	var nilLimiter *KeyedRateLimiter
+	if err := nilLimiter.Wait(context.Background(), "keywords"); err != nil {
+		t.Errorf("expected nil limiter to admit everything, got %v", err)
+	}
+}
@@ -0,0 +1,463 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Source of the recommendations written by the collaborative filtering engine
+const RecommendationSourceCollaborative = "collaborative"
+
+// Weight of each kind of engagement a user gives a post in the interaction matrix
+var recommendationWeights = map[string]float64{
+	"view":    1,
+	"like":    3,
+	"comment": 4,
+	"share":   5,
+	"remix":   5,
+}
+
+// Bounds of a recommendation build
+const (
+	maxRecommendationUsers        = 20000 // most recently active users read per build
+	maxUserEngagedPosts           = 50    // a user's most engaged posts in the matrix
+	maxSimilarPosts               = 50    // most similar posts kept per post
+	minCoEngagedUsers             = 2     // users two posts need in common to count as similar
+	maxPendingRecommendationUsers = 50000 // users buffered between flushes before engagement is dropped
+)
+
+// similarPost is a post co-engaged with another and the cosine similarity of the two
+type similarPost struct {
+	postID     string
+	similarity float64
+}
+
+// RecommendationEngine recommends posts from the engagement of users with similar taste, or
+// from the keywords and categories of what users liked, so recommendations work without the
+// Flink job. Consumed views, interactions and remixes are buffered per user and added to
+// recommendation_interactions/{user} with increments on every flush, so every instance
+// contributes its share of the events. On a schedule the elected instance reads the engagement
+// of the recently active users and, depending on the mode, computes the item-item cosine
+// similarity of their co-engagement, matches their affinity profiles against the top trending
+// posts, or both. Each recommender writes the user's top posts to recommendations/{user}/items,
+// replacing its previous ones. Posts the user engaged with, recently viewed or created are
+// never recommended.
+type RecommendationEngine struct {
+	firestoreClient *FirestoreClient
+	ctx             context.Context
+	cancel          context.CancelFunc
+	flushInterval   time.Duration
+	buildInterval   time.Duration
+	activeWindow    time.Duration
+	topN            int
+	mode            string
+	leader          *LeaderElector
+
+	mu      sync.Mutex
+	pending map[string]map[string]float64 // user ID -> post ID -> engagement since the last flush
+}
+
+func NewRecommendationEngine(firestoreClient *FirestoreClient, flushInterval, buildInterval, activeWindow time.Duration, topN int, mode string) (*RecommendationEngine, error) {
+	if err := ValidateRecommendationMode(mode); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RecommendationEngine{
+		firestoreClient: firestoreClient,
+		ctx:             ctx,
+		cancel:          cancel,
+		flushInterval:   flushInterval,
+		buildInterval:   buildInterval,
+		activeWindow:    activeWindow,
+		topN:            topN,
+		mode:            mode,
+		pending:         make(map[string]map[string]float64),
+	}, nil
+}
+
+// UseRecommendations feeds consumed views, interactions and remixes to the recommendation engine
+func (ep *EventProcessor) UseRecommendations(recommendations *RecommendationEngine) {
+	ep.recommendations = recommendations
+}
+
+// UseLeaderElector builds the recommendations on the elected instance only; engagement is
+// flushed by every instance
+func (re *RecommendationEngine) UseLeaderElector(leader *LeaderElector) {
+	re.leader = leader
+}
+
+// Start begins the periodic flush and build loops
+func (re *RecommendationEngine) Start() {
+	logger.Infof("🎯 Starting %s recommendation engine (flush every %v, build every %v, top %d)", re.mode, re.flushInterval, re.buildInterval, re.topN)
+
+	flush := time.NewTicker(re.flushInterval)
+	build := time.NewTicker(re.buildInterval)
+	go func() {
+		for {
+			select {
+			case <-re.ctx.Done():
+				flush.Stop()
+				build.Stop()
+				logger.Info("🛑 Recommendation engine stopped")
+				return
+			case <-flush.C:
+				if err := re.Flush(); err != nil {
+					logger.Errorf("❌ Recommendation engagement flush failed: %v", err)
+				}
+			case <-build.C:
+				if !re.leader.IsLeader() {
+					continue
+				}
+				if err := re.Build(); err != nil {
+					logger.Errorf("❌ Recommendation build failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the loops and writes out the engagement still buffered
+func (re *RecommendationEngine) Stop() {
+	re.cancel()
+	if err := re.Flush(); err != nil {
+		logger.Errorf("❌ Final recommendation engagement flush failed: %v", err)
+	}
+}
+
+// RecordEngagement adds a user's view, interaction or remix of a post to the matrix
+func (re *RecommendationEngine) RecordEngagement(userID, postID, eventType string) {
+	if re == nil || userID == "" || postID == "" {
+		return
+	}
+	weight, ok := recommendationWeights[eventType]
+	if !ok {
+		return
+	}
+
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	posts, ok := re.pending[userID]
+	if !ok {
+		if len(re.pending) >= maxPendingRecommendationUsers {
+			return
+		}
+		posts = make(map[string]float64)
+		re.pending[userID] = posts
+	}
+	posts[postID] += weight
+}
+
+// Flush adds the engagement recorded since the last flush to the stored engagement of users
+func (re *RecommendationEngine) Flush() error {
+	re.mu.Lock()
+	pending := re.pending
+	re.pending = make(map[string]map[string]float64)
+	re.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	failed, err := re.firestoreClient.IncrementRecommendationInteractions(pending)
+	if len(failed) > 0 {
+		// Keep the engagement for the next flush
+		re.mu.Lock()
+		for _, userID := range failed {
+			for postID, weight := range pending[userID] {
+				if re.pending[userID] == nil {
+					re.pending[userID] = make(map[string]float64)
+				}
+				re.pending[userID][postID] += weight
+			}
+		}
+		re.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("🎯 Flushed the engagement of %d users", len(pending))
+	return nil
+}
+
+// Build writes the recommendations of the active users from the recommenders of the mode
+func (re *RecommendationEngine) Build() error {
+	startTime := time.Now()
+	matrix, err := re.firestoreClient.RecommendationInteractions(startTime.Add(-re.activeWindow), maxRecommendationUsers)
+	if err != nil {
+		return err
+	}
+
+	if re.mode != RecommendationModeContent {
+		similar := itemSimilarities(matrix)
+		recommendations := make(map[string][]models.Recommendation, len(matrix))
+		for userID, engaged := range matrix {
+			recommendations[userID] = recommendFromSimilar(userID, engaged, similar, re.topN*recommendationHeadroom, startTime)
+		}
+		if _, err := re.firestoreClient.excludeSeenAndOwn(recommendations, re.topN); err != nil {
+			return err
+		}
+		written, err := re.firestoreClient.ReplaceRecommendations(RecommendationSourceCollaborative, recommendations)
+		if err != nil {
+			return err
+		}
+		logger.Infof("🎯 Built collaborative recommendations for %d of %d active users from %d posts", written, len(matrix), len(similar))
+	}
+
+	if re.mode != RecommendationModeCollaborative {
+		recommendations, err := re.contentRecommendations(matrix, startTime)
+		if err != nil {
+			return err
+		}
+		if _, err := re.firestoreClient.excludeSeenAndOwn(recommendations, re.topN); err != nil {
+			return err
+		}
+		written, err := re.firestoreClient.ReplaceRecommendations(RecommendationSourceContent, recommendations)
+		if err != nil {
+			return err
+		}
+		logger.Infof("🎯 Built content recommendations for %d of %d active users", written, len(matrix))
+	}
+
+	logger.Infof("🎯 Recommendation build took %v", time.Since(startTime))
+	return nil
+}
+
+// itemSimilarities returns the posts most similar to each post by the cosine similarity of
+// their engagement vectors over users, most similar first. Posts need minCoEngagedUsers users
+// in common, so a single user's taste does not make posts similar.
+func itemSimilarities(matrix map[string]map[string]float64) map[string][]similarPost {
+	type pair struct{ a, b string }
+	norms := make(map[string]float64)
+	dots := make(map[pair]float64)
+	common := make(map[pair]int)
+	for _, engaged := range matrix {
+		postIDs := make([]string, 0, len(engaged))
+		for postID, weight := range engaged {
+			postIDs = append(postIDs, postID)
+			norms[postID] += weight * weight
+		}
+		sort.Strings(postIDs)
+		for i, a := range postIDs {
+			for _, b := range postIDs[i+1:] {
+				dots[pair{a, b}] += engaged[a] * engaged[b]
+				common[pair{a, b}]++
+			}
+		}
+	}
+
+	similar := make(map[string][]similarPost)
+	for p, dot := range dots {
+		if common[p] < minCoEngagedUsers {
+			continue
+		}
+		similarity := dot / math.Sqrt(norms[p.a]*norms[p.b])
+		similar[p.a] = append(similar[p.a], similarPost{postID: p.b, similarity: similarity})
+		similar[p.b] = append(similar[p.b], similarPost{postID: p.a, similarity: similarity})
+	}
+	for postID, posts := range similar {
+		sort.Slice(posts, func(i, j int) bool {
+			if posts[i].similarity != posts[j].similarity {
+				return posts[i].similarity > posts[j].similarity
+			}
+			return posts[i].postID < posts[j].postID
+		})
+		if len(posts) > maxSimilarPosts {
+			similar[postID] = posts[:maxSimilarPosts]
+		}
+	}
+	return similar
+}
+
+// recommendFromSimilar scores the posts similar to those a user engaged with by the user's
+// engagement times the similarity, summed over the engaged posts, and returns the topN posts
+// the user has not engaged with yet. The reason names the engaged post contributing most.
+func recommendFromSimilar(userID string, engaged map[string]float64, similar map[string][]similarPost, topN int, now time.Time) []models.Recommendation {
+	scores := make(map[string]float64)
+	because := make(map[string]string)
+	strongest := make(map[string]float64)
+	for postID, weight := range engaged {
+		for _, candidate := range similar[postID] {
+			if _, seen := engaged[candidate.postID]; seen {
+				continue
+			}
+			contribution := weight * candidate.similarity
+			scores[candidate.postID] += contribution
+			if contribution > strongest[candidate.postID] || (contribution == strongest[candidate.postID] && postID < because[candidate.postID]) {
+				strongest[candidate.postID] = contribution
+				because[candidate.postID] = postID
+			}
+		}
+	}
+
+	recs := make([]models.Recommendation, 0, len(scores))
+	for postID, score := range scores {
+		recs = append(recs, models.Recommendation{
+			UserID:      userID,
+			PostID:      postID,
+			Score:       score,
+			Reason:      fmt.Sprintf("People who engaged with post %s also engaged with this", because[postID]),
+			GeneratedAt: now,
+			Source:      RecommendationSourceCollaborative,
+		})
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].PostID < recs[j].PostID
+	})
+	if len(recs) > topN {
+		recs = recs[:topN]
+	}
+	return recs
+}
+
+// strongestEngagement keeps the limit posts a user engaged with most, damping the raw weights
+// logarithmically so repeated views do not drown out likes and shares
+func strongestEngagement(posts map[string]float64, limit int) map[string]float64 {
+	postIDs := make([]string, 0, len(posts))
+	for postID, weight := range posts {
+		if weight > 0 {
+			postIDs = append(postIDs, postID)
+		}
+	}
+	sort.Slice(postIDs, func(i, j int) bool {
+		if posts[postIDs[i]] != posts[postIDs[j]] {
+			return posts[postIDs[i]] > posts[postIDs[j]]
+		}
+		return postIDs[i] < postIDs[j]
+	})
+	if len(postIDs) > limit {
+		postIDs = postIDs[:limit]
+	}
+
+	engaged := make(map[string]float64, len(postIDs))
+	for _, postID := range postIDs {
+		engaged[postID] = math.Log1p(posts[postID])
+	}
+	return engaged
+}
+
+// recommendationInteractions is the stored engagement of a user with posts
+type recommendationInteractions struct {
+	Posts     map[string]float64
+	UpdatedAt time.Time
+}
+
+// IncrementRecommendationInteractions adds engagement to the stored engagement of users,
+// returning the users whose engagement could not be written
+func (fc *FirestoreClient) IncrementRecommendationInteractions(pending map[string]map[string]float64) ([]string, error) {
+	bw := fc.client.BulkWriter(fc.ctx)
+	userIDs := make([]string, 0, len(pending))
+	jobs := make([]*firestore.BulkWriterJob, 0, len(pending))
+	var failed []string
+	var lastErr error
+	now := time.Now()
+	for userID, posts := range pending {
+		increments := make(map[string]interface{}, len(posts))
+		for postID, weight := range posts {
+			increments[postID] = firestore.Increment(weight)
+		}
+
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(fc.client.Collection("recommendation_interactions").Doc(userID), map[string]interface{}{
+			"Posts":     increments,
+			"UpdatedAt": now,
+		}, firestore.MergeAll)
+		if err != nil {
+			failed, lastErr = append(failed, userID), err
+			continue
+		}
+		userIDs = append(userIDs, userID)
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			failed, lastErr = append(failed, userIDs[i]), err
+		}
+	}
+	return failed, lastErr
+}
+
+// RecommendationInteractions returns the strongest engagement of at most limit users active
+// since the given time, by user and post
+func (fc *FirestoreClient) RecommendationInteractions(since time.Time, limit int) (map[string]map[string]float64, error) {
+	docs, err := fc.client.Collection("recommendation_interactions").
+		Where("UpdatedAt", ">=", since).
+		OrderBy("UpdatedAt", firestore.Desc).
+		Limit(limit).
+		Documents(fc.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := make(map[string]map[string]float64, len(docs))
+	for _, doc := range docs {
+		var interactions recommendationInteractions
+		if err := doc.DataTo(&interactions); err != nil {
+			logger.Warnf("Skipping unreadable engagement of user %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		if engaged := strongestEngagement(interactions.Posts, maxUserEngagedPosts); len(engaged) > 0 {
+			matrix[doc.Ref.ID] = engaged
+		}
+	}
+	return matrix, nil
+}
+
+// ReplaceRecommendations writes the recommendations of users from one recommender, deleting
+// the recommender's earlier recommendations they no longer include, and returns the number of
+// users written
+func (fc *FirestoreClient) ReplaceRecommendations(source string, recommendations map[string][]models.Recommendation) (int, error) {
+	bw := fc.client.BulkWriter(fc.ctx)
+	var jobs []*firestore.BulkWriterJob
+	written := 0
+	var lastErr error
+	for userID, recs := range recommendations {
+		items := fc.client.Collection("recommendations").Doc(userID).Collection("items")
+		previous, err := items.Where("Source", "==", source).Select().Documents(fc.ctx).GetAll()
+		Quotas.Record(QuotaFirestore, int64(len(previous)+1))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		keep := make(map[string]bool, len(recs))
+		for _, rec := range recs {
+			keep[rec.PostID] = true
+			Quotas.Record(QuotaFirestore, 1)
+			if job, err := bw.Set(items.Doc(rec.PostID), fc.withExpiry(rec)); err == nil {
+				jobs = append(jobs, job)
+			}
+		}
+		for _, doc := range previous {
+			if keep[doc.Ref.ID] {
+				continue
+			}
+			Quotas.Record(QuotaFirestore, 1)
+			if job, err := bw.Delete(doc.Ref); err == nil {
+				jobs = append(jobs, job)
+			}
+		}
+		written++
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			lastErr = err
+		}
+	}
+	return written, lastErr
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRecommendationEngineRecordEngagement(t *testing.T) {
+	engine, err := NewRecommendationEngine(nil, time.Minute, time.Hour, 24*time.Hour, 10, RecommendationModeHybrid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.RecordEngagement("user-1", "post-1", "view")
+	engine.RecordEngagement("user-1", "post-1", "like")
+	engine.RecordEngagement("user-1", "post-2", "remix")
+	engine.RecordEngagement("user-1", "post-3", "unknown")
+	engine.RecordEngagement("", "post-1", "like")
+
+	posts := engine.pending["user-1"]
+	if len(engine.pending) != 1 || len(posts) != 2 {
+		t.Fatalf("Expected 2 posts of 1 user, got %+v", engine.pending)
+	}
+	if posts["post-1"] != 4 || posts["post-2"] != 5 {
+		t.Errorf("Expected weights 4 and 5, got %+v", posts)
+	}
+
+	var nilEngine *RecommendationEngine
+	nilEngine.RecordEngagement("user-1", "post-1", "like")
+}
+
+func TestItemSimilarities(t *testing.T) {
+	matrix := map[string]map[string]float64{
+		"user-1": {"a": 1, "b": 1, "c": 1},
+		"user-2": {"a": 1, "b": 1},
+		"user-3": {"a": 1, "d": 1},
+	}
+	similar := itemSimilarities(matrix)
+
+	// a and b share two users; c and d share one user with a, too few to count
+	if len(similar["a"]) != 1 || similar["a"][0].postID != "b" {
+		t.Fatalf("Expected a to be similar to b only, got %+v", similar["a"])
+	}
+	expected := 2 / math.Sqrt(3*2)
+	if got := similar["a"][0].similarity; math.Abs(got-expected) > 0.0001 {
+		t.Errorf("Expected a similarity of %.4f, got %.4f", expected, got)
+	}
+	if len(similar["c"]) != 0 || len(similar["d"]) != 0 {
+		t.Errorf("Expected c and d to have no similar posts, got %+v and %+v", similar["c"], similar["d"])
+	}
+}
+
+func TestRecommendFromSimilar(t *testing.T) {
+	similar := map[string][]similarPost{
+		"a": {{postID: "b", similarity: 0.9}, {postID: "c", similarity: 0.5}},
+		"b": {{postID: "a", similarity: 0.9}, {postID: "c", similarity: 0.8}, {postID: "d", similarity: 0.1}},
+	}
+	engaged := map[string]float64{"a": 2, "x": 1}
+
+	recs := recommendFromSimilar("user-1", engaged, similar, 10, time.Now())
+	if len(recs) != 2 || recs[0].PostID != "b" || recs[1].PostID != "c" {
+		t.Fatalf("Expected b then c, got %+v", recs)
+	}
+	if recs[0].Score != 1.8 || recs[0].UserID != "user-1" || recs[0].Source != RecommendationSourceCollaborative {
+		t.Errorf("Unexpected recommendation %+v", recs[0])
+	}
+	if recs[0].Reason != "People who engaged with post a also engaged with this" {
+		t.Errorf("Unexpected reason %q", recs[0].Reason)
+	}
+
+	// Engaged posts are never recommended, and the list is cut to topN
+	engaged = map[string]float64{"a": 1, "b": 1}
+	recs = recommendFromSimilar("user-1", engaged, similar, 1, time.Now())
+	if len(recs) != 1 || recs[0].PostID != "c" || math.Abs(recs[0].Score-1.3) > 0.0001 {
+		t.Errorf("Expected c only with score 1.3, got %+v", recs)
+	}
+	if recs[0].Reason != "People who engaged with post b also engaged with this" {
+		t.Errorf("Expected the strongest contributor in the reason, got %q", recs[0].Reason)
+	}
+}
+
+func TestStrongestEngagement(t *testing.T) {
+	engaged := strongestEngagement(map[string]float64{"a": 10, "b": 3, "c": 1, "d": 0}, 2)
+	if len(engaged) != 2 {
+		t.Fatalf("Expected the 2 strongest posts, got %+v", engaged)
+	}
+	if engaged["a"] != math.Log1p(10) || engaged["b"] != math.Log1p(3) {
+		t.Errorf("Expected log-damped weights, got %+v", engaged)
+	}
+}
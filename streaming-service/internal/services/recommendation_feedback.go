@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Feedback a user gives on a recommended post
+const (
+	RecommendationFeedbackImpression  = "impression"
+	RecommendationFeedbackClick       = "click"
+	RecommendationFeedbackDismiss     = "dismiss"
+	RecommendationFeedbackHideCreator = "hide-creator"
+)
+
+// Demotion of recommendations by feedback
+const (
+	feedbackCreatorDemotion = 0.5 // score factor per post of the creator the user dismissed
+	feedbackIgnoredDemotion = 0.8 // score factor per impression of a post never clicked, beyond the free ones
+	feedbackFreeImpressions = 2   // impressions of a post before it counts as ignored
+)
+
+// postFeedback is the stored feedback of a user on one recommended post
+type postFeedback struct {
+	Impressions int64
+	Clicks      int64
+	DismissedAt time.Time
+	UpdatedAt   time.Time
+}
+
+// creatorFeedback is the stored feedback of a user on the posts of one creator
+type creatorFeedback struct {
+	Dismissals int64
+	HiddenAt   time.Time
+	UpdatedAt  time.Time
+}
+
+// recommendationFeedback is the stored feedback of a user on their recommendations
+type recommendationFeedback struct {
+	Posts     map[string]postFeedback
+	Creators  map[string]creatorFeedback
+	UpdatedAt time.Time
+}
+
+// feedbackSignals is the feedback of a user that still re-ranks their recommendations
+type feedbackSignals struct {
+	dismissed         map[string]bool // post IDs
+	ignored           map[string]int  // post ID -> impressions without a click beyond the free ones
+	hiddenCreators    map[string]bool
+	creatorDismissals map[string]int // creator ID -> posts of theirs dismissed
+}
+
+// ApplyRecommendationFeedback stores a user's feedback on a recommended post in
+// recommendation_feedback/{user}. Dismissals and hidden creators are kept against the creator
+// of the post, looked up when the event does not name it.
+func (fc *FirestoreClient) ApplyRecommendationFeedback(event models.RecommendationFeedbackEvent) error {
+	if fc.feedbackTTL <= 0 {
+		return nil
+	}
+	at := event.OccurredAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	post := map[string]interface{}{"UpdatedAt": at}
+	var creator map[string]interface{}
+	switch event.Action {
+	case RecommendationFeedbackImpression:
+		post["Impressions"] = firestore.Increment(1)
+	case RecommendationFeedbackClick:
+		post["Clicks"] = firestore.Increment(1)
+	case RecommendationFeedbackDismiss:
+		post["DismissedAt"] = at
+		creator = map[string]interface{}{"Dismissals": firestore.Increment(1), "UpdatedAt": at}
+	case RecommendationFeedbackHideCreator:
+		post = nil
+		creator = map[string]interface{}{"HiddenAt": at, "UpdatedAt": at}
+	default:
+		return fmt.Errorf("unknown recommendation feedback %q", event.Action)
+	}
+
+	data := map[string]interface{}{"UpdatedAt": time.Now()}
+	if post != nil {
+		data["Posts"] = map[string]interface{}{event.PostID: post}
+	}
+	if creator != nil {
+		creatorID := event.CreatorID
+		if creatorID == "" {
+			owners, err := fc.PostOwners([]string{event.PostID})
+			if err != nil {
+				return err
+			}
+			creatorID = owners[event.PostID]
+		}
+		switch {
+		case creatorID != "":
+			data["Creators"] = map[string]interface{}{creatorID: creator}
+		case post == nil:
+			return fmt.Errorf("creator of post %s not found", event.PostID)
+		}
+	}
+
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("recommendation_feedback").Doc(event.UserID).Set(fc.ctx, data, firestore.MergeAll)
+	return err
+}
+
+// RecommendationFeedback returns the feedback of a user given within the feedback TTL, pruning
+// older feedback from the stored document. There is none when feedback is ignored.
+func (fc *FirestoreClient) RecommendationFeedback(userID string) (feedbackSignals, error) {
+	var stored recommendationFeedback
+	if fc.feedbackTTL <= 0 {
+		return feedbackSignalsOf(stored, time.Now()), nil
+	}
+
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := fc.client.Collection("recommendation_feedback").Doc(userID).Get(fc.ctx)
+	if status.Code(err) == codes.NotFound {
+		return feedbackSignalsOf(stored, time.Now()), nil
+	}
+	if err != nil {
+		return feedbackSignals{}, err
+	}
+	if err := doc.DataTo(&stored); err != nil {
+		logger.Warnf("Skipping unreadable recommendation feedback of user %s: %v", userID, err)
+		return feedbackSignalsOf(recommendationFeedback{}, time.Now()), nil
+	}
+
+	cutoff := time.Now().Add(-fc.feedbackTTL)
+	var expired []firestore.Update
+	for postID, post := range stored.Posts {
+		if post.UpdatedAt.Before(cutoff) {
+			expired = append(expired, firestore.Update{FieldPath: firestore.FieldPath{"Posts", postID}, Value: firestore.Delete})
+		}
+	}
+	for creatorID, creator := range stored.Creators {
+		if creator.UpdatedAt.Before(cutoff) {
+			expired = append(expired, firestore.Update{FieldPath: firestore.FieldPath{"Creators", creatorID}, Value: firestore.Delete})
+		}
+	}
+	if len(expired) > 0 {
+		Quotas.Record(QuotaFirestore, 1)
+		if _, err := fc.client.Collection("recommendation_feedback").Doc(userID).Update(fc.ctx, expired); err != nil {
+			logger.Warnf("Failed to prune the recommendation feedback of user %s: %v", userID, err)
+		}
+	}
+	return feedbackSignalsOf(stored, cutoff), nil
+}
+
+// feedbackSignalsOf reads the feedback updated since the cutoff
+func feedbackSignalsOf(stored recommendationFeedback, cutoff time.Time) feedbackSignals {
+	signals := feedbackSignals{
+		dismissed:         make(map[string]bool),
+		ignored:           make(map[string]int),
+		hiddenCreators:    make(map[string]bool),
+		creatorDismissals: make(map[string]int),
+	}
+	for postID, post := range stored.Posts {
+		if post.UpdatedAt.Before(cutoff) {
+			continue
+		}
+		if !post.DismissedAt.IsZero() {
+			signals.dismissed[postID] = true
+		}
+		if ignored := post.Impressions - feedbackFreeImpressions; post.Clicks == 0 && ignored > 0 {
+			signals.ignored[postID] = int(ignored)
+		}
+	}
+	for creatorID, creator := range stored.Creators {
+		if creator.UpdatedAt.Before(cutoff) {
+			continue
+		}
+		if !creator.HiddenAt.IsZero() {
+			signals.hiddenCreators[creatorID] = true
+		}
+		if creator.Dismissals > 0 {
+			signals.creatorDismissals[creatorID] = int(creator.Dismissals)
+		}
+	}
+	return signals
+}
+
+// rerankWithFeedback drops the dismissed posts and the posts of hidden creators, demotes the
+// posts of creators the user dismissed posts of and the posts shown repeatedly without a click,
+// and orders the rest by their demoted score
+func rerankWithFeedback(recs []models.Recommendation, feedback feedbackSignals, owners map[string]string) []models.Recommendation {
+	kept := make([]models.Recommendation, 0, len(recs))
+	for _, rec := range recs {
+		creatorID := owners[rec.PostID]
+		if feedback.dismissed[rec.PostID] || (creatorID != "" && feedback.hiddenCreators[creatorID]) {
+			continue
+		}
+		if dismissals := feedback.creatorDismissals[creatorID]; creatorID != "" && dismissals > 0 {
+			rec.Score *= math.Pow(feedbackCreatorDemotion, float64(dismissals))
+		}
+		if ignored := feedback.ignored[rec.PostID]; ignored > 0 {
+			rec.Score *= math.Pow(feedbackIgnoredDemotion, float64(ignored))
+		}
+		kept = append(kept, rec)
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Score > kept[j].Score
+	})
+	return kept
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestFeedbackSignalsOf(t *testing.T) {
+	now := time.Now()
+	cutoff := now.Add(-24 * time.Hour)
+	stored := recommendationFeedback{
+		Posts: map[string]postFeedback{
+			"dismissed": {Impressions: 1, DismissedAt: now, UpdatedAt: now},
+			"ignored":   {Impressions: 5, UpdatedAt: now},
+			"clicked":   {Impressions: 5, Clicks: 1, UpdatedAt: now},
+			"expired":   {DismissedAt: cutoff.Add(-time.Hour), UpdatedAt: cutoff.Add(-time.Hour)},
+		},
+		Creators: map[string]creatorFeedback{
+			"hidden":    {HiddenAt: now, UpdatedAt: now},
+			"dismissed": {Dismissals: 2, UpdatedAt: now},
+		},
+	}
+
+	signals := feedbackSignalsOf(stored, cutoff)
+	if !signals.dismissed["dismissed"] || signals.dismissed["expired"] {
+		t.Errorf("Expected only the recent dismissal to count, got %+v", signals.dismissed)
+	}
+	if len(signals.ignored) != 1 || signals.ignored["ignored"] != 5-feedbackFreeImpressions {
+		t.Errorf("Expected the unclicked post to be ignored %d times, got %+v", 5-feedbackFreeImpressions, signals.ignored)
+	}
+	if !signals.hiddenCreators["hidden"] || signals.creatorDismissals["dismissed"] != 2 {
+		t.Errorf("Expected the hidden and dismissed creators, got %+v and %+v", signals.hiddenCreators, signals.creatorDismissals)
+	}
+}
+
+func TestRerankWithFeedback(t *testing.T) {
+	recs := []models.Recommendation{
+		{PostID: "a", Score: 10},
+		{PostID: "dismissed", Score: 9},
+		{PostID: "hidden", Score: 8},
+		{PostID: "b", Score: 7},
+		{PostID: "c", Score: 6},
+	}
+	owners := map[string]string{"a": "creator-1", "hidden": "creator-2", "b": "creator-3", "c": "creator-3"}
+	feedback := feedbackSignalsOf(recommendationFeedback{}, time.Now())
+	feedback.dismissed["dismissed"] = true
+	feedback.hiddenCreators["creator-2"] = true
+	feedback.creatorDismissals["creator-1"] = 1
+	feedback.ignored["b"] = 1
+
+	ranked := rerankWithFeedback(recs, feedback, owners)
+	order := make([]string, len(ranked))
+	for i, rec := range ranked {
+		order[i] = rec.PostID
+	}
+	if len(order) != 3 || order[0] != "c" || order[1] != "b" || order[2] != "a" {
+		t.Fatalf("Expected c, b, a, got %v", order)
+	}
+	if math.Abs(ranked[2].Score-10*feedbackCreatorDemotion) > 1e-9 {
+		t.Errorf("Expected a demoted score of %.2f, got %.2f", 10*feedbackCreatorDemotion, ranked[2].Score)
+	}
+	if recs[0].Score != 10 {
+		t.Error("Expected the input recommendations to be left unchanged")
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Source of the trending posts blended into recommendations when they are read
+const RecommendationSourceTrending = "trending"
+
+// Bounds of the recommendation sweep
+const (
+	maxSweptRecommendations    = 5000 // expired recommendations handled per sweep
+	recommendationRefreshDecay = 0.5  // score factor of a recommendation refreshed by the sweep
+)
+
+// RecommendationSweeper deletes expired recommendations, or refreshes those whose post is
+// still recent trending content with a decayed score so fresher recommendations overtake them
+type RecommendationSweeper struct {
+	firestoreClient *FirestoreClient
+	ctx             context.Context
+	cancel          context.CancelFunc
+	interval        time.Duration
+	leader          *LeaderElector
+}
+
+func NewRecommendationSweeper(firestoreClient *FirestoreClient, interval time.Duration) *RecommendationSweeper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RecommendationSweeper{
+		firestoreClient: firestoreClient,
+		ctx:             ctx,
+		cancel:          cancel,
+		interval:        interval,
+	}
+}
+
+// UseLeaderElector sweeps on the elected instance only
+func (rs *RecommendationSweeper) UseLeaderElector(leader *LeaderElector) {
+	rs.leader = leader
+}
+
+// Start begins the periodic sweep loop
+func (rs *RecommendationSweeper) Start() {
+	logger.Infof("🧹 Starting recommendation sweeper (interval %v, TTL %v)", rs.interval, rs.firestoreClient.recommendationTTL)
+
+	ticker := time.NewTicker(rs.interval)
+	go func() {
+		for {
+			select {
+			case <-rs.ctx.Done():
+				ticker.Stop()
+				logger.Info("🛑 Recommendation sweeper stopped")
+				return
+			case <-ticker.C:
+				if !rs.leader.IsLeader() {
+					continue
+				}
+				if err := rs.Sweep(); err != nil {
+					logger.Errorf("❌ Recommendation sweep failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the sweep loop
+func (rs *RecommendationSweeper) Stop() {
+	rs.cancel()
+}
+
+// Sweep refreshes or deletes the recommendations that expired
+func (rs *RecommendationSweeper) Sweep() error {
+	refreshed, deleted, err := rs.firestoreClient.SweepRecommendations(time.Now())
+	if err != nil {
+		return err
+	}
+	if refreshed > 0 || deleted > 0 {
+		logger.Infof("🧹 Swept expired recommendations: %d refreshed, %d deleted", refreshed, deleted)
+	}
+	return nil
+}
+
+// SweepRecommendations refreshes the expired recommendations of posts that are still recent
+// trending content and deletes the others, returning how many of each. Recommendations stored
+// without an expiry are given one. The collection group query needs the collection group index
+// on ExpiresAt in firestore.indexes.json.
+func (fc *FirestoreClient) SweepRecommendations(now time.Time) (refreshed, deleted int, err error) {
+	docs, err := fc.client.CollectionGroup("items").
+		Where("ExpiresAt", "<", now).
+		Limit(maxSweptRecommendations).
+		Documents(fc.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(docs) == maxSweptRecommendations {
+		logger.Warnf("Sweeping the first %d expired recommendations, the rest are left for the next sweep", maxSweptRecommendations)
+	}
+
+	expired := make(map[*firestore.DocumentRef]models.Recommendation, len(docs))
+	postSet := make(map[string]bool)
+	bw := fc.client.BulkWriter(fc.ctx)
+	for _, doc := range docs {
+		// recommendations/{userID}/items/{postID}
+		if doc.Ref.Parent.Parent == nil || doc.Ref.Parent.Parent.Parent.ID != "recommendations" {
+			continue
+		}
+		var rec models.Recommendation
+		if err := doc.DataTo(&rec); err != nil {
+			logger.Debugf("Skipping unreadable recommendation %s: %v", doc.Ref.Path, err)
+			continue
+		}
+		expiresAt := fc.recommendationExpiry(rec)
+		if expiresAt.After(now) {
+			// Stored without an expiry
+			Quotas.Record(QuotaFirestore, 1)
+			if _, err := bw.Update(doc.Ref, []firestore.Update{{Path: "ExpiresAt", Value: expiresAt}}); err != nil {
+				logger.Warnf("Failed to set the expiry of recommendation %s: %v", doc.Ref.Path, err)
+			}
+			continue
+		}
+		expired[doc.Ref] = rec
+		postSet[doc.Ref.ID] = true
+	}
+
+	postIDs := make([]string, 0, len(postSet))
+	for postID := range postSet {
+		postIDs = append(postIDs, postID)
+	}
+	recent, err := fc.recentTrendingPosts(postIDs, now)
+	if err != nil {
+		bw.End()
+		return 0, 0, err
+	}
+
+	for ref, rec := range expired {
+		Quotas.Record(QuotaFirestore, 1)
+		if recent[ref.ID] {
+			_, err = bw.Update(ref, []firestore.Update{
+				{Path: "Score", Value: rec.Score * recommendationRefreshDecay},
+				{Path: "ExpiresAt", Value: now.Add(fc.recommendationTTL)},
+			})
+			if err == nil {
+				refreshed++
+			}
+		} else if _, err = bw.Delete(ref); err == nil {
+			deleted++
+		}
+		if err != nil {
+			logger.Warnf("Failed to sweep recommendation %s: %v", ref.Path, err)
+		}
+	}
+	bw.End()
+	return refreshed, deleted, nil
+}
+
+// recentTrendingPosts reports which of the posts had their trending score updated within the
+// recent trending window
+func (fc *FirestoreClient) recentTrendingPosts(postIDs []string, now time.Time) (map[string]bool, error) {
+	cutoff := now.Add(-fc.trendingRecent)
+	recent := make(map[string]bool, len(postIDs))
+	err := fc.getAllInBatches("trending_scores", postIDs, func(doc *firestore.DocumentSnapshot) {
+		if updatedAt, ok := doc.Data()["UpdatedAt"].(time.Time); ok && !updatedAt.Before(cutoff) {
+			recent[doc.Ref.ID] = true
+		}
+	})
+	return recent, err
+}
+
+// RecentTrendingRecommendations returns the top trending posts, of the limit highest scored,
+// whose score was updated within the recent trending window, as recommendations for the user
+func (fc *FirestoreClient) RecentTrendingRecommendations(userID string, limit int, now time.Time) ([]models.Recommendation, error) {
+	docs, err := fc.client.Collection("trending_scores").
+		OrderBy("Score", firestore.Desc).
+		Select("Score", "Category", "UpdatedAt").
+		Limit(limit).
+		Documents(fc.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := now.Add(-fc.trendingRecent)
+	recs := make([]models.Recommendation, 0, len(docs))
+	for _, doc := range docs {
+		data := doc.Data()
+		if updatedAt, ok := data["UpdatedAt"].(time.Time); !ok || updatedAt.Before(cutoff) {
+			continue
+		}
+		score, _ := data["Score"].(float64)
+		category, _ := data["Category"].(string)
+		recs = append(recs, models.Recommendation{
+			UserID:      userID,
+			PostID:      doc.Ref.ID,
+			Score:       score,
+			Reason:      "Trending now",
+			Category:    category,
+			GeneratedAt: now,
+			Source:      RecommendationSourceTrending,
+		})
+	}
+	return recs, nil
+}
+
+// withExpiry sets the expiry of a recommendation about to be stored, when it has none
+func (fc *FirestoreClient) withExpiry(rec models.Recommendation) models.Recommendation {
+	if fc.recommendationTTL > 0 && rec.ExpiresAt.IsZero() {
+		rec.ExpiresAt = fc.recommendationExpiry(rec)
+	}
+	return rec
+}
+
+// recommendationExpiry is when a recommendation stops being served: its stored expiry, or
+// the recommendation TTL after it was generated. It is zero when recommendations do not expire.
+func (fc *FirestoreClient) recommendationExpiry(rec models.Recommendation) time.Time {
+	if fc.recommendationTTL <= 0 {
+		return time.Time{}
+	}
+	if !rec.ExpiresAt.IsZero() {
+		return rec.ExpiresAt
+	}
+	generatedAt := rec.GeneratedAt
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+	return generatedAt.Add(fc.recommendationTTL)
+}
+
+// recommendationExpired reports whether a recommendation is no longer served
+func (fc *FirestoreClient) recommendationExpired(rec models.Recommendation, now time.Time) bool {
+	expiresAt := fc.recommendationExpiry(rec)
+	return !expiresAt.IsZero() && !expiresAt.After(now)
+}
+
+// withoutRecommended drops the trending posts already among the recommendations
+func withoutRecommended(trending, recs []models.Recommendation) []models.Recommendation {
+	recommended := make(map[string]bool, len(recs))
+	for _, rec := range recs {
+		recommended[rec.PostID] = true
+	}
+	kept := make([]models.Recommendation, 0, len(trending))
+	for _, rec := range trending {
+		if !recommended[rec.PostID] {
+			kept = append(kept, rec)
+		}
+	}
+	return kept
+}
+
+// splitTrending separates the blended trending posts from the personal recommendations,
+// keeping the order of each
+func splitTrending(recs []models.Recommendation) (personal, trending []models.Recommendation) {
+	for _, rec := range recs {
+		if rec.Source == RecommendationSourceTrending {
+			trending = append(trending, rec)
+		} else {
+			personal = append(personal, rec)
+		}
+	}
+	return personal, trending
+}
+
+// blendTrending fills the share of the limit slots with trending posts, spread evenly among
+// the personal recommendations; trending posts also fill the slots personal ones leave empty.
+// Without a share, it returns the personal recommendations alone.
+func blendTrending(personal, trending []models.Recommendation, limit int, share float64) []models.Recommendation {
+	if share <= 0 {
+		trending = nil
+	}
+	slots := int(math.Round(float64(limit) * share))
+	if short := limit - len(personal); short > slots {
+		slots = short
+	}
+	slots = max(0, min(slots, len(trending)))
+	total := min(limit, len(personal)+slots)
+
+	blended := make([]models.Recommendation, 0, total)
+	p, t := 0, 0
+	for i := 0; i < total; i++ {
+		// A slot is trending each time another 1/slots of the slots is filled
+		if t < slots && (p >= total-slots || (i+1)*slots/total > t) {
+			blended = append(blended, trending[t])
+			t++
+		} else {
+			blended = append(blended, personal[p])
+			p++
+		}
+	}
+	return blended
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestRecommendationExpiry(t *testing.T) {
+	now := time.Now()
+	fc := &FirestoreClient{recommendationTTL: 48 * time.Hour}
+
+	fresh := fc.withExpiry(models.Recommendation{GeneratedAt: now.Add(-time.Hour)})
+	if !fresh.ExpiresAt.Equal(now.Add(47 * time.Hour)) {
+		t.Errorf("Expected the expiry 48h after generation, got %v", fresh.ExpiresAt)
+	}
+	if fc.recommendationExpired(fresh, now) {
+		t.Error("Expected a recommendation generated an hour ago to be served")
+	}
+
+	// Stored without an expiry, it expires the TTL after it was generated
+	if !fc.recommendationExpired(models.Recommendation{GeneratedAt: now.Add(-72 * time.Hour)}, now) {
+		t.Error("Expected a recommendation generated 72h ago to have expired")
+	}
+	// A refreshed expiry outlives the generation time
+	refreshed := models.Recommendation{GeneratedAt: now.Add(-72 * time.Hour), ExpiresAt: now.Add(time.Hour)}
+	if fc.recommendationExpired(refreshed, now) {
+		t.Error("Expected a refreshed recommendation to be served")
+	}
+
+	noTTL := &FirestoreClient{}
+	if rec := noTTL.withExpiry(models.Recommendation{GeneratedAt: now}); !rec.ExpiresAt.IsZero() || noTTL.recommendationExpired(models.Recommendation{}, now) {
+		t.Error("Expected recommendations not to expire without a TTL")
+	}
+}
+
+func TestBlendTrending(t *testing.T) {
+	recommendations := func(prefix string, n int) []models.Recommendation {
+		recs := make([]models.Recommendation, n)
+		for i := range recs {
+			recs[i] = models.Recommendation{PostID: fmt.Sprintf("%s%d", prefix, i)}
+		}
+		return recs
+	}
+	order := func(recs []models.Recommendation) string {
+		var s string
+		for _, rec := range recs {
+			s += rec.PostID + " "
+		}
+		return s
+	}
+
+	tests := []struct {
+		name     string
+		personal int
+		trending int
+		limit    int
+		share    float64
+		expected string
+	}{
+		{"spread evenly", 10, 10, 10, 0.2, "p0 p1 p2 p3 t0 p4 p5 p6 p7 t1 "},
+		{"no share", 10, 10, 5, 0, "p0 p1 p2 p3 p4 "},
+		{"fill empty slots", 2, 10, 5, 0.2, "p0 t0 p1 t1 t2 "},
+		{"too few trending", 10, 0, 4, 0.5, "p0 p1 p2 p3 "},
+		{"too few of both", 1, 1, 5, 0.2, "p0 t0 "},
+	}
+	for _, tt := range tests {
+		blended := blendTrending(recommendations("p", tt.personal), recommendations("t", tt.trending), tt.limit, tt.share)
+		if got := order(blended); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestSplitTrending(t *testing.T) {
+	recs := []models.Recommendation{{PostID: "a"}, {PostID: "b", Source: RecommendationSourceTrending}, {PostID: "c", Source: RecommendationSourceContent}}
+	trending := withoutRecommended([]models.Recommendation{{PostID: "a"}, {PostID: "d"}}, recs)
+	if len(trending) != 1 || trending[0].PostID != "d" {
+		t.Errorf("Expected only d to remain, got %+v", trending)
+	}
+
+	personal, blended := splitTrending(recs)
+	if len(personal) != 2 || personal[1].PostID != "c" || len(blended) != 1 || blended[0].PostID != "b" {
+		t.Errorf("Expected a and c personal and b trending, got %+v and %+v", personal, blended)
+	}
+}
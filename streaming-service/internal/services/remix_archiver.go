@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Number of top remixes kept in a chain summary
+	remixSummaryTopN = 5
+
+	// Maximum remix-of-remix depth followed when measuring a chain
+	maxRemixChainDepth = 10
+
+	// Remixes archived per transaction: a copy and a delete per remix plus the summary stay
+	// within Firestore's 500 writes per transaction
+	remixArchiveBatchSize = 200
+)
+
+// RemixArchiver rolls up finished remix chains into summary documents and moves
+// the raw remix entries to cold storage so hot remix_chains reads stay small
+type RemixArchiver struct {
+	firestoreClient *FirestoreClient
+	ctx             context.Context
+	cancel          context.CancelFunc
+	archiveAfter    time.Duration
+	runInterval     time.Duration
+	leader          *LeaderElector
+}
+
+// NewRemixArchiver creates a new remix archiver. Chains whose newest remix is older
+// than archiveAfter are considered finished.
+func NewRemixArchiver(firestoreClient *FirestoreClient, archiveAfter, runInterval time.Duration) *RemixArchiver {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &RemixArchiver{
+		firestoreClient: firestoreClient,
+		ctx:             ctx,
+		cancel:          cancel,
+		archiveAfter:    archiveAfter,
+		runInterval:     runInterval,
+	}
+}
+
+// UseLeaderElector archives on the elected instance only
+func (ra *RemixArchiver) UseLeaderElector(leader *LeaderElector) {
+	ra.leader = leader
+}
+
+// Start begins the periodic archive loop
+func (ra *RemixArchiver) Start() {
+	logger.Infof("🗄️ Starting remix archiver (archive after %v, interval %v)", ra.archiveAfter, ra.runInterval)
+
+	ticker := time.NewTicker(ra.runInterval)
+	go func() {
+		for {
+			select {
+			case <-ra.ctx.Done():
+				ticker.Stop()
+				logger.Info("🛑 Remix archiver stopped")
+				return
+			case <-ticker.C:
+				if !ra.leader.IsLeader() {
+					continue
+				}
+				if err := ra.ArchiveFinishedChains(); err != nil {
+					logger.Errorf("❌ Remix archiving failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop gracefully stops the archiver
+func (ra *RemixArchiver) Stop() {
+	ra.cancel()
+}
+
+// ArchiveFinishedChains archives every remix chain that has had no new remix within the archive window
+func (ra *RemixArchiver) ArchiveFinishedChains() error {
+	startTime := time.Now()
+	cutoff := startTime.Add(-ra.archiveAfter)
+	logger.Debug("🗄️ Starting remix chain archiving...")
+
+	// DocumentRefs also lists chain parents that only exist through their subcollection
+	refs := ra.firestoreClient.client.Collection("remix_chains").DocumentRefs(ra.ctx)
+
+	archivedCount := 0
+	errorCount := 0
+
+	for {
+		chainRef, err := refs.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list remix chains: %w", err)
+		}
+
+		archived, err := ra.archiveChain(chainRef.ID, cutoff)
+		if err != nil {
+			logger.Debugf(" Failed to archive remix chain %s: %v", chainRef.ID, err)
+			errorCount++
+			continue
+		}
+		if archived {
+			archivedCount++
+		}
+	}
+
+	logger.Infof("✅ Remix archiving complete: archived=%d, errors=%d, duration=%v",
+		archivedCount, errorCount, time.Since(startTime))
+	return nil
+}
+
+// archivedRemix is a raw remix entry read from the hot collection
+type archivedRemix struct {
+	ref       *firestore.DocumentRef
+	data      map[string]interface{}
+	createdAt time.Time
+}
+
+// archiveChain archives a single chain if it is finished; returns whether it was archived
+func (ra *RemixArchiver) archiveChain(originalPostID string, cutoff time.Time) (bool, error) {
+	remixes, err := ra.loadRemixes(originalPostID)
+	if err != nil {
+		return false, err
+	}
+
+	// Remixes without a creation time cannot be judged finished, so they stay hot
+	dated := make([]archivedRemix, 0, len(remixes))
+	for _, r := range remixes {
+		if !r.createdAt.IsZero() {
+			dated = append(dated, r)
+		}
+	}
+	if skipped := len(remixes) - len(dated); skipped > 0 {
+		logger.Debugf("🗄️ Remix chain %s: %d remixes without created_at left unarchived", originalPostID, skipped)
+	}
+	if len(dated) == 0 {
+		return false, nil
+	}
+
+	// A chain is finished only when even its newest remix is past the cutoff
+	for _, r := range dated {
+		if r.createdAt.After(cutoff) {
+			return false, nil
+		}
+	}
+
+	depth := ra.chainDepth(originalPostID, map[string]bool{}, 0)
+	archived := 0
+	for start := 0; start < len(dated); start += remixArchiveBatchSize {
+		end := start + remixArchiveBatchSize
+		if end > len(dated) {
+			end = len(dated)
+		}
+		n, err := ra.archiveBatch(originalPostID, dated[start:end], depth)
+		if err != nil {
+			return archived > 0, err
+		}
+		archived += n
+	}
+
+	logger.Debugf("🗄️ Archived remix chain %s: remixes=%d, depth=%d", originalPostID, archived, depth)
+	return archived > 0, nil
+}
+
+// archiveBatch copies a batch of remixes to cold storage, deletes them from the hot collection
+// and folds them into the chain summary in one transaction, so the remix count never counts a
+// remix twice or misses one. Remixes an earlier run already moved are left out. It returns how
+// many remixes were moved.
+func (ra *RemixArchiver) archiveBatch(originalPostID string, batch []archivedRemix, depth int) (int, error) {
+	client := ra.firestoreClient.client
+	summaryRef := client.Collection("remix_chain_summaries").Doc(originalPostID)
+	archiveRemixes := client.Collection("remix_chains_archive").Doc(originalPostID).Collection("remixes")
+
+	// Scored outside the transaction, which may be retried
+	entries := ra.scoreRemixes(batch)
+	refs := make([]*firestore.DocumentRef, len(batch))
+	for i, r := range batch {
+		refs[i] = r.ref
+	}
+
+	archived := 0
+	err := client.RunTransaction(ra.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// The summary and every remix are read, each remix is copied and deleted, and the
+		// summary written
+		Quotas.Record(QuotaFirestore, int64(3*len(batch)+2))
+		archived = 0
+
+		summary := models.RemixChainSummary{OriginalPostID: originalPostID}
+		doc, err := tx.Get(summaryRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return fmt.Errorf("failed to read summary: %w", err)
+		}
+		if err == nil {
+			if err := doc.DataTo(&summary); err != nil {
+				return fmt.Errorf("failed to parse summary: %w", err)
+			}
+		}
+
+		docs, err := tx.GetAll(refs)
+		if err != nil {
+			return fmt.Errorf("failed to read remixes: %w", err)
+		}
+		top := summary.TopRemixes
+		for i, doc := range docs {
+			if !doc.Exists() {
+				continue
+			}
+			r := batch[i]
+			if err := tx.Set(archiveRemixes.Doc(r.ref.ID), r.data); err != nil {
+				return fmt.Errorf("failed to archive remix %s: %w", r.ref.ID, err)
+			}
+			if err := tx.Delete(r.ref); err != nil {
+				return fmt.Errorf("failed to delete remix %s: %w", r.ref.ID, err)
+			}
+			archived++
+
+			if summary.OldestRemixAt.IsZero() || r.createdAt.Before(summary.OldestRemixAt) {
+				summary.OldestRemixAt = r.createdAt
+			}
+			if r.createdAt.After(summary.NewestRemixAt) {
+				summary.NewestRemixAt = r.createdAt
+			}
+			top = append(top, entries[i])
+		}
+		if archived == 0 {
+			return nil
+		}
+
+		summary.RemixCount += int64(archived)
+		if depth > summary.Depth {
+			summary.Depth = depth
+		}
+		summary.TopRemixes = rankTopRemixes(top)
+		summary.ArchivedAt = time.Now()
+		return tx.Set(summaryRef, &summary)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return archived, nil
+}
+
+// loadRemixes reads the hot remix entries of a chain
+func (ra *RemixArchiver) loadRemixes(originalPostID string) ([]archivedRemix, error) {
+	iter := ra.firestoreClient.client.Collection("remix_chains").Doc(originalPostID).Collection("remixes").Documents(ra.ctx)
+
+	var remixes []archivedRemix
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data := doc.Data()
+		createdAt, _ := data["created_at"].(time.Time)
+		remixes = append(remixes, archivedRemix{
+			ref:       doc.Ref,
+			data:      data,
+			createdAt: createdAt,
+		})
+	}
+	return remixes, nil
+}
+
+// chainDepth returns the longest remix-of-remix path below a post, across hot and archived entries
+func (ra *RemixArchiver) chainDepth(postID string, visited map[string]bool, level int) int {
+	if level >= maxRemixChainDepth || visited[postID] {
+		return 0
+	}
+	visited[postID] = true
+
+	remixIDs := map[string]bool{}
+	for _, collection := range []string{"remix_chains", "remix_chains_archive"} {
+		refs := ra.firestoreClient.client.Collection(collection).Doc(postID).Collection("remixes").DocumentRefs(ra.ctx)
+		for {
+			ref, err := refs.Next()
+			if err != nil {
+				break
+			}
+			remixIDs[ref.ID] = true
+		}
+	}
+	if len(remixIDs) == 0 {
+		return 0
+	}
+
+	deepest := 0
+	for remixID := range remixIDs {
+		if d := ra.chainDepth(remixID, visited, level+1); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest + 1
+}
+
+// scoreRemixes returns a summary entry with the trending score of each remix
+func (ra *RemixArchiver) scoreRemixes(remixes []archivedRemix) []models.RemixSummaryEntry {
+	entries := make([]models.RemixSummaryEntry, 0, len(remixes))
+	for _, r := range remixes {
+		entry := models.RemixSummaryEntry{PostID: r.ref.ID}
+		if score, err := ra.firestoreClient.GetPostStats(r.ref.ID); err == nil && score != nil {
+			entry.Score = score.Score
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// rankTopRemixes keeps the highest scored entries
+func rankTopRemixes(entries []models.RemixSummaryEntry) []models.RemixSummaryEntry {
+	entries = append([]models.RemixSummaryEntry{}, entries...)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Score > entries[j].Score
+	})
+
+	if len(entries) > remixSummaryTopN {
+		entries = entries[:remixSummaryTopN]
+	}
+	return entries
+}
+
+// GetChainSummary returns the archived summary of a remix chain, or nil if nothing was archived yet
+func (ra *RemixArchiver) GetChainSummary(originalPostID string) (*models.RemixChainSummary, error) {
+	return ra.firestoreClient.GetRemixChainSummary(originalPostID)
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestRankTopRemixes(t *testing.T) {
+	entries := []models.RemixSummaryEntry{
+		{PostID: "a", Score: 1}, {PostID: "b", Score: 6}, {PostID: "c", Score: 3},
+		{PostID: "d", Score: 5}, {PostID: "e", Score: 2}, {PostID: "f", Score: 4},
+	}
+
+	top := rankTopRemixes(entries)
+	if len(top) != remixSummaryTopN || top[0].PostID != "b" || top[len(top)-1].PostID != "e" {
+		t.Errorf("Unexpected top remixes %v", top)
+	}
+	if entries[0].PostID != "a" {
+		t.Error("Expected the entries passed in to stay unsorted")
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// Trending posts considered as remix candidates
+	remixCandidatePool = 100
+
+	// User posts read to build the style profile
+	styleProfilePosts = 50
+
+	// Remix rate (remixes per view) and velocity treated as the top of their scale
+	remixRateCeiling = 0.05
+	velocityCeiling  = 10.0
+)
+
+// Weights of the remix-friendliness components
+const (
+	remixRateWeight  = 0.45
+	styleMatchWeight = 0.35
+	velocityWeight   = 0.20
+)
+
+// RemixSuggestion is a post ranked by how worth remixing it is for a user
+type RemixSuggestion struct {
+	PostID       string   `json:"postId"`
+	Score        float64  `json:"score"`
+	RemixRate    float64  `json:"remixRate"`
+	StyleMatch   float64  `json:"styleMatch"`
+	Velocity     float64  `json:"velocity"`
+	Reasons      []string `json:"reasons"`
+	ContentType  string   `json:"contentType,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	ThumbnailURL string   `json:"thumbnailUrl,omitempty"`
+}
+
+// styleProfile summarizes the styles, categories and keywords of a user's own posts
+type styleProfile struct {
+	styles     map[string]int
+	categories map[string]int
+	keywords   map[string]bool
+}
+
+func (p styleProfile) empty() bool {
+	return len(p.styles) == 0 && len(p.categories) == 0 && len(p.keywords) == 0
+}
+
+// GetRemixSuggestions ranks trending posts by remix-friendliness for a user: a high remix rate
+// relative to views, a match with the user's style profile and rising engagement velocity
+func (da *DashboardAnalytics) GetRemixSuggestions(userID string, limit int) ([]RemixSuggestion, error) {
+	logger.Debugf("📊 Calculating remix suggestions for user %s...", userID)
+
+	profile, err := da.userStyleProfile(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build style profile: %w", err)
+	}
+
+	iter := da.firestoreClient.client.Collection("trending_scores").
+		OrderBy("Score", firestore.Desc).
+		Limit(remixCandidatePool).
+		Documents(da.ctx)
+
+	suggestions := []RemixSuggestion{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var score models.TrendingScore
+		if err := doc.DataTo(&score); err != nil {
+			continue
+		}
+
+		postDoc, err := da.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(da.ctx)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		postData := postDoc.Data()
+
+		// Users remix other people's work; flagged posts are never suggested
+		if owner, _ := postData["userId"].(string); owner == userID || isModerationFlagged(postData) {
+			continue
+		}
+
+		suggestions = append(suggestions, rankRemixCandidate(score, postData, profile))
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	logger.Infof("✅ Remix suggestions calculated for user %s: %d posts", userID, len(suggestions))
+	return suggestions, nil
+}
+
+// userStyleProfile reads the style, category and keywords of the user's recent posts
+func (da *DashboardAnalytics) userStyleProfile(userID string) (styleProfile, error) {
+	profile := styleProfile{
+		styles:     make(map[string]int),
+		categories: make(map[string]int),
+		keywords:   make(map[string]bool),
+	}
+
+	iter := da.firestoreClient.client.Collection("posts").
+		Where("userId", "==", userID).
+		Limit(styleProfilePosts).
+		Documents(da.ctx)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return profile, err
+		}
+
+		data := doc.Data()
+		if style, ok := data["style"].(string); ok && style != "" {
+			profile.styles[strings.ToLower(style)]++
+		}
+		if category, ok := data["category"].(string); ok && category != "" {
+			profile.categories[strings.ToLower(category)]++
+		}
+		for _, keyword := range groupingKeywords(data) {
+			profile.keywords[strings.ToLower(keyword)] = true
+		}
+	}
+
+	return profile, nil
+}
+
+// rankRemixCandidate scores a single candidate post and explains the score
+func rankRemixCandidate(score models.TrendingScore, postData map[string]interface{}, profile styleProfile) RemixSuggestion {
+	suggestion := RemixSuggestion{
+		PostID:   score.PostID,
+		Velocity: score.EngagementVelocity,
+		Reasons:  []string{},
+	}
+	suggestion.ContentType, _ = postData["contentType"].(string)
+	suggestion.Title, _ = postData["title"].(string)
+	if media, ok := postData["media"].(map[string]interface{}); ok {
+		suggestion.ThumbnailURL, _ = media["thumbnail_url"].(string)
+	}
+
+	if score.ViewCount > 0 {
+		suggestion.RemixRate = float64(score.RemixCount) / float64(score.ViewCount)
+	}
+	remixComponent := min64(suggestion.RemixRate/remixRateCeiling, 1)
+	if remixComponent >= 0.5 {
+		suggestion.Reasons = append(suggestion.Reasons,
+			fmt.Sprintf("Frequently remixed: %d remixes from %d views", score.RemixCount, score.ViewCount))
+	}
+
+	style, _ := postData["style"].(string)
+	category, _ := postData["category"].(string)
+	suggestion.StyleMatch = styleMatch(profile, style, category, groupingKeywords(postData))
+	if suggestion.StyleMatch >= 0.5 {
+		if style != "" && profile.styles[strings.ToLower(style)] > 0 {
+			suggestion.Reasons = append(suggestion.Reasons, fmt.Sprintf("Matches your %s style", style))
+		} else {
+			suggestion.Reasons = append(suggestion.Reasons, "Similar to what you create")
+		}
+	}
+
+	velocityComponent := min64(score.EngagementVelocity/velocityCeiling, 1)
+	if velocityComponent >= 0.5 {
+		suggestion.Reasons = append(suggestion.Reasons, "Rising fast right now")
+	}
+
+	suggestion.Score = remixRateWeight*remixComponent +
+		styleMatchWeight*suggestion.StyleMatch +
+		velocityWeight*velocityComponent
+	return suggestion
+}
+
+// styleMatch returns how well a post matches a style profile, from 0 to 1
+func styleMatch(profile styleProfile, style, category string, keywords []string) float64 {
+	if profile.empty() {
+		return 0
+	}
+
+	match := 0.0
+	if style != "" && profile.styles[strings.ToLower(style)] > 0 {
+		match += 0.5
+	}
+	if category != "" && profile.categories[strings.ToLower(category)] > 0 {
+		match += 0.3
+	}
+	if len(keywords) > 0 {
+		shared := 0
+		for _, keyword := range keywords {
+			if profile.keywords[strings.ToLower(keyword)] {
+				shared++
+			}
+		}
+		match += 0.2 * float64(shared) / float64(len(keywords))
+	}
+	return match
+}
+
+// stringSlice converts a Firestore array value into strings, skipping other element types
+// groupingKeywords returns a post's English keywords, so posts written in different languages
+// match, falling back to the keywords of posts extracted before translations were stored
+func groupingKeywords(postData map[string]interface{}) []string {
+	if keywords := stringSlice(postData["english_keywords"]); len(keywords) > 0 {
+		return keywords
+	}
+	return stringSlice(postData["keywords"])
+}
+
+func stringSlice(value interface{}) []string {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestRankRemixCandidate(t *testing.T) {
+	profile := styleProfile{
+		styles:     map[string]int{"watercolor": 3},
+		categories: map[string]int{"art": 4},
+		keywords:   map[string]bool{"sunset": true, "ocean": true},
+	}
+
+	postData := map[string]interface{}{
+		"style":    "Watercolor",
+		"category": "art",
+		"keywords": []interface{}{"sunset", "mountain"},
+		"title":    "Evening",
+	}
+	score := models.TrendingScore{PostID: "p1", ViewCount: 100, RemixCount: 5, EngagementVelocity: 12}
+
+	suggestion := rankRemixCandidate(score, postData, profile)
+
+	if suggestion.StyleMatch != 0.9 {
+		t.Errorf("StyleMatch = %v, want 0.9", suggestion.StyleMatch)
+	}
+	if len(suggestion.Reasons) != 3 {
+		t.Errorf("Expected remix, style and velocity reasons, got %v", suggestion.Reasons)
+	}
+	want := remixRateWeight + styleMatchWeight*0.9 + velocityWeight
+	if diff := suggestion.Score - want; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Score = %v, want %v", suggestion.Score, want)
+	}
+
+	quiet := rankRemixCandidate(models.TrendingScore{PostID: "p2", ViewCount: 1000}, map[string]interface{}{}, profile)
+	if quiet.Score != 0 || len(quiet.Reasons) != 0 {
+		t.Errorf("Expected unranked candidate, got %+v", quiet)
+	}
+}
+
+func TestStyleMatchEmptyProfile(t *testing.T) {
+	if got := styleMatch(styleProfile{}, "watercolor", "art", []string{"sunset"}); got != 0 {
+		t.Errorf("styleMatch = %v, want 0 for a user without posts", got)
+	}
+}
@@ -0,0 +1,14 @@
+package services
+
+import "time"
+
+// StartOfDay returns midnight of the calendar day t falls on in loc
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// ReportingDate formats the calendar day t falls on in loc as YYYY-MM-DD
+func ReportingDate(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartOfDayUsesReportingZone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	// 02:30 UTC on March 10 is the evening of March 9 in New York
+	start := StartOfDay(time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC), loc)
+	if want := time.Date(2024, 3, 9, 0, 0, 0, 0, loc); !start.Equal(want) {
+		t.Errorf("expected %v, got %v", want, start)
+	}
+
+	// March 10 loses an hour to daylight saving time
+	day := StartOfDay(time.Date(2024, 3, 10, 12, 0, 0, 0, loc), loc)
+	if length := day.AddDate(0, 0, 1).Sub(day); length != 23*time.Hour {
+		t.Errorf("expected a 23 hour day, got %v", length)
+	}
+
+	if date := ReportingDate(time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC), loc); date != "2024-03-09" {
+		t.Errorf("expected 2024-03-09, got %s", date)
+	}
+}
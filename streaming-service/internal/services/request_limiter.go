@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Request rate limit backends selectable via RATE_LIMIT_BACKEND
+const (
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendRedis  = "redis"
+)
+
+// Prefix of the token buckets stored in Redis
+const redisRateLimitPrefix = "ratelimit:"
+
+// Timeout of each Redis command, after which the request falls back to the memory buckets
+const redisTimeout = 500 * time.Millisecond
+
+// tokenBucketScript takes a token from the bucket in KEYS[1], refilled at ARGV[1] tokens per
+// second up to ARGV[2], at the time ARGV[3] in milliseconds. It returns whether a token was
+// taken and otherwise how many milliseconds until one is free. Same rules as takeToken.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(state[1]) or burst
+local at = tonumber(state[2]) or now
+if now > at then
+  tokens = math.min(burst, tokens + (now - at) / 1000 * rate)
+  at = now
+end
+local allowed, wait = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', tostring(at))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, wait}
+`
+
+// tokenBucketRunner runs tokenBucketScript by its SHA, loading it on the first call
+var tokenBucketRunner = redis.NewScript(tokenBucketScript)
+
+// RequestRate is the requests per second and burst allowed per client; QPS 0 disables it
+type RequestRate struct {
+	QPS   float64 `json:"qps"`
+	Burst int     `json:"burst"`
+}
+
+// RequestLimiterStats reports the requests seen by a RequestLimiter
+type RequestLimiterStats struct {
+	Backend     string      `json:"backend"`
+	IP          RequestRate `json:"ip"`
+	User        RequestRate `json:"user"`
+	Allowed     int64       `json:"allowed"`
+	Limited     int64       `json:"limited"`
+	RedisErrors int64       `json:"redis_errors"`
+	Buckets     int         `json:"buckets"` // buckets held in memory
+}
+
+// RequestLimiter limits the requests of each client IP and of each user with token buckets.
+// Buckets live in memory, per instance, or in Redis, shared by every replica. When Redis
+// cannot be reached the in-memory buckets take over, so an outage never blocks requests.
+type RequestLimiter struct {
+	ip   RequestRate
+	user RequestRate
+
+	memory *memoryBuckets
+	redis  *redis.Client
+
+	allowed     atomic.Int64
+	limited     atomic.Int64
+	redisErrors atomic.Int64
+	redisDown   atomic.Bool
+}
+
+// NewRequestLimiter creates the request limiter configured for the ingestion and analytics
+// routes, or nil when both limits are disabled
+func NewRequestLimiter(cfg *config.Config) (*RequestLimiter, error) {
+	limiter := &RequestLimiter{
+		ip:     RequestRate{QPS: cfg.RateLimitIPQPS, Burst: max(cfg.RateLimitIPBurst, 1)},
+		user:   RequestRate{QPS: cfg.RateLimitUserQPS, Burst: max(cfg.RateLimitUserBurst, 1)},
+		memory: newMemoryBuckets(),
+	}
+	if limiter.ip.QPS <= 0 && limiter.user.QPS <= 0 {
+		return nil, nil
+	}
+
+	switch cfg.RateLimitBackend {
+	case "", RateLimitBackendMemory:
+	case RateLimitBackendRedis:
+		if cfg.RedisAddr == "" {
+			return nil, fmt.Errorf("REDIS_ADDR is required for the %s rate limit backend", RateLimitBackendRedis)
+		}
+		limiter.redis = redis.NewClient(&redis.Options{
+			Addr:         cfg.RedisAddr,
+			Password:     cfg.RedisPassword,
+			DB:           cfg.RedisDB,
+			Protocol:     2,
+			DialTimeout:  redisTimeout,
+			ReadTimeout:  redisTimeout,
+			WriteTimeout: redisTimeout,
+			PoolSize:     8,
+			MaxRetries:   -1, // a failed take falls back to memory rather than retrying
+			// No CLIENT SETINFO on connect, which Redis before 7.2 rejects
+			DisableIndentity: true,
+		})
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.RateLimitBackend)
+	}
+	return limiter, nil
+}
+
+// Allow takes a token from the buckets of the client IP and of the user, when known. It
+// returns false and how long to wait when either bucket is empty.
+func (l *RequestLimiter) Allow(ip, user string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	now := time.Now()
+	if ip != "" {
+		if ok, wait := l.take("ip:"+ip, l.ip, now); !ok {
+			l.limited.Add(1)
+			return false, wait
+		}
+	}
+	if user != "" {
+		if ok, wait := l.take("user:"+user, l.user, now); !ok {
+			l.limited.Add(1)
+			return false, wait
+		}
+	}
+	l.allowed.Add(1)
+	return true, 0
+}
+
+// take takes a token from one bucket in Redis, or in memory without Redis or while it fails
+func (l *RequestLimiter) take(key string, limit RequestRate, now time.Time) (bool, time.Duration) {
+	if limit.QPS <= 0 {
+		return true, 0
+	}
+	if l.redis != nil {
+		ok, wait, err := l.takeRedis(key, limit, now)
+		if err == nil {
+			if l.redisDown.Swap(false) {
+				logger.Infof("✅ Rate limiting is back on Redis")
+			}
+			return ok, wait
+		}
+		l.redisErrors.Add(1)
+		if !l.redisDown.Swap(true) {
+			logger.Warnf("⚠️ Rate limiting falls back to this instance, Redis failed: %v", err)
+		}
+	}
+	return l.memory.take(key, limit, now)
+}
+
+func (l *RequestLimiter) takeRedis(key string, limit RequestRate, now time.Time) (bool, time.Duration, error) {
+	reply, err := tokenBucketRunner.Run(context.Background(), l.redis, []string{redisRateLimitPrefix + key},
+		strconv.FormatFloat(limit.QPS, 'f', -1, 64), limit.Burst, now.UnixMilli()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(reply) != 2 {
+		return false, 0, fmt.Errorf("unexpected token bucket reply %v", reply)
+	}
+	return reply[0] == 1, time.Duration(reply[1]) * time.Millisecond, nil
+}
+
+// Stats returns the requests allowed and limited since startup
+func (l *RequestLimiter) Stats() RequestLimiterStats {
+	if l == nil {
+		return RequestLimiterStats{}
+	}
+
+	backend := RateLimitBackendMemory
+	if l.redis != nil {
+		backend = RateLimitBackendRedis
+	}
+	buckets, _ := l.memory.len(context.Background())
+	return RequestLimiterStats{
+		Backend:     backend,
+		IP:          l.ip,
+		User:        l.user,
+		Allowed:     l.allowed.Load(),
+		Limited:     l.limited.Load(),
+		RedisErrors: l.redisErrors.Load(),
+		Buckets:     buckets,
+	}
+}
+
+// RegisterCaches hands the in-memory buckets to the cache maintainer, which drops the buckets
+// of clients gone quiet
+func (l *RequestLimiter) RegisterCaches(maintainer *CacheMaintainer) {
+	if l != nil {
+		maintainer.Register("request_rate_limits", l.memory.evictIdle, l.memory.len)
+	}
+}
+
+// Close closes the Redis connections
+func (l *RequestLimiter) Close() {
+	if l != nil && l.redis != nil {
+		l.redis.Close()
+	}
+}
+
+// tokenBucket is the state of one client's bucket in memory
+type tokenBucket struct {
+	tokens float64
+	at     time.Time // last refill
+	full   time.Time // when the bucket is full again, after which it can be dropped
+}
+
+// memoryBuckets holds the token buckets of this instance
+type memoryBuckets struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+func newMemoryBuckets() *memoryBuckets {
+	return &memoryBuckets{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+func (m *memoryBuckets) take(key string, limit RequestRate, now time.Time) (bool, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), at: now}
+		m.buckets[key] = bucket
+	}
+	allowed, wait := takeToken(bucket, limit, now)
+	bucket.full = now.Add(time.Duration((float64(limit.Burst) - bucket.tokens) / limit.QPS * float64(time.Second)))
+	return allowed, wait
+}
+
+// takeToken refills a bucket up to the burst for the time since its last refill and takes a
+// token, or returns how long until one is free
+func takeToken(bucket *tokenBucket, limit RequestRate, now time.Time) (bool, time.Duration) {
+	if now.After(bucket.at) {
+		bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.at).Seconds()*limit.QPS)
+		bucket.at = now
+	}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	return false, time.Duration(math.Ceil((1-bucket.tokens)/limit.QPS*1000)) * time.Millisecond
+}
+
+// evictIdle drops full buckets, which behave the same as new ones
+func (m *memoryBuckets) evictIdle(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	removed := 0
+	for key, bucket := range m.buckets {
+		if !bucket.full.After(now) {
+			delete(m.buckets, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (m *memoryBuckets) len(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets), nil
+}
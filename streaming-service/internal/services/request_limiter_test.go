@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+func TestNewRequestLimiter(t *testing.T) {
+	if limiter, err := NewRequestLimiter(&config.Config{}); limiter != nil || err != nil {
+		t.Errorf("Expected no limiter without limits, got %v (%v)", limiter, err)
+	}
+	if _, err := NewRequestLimiter(&config.Config{RateLimitIPQPS: 1, RateLimitBackend: RateLimitBackendRedis}); err == nil {
+		t.Error("Expected an error for the redis backend without an address")
+	}
+	if _, err := NewRequestLimiter(&config.Config{RateLimitIPQPS: 1, RateLimitBackend: "memcached"}); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+func TestRequestLimiter_Memory(t *testing.T) {
+	limiter, err := NewRequestLimiter(&config.Config{RateLimitIPQPS: 2, RateLimitIPBurst: 2, RateLimitUserQPS: 1, RateLimitUserBurst: 1})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+
+	if ok, _ := limiter.Allow("1.2.3.4", ""); !ok {
+		t.Fatal("Expected the first request to pass")
+	}
+	if ok, _ := limiter.Allow("1.2.3.4", ""); !ok {
+		t.Fatal("Expected the burst to allow a second request")
+	}
+	ok, wait := limiter.Allow("1.2.3.4", "")
+	if ok || wait <= 0 || wait > 500*time.Millisecond {
+		t.Errorf("Expected the third request to wait up to 500ms, got %v %v", ok, wait)
+	}
+
+	// Another IP has its own bucket, but the user's bucket is shared across IPs
+	if ok, _ := limiter.Allow("5.6.7.8", "key:ops"); !ok {
+		t.Error("Expected a new IP and user to pass")
+	}
+	if ok, _ := limiter.Allow("9.9.9.9", "key:ops"); ok {
+		t.Error("Expected the user to be limited from another IP")
+	}
+
+	stats := limiter.Stats()
+	if stats.Backend != RateLimitBackendMemory || stats.Allowed != 3 || stats.Limited != 2 || stats.Buckets != 4 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestMemoryBuckets_EvictIdle(t *testing.T) {
+	buckets := newMemoryBuckets()
+	start := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	limit := RequestRate{QPS: 1, Burst: 5}
+	buckets.take("busy", limit, start)
+	buckets.take("busy", limit, start)
+	buckets.take("idle", limit, start.Add(-time.Minute))
+
+	buckets.now = func() time.Time { return start }
+	if removed, _ := buckets.evictIdle(context.Background()); removed != 1 {
+		t.Errorf("Expected only the refilled bucket to be evicted, got %d", removed)
+	}
+	buckets.now = func() time.Time { return start.Add(2 * time.Second) }
+	if removed, _ := buckets.evictIdle(context.Background()); removed != 1 {
+		t.Errorf("Expected the busy bucket to be evicted once full, got %d", removed)
+	}
+}
+
+// fakeRedis speaks like a Redis 5 without the script cached: it answers scripts with reply
+// and records them, rejects HELLO and acknowledges any other command
+func fakeRedis(t *testing.T, reply string) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	commands := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readCommand(reader)
+					if err != nil {
+						return
+					}
+					switch command := strings.ToUpper(args[0]); command {
+					case "HELLO":
+						conn.Write([]byte("-ERR unknown command 'HELLO'\r\n"))
+					case "EVALSHA":
+						commands <- command
+						conn.Write([]byte("-NOSCRIPT No matching script. Please use EVAL.\r\n"))
+					case "EVAL":
+						commands <- command
+						conn.Write([]byte(reply))
+					default:
+						conn.Write([]byte("+OK\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), commands
+}
+
+// readCommand reads a command sent by a client, an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("expected an array, got %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
+
+func TestRequestLimiter_Redis(t *testing.T) {
+	addr, commands := fakeRedis(t, "*2\r\n:0\r\n:1500\r\n")
+	limiter, err := NewRequestLimiter(&config.Config{RateLimitIPQPS: 1, RateLimitBackend: RateLimitBackendRedis, RedisAddr: addr})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	ok, wait := limiter.Allow("1.2.3.4", "")
+	if ok || wait != 1500*time.Millisecond {
+		t.Errorf("Expected the bucket in Redis to limit the request for 1.5s, got %v %v", ok, wait)
+	}
+	if command := <-commands; command != "EVALSHA" {
+		t.Errorf("Expected the script to be run by its SHA first, got %s", command)
+	}
+	if command := <-commands; command != "EVAL" {
+		t.Errorf("Expected the script to be sent once Redis did not know it, got %s", command)
+	}
+}
+
+func TestRequestLimiter_RedisDownFallsBackToMemory(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	limiter, err := NewRequestLimiter(&config.Config{RateLimitIPQPS: 1, RateLimitIPBurst: 1, RateLimitBackend: RateLimitBackendRedis, RedisAddr: addr})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+
+	if ok, _ := limiter.Allow("1.2.3.4", ""); !ok {
+		t.Error("Expected the request to pass on the memory buckets")
+	}
+	if ok, _ := limiter.Allow("1.2.3.4", ""); ok {
+		t.Error("Expected the memory buckets to limit the second request")
+	}
+	if stats := limiter.Stats(); stats.RedisErrors != 2 {
+		t.Errorf("Expected both Redis failures counted, got %+v", stats)
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a rendered HTTP response body and headers with the validators it is
+// served with
+type CachedResponse struct {
+	Body         []byte
+	Header       http.Header
+	ETag         string
+	LastModified time.Time
+	expiresAt    time.Time
+}
+
+// ResponseCache keeps rendered responses keyed by request for a short TTL, so dashboards
+// polling the same query share one calculation. A response recalculated unchanged keeps its
+// Last-Modified time until it is evicted, so clients revalidating it still get a 304.
+type ResponseCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*CachedResponse
+	now     func() time.Time
+}
+
+func NewResponseCache(ttl time.Duration, maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*CachedResponse),
+		now:        time.Now,
+	}
+}
+
+// TTL returns how long responses are served from the cache
+func (c *ResponseCache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Get returns the cached response of a request unless it expired
+func (c *ResponseCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Set caches the response of a request and returns it with its validators
+func (c *ResponseCache) Set(key string, body []byte, header http.Header) *CachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	sum := sha256.Sum256(body)
+	entry := &CachedResponse{
+		Body:         body,
+		Header:       header.Clone(),
+		ETag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		LastModified: now.UTC().Truncate(time.Second),
+		expiresAt:    now.Add(c.ttl),
+	}
+	if previous, ok := c.entries[key]; ok && previous.ETag == entry.ETag {
+		entry.LastModified = previous.LastModified
+	} else if !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evictLocked(len(c.entries) - c.maxEntries + 1 + c.maxEntries/memoryCacheEvictFraction)
+	}
+	c.entries[key] = entry
+	return entry
+}
+
+// Len returns the number of cached responses, including expired ones not yet evicted
+func (c *ResponseCache) Len(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), nil
+}
+
+// EvictExpired removes expired responses and trims the cache to its size bound, returning the
+// number of responses removed
+func (c *ResponseCache) EvictExpired(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	excess := 0
+	if c.maxEntries > 0 && len(c.entries) > c.maxEntries {
+		excess = len(c.entries) - c.maxEntries
+	}
+	return c.evictLocked(excess), nil
+}
+
+// evictLocked removes expired responses, then the responses closest to expiry until at least
+// minRemoved are gone; callers hold the lock
+func (c *ResponseCache) evictLocked(minRemoved int) int {
+	now := c.now()
+	removed := 0
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	if removed >= minRemoved {
+		return removed
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].expiresAt.Before(c.entries[keys[j]].expiresAt)
+	})
+	for _, key := range keys[:min(minRemoved-removed, len(keys))] {
+		delete(c.entries, key)
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+	cache := NewResponseCache(10*time.Second, 0)
+	cache.now = func() time.Time { return now }
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	first := cache.Set("/dashboard/metrics?", []byte(`{"views":1}`), header)
+	if first.ETag == "" || !first.LastModified.Equal(now) || first.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected cached response %+v", first)
+	}
+	if cached, ok := cache.Get("/dashboard/metrics?"); !ok || cached != first {
+		t.Error("Expected the cached response before it expires")
+	}
+
+	// Recalculated unchanged after expiry, the response keeps its validators
+	now = now.Add(11 * time.Second)
+	if _, ok := cache.Get("/dashboard/metrics?"); ok {
+		t.Error("Expected no response after the TTL")
+	}
+	same := cache.Set("/dashboard/metrics?", []byte(`{"views":1}`), header)
+	if same.ETag != first.ETag || !same.LastModified.Equal(first.LastModified) {
+		t.Errorf("Expected unchanged validators, got %+v", same)
+	}
+	changed := cache.Set("/dashboard/metrics?", []byte(`{"views":2}`), header)
+	if changed.ETag == first.ETag || !changed.LastModified.Equal(now) {
+		t.Errorf("Expected new validators for a changed response, got %+v", changed)
+	}
+
+	cache.Set("/trending?limit=5", []byte(`[]`), header)
+	now = now.Add(11 * time.Second)
+	if removed, _ := cache.EvictExpired(context.Background()); removed != 2 {
+		t.Errorf("Expected 2 expired responses evicted, got %d", removed)
+	}
+	if size, _ := cache.Len(context.Background()); size != 0 {
+		t.Errorf("Expected an empty cache, got %d responses", size)
+	}
+}
+
+func TestResponseCache_MaxEntries(t *testing.T) {
+	cache := NewResponseCache(time.Minute, 2)
+	cache.Set("a", []byte("a"), nil)
+	cache.Set("b", []byte("b"), nil)
+	cache.Set("c", []byte("c"), nil)
+
+	if size, _ := cache.Len(context.Background()); size != 2 {
+		t.Errorf("Expected the cache trimmed to 2 responses, got %d", size)
+	}
+	if _, ok := cache.Get("c"); !ok {
+		t.Error("Expected the newest response to be cached")
+	}
+}
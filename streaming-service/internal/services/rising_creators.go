@@ -0,0 +1,205 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// Period whose engagement is compared with the one before it
+	risingCreatorsPeriod = 7 * 24 * time.Hour
+
+	// Daily engagement snapshots are kept for two periods so the previous one can be compared
+	creatorSnapshotRetention = 2*risingCreatorsPeriod + 24*time.Hour
+)
+
+// creatorEngagementSnapshot holds a creator's cumulative engagement on one UTC day, as of the
+// last tier classification that day
+type creatorEngagementSnapshot struct {
+	UserID     string
+	Day        time.Time
+	TakenAt    time.Time
+	Tier       string
+	Views      int64
+	Engagement int64 // likes and comments
+	ExpiresAt  time.Time
+}
+
+// RisingCreator is a creator ranked by how much their engagement grew over the last week
+type RisingCreator struct {
+	UserID             string  `json:"userId"`
+	Username           string  `json:"username"`
+	DisplayName        string  `json:"displayName"`
+	PhotoURL           string  `json:"photoURL"`
+	Tier               string  `json:"tier"`
+	Engagement         int64   `json:"engagement"`         // likes and comments in the last week
+	PreviousEngagement int64   `json:"previousEngagement"` // likes and comments in the week before
+	Views              int64   `json:"views"`              // views in the last week
+	Growth             float64 `json:"growth"`
+}
+
+// SaveCreatorEngagementSnapshot stores a creator's cumulative engagement for today, replacing
+// the snapshot taken earlier the same day
+func (fc *FirestoreClient) SaveCreatorEngagementSnapshot(creator CreatorMetrics) error {
+	day := creator.CalculatedAt.UTC().Truncate(24 * time.Hour)
+
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("creator_engagement").Doc(fmt.Sprintf("%s_%s", creator.UserID, day.Format("20060102"))).Set(fc.ctx, creatorEngagementSnapshot{
+		UserID:     creator.UserID,
+		Day:        day,
+		TakenAt:    creator.CalculatedAt,
+		Tier:       creator.Tier,
+		Views:      creator.TotalViews,
+		Engagement: creator.TotalLikes + creator.TotalComments,
+		ExpiresAt:  day.Add(creatorSnapshotRetention),
+	})
+	return err
+}
+
+// GetRisingCreators returns the creators whose engagement grew most over the last week compared
+// with the week before. Creators with less than minEngagement in the last week are left out.
+func (da *DashboardAnalytics) GetRisingCreators(minEngagement int64, limit int) ([]RisingCreator, error) {
+	logger.Debug("📊 Calculating rising creators...")
+
+	now := time.Now()
+	Quotas.Record(QuotaFirestore, 1)
+	iter := da.firestoreClient.client.Collection("creator_engagement").
+		Where("Day", ">=", now.Add(-creatorSnapshotRetention).UTC().Truncate(24*time.Hour)).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	var snapshots []creatorEngagementSnapshot
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var snapshot creatorEngagementSnapshot
+		if err := doc.DataTo(&snapshot); err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	rising := rankRisingCreators(snapshots, now, minEngagement, limit)
+	for i := range rising {
+		da.enrichRisingCreator(&rising[i])
+	}
+
+	logger.Infof("✅ Rising creators calculated: %d creators", len(rising))
+	return rising, nil
+}
+
+// rankRisingCreators turns cumulative snapshots into the engagement of the last period and the
+// one before, and orders growing creators by growth, then by engagement. Nothing is ranked
+// until snapshots reach a week back; a creator without a snapshot old enough counts as having
+// had no engagement yet.
+func rankRisingCreators(snapshots []creatorEngagementSnapshot, now time.Time, minEngagement int64, limit int) []RisingCreator {
+	if len(snapshots) == 0 {
+		return []RisingCreator{}
+	}
+
+	historyStart := snapshots[0].TakenAt
+	byCreator := make(map[string][]creatorEngagementSnapshot)
+	for _, snapshot := range snapshots {
+		if snapshot.TakenAt.Before(historyStart) {
+			historyStart = snapshot.TakenAt
+		}
+		byCreator[snapshot.UserID] = append(byCreator[snapshot.UserID], snapshot)
+	}
+
+	weekAgo := now.Add(-risingCreatorsPeriod)
+	twoWeeksAgo := weekAgo.Add(-risingCreatorsPeriod)
+	if historyStart.After(weekAgo) {
+		return []RisingCreator{}
+	}
+
+	rising := []RisingCreator{}
+	for userID, creatorSnapshots := range byCreator {
+		sort.Slice(creatorSnapshots, func(i, j int) bool {
+			return creatorSnapshots[i].TakenAt.Before(creatorSnapshots[j].TakenAt)
+		})
+		latest := creatorSnapshots[len(creatorSnapshots)-1]
+
+		atWeekAgo, _ := snapshotAt(creatorSnapshots, weekAgo)
+		atTwoWeeksAgo, known := snapshotAt(creatorSnapshots, twoWeeksAgo)
+		if !known && historyStart.After(twoWeeksAgo) {
+			// Snapshots cover only part of the previous week; it starts at the creator's first one
+			atTwoWeeksAgo = creatorSnapshots[0]
+			if !atTwoWeeksAgo.TakenAt.Before(weekAgo) {
+				atTwoWeeksAgo = atWeekAgo
+			}
+		}
+
+		creator := RisingCreator{
+			UserID:             userID,
+			Tier:               latest.Tier,
+			Engagement:         max(latest.Engagement-atWeekAgo.Engagement, 0),
+			PreviousEngagement: max(atWeekAgo.Engagement-atTwoWeeksAgo.Engagement, 0),
+			Views:              max(latest.Views-atWeekAgo.Views, 0),
+		}
+		if creator.Engagement < minEngagement || creator.Engagement <= creator.PreviousEngagement {
+			continue
+		}
+		// Creators new this week grow from a baseline of one engagement
+		creator.Growth = float64(creator.Engagement-creator.PreviousEngagement) / math.Max(float64(creator.PreviousEngagement), 1)
+		rising = append(rising, creator)
+	}
+
+	sort.Slice(rising, func(i, j int) bool {
+		if rising[i].Growth != rising[j].Growth {
+			return rising[i].Growth > rising[j].Growth
+		}
+		if rising[i].Engagement != rising[j].Engagement {
+			return rising[i].Engagement > rising[j].Engagement
+		}
+		return rising[i].UserID < rising[j].UserID
+	})
+	if len(rising) > limit {
+		rising = rising[:limit]
+	}
+	return rising
+}
+
+// snapshotAt returns the last snapshot taken at or before t from snapshots sorted by time; ok
+// is false when there is none, which leaves a zero snapshot
+func snapshotAt(snapshots []creatorEngagementSnapshot, t time.Time) (creatorEngagementSnapshot, bool) {
+	var found creatorEngagementSnapshot
+	ok := false
+	for _, snapshot := range snapshots {
+		if snapshot.TakenAt.After(t) {
+			break
+		}
+		found, ok = snapshot, true
+	}
+	return found, ok
+}
+
+// enrichRisingCreator adds the profile details of a creator
+func (da *DashboardAnalytics) enrichRisingCreator(creator *RisingCreator) {
+	Quotas.Record(QuotaFirestore, 1)
+	userDoc, err := da.firestoreClient.client.Collection("users").Doc(creator.UserID).Get(da.ctx)
+	if err != nil {
+		return
+	}
+
+	userData := userDoc.Data()
+	if username, ok := userData["username"].(string); ok {
+		creator.Username = username
+	}
+	if displayName, ok := userData["displayName"].(string); ok {
+		creator.DisplayName = displayName
+	}
+	if photoURL, ok := userData["photoURL"].(string); ok {
+		creator.PhotoURL = photoURL
+	}
+}
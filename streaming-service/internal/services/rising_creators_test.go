@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankRisingCreators(t *testing.T) {
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+	snap := func(userID string, daysAgo int, engagement int64) creatorEngagementSnapshot {
+		return creatorEngagementSnapshot{UserID: userID, TakenAt: now.AddDate(0, 0, -daysAgo), Engagement: engagement, Views: engagement * 10}
+	}
+
+	snapshots := []creatorEngagementSnapshot{
+		// Big but flat: 1000 engagements in each week
+		snap("star", 15, 10000), snap("star", 8, 11000), snap("star", 0, 12000),
+		// Small but doubling: 50 then 100
+		snap("riser", 15, 100), snap("riser", 8, 150), snap("riser", 0, 250),
+		// New this week, from nothing to 40
+		snap("newcomer", 2, 10), snap("newcomer", 0, 40),
+		// Growing but below the activity threshold
+		snap("quiet", 15, 0), snap("quiet", 8, 2), snap("quiet", 0, 10),
+	}
+
+	rising := rankRisingCreators(snapshots, now, 20, 10)
+	if len(rising) != 2 {
+		t.Fatalf("expected 2 rising creators, got %+v", rising)
+	}
+	if rising[0].UserID != "newcomer" || rising[0].Engagement != 40 || rising[0].PreviousEngagement != 0 || rising[0].Growth != 40 {
+		t.Errorf("expected newcomer first with growth 40, got %+v", rising[0])
+	}
+	if rising[1].UserID != "riser" || rising[1].Engagement != 100 || rising[1].PreviousEngagement != 50 || rising[1].Growth != 1 || rising[1].Views != 1000 {
+		t.Errorf("expected riser second with growth 1, got %+v", rising[1])
+	}
+
+	if limited := rankRisingCreators(snapshots, now, 20, 1); len(limited) != 1 {
+		t.Errorf("expected limit to apply, got %d", len(limited))
+	}
+}
+
+func TestRankRisingCreatorsNeedsAWeekOfHistory(t *testing.T) {
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+	snapshots := []creatorEngagementSnapshot{
+		{UserID: "a", TakenAt: now.AddDate(0, 0, -3), Engagement: 10},
+		{UserID: "a", TakenAt: now, Engagement: 500},
+	}
+	if rising := rankRisingCreators(snapshots, now, 1, 10); len(rising) != 0 {
+		t.Errorf("expected no ranking before snapshots reach a week back, got %+v", rising)
+	}
+
+	// With only part of the previous week covered it starts at the creator's first snapshot
+	snapshots = append(snapshots, creatorEngagementSnapshot{UserID: "b", TakenAt: now.AddDate(0, 0, -10), Engagement: 100},
+		creatorEngagementSnapshot{UserID: "b", TakenAt: now.AddDate(0, 0, -7), Engagement: 120},
+		creatorEngagementSnapshot{UserID: "b", TakenAt: now, Engagement: 200})
+	rising := rankRisingCreators(snapshots, now, 1, 10)
+	if len(rising) != 2 || rising[1].UserID != "b" || rising[1].PreviousEngagement != 20 || rising[1].Engagement != 80 {
+		t.Errorf("expected b with 20 then 80 engagements, got %+v", rising)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// Ways rising posts are ranked: by the trending score gained over the window, or by the score
+// gained per hour since the post started gaining in it
+const (
+	RisingByDelta    = "delta"
+	RisingByVelocity = "velocity"
+)
+
+// MaxRisingHours is the longest window rising posts are ranked over
+const MaxRisingHours = 72
+
+// RisingPost is a post ranked by how much its trending score grew over the last hours
+type RisingPost struct {
+	PostID          string    `json:"postId"`
+	ContentType     string    `json:"contentType,omitempty"`
+	Title           string    `json:"title,omitempty"`
+	Score           float64   `json:"score"`
+	ScoreDelta      float64   `json:"scoreDelta"`
+	Velocity        float64   `json:"velocity"` // score gained per hour
+	ViewDelta       int64     `json:"viewDelta"`
+	EngagementDelta int64     `json:"engagementDelta"` // likes, comments, shares and remixes
+	RisingSince     time.Time `json:"risingSince"`
+}
+
+// GetScoreSnapshotsSince returns the hourly score snapshots of every post since the hour from
+// falls in, by post. The collection group query needs the collection group index on Hour in
+// firestore.indexes.json.
+func (fc *FirestoreClient) GetScoreSnapshotsSince(from time.Time) (map[string][]scoreSnapshot, error) {
+	docs, err := fc.client.CollectionGroup("score_history").
+		Where("Hour", ">=", rollupStart(RollupHour, from, fc.reportingLoc)).
+		Documents(fc.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	byPost := make(map[string][]scoreSnapshot)
+	for _, doc := range docs {
+		var snapshot scoreSnapshot
+		if err := doc.DataTo(&snapshot); err != nil {
+			logger.Debugf(" Skipping unreadable score snapshot %s: %v", doc.Ref.Path, err)
+			continue
+		}
+		// trending_scores/{postID}/score_history/{hour}
+		postID := doc.Ref.Parent.Parent.ID
+		byPost[postID] = append(byPost[postID], snapshot)
+	}
+	return byPost, nil
+}
+
+// GetRisingPosts returns the posts whose trending score grew most over the last hours clock
+// hours, the current one included, ranked by delta or velocity
+func (da *DashboardAnalytics) GetRisingPosts(hours, limit int, by string) ([]RisingPost, error) {
+	logger.Debugf("📊 Calculating rising posts over %d hours...", hours)
+
+	now := time.Now()
+	start := rollupStart(RollupHour, now, da.firestoreClient.reportingLoc).Add(-time.Duration(hours-1) * time.Hour)
+	snapshots, err := da.firestoreClient.GetScoreSnapshotsSince(start)
+	if err != nil {
+		return nil, err
+	}
+
+	rising := rankRisingPosts(snapshots, start, now, by, limit)
+	for i := range rising {
+		post := &rising[i]
+		score, err := da.firestoreClient.GetPostStats(post.PostID)
+		if err != nil {
+			logger.Debugf(" Rising post %s left without details: %v", post.PostID, err)
+			continue
+		}
+		if score == nil {
+			continue
+		}
+		post.ContentType, post.Title = score.ContentType, score.Title
+	}
+
+	logger.Infof("✅ Rising posts calculated: %d posts", len(rising))
+	return rising, nil
+}
+
+// rankRisingPosts adds up what each post gained over the hourly snapshots since start and
+// orders the posts that gained score by delta or velocity, then by delta and post ID. Velocity
+// is measured from start, or from the first hour a post gained in when that is later, and
+// over at least an hour.
+func rankRisingPosts(snapshots map[string][]scoreSnapshot, start, now time.Time, by string, limit int) []RisingPost {
+	rising := []RisingPost{}
+	for postID, postSnapshots := range snapshots {
+		sort.Slice(postSnapshots, func(i, j int) bool { return postSnapshots[i].Hour.Before(postSnapshots[j].Hour) })
+
+		post := RisingPost{PostID: postID, RisingSince: start}
+		first := true
+		for _, snapshot := range postSnapshots {
+			if snapshot.Hour.Before(start) {
+				continue
+			}
+			if first && snapshot.Hour.After(start) {
+				post.RisingSince = snapshot.Hour
+			}
+			first = false
+			post.Score = snapshot.Score
+			post.ScoreDelta += snapshot.ScoreGain
+			post.ViewDelta += snapshot.ViewGain
+			post.EngagementDelta += snapshot.EngagementGain
+		}
+		if post.ScoreDelta <= 0 {
+			continue
+		}
+		post.Velocity = post.ScoreDelta / max(now.Sub(post.RisingSince).Hours(), 1)
+		rising = append(rising, post)
+	}
+
+	sort.Slice(rising, func(i, j int) bool {
+		if by == RisingByVelocity && rising[i].Velocity != rising[j].Velocity {
+			return rising[i].Velocity > rising[j].Velocity
+		}
+		if rising[i].ScoreDelta != rising[j].ScoreDelta {
+			return rising[i].ScoreDelta > rising[j].ScoreDelta
+		}
+		return rising[i].PostID < rising[j].PostID
+	})
+	if len(rising) > limit {
+		rising = rising[:limit]
+	}
+	return rising
+}
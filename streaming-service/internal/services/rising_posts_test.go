@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankRisingPosts(t *testing.T) {
+	start := time.Date(2024, 5, 10, 6, 0, 0, 0, time.UTC)
+	now := start.Add(5*time.Hour + 30*time.Minute)
+	snapshots := map[string][]scoreSnapshot{
+		// Old post with a high score, gaining slowly all window; the gain before it is ignored
+		"steady": {
+			{Hour: start.Add(-time.Hour), Score: 500, ScoreGain: 100},
+			{Hour: start.Add(3 * time.Hour), Score: 530, ScoreGain: 20, ViewGain: 40, EngagementGain: 4},
+			{Hour: start, Score: 510, ScoreGain: 10, ViewGain: 10},
+		},
+		// New post breaking in the last hours
+		"breaking": {
+			{Hour: start.Add(4 * time.Hour), Score: 12, ScoreGain: 12, ViewGain: 50, EngagementGain: 9},
+			{Hour: start.Add(5 * time.Hour), Score: 25, ScoreGain: 13, ViewGain: 60, EngagementGain: 11},
+		},
+		// Decaying post
+		"fading": {{Hour: start.Add(2 * time.Hour), Score: 80, ScoreGain: -5}},
+	}
+
+	rising := rankRisingPosts(snapshots, start, now, RisingByDelta, 10)
+	if len(rising) != 2 {
+		t.Fatalf("Expected 2 rising posts, got %+v", rising)
+	}
+	steady := rising[0]
+	if steady.PostID != "steady" || steady.Score != 530 || steady.ScoreDelta != 30 || steady.ViewDelta != 50 || steady.EngagementDelta != 4 {
+		t.Errorf("Unexpected steady post %+v", steady)
+	}
+	if !steady.RisingSince.Equal(start) || steady.Velocity != 30/5.5 {
+		t.Errorf("Expected a velocity over the whole window, got %+v", steady)
+	}
+
+	breaking := rising[1]
+	if breaking.PostID != "breaking" || breaking.ScoreDelta != 25 || breaking.ViewDelta != 110 || breaking.EngagementDelta != 20 {
+		t.Errorf("Unexpected breaking post %+v", breaking)
+	}
+	if !breaking.RisingSince.Equal(start.Add(4*time.Hour)) || breaking.Velocity != 25/1.5 {
+		t.Errorf("Expected a velocity since the post started gaining, got %+v", breaking)
+	}
+
+	byVelocity := rankRisingPosts(snapshots, start, now, RisingByVelocity, 1)
+	if len(byVelocity) != 1 || byVelocity[0].PostID != "breaking" {
+		t.Errorf("Expected the breaking post first by velocity, got %+v", byVelocity)
+	}
+
+	if empty := rankRisingPosts(nil, start, now, RisingByDelta, 10); len(empty) != 0 {
+		t.Errorf("Expected no rising posts without snapshots, got %+v", empty)
+	}
+}
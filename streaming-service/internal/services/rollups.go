@@ -0,0 +1,313 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+)
+
+// Intervals engagement is rolled up over
+const (
+	RollupHour = "hour"
+	RollupDay  = "day"
+)
+
+// Retention of the rollups. Hourly rollups of every post back the dashboard's time ranges and
+// are kept as long as its buckets; those of single posts for a month.
+const (
+	globalHourlyRollupRetention = dashboardBucketRetention
+	postHourlyRollupRetention   = 31 * 24 * time.Hour
+	dailyRollupRetention        = 400 * 24 * time.Hour
+)
+
+// EngagementRollup is the engagement of a post, or of every post, during one hour or day
+type EngagementRollup struct {
+	PostID    string    `json:"postId,omitempty"` // empty for the rollups of every post
+	Start     time.Time `json:"start"`
+	Views     int64     `json:"views"`
+	Likes     int64     `json:"likes"`
+	Comments  int64     `json:"comments"`
+	Shares    int64     `json:"shares"`
+	Remixes   int64     `json:"remixes"`
+	Posts     int64     `json:"posts,omitempty"` // new posts, in the rollups of every post only
+	ExpiresAt time.Time `json:"-"`               // retention cutoff for the rollup
+}
+
+// rollupKey identifies a rollup document
+type rollupKey struct {
+	postID   string // empty for the rollups of every post
+	interval string
+	start    time.Time
+}
+
+// RollupService maintains hourly and daily engagement rollups, per post and of every post,
+// from consumed events. Events are counted in the period they happened in and buffered in
+// memory; each flush adds them to the stored rollups with increments, so several instances
+// can flush the same period safely. Days are calendar days in the reporting time zone.
+type RollupService struct {
+	firestoreClient *FirestoreClient
+	flusher         *periodicFlusher
+
+	mu      sync.Mutex
+	pending map[rollupKey]map[string]int64 // rollup -> field -> count since the last flush
+}
+
+// NewRollupService creates a rollup service that flushes every flushInterval
+func NewRollupService(firestoreClient *FirestoreClient, flushInterval time.Duration) *RollupService {
+	rs := &RollupService{
+		firestoreClient: firestoreClient,
+		pending:         make(map[rollupKey]map[string]int64),
+	}
+	rs.flusher = newPeriodicFlusher("engagement rollups", flushInterval, rs.Flush)
+	return rs
+}
+
+// Start adds the buffered counts to the stored hourly and daily rollups every flush interval
+func (rs *RollupService) Start() {
+	logger.Infof("📈 Starting engagement rollups (flush interval %v)", rs.flusher.interval)
+	rs.flusher.start()
+}
+
+// Stop ends the periodic increments and adds the counts still buffered
+func (rs *RollupService) Stop() {
+	rs.flusher.stop()
+}
+
+// RecordInteraction counts an interaction with a post at the time it happened
+func (rs *RollupService) RecordInteraction(postID, eventType string, at time.Time) {
+	switch eventType {
+	case "view":
+		rs.record(postID, "Views", at)
+	case "like":
+		rs.record(postID, "Likes", at)
+	case "comment":
+		rs.record(postID, "Comments", at)
+	case "share":
+		rs.record(postID, "Shares", at)
+	}
+}
+
+// RecordView counts a view of a post at the time it happened
+func (rs *RollupService) RecordView(postID string, at time.Time) {
+	rs.record(postID, "Views", at)
+}
+
+// RecordRemix counts a remix of a post at the time it happened
+func (rs *RollupService) RecordRemix(postID string, at time.Time) {
+	rs.record(postID, "Remixes", at)
+}
+
+// RecordPost counts a new post at the time it was created
+func (rs *RollupService) RecordPost(at time.Time) {
+	rs.record("", "Posts", at)
+}
+
+// record adds one to a field of the rollups of every post and, when postID is set, of the
+// post, for the hour and day of at
+func (rs *RollupService) record(postID, field string, at time.Time) {
+	if rs == nil {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for _, interval := range []string{RollupHour, RollupDay} {
+		start := rollupStart(interval, at, rs.firestoreClient.reportingLoc)
+		rs.add(rollupKey{interval: interval, start: start}, field, 1)
+		if postID != "" {
+			rs.add(rollupKey{postID: postID, interval: interval, start: start}, field, 1)
+		}
+	}
+}
+
+// add adds to a field of a pending rollup; the caller holds rs.mu
+func (rs *RollupService) add(key rollupKey, field string, n int64) {
+	counts, ok := rs.pending[key]
+	if !ok {
+		counts = make(map[string]int64)
+		rs.pending[key] = counts
+	}
+	counts[field] += n
+}
+
+// Flush adds the counts recorded since the last flush to the stored rollups
+func (rs *RollupService) Flush() error {
+	rs.mu.Lock()
+	pending := rs.pending
+	rs.pending = make(map[rollupKey]map[string]int64)
+	rs.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	failed, err := rs.firestoreClient.IncrementRollups(pending)
+	if len(failed) > 0 {
+		// Keep the counts for the next flush
+		rs.mu.Lock()
+		for _, key := range failed {
+			for field, n := range pending[key] {
+				rs.add(key, field, n)
+			}
+		}
+		rs.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("📈 Flushed %d engagement rollups", len(pending))
+	return nil
+}
+
+// rollupStart returns the start of the hour or of the day in loc that t falls in
+func rollupStart(interval string, t time.Time, loc *time.Location) time.Time {
+	if interval == RollupDay {
+		return StartOfDay(t, loc)
+	}
+	return t.UTC().Truncate(time.Hour)
+}
+
+// rollupStarts returns the starts of the periods of an interval from the one from falls in
+// up to to
+func rollupStarts(interval string, from, to time.Time, loc *time.Location) []time.Time {
+	var starts []time.Time
+	for start := rollupStart(interval, from, loc); start.Before(to); {
+		starts = append(starts, start)
+		if interval == RollupDay {
+			// AddDate keeps days that gain or lose an hour to DST at their real length
+			start = start.AddDate(0, 0, 1)
+		} else {
+			start = start.Add(time.Hour)
+		}
+	}
+	return starts
+}
+
+// rollupRef returns the document of a rollup
+func (fc *FirestoreClient) rollupRef(key rollupKey) *firestore.DocumentRef {
+	collection, id := "engagement_rollups_hourly", key.start.UTC().Format("2006010215")
+	if key.interval == RollupDay {
+		collection, id = "engagement_rollups_daily", key.start.Format("20060102")
+	}
+	if key.postID != "" {
+		collection, id = "post_"+collection, key.postID+"_"+id
+	}
+	return fc.client.Collection(collection).Doc(id)
+}
+
+// rollupRetention returns how long a rollup is kept after its period starts
+func rollupRetention(key rollupKey) time.Duration {
+	switch {
+	case key.interval == RollupDay:
+		return dailyRollupRetention
+	case key.postID != "":
+		return postHourlyRollupRetention
+	default:
+		return globalHourlyRollupRetention
+	}
+}
+
+// IncrementRollups adds counts to the fields of stored rollups, creating the missing ones. It
+// returns the rollups whose write failed with the last error.
+func (fc *FirestoreClient) IncrementRollups(counts map[rollupKey]map[string]int64) ([]rollupKey, error) {
+	bw := fc.client.BulkWriter(fc.ctx)
+	keys := make([]rollupKey, 0, len(counts))
+	jobs := make([]*firestore.BulkWriterJob, 0, len(counts))
+	var failed []rollupKey
+	var lastErr error
+	for key, fields := range counts {
+		data := map[string]interface{}{
+			"Start":     key.start,
+			"ExpiresAt": key.start.Add(rollupRetention(key)),
+		}
+		if key.postID != "" {
+			data["PostID"] = key.postID
+		}
+		for field, n := range fields {
+			data[field] = firestore.Increment(n)
+		}
+
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(fc.rollupRef(key), data, firestore.MergeAll)
+		if err != nil {
+			failed, lastErr = append(failed, key), err
+			continue
+		}
+		keys = append(keys, key)
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			failed, lastErr = append(failed, keys[i]), err
+		}
+	}
+	return failed, lastErr
+}
+
+// GetEngagementRollups returns the rollups of a post, or of every post when postID is empty,
+// for the periods of an interval starting at starts. Periods without engagement are zero.
+func (fc *FirestoreClient) GetEngagementRollups(postID, interval string, starts []time.Time) ([]EngagementRollup, error) {
+	refs := make([]*firestore.DocumentRef, len(starts))
+	for i, start := range starts {
+		refs[i] = fc.rollupRef(rollupKey{postID: postID, interval: interval, start: start})
+	}
+	Quotas.Record(QuotaFirestore, int64(len(refs)))
+	docs, err := fc.client.GetAll(fc.ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	rollups := make([]EngagementRollup, len(starts))
+	for i, doc := range docs {
+		if doc.Exists() {
+			if err := doc.DataTo(&rollups[i]); err != nil {
+				logger.Debugf(" Skipping unreadable rollup %s: %v", doc.Ref.ID, err)
+			}
+		}
+		rollups[i].PostID = postID
+		rollups[i].Start = starts[i]
+	}
+	return rollups, nil
+}
+
+// GetPostTimeseries returns a post's engagement over the last periods of an interval, oldest
+// first, ending with the current hour or day
+func (fc *FirestoreClient) GetPostTimeseries(postID, interval string, periods int, now time.Time) ([]EngagementRollup, error) {
+	var from time.Time
+	if interval == RollupDay {
+		from = StartOfDay(now, fc.reportingLoc).AddDate(0, 0, 1-periods)
+	} else {
+		from = now.Add(-time.Duration(periods-1) * time.Hour)
+	}
+	return fc.GetEngagementRollups(postID, interval, rollupStarts(interval, from, now, fc.reportingLoc))
+}
+
+// engagementTrends sums rollups into one engagement trend per calendar day in loc from the day
+// of from up to to. Rollups are assigned to the day they start in.
+func engagementTrends(rollups []EngagementRollup, from, to time.Time, loc *time.Location) []EngagementTrend {
+	trends := []EngagementTrend{}
+	days := make(map[string]int)
+	for _, day := range rollupStarts(RollupDay, from, to, loc) {
+		days[ReportingDate(day, loc)] = len(trends)
+		trends = append(trends, EngagementTrend{Date: day})
+	}
+
+	for _, rollup := range rollups {
+		i, ok := days[ReportingDate(rollup.Start, loc)]
+		if !ok {
+			continue
+		}
+		trends[i].PostCount += int(rollup.Posts)
+		trends[i].Views += rollup.Views
+		trends[i].Likes += rollup.Likes
+		trends[i].Comments += rollup.Comments
+	}
+	return trends
+}
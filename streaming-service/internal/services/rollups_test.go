@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollupService_Record(t *testing.T) {
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+	rs := NewRollupService(&FirestoreClient{reportingLoc: istanbul}, time.Minute)
+
+	// 22:30 UTC is already the next day in Istanbul
+	at := time.Date(2024, 5, 10, 22, 30, 0, 0, time.UTC)
+	rs.RecordView("post-1", at)
+	rs.RecordInteraction("post-1", "like", at)
+	rs.RecordInteraction("post-1", "bookmark", at)
+	rs.RecordPost(at)
+
+	hour := time.Date(2024, 5, 10, 22, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 5, 11, 0, 0, 0, 0, istanbul)
+	global := rs.pending[rollupKey{interval: RollupDay, start: day}]
+	if global["Views"] != 1 || global["Likes"] != 1 || global["Posts"] != 1 || len(global) != 3 {
+		t.Errorf("Unexpected daily rollup of every post %v", global)
+	}
+	post := rs.pending[rollupKey{postID: "post-1", interval: RollupHour, start: hour}]
+	if post["Views"] != 1 || post["Likes"] != 1 || post["Posts"] != 0 {
+		t.Errorf("Unexpected hourly rollup of post-1 %v", post)
+	}
+	if len(rs.pending) != 4 {
+		t.Errorf("Expected 4 pending rollups, got %d", len(rs.pending))
+	}
+
+	var disabled *RollupService
+	disabled.RecordView("post-1", at)
+}
+
+func TestRollupStarts(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	// The day DST starts is 23 hours long
+	from := time.Date(2024, 3, 9, 15, 0, 0, 0, newYork)
+	days := rollupStarts(RollupDay, from, from.AddDate(0, 0, 2), newYork)
+	if len(days) != 3 || !days[1].Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, newYork)) || days[2].Sub(days[1]) != 23*time.Hour {
+		t.Errorf("Unexpected day starts %v", days)
+	}
+
+	hours := rollupStarts(RollupHour, time.Date(2024, 3, 9, 15, 40, 0, 0, time.UTC), time.Date(2024, 3, 9, 18, 0, 0, 0, time.UTC), newYork)
+	if len(hours) != 3 || hours[0].Minute() != 0 || hours[0].Hour() != 15 {
+		t.Errorf("Unexpected hour starts %v", hours)
+	}
+
+	if rollupRetention(rollupKey{postID: "post-1", interval: RollupHour}) >= rollupRetention(rollupKey{interval: RollupHour}) {
+		t.Error("Expected hourly rollups of single posts to be kept shorter than those of every post")
+	}
+}
+
+func TestEngagementTrends(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	from := time.Date(2024, 5, 10, 0, 0, 0, 0, tokyo)
+	to := from.AddDate(0, 0, 2)
+
+	// Hourly rollups are assigned to the local day they start in
+	rollups := []EngagementRollup{
+		{Start: time.Date(2024, 5, 10, 14, 0, 0, 0, time.UTC), Views: 10, Likes: 2, Posts: 1},
+		{Start: time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC), Views: 5, Comments: 1},
+		{Start: time.Date(2024, 5, 12, 15, 0, 0, 0, time.UTC), Views: 100},
+	}
+	trends := engagementTrends(rollups, from, to, tokyo)
+	if len(trends) != 2 {
+		t.Fatalf("Expected 2 days, got %d", len(trends))
+	}
+	if trends[0].Views != 10 || trends[0].Likes != 2 || trends[0].PostCount != 1 {
+		t.Errorf("Unexpected first day %+v", trends[0])
+	}
+	if trends[1].Views != 5 || trends[1].Comments != 1 || !trends[1].Date.Equal(from.AddDate(0, 0, 1)) {
+		t.Errorf("Unexpected second day %+v", trends[1])
+	}
+}
@@ -0,0 +1,481 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Scores and regional counts buffered before the scoped rankings are written out early
+const maxPendingScopedScores = 50000
+
+// Characters kept in the collection name of a category; others become underscores
+var scopeNameUnsafe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// TrendingScope selects a scoped trending ranking: the posts of a category, the engagement
+// from a region (a country or a region group), or both
+type TrendingScope struct {
+	Category string `json:"category,omitempty"`
+	Region   string `json:"region,omitempty"`
+}
+
+// NewTrendingScope normalizes a scope as given in a request. Categories are stored lowercase,
+// regions uppercase.
+func NewTrendingScope(category, region string) TrendingScope {
+	return TrendingScope{
+		Category: strings.ToLower(strings.TrimSpace(category)),
+		Region:   strings.ToUpper(strings.TrimSpace(region)),
+	}
+}
+
+// IsEmpty reports whether the scope is the global ranking
+func (s TrendingScope) IsEmpty() bool {
+	return s == TrendingScope{}
+}
+
+// Collection returns the collection of the scope's ranking: trending_scores_category_music,
+// trending_scores_region_EU or trending_scores_region_EU_category_music
+func (s TrendingScope) Collection() string {
+	parts := []string{"trending_scores"}
+	if s.Region != "" {
+		parts = append(parts, "region", s.Region)
+	}
+	if s.Category != "" {
+		parts = append(parts, "category", strings.Trim(scopeNameUnsafe.ReplaceAllString(s.Category, "_"), "_"))
+	}
+	return strings.Join(parts, "_")
+}
+
+// IsTrendingRegion reports whether region is ranked: a country code or a region group
+func IsTrendingRegion(region string, groups map[string][]string) bool {
+	if _, ok := groups[region]; ok {
+		return true
+	}
+	return len(region) == 2 && regionPattern.MatchString(region)
+}
+
+// scopedScoreKey identifies a post's counts in a regional ranking, split by category once the
+// post's category is known
+type scopedScoreKey struct {
+	region   string
+	category string
+	postID   string
+}
+
+// scope returns the ranking the counts are written to
+func (k scopedScoreKey) scope() TrendingScope {
+	return TrendingScope{Region: k.region, Category: k.category}
+}
+
+// regionalCounts are the engagement a post gained from a region since the last flush
+type regionalCounts struct {
+	Views    int64
+	Likes    int64
+	Comments int64
+	Shares   int64
+}
+
+// ScopedTrending keeps the trending rankings of categories and regions in
+// trending_scores_{scope} collections, so a scoped feed is a plain ordered read. Category
+// rankings hold copies of the posts' trending scores; regional rankings score the views and
+// interactions from the region with the shared formula. Interactions carry no region, so they
+// count under the region of the user's last view. Both are buffered in memory and written out
+// on every flush.
+type ScopedTrending struct {
+	firestoreClient *FirestoreClient
+	flusher         *periodicFlusher
+
+	// Region groups containing each country
+	groupsOf map[string][]string
+
+	mu       sync.Mutex
+	scores   map[string]models.TrendingScore // latest score of every post written since the flush
+	regional map[scopedScoreKey]regionalCounts
+	viewers  map[string]string // user ID -> region of the user's last view
+}
+
+// NewScopedTrending creates scoped rankings that flush every flushInterval. Region groups are
+// ranked alongside the countries they contain.
+func NewScopedTrending(firestoreClient *FirestoreClient, flushInterval time.Duration, regionGroups map[string][]string) *ScopedTrending {
+	groupsOf := make(map[string][]string)
+	for group, countries := range regionGroups {
+		for _, country := range countries {
+			groupsOf[country] = append(groupsOf[country], group)
+		}
+	}
+	for _, groups := range groupsOf {
+		sort.Strings(groups)
+	}
+
+	st := &ScopedTrending{
+		firestoreClient: firestoreClient,
+		groupsOf:        groupsOf,
+		scores:          make(map[string]models.TrendingScore),
+		regional:        make(map[scopedScoreKey]regionalCounts),
+		viewers:         make(map[string]string),
+	}
+	st.flusher = newPeriodicFlusher("scoped trending", flushInterval, st.Flush)
+	return st
+}
+
+// SetScopedTrending copies every versioned score write into the category rankings
+func (fc *FirestoreClient) SetScopedTrending(scopes *ScopedTrending) {
+	fc.scopes = scopes
+}
+
+// UseScopedTrending counts consumed views and interactions into the regional rankings
+func (ep *EventProcessor) UseScopedTrending(scopes *ScopedTrending) {
+	ep.scopes = scopes
+}
+
+// Start writes the buffered scores and regional counts into the category and region rankings
+// every flush interval
+func (st *ScopedTrending) Start() {
+	logger.Infof("🗺️ Starting scoped trending (flush interval %v)", st.flusher.interval)
+	st.flusher.start()
+}
+
+// Stop ends the periodic writes and writes out the scores and counts still buffered
+func (st *ScopedTrending) Stop() {
+	st.flusher.stop()
+}
+
+// RecordScore keeps the latest trending score of a post for its category ranking and for the
+// facets of its regional rankings
+func (st *ScopedTrending) RecordScore(score *models.TrendingScore) {
+	if st == nil || score == nil || score.PostID == "" {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.scores[score.PostID]; !ok && len(st.scores) >= maxPendingScopedScores {
+		st.flusher.flushEarly()
+	}
+	st.scores[score.PostID] = *score
+}
+
+// RecordView counts a consumed view under the country it came from and the region groups
+// containing it, and remembers the country for the user's interactions
+func (st *ScopedTrending) RecordView(event models.ViewEvent) {
+	if st == nil {
+		return
+	}
+	country := viewCountry(event.Region)
+	if country == "" {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.count(event.PostID, country, regionalCounts{Views: 1})
+	if event.UserID != "" {
+		if len(st.viewers) >= maxCachedPostOwners {
+			st.viewers = make(map[string]string)
+		}
+		st.viewers[event.UserID] = country
+	}
+}
+
+// RecordInteraction counts a consumed like, comment or share under the country of the user's
+// last view
+func (st *ScopedTrending) RecordInteraction(postID, userID, eventType string) {
+	if st == nil {
+		return
+	}
+	var counts regionalCounts
+	switch eventType {
+	case "like":
+		counts.Likes = 1
+	case "comment":
+		counts.Comments = 1
+	case "share":
+		counts.Shares = 1
+	default:
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if country, ok := st.viewers[userID]; ok {
+		st.count(postID, country, counts)
+	}
+}
+
+// count adds counts to the post's rankings in the country and its region groups; the caller
+// holds the lock
+func (st *ScopedTrending) count(postID, country string, counts regionalCounts) {
+	for _, region := range append([]string{country}, st.groupsOf[country]...) {
+		key := scopedScoreKey{region: region, postID: postID}
+		pending, ok := st.regional[key]
+		if !ok && len(st.regional) >= maxPendingScopedScores {
+			st.flusher.flushEarly()
+		}
+		pending.Views += counts.Views
+		pending.Likes += counts.Likes
+		pending.Comments += counts.Comments
+		pending.Shares += counts.Shares
+		st.regional[key] = pending
+	}
+}
+
+// viewCountry returns the country of a view's region code, "" when it is not a valid code
+func viewCountry(region string) string {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if !regionPattern.MatchString(region) {
+		return ""
+	}
+	return region[:2]
+}
+
+// Flush writes the buffered scores and regional counts. Regional counts that failed to be
+// written are kept for the next flush.
+func (st *ScopedTrending) Flush() error {
+	st.mu.Lock()
+	scores, regional := st.scores, st.regional
+	st.scores = make(map[string]models.TrendingScore)
+	st.regional = make(map[scopedScoreKey]regionalCounts)
+	st.mu.Unlock()
+
+	if len(scores) == 0 && len(regional) == 0 {
+		return nil
+	}
+	failed, err := st.firestoreClient.writeScopedScores(scores, regional)
+	if len(failed) > 0 {
+		st.mu.Lock()
+		for key, counts := range failed {
+			pending := st.regional[key]
+			pending.Views += counts.Views
+			pending.Likes += counts.Likes
+			pending.Comments += counts.Comments
+			pending.Shares += counts.Shares
+			st.regional[key] = pending
+		}
+		st.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("🗺️ Flushed %d category scores and %d regional counts", len(scores), len(regional))
+	return nil
+}
+
+// regionalScore returns a post's score in a regional ranking: its facets from the post's
+// trending score, the regional counts stored so far plus the new ones, scored with the shared
+// formula. Posts without a known publication time decay from the last regional update.
+func regionalScore(global, stored models.TrendingScore, gained regionalCounts, scoring *ScoringEngine, now time.Time) models.TrendingScore {
+	score := models.TrendingScore{
+		PostID:          global.PostID,
+		ContentType:     global.ContentType,
+		Category:        global.Category,
+		Keywords:        global.Keywords,
+		CreatorID:       global.CreatorID,
+		Style:           global.Style,
+		PublishedAt:     global.PublishedAt,
+		DurationSeconds: global.DurationSeconds,
+		ViewCount:       stored.ViewCount + gained.Views,
+		LikeCount:       stored.LikeCount + gained.Likes,
+		CommentCount:    stored.CommentCount + gained.Comments,
+		ShareCount:      stored.ShareCount + gained.Shares,
+		CalculatedAt:    now,
+	}
+	score.Score = scoring.Score(score, regionalAnchor(score.PublishedAt, stored.CalculatedAt, now))
+	return score
+}
+
+// regionalAnchor returns the time a regional score decays from
+func regionalAnchor(publishedAt, calculatedAt, now time.Time) time.Time {
+	if !publishedAt.IsZero() {
+		return publishedAt
+	}
+	if !calculatedAt.IsZero() {
+		return calculatedAt
+	}
+	return now
+}
+
+// writeScopedScores copies scores into their category rankings and adds regional counts to
+// the regional rankings, and to the regional rankings of the post's category. Counts are
+// incremented, so flushes of several instances add up; the score is recalculated from the
+// counts read before the write. It returns the regional counts whose write failed, by the
+// ranking they failed to reach, with the last error; when nothing could be read it returns
+// all of them.
+func (fc *FirestoreClient) writeScopedScores(scores map[string]models.TrendingScore, regional map[scopedScoreKey]regionalCounts) (map[scopedScoreKey]regionalCounts, error) {
+	// Facets of the regionally counted posts, read for posts whose score was not written
+	// since the last flush
+	facets := make(map[string]models.TrendingScore, len(scores))
+	for postID, score := range scores {
+		facets[postID] = score
+	}
+	var missing []*firestore.DocumentRef
+	for key := range regional {
+		if _, ok := facets[key.postID]; !ok {
+			facets[key.postID] = models.TrendingScore{PostID: key.postID}
+			missing = append(missing, fc.client.Collection("trending_scores").Doc(key.postID))
+		}
+	}
+	if len(missing) > 0 {
+		docs, err := fc.client.GetAll(fc.ctx, missing)
+		Quotas.Record(QuotaFirestore, int64(len(missing)))
+		if err != nil {
+			return regional, err
+		}
+		for _, doc := range docs {
+			var score models.TrendingScore
+			if doc.Exists() && doc.DataTo(&score) == nil {
+				score.PostID = doc.Ref.ID
+				facets[doc.Ref.ID] = score
+			}
+		}
+	}
+
+	// Counts of a region also count in the region's ranking of the post's category; counts
+	// kept from a failed write already name their ranking
+	writes := make(map[scopedScoreKey]regionalCounts, len(regional))
+	for key, counts := range regional {
+		writes[key] = counts
+		if category := facets[key.postID].Category; key.category == "" && category != "" {
+			writes[scopedScoreKey{region: key.region, category: category, postID: key.postID}] = counts
+		}
+	}
+
+	// What the regional rankings store so far
+	keys := keysOf(writes)
+	refs := make([]*firestore.DocumentRef, len(keys))
+	for i, key := range keys {
+		refs[i] = fc.client.Collection(key.scope().Collection()).Doc(key.postID)
+	}
+	stored := make([]models.TrendingScore, len(refs))
+	if len(refs) > 0 {
+		docs, err := fc.client.GetAll(fc.ctx, refs)
+		Quotas.Record(QuotaFirestore, int64(len(refs)))
+		if err != nil {
+			return regional, err
+		}
+		for i, doc := range docs {
+			if doc.Exists() {
+				doc.DataTo(&stored[i])
+			}
+		}
+	}
+
+	bw := fc.client.BulkWriter(fc.ctx)
+	var categoryJobs []*firestore.BulkWriterJob
+	var lastErr error
+	for postID, score := range scores {
+		if score.Category == "" {
+			continue
+		}
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(fc.client.Collection(TrendingScope{Category: score.Category}.Collection()).Doc(postID), score)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		categoryJobs = append(categoryJobs, job)
+	}
+
+	now := time.Now()
+	failed := make(map[scopedScoreKey]regionalCounts)
+	var jobKeys []scopedScoreKey
+	var jobs []*firestore.BulkWriterJob
+	for i, key := range keys {
+		gained := writes[key]
+		score := regionalScore(facets[key.postID], stored[i], gained, fc.scoring, now)
+		data := map[string]interface{}{
+			"PostID":          score.PostID,
+			"Score":           score.Score,
+			"ViewCount":       firestore.Increment(gained.Views),
+			"LikeCount":       firestore.Increment(gained.Likes),
+			"CommentCount":    firestore.Increment(gained.Comments),
+			"ShareCount":      firestore.Increment(gained.Shares),
+			"ContentType":     score.ContentType,
+			"Category":        score.Category,
+			"Keywords":        score.Keywords,
+			"CreatorID":       score.CreatorID,
+			"Style":           score.Style,
+			"PublishedAt":     score.PublishedAt,
+			"DurationSeconds": score.DurationSeconds,
+			"CalculatedAt":    score.CalculatedAt,
+		}
+
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(refs[i], data, firestore.MergeAll)
+		if err != nil {
+			failed[key], lastErr = gained, err
+			continue
+		}
+		jobs, jobKeys = append(jobs, job), append(jobKeys, key)
+	}
+	bw.End()
+
+	for _, job := range categoryJobs {
+		if _, err := job.Results(); err != nil {
+			lastErr = err
+		}
+	}
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			failed[jobKeys[i]], lastErr = writes[jobKeys[i]], err
+		}
+	}
+	return failed, lastErr
+}
+
+// keysOf returns the rankings regional counts are written to
+func keysOf(regional map[scopedScoreKey]regionalCounts) []scopedScoreKey {
+	keys := make([]scopedScoreKey, 0, len(regional))
+	for key := range regional {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// GetScopedTrendingPosts returns the trending posts of a scope's ranking that match a content
+// filter, enriched like GetFilteredTrendingPosts. The filter is matched on the candidates read,
+// so a category ranking is filtered by content type and keyword only. Regional
+// scores of posts with a known publication time are decayed to now, since they are only
+// recalculated when the region engages with the post.
+func (da *DashboardAnalytics) GetScopedTrendingPosts(scope TrendingScope, filter ContentFilter, limit int, fields FieldSet, creatorTier string) ([]models.TrendingScore, error) {
+	logger.Debugf("📊 Getting trending posts of %s for %+v (limit: %d)...", scope.Collection(), filter, limit)
+
+	docs, err := da.firestoreClient.client.Collection(scope.Collection()).
+		OrderBy("Score", firestore.Desc).
+		Limit(filteredTrendingCandidates).
+		Documents(da.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]models.TrendingScore, 0, len(docs))
+	for _, doc := range docs {
+		var score models.TrendingScore
+		if err := doc.DataTo(&score); err != nil {
+			continue
+		}
+		score.PostID = doc.Ref.ID
+		if !filter.matchesScore(score) {
+			continue
+		}
+		if scope.Region != "" && !score.PublishedAt.IsZero() {
+			score.Score = da.firestoreClient.scoring.Score(score, score.PublishedAt)
+		}
+		candidates = append(candidates, score)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	posts, err := da.enrichTrendingCandidates(scoresInOrder(candidates), limit, fields, creatorTier)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("📊 Trending posts of %s for %+v: %d", scope.Collection(), filter, len(posts))
+	return posts, nil
+}
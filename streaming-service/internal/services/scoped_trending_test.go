@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestTrendingScopeCollection(t *testing.T) {
+	tests := []struct {
+		scope    TrendingScope
+		expected string
+	}{
+		{NewTrendingScope(" Music ", ""), "trending_scores_category_music"},
+		{NewTrendingScope("", "eu"), "trending_scores_region_EU"},
+		{NewTrendingScope("music", "DE"), "trending_scores_region_DE_category_music"},
+		{NewTrendingScope("food/drink", ""), "trending_scores_category_food_drink"},
+	}
+	for _, test := range tests {
+		if got := test.scope.Collection(); got != test.expected {
+			t.Errorf("%+v: expected %s, got %s", test.scope, test.expected, got)
+		}
+	}
+	if !NewTrendingScope(" ", "").IsEmpty() {
+		t.Error("Expected a blank scope to be the global ranking")
+	}
+}
+
+func TestIsTrendingRegion(t *testing.T) {
+	groups := map[string][]string{"EU": {"DE", "FR"}}
+	for region, expected := range map[string]bool{"EU": true, "DE": true, "US": true, "US-CA": false, "LATAM": false, "": false} {
+		if got := IsTrendingRegion(region, groups); got != expected {
+			t.Errorf("%q: expected %v, got %v", region, expected, got)
+		}
+	}
+}
+
+func TestScopedTrendingCountsRegions(t *testing.T) {
+	st := NewScopedTrending(nil, time.Minute, map[string][]string{"EU": {"DE", "FR"}})
+
+	st.RecordView(models.ViewEvent{PostID: "post-1", UserID: "user-1", Region: "de-by"})
+	st.RecordView(models.ViewEvent{PostID: "post-1", UserID: "user-2", Region: "US"})
+	st.RecordView(models.ViewEvent{PostID: "post-1", UserID: "user-3", Region: "nowhere"})
+	st.RecordInteraction("post-1", "user-1", "like")
+	st.RecordInteraction("post-1", "user-1", "view")
+	st.RecordInteraction("post-1", "user-unknown", "share")
+
+	expected := map[scopedScoreKey]regionalCounts{
+		{region: "DE", postID: "post-1"}: {Views: 1, Likes: 1},
+		{region: "EU", postID: "post-1"}: {Views: 1, Likes: 1},
+		{region: "US", postID: "post-1"}: {Views: 1},
+	}
+	if len(st.regional) != len(expected) {
+		t.Fatalf("Expected %d regional counts, got %+v", len(expected), st.regional)
+	}
+	for key, counts := range expected {
+		if st.regional[key] != counts {
+			t.Errorf("%+v: expected %+v, got %+v", key, counts, st.regional[key])
+		}
+	}
+}
+
+func TestRegionalScore(t *testing.T) {
+	now := time.Now()
+	global := models.TrendingScore{PostID: "post-1", Category: "music", ContentType: "music", ViewCount: 1000, LikeCount: 100, PublishedAt: now.Add(-48 * time.Hour)}
+	stored := models.TrendingScore{ViewCount: 10, LikeCount: 2}
+
+	score := regionalScore(global, stored, regionalCounts{Views: 5, Likes: 1, Shares: 1}, nil, now)
+	if score.ViewCount != 15 || score.LikeCount != 3 || score.ShareCount != 1 {
+		t.Errorf("Expected the regional counts added up, got %+v", score)
+	}
+	if score.Category != "music" || score.ContentType != "music" || !score.PublishedAt.Equal(global.PublishedAt) {
+		t.Errorf("Expected the facets of the global score, got %+v", score)
+	}
+	if expected := DefaultScoringConfig.score(score, global.PublishedAt); abs(score.Score-expected) > 0.01 {
+		t.Errorf("Expected the shared formula's %.2f, got %.2f", expected, score.Score)
+	}
+}
+
+func TestScopedTrendingKeepsValuesWhenFull(t *testing.T) {
+	st := NewScopedTrending(nil, time.Minute, nil)
+	flushes := make(chan struct{}, 2)
+	st.flusher = newPeriodicFlusher("scoped trending", time.Minute, func() error {
+		flushes <- struct{}{}
+		return nil
+	})
+
+	for i := 0; i < maxPendingScopedScores; i++ {
+		st.scores[fmt.Sprintf("post-%d", i)] = models.TrendingScore{}
+		st.regional[scopedScoreKey{region: "US", postID: fmt.Sprintf("post-%d", i)}] = regionalCounts{}
+	}
+
+	st.RecordScore(&models.TrendingScore{PostID: "post-new", Score: 42})
+	st.RecordView(models.ViewEvent{PostID: "post-new", Region: "US"})
+
+	select {
+	case <-flushes:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a full buffer to start an early flush")
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.scores["post-new"].Score != 42 {
+		t.Errorf("Expected the score that filled the buffer to be kept, got %+v", st.scores["post-new"])
+	}
+	if st.regional[scopedScoreKey{region: "US", postID: "post-new"}].Views != 1 {
+		t.Error("Expected the view that filled the buffer to be counted")
+	}
+}
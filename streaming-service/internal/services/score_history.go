@@ -0,0 +1,272 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Snapshots buffered before the score history is written out early; snapshots of further
+// hours are dropped until the flush completes
+const maxPendingScoreSnapshots = 50000
+
+// scoreHistoryKey identifies the snapshot of a post's trending score for one hour
+type scoreHistoryKey struct {
+	postID string
+	hour   time.Time
+}
+
+// scoreSnapshot is a stored snapshot of a post's trending score, the last one of its hour, with
+// what the post gained during the hour
+type scoreSnapshot struct {
+	Hour               time.Time
+	Score              float64
+	ViralProbability   float64
+	EngagementVelocity float64
+	ViewCount          int64
+	LikeCount          int64
+	CommentCount       int64
+	ShareCount         int64
+	RemixCount         int64
+
+	// Gained over the versioned writes of the hour; incremented on every flush, so writes of
+	// several instances add up
+	ScoreGain      float64
+	ViewGain       int64
+	EngagementGain int64 // likes, comments, shares and remixes
+
+	RecordedAt time.Time
+	ExpiresAt  time.Time // retention cutoff for the snapshot
+}
+
+// ScoreHistory keeps an hourly history of every post's trending score in the score_history
+// subcollection of its score. Every versioned score write replaces the pending snapshot of
+// its hour, so each hour keeps the last score of the hour and adds up what the write gained;
+// snapshots are buffered in memory and written out on every flush.
+type ScoreHistory struct {
+	firestoreClient *FirestoreClient
+	flusher         *periodicFlusher
+	retention       time.Duration
+
+	mu      sync.Mutex
+	pending map[scoreHistoryKey]scoreSnapshot
+}
+
+// NewScoreHistory creates a score history that flushes every flushInterval and keeps
+// snapshots for retention
+func NewScoreHistory(firestoreClient *FirestoreClient, flushInterval, retention time.Duration) *ScoreHistory {
+	sh := &ScoreHistory{
+		firestoreClient: firestoreClient,
+		retention:       retention,
+		pending:         make(map[scoreHistoryKey]scoreSnapshot),
+	}
+	sh.flusher = newPeriodicFlusher("score history", flushInterval, sh.Flush)
+	return sh
+}
+
+// SetScoreHistory records a snapshot of every versioned score write into history
+func (fc *FirestoreClient) SetScoreHistory(history *ScoreHistory) {
+	fc.history = history
+}
+
+// Start writes the buffered hourly snapshots into the score_history subcollections every
+// flush interval
+func (sh *ScoreHistory) Start() {
+	logger.Infof("📉 Starting score history (flush interval %v, retention %v)", sh.flusher.interval, sh.retention)
+	sh.flusher.start()
+}
+
+// Stop ends the periodic writes and writes out the snapshots still buffered, so the current
+// hour is not lost on shutdown
+func (sh *ScoreHistory) Stop() {
+	sh.flusher.stop()
+}
+
+// Record replaces the pending snapshot of the score's post for the hour of at and adds what
+// the score gained over previous, the score before the write
+func (sh *ScoreHistory) Record(previous, score *models.TrendingScore, at time.Time) {
+	if sh == nil || score == nil || score.PostID == "" {
+		return
+	}
+	if previous == nil {
+		previous = &models.TrendingScore{}
+	}
+
+	hour := rollupStart(RollupHour, at, sh.firestoreClient.reportingLoc)
+	key := scoreHistoryKey{postID: score.PostID, hour: hour}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	pending, ok := sh.pending[key]
+	if !ok && len(sh.pending) >= maxPendingScoreSnapshots {
+		sh.flusher.flushEarly()
+		return
+	}
+	sh.pending[key] = scoreSnapshot{
+		Hour:               hour,
+		Score:              score.Score,
+		ViralProbability:   score.ViralProbability,
+		EngagementVelocity: score.EngagementVelocity,
+		ViewCount:          score.ViewCount,
+		LikeCount:          score.LikeCount,
+		CommentCount:       score.CommentCount,
+		ShareCount:         score.ShareCount,
+		RemixCount:         score.RemixCount,
+		ScoreGain:          pending.ScoreGain + score.Score - previous.Score,
+		ViewGain:           pending.ViewGain + score.ViewCount - previous.ViewCount,
+		EngagementGain:     pending.EngagementGain + scoreEngagement(score) - scoreEngagement(previous),
+		RecordedAt:         at,
+		ExpiresAt:          hour.Add(sh.retention),
+	}
+}
+
+// scoreEngagement returns the likes, comments, shares and remixes of a score
+func scoreEngagement(score *models.TrendingScore) int64 {
+	return score.LikeCount + score.CommentCount + score.ShareCount + score.RemixCount
+}
+
+// Flush writes the buffered snapshots, keeping the failed ones for the next flush. When a newer
+// snapshot of the same hour was recorded meanwhile, only the gains of the failed one are kept.
+func (sh *ScoreHistory) Flush() error {
+	sh.mu.Lock()
+	pending := sh.pending
+	sh.pending = make(map[scoreHistoryKey]scoreSnapshot)
+	sh.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	failed, err := sh.firestoreClient.WriteScoreSnapshots(pending)
+	if len(failed) > 0 {
+		sh.mu.Lock()
+		for _, key := range failed {
+			snapshot, ok := sh.pending[key]
+			if !ok {
+				sh.pending[key] = pending[key]
+				continue
+			}
+			snapshot.ScoreGain += pending[key].ScoreGain
+			snapshot.ViewGain += pending[key].ViewGain
+			snapshot.EngagementGain += pending[key].EngagementGain
+			sh.pending[key] = snapshot
+		}
+		sh.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("📉 Flushed %d score snapshots", len(pending))
+	return nil
+}
+
+// scoreHistoryRef returns the document of a score snapshot
+func (fc *FirestoreClient) scoreHistoryRef(key scoreHistoryKey) *firestore.DocumentRef {
+	return fc.client.Collection("trending_scores").Doc(key.postID).Collection("score_history").Doc(key.hour.UTC().Format("2006010215"))
+}
+
+// WriteScoreSnapshots stores score snapshots, replacing earlier snapshots of the same hours but
+// adding up their gains. It returns the snapshots whose write failed with the last error.
+func (fc *FirestoreClient) WriteScoreSnapshots(snapshots map[scoreHistoryKey]scoreSnapshot) ([]scoreHistoryKey, error) {
+	bw := fc.client.BulkWriter(fc.ctx)
+	keys := make([]scoreHistoryKey, 0, len(snapshots))
+	jobs := make([]*firestore.BulkWriterJob, 0, len(snapshots))
+	var failed []scoreHistoryKey
+	var lastErr error
+	for key, snapshot := range snapshots {
+		data := map[string]interface{}{
+			"Hour":               snapshot.Hour,
+			"Score":              snapshot.Score,
+			"ViralProbability":   snapshot.ViralProbability,
+			"EngagementVelocity": snapshot.EngagementVelocity,
+			"ViewCount":          snapshot.ViewCount,
+			"LikeCount":          snapshot.LikeCount,
+			"CommentCount":       snapshot.CommentCount,
+			"ShareCount":         snapshot.ShareCount,
+			"RemixCount":         snapshot.RemixCount,
+			"ScoreGain":          firestore.Increment(snapshot.ScoreGain),
+			"ViewGain":           firestore.Increment(snapshot.ViewGain),
+			"EngagementGain":     firestore.Increment(snapshot.EngagementGain),
+			"RecordedAt":         snapshot.RecordedAt,
+			"ExpiresAt":          snapshot.ExpiresAt,
+		}
+
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(fc.scoreHistoryRef(key), data, firestore.MergeAll)
+		if err != nil {
+			failed, lastErr = append(failed, key), err
+			continue
+		}
+		keys = append(keys, key)
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			failed, lastErr = append(failed, keys[i]), err
+		}
+	}
+	return failed, lastErr
+}
+
+// ScoreHistoryPoint is a post's trending score at one point of its history
+type ScoreHistoryPoint struct {
+	Time             time.Time `json:"time"`
+	Score            float64   `json:"score"`
+	ViralProbability float64   `json:"viralProbability"`
+	ViewCount        int64     `json:"viewCount"`
+	Engagement       int64     `json:"engagement"` // likes, comments, shares and remixes
+}
+
+// GetScoreHistory returns a post's trending score since from, oldest first, downsampled to at
+// most points points
+func (fc *FirestoreClient) GetScoreHistory(postID string, from time.Time, points int) ([]ScoreHistoryPoint, error) {
+	docs, err := fc.client.Collection("trending_scores").Doc(postID).Collection("score_history").
+		Where("Hour", ">=", rollupStart(RollupHour, from, fc.reportingLoc)).
+		OrderBy("Hour", firestore.Asc).
+		Documents(fc.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]scoreSnapshot, 0, len(docs))
+	for _, doc := range docs {
+		var snapshot scoreSnapshot
+		if err := doc.DataTo(&snapshot); err != nil {
+			logger.Debugf(" Skipping unreadable score snapshot %s: %v", doc.Ref.Path, err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return downsampleScoreHistory(snapshots, points), nil
+}
+
+// downsampleScoreHistory splits hourly snapshots, oldest first, into at most points runs of
+// consecutive snapshots and keeps the last snapshot of each run, so the latest score is always
+// the last point
+func downsampleScoreHistory(snapshots []scoreSnapshot, points int) []ScoreHistoryPoint {
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Hour.Before(snapshots[j].Hour) })
+
+	n := len(snapshots)
+	if points <= 0 || points > n {
+		points = n
+	}
+	history := make([]ScoreHistoryPoint, 0, points)
+	for i := 1; i <= points; i++ {
+		// Last snapshot of the i-th of points runs of nearly equal length
+		snapshot := snapshots[i*n/points-1]
+		history = append(history, ScoreHistoryPoint{
+			Time:             snapshot.RecordedAt,
+			Score:            snapshot.Score,
+			ViralProbability: snapshot.ViralProbability,
+			ViewCount:        snapshot.ViewCount,
+			Engagement:       snapshot.LikeCount + snapshot.CommentCount + snapshot.ShareCount + snapshot.RemixCount,
+		})
+	}
+	return history
+}
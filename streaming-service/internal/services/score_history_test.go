@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestScoreHistory_Record(t *testing.T) {
+	sh := NewScoreHistory(&FirestoreClient{reportingLoc: time.UTC}, time.Minute, 24*time.Hour)
+	hour := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+
+	sh.Record(nil, &models.TrendingScore{PostID: "post-1", Score: 10, ViewCount: 4}, hour.Add(5*time.Minute))
+	sh.Record(&models.TrendingScore{Score: 10, ViewCount: 4}, &models.TrendingScore{PostID: "post-1", Score: 12, ViewCount: 6, LikeCount: 3}, hour.Add(50*time.Minute))
+	sh.Record(&models.TrendingScore{Score: 12}, &models.TrendingScore{PostID: "post-1", Score: 15}, hour.Add(70*time.Minute))
+	sh.Record(nil, &models.TrendingScore{PostID: "post-2", Score: 1}, hour)
+
+	if len(sh.pending) != 3 {
+		t.Fatalf("Expected one snapshot per post and hour, got %d", len(sh.pending))
+	}
+	snapshot := sh.pending[scoreHistoryKey{postID: "post-1", hour: hour}]
+	if snapshot.Score != 12 || snapshot.LikeCount != 3 || !snapshot.ExpiresAt.Equal(hour.Add(24*time.Hour)) {
+		t.Errorf("Expected the last score of the hour, got %+v", snapshot)
+	}
+	if snapshot.ScoreGain != 12 || snapshot.ViewGain != 6 || snapshot.EngagementGain != 3 {
+		t.Errorf("Expected the gains of both writes of the hour, got %+v", snapshot)
+	}
+
+	var disabled *ScoreHistory
+	disabled.Record(nil, &models.TrendingScore{PostID: "post-1"}, hour)
+}
+
+func TestDownsampleScoreHistory(t *testing.T) {
+	start := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	var snapshots []scoreSnapshot
+	for i := 9; i >= 0; i-- {
+		at := start.Add(time.Duration(i) * time.Hour)
+		snapshots = append(snapshots, scoreSnapshot{Hour: at, RecordedAt: at, Score: float64(i), LikeCount: 1, RemixCount: 1})
+	}
+
+	history := downsampleScoreHistory(snapshots, 4)
+	if len(history) != 4 {
+		t.Fatalf("Expected 4 points, got %d", len(history))
+	}
+	// Runs of 2, 3, 2 and 3 hours
+	for i, expected := range []float64{1, 4, 6, 9} {
+		if history[i].Score != expected {
+			t.Errorf("Point %d: expected score %v, got %v", i, expected, history[i].Score)
+		}
+	}
+	if history[3].Engagement != 2 || !history[3].Time.Equal(start.Add(9*time.Hour)) {
+		t.Errorf("Unexpected last point %+v", history[3])
+	}
+
+	if all := downsampleScoreHistory(snapshots[:3], 48); len(all) != 3 {
+		t.Errorf("Expected every snapshot when there are fewer than points, got %d", len(all))
+	}
+	if empty := downsampleScoreHistory(nil, 48); len(empty) != 0 {
+		t.Errorf("Expected no points without snapshots, got %d", len(empty))
+	}
+}
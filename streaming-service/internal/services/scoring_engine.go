@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// ScoringConfig holds the weights and time decay of the trending score formula
+type ScoringConfig struct {
+	// Weight of each view (after unique viewer and completion adjustments) and interaction
+	ViewWeight    float64 `json:"view_weight"`
+	LikeWeight    float64 `json:"like_weight"`
+	CommentWeight float64 `json:"comment_weight"`
+	ShareWeight   float64 `json:"share_weight"`
+	RemixWeight   float64 `json:"remix_weight"`
+
+	// Weight of the interactions per hour since the post was created
+	VelocityWeight float64 `json:"velocity_weight"`
+
+	// Hyperbolic decay of the weighted counts: after 1/DecayLambda hours they count half
+	DecayLambda float64 `json:"decay_lambda"`
+
+	// Bonus of a brand new post, shrinking linearly to nothing over RecencyHours
+	RecencyBonus float64 `json:"recency_bonus"`
+	RecencyHours float64 `json:"recency_hours"`
+
+	// Weigh unique viewers instead of raw views, and scale views by completion
+	UniqueViewers  bool `json:"unique_viewers"`
+	CompletionRate bool `json:"completion_rate"`
+}
+
+// DefaultScoringConfig is the formula used when nothing is configured
+var DefaultScoringConfig = ScoringConfig{
+	ViewWeight:     0.1,
+	LikeWeight:     1,
+	CommentWeight:  2,
+	ShareWeight:    3,
+	RemixWeight:    5,
+	VelocityWeight: 5,
+	DecayLambda:    0.03,
+	RecencyBonus:   10,
+	RecencyHours:   24,
+	CompletionRate: true,
+}
+
+// ScoringConfigFrom returns the scoring formula configured by the environment
+func ScoringConfigFrom(cfg *config.Config) ScoringConfig {
+	return ScoringConfig{
+		ViewWeight:     cfg.ScoreViewWeight,
+		LikeWeight:     cfg.ScoreLikeWeight,
+		CommentWeight:  cfg.ScoreCommentWeight,
+		ShareWeight:    cfg.ScoreShareWeight,
+		RemixWeight:    cfg.ScoreRemixWeight,
+		VelocityWeight: cfg.ScoreVelocityWeight,
+		DecayLambda:    cfg.ScoreDecayLambda,
+		RecencyBonus:   cfg.ScoreRecencyBonus,
+		RecencyHours:   cfg.ScoreRecencyHours,
+		UniqueViewers:  cfg.ScoreUniqueViewers,
+		CompletionRate: cfg.ScoreCompletionRate,
+	}
+}
+
+// Validate reports a formula that would rank posts by nonsense
+func (sc ScoringConfig) Validate() error {
+	weights := map[string]float64{
+		"view_weight":     sc.ViewWeight,
+		"like_weight":     sc.LikeWeight,
+		"comment_weight":  sc.CommentWeight,
+		"share_weight":    sc.ShareWeight,
+		"remix_weight":    sc.RemixWeight,
+		"velocity_weight": sc.VelocityWeight,
+		"decay_lambda":    sc.DecayLambda,
+		"recency_bonus":   sc.RecencyBonus,
+		"recency_hours":   sc.RecencyHours,
+	}
+	for name, value := range weights {
+		if value < 0 {
+			return fmt.Errorf("%s must not be negative, got %g", name, value)
+		}
+	}
+	if sc.RecencyBonus > 0 && sc.RecencyHours == 0 {
+		return fmt.Errorf("recency_hours must be positive when recency_bonus is set")
+	}
+	return nil
+}
+
+// options returns the optional inputs of the formula
+func (sc ScoringConfig) options() scoringOptions {
+	return scoringOptions{uniqueViewers: sc.UniqueViewers, completion: sc.CompletionRate}
+}
+
+// score calculates a trending score with time decay from the given creation time
+func (sc ScoringConfig) score(score models.TrendingScore, createdAt time.Time) float64 {
+	// Avoid division by zero for very new posts
+	hours := max(time.Since(createdAt).Hours(), 0.1)
+
+	baseScore := scoredViews(score, sc.options())*sc.ViewWeight +
+		float64(score.LikeCount)*sc.LikeWeight +
+		float64(score.CommentCount)*sc.CommentWeight +
+		float64(score.ShareCount)*sc.ShareWeight +
+		float64(score.RemixCount)*sc.RemixWeight
+
+	// Engagement per hour
+	totalEngagement := float64(score.LikeCount + score.CommentCount + score.ShareCount + score.RemixCount)
+	velocity := totalEngagement / hours
+
+	decay := 1 / (1 + sc.DecayLambda*hours)
+
+	recencyBonus := 0.0
+	if hours < sc.RecencyHours {
+		recencyBonus = sc.RecencyBonus * (1 - hours/sc.RecencyHours)
+	}
+
+	return baseScore*decay + velocity*sc.VelocityWeight + recencyBonus
+}
+
+// ScoringEngine calculates trending scores for every code path that writes them. Its formula
+// starts from the environment's and is overridden by the fields set on the
+// scoring_config/current document, which is re-read periodically so the formula changes
+// without a deploy. An invalid document is ignored and the previous formula kept. The manual
+// trending overrides of posts are re-read with it and applied to their scores. The optional
+// scoring_config/shadow document sets an experimental formula that posts are scored with
+// alongside, for comparison only.
+type ScoringEngine struct {
+	firestoreClient *FirestoreClient // nil when the formula is not read from Firestore
+	base            ScoringConfig
+	reloadInterval  time.Duration
+	ctx             context.Context
+	cancel          context.CancelFunc
+
+	mu        sync.RWMutex
+	current   ScoringConfig
+	shadow    *ScoringConfig // nil when nothing is shadow scored
+	overrides map[string]models.TrendingOverride
+}
+
+func NewScoringEngine(base ScoringConfig, firestoreClient *FirestoreClient, reloadInterval time.Duration) *ScoringEngine {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ScoringEngine{
+		firestoreClient: firestoreClient,
+		base:            base,
+		reloadInterval:  reloadInterval,
+		ctx:             ctx,
+		cancel:          cancel,
+		current:         base,
+	}
+}
+
+// Config returns the formula in use; a nil engine uses the default formula
+func (se *ScoringEngine) Config() ScoringConfig {
+	if se == nil {
+		return DefaultScoringConfig
+	}
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.current
+}
+
+// Score calculates a trending score with time decay from the given creation time, with the
+// post's manual override applied
+func (se *ScoringEngine) Score(score models.TrendingScore, createdAt time.Time) float64 {
+	calculated := se.Config().score(score, createdAt)
+	if override, ok := se.Override(score.PostID); ok {
+		return overriddenScore(calculated, override)
+	}
+	return calculated
+}
+
+// Start reads the Firestore overrides now and then every reload interval
+func (se *ScoringEngine) Start() {
+	if se.firestoreClient == nil {
+		return
+	}
+	if se.reloadInterval <= 0 {
+		// The formula document is ignored, but the trending overrides still apply
+		if err := se.reloadTrendingOverrides(); err != nil {
+			logger.Errorf("❌ Failed to load trending overrides: %v", err)
+		}
+		return
+	}
+	logger.Infof("⚖️ Starting scoring config reload every %v", se.reloadInterval)
+	if err := se.Reload(); err != nil {
+		logger.Errorf("❌ Failed to load scoring config: %v", err)
+	}
+
+	ticker := time.NewTicker(se.reloadInterval)
+	go func() {
+		for {
+			select {
+			case <-se.ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				if err := se.Reload(); err != nil {
+					logger.Errorf("❌ Failed to reload scoring config: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops reloading the formula
+func (se *ScoringEngine) Stop() {
+	se.cancel()
+}
+
+// Reload re-reads the Firestore overrides and the shadow formula, keeping the formulas in use
+// when they are invalid
+func (se *ScoringEngine) Reload() error {
+	if err := se.reloadTrendingOverrides(); err != nil {
+		return err
+	}
+
+	Quotas.Record(QuotaFirestore, 1)
+	overrides := map[string]interface{}{}
+	doc, err := se.firestoreClient.client.Collection("scoring_config").Doc("current").Get(se.ctx)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		overrides = doc.Data()
+	}
+
+	next := overrideScoringConfig(se.base, overrides)
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("invalid scoring_config/current: %w", err)
+	}
+
+	se.mu.Lock()
+	if next != se.current {
+		logger.Infof("⚖️ Scoring config changed: %+v", next)
+		se.current = next
+	}
+	se.mu.Unlock()
+	return se.reloadShadow(next)
+}
+
+// overrideScoringConfig returns the formula with the fields set on an override document
+func overrideScoringConfig(sc ScoringConfig, overrides map[string]interface{}) ScoringConfig {
+	weights := map[string]*float64{
+		"view_weight":     &sc.ViewWeight,
+		"like_weight":     &sc.LikeWeight,
+		"comment_weight":  &sc.CommentWeight,
+		"share_weight":    &sc.ShareWeight,
+		"remix_weight":    &sc.RemixWeight,
+		"velocity_weight": &sc.VelocityWeight,
+		"decay_lambda":    &sc.DecayLambda,
+		"recency_bonus":   &sc.RecencyBonus,
+		"recency_hours":   &sc.RecencyHours,
+	}
+	for key, weight := range weights {
+		if _, ok := overrides[key]; ok {
+			*weight = getFloat64(overrides, key)
+		}
+	}
+	if value, ok := overrides["unique_viewers"].(bool); ok {
+		sc.UniqueViewers = value
+	}
+	if value, ok := overrides["completion_rate"].(bool); ok {
+		sc.CompletionRate = value
+	}
+	return sc
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestScoringConfigScore(t *testing.T) {
+	score := models.TrendingScore{ViewCount: 100, LikeCount: 10, CommentCount: 5, ShareCount: 2, RemixCount: 1}
+	createdAt := time.Now().Add(-100 * time.Hour)
+
+	// Past the recency window: 41 weighted counts decayed by 1/(1+0.03*100), plus 18 interactions
+	// over 100 hours at velocity weight 5
+	expected := 41.0/4 + 18.0/100*5
+	if got := DefaultScoringConfig.score(score, createdAt); got < expected-0.01 || got > expected+0.01 {
+		t.Errorf("Expected %.2f, got %.2f", expected, got)
+	}
+
+	doubled := DefaultScoringConfig
+	doubled.LikeWeight = 2
+	if got := doubled.score(score, createdAt); got <= expected {
+		t.Errorf("Expected a heavier like weight to raise the score above %.2f, got %.2f", expected, got)
+	}
+
+	fresh := DefaultScoringConfig.score(models.TrendingScore{}, time.Now())
+	if fresh < 9.9 || fresh > 10 {
+		t.Errorf("Expected a new post without engagement to get the recency bonus, got %.2f", fresh)
+	}
+}
+
+func TestOverrideScoringConfig(t *testing.T) {
+	overridden := overrideScoringConfig(DefaultScoringConfig, map[string]interface{}{
+		"share_weight":   int64(4),
+		"decay_lambda":   0.05,
+		"unique_viewers": true,
+		"unknown_field":  1.0,
+	})
+
+	expected := DefaultScoringConfig
+	expected.ShareWeight = 4
+	expected.DecayLambda = 0.05
+	expected.UniqueViewers = true
+	if overridden != expected {
+		t.Errorf("Expected %+v, got %+v", expected, overridden)
+	}
+	if unchanged := overrideScoringConfig(DefaultScoringConfig, nil); unchanged != DefaultScoringConfig {
+		t.Errorf("Expected no overrides to keep the formula, got %+v", unchanged)
+	}
+}
+
+func TestScoringConfigValidate(t *testing.T) {
+	if err := DefaultScoringConfig.Validate(); err != nil {
+		t.Errorf("Expected the default formula to be valid, got %v", err)
+	}
+
+	negative := DefaultScoringConfig
+	negative.LikeWeight = -1
+	if err := negative.Validate(); err == nil {
+		t.Error("Expected a negative weight to be rejected")
+	}
+
+	noWindow := DefaultScoringConfig
+	noWindow.RecencyHours = 0
+	if err := noWindow.Validate(); err == nil {
+		t.Error("Expected a recency bonus without a window to be rejected")
+	}
+}
+
+func TestScoringEngineNilUsesDefault(t *testing.T) {
+	var engine *ScoringEngine
+	if engine.Config() != DefaultScoringConfig {
+		t.Errorf("Expected a nil engine to use the default formula, got %+v", engine.Config())
+	}
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Bounds of the scores sampled and the top list compared between the live and shadow formulas
+const (
+	MaxScoringCompareSample = 1000
+	MaxScoringCompareTop    = 100
+)
+
+// ErrNoShadowFormula reports a comparison requested while no shadow formula is configured
+var ErrNoShadowFormula = errors.New("no shadow scoring formula is configured")
+
+// ScoringComparison compares the rankings of the highest scored posts under the live formula
+// and the shadow formula
+type ScoringComparison struct {
+	Live   ScoringConfig `json:"live"`
+	Shadow ScoringConfig `json:"shadow"`
+
+	// Fingerprint of the shadow formula; only shadow scores calculated with it are compared
+	ShadowFormula string `json:"shadow_formula"`
+
+	// Posts compared, and posts sampled whose shadow score predates the shadow formula
+	Compared int `json:"compared"`
+	Stale    int `json:"stale"`
+
+	// Spearman correlation of the two rankings of the compared posts, from -1 to 1
+	RankCorrelation float64 `json:"rank_correlation"`
+
+	// Posts in the shadow top list but not the live one, posts in the live top list but not
+	// the shadow one, and posts in both that changed rank, furthest moved first
+	Top        int          `json:"top"`
+	TopOverlap int          `json:"top_overlap"`
+	Entered    []RankChange `json:"entered"`
+	Dropped    []RankChange `json:"dropped"`
+	Moved      []RankChange `json:"moved"`
+
+	ComparedAt time.Time `json:"compared_at"`
+}
+
+// RankChange is the rank and score of a post under both formulas, ranked among the compared
+// posts from 1
+type RankChange struct {
+	PostID      string  `json:"post_id"`
+	LiveRank    int     `json:"live_rank"`
+	ShadowRank  int     `json:"shadow_rank"`
+	LiveScore   float64 `json:"live_score"`
+	ShadowScore float64 `json:"shadow_score"`
+}
+
+// Shadow returns the shadow formula, scored alongside the live one without affecting rankings
+func (se *ScoringEngine) Shadow() (ScoringConfig, bool) {
+	if se == nil {
+		return ScoringConfig{}, false
+	}
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	if se.shadow == nil {
+		return ScoringConfig{}, false
+	}
+	return *se.shadow, true
+}
+
+// Apply calculates the score of a post with time decay from the given creation time, and its
+// shadow score when a shadow formula is configured, both with the post's manual override
+func (se *ScoringEngine) Apply(score *models.TrendingScore, createdAt time.Time) {
+	score.Score = se.Score(*score, createdAt)
+
+	shadow, ok := se.Shadow()
+	if !ok {
+		score.ShadowScore, score.ShadowFormula = 0, ""
+		return
+	}
+	score.ShadowScore = shadow.score(*score, createdAt)
+	if override, ok := se.Override(score.PostID); ok {
+		score.ShadowScore = overriddenScore(score.ShadowScore, override)
+	}
+	score.ShadowFormula = shadowFingerprint(shadow)
+}
+
+// shadowCurrent reports whether a score's shadow score was calculated with the shadow formula
+// in use, or the score has none while nothing is shadow scored
+func (se *ScoringEngine) shadowCurrent(score models.TrendingScore) bool {
+	shadow, ok := se.Shadow()
+	if !ok {
+		return score.ShadowFormula == ""
+	}
+	return score.ShadowFormula == shadowFingerprint(shadow)
+}
+
+// reloadShadow re-reads the scoring_config/shadow document, whose fields override the live
+// formula; without the document nothing is shadow scored
+func (se *ScoringEngine) reloadShadow(live ScoringConfig) error {
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := se.firestoreClient.client.Collection("scoring_config").Doc("shadow").Get(se.ctx)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+
+	var next *ScoringConfig
+	if err == nil {
+		shadow := overrideScoringConfig(live, doc.Data())
+		if err := shadow.Validate(); err != nil {
+			return fmt.Errorf("invalid scoring_config/shadow: %w", err)
+		}
+		next = &shadow
+	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	switch {
+	case next == nil && se.shadow != nil:
+		logger.Infof("🌓 Shadow scoring stopped")
+	case next != nil && (se.shadow == nil || *next != *se.shadow):
+		logger.Infof("🌓 Shadow scoring config %s: %+v", shadowFingerprint(*next), *next)
+	}
+	se.shadow = next
+	return nil
+}
+
+// shadowFingerprint identifies a formula, so shadow scores of an earlier shadow formula are
+// told apart
+func shadowFingerprint(sc ScoringConfig) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%+v", sc)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// CompareScoring compares the live and shadow rankings of the sample highest scored posts
+// under either formula, listing the differences of their top lists. Posts are shadow scored
+// whenever their score is recalculated; the trending updater recalculates the scores of an
+// earlier shadow formula as it reaches them.
+func (fc *FirestoreClient) CompareScoring(sample, top int) (*ScoringComparison, error) {
+	shadow, ok := fc.scoring.Shadow()
+	if !ok {
+		return nil, ErrNoShadowFormula
+	}
+
+	scores := fc.client.Collection("trending_scores")
+	sampled := make(map[string]models.TrendingScore)
+	for _, field := range []string{"Score", "ShadowScore"} {
+		docs, err := scores.OrderBy(field, firestore.Desc).Limit(sample).Documents(fc.ctx).GetAll()
+		Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			var score models.TrendingScore
+			if err := doc.DataTo(&score); err != nil {
+				continue
+			}
+			score.PostID = doc.Ref.ID
+			sampled[score.PostID] = score
+		}
+	}
+
+	comparison := compareRankings(sampled, shadowFingerprint(shadow), top)
+	comparison.Live = fc.scoring.Config()
+	comparison.Shadow = shadow
+	return comparison, nil
+}
+
+// compareRankings ranks the scores calculated with the given shadow formula both ways
+func compareRankings(sampled map[string]models.TrendingScore, formula string, top int) *ScoringComparison {
+	comparison := &ScoringComparison{
+		ShadowFormula: formula,
+		Top:           top,
+		Entered:       []RankChange{},
+		Dropped:       []RankChange{},
+		Moved:         []RankChange{},
+		ComparedAt:    time.Now(),
+	}
+
+	var compared []models.TrendingScore
+	for _, score := range sampled {
+		if score.ShadowFormula != formula {
+			comparison.Stale++
+			continue
+		}
+		compared = append(compared, score)
+	}
+	comparison.Compared = len(compared)
+	if len(compared) == 0 {
+		return comparison
+	}
+
+	live := make([]float64, len(compared))
+	shadow := make([]float64, len(compared))
+	for i, score := range compared {
+		live[i], shadow[i] = score.Score, score.ShadowScore
+	}
+	comparison.RankCorrelation = spearman(live, shadow)
+
+	liveRanks := ranking(compared, func(score models.TrendingScore) float64 { return score.Score })
+	shadowRanks := ranking(compared, func(score models.TrendingScore) float64 { return score.ShadowScore })
+	for _, score := range compared {
+		change := RankChange{
+			PostID:      score.PostID,
+			LiveRank:    liveRanks[score.PostID],
+			ShadowRank:  shadowRanks[score.PostID],
+			LiveScore:   score.Score,
+			ShadowScore: score.ShadowScore,
+		}
+		inLive, inShadow := change.LiveRank <= top, change.ShadowRank <= top
+		switch {
+		case inLive && inShadow:
+			comparison.TopOverlap++
+			if change.LiveRank != change.ShadowRank {
+				comparison.Moved = append(comparison.Moved, change)
+			}
+		case inShadow:
+			comparison.Entered = append(comparison.Entered, change)
+		case inLive:
+			comparison.Dropped = append(comparison.Dropped, change)
+		}
+	}
+
+	sort.Slice(comparison.Entered, func(i, j int) bool { return comparison.Entered[i].ShadowRank < comparison.Entered[j].ShadowRank })
+	sort.Slice(comparison.Dropped, func(i, j int) bool { return comparison.Dropped[i].LiveRank < comparison.Dropped[j].LiveRank })
+	sort.Slice(comparison.Moved, func(i, j int) bool {
+		a, b := comparison.Moved[i], comparison.Moved[j]
+		da, db := abs(float64(a.ShadowRank-a.LiveRank)), abs(float64(b.ShadowRank-b.LiveRank))
+		if da != db {
+			return da > db
+		}
+		return a.LiveRank < b.LiveRank
+	})
+	return comparison
+}
+
+// ranking returns the 1-based rank of each post by value, highest first, ties by post ID
+func ranking(scores []models.TrendingScore, value func(models.TrendingScore) float64) map[string]int {
+	ordered := append([]models.TrendingScore(nil), scores...)
+	sort.Slice(ordered, func(i, j int) bool {
+		if value(ordered[i]) != value(ordered[j]) {
+			return value(ordered[i]) > value(ordered[j])
+		}
+		return ordered[i].PostID < ordered[j].PostID
+	})
+	ranks := make(map[string]int, len(ordered))
+	for i, score := range ordered {
+		ranks[score.PostID] = i + 1
+	}
+	return ranks
+}
+
+// spearman returns the Spearman rank correlation of two series, ranking ties by their mean
+// rank; 0 when either series has no spread
+func spearman(x, y []float64) float64 {
+	rx, ry := fractionalRanks(x), fractionalRanks(y)
+	n := float64(len(x))
+	if n < 2 {
+		return 0
+	}
+
+	var meanX, meanY float64
+	for i := range rx {
+		meanX += rx[i]
+		meanY += ry[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range rx {
+		dx, dy := rx[i]-meanX, ry[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// fractionalRanks ranks values from 1 upwards, giving tied values the mean of their ranks
+func fractionalRanks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && values[order[end+1]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end)/2 + 1
+		for k := start; k <= end; k++ {
+			ranks[order[k]] = rank
+		}
+		start = end + 1
+	}
+	return ranks
+}
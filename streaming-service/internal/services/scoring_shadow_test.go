@@ -0,0 +1,108 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestSpearman(t *testing.T) {
+	tests := []struct {
+		name     string
+		x, y     []float64
+		expected float64
+	}{
+		{"same order", []float64{1, 2, 3, 4}, []float64{10, 20, 30, 40}, 1},
+		{"reversed", []float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}, -1},
+		{"one swap", []float64{1, 2, 3, 4, 5}, []float64{1, 2, 3, 5, 4}, 0.9},
+		{"ties", []float64{1, 2, 2, 3}, []float64{1, 2, 3, 4}, 0.9487},
+		{"no spread", []float64{1, 1, 1}, []float64{1, 2, 3}, 0},
+		{"single", []float64{1}, []float64{1}, 0},
+	}
+	for _, test := range tests {
+		if got := spearman(test.x, test.y); math.Abs(got-test.expected) > 0.0001 {
+			t.Errorf("%s: expected %.4f, got %.4f", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestCompareRankings(t *testing.T) {
+	scored := func(postID string, live, shadow float64, formula string) models.TrendingScore {
+		return models.TrendingScore{PostID: postID, Score: live, ShadowScore: shadow, ShadowFormula: formula}
+	}
+	sampled := map[string]models.TrendingScore{
+		"a": scored("a", 100, 50, "f1"),
+		"b": scored("b", 90, 80, "f1"),
+		"c": scored("c", 80, 90, "f1"),
+		"d": scored("d", 10, 100, "f1"),
+		"e": scored("e", 95, 500, "f0"), // scored with an earlier shadow formula
+	}
+
+	comparison := compareRankings(sampled, "f1", 2)
+	if comparison.Compared != 4 || comparison.Stale != 1 {
+		t.Fatalf("Expected 4 posts compared and 1 stale, got %d and %d", comparison.Compared, comparison.Stale)
+	}
+	// Live: a, b, c, d; shadow: d, c, b, a
+	if comparison.RankCorrelation != -1 {
+		t.Errorf("Expected a rank correlation of -1, got %f", comparison.RankCorrelation)
+	}
+	if comparison.TopOverlap != 0 {
+		t.Errorf("Expected no overlap of the top 2, got %d", comparison.TopOverlap)
+	}
+	if len(comparison.Entered) != 2 || comparison.Entered[0].PostID != "d" || comparison.Entered[1].PostID != "c" {
+		t.Errorf("Expected d and c to enter the top list, got %+v", comparison.Entered)
+	}
+	if len(comparison.Dropped) != 2 || comparison.Dropped[0].PostID != "a" || comparison.Dropped[0].ShadowRank != 4 {
+		t.Errorf("Expected a and b to drop out of the top list, got %+v", comparison.Dropped)
+	}
+
+	comparison = compareRankings(sampled, "f1", 4)
+	if comparison.TopOverlap != 4 || len(comparison.Moved) != 4 {
+		t.Fatalf("Expected every post to move within the top 4, got %d overlapping and %+v", comparison.TopOverlap, comparison.Moved)
+	}
+	if comparison.Moved[0].PostID != "a" || comparison.Moved[1].PostID != "d" {
+		t.Errorf("Expected the posts moved furthest first, got %+v", comparison.Moved)
+	}
+
+	if empty := compareRankings(sampled, "f2", 2); empty.Compared != 0 || empty.Stale != 5 {
+		t.Errorf("Expected nothing compared under another formula, got %+v", empty)
+	}
+}
+
+func TestScoringEngineApplyShadow(t *testing.T) {
+	engine := NewScoringEngine(DefaultScoringConfig, nil, 0)
+	score := models.TrendingScore{PostID: "post-1", LikeCount: 10, ShadowScore: 7, ShadowFormula: "old"}
+	createdAt := time.Now().Add(-100 * time.Hour)
+
+	engine.Apply(&score, createdAt)
+	if score.ShadowScore != 0 || score.ShadowFormula != "" {
+		t.Errorf("Expected no shadow score without a shadow formula, got %f (%s)", score.ShadowScore, score.ShadowFormula)
+	}
+	if !engine.shadowCurrent(score) {
+		t.Error("Expected a score without a shadow score to be current")
+	}
+
+	shadow := DefaultScoringConfig
+	shadow.LikeWeight = 3
+	engine.shadow = &shadow
+	engine.setTrendingOverride(models.TrendingOverride{PostID: "post-1", Multiplier: 2})
+
+	engine.Apply(&score, createdAt)
+	expected := 2 * shadow.score(score, createdAt)
+	if math.Abs(score.ShadowScore-expected) > 0.0001 {
+		t.Errorf("Expected the overridden shadow score %.4f, got %.4f", expected, score.ShadowScore)
+	}
+	if score.ShadowScore <= score.Score {
+		t.Errorf("Expected a heavier shadow like weight to score above %.4f, got %.4f", score.Score, score.ShadowScore)
+	}
+	if score.ShadowFormula != shadowFingerprint(shadow) || !engine.shadowCurrent(score) {
+		t.Errorf("Expected the shadow formula's fingerprint, got %s", score.ShadowFormula)
+	}
+
+	shadow.LikeWeight = 4
+	if engine.shadowCurrent(score) {
+		t.Error("Expected a shadow score of an earlier formula not to be current")
+	}
+}
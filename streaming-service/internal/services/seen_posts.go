@@ -0,0 +1,259 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Users whose views are buffered between flushes before further views are dropped
+const maxPendingSeenUsers = 50000
+
+// Recommendations computed per recommendation returned, so enough remain once the seen and
+// own posts are filtered out
+const recommendationHeadroom = 2
+
+// SeenPostTracker remembers the posts each user viewed, so recommendations skip them. Views
+// are buffered in memory and merged into seen_posts/{user} on every flush, each post with the
+// time it was last viewed. A view counts for the seen TTL; expired entries are pruned when the
+// set is read, and a set untouched for the TTL expires as a whole.
+type SeenPostTracker struct {
+	firestoreClient *FirestoreClient
+	flusher         *periodicFlusher
+
+	mu      sync.Mutex
+	pending map[string]map[string]time.Time // user ID -> post ID -> latest view since the last flush
+}
+
+func NewSeenPostTracker(firestoreClient *FirestoreClient, flushInterval time.Duration) *SeenPostTracker {
+	st := &SeenPostTracker{
+		firestoreClient: firestoreClient,
+		pending:         make(map[string]map[string]time.Time),
+	}
+	st.flusher = newPeriodicFlusher("seen posts", flushInterval, st.Flush)
+	return st
+}
+
+// UseSeenPosts records the posts users view for the recommendations to skip
+func (ep *EventProcessor) UseSeenPosts(seen *SeenPostTracker) {
+	ep.seen = seen
+}
+
+// Start merges the buffered views into the users' seen post sets every flush interval
+func (st *SeenPostTracker) Start() {
+	logger.Infof("👀 Starting seen post tracking (flush interval %v, TTL %v)", st.flusher.interval, st.firestoreClient.seenTTL)
+	st.flusher.start()
+}
+
+// Stop ends the periodic merges and merges the views still buffered, so recently seen posts
+// stay out of recommendations after a restart
+func (st *SeenPostTracker) Stop() {
+	st.flusher.stop()
+}
+
+// RecordView remembers that a user viewed a post at the given time
+func (st *SeenPostTracker) RecordView(userID, postID string, at time.Time) {
+	if st == nil || userID == "" || postID == "" {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	posts, ok := st.pending[userID]
+	if !ok {
+		if len(st.pending) >= maxPendingSeenUsers {
+			return
+		}
+		posts = make(map[string]time.Time)
+		st.pending[userID] = posts
+	}
+	if at.After(posts[postID]) {
+		posts[postID] = at
+	}
+}
+
+// Flush merges the views recorded since the last flush into the stored seen sets
+func (st *SeenPostTracker) Flush() error {
+	st.mu.Lock()
+	pending := st.pending
+	st.pending = make(map[string]map[string]time.Time)
+	st.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	failed, err := st.firestoreClient.MergeSeenPosts(pending)
+	if len(failed) > 0 {
+		// Keep the views for the next flush
+		st.mu.Lock()
+		for _, userID := range failed {
+			for postID, at := range pending[userID] {
+				if st.pending[userID] == nil {
+					st.pending[userID] = make(map[string]time.Time)
+				}
+				if at.After(st.pending[userID][postID]) {
+					st.pending[userID][postID] = at
+				}
+			}
+		}
+		st.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("👀 Flushed the views of %d users", len(pending))
+	return nil
+}
+
+// seenPosts is the stored seen set of a user
+type seenPosts struct {
+	Posts     map[string]time.Time
+	UpdatedAt time.Time
+	ExpiresAt time.Time // retention cutoff of the whole set
+}
+
+// MergeSeenPosts adds views to the stored seen sets of users, returning the users whose views
+// could not be written
+func (fc *FirestoreClient) MergeSeenPosts(pending map[string]map[string]time.Time) ([]string, error) {
+	bw := fc.client.BulkWriter(fc.ctx)
+	userIDs := make([]string, 0, len(pending))
+	jobs := make([]*firestore.BulkWriterJob, 0, len(pending))
+	var failed []string
+	var lastErr error
+	now := time.Now()
+	for userID, posts := range pending {
+		views := make(map[string]interface{}, len(posts))
+		for postID, at := range posts {
+			views[postID] = at
+		}
+
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(fc.client.Collection("seen_posts").Doc(userID), map[string]interface{}{
+			"Posts":     views,
+			"UpdatedAt": now,
+			"ExpiresAt": now.Add(fc.seenTTL),
+		}, firestore.MergeAll)
+		if err != nil {
+			failed, lastErr = append(failed, userID), err
+			continue
+		}
+		userIDs = append(userIDs, userID)
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			failed, lastErr = append(failed, userIDs[i]), err
+		}
+	}
+	return failed, lastErr
+}
+
+// SeenPosts returns the posts each of the users viewed within the seen TTL, pruning the
+// expired views from the stored sets. Nothing is seen when views are not tracked.
+func (fc *FirestoreClient) SeenPosts(userIDs []string) (map[string]map[string]bool, error) {
+	seen := make(map[string]map[string]bool, len(userIDs))
+	if fc.seenTTL <= 0 {
+		return seen, nil
+	}
+
+	cutoff := time.Now().Add(-fc.seenTTL)
+	expired := make(map[*firestore.DocumentRef][]firestore.Update)
+	err := fc.getAllInBatches("seen_posts", userIDs, func(doc *firestore.DocumentSnapshot) {
+		var stored seenPosts
+		if err := doc.DataTo(&stored); err != nil {
+			logger.Warnf("Skipping unreadable seen posts of user %s: %v", doc.Ref.ID, err)
+			return
+		}
+		posts := make(map[string]bool, len(stored.Posts))
+		for postID, at := range stored.Posts {
+			if at.Before(cutoff) {
+				expired[doc.Ref] = append(expired[doc.Ref], firestore.Update{FieldPath: firestore.FieldPath{"Posts", postID}, Value: firestore.Delete})
+				continue
+			}
+			posts[postID] = true
+		}
+		seen[doc.Ref.ID] = posts
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(expired) > 0 {
+		bw := fc.client.BulkWriter(fc.ctx)
+		for ref, updates := range expired {
+			Quotas.Record(QuotaFirestore, 1)
+			if _, err := bw.Update(ref, updates); err != nil {
+				logger.Warnf("Failed to prune the seen posts of user %s: %v", ref.ID, err)
+			}
+		}
+		bw.End()
+	}
+	return seen, nil
+}
+
+// PostOwners returns the user ID of the creator of each post that exists
+func (fc *FirestoreClient) PostOwners(postIDs []string) (map[string]string, error) {
+	owners := make(map[string]string, len(postIDs))
+	err := fc.getAllInBatches("posts", postIDs, func(doc *firestore.DocumentSnapshot) {
+		if owner, _ := doc.Data()["userId"].(string); owner != "" {
+			owners[doc.Ref.ID] = owner
+		}
+	})
+	return owners, err
+}
+
+// excludeSeenAndOwn drops from the recommendations of each user the posts the user viewed
+// within the seen TTL and the user's own posts, keeping at most limit per user. It returns the
+// creators of the recommended posts.
+func (fc *FirestoreClient) excludeSeenAndOwn(recommendations map[string][]models.Recommendation, limit int) (map[string]string, error) {
+	userIDs := make([]string, 0, len(recommendations))
+	postSet := make(map[string]bool)
+	for userID, recs := range recommendations {
+		userIDs = append(userIDs, userID)
+		for _, rec := range recs {
+			postSet[rec.PostID] = true
+		}
+	}
+	postIDs := make([]string, 0, len(postSet))
+	for postID := range postSet {
+		postIDs = append(postIDs, postID)
+	}
+
+	seen, err := fc.SeenPosts(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := fc.PostOwners(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	for userID, recs := range recommendations {
+		recommendations[userID] = unseenRecommendations(userID, recs, seen[userID], owners, limit)
+	}
+	return owners, nil
+}
+
+// unseenRecommendations keeps, in order, at most limit recommendations of posts the user has
+// not seen and did not create
+func unseenRecommendations(userID string, recs []models.Recommendation, seen map[string]bool, owners map[string]string, limit int) []models.Recommendation {
+	kept := make([]models.Recommendation, 0, min(len(recs), limit))
+	for _, rec := range recs {
+		if len(kept) >= limit {
+			break
+		}
+		if seen[rec.PostID] || owners[rec.PostID] == userID {
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	return kept
+}
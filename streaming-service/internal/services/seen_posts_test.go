@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestSeenPostTrackerRecordView(t *testing.T) {
+	tracker := NewSeenPostTracker(nil, time.Minute)
+	earlier := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	tracker.RecordView("user-1", "post-1", later)
+	tracker.RecordView("user-1", "post-1", earlier)
+	tracker.RecordView("user-1", "post-2", time.Time{})
+	tracker.RecordView("", "post-1", later)
+	tracker.RecordView("user-2", "", later)
+
+	posts := tracker.pending["user-1"]
+	if len(tracker.pending) != 1 || len(posts) != 2 {
+		t.Fatalf("Expected 2 posts of 1 user, got %+v", tracker.pending)
+	}
+	if !posts["post-1"].Equal(later) {
+		t.Errorf("Expected the latest view to be kept, got %v", posts["post-1"])
+	}
+	if posts["post-2"].IsZero() {
+		t.Error("Expected a view without a time to count as now")
+	}
+
+	var nilTracker *SeenPostTracker
+	nilTracker.RecordView("user-1", "post-1", later)
+}
+
+func TestUnseenRecommendations(t *testing.T) {
+	recs := []models.Recommendation{
+		{PostID: "seen"},
+		{PostID: "a"},
+		{PostID: "own"},
+		{PostID: "b"},
+		{PostID: "c"},
+	}
+	seen := map[string]bool{"seen": true}
+	owners := map[string]string{"own": "user-1", "a": "user-2"}
+
+	kept := unseenRecommendations("user-1", recs, seen, owners, 2)
+	if len(kept) != 2 || kept[0].PostID != "a" || kept[1].PostID != "b" {
+		t.Errorf("Expected a and b, got %+v", kept)
+	}
+
+	kept = unseenRecommendations("user-1", recs, nil, nil, 10)
+	if len(kept) != len(recs) {
+		t.Errorf("Expected all %d recommendations without seen or owned posts, got %d", len(recs), len(kept))
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Aggregate sentiment within this distance of neutral leaves the viral probability unchanged
+const sentimentNeutralBand = 0.2
+
+// Sentiment labels
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+// AnalyzeSentiment uses Gemini to score the sentiment of a comment
+func (v *VertexAIClient) AnalyzeSentiment(text string) (*models.SentimentResult, error) {
+	// Check cache first
+	cacheKey := "sentiment:" + text
+	var cached models.SentimentResult
+	if v.getFromCache(cacheKey, &cached) {
+		return &cached, nil
+	}
+
+	systemPrompt := `You are a sentiment analyzer for comments on AI-generated content.
+Return ONLY a valid JSON object with these exact fields:
+- score: number between -1 (very negative) and 1 (very positive), 0 for neutral
+- label: one of positive, neutral, negative
+- confidence: number between 0 and 1
+
+Example response:
+{
+  "score": 0.8,
+  "label": "positive",
+  "confidence": 0.9
+}
+
+Do not include any explanation, only return the JSON object.`
+
+	userPrompt := fmt.Sprintf("Comment: %s\n\nScore the sentiment of this comment.", text)
+
+	response, err := v.callGemini(config.GeminiUseCaseSentiment, systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tolerate extra text around the JSON object
+	jsonStart := strings.Index(response, "{")
+	jsonEnd := strings.LastIndex(response, "}")
+	if jsonStart < 0 || jsonEnd <= jsonStart {
+		return nil, fmt.Errorf("no JSON object in gemini response")
+	}
+
+	var result models.SentimentResult
+	if err := json.Unmarshal([]byte(response[jsonStart:jsonEnd+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse sentiment: %w", err)
+	}
+	normalizeSentiment(&result)
+
+	v.putInCache(cacheKey, &result)
+	return &result, nil
+}
+
+// normalizeSentiment clamps the score and confidence and derives the label from the score
+// when the model returned an unknown one
+func normalizeSentiment(result *models.SentimentResult) {
+	result.Score = math.Max(-1, math.Min(1, result.Score))
+	result.Confidence = clamp01(result.Confidence)
+
+	switch result.Label {
+	case SentimentPositive, SentimentNeutral, SentimentNegative:
+	default:
+		result.Label = sentimentLabel(result.Score)
+	}
+}
+
+func sentimentLabel(score float64) string {
+	switch {
+	case score >= sentimentNeutralBand:
+		return SentimentPositive
+	case score <= -sentimentNeutralBand:
+		return SentimentNegative
+	default:
+		return SentimentNeutral
+	}
+}
+
+// addSentiment folds one comment's sentiment into a post's running mean
+func addSentiment(score *models.TrendingScore, sentiment float64) {
+	score.SentimentCount++
+	score.SentimentScore += (sentiment - score.SentimentScore) / float64(score.SentimentCount)
+}
+
+// applySentiment scales a viral probability by the aggregate comment sentiment. Only posts
+// with enough scored comments and a clearly positive or negative mood are affected.
+func applySentiment(probability, sentiment float64, count int64, weight float64, minComments int) float64 {
+	if count < int64(minComments) || math.Abs(sentiment) < sentimentNeutralBand {
+		return probability
+	}
+	return clamp01(probability * (1 + weight*sentiment))
+}
+
+// ApplyCommentSentiment adds a comment's sentiment to the aggregate on the post's trending score
+func (fc *FirestoreClient) ApplyCommentSentiment(postID string, sentiment float64) (*models.TrendingScore, error) {
+	return fc.ApplyTrendingScore(postID, "comment_sentiment", func(score *models.TrendingScore, exists bool) {
+		addSentiment(score, sentiment)
+	})
+}
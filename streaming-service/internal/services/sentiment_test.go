@@ -0,0 +1,62 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestAddSentimentKeepsRunningMean(t *testing.T) {
+	score := &models.TrendingScore{}
+	for _, s := range []float64{1, 0.5, -0.3} {
+		addSentiment(score, s)
+	}
+
+	if score.SentimentCount != 3 {
+		t.Errorf("expected 3 comments, got %d", score.SentimentCount)
+	}
+	if math.Abs(score.SentimentScore-0.4) > 1e-9 {
+		t.Errorf("expected mean 0.4, got %f", score.SentimentScore)
+	}
+}
+
+func TestApplySentiment(t *testing.T) {
+	tests := []struct {
+		name      string
+		sentiment float64
+		count     int64
+		want      float64
+	}{
+		{"positive boosts", 0.8, 10, 0.5 * 1.2},
+		{"negative dampens", -0.8, 10, 0.5 * 0.8},
+		{"neutral ignored", 0.1, 10, 0.5},
+		{"too few comments", 0.9, 2, 0.5},
+	}
+
+	for _, tt := range tests {
+		got := applySentiment(0.5, tt.sentiment, tt.count, 0.25, 3)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected %f, got %f", tt.name, tt.want, got)
+		}
+	}
+
+	if got := applySentiment(0.95, 1, 10, 0.25, 3); got != 1 {
+		t.Errorf("expected probability capped at 1, got %f", got)
+	}
+}
+
+func TestNormalizeSentiment(t *testing.T) {
+	result := &models.SentimentResult{Score: -1.7, Label: "angry", Confidence: 1.4}
+	normalizeSentiment(result)
+
+	if result.Score != -1 {
+		t.Errorf("expected score clamped to -1, got %f", result.Score)
+	}
+	if result.Confidence != 1 {
+		t.Errorf("expected confidence clamped to 1, got %f", result.Confidence)
+	}
+	if result.Label != SentimentNegative {
+		t.Errorf("expected label derived from score, got %s", result.Label)
+	}
+}
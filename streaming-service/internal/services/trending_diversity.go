@@ -0,0 +1,76 @@
+package services
+
+import (
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// TrendingDiversity limits how much of a trending feed one creator or one content type takes:
+// a creator's posts past MaxPerCreator make room for the next posts in score order, and after
+// MaxContentTypeRun posts of one content type in a row the best post of another type moves up
+type TrendingDiversity struct {
+	MaxPerCreator     int // 0 for no limit
+	MaxContentTypeRun int // 0 for no limit
+}
+
+// TrendingDiversityFrom returns the diversity rules configured by the environment
+func TrendingDiversityFrom(cfg *config.Config) TrendingDiversity {
+	return TrendingDiversity{MaxPerCreator: cfg.TrendingMaxPerCreator, MaxContentTypeRun: cfg.TrendingMaxContentTypeRun}
+}
+
+// admit reports whether a feed holding perCreator posts of each creator has room for another
+// post of creatorID, counting the post when it has. Posts of unknown creators always have room.
+func (d TrendingDiversity) admit(perCreator map[string]int, creatorID string) bool {
+	if d.MaxPerCreator <= 0 || creatorID == "" {
+		return true
+	}
+	if perCreator[creatorID] >= d.MaxPerCreator {
+		return false
+	}
+	perCreator[creatorID]++
+	return true
+}
+
+// interleave reorders a feed ranked by score so that no more than MaxContentTypeRun posts of
+// one content type follow each other, moving up the best post of another type where the feed
+// has one
+func (d TrendingDiversity) interleave(posts []models.TrendingScore) []models.TrendingScore {
+	if d.MaxContentTypeRun <= 0 || len(posts) < 2 {
+		return posts
+	}
+
+	remaining := append([]models.TrendingScore(nil), posts...)
+	interleaved := make([]models.TrendingScore, 0, len(posts))
+	run := 0
+	for len(remaining) > 0 {
+		pick := 0
+		if run >= d.MaxContentTypeRun {
+			last := interleaved[len(interleaved)-1].ContentType
+			for i, post := range remaining {
+				if post.ContentType != last {
+					pick = i
+					break
+				}
+			}
+		}
+
+		next := remaining[pick]
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+		if len(interleaved) > 0 && interleaved[len(interleaved)-1].ContentType == next.ContentType {
+			run++
+		} else {
+			run = 1
+		}
+		interleaved = append(interleaved, next)
+	}
+	return interleaved
+}
+
+// creatorOf returns the creator of a trending candidate, from its score or its post document
+func creatorOf(score models.TrendingScore, postData map[string]interface{}) string {
+	if score.CreatorID != "" {
+		return score.CreatorID
+	}
+	creatorID, _ := postData["userId"].(string)
+	return creatorID
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestTrendingDiversityAdmit(t *testing.T) {
+	diversity := TrendingDiversity{MaxPerCreator: 2}
+	perCreator := make(map[string]int)
+
+	admitted := []bool{
+		diversity.admit(perCreator, "creator-1"),
+		diversity.admit(perCreator, "creator-1"),
+		diversity.admit(perCreator, "creator-1"),
+		diversity.admit(perCreator, "creator-2"),
+		diversity.admit(perCreator, ""),
+		diversity.admit(perCreator, ""),
+		diversity.admit(perCreator, ""),
+	}
+	expected := []bool{true, true, false, true, true, true, true}
+	for i := range expected {
+		if admitted[i] != expected[i] {
+			t.Errorf("Post %d: expected admitted=%v, got %v", i, expected[i], admitted[i])
+		}
+	}
+
+	unlimited := TrendingDiversity{}
+	for i := 0; i < 10; i++ {
+		if !unlimited.admit(perCreator, "creator-1") {
+			t.Fatal("Expected no creator limit when disabled")
+		}
+	}
+}
+
+func TestTrendingDiversityInterleave(t *testing.T) {
+	feed := func(types string) []models.TrendingScore {
+		posts := make([]models.TrendingScore, len(types))
+		for i, contentType := range types {
+			posts[i] = models.TrendingScore{PostID: string(rune('a' + i)), ContentType: string(contentType)}
+		}
+		return posts
+	}
+	order := func(posts []models.TrendingScore) string {
+		var b strings.Builder
+		for _, post := range posts {
+			b.WriteString(post.ContentType)
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		maxRun   int
+		feed     string
+		expected string
+	}{
+		{2, "iiiivv", "iiviiv"},
+		{2, "iiiii", "iiiii"}, // nothing to interleave with
+		{1, "iivv", "iviv"},
+		{0, "iiiivv", "iiiivv"},
+	}
+	for _, test := range tests {
+		got := TrendingDiversity{MaxContentTypeRun: test.maxRun}.interleave(feed(test.feed))
+		if order(got) != test.expected {
+			t.Errorf("Run of %d over %s: expected %s, got %s", test.maxRun, test.feed, test.expected, order(got))
+		}
+	}
+
+	// Moved-up posts keep their relative score order
+	got := TrendingDiversity{MaxContentTypeRun: 1}.interleave(feed("iivv"))
+	if got[0].PostID != "a" || got[1].PostID != "c" || got[2].PostID != "b" || got[3].PostID != "d" {
+		t.Errorf("Expected a, c, b, d, got %+v", got)
+	}
+}
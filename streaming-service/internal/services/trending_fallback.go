@@ -0,0 +1,106 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+// Recent posts scanned when looking for fallback content
+const fallbackScanLimit = 200
+
+// GetFallbackPosts returns recent public posts with content created since the given time, ranked
+// by engagement with the trending time decay. They fill trending feeds that have too few trending
+// posts, e.g. on new deployments or during quiet hours. Posts in exclude are skipped; an empty
+// filter or creatorTier matches every post or tier.
+func (da *DashboardAnalytics) GetFallbackPosts(filter ContentFilter, creatorTier string, since time.Time, exclude map[string]bool, limit int, fields FieldSet) ([]models.TrendingScore, error) {
+	logger.Debugf("📊 Getting fallback posts since %s (filter: %+v, limit: %d)...", since.Format(time.RFC3339), filter, limit)
+
+	tiers, err := da.tiersFor(creatorTier)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := da.firestoreClient.client.Collection("posts").
+		Where("isPublic", "==", true).
+		Where("createdAt", ">=", since).
+		OrderBy("createdAt", firestore.Desc).
+		Limit(fallbackScanLimit).
+		Documents(da.ctx)
+	defer iter.Stop()
+
+	candidates := []models.TrendingScore{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if exclude[doc.Ref.ID] {
+			continue
+		}
+
+		postData := doc.Data()
+		if isModerationFlagged(postData) {
+			continue
+		}
+		if !filter.matchesPost(postData) {
+			continue
+		}
+		if !inCreatorTier(tiers, creatorTier, postData) {
+			continue
+		}
+
+		score := fallbackScore(doc.Ref.ID, postData, da.firestoreClient.scoring)
+		if keep, _ := weighDuplicate(&score, postData, da.firestoreClient.duplicateWeight); !keep {
+			continue
+		}
+		if enrichTrendingScore(&score, postData, fields) == 0 || score.ContentType == "" {
+			continue
+		}
+		candidates = append(candidates, score)
+	}
+
+	fallback := rankFallbackPosts(candidates, limit)
+	logger.Debugf("📊 Fallback posts: %d of %d candidates", len(fallback), len(candidates))
+	return fallback, nil
+}
+
+// fallbackScore scores a post that has no trending score from the counters on its document
+func fallbackScore(postID string, postData map[string]interface{}, scoring *ScoringEngine) models.TrendingScore {
+	score := models.TrendingScore{
+		PostID:       postID,
+		ViewCount:    getInt64(postData, "view_count"),
+		LikeCount:    getInt64(postData, "like_count"),
+		CommentCount: getInt64(postData, "comment_count"),
+		ShareCount:   getInt64(postData, "share_count"),
+		RemixCount:   getInt64(postData, "remix_count"),
+		CalculatedAt: time.Now(),
+	}
+
+	createdAt, ok := decayAnchor(postID, postData)
+	if !ok {
+		createdAt = score.CalculatedAt
+	}
+	// Posts without a trending score have no unique viewer estimate or completion rate either
+	score.Score = scoring.Score(score, createdAt)
+	return score
+}
+
+// rankFallbackPosts orders fallback posts by score, keeping the query's newest-first order for
+// ties, and keeps at most limit of them
+func rankFallbackPosts(posts []models.TrendingScore, limit int) []models.TrendingScore {
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Score > posts[j].Score
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts
+}
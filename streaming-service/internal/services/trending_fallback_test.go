@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestFallbackScore(t *testing.T) {
+	engaged := fallbackScore("engaged", map[string]interface{}{
+		"like_count":    int64(12),
+		"comment_count": int64(3),
+		"createdAt":     time.Now().Add(-48 * time.Hour),
+	}, nil)
+	quiet := fallbackScore("quiet", map[string]interface{}{
+		"createdAt": time.Now().Add(-48 * time.Hour),
+	}, nil)
+
+	if engaged.PostID != "engaged" || engaged.LikeCount != 12 || engaged.CommentCount != 3 {
+		t.Errorf("expected counts copied from the post, got %+v", engaged)
+	}
+	if engaged.Score <= quiet.Score {
+		t.Errorf("expected engaged post to outscore quiet one, got %.2f and %.2f", engaged.Score, quiet.Score)
+	}
+}
+
+func TestRankFallbackPosts(t *testing.T) {
+	posts := []models.TrendingScore{
+		{PostID: "newest", Score: 1},
+		{PostID: "best", Score: 5},
+		{PostID: "older", Score: 1},
+		{PostID: "oldest", Score: 0.5},
+	}
+
+	ranked := rankFallbackPosts(posts, 3)
+	if len(ranked) != 3 {
+		t.Fatalf("expected 3 posts, got %d", len(ranked))
+	}
+	for i, want := range []string{"best", "newest", "older"} {
+		if ranked[i].PostID != want {
+			t.Errorf("position %d: expected %s, got %s", i, want, ranked[i].PostID)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Source the scores rescored after an override change are written under
+const trendingOverrideSource = "trending_override"
+
+// Bounds of a manual score multiplier
+const (
+	MinTrendingMultiplier = 0.01
+	MaxTrendingMultiplier = 100
+)
+
+// ValidateTrendingOverride reports an override that cannot be applied
+func ValidateTrendingOverride(override models.TrendingOverride) error {
+	if override.Pinned && override.Excluded {
+		return fmt.Errorf("a post cannot be both pinned and excluded")
+	}
+	if override.Multiplier < MinTrendingMultiplier || override.Multiplier > MaxTrendingMultiplier {
+		return fmt.Errorf("multiplier must be between %g and %g", float64(MinTrendingMultiplier), float64(MaxTrendingMultiplier))
+	}
+	return nil
+}
+
+// overriddenScore applies a manual override to a calculated score: excluded posts score
+// nothing, the others are scaled by the multiplier
+func overriddenScore(score float64, override models.TrendingOverride) float64 {
+	if override.Excluded {
+		return 0
+	}
+	if override.Multiplier > 0 {
+		return score * override.Multiplier
+	}
+	return score
+}
+
+// Override returns the manual trending override of a post
+func (se *ScoringEngine) Override(postID string) (models.TrendingOverride, bool) {
+	if se == nil {
+		return models.TrendingOverride{}, false
+	}
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	override, ok := se.overrides[postID]
+	return override, ok
+}
+
+// TrendingOverrides returns the manual trending overrides, most recently updated first
+func (se *ScoringEngine) TrendingOverrides() []models.TrendingOverride {
+	if se == nil {
+		return nil
+	}
+	se.mu.RLock()
+	overrides := make([]models.TrendingOverride, 0, len(se.overrides))
+	for _, override := range se.overrides {
+		overrides = append(overrides, override)
+	}
+	se.mu.RUnlock()
+
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].UpdatedAt.After(overrides[j].UpdatedAt) })
+	return overrides
+}
+
+// setTrendingOverride applies an override on this instance before the next reload
+func (se *ScoringEngine) setTrendingOverride(override models.TrendingOverride) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	if se.overrides == nil {
+		se.overrides = make(map[string]models.TrendingOverride)
+	}
+	se.overrides[override.PostID] = override
+}
+
+// deleteTrendingOverride removes an override on this instance before the next reload
+func (se *ScoringEngine) deleteTrendingOverride(postID string) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	delete(se.overrides, postID)
+}
+
+// reloadTrendingOverrides re-reads the trending_overrides collection
+func (se *ScoringEngine) reloadTrendingOverrides() error {
+	docs, err := se.firestoreClient.client.Collection("trending_overrides").Documents(se.ctx).GetAll()
+	Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+	if err != nil {
+		return err
+	}
+
+	overrides := make(map[string]models.TrendingOverride, len(docs))
+	for _, doc := range docs {
+		var override models.TrendingOverride
+		if err := doc.DataTo(&override); err != nil {
+			logger.Warnf("Skipping unreadable trending override %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		override.PostID = doc.Ref.ID
+		overrides[override.PostID] = override
+	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	if len(overrides) != len(se.overrides) {
+		logger.Infof("📌 Trending overrides: %d", len(overrides))
+	}
+	se.overrides = overrides
+	return nil
+}
+
+// SetTrendingOverride stores the manual trending override of a post and rescores the post with
+// it. The override applies on this instance right away and on the others once they reload the
+// scoring config. The rescored score is nil when the post has none yet.
+func (fc *FirestoreClient) SetTrendingOverride(override models.TrendingOverride) (*models.TrendingScore, error) {
+	override.UpdatedAt = time.Now()
+	Quotas.Record(QuotaFirestore, 1)
+	if _, err := fc.client.Collection("trending_overrides").Doc(override.PostID).Set(fc.ctx, override); err != nil {
+		return nil, err
+	}
+	fc.scoring.setTrendingOverride(override)
+	return fc.rescoreOverridden(override.PostID)
+}
+
+// DeleteTrendingOverride removes the manual trending override of a post and rescores the post
+// without it, reporting false when the post had none
+func (fc *FirestoreClient) DeleteTrendingOverride(postID string) (bool, error) {
+	ref := fc.client.Collection("trending_overrides").Doc(postID)
+	Quotas.Record(QuotaFirestore, 2)
+	if _, err := ref.Get(fc.ctx); err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := ref.Delete(fc.ctx); err != nil {
+		return false, err
+	}
+	fc.scoring.deleteTrendingOverride(postID)
+	_, err := fc.rescoreOverridden(postID)
+	return true, err
+}
+
+// rescoreOverridden recalculates the stored score of a post whose override changed. Posts
+// without a score get the override applied once they are scored.
+func (fc *FirestoreClient) rescoreOverridden(postID string) (*models.TrendingScore, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	current, err := fc.GetPostStats(postID)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	createdAt := current.PublishedAt
+	if createdAt.IsZero() {
+		createdAt = fc.PostCreatedAt(postID, current.CalculatedAt)
+	}
+
+	return fc.ApplyTrendingScore(postID, trendingOverrideSource, func(latest *models.TrendingScore, exists bool) {
+		fc.scoring.Apply(latest, createdAt)
+		latest.CalculatedAt = time.Now()
+	})
+}
+
+// ApplyTrendingOverrides applies the manual trending overrides to a trending feed of at most
+// limit posts: excluded posts are dropped, the pinned posts matching filter lead the feed in
+// score order, and every overridden post is flagged with its override
+func (da *DashboardAnalytics) ApplyTrendingOverrides(posts []models.TrendingScore, filter ContentFilter, limit int, fields FieldSet, creatorTier string) ([]models.TrendingScore, error) {
+	overrides := da.firestoreClient.scoring.TrendingOverrides()
+	if len(overrides) == 0 {
+		return posts, nil
+	}
+
+	var refs []*firestore.DocumentRef
+	for _, override := range overrides {
+		if override.Pinned {
+			refs = append(refs, da.firestoreClient.client.Collection("trending_scores").Doc(override.PostID))
+		}
+	}
+	var pinned []models.TrendingScore
+	if len(refs) > 0 {
+		docs, err := da.firestoreClient.client.GetAll(da.ctx, refs)
+		Quotas.Record(QuotaFirestore, int64(len(refs)))
+		if err != nil {
+			return nil, err
+		}
+		var candidates []models.TrendingScore
+		for _, doc := range docs {
+			if !doc.Exists() {
+				continue
+			}
+			var score models.TrendingScore
+			if err := doc.DataTo(&score); err != nil {
+				continue
+			}
+			score.PostID = doc.Ref.ID
+			if filter.matchesScore(score) {
+				candidates = append(candidates, score)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+		// Pinned posts pass the same moderation, tier and content checks as the others
+		pinned, err = da.enrichTrendingCandidates(scoresInOrder(candidates), limit, fields, creatorTier)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mergeTrendingOverrides(posts, pinned, da.firestoreClient.scoring, limit), nil
+}
+
+// mergeTrendingOverrides puts the pinned posts ahead of the other posts of a feed, without the
+// excluded ones, and flags the overridden posts
+func mergeTrendingOverrides(posts, pinned []models.TrendingScore, scoring *ScoringEngine, limit int) []models.TrendingScore {
+	merged := make([]models.TrendingScore, 0, limit)
+	seen := make(map[string]bool, len(pinned))
+	for _, post := range pinned {
+		if len(merged) < limit {
+			merged = append(merged, post)
+			seen[post.PostID] = true
+		}
+	}
+	for _, post := range posts {
+		if len(merged) >= limit {
+			break
+		}
+		if override, ok := scoring.Override(post.PostID); seen[post.PostID] || (ok && override.Excluded) {
+			continue
+		}
+		merged = append(merged, post)
+	}
+
+	for i := range merged {
+		if override, ok := scoring.Override(merged[i].PostID); ok {
+			merged[i].Override = &override
+		}
+	}
+	return merged
+}
+
+// scoresInOrder returns a candidate source for enrichTrendingCandidates taking scores in order
+func scoresInOrder(scores []models.TrendingScore) func() (models.TrendingScore, bool, error) {
+	return func() (models.TrendingScore, bool, error) {
+		if len(scores) == 0 {
+			return models.TrendingScore{}, false, nil
+		}
+		next := scores[0]
+		scores = scores[1:]
+		return next, true, nil
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestValidateTrendingOverride(t *testing.T) {
+	tests := []struct {
+		override models.TrendingOverride
+		valid    bool
+	}{
+		{models.TrendingOverride{Pinned: true, Multiplier: 1}, true},
+		{models.TrendingOverride{Excluded: true, Multiplier: 1}, true},
+		{models.TrendingOverride{Multiplier: 2.5}, true},
+		{models.TrendingOverride{Pinned: true, Excluded: true, Multiplier: 1}, false},
+		{models.TrendingOverride{Multiplier: 0}, false},
+		{models.TrendingOverride{Multiplier: 1000}, false},
+	}
+	for _, test := range tests {
+		if err := ValidateTrendingOverride(test.override); (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid=%v, got %v", test.override, test.valid, err)
+		}
+	}
+}
+
+func TestScoringEngineAppliesOverrides(t *testing.T) {
+	se := NewScoringEngine(DefaultScoringConfig, nil, 0)
+	score := models.TrendingScore{PostID: "post-1", ViewCount: 100, LikeCount: 10}
+	createdAt := time.Now().Add(-10 * time.Hour)
+	calculated := se.Score(score, createdAt)
+
+	se.setTrendingOverride(models.TrendingOverride{PostID: "post-1", Multiplier: 2})
+	if got := se.Score(score, createdAt); abs(got-2*calculated) > 0.01 {
+		t.Errorf("Expected the boosted score %.2f, got %.2f", 2*calculated, got)
+	}
+
+	se.setTrendingOverride(models.TrendingOverride{PostID: "post-1", Excluded: true, Multiplier: 1})
+	if got := se.Score(score, createdAt); got != 0 {
+		t.Errorf("Expected an excluded post to score nothing, got %.2f", got)
+	}
+
+	se.deleteTrendingOverride("post-1")
+	if got := se.Score(score, createdAt); abs(got-calculated) > 0.01 {
+		t.Errorf("Expected the calculated score back, got %.2f", got)
+	}
+
+	var unset *ScoringEngine
+	if _, ok := unset.Override("post-1"); ok {
+		t.Error("Expected no overrides without an engine")
+	}
+}
+
+func TestMergeTrendingOverrides(t *testing.T) {
+	se := NewScoringEngine(DefaultScoringConfig, nil, 0)
+	se.setTrendingOverride(models.TrendingOverride{PostID: "pinned", Pinned: true, Multiplier: 1})
+	se.setTrendingOverride(models.TrendingOverride{PostID: "excluded", Excluded: true, Multiplier: 1})
+	se.setTrendingOverride(models.TrendingOverride{PostID: "boosted", Multiplier: 3})
+
+	posts := []models.TrendingScore{{PostID: "a"}, {PostID: "excluded"}, {PostID: "pinned"}, {PostID: "boosted"}, {PostID: "b"}}
+	merged := mergeTrendingOverrides(posts, []models.TrendingScore{{PostID: "pinned"}}, se, 4)
+
+	expected := []string{"pinned", "a", "boosted", "b"}
+	if len(merged) != len(expected) {
+		t.Fatalf("Expected %v, got %+v", expected, merged)
+	}
+	for i, postID := range expected {
+		if merged[i].PostID != postID {
+			t.Errorf("Position %d: expected %s, got %s", i, postID, merged[i].PostID)
+		}
+	}
+	if merged[0].Override == nil || !merged[0].Override.Pinned {
+		t.Errorf("Expected the pinned post to be flagged, got %+v", merged[0].Override)
+	}
+	if merged[2].Override == nil || merged[2].Override.Multiplier != 3 {
+		t.Errorf("Expected the boosted post to be flagged, got %+v", merged[2].Override)
+	}
+	if merged[1].Override != nil {
+		t.Errorf("Expected a post without override to be unflagged, got %+v", merged[1].Override)
+	}
+}
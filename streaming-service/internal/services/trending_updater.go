@@ -2,40 +2,74 @@ package services
 
 import (
 	"context"
+	"sync"
 	"time"
 
+	"cloud.google.com/go/firestore"
 	"confluent-viral-intelligence/internal/logger"
 	"confluent-viral-intelligence/internal/models"
-	"google.golang.org/api/iterator"
 )
 
-// TrendingUpdater periodically recalculates trending scores with time decay
+// Source the updater writes scores under; its own writes do not mark posts as changed
+const trendingUpdaterSource = "trending_updater"
+
+// Changed posts remembered for the next run; further posts wait for the decay pass
+const maxDirtyTrendingPosts = 100000
+
+// TrendingUpdater periodically recalculates trending scores with time decay. Each run
+// recalculates the posts whose scores were written since the last run, then re-decays the
+// current top of the feed and a rotating shard of the other scores, so every score is decayed
+// over a few runs without reading the whole collection every run. With a leader elector only
+// the leader runs the decay pass; every instance recalculates the posts it changed.
 type TrendingUpdater struct {
 	firestoreClient *FirestoreClient
 	ctx             context.Context
 	cancel          context.CancelFunc
 	updateInterval  time.Duration
+
+	// Scores decayed per run: the top of the feed, and the next shard in document order
+	topSize   int
+	shardSize int
+
+	mu     sync.Mutex
+	dirty  map[string]struct{}
+	cursor string // ID of the last score of the previous shard, "" to start over
+
+	leader *LeaderElector // nil when every instance runs the decay pass
 }
 
 // NewTrendingUpdater creates a new trending updater
-func NewTrendingUpdater(firestoreClient *FirestoreClient, updateInterval time.Duration) *TrendingUpdater {
+func NewTrendingUpdater(firestoreClient *FirestoreClient, updateInterval time.Duration, topSize, shardSize int) *TrendingUpdater {
 	ctx, cancel := context.WithCancel(context.Background())
-	
+
 	return &TrendingUpdater{
 		firestoreClient: firestoreClient,
 		ctx:             ctx,
 		cancel:          cancel,
 		updateInterval:  updateInterval,
+		topSize:         topSize,
+		shardSize:       shardSize,
+		dirty:           make(map[string]struct{}),
 	}
 }
 
+// SetTrendingUpdater marks the posts of every versioned score write for recalculation
+func (fc *FirestoreClient) SetTrendingUpdater(updater *TrendingUpdater) {
+	fc.updater = updater
+}
+
+// UseLeaderElector runs the decay pass only while this instance is the leader
+func (tu *TrendingUpdater) UseLeaderElector(leader *LeaderElector) {
+	tu.leader = leader
+}
+
 // Start begins the periodic update loop
 func (tu *TrendingUpdater) Start() {
-	logger.Infof("🔄 Starting trending updater with interval: %v", tu.updateInterval)
-	
+	logger.Infof("🔄 Starting trending updater with interval: %v (top %d, shard %d)", tu.updateInterval, tu.topSize, tu.shardSize)
+
 	// Run immediately on start
-	tu.updateAllTrendingScores()
-	
+	tu.updateTrendingScores()
+
 	// Then run periodically
 	ticker := time.NewTicker(tu.updateInterval)
 	go func() {
@@ -46,7 +80,7 @@ func (tu *TrendingUpdater) Start() {
 				logger.Info("🛑 Trending updater stopped")
 				return
 			case <-ticker.C:
-				tu.updateAllTrendingScores()
+				tu.updateTrendingScores()
 			}
 		}
 	}()
@@ -57,123 +91,160 @@ func (tu *TrendingUpdater) Stop() {
 	tu.cancel()
 }
 
-// updateAllTrendingScores recalculates all trending scores with current time decay
-func (tu *TrendingUpdater) updateAllTrendingScores() {
+// MarkDirty queues a post whose score was written for recalculation on the next run
+func (tu *TrendingUpdater) MarkDirty(postID string) {
+	if tu == nil {
+		return
+	}
+	tu.mu.Lock()
+	defer tu.mu.Unlock()
+	if len(tu.dirty) < maxDirtyTrendingPosts {
+		tu.dirty[postID] = struct{}{}
+	}
+}
+
+// drainDirty returns the posts changed since the last run and starts a new set
+func (tu *TrendingUpdater) drainDirty() []string {
+	tu.mu.Lock()
+	defer tu.mu.Unlock()
+	postIDs := make([]string, 0, len(tu.dirty))
+	for postID := range tu.dirty {
+		postIDs = append(postIDs, postID)
+	}
+	tu.dirty = make(map[string]struct{})
+	return postIDs
+}
+
+// updateTrendingScores recalculates the changed scores and re-decays the top of the feed and
+// the next shard of scores
+func (tu *TrendingUpdater) updateTrendingScores() {
 	startTime := time.Now()
 	logger.Debug("🔄 Starting trending scores update...")
-	
-	// Get all trending scores
-	iter := tu.firestoreClient.client.Collection("trending_scores").Documents(tu.ctx)
-	
+
+	dirty := tu.drainDirty()
+	candidates, err := tu.candidates(dirty)
+	if err != nil {
+		logger.Errorf("❌ Failed to read trending scores to update: %v", err)
+		// Try the changed posts again on the next run
+		for _, postID := range dirty {
+			tu.MarkDirty(postID)
+		}
+		return
+	}
+
 	updatedCount := 0
 	errorCount := 0
-	
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			// Silently skip errors to avoid log spam
-			errorCount++
+	for _, score := range candidates {
+		// Recalculate score with current time decay
+		createdAt := tu.postCreatedAt(score)
+		newScore := tu.firestoreClient.scoring.Score(score, createdAt)
+
+		// Only update if score changed significantly (> 1% change), or its shadow score is
+		// of another formula
+		if abs(newScore-score.Score) <= score.Score*0.01 && tu.firestoreClient.scoring.shadowCurrent(score) {
 			continue
 		}
-		
-		var score models.TrendingScore
-		if err := doc.DataTo(&score); err != nil {
-			// Silently skip parsing errors
+
+		// Recalculate on the latest copy so concurrent count updates are kept
+		_, err := tu.firestoreClient.ApplyTrendingScore(score.PostID, trendingUpdaterSource, func(latest *models.TrendingScore, exists bool) {
+			tu.firestoreClient.scoring.Apply(latest, createdAt)
+			latest.CalculatedAt = time.Now()
+		})
+		if err != nil {
+			// Silently skip save errors
 			errorCount++
-			continue
-		}
-		
-		// Recalculate score with current time decay
-		newScore := tu.calculateDynamicScore(score)
-		
-		// Only update if score changed significantly (> 1% change)
-		if abs(newScore-score.Score) > score.Score*0.01 {
-			score.Score = newScore
-			score.CalculatedAt = time.Now()
-			
-			// Update in Firestore
-			if err := tu.firestoreClient.SaveTrendingScore(score); err != nil {
-				// Silently skip save errors
-				errorCount++
-			} else {
-				updatedCount++
-			}
+		} else {
+			updatedCount++
 		}
 	}
-	
+
 	duration := time.Since(startTime)
 	// Only log summary at info level if there were updates or errors
 	if updatedCount > 0 || errorCount > 0 {
-		logger.Infof("✅ Trending scores update complete: updated=%d, errors=%d, duration=%v", 
-			updatedCount, errorCount, duration)
+		logger.Infof("✅ Trending scores update complete: changed=%d, checked=%d, updated=%d, errors=%d, duration=%v",
+			len(dirty), len(candidates), updatedCount, errorCount, duration)
 	}
 }
 
-// calculateDynamicScore calculates trending score with time decay based on post creation time
-func (tu *TrendingUpdater) calculateDynamicScore(score models.TrendingScore) float64 {
-	// Get post creation time from Firestore
-	postDoc, err := tu.firestoreClient.client.Collection("posts").Doc(score.PostID).Get(tu.ctx)
-	if err != nil {
-		// If we can't get post creation time, use calculated_at as fallback
-		return tu.calculateScoreWithAge(score, score.CalculatedAt)
+// candidates reads the scores of the changed posts and, on the leader, the top of the feed and
+// the next shard, each score once
+func (tu *TrendingUpdater) candidates(dirty []string) ([]models.TrendingScore, error) {
+	collection := tu.firestoreClient.client.Collection("trending_scores")
+	seen := make(map[string]bool)
+	var candidates []models.TrendingScore
+	add := func(docs []*firestore.DocumentSnapshot) {
+		for _, doc := range docs {
+			if !doc.Exists() || seen[doc.Ref.ID] {
+				continue
+			}
+			var score models.TrendingScore
+			if err := doc.DataTo(&score); err != nil {
+				// Silently skip parsing errors
+				continue
+			}
+			score.PostID = doc.Ref.ID
+			seen[doc.Ref.ID] = true
+			candidates = append(candidates, score)
+		}
 	}
-	
-	var postData map[string]interface{}
-	if err := postDoc.DataTo(&postData); err != nil {
-		return tu.calculateScoreWithAge(score, score.CalculatedAt)
+
+	if len(dirty) > 0 {
+		refs := make([]*firestore.DocumentRef, len(dirty))
+		for i, postID := range dirty {
+			refs[i] = collection.Doc(postID)
+		}
+		docs, err := tu.firestoreClient.client.GetAll(tu.ctx, refs)
+		Quotas.Record(QuotaFirestore, int64(len(refs)))
+		if err != nil {
+			return nil, err
+		}
+		add(docs)
 	}
-	
-	// Get creation time
-	var createdAt time.Time
-	if createdAtVal, ok := postData["created_at"].(time.Time); ok {
-		createdAt = createdAtVal
-	} else {
-		createdAt = score.CalculatedAt
+	if !tu.leader.IsLeader() {
+		return candidates, nil
 	}
-	
-	return tu.calculateScoreWithAge(score, createdAt)
+
+	if tu.topSize > 0 {
+		docs, err := collection.OrderBy("Score", firestore.Desc).Limit(tu.topSize).Documents(tu.ctx).GetAll()
+		Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+		if err != nil {
+			return nil, err
+		}
+		add(docs)
+	}
+
+	if tu.shardSize > 0 {
+		query := collection.OrderBy(firestore.DocumentID, firestore.Asc).Limit(tu.shardSize)
+		if tu.cursor != "" {
+			query = query.StartAfter(tu.cursor)
+		}
+		docs, err := query.Documents(tu.ctx).GetAll()
+		Quotas.Record(QuotaFirestore, int64(len(docs)+1))
+		if err != nil {
+			return nil, err
+		}
+		tu.cursor = nextShardCursor(docs, tu.shardSize)
+		add(docs)
+	}
+	return candidates, nil
 }
 
-// calculateScoreWithAge calculates score with time decay from a specific creation time
-func (tu *TrendingUpdater) calculateScoreWithAge(score models.TrendingScore, createdAt time.Time) float64 {
-	// Calculate hours since post creation
-	hoursSinceCreation := time.Since(createdAt).Hours()
-	
-	// Avoid division by zero for very new posts
-	if hoursSinceCreation < 0.1 {
-		hoursSinceCreation = 0.1
+// nextShardCursor returns where the shard after docs starts: after its last score, or over from
+// the first one when the shard reached the end of the collection
+func nextShardCursor(docs []*firestore.DocumentSnapshot, shardSize int) string {
+	if len(docs) < shardSize {
+		return ""
 	}
-	
-	// Weighted scoring algorithm
-	// Views: 0.1, Likes: 1.0, Comments: 2.0, Shares: 3.0, Remixes: 5.0
-	baseScore := float64(score.ViewCount)*0.1 +
-		float64(score.LikeCount)*1.0 +
-		float64(score.CommentCount)*2.0 +
-		float64(score.ShareCount)*3.0 +
-		float64(score.RemixCount)*5.0
-	
-	// Calculate engagement velocity (engagement per hour)
-	totalEngagement := float64(score.LikeCount + score.CommentCount + score.ShareCount + score.RemixCount)
-	engagementVelocity := totalEngagement / hoursSinceCreation
-	
-	// Apply time decay factor (exponential decay)
-	// λ = 0.03 gives half-life of ~23 hours (faster decay for more dynamic trending)
-	lambda := 0.03
-	timeDecayFactor := 1.0 / (1.0 + lambda*hoursSinceCreation)
-	
-	// Recency bonus for new posts (last 24 hours)
-	recencyBonus := 0.0
-	if hoursSinceCreation < 24 {
-		recencyBonus = 10.0 * (1.0 - hoursSinceCreation/24.0)
+	return docs[len(docs)-1].Ref.ID
+}
+
+// postCreatedAt returns the creation time of the scored post: its stored publication time,
+// else the post document's, falling back to calculated_at
+func (tu *TrendingUpdater) postCreatedAt(score models.TrendingScore) time.Time {
+	if !score.PublishedAt.IsZero() {
+		return score.PublishedAt
 	}
-	
-	// Calculate final trending score with time decay and velocity
-	trendingScore := (baseScore * timeDecayFactor) + (engagementVelocity * 5.0) + recencyBonus
-	
-	return trendingScore
+	return tu.firestoreClient.PostCreatedAt(score.PostID, score.CalculatedAt)
 }
 
 // abs returns absolute value of float64
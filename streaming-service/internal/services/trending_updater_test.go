@@ -0,0 +1,41 @@
+package services
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestTrendingUpdaterDirtyPosts(t *testing.T) {
+	var unset *TrendingUpdater
+	unset.MarkDirty("post-1") // no updater running
+
+	tu := NewTrendingUpdater(nil, time.Minute, 10, 10)
+	tu.MarkDirty("post-1")
+	tu.MarkDirty("post-2")
+	tu.MarkDirty("post-1")
+
+	dirty := tu.drainDirty()
+	sort.Strings(dirty)
+	if len(dirty) != 2 || dirty[0] != "post-1" || dirty[1] != "post-2" {
+		t.Errorf("Expected post-1 and post-2 once each, got %v", dirty)
+	}
+	if again := tu.drainDirty(); len(again) != 0 {
+		t.Errorf("Expected the changed posts to be drained, got %v", again)
+	}
+}
+
+func TestNextShardCursor(t *testing.T) {
+	docs := []*firestore.DocumentSnapshot{
+		{Ref: &firestore.DocumentRef{ID: "a"}},
+		{Ref: &firestore.DocumentRef{ID: "b"}},
+	}
+	if cursor := nextShardCursor(docs, 2); cursor != "b" {
+		t.Errorf("Expected the next shard to start after b, got %q", cursor)
+	}
+	if cursor := nextShardCursor(docs, 3); cursor != "" {
+		t.Errorf("Expected a short shard to start over, got %q", cursor)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// postViewers is the stored all-time viewer sketch of a post
+type postViewers struct {
+	PostID        string
+	Sketch        []byte
+	UniqueViewers int64 // estimate of the sketch when it was written
+	UpdatedAt     time.Time
+}
+
+// MergePostViewers folds viewers into the all-time viewer sketch of a post and, when the
+// estimate of its unique viewers grows, stores the new estimate on its trending score
+func (fc *FirestoreClient) MergePostViewers(postID string, sketch *HyperLogLog) error {
+	ref := fc.client.Collection("post_viewers").Doc(postID)
+
+	var previous, estimate int64
+	err := fc.client.RunTransaction(fc.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// One read and one write per attempt
+		Quotas.Record(QuotaFirestore, 2)
+
+		merged := NewHyperLogLog()
+		previous = 0
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var stored postViewers
+			if err := doc.DataTo(&stored); err != nil {
+				return fmt.Errorf("failed to parse viewers of post %s: %w", postID, err)
+			}
+			if existing, err := HyperLogLogFromBytes(stored.Sketch); err == nil {
+				merged = existing
+				previous = stored.UniqueViewers
+			}
+		}
+		merged.Merge(sketch)
+		estimate = int64(merged.Count())
+
+		return tx.Set(ref, postViewers{
+			PostID:        postID,
+			Sketch:        merged.Bytes(),
+			UniqueViewers: estimate,
+			UpdatedAt:     time.Now(),
+		})
+	})
+	if err != nil || estimate <= previous {
+		return err
+	}
+
+	_, err = fc.ApplyTrendingScore(postID, "unique_viewers", func(score *models.TrendingScore, exists bool) {
+		// Flushes of several instances may land out of order; the sketch only ever grows
+		if estimate > score.UniqueViewers {
+			score.UniqueViewers = estimate
+		}
+	})
+	return err
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestScoredViews(t *testing.T) {
+	tests := []struct {
+		name          string
+		score         models.TrendingScore
+		uniqueViewers bool
+		expected      float64
+	}{
+		{"raw views when disabled", models.TrendingScore{ViewCount: 100, UniqueViewers: 10}, false, 100},
+		{"unique viewers when enabled", models.TrendingScore{ViewCount: 100, UniqueViewers: 10}, true, 10},
+		{"raw views when unique viewers are unknown", models.TrendingScore{ViewCount: 100}, true, 100},
+		{"estimate capped at raw views", models.TrendingScore{ViewCount: 100, UniqueViewers: 103}, true, 100},
+	}
+	for _, test := range tests {
+		if got := scoredViews(test.score, scoringOptions{uniqueViewers: test.uniqueViewers}); got != test.expected {
+			t.Errorf("%s: expected %.0f, got %.0f", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestScoringEngine_UniqueViewers(t *testing.T) {
+	createdAt := time.Now().Add(-48 * time.Hour)
+	refreshed := models.TrendingScore{ViewCount: 5000, UniqueViewers: 50, LikeCount: 10}
+	organic := models.TrendingScore{ViewCount: 2000, UniqueViewers: 1900, LikeCount: 10}
+
+	rawViews := NewScoringEngine(DefaultScoringConfig, nil, 0)
+	if rawViews.Score(refreshed, createdAt) <= rawViews.Score(organic, createdAt) {
+		t.Error("Expected raw views to favour the refreshed post")
+	}
+
+	unique := DefaultScoringConfig
+	unique.UniqueViewers = true
+	uniqueViewers := NewScoringEngine(unique, nil, 0)
+	if uniqueViewers.Score(refreshed, createdAt) >= uniqueViewers.Score(organic, createdAt) {
+		t.Error("Expected unique viewers to favour the organically viewed post")
+	}
+
+	fc := &FirestoreClient{scoring: uniqueViewers}
+	refreshed.CalculatedAt, organic.CalculatedAt = createdAt, createdAt
+	fc.rescore(&refreshed, true)
+	fc.rescore(&organic, true)
+	if refreshed.Score >= organic.Score {
+		t.Error("Expected counted events to weigh unique viewers when enabled")
+	}
+}
@@ -3,13 +3,15 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
-	"sync"
 	"time"
 
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
 	"cloud.google.com/go/vertexai/genai"
 	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
 	"confluent-viral-intelligence/internal/models"
 	"google.golang.org/api/option"
 )
@@ -18,53 +20,126 @@ import (
 type cacheEntry struct {
 	response  interface{}
 	expiresAt time.Time
+	size      int64 // bytes accounted to the memory guard
 }
 
 type VertexAIClient struct {
 	genaiClient *genai.Client
+	predictor   *aiplatform.PredictionClient // only set in endpoint prediction mode
+	breaker     *CircuitBreaker
+	retry       RetryPolicy
+	limiter     *KeyedRateLimiter
+	usage       *AIUsageTracker
 	config      *config.Config
 	ctx         context.Context
-	cache       map[string]*cacheEntry
-	cacheMutex  sync.RWMutex
+	cancel      context.CancelFunc
+	cache       AICache
 	cacheTTL    time.Duration
+	cacheStats  aiCacheMetrics
 }
 
-func NewVertexAIClient(ctx context.Context, cfg *config.Config) (*VertexAIClient, error) {
-	client, err := genai.NewClient(ctx, cfg.GoogleCloudProject, cfg.VertexAILocation, option.WithEndpoint(fmt.Sprintf("%s-aiplatform.googleapis.com:443", cfg.VertexAILocation)))
+// NewVertexAIClient creates the Gemini client. AI responses are cached in the given backend,
+// or in process memory when cache is nil.
+func NewVertexAIClient(ctx context.Context, cfg *config.Config, cache AICache) (*VertexAIClient, error) {
+	client, err := genai.NewClient(ctx, cfg.GoogleCloudProject, cfg.GeminiLocation, option.WithEndpoint(fmt.Sprintf("%s-aiplatform.googleapis.com:443", cfg.GeminiLocation)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create genai client: %w", err)
 	}
 
-	return &VertexAIClient{
+	var predictor *aiplatform.PredictionClient
+	if cfg.ViralPredictionMode == ViralPredictionModeEndpoint {
+		if cfg.VertexAIEndpointID == "" {
+			return nil, fmt.Errorf("VERTEX_AI_ENDPOINT_ID is required for %s prediction mode", ViralPredictionModeEndpoint)
+		}
+		predictor, err = aiplatform.NewPredictionClient(ctx, option.WithEndpoint(fmt.Sprintf("%s-aiplatform.googleapis.com:443", cfg.VertexAILocation)))
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to create prediction client: %w", err)
+		}
+	}
+
+	breaker, retry, limiter, err := newAICallPolicies("vertex_ai", cfg)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	if cache == nil {
+		cache = NewMemoryAICache(cfg.AICacheMaxEntries)
+	}
+	cacheTTL := time.Duration(cfg.AICacheTTLMinutes) * time.Minute
+	if cacheTTL <= 0 {
+		cacheTTL = 1 * time.Hour
+	}
+
+	// Usage is reported per business day; an invalid zone is rejected at startup
+	reportingLoc, _ := cfg.ReportingLocation()
+
+	ctx, cancel := context.WithCancel(ctx)
+	v := &VertexAIClient{
 		genaiClient: client,
+		predictor:   predictor,
+		breaker:     breaker,
+		retry:       retry,
+		limiter:     limiter,
+		usage:       NewAIUsageTracker(cfg.GeminiInputCostPerMillionTokens, cfg.GeminiOutputCostPerMillionTokens, reportingLoc),
 		config:      cfg,
 		ctx:         ctx,
-		cache:       make(map[string]*cacheEntry),
-		cacheTTL:    1 * time.Hour, // 1 hour TTL as per requirements
-	}, nil
+		cancel:      cancel,
+		cache:       cache,
+		cacheTTL:    cacheTTL,
+	}
+
+	return v, nil
 }
 
 // ExtractKeywords uses Gemini to extract keywords from content prompt
 func (v *VertexAIClient) ExtractKeywords(prompt string, contentType string) (*models.KeywordExtractionResponse, error) {
 	// Check cache first
 	cacheKey := fmt.Sprintf("keywords:%s:%s", contentType, prompt)
-	if cached := v.getFromCache(cacheKey); cached != nil {
-		if result, ok := cached.(*models.KeywordExtractionResponse); ok {
-			return result, nil
-		}
+	var cached models.KeywordExtractionResponse
+	if v.getFromCache(cacheKey, &cached) {
+		return &cached, nil
+	}
+
+	language := detectLanguage(prompt)
+
+	// Call Gemini API
+	response, err := v.callGemini(config.GeminiUseCaseKeywords, keywordSystemPrompt, keywordUserPrompt(prompt, contentType, language))
+	if err != nil {
+		// Fallback to simple keyword extraction
+		return fallbackKeywordExtraction(prompt, contentType), nil
+	}
+
+	result, err := parseKeywordResponse(response, prompt, contentType, language)
+	if err != nil {
+		// Fallback to simple keyword extraction
+		return fallbackKeywordExtraction(prompt, contentType), nil
 	}
 
-	// System prompt for keyword extraction
-	systemPrompt := `You are an AI content analyzer. Extract relevant keywords, category, style, and mood from the given content prompt.
+	// Cache the result
+	v.putInCache(cacheKey, result)
+
+	return result, nil
+}
+
+// keywordSystemPrompt asks for keywords in the language the prompt appears to be written in
+const keywordSystemPrompt = `You are an AI content analyzer. Extract relevant keywords, hashtags, category, style, and mood from the given content prompt.
 Return ONLY a valid JSON object with these exact fields:
-- keywords: array of 5-10 relevant keywords (strings)
+- language: ISO 639-1 code of the language the prompt is written in (en, tr, es, ja, ...)
+- keywords: array of 5-10 relevant keywords (strings), in the language of the prompt
+- english_keywords: the same keywords translated to English, in the same order
+- hashtags: array of 1-5 topic hashtags people would follow, lowercase, single words without the # sign
 - category: main category (art, photography, music, voice, video, text)
-- style: artistic style or genre (string)
-- mood: emotional tone (string)
+- style: artistic style or genre (string), in English
+- mood: emotional tone (string), in English
 
 Example response:
 {
-  "keywords": ["sunset", "mountains", "landscape", "nature", "golden hour"],
+  "language": "es",
+  "keywords": ["atardecer", "montañas", "paisaje", "naturaleza", "hora dorada"],
+  "english_keywords": ["sunset", "mountains", "landscape", "nature", "golden hour"],
+  "hashtags": ["horadorada", "montañas", "fotografiadepaisaje"],
   "category": "photography",
   "style": "landscape",
   "mood": "peaceful"
@@ -72,15 +147,13 @@ Example response:
 
 Do not include any explanation, only return the JSON object.`
 
-	userPrompt := fmt.Sprintf("Content Type: %s\nPrompt: %s\n\nExtract keywords, category, style, and mood from this prompt.", contentType, prompt)
-
-	// Call Gemini API
-	response, err := v.callGemini(systemPrompt, userPrompt)
-	if err != nil {
-		// Fallback to simple keyword extraction
-		return v.fallbackKeywordExtraction(prompt, contentType), nil
-	}
+// keywordUserPrompt describes the content to extract keywords from
+func keywordUserPrompt(prompt, contentType, language string) string {
+	return fmt.Sprintf("Content Type: %s\nPrompt (probably %s): %s\n\nExtract keywords, hashtags, category, style, and mood from this prompt.", contentType, languageNames[language], prompt)
+}
 
+// parseKeywordResponse decodes and normalizes the keyword JSON returned by a model
+func parseKeywordResponse(response, prompt, contentType, language string) (*models.KeywordExtractionResponse, error) {
 	// Parse JSON response
 	var result models.KeywordExtractionResponse
 	if err := json.Unmarshal([]byte(response), &result); err != nil {
@@ -90,16 +163,23 @@ Do not include any explanation, only return the JSON object.`
 		if jsonStart >= 0 && jsonEnd > jsonStart {
 			jsonStr := response[jsonStart : jsonEnd+1]
 			if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-				// Fallback to simple keyword extraction
-				return v.fallbackKeywordExtraction(prompt, contentType), nil
+				return nil, fmt.Errorf("failed to parse keywords: %w", err)
 			}
 		} else {
-			// Fallback to simple keyword extraction
-			return v.fallbackKeywordExtraction(prompt, contentType), nil
+			return nil, fmt.Errorf("no JSON object in model response")
 		}
 	}
 
 	// Validate response
+	normalizeKeywords(&result, contentType)
+	normalizeLanguage(&result, language)
+	result.Hashtags = normalizeHashtags(append(hashtagsInText(prompt), result.Hashtags...))
+
+	return &result, nil
+}
+
+// normalizeKeywords keeps the keyword count between 5 and 10
+func normalizeKeywords(result *models.KeywordExtractionResponse, contentType string) {
 	if len(result.Keywords) < 5 || len(result.Keywords) > 10 {
 		// Adjust keywords to be within range
 		if len(result.Keywords) < 5 {
@@ -112,26 +192,40 @@ Do not include any explanation, only return the JSON object.`
 			result.Keywords = result.Keywords[:10]
 		}
 	}
+}
 
-	// Cache the result
-	v.putInCache(cacheKey, &result)
+// normalizeLanguage fills in the prompt language when the model left it out and lowercases the
+// English fields used for grouping. English keywords of English prompts are the keywords.
+func normalizeLanguage(result *models.KeywordExtractionResponse, detected string) {
+	result.Language = strings.ToLower(strings.TrimSpace(result.Language))
+	if len(result.Language) != 2 {
+		result.Language = detected
+	}
 
-	return &result, nil
+	if len(result.EnglishKeywords) == 0 && result.Language == LanguageEnglish {
+		result.EnglishKeywords = result.Keywords
+	}
+	englishKeywords := make([]string, 0, len(result.EnglishKeywords))
+	for _, keyword := range result.EnglishKeywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			englishKeywords = append(englishKeywords, keyword)
+		}
+	}
+	result.EnglishKeywords = englishKeywords[:min(10, len(englishKeywords))]
+
+	result.Category = strings.ToLower(strings.TrimSpace(result.Category))
+	result.Style = strings.ToLower(strings.TrimSpace(result.Style))
 }
 
 // fallbackKeywordExtraction provides simple keyword extraction when AI fails
-func (v *VertexAIClient) fallbackKeywordExtraction(prompt string, contentType string) *models.KeywordExtractionResponse {
-	// Extract simple keywords from prompt
-	words := strings.Fields(strings.ToLower(prompt))
+func fallbackKeywordExtraction(prompt string, contentType string) *models.KeywordExtractionResponse {
+	language := detectLanguage(prompt)
 	keywords := []string{contentType, "ai-generated"}
-	
+
 	// Add first few meaningful words from prompt
-	for _, word := range words {
-		if len(word) > 3 && len(keywords) < 10 {
-			// Remove common words
-			if word != "the" && word != "and" && word != "with" && word != "for" {
-				keywords = append(keywords, word)
-			}
+	for _, word := range promptWords(prompt) {
+		if len(keywords) < 10 && meaningfulWord(word, language) {
+			keywords = append(keywords, word)
 		}
 	}
 
@@ -144,11 +238,21 @@ func (v *VertexAIClient) fallbackKeywordExtraction(prompt string, contentType st
 		keywords = append(keywords, kw)
 	}
 
+	// Prompt words cannot be translated without the model; other prompts only get the
+	// language-neutral keywords in English
+	englishKeywords := keywords
+	if language != LanguageEnglish {
+		englishKeywords = append([]string{contentType, "ai-generated"}, defaultKeywords...)
+	}
+
 	return &models.KeywordExtractionResponse{
-		Keywords: keywords[:min(10, len(keywords))],
-		Category: contentType,
-		Style:    "general",
-		Mood:     "neutral",
+		Keywords:        keywords[:min(10, len(keywords))],
+		Hashtags:        normalizeHashtags(hashtagsInText(prompt)),
+		Category:        contentType,
+		Style:           "general",
+		Mood:            "neutral",
+		Language:        language,
+		EnglishKeywords: englishKeywords[:min(10, len(englishKeywords))],
 	}
 }
 
@@ -159,8 +263,23 @@ func min(a, b int) int {
 	return b
 }
 
-// PredictVirality predicts if content will go viral based on engagement metrics
+// PredictVirality predicts if content will go viral based on engagement metrics.
+// The model is selected by VIRAL_PREDICTION_MODE; the heuristic is used as fallback
+// whenever the configured model fails.
 func (v *VertexAIClient) PredictVirality(req models.ViralPredictionRequest) (*models.ViralPredictionResponse, error) {
+	if mode := v.config.ViralPredictionMode; mode != ViralPredictionModeHeuristic {
+		resp, err := v.predictWithModel(mode, req)
+		if err == nil {
+			return resp, nil
+		}
+		logger.Debugf(" %s virality prediction failed for %s, using heuristic: %v", mode, req.PostID, err)
+	}
+
+	return predictViralityHeuristic(req, v.config), nil
+}
+
+// predictViralityHeuristic scores virality with the hand-tuned weighted engagement formula
+func predictViralityHeuristic(req models.ViralPredictionRequest, cfg *config.Config) *models.ViralPredictionResponse {
 	// Calculate weighted engagement score (as per requirements: views: 1x, likes: 2x, comments: 3x, shares: 5x, remixes: 4x)
 	engagementScore := float64(req.ViewCount)*1.0 + 
 		float64(req.LikeCount)*2.0 + 
@@ -180,30 +299,8 @@ func (v *VertexAIClient) PredictVirality(req models.ViralPredictionRequest) (*mo
 	// Calculate viral score combining engagement, velocity, and time
 	viralScore := (engagementScore + velocityFactor) * timeDecay
 
-	// Calculate viral probability based on score
-	// Using a sigmoid-like function to map score to probability [0, 1]
-	viralProbability := 0.0
-	if viralScore > 200 {
-		viralProbability = 0.95
-	} else if viralScore > 150 {
-		viralProbability = 0.85
-	} else if viralScore > 100 {
-		viralProbability = 0.75
-	} else if viralScore > 70 {
-		viralProbability = 0.65
-	} else if viralScore > 50 {
-		viralProbability = 0.55
-	} else if viralScore > 30 {
-		viralProbability = 0.40
-	} else if viralScore > 20 {
-		viralProbability = 0.30
-	} else if viralScore > 10 {
-		viralProbability = 0.20
-	} else if viralScore > 5 {
-		viralProbability = 0.10
-	} else {
-		viralProbability = 0.05
-	}
+	// Map the score to a probability with the configured score tiers
+	viralProbability := cfg.ViralProbabilityForScore(viralScore)
 
 	// Boost probability if engagement velocity is very high
 	if req.EngagementVelocity > 20 {
@@ -212,6 +309,10 @@ func (v *VertexAIClient) PredictVirality(req models.ViralPredictionRequest) (*mo
 		viralProbability = min64(viralProbability*1.1, 1.0)
 	}
 
+	// Strongly positive comment streams boost the probability, negative ones dampen it
+	viralProbability = applySentiment(viralProbability, req.SentimentScore, req.SentimentCount,
+		cfg.SentimentViralityWeight, cfg.SentimentMinComments)
+
 	// Calculate confidence based on data availability
 	confidence := 0.5 // Base confidence
 	totalEngagement := req.ViewCount + req.LikeCount + req.CommentCount + req.ShareCount + req.RemixCount
@@ -245,7 +346,8 @@ func (v *VertexAIClient) PredictVirality(req models.ViralPredictionRequest) (*mo
 		ViralProbability:  viralProbability,
 		Confidence:        confidence,
 		PredictedPeakTime: predictedPeakTime,
-	}, nil
+		Source:            ViralPredictionModeHeuristic,
+	}
 }
 
 func min64(a, b float64) float64 {
@@ -255,22 +357,26 @@ func min64(a, b float64) float64 {
 	return b
 }
 
-// callGemini makes a request to Gemini Pro API
-func (v *VertexAIClient) callGemini(systemPrompt, userPrompt string) (string, error) {
-	// Get Gemini Pro model
-	model := v.genaiClient.GenerativeModel("gemini-pro")
-	
-	// Configure model parameters
-	model.Temperature = 0.2 // Lower temperature for more consistent results
-	model.TopP = 0.8
-	model.TopK = 40
-	model.MaxOutputTokens = 1024
-
+// callGemini makes a request to Gemini using the model and parameters configured for the use case
+func (v *VertexAIClient) callGemini(useCase, systemPrompt, userPrompt string) (string, error) {
 	// Combine system prompt and user prompt
 	fullPrompt := fmt.Sprintf("%s\n\n%s", systemPrompt, userPrompt)
+	return v.callGeminiParts(useCase, genai.Text(fullPrompt))
+}
+
+// callGeminiParts sends text and media parts to Gemini and returns the text of the answer
+func (v *VertexAIClient) callGeminiParts(useCase string, parts ...genai.Part) (string, error) {
+	model := v.generativeModel(useCase)
 
 	// Generate content
-	resp, err := model.GenerateContent(v.ctx, genai.Text(fullPrompt))
+	var resp *genai.GenerateContentResponse
+	err := v.guard(useCase, func(ctx context.Context) error {
+		Quotas.Record(QuotaVertexAI, 1)
+		var err error
+		resp, err = model.GenerateContent(ctx, parts...)
+		return err
+	})
+	v.usage.Record(useCase, resp, err)
 	if err != nil {
 		return "", fmt.Errorf("gemini generation failed: %w", err)
 	}
@@ -296,48 +402,137 @@ func (v *VertexAIClient) callGemini(systemPrompt, userPrompt string) (string, er
 	return result.String(), nil
 }
 
-// getFromCache retrieves a cached response if it exists and hasn't expired
-func (v *VertexAIClient) getFromCache(key string) interface{} {
-	v.cacheMutex.RLock()
-	defer v.cacheMutex.RUnlock()
+// generativeModel returns a Gemini model configured with the settings of a use case
+func (v *VertexAIClient) generativeModel(useCase string) *genai.GenerativeModel {
+	settings := v.config.GeminiFor(useCase)
 
-	entry, exists := v.cache[key]
-	if !exists {
-		return nil
+	modelName := settings.Model
+	if vision, ok := textOnlyGeminiModels[modelName]; ok && useCase == config.GeminiUseCaseVision {
+		modelName = vision
 	}
 
-	// Check if expired
-	if time.Now().After(entry.expiresAt) {
-		return nil
+	model := v.genaiClient.GenerativeModel(modelName)
+	model.Temperature = float32(settings.Temperature)
+	model.TopP = float32(settings.TopP)
+	model.TopK = float32(settings.TopK)
+	model.MaxOutputTokens = int32(settings.MaxOutputTokens)
+
+	if threshold, ok := harmBlockThresholds[settings.SafetyThreshold]; ok {
+		for category := range harmCategoryNames {
+			model.SafetySettings = append(model.SafetySettings, &genai.SafetySetting{
+				Category:  category,
+				Threshold: threshold,
+			})
+		}
 	}
+	return model
+}
 
-	return entry.response
+// harmBlockThresholds maps the configured safety threshold names to Vertex AI thresholds
+var harmBlockThresholds = map[string]genai.HarmBlockThreshold{
+	config.GeminiSafetyBlockNone:           genai.HarmBlockNone,
+	config.GeminiSafetyBlockOnlyHigh:       genai.HarmBlockOnlyHigh,
+	config.GeminiSafetyBlockMediumAndAbove: genai.HarmBlockMediumAndAbove,
+	config.GeminiSafetyBlockLowAndAbove:    genai.HarmBlockLowAndAbove,
+}
+
+// guard runs a Vertex AI call behind the rate limiter of its key and the circuit breaker,
+// retrying transient failures with jitter and bounding every attempt with the configured timeout
+func (v *VertexAIClient) guard(key string, call func(ctx context.Context) error) error {
+	if err := v.limiter.Wait(v.ctx, key); err != nil {
+		return err
+	}
+
+	return v.breaker.Execute(func() error {
+		return v.retry.Do(v.ctx, func(ctx context.Context) error {
+			err := call(ctx)
+			var blocked *genai.BlockedError
+			if errors.As(err, &blocked) {
+				// Safety blocks are answers, not outages
+				return Permanent(err)
+			}
+			return err
+		})
+	})
+}
+
+// BreakerState returns the state of the Vertex AI circuit breaker
+func (v *VertexAIClient) BreakerState() BreakerState {
+	return v.breaker.State()
+}
+
+// RateLimitStats returns the queued, delayed and dropped Vertex AI requests per use case
+func (v *VertexAIClient) RateLimitStats() []RateLimitStats {
+	if v == nil {
+		return []RateLimitStats{}
+	}
+	return v.limiter.Stats()
+}
+
+// Usage returns the Gemini token usage and estimated cost of the last days, newest first
+func (v *VertexAIClient) Usage(days int) []AIUsageDay {
+	return v.usage.Usage(days)
+}
+
+// getFromCache decodes a cached response into dest if it exists and hasn't expired.
+// Cache failures count as misses so a broken backend never fails the AI call.
+func (v *VertexAIClient) getFromCache(key string, dest interface{}) bool {
+	data, ok, err := v.cache.Get(v.ctx, key)
+	if err != nil {
+		v.cacheStats.errors.Add(1)
+		logger.Debugf(" AI cache read failed: %v", err)
+	}
+	if !ok {
+		v.cacheStats.misses.Add(1)
+		return false
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		v.cacheStats.errors.Add(1)
+		v.cacheStats.misses.Add(1)
+		return false
+	}
+	v.cacheStats.hits.Add(1)
+	return true
 }
 
 // putInCache stores a response in the cache with TTL
 func (v *VertexAIClient) putInCache(key string, response interface{}) {
-	v.cacheMutex.Lock()
-	defer v.cacheMutex.Unlock()
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = v.cache.Set(v.ctx, key, data, v.cacheTTL)
+	}
+	if err != nil {
+		v.cacheStats.errors.Add(1)
+		logger.Debugf(" AI cache write failed: %v", err)
+		return
+	}
+	v.cacheStats.sets.Add(1)
+}
 
-	v.cache[key] = &cacheEntry{
-		response:  response,
-		expiresAt: time.Now().Add(v.cacheTTL),
+// EvictExpiredCache removes expired entries and trims the AI cache to its size bound
+func (v *VertexAIClient) EvictExpiredCache(ctx context.Context) (int, error) {
+	removed, err := v.cache.EvictExpired(ctx)
+	v.cacheStats.evictions.Add(int64(removed))
+	if err != nil {
+		v.cacheStats.errors.Add(1)
 	}
+	return removed, err
 }
 
-// cleanExpiredCache removes expired entries from cache (should be called periodically)
-func (v *VertexAIClient) cleanExpiredCache() {
-	v.cacheMutex.Lock()
-	defer v.cacheMutex.Unlock()
+// CacheSize returns the number of entries in the AI cache
+func (v *VertexAIClient) CacheSize(ctx context.Context) (int, error) {
+	return v.cache.Len(ctx)
+}
 
-	now := time.Now()
-	for key, entry := range v.cache {
-		if now.After(entry.expiresAt) {
-			delete(v.cache, key)
-		}
-	}
+// CacheStats returns AI cache hit/miss metrics
+func (v *VertexAIClient) CacheStats() AICacheStats {
+	return v.cacheStats.stats(v.cache.Backend())
 }
 
 func (v *VertexAIClient) Close() error {
+	v.cancel()
+	if v.predictor != nil {
+		v.predictor.Close()
+	}
 	return v.genaiClient.Close()
 }
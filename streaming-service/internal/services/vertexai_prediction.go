@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Viral prediction modes selectable via VIRAL_PREDICTION_MODE
+const (
+	ViralPredictionModeHeuristic = "heuristic"
+	ViralPredictionModeGemini    = "gemini"
+	ViralPredictionModeEndpoint  = "endpoint"
+)
+
+// viralFeatures derives the model features from the raw engagement counts
+func viralFeatures(req models.ViralPredictionRequest) map[string]interface{} {
+	rate := func(count int64) float64 {
+		if req.ViewCount <= 0 {
+			return 0
+		}
+		return float64(count) / float64(req.ViewCount)
+	}
+
+	hoursElapsed := float64(req.TimeElapsed) / 60.0
+	if hoursElapsed < 0.1 {
+		hoursElapsed = 0.1
+	}
+	totalEngagement := req.LikeCount + req.CommentCount + req.ShareCount + req.RemixCount
+
+	return map[string]interface{}{
+		"content_type":               req.ContentType,
+		"view_count":                 float64(req.ViewCount),
+		"like_count":                 float64(req.LikeCount),
+		"comment_count":              float64(req.CommentCount),
+		"share_count":                float64(req.ShareCount),
+		"remix_count":                float64(req.RemixCount),
+		"engagement_velocity":        req.EngagementVelocity,
+		"minutes_since_creation":     float64(req.TimeElapsed),
+		"like_rate":                  rate(req.LikeCount),
+		"comment_rate":               rate(req.CommentCount),
+		"share_rate":                 rate(req.ShareCount),
+		"remix_rate":                 rate(req.RemixCount),
+		"engagement_per_hour":        float64(totalEngagement) / hoursElapsed,
+		"previous_score":             req.PreviousScore,
+		"previous_viral_probability": req.PreviousViralProbability,
+		"sentiment_score":            req.SentimentScore,
+		"sentiment_count":            float64(req.SentimentCount),
+	}
+}
+
+// predictViralityWithGemini asks Gemini to score virality from the engagement features
+func (v *VertexAIClient) predictViralityWithGemini(req models.ViralPredictionRequest) (*models.ViralPredictionResponse, error) {
+	userPrompt, err := viralityUserPrompt(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := v.callGemini(config.GeminiUseCaseVirality, viralitySystemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseViralityResponse(response)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Source = ViralPredictionModeGemini
+	return result, nil
+}
+
+// viralitySystemPrompt asks a language model for a virality estimate as JSON
+const viralitySystemPrompt = `You are a social media virality analyst. Given engagement features of a post, estimate how likely it is to go viral.
+Return ONLY a valid JSON object with these exact fields:
+- viral_probability: number between 0 and 1
+- confidence: number between 0 and 1, lower when there is little engagement data
+- predicted_peak_time: integer, minutes from now until engagement peaks
+
+Example response:
+{
+  "viral_probability": 0.42,
+  "confidence": 0.7,
+  "predicted_peak_time": 90
+}
+
+Do not include any explanation, only return the JSON object.`
+
+// viralityUserPrompt lists the engagement features of a post for the model
+func viralityUserPrompt(req models.ViralPredictionRequest) (string, error) {
+	features, err := json.MarshalIndent(viralFeatures(req), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode features: %w", err)
+	}
+	return fmt.Sprintf("Engagement features:\n%s\n\nEstimate the virality of this post.", features), nil
+}
+
+// parseViralityResponse decodes the prediction JSON returned by a model
+func parseViralityResponse(response string) (*models.ViralPredictionResponse, error) {
+	// Tolerate extra text around the JSON object
+	jsonStart := strings.Index(response, "{")
+	jsonEnd := strings.LastIndex(response, "}")
+	if jsonStart < 0 || jsonEnd <= jsonStart {
+		return nil, fmt.Errorf("no JSON object in model response")
+	}
+
+	var result models.ViralPredictionResponse
+	if err := json.Unmarshal([]byte(response[jsonStart:jsonEnd+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse prediction: %w", err)
+	}
+	return normalizePrediction(&result), nil
+}
+
+// predictViralityWithEndpoint calls a custom-trained model deployed to a Vertex AI endpoint.
+// The model receives the feature map as a single instance and must return either a number
+// (the viral probability) or an object with the ViralPredictionResponse fields.
+func (v *VertexAIClient) predictViralityWithEndpoint(req models.ViralPredictionRequest) (*models.ViralPredictionResponse, error) {
+	if v.predictor == nil {
+		return nil, fmt.Errorf("prediction client not configured")
+	}
+
+	instance, err := structpb.NewStruct(viralFeatures(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode features: %w", err)
+	}
+
+	var resp *aiplatformpb.PredictResponse
+	err = v.guard(config.VertexAIRateKeyPrediction, func(ctx context.Context) error {
+		Quotas.Record(QuotaVertexAI, 1)
+		var err error
+		resp, err = v.predictor.Predict(ctx, &aiplatformpb.PredictRequest{
+			Endpoint: fmt.Sprintf("projects/%s/locations/%s/endpoints/%s",
+				v.config.GoogleCloudProject, v.config.VertexAILocation, v.config.VertexAIEndpointID),
+			Instances: []*structpb.Value{structpb.NewStructValue(instance)},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("endpoint prediction failed: %w", err)
+	}
+	if len(resp.Predictions) == 0 {
+		return nil, fmt.Errorf("no predictions returned from endpoint")
+	}
+
+	result, err := parseEndpointPrediction(resp.Predictions[0])
+	if err != nil {
+		return nil, err
+	}
+
+	result.Source = ViralPredictionModeEndpoint
+	return normalizePrediction(result), nil
+}
+
+// parseEndpointPrediction converts a single endpoint prediction into a response
+func parseEndpointPrediction(prediction *structpb.Value) (*models.ViralPredictionResponse, error) {
+	switch kind := prediction.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		return &models.ViralPredictionResponse{ViralProbability: kind.NumberValue}, nil
+	case *structpb.Value_StructValue:
+		data, err := json.Marshal(kind.StructValue.AsMap())
+		if err != nil {
+			return nil, err
+		}
+		var result models.ViralPredictionResponse
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse endpoint prediction: %w", err)
+		}
+		return &result, nil
+	default:
+		return nil, fmt.Errorf("unsupported prediction value type %T", kind)
+	}
+}
+
+// normalizePrediction clamps model output into valid ranges and fills missing fields
+func normalizePrediction(resp *models.ViralPredictionResponse) *models.ViralPredictionResponse {
+	resp.ViralProbability = clamp01(resp.ViralProbability)
+	if resp.Confidence <= 0 {
+		resp.Confidence = 0.5
+	}
+	resp.Confidence = clamp01(resp.Confidence)
+	if resp.PredictedPeakTime <= 0 {
+		resp.PredictedPeakTime = 60
+	}
+	return resp
+}
+
+func clamp01(x float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
@@ -6,6 +6,7 @@ import (
 
 	"confluent-viral-intelligence/internal/config"
 	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func TestPredictVirality(t *testing.T) {
@@ -14,7 +15,7 @@ func TestPredictVirality(t *testing.T) {
 		VertexAILocation:   "us-central1",
 	}
 
-	client, err := NewVertexAIClient(context.Background(), cfg)
+	client, err := NewVertexAIClient(context.Background(), cfg, nil)
 	if err != nil {
 		t.Skipf("Skipping test - could not create Vertex AI client: %v", err)
 		return
@@ -108,19 +109,43 @@ func TestPredictVirality(t *testing.T) {
 	}
 }
 
-func TestFallbackKeywordExtraction(t *testing.T) {
-	cfg := &config.Config{
-		GoogleCloudProject: "yarimai",
-		VertexAILocation:   "us-central1",
+func TestViralTiers(t *testing.T) {
+	// Defaults: the built-in score table and alert tiers around the 0.7 threshold
+	cfg := &config.Config{}
+	if p := predictViralityHeuristic(models.ViralPredictionRequest{ViewCount: 120}, cfg).ViralProbability; p != 0.75 {
+		t.Errorf("expected 0.75 from the default table, got %v", p)
+	}
+	if tier, ok := cfg.ViralAlertTierFor(0.72); !ok || tier.Name != config.ViralTierViral {
+		t.Errorf("expected viral tier at 0.72, got %+v", tier)
+	}
+	if _, ok := cfg.ViralAlertTierFor(0.3); ok {
+		t.Error("expected no tier at 0.3")
+	}
+	if cfg.ViralAlertTierRank(config.ViralTierMegaViral) <= cfg.ViralAlertTierRank(config.ViralTierWarming) || cfg.ViralAlertTierRank("") != 0 {
+		t.Error("expected tiers ranked from warming up to mega_viral")
 	}
 
-	client, err := NewVertexAIClient(context.Background(), cfg)
-	if err != nil {
-		t.Skipf("Skipping test - could not create Vertex AI client: %v", err)
-		return
+	// Configured tables replace the defaults
+	cfg = &config.Config{
+		ViralProbabilityThreshold: 0.8,
+		ViralScoreTiers:           []config.ViralScoreTier{{MinScore: 100, Probability: 0.9}, {MinScore: 0, Probability: 0.1}},
+		ViralAlertTiers:           []config.ViralAlertTier{{Name: "hot", MinProbability: 0.85}},
 	}
-	defer client.Close()
+	if p := predictViralityHeuristic(models.ViralPredictionRequest{ViewCount: 120}, cfg).ViralProbability; p != 0.9 {
+		t.Errorf("expected 0.9 from the configured table, got %v", p)
+	}
+	if p := predictViralityHeuristic(models.ViralPredictionRequest{}, cfg).ViralProbability; p != 0.1 {
+		t.Errorf("expected the lowest tier for a zero score, got %v", p)
+	}
+	if tier, ok := cfg.ViralAlertTierFor(0.9); !ok || tier.Name != "hot" {
+		t.Errorf("expected configured tier, got %+v", tier)
+	}
+	if cfg.ViralThreshold() != 0.8 {
+		t.Errorf("expected configured threshold, got %v", cfg.ViralThreshold())
+	}
+}
 
+func TestFallbackKeywordExtraction(t *testing.T) {
 	tests := []struct {
 		name        string
 		prompt      string
@@ -153,7 +178,7 @@ func TestFallbackKeywordExtraction(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp := client.fallbackKeywordExtraction(tt.prompt, tt.contentType)
+			resp := fallbackKeywordExtraction(tt.prompt, tt.contentType)
 
 			// Check keyword count
 			if len(resp.Keywords) < tt.wantMinKw || len(resp.Keywords) > tt.wantMaxKw {
@@ -184,7 +209,7 @@ func TestCaching(t *testing.T) {
 		VertexAILocation:   "us-central1",
 	}
 
-	client, err := NewVertexAIClient(context.Background(), cfg)
+	client, err := NewVertexAIClient(context.Background(), cfg, nil)
 	if err != nil {
 		t.Skipf("Skipping test - could not create Vertex AI client: %v", err)
 		return
@@ -204,14 +229,9 @@ func TestCaching(t *testing.T) {
 	client.putInCache(key, value)
 
 	// Get from cache
-	cached := client.getFromCache(key)
-	if cached == nil {
-		t.Fatal("Expected cached value, got nil")
-	}
-
-	cachedResp, ok := cached.(*models.KeywordExtractionResponse)
-	if !ok {
-		t.Fatal("Cached value is not KeywordExtractionResponse")
+	var cachedResp models.KeywordExtractionResponse
+	if !client.getFromCache(key, &cachedResp) {
+		t.Fatal("Expected cached value, got miss")
 	}
 
 	if len(cachedResp.Keywords) != len(value.Keywords) {
@@ -219,11 +239,71 @@ func TestCaching(t *testing.T) {
 	}
 
 	// Test cache expiration
-	client.cleanExpiredCache()
+	client.EvictExpiredCache(context.Background())
 
 	// Cache should still exist (not expired yet)
-	cached = client.getFromCache(key)
-	if cached == nil {
+	if !client.getFromCache(key, &cachedResp) {
 		t.Error("Cache was cleaned too early")
 	}
+
+	if stats := client.CacheStats(); stats.Hits != 2 || stats.Sets != 1 {
+		t.Errorf("Cache stats = %+v, want 2 hits and 1 set", stats)
+	}
+}
+
+func TestViralFeatures(t *testing.T) {
+	features := viralFeatures(models.ViralPredictionRequest{
+		ViewCount:     200,
+		LikeCount:     50,
+		ShareCount:    10,
+		TimeElapsed:   120,
+		ContentType:   "video",
+		PreviousScore: 42,
+	})
+
+	if features["like_rate"] != 0.25 {
+		t.Errorf("like_rate = %v, want 0.25", features["like_rate"])
+	}
+	if features["share_rate"] != 0.05 {
+		t.Errorf("share_rate = %v, want 0.05", features["share_rate"])
+	}
+	if features["engagement_per_hour"] != 30.0 {
+		t.Errorf("engagement_per_hour = %v, want 30", features["engagement_per_hour"])
+	}
+	if features["previous_score"] != 42.0 {
+		t.Errorf("previous_score = %v, want 42", features["previous_score"])
+	}
+
+	// No views must not divide by zero
+	empty := viralFeatures(models.ViralPredictionRequest{})
+	if empty["like_rate"] != 0.0 {
+		t.Errorf("like_rate without views = %v, want 0", empty["like_rate"])
+	}
+}
+
+func TestParseEndpointPrediction(t *testing.T) {
+	number, err := parseEndpointPrediction(structpb.NewNumberValue(1.7))
+	if err != nil {
+		t.Fatalf("parseEndpointPrediction(number) error = %v", err)
+	}
+	if got := normalizePrediction(number); got.ViralProbability != 1.0 || got.PredictedPeakTime != 60 {
+		t.Errorf("normalized number prediction = %+v, want probability 1.0 and peak 60", got)
+	}
+
+	obj, _ := structpb.NewStruct(map[string]interface{}{
+		"viral_probability":   0.8,
+		"confidence":          0.9,
+		"predicted_peak_time": 30,
+	})
+	resp, err := parseEndpointPrediction(structpb.NewStructValue(obj))
+	if err != nil {
+		t.Fatalf("parseEndpointPrediction(struct) error = %v", err)
+	}
+	if resp.ViralProbability != 0.8 || resp.Confidence != 0.9 || resp.PredictedPeakTime != 30 {
+		t.Errorf("struct prediction = %+v", resp)
+	}
+
+	if _, err := parseEndpointPrediction(structpb.NewStringValue("high")); err == nil {
+		t.Error("Expected error for string prediction")
+	}
 }
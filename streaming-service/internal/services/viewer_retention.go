@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Retention of the daily viewer sketches. Sketches of single posts are kept a little longer
+// than the longest cohort range of the retention endpoint.
+const (
+	viewerSketchRetention     = dashboardBucketRetention
+	postViewerSketchRetention = 45 * 24 * time.Hour
+)
+
+// Cohorts the retention endpoint covers at most, and the days after its cohort day a viewer
+// returns on to count towards D7
+const (
+	MaxRetentionCohorts = 30
+	retentionReturnDays = 7
+)
+
+// viewerDayKey identifies the viewer sketch of a post, or of every post, on one day
+type viewerDayKey struct {
+	postID string // empty for the viewers of every post
+	day    time.Time
+	active bool // the sketch of every acting user, viewers and interacting users alike
+}
+
+// viewerSketch is a stored daily viewer sketch
+type viewerSketch struct {
+	PostID    string
+	Day       time.Time
+	Sketch    []byte
+	UpdatedAt time.Time
+	ExpiresAt time.Time // retention cutoff for the sketch
+}
+
+// viewerSketchBytes is the memory accounted for a buffered viewer sketch
+func viewerSketchBytes(key viewerDayKey) int64 {
+	return int64(hllRegisters + len(key.postID) + memoryEntryOverhead)
+}
+
+// RetentionTracker collects the unique viewers of each post, and of every post, per day into
+// HyperLogLog sketches that back returning-viewer cohorts, and the users acting on any post,
+// by viewing, interacting or remixing, into the sketches behind the active user counts. The
+// viewers of each post are also merged into its all-time sketch behind the unique viewers of
+// its trending score. Viewers are
+// buffered in memory and merged into Firestore on every flush; merging is idempotent, so
+// several instances can flush the same day safely. Days are calendar days in the reporting
+// time zone.
+type RetentionTracker struct {
+	firestoreClient *FirestoreClient
+	flusher         *periodicFlusher
+
+	mu      sync.Mutex
+	pending map[viewerDayKey]*HyperLogLog // sketch -> viewers since the last flush
+}
+
+func NewRetentionTracker(firestoreClient *FirestoreClient, flushInterval time.Duration) *RetentionTracker {
+	rt := &RetentionTracker{
+		firestoreClient: firestoreClient,
+		pending:         make(map[viewerDayKey]*HyperLogLog),
+	}
+	rt.flusher = newPeriodicFlusher("viewer retention", flushInterval, rt.Flush)
+	return rt
+}
+
+// Start merges the buffered daily viewer sketches into Firestore every flush interval
+func (rt *RetentionTracker) Start() {
+	logger.Infof("🔁 Starting viewer retention tracker (flush interval %v)", rt.flusher.interval)
+	rt.flusher.start()
+}
+
+// Stop ends the periodic merges and merges the viewers still buffered, so the day's
+// retention survives a restart
+func (rt *RetentionTracker) Stop() {
+	rt.flusher.stop()
+}
+
+// RecordView adds a viewer to the viewers of a post and of every post on the day the view
+// happened. Anonymous views are not counted.
+func (rt *RetentionTracker) RecordView(postID, viewerID string, at time.Time) {
+	if rt == nil || postID == "" || viewerID == "" {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	day := rollupStart(RollupDay, at, rt.firestoreClient.reportingLoc)
+	rt.add(viewerID, viewerDayKey{day: day}, viewerDayKey{postID: postID, day: day}, viewerDayKey{day: day, active: true})
+}
+
+// add adds a user to pending sketches
+func (rt *RetentionTracker) add(userID string, keys ...viewerDayKey) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	for _, key := range keys {
+		sketch, ok := rt.pending[key]
+		if !ok {
+			if !Memory.Reserve(MemoryPoolRetention, viewerSketchBytes(key)) {
+				// Buffered sketches are at their ceiling; write them out early and drop this event
+				rt.flusher.flushEarly()
+				return
+			}
+			sketch = NewHyperLogLog()
+			rt.pending[key] = sketch
+		}
+		sketch.Add(userID)
+	}
+}
+
+// Flush merges the buffered viewers into the stored daily sketches and the all-time sketches
+// of their posts
+func (rt *RetentionTracker) Flush() error {
+	rt.mu.Lock()
+	pending := rt.pending
+	rt.pending = make(map[viewerDayKey]*HyperLogLog)
+	rt.mu.Unlock()
+
+	var lastErr error
+	for key, sketch := range pending {
+		err := rt.firestoreClient.MergeViewerSketch(key, sketch)
+		if err == nil && key.postID != "" {
+			err = rt.firestoreClient.MergePostViewers(key.postID, sketch)
+		}
+		if err != nil {
+			logger.Infof("Failed to merge viewers of %s: %v", rt.firestoreClient.viewerSketchRef(key).ID, err)
+			lastErr = err
+
+			// Keep the viewers for the next flush
+			rt.mu.Lock()
+			if newer, ok := rt.pending[key]; ok {
+				sketch.Merge(newer)
+				Memory.Release(MemoryPoolRetention, viewerSketchBytes(key))
+			}
+			rt.pending[key] = sketch
+			rt.mu.Unlock()
+			continue
+		}
+		Memory.Release(MemoryPoolRetention, viewerSketchBytes(key))
+	}
+
+	if len(pending) > 0 {
+		logger.Debugf("🔁 Flushed %d daily viewer sketches", len(pending))
+	}
+	return lastErr
+}
+
+// viewerSketchRef returns the document of a daily viewer sketch
+func (fc *FirestoreClient) viewerSketchRef(key viewerDayKey) *firestore.DocumentRef {
+	if key.active {
+		return fc.client.Collection("active_users_daily").Doc(key.day.Format("20060102"))
+	}
+	if key.postID != "" {
+		return fc.client.Collection("post_viewers_daily").Doc(key.postID + "_" + key.day.Format("20060102"))
+	}
+	return fc.client.Collection("viewers_daily").Doc(key.day.Format("20060102"))
+}
+
+// MergeViewerSketch folds viewers into a stored daily viewer sketch
+func (fc *FirestoreClient) MergeViewerSketch(key viewerDayKey, sketch *HyperLogLog) error {
+	ref := fc.viewerSketchRef(key)
+	retention := viewerSketchRetention
+	if key.postID != "" {
+		retention = postViewerSketchRetention
+	}
+
+	return fc.client.RunTransaction(fc.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// One read and one write per attempt
+		Quotas.Record(QuotaFirestore, 2)
+
+		merged := NewHyperLogLog()
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var stored viewerSketch
+			if err := doc.DataTo(&stored); err != nil {
+				return fmt.Errorf("failed to parse viewers of %s: %w", ref.ID, err)
+			}
+			if existing, err := HyperLogLogFromBytes(stored.Sketch); err == nil {
+				merged = existing
+			}
+		}
+		merged.Merge(sketch)
+
+		return tx.Set(ref, viewerSketch{
+			PostID:    key.postID,
+			Day:       key.day,
+			Sketch:    merged.Bytes(),
+			UpdatedAt: time.Now(),
+			ExpiresAt: key.day.Add(retention),
+		})
+	})
+}
+
+// GetViewerSketches returns the daily viewer sketches of a post, or of every post when postID
+// is empty, for the days starting at days. Days without viewers have empty sketches.
+func (fc *FirestoreClient) GetViewerSketches(postID string, days []time.Time) ([]*HyperLogLog, error) {
+	keys := make([]viewerDayKey, len(days))
+	for i, day := range days {
+		keys[i] = viewerDayKey{postID: postID, day: day}
+	}
+	return fc.getDaySketches(keys)
+}
+
+// getDaySketches returns stored daily sketches, empty for the missing ones
+func (fc *FirestoreClient) getDaySketches(keys []viewerDayKey) ([]*HyperLogLog, error) {
+	refs := make([]*firestore.DocumentRef, len(keys))
+	for i, key := range keys {
+		refs[i] = fc.viewerSketchRef(key)
+	}
+	Quotas.Record(QuotaFirestore, int64(len(refs)))
+	docs, err := fc.client.GetAll(fc.ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	sketches := make([]*HyperLogLog, len(keys))
+	for i, doc := range docs {
+		sketches[i] = NewHyperLogLog()
+		if !doc.Exists() {
+			continue
+		}
+		var stored viewerSketch
+		if err := doc.DataTo(&stored); err != nil {
+			logger.Debugf(" Skipping unreadable viewer sketch %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		if sketch, err := HyperLogLogFromBytes(stored.Sketch); err == nil {
+			sketches[i] = sketch
+		}
+	}
+	return sketches, nil
+}
+
+// RetentionCohort is the viewers of one day, the engagement of that day and how many of the
+// viewers returned 1 and 7 days later. Return rates are null until the return day has ended.
+type RetentionCohort struct {
+	Date           string   `json:"date"`
+	Viewers        uint64   `json:"viewers"`
+	Views          int64    `json:"views"`
+	Likes          int64    `json:"likes"`
+	Comments       int64    `json:"comments"`
+	Shares         int64    `json:"shares"`
+	ViewsPerViewer float64  `json:"viewsPerViewer"`
+	EngagementRate float64  `json:"engagementRate"`
+	D1Returning    *uint64  `json:"d1Returning"`
+	D1Rate         *float64 `json:"d1Rate"`
+	D7Returning    *uint64  `json:"d7Returning"`
+	D7Rate         *float64 `json:"d7Rate"`
+}
+
+// ViewerRetention is the returning-viewer cohorts of a post, or of every post, oldest first.
+// D1Rate and D7Rate are the share of viewers of every cohort with a completed return day who
+// came back on it.
+type ViewerRetention struct {
+	PostID       string            `json:"postId,omitempty"`
+	D1Rate       float64           `json:"d1Rate"`
+	D7Rate       float64           `json:"d7Rate"`
+	Cohorts      []RetentionCohort `json:"cohorts"`
+	CalculatedAt time.Time         `json:"calculatedAt"`
+}
+
+// GetViewerRetention returns the daily viewer cohorts of the last days, ending today, of a
+// post or of every post when postID is empty
+func (da *DashboardAnalytics) GetViewerRetention(postID string, days int) (*ViewerRetention, error) {
+	logger.Debugf("📊 Calculating viewer retention over %d days...", days)
+
+	now := time.Now()
+	loc := da.firestoreClient.reportingLoc
+	starts := rollupStarts(RollupDay, StartOfDay(now, loc).AddDate(0, 0, 1-days), now, loc)
+
+	sketches, err := da.firestoreClient.GetViewerSketches(postID, starts)
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := da.firestoreClient.GetEngagementRollups(postID, RollupDay, starts)
+	if err != nil {
+		return nil, err
+	}
+
+	retention := buildViewerRetention(starts, sketches, rollups, loc)
+	retention.PostID = postID
+	retention.CalculatedAt = now
+
+	logger.Infof("✅ Viewer retention calculated: D1 %.1f%%, D7 %.1f%%", retention.D1Rate*100, retention.D7Rate*100)
+	return retention, nil
+}
+
+// buildViewerRetention builds one cohort per day from the viewer sketches and daily rollups of
+// consecutive days. The last day is today, which has not ended.
+func buildViewerRetention(days []time.Time, sketches []*HyperLogLog, rollups []EngagementRollup, loc *time.Location) *ViewerRetention {
+	retention := &ViewerRetention{Cohorts: []RetentionCohort{}}
+	var d1Viewers, d1Returning, d7Viewers, d7Returning uint64
+
+	today := len(days) - 1
+	for i := range days {
+		rollup := rollups[i]
+		cohort := RetentionCohort{
+			Date:     ReportingDate(days[i], loc),
+			Viewers:  sketches[i].Count(),
+			Views:    rollup.Views,
+			Likes:    rollup.Likes,
+			Comments: rollup.Comments,
+			Shares:   rollup.Shares,
+		}
+		if cohort.Viewers > 0 {
+			cohort.ViewsPerViewer = float64(cohort.Views) / float64(cohort.Viewers)
+		}
+		if cohort.Views > 0 {
+			cohort.EngagementRate = (float64(cohort.Likes+cohort.Comments+cohort.Shares) / float64(cohort.Views)) * 100
+		}
+
+		if i+1 < today {
+			returning := estimateIntersection(sketches[i], sketches[i+1])
+			cohort.D1Returning, cohort.D1Rate = &returning, returnRate(returning, cohort.Viewers)
+			d1Viewers, d1Returning = d1Viewers+cohort.Viewers, d1Returning+returning
+		}
+		if i+retentionReturnDays < today {
+			returning := estimateIntersection(sketches[i], sketches[i+retentionReturnDays])
+			cohort.D7Returning, cohort.D7Rate = &returning, returnRate(returning, cohort.Viewers)
+			d7Viewers, d7Returning = d7Viewers+cohort.Viewers, d7Returning+returning
+		}
+		retention.Cohorts = append(retention.Cohorts, cohort)
+	}
+
+	retention.D1Rate = *returnRate(d1Returning, d1Viewers)
+	retention.D7Rate = *returnRate(d7Returning, d7Viewers)
+	return retention
+}
+
+// returnRate returns the share of viewers who returned, 0 without viewers
+func returnRate(returning, viewers uint64) *float64 {
+	rate := 0.0
+	if viewers > 0 {
+		rate = float64(returning) / float64(viewers)
+	}
+	return &rate
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetentionTracker_RecordView(t *testing.T) {
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+	rt := NewRetentionTracker(&FirestoreClient{reportingLoc: istanbul}, time.Minute)
+
+	// 22:30 UTC is already the next day in Istanbul
+	at := time.Date(2024, 5, 10, 22, 30, 0, 0, time.UTC)
+	rt.RecordView("post-1", "viewer-1", at)
+	rt.RecordView("post-1", "viewer-1", at)
+	rt.RecordView("post-2", "viewer-2", at)
+	rt.RecordView("post-1", "", at)
+
+	day := time.Date(2024, 5, 11, 0, 0, 0, 0, istanbul)
+	if viewers := rt.pending[viewerDayKey{day: day}].Count(); viewers != 2 {
+		t.Errorf("Expected 2 viewers of every post, got %d", viewers)
+	}
+	if viewers := rt.pending[viewerDayKey{postID: "post-1", day: day}].Count(); viewers != 1 {
+		t.Errorf("Expected 1 viewer of post-1, got %d", viewers)
+	}
+	if users := rt.pending[viewerDayKey{day: day, active: true}].Count(); users != 2 {
+		t.Errorf("Expected 2 active users, got %d", users)
+	}
+	if len(rt.pending) != 4 {
+		t.Errorf("Expected 4 pending sketches, got %d", len(rt.pending))
+	}
+	Memory.Release(MemoryPoolRetention, Memory.Used(MemoryPoolRetention))
+
+	var disabled *RetentionTracker
+	disabled.RecordView("post-1", "viewer-1", at)
+}
+
+func TestBuildViewerRetention(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	days := rollupStarts(RollupDay, from, from.AddDate(0, 0, 10), time.UTC)
+
+	// 1000 viewers on the first day, half of them back the next day and a fifth a week later
+	sketches := make([]*HyperLogLog, len(days))
+	for i := range sketches {
+		sketches[i] = NewHyperLogLog()
+	}
+	for v := 0; v < 1000; v++ {
+		sketches[0].Add(fmt.Sprintf("viewer-%d", v))
+		if v%2 == 0 {
+			sketches[1].Add(fmt.Sprintf("viewer-%d", v))
+		}
+		if v%5 == 0 {
+			sketches[7].Add(fmt.Sprintf("viewer-%d", v))
+		}
+	}
+	rollups := make([]EngagementRollup, len(days))
+	rollups[0] = EngagementRollup{Views: 2000, Likes: 150, Comments: 30, Shares: 20}
+
+	retention := buildViewerRetention(days, sketches, rollups, time.UTC)
+	if len(retention.Cohorts) != 10 {
+		t.Fatalf("Expected 10 cohorts, got %d", len(retention.Cohorts))
+	}
+	first := retention.Cohorts[0]
+	if first.Date != "2024-05-01" || first.ViewsPerViewer < 1.9 || first.ViewsPerViewer > 2.1 || first.EngagementRate != 10 {
+		t.Errorf("Unexpected first cohort %+v", first)
+	}
+	if first.D1Rate == nil || *first.D1Rate < 0.45 || *first.D1Rate > 0.55 {
+		t.Errorf("Expected a D1 rate near 0.5, got %v", first.D1Rate)
+	}
+	if first.D7Rate == nil || *first.D7Rate < 0.15 || *first.D7Rate > 0.25 {
+		t.Errorf("Expected a D7 rate near 0.2, got %v", first.D7Rate)
+	}
+
+	// Return days that have not ended yet leave the rates open
+	if last := retention.Cohorts[9]; last.D1Rate != nil || last.D7Rate != nil {
+		t.Errorf("Expected today's cohort to have no return rates, got %+v", last)
+	}
+	if retention.Cohorts[8].D1Rate != nil || retention.Cohorts[7].D1Rate == nil || retention.Cohorts[2].D7Rate != nil {
+		t.Error("Expected D1 rates up to the day before yesterday and D7 rates up to 8 days ago")
+	}
+
+	// The overall rates weigh in every cohort whose return day has ended, also the later
+	// cohorts nobody returned from
+	if retention.D1Rate >= *first.D1Rate || retention.D7Rate >= *first.D7Rate || retention.D7Rate == 0 {
+		t.Errorf("Unexpected overall rates D1 %.3f, D7 %.3f", retention.D1Rate, retention.D7Rate)
+	}
+}
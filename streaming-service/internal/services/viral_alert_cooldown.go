@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"google.golang.org/api/iterator"
+)
+
+// viralAlertState is the last viral alert sent for a post
+type viralAlertState struct {
+	PostID    string
+	Tier      string
+	AlertedAt time.Time
+}
+
+// ViralAlertCooldown keeps posts whose viral probability oscillates around a tier threshold
+// from being announced over and over. Once a post is alerted at a tier, it is alerted again
+// within the cooldown only when it reaches a higher tier. Alert states live in memory and are
+// persisted to Firestore on every flush, so a restart keeps the cooldowns; as trending scores
+// of a post are consumed from one partition, one instance decides the alerts of each post.
+type ViralAlertCooldown struct {
+	firestoreClient *FirestoreClient
+	config          *config.Config
+	ctx             context.Context
+	cancel          context.CancelFunc
+	cooldown        time.Duration
+	flushInterval   time.Duration
+
+	mu     sync.Mutex
+	states map[string]viralAlertState
+	dirty  map[string]bool // posts alerted since the last flush
+}
+
+// NewViralAlertCooldown creates a cooldown that remembers each post's alerted tier for
+// cooldown and persists alert states every flushInterval
+func NewViralAlertCooldown(firestoreClient *FirestoreClient, cfg *config.Config, cooldown, flushInterval time.Duration) *ViralAlertCooldown {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ViralAlertCooldown{
+		firestoreClient: firestoreClient,
+		config:          cfg,
+		ctx:             ctx,
+		cancel:          cancel,
+		cooldown:        cooldown,
+		flushInterval:   flushInterval,
+		states:          make(map[string]viralAlertState),
+		dirty:           make(map[string]bool),
+	}
+}
+
+// Start loads the alert states still cooling down and begins the periodic flush loop
+func (vc *ViralAlertCooldown) Start() {
+	logger.Infof("🔕 Starting viral alert cooldown (%v, flush interval %v)", vc.cooldown, vc.flushInterval)
+
+	states, err := vc.firestoreClient.LoadViralAlertStates(time.Now().Add(-vc.cooldown))
+	if err != nil {
+		logger.Warnf("Failed to load viral alert states, cooldowns start empty: %v", err)
+	}
+	vc.mu.Lock()
+	for _, state := range states {
+		vc.states[state.PostID] = state
+	}
+	vc.mu.Unlock()
+
+	ticker := time.NewTicker(vc.flushInterval)
+	go func() {
+		for {
+			select {
+			case <-vc.ctx.Done():
+				ticker.Stop()
+				logger.Info("🛑 Viral alert cooldown stopped")
+				return
+			case <-ticker.C:
+				if err := vc.Flush(); err != nil {
+					logger.Errorf("❌ Viral alert state flush failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the flush loop and persists the alert states not yet written
+func (vc *ViralAlertCooldown) Stop() {
+	vc.cancel()
+	if err := vc.Flush(); err != nil {
+		logger.Errorf("❌ Final viral alert state flush failed: %v", err)
+	}
+}
+
+// Allow reports whether a post reaching tier should be alerted, and records the alert when
+// it should. A nil cooldown allows every alert.
+func (vc *ViralAlertCooldown) Allow(postID, tier string, now time.Time) bool {
+	if vc == nil {
+		return true
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	if last, ok := vc.states[postID]; ok && now.Sub(last.AlertedAt) < vc.cooldown &&
+		vc.config.ViralAlertTierRank(tier) <= vc.config.ViralAlertTierRank(last.Tier) {
+		logger.Debugf(" Viral alert of post %s at %s held back: alerted at %s %v ago",
+			postID, tier, last.Tier, now.Sub(last.AlertedAt).Round(time.Second))
+		return false
+	}
+
+	vc.states[postID] = viralAlertState{PostID: postID, Tier: tier, AlertedAt: now}
+	vc.dirty[postID] = true
+	return true
+}
+
+// Flush writes the alert states recorded since the last flush and forgets those whose
+// cooldown has passed
+func (vc *ViralAlertCooldown) Flush() error {
+	now := time.Now()
+	vc.mu.Lock()
+	pending := make([]viralAlertState, 0, len(vc.dirty))
+	for postID := range vc.dirty {
+		pending = append(pending, vc.states[postID])
+	}
+	vc.dirty = make(map[string]bool)
+	for postID, state := range vc.states {
+		if now.Sub(state.AlertedAt) >= vc.cooldown {
+			delete(vc.states, postID)
+		}
+	}
+	vc.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := vc.firestoreClient.SaveViralAlertStates(pending); err != nil {
+		// Keep the states for the next flush unless newer alerts replaced them
+		vc.mu.Lock()
+		for _, state := range pending {
+			if current, ok := vc.states[state.PostID]; ok && current.AlertedAt.Equal(state.AlertedAt) {
+				vc.dirty[state.PostID] = true
+			}
+		}
+		vc.mu.Unlock()
+		return err
+	}
+
+	logger.Debugf("🔕 Flushed alert states of %d posts", len(pending))
+	return nil
+}
+
+// SaveViralAlertStates stores the last viral alert of each post
+func (fc *FirestoreClient) SaveViralAlertStates(states []viralAlertState) error {
+	bw := fc.client.BulkWriter(fc.ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(states))
+	for _, state := range states {
+		Quotas.Record(QuotaFirestore, 1)
+		job, err := bw.Set(fc.client.Collection("viral_alert_states").Doc(state.PostID), state)
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadViralAlertStates returns the viral alert states of the posts alerted since the given time
+func (fc *FirestoreClient) LoadViralAlertStates(since time.Time) ([]viralAlertState, error) {
+	iter := fc.client.Collection("viral_alert_states").
+		Where("AlertedAt", ">=", since).
+		Documents(fc.ctx)
+	defer iter.Stop()
+
+	var states []viralAlertState
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return states, err
+		}
+		Quotas.Record(QuotaFirestore, 1)
+
+		var state viralAlertState
+		if err := doc.DataTo(&state); err != nil {
+			continue
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
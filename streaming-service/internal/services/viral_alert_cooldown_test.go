@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+)
+
+func TestViralAlertCooldown_Allow(t *testing.T) {
+	cfg := &config.Config{ViralAlertTiers: []config.ViralAlertTier{
+		{Name: config.ViralTierMegaViral, MinProbability: 0.9},
+		{Name: config.ViralTierViral, MinProbability: 0.7},
+		{Name: config.ViralTierWarming, MinProbability: 0.5},
+	}}
+	cooldown := NewViralAlertCooldown(nil, cfg, time.Hour, time.Minute)
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		tier    string
+		after   time.Duration
+		allowed bool
+	}{
+		{config.ViralTierViral, 0, true},
+		// Dipping below 0.7 and back within the cooldown is not news
+		{config.ViralTierViral, 5 * time.Minute, false},
+		{config.ViralTierWarming, 10 * time.Minute, false},
+		// Upgrades always are
+		{config.ViralTierMegaViral, 15 * time.Minute, true},
+		{config.ViralTierViral, 20 * time.Minute, false},
+		// Once the cooldown of the last alert passed, the post is alerted again
+		{config.ViralTierViral, 76 * time.Minute, true},
+	}
+	for i, step := range steps {
+		if allowed := cooldown.Allow("post-1", step.tier, start.Add(step.after)); allowed != step.allowed {
+			t.Errorf("Step %d (%s after %v): expected allowed=%v", i, step.tier, step.after, step.allowed)
+		}
+	}
+
+	if !cooldown.Allow("post-2", config.ViralTierViral, start) {
+		t.Error("Expected cooldowns to be per post")
+	}
+	if len(cooldown.dirty) != 2 {
+		t.Errorf("Expected the alerted posts to be pending persistence, got %d", len(cooldown.dirty))
+	}
+
+	var disabled *ViralAlertCooldown
+	if !disabled.Allow("post-1", config.ViralTierViral, start) || !disabled.Allow("post-1", config.ViralTierViral, start) {
+		t.Error("Expected a nil cooldown to allow every alert")
+	}
+}
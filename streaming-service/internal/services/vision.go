@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/vertexai/genai"
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+const (
+	// Visual keywords and objects kept per post
+	maxVisualTerms = 10
+
+	// Dominant colors kept per post
+	maxVisualColors = 5
+
+	// Prompt and visual keywords kept together on a post
+	maxMergedKeywords = 15
+)
+
+// AnalyzeMedia runs Gemini vision on an output image or video and describes what it shows
+func (v *VertexAIClient) AnalyzeMedia(url string) (*models.VisualAnalysis, error) {
+	cacheKey := "vision:" + url
+	var cached models.VisualAnalysis
+	if v.getFromCache(cacheKey, &cached) {
+		return &cached, nil
+	}
+
+	part, err := visionPart(url)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := `You are an AI content analyzer. Describe the attached image or video.
+Return ONLY a valid JSON object with these exact fields, all text in English:
+- keywords: array of 5-10 keywords describing the subject, setting and style (strings)
+- objects: array of up to 10 objects clearly visible (strings)
+- dominant_colors: array of up to 5 color names, most prominent first (strings)
+- nsfw_likelihood: number between 0 (safe for work) and 1 (sexually explicit or graphic)
+
+Example response:
+{
+  "keywords": ["lighthouse", "seascape", "sunset", "oil painting", "coast"],
+  "objects": ["lighthouse", "cliff", "waves", "seagull"],
+  "dominant_colors": ["orange", "navy blue", "white"],
+  "nsfw_likelihood": 0.02
+}
+
+Do not include any explanation, only return the JSON object.`
+
+	response, err := v.callGeminiParts(config.GeminiUseCaseVision, genai.Text(prompt), part)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tolerate extra text around the JSON object
+	jsonStart := strings.Index(response, "{")
+	jsonEnd := strings.LastIndex(response, "}")
+	if jsonStart < 0 || jsonEnd <= jsonStart {
+		return nil, fmt.Errorf("no JSON object in gemini response")
+	}
+
+	var result models.VisualAnalysis
+	if err := json.Unmarshal([]byte(response[jsonStart:jsonEnd+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse visual analysis: %w", err)
+	}
+	normalizeVisualAnalysis(&result)
+
+	v.putInCache(cacheKey, &result)
+	return &result, nil
+}
+
+// visionPart references gs:// images and videos directly and downloads http(s) images to send
+// them inline
+func visionPart(url string) (genai.Part, error) {
+	if part, ok := mediaPart(url); ok {
+		return part, nil
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("unsupported media URL %q", url)
+	}
+
+	resp, err := mediaHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("media download returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMediaDownloadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %w", err)
+	}
+	if len(data) > maxMediaDownloadBytes {
+		return nil, fmt.Errorf("media larger than %d bytes", maxMediaDownloadBytes)
+	}
+
+	mimeType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("unsupported media type %q", mimeType)
+	}
+	return genai.Blob{MIMEType: mimeType, Data: data}, nil
+}
+
+// normalizeVisualAnalysis cleans up the lists returned by the model and clamps the likelihood
+func normalizeVisualAnalysis(result *models.VisualAnalysis) {
+	result.Keywords = mergeTerms(nil, result.Keywords, maxVisualTerms)
+	result.Objects = mergeTerms(nil, result.Objects, maxVisualTerms)
+	result.DominantColors = mergeTerms(nil, result.DominantColors, maxVisualColors)
+	result.NSFWLikelihood = clamp01(result.NSFWLikelihood)
+}
+
+// mergeVisualKeywords adds the visual keywords and objects after the prompt-derived keywords.
+// They are English, so they only join the keywords themselves for English prompts.
+func mergeVisualKeywords(result *models.KeywordExtractionResponse, visual *models.VisualAnalysis) {
+	visualTerms := append(append([]string{}, visual.Keywords...), visual.Objects...)
+
+	result.EnglishKeywords = mergeTerms(result.EnglishKeywords, visualTerms, maxMergedKeywords)
+	if result.Language == LanguageEnglish {
+		result.Keywords = mergeTerms(result.Keywords, visualTerms, maxMergedKeywords)
+	}
+}
+
+// mergeTerms appends lowercased extra terms to base, skipping empty and duplicate ones, until
+// limit terms are kept
+func mergeTerms(base, extra []string, limit int) []string {
+	seen := make(map[string]bool, len(base)+len(extra))
+	merged := make([]string, 0, min(len(base)+len(extra), limit))
+	for _, term := range base {
+		seen[strings.ToLower(term)] = true
+		if len(merged) < limit {
+			merged = append(merged, term)
+		}
+	}
+	for _, term := range extra {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if len(merged) >= limit {
+			break
+		}
+		if term == "" || seen[term] {
+			continue
+		}
+		seen[term] = true
+		merged = append(merged, term)
+	}
+	return merged
+}
+
+// SaveVisualAnalysis stores what Gemini vision saw in a post's output on the post document
+func (fc *FirestoreClient) SaveVisualAnalysis(postID string, visual *models.VisualAnalysis) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.client.Collection("posts").Doc(postID).Set(fc.ctx, map[string]interface{}{
+		"visual": map[string]interface{}{
+			"keywords":        visual.Keywords,
+			"objects":         visual.Objects,
+			"dominant_colors": visual.DominantColors,
+			"nsfw_likelihood": visual.NSFWLikelihood,
+			"analyzed_at":     time.Now(),
+		},
+	}, firestore.MergeAll)
+	return err
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/vertexai/genai"
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestMergeVisualKeywords(t *testing.T) {
+	visual := &models.VisualAnalysis{
+		Keywords: []string{"sunset", "seascape"},
+		Objects:  []string{"lighthouse", "waves"},
+	}
+
+	en := &models.KeywordExtractionResponse{
+		Language:        LanguageEnglish,
+		Keywords:        []string{"lighthouse", "sunset"},
+		EnglishKeywords: []string{"lighthouse", "sunset"},
+	}
+	mergeVisualKeywords(en, visual)
+	want := []string{"lighthouse", "sunset", "seascape", "waves"}
+	if !reflect.DeepEqual(en.Keywords, want) || !reflect.DeepEqual(en.EnglishKeywords, want) {
+		t.Errorf("expected %v, got %v and %v", want, en.Keywords, en.EnglishKeywords)
+	}
+
+	es := &models.KeywordExtractionResponse{
+		Language:        LanguageSpanish,
+		Keywords:        []string{"faro"},
+		EnglishKeywords: []string{"lighthouse"},
+	}
+	mergeVisualKeywords(es, visual)
+	if !reflect.DeepEqual(es.Keywords, []string{"faro"}) {
+		t.Errorf("expected spanish keywords untouched, got %v", es.Keywords)
+	}
+	if !reflect.DeepEqual(es.EnglishKeywords, []string{"lighthouse", "sunset", "seascape", "waves"}) {
+		t.Errorf("expected visual terms in english keywords, got %v", es.EnglishKeywords)
+	}
+}
+
+func TestNormalizeVisualAnalysis(t *testing.T) {
+	result := &models.VisualAnalysis{
+		Keywords:       []string{" Sunset", "sunset", "", "Coast"},
+		DominantColors: []string{"orange", "blue", "white", "black", "red", "green"},
+		NSFWLikelihood: 1.4,
+	}
+	normalizeVisualAnalysis(result)
+
+	if !reflect.DeepEqual(result.Keywords, []string{"sunset", "coast"}) {
+		t.Errorf("expected cleaned keywords, got %v", result.Keywords)
+	}
+	if len(result.DominantColors) != maxVisualColors {
+		t.Errorf("expected %d colors, got %v", maxVisualColors, result.DominantColors)
+	}
+	if result.NSFWLikelihood != 1 {
+		t.Errorf("expected likelihood clamped to 1, got %v", result.NSFWLikelihood)
+	}
+}
+
+func TestVisionPart(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	part, err := visionPart(server.URL + "/0.png")
+	if err != nil {
+		t.Fatalf("expected inline image, got %v", err)
+	}
+	if blob, ok := part.(genai.Blob); !ok || blob.MIMEType != "image/png" {
+		t.Errorf("expected png blob, got %#v", part)
+	}
+
+	if part, err := visionPart("gs://outputs/post/0.mp4"); err != nil {
+		t.Errorf("expected gs:// video to be referenced, got %v", err)
+	} else if _, ok := part.(genai.FileData); !ok {
+		t.Errorf("expected file data part, got %#v", part)
+	}
+
+	for _, url := range []string{server.URL + "/page", "ftp://outputs/0.png"} {
+		if _, err := visionPart(url); err == nil {
+			t.Errorf("%s: expected an error", url)
+		}
+	}
+}
@@ -0,0 +1,669 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+// Events webhooks can subscribe to
+const (
+	WebhookEventViralAlert       = "viral_alert"        // a post reached a higher viral tier
+	WebhookEventScoreThreshold   = "score_threshold"    // a post's trending score crossed the webhook's threshold
+	WebhookEventNewTrendingEntry = "new_trending_entry" // a post entered the top trending posts
+	WebhookEventTest             = "test"               // sent on request by the test endpoint only
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{WebhookEventViralAlert, WebhookEventScoreThreshold, WebhookEventNewTrendingEntry}
+
+// Headers of webhook deliveries
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	// How long the registered webhooks are cached before they are read again; changes made
+	// through this instance apply at once
+	webhookCacheTTL = 30 * time.Second
+
+	// Deliveries waiting for a worker; events beyond are dropped and logged
+	webhookQueueSize = 1000
+	webhookWorkers   = 4
+
+	// Bytes of a receiver's response kept in the delivery log
+	maxWebhookResponseBytes = 512
+)
+
+// Webhook is an external URL that receives events by HTTP POST. The secret signs every
+// delivery and is only returned when the webhook is created.
+type Webhook struct {
+	ID             string    `json:"id"`
+	URL            string    `json:"url"`
+	Events         []string  `json:"events"`
+	ScoreThreshold float64   `json:"score_threshold,omitempty"` // trending score of score_threshold events
+	Secret         string    `json:"secret,omitempty"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook is active and receives event
+func (w *Webhook) Subscribes(event string) bool {
+	return w.Active && slices.Contains(w.Events, event)
+}
+
+// WebhookValidationError names the field of a webhook that is invalid
+type WebhookValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *WebhookValidationError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidateWebhook checks what the request bindings cannot: that the URL is an absolute http(s)
+// URL, that the events are known and that score_threshold subscribers set a threshold
+func ValidateWebhook(hook Webhook) error {
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return &WebhookValidationError{Field: "url", Message: "must be an absolute http or https URL"}
+	}
+	if len(hook.Events) == 0 {
+		return &WebhookValidationError{Field: "events", Message: "must list at least one event"}
+	}
+	for _, event := range hook.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return &WebhookValidationError{Field: "events", Message: fmt.Sprintf("has unknown event %q", event)}
+		}
+	}
+	if slices.Contains(hook.Events, WebhookEventScoreThreshold) && hook.ScoreThreshold <= 0 {
+		return &WebhookValidationError{Field: "score_threshold", Message: "must be above 0 to receive score_threshold events"}
+	}
+	return nil
+}
+
+// WebhookDelivery is the log entry of one event sent to a webhook
+type WebhookDelivery struct {
+	ID          string    `json:"id"`
+	WebhookID   string    `json:"webhook_id"`
+	Event       string    `json:"event"`
+	Attempts    int       `json:"attempts"`
+	Delivered   bool      `json:"delivered"`
+	StatusCode  int       `json:"status_code,omitempty"` // of the last attempt
+	Error       string    `json:"error,omitempty"`       // of the last attempt
+	Response    string    `json:"response,omitempty"`    // start of the last response body
+	CreatedAt   time.Time `json:"created_at"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// WebhookPayload is the JSON body of a delivery
+type WebhookPayload struct {
+	ID        string      `json:"id"` // delivery ID, the same on every retry
+	Event     string      `json:"event"`
+	Timestamp string      `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// ScoreThresholdData is the data of a score_threshold event
+type ScoreThresholdData struct {
+	PostID        string  `json:"post_id"`
+	Threshold     float64 `json:"threshold"`
+	Score         float64 `json:"score"`
+	PreviousScore float64 `json:"previous_score"`
+	ViewCount     int64   `json:"view_count"`
+}
+
+// TrendingEntryData is the data of a new_trending_entry event
+type TrendingEntryData struct {
+	PostID string  `json:"post_id"`
+	Rank   int     `json:"rank"` // 1 is the top post
+	Score  float64 `json:"score"`
+}
+
+// WebhookTestData is the data of a test event
+type WebhookTestData struct {
+	WebhookID string `json:"webhook_id"`
+	Message   string `json:"message"`
+}
+
+// SignWebhook returns the signature header of a delivery body sent at timestamp:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>" keyed by the secret>".
+// Receivers recompute it to check the delivery came from us, and reject old timestamps to
+// prevent replays.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// webhookJob is an event waiting to be delivered to one webhook
+type webhookJob struct {
+	hook     Webhook
+	delivery WebhookDelivery
+	body     []byte
+}
+
+// WebhookDispatcher delivers events to the registered webhooks. Deliveries are signed with
+// the webhook's secret and retried with exponential backoff until one gets a 2xx answer or
+// the attempts run out; the outcome of each is logged in Firestore. Viral alerts and score
+// thresholds are raised by the instance that consumes the post's trending scores, so each is
+// delivered once; the trending list is watched by every processing instance.
+type WebhookDispatcher struct {
+	firestoreClient *FirestoreClient
+	httpClient      *http.Client
+	ctx             context.Context
+	cancel          context.CancelFunc
+	queue           chan webhookJob
+	wg              sync.WaitGroup
+
+	maxAttempts   int
+	retryBackoff  time.Duration
+	trendingEvery time.Duration
+	trendingSize  int
+
+	mu       sync.Mutex
+	hooks    []Webhook
+	loadedAt time.Time
+	trending map[string]bool // top posts at the last check, nil before the first
+}
+
+// NewWebhookDispatcher creates a dispatcher with the webhook settings of cfg
+func NewWebhookDispatcher(firestoreClient *FirestoreClient, cfg *config.Config) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WebhookDispatcher{
+		firestoreClient: firestoreClient,
+		httpClient:      &http.Client{Timeout: time.Duration(cfg.WebhookTimeoutSeconds) * time.Second},
+		ctx:             ctx,
+		cancel:          cancel,
+		queue:           make(chan webhookJob, webhookQueueSize),
+		maxAttempts:     max(cfg.WebhookMaxAttempts, 1),
+		retryBackoff:    time.Duration(cfg.WebhookRetryBackoffSeconds) * time.Second,
+		trendingEvery:   time.Duration(cfg.WebhookTrendingCheckSeconds) * time.Second,
+		trendingSize:    cfg.WebhookTrendingSize,
+	}
+}
+
+// Start begins delivering queued events and, when configured, watching the trending list
+func (wd *WebhookDispatcher) Start() {
+	logger.Infof("🪝 Starting webhook dispatcher (%d attempts, first retry after %v)", wd.maxAttempts, wd.retryBackoff)
+
+	for i := 0; i < webhookWorkers; i++ {
+		wd.wg.Add(1)
+		go func() {
+			defer wd.wg.Done()
+			for {
+				select {
+				case <-wd.ctx.Done():
+					return
+				case job := <-wd.queue:
+					wd.attempt(job)
+				}
+			}
+		}()
+	}
+
+	if wd.trendingEvery > 0 && wd.trendingSize > 0 {
+		ticker := time.NewTicker(wd.trendingEvery)
+		go func() {
+			for {
+				select {
+				case <-wd.ctx.Done():
+					ticker.Stop()
+					return
+				case <-ticker.C:
+					if err := wd.CheckTrending(); err != nil {
+						logger.Errorf("❌ Webhook trending check failed: %v", err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Stop stops delivering; deliveries still queued or waiting for a retry are dropped
+func (wd *WebhookDispatcher) Stop() {
+	wd.cancel()
+	wd.wg.Wait()
+	if pending := len(wd.queue); pending > 0 {
+		logger.Warnf("⚠️ Dropped %d queued webhook deliveries on shutdown", pending)
+	}
+	logger.Info("🛑 Webhook dispatcher stopped")
+}
+
+// ViralAlert delivers a viral alert to the webhooks subscribed to viral_alert
+func (wd *WebhookDispatcher) ViralAlert(alert models.ViralAlert) {
+	if wd == nil {
+		return
+	}
+	wd.publish(WebhookEventViralAlert, alert)
+}
+
+// ScoreChanged delivers a score_threshold event to every webhook whose threshold the post's
+// trending score crossed upwards from previous to current. previous is nil for a new post.
+func (wd *WebhookDispatcher) ScoreChanged(previous, current *models.TrendingScore) {
+	if wd == nil || current == nil {
+		return
+	}
+	previousScore := 0.0
+	if previous != nil {
+		previousScore = previous.Score
+	}
+	if current.Score <= previousScore {
+		return
+	}
+
+	for _, hook := range wd.subscribers(WebhookEventScoreThreshold) {
+		if crossedThreshold(previousScore, current.Score, hook.ScoreThreshold) {
+			wd.enqueue(hook, WebhookEventScoreThreshold, ScoreThresholdData{
+				PostID:        current.PostID,
+				Threshold:     hook.ScoreThreshold,
+				Score:         current.Score,
+				PreviousScore: previousScore,
+				ViewCount:     current.ViewCount,
+			})
+		}
+	}
+}
+
+// crossedThreshold reports whether a score rose from below threshold to at least it
+func crossedThreshold(previous, current, threshold float64) bool {
+	return threshold > 0 && previous < threshold && current >= threshold
+}
+
+// CheckTrending compares the top trending posts with the previous check and delivers a
+// new_trending_entry event for every post that entered them. The first check only records
+// the posts trending at startup.
+func (wd *WebhookDispatcher) CheckTrending() error {
+	posts, err := wd.firestoreClient.GetTrendingPosts(wd.trendingSize)
+	if err != nil {
+		return err
+	}
+
+	wd.mu.Lock()
+	entries := newTrendingEntries(wd.trending, posts)
+	wd.trending = make(map[string]bool, len(posts))
+	for _, post := range posts {
+		wd.trending[post.PostID] = true
+	}
+	wd.mu.Unlock()
+
+	for _, entry := range entries {
+		wd.publish(WebhookEventNewTrendingEntry, entry)
+	}
+	return nil
+}
+
+// newTrendingEntries returns the posts of top, ranked, that were not in previous. A nil
+// previous means there was no earlier check to compare with.
+func newTrendingEntries(previous map[string]bool, top []models.TrendingScore) []TrendingEntryData {
+	if previous == nil {
+		return nil
+	}
+	var entries []TrendingEntryData
+	for i, post := range top {
+		if !previous[post.PostID] {
+			entries = append(entries, TrendingEntryData{PostID: post.PostID, Rank: i + 1, Score: post.Score})
+		}
+	}
+	return entries
+}
+
+// publish queues event for every webhook subscribed to it
+func (wd *WebhookDispatcher) publish(event string, data interface{}) {
+	for _, hook := range wd.subscribers(event) {
+		wd.enqueue(hook, event, data)
+	}
+}
+
+// enqueue queues the first attempt of delivering an event to a webhook
+func (wd *WebhookDispatcher) enqueue(hook Webhook, event string, data interface{}) {
+	job, err := newWebhookJob(hook, event, data, time.Now())
+	if err != nil {
+		logger.Errorf("❌ Failed to encode %s webhook event: %v", event, err)
+		return
+	}
+	select {
+	case wd.queue <- job:
+	default:
+		logger.Warnf("⚠️ Webhook queue full, dropped %s event for webhook %s", event, hook.ID)
+	}
+}
+
+func newWebhookJob(hook Webhook, event string, data interface{}, now time.Time) (webhookJob, error) {
+	delivery := WebhookDelivery{
+		ID:        newWebhookID("dlv_"),
+		WebhookID: hook.ID,
+		Event:     event,
+		CreatedAt: now,
+	}
+	body, err := json.Marshal(WebhookPayload{
+		ID:        delivery.ID,
+		Event:     event,
+		Timestamp: now.UTC().Format(time.RFC3339),
+		Data:      data,
+	})
+	if err != nil {
+		return webhookJob{}, err
+	}
+	return webhookJob{hook: hook, delivery: delivery, body: body}, nil
+}
+
+// attempt sends a job once and either logs its outcome or schedules the next attempt
+func (wd *WebhookDispatcher) attempt(job webhookJob) {
+	wd.send(&job)
+	if job.delivery.Delivered || job.delivery.Attempts >= wd.maxAttempts {
+		if !job.delivery.Delivered {
+			logger.Warnf("⚠️ Gave up delivering %s to webhook %s after %d attempts: %s", job.delivery.Event, job.hook.ID, job.delivery.Attempts, job.delivery.Error)
+		}
+		wd.logDelivery(job.delivery)
+		return
+	}
+
+	// Retries wait 1, 2, 4, ... times the backoff
+	wait := wd.retryBackoff << (job.delivery.Attempts - 1)
+	time.AfterFunc(wait, func() {
+		select {
+		case <-wd.ctx.Done():
+		case wd.queue <- job:
+		}
+	})
+}
+
+// send POSTs the job's body to its webhook and records the outcome on its delivery
+func (wd *WebhookDispatcher) send(job *webhookJob) {
+	job.delivery.Attempts++
+	job.delivery.CompletedAt = time.Now()
+	job.delivery.Delivered, job.delivery.StatusCode, job.delivery.Error, job.delivery.Response = false, 0, "", ""
+
+	req, err := http.NewRequestWithContext(wd.ctx, http.MethodPost, job.hook.URL, bytes.NewReader(job.body))
+	if err != nil {
+		job.delivery.Error = err.Error()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, job.delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, job.delivery.ID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(job.hook.Secret, time.Now(), job.body))
+
+	resp, err := wd.httpClient.Do(req)
+	job.delivery.CompletedAt = time.Now()
+	if err != nil {
+		job.delivery.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBytes))
+	job.delivery.StatusCode = resp.StatusCode
+	job.delivery.Response = string(response)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		job.delivery.Error = fmt.Sprintf("webhook returned status %d", resp.StatusCode)
+		return
+	}
+	job.delivery.Delivered = true
+}
+
+func (wd *WebhookDispatcher) logDelivery(delivery WebhookDelivery) {
+	if err := wd.firestoreClient.RecordWebhookDelivery(delivery); err != nil {
+		logger.Debugf(" Failed to log delivery %s of webhook %s: %v", delivery.ID, delivery.WebhookID, err)
+	}
+}
+
+// subscribers returns the active webhooks subscribed to event, reading the webhooks again
+// once the cached ones are older than webhookCacheTTL
+func (wd *WebhookDispatcher) subscribers(event string) []Webhook {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	if time.Since(wd.loadedAt) > webhookCacheTTL {
+		hooks, err := wd.firestoreClient.ListWebhooks()
+		if err != nil {
+			// Keep delivering to the webhooks known so far
+			logger.Errorf("❌ Failed to load webhooks: %v", err)
+		} else {
+			wd.hooks = hooks
+		}
+		wd.loadedAt = time.Now()
+	}
+
+	var subscribed []Webhook
+	for _, hook := range wd.hooks {
+		if hook.Subscribes(event) {
+			subscribed = append(subscribed, hook)
+		}
+	}
+	return subscribed
+}
+
+// invalidate makes the next event read the webhooks again
+func (wd *WebhookDispatcher) invalidate() {
+	wd.mu.Lock()
+	wd.loadedAt = time.Time{}
+	wd.mu.Unlock()
+}
+
+// Create registers a webhook with a new ID and secret and returns it, secret included
+func (wd *WebhookDispatcher) Create(hook Webhook) (*Webhook, error) {
+	if err := ValidateWebhook(hook); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	hook.ID = newWebhookID("wh_")
+	hook.Secret = newWebhookID("whsec_")
+	hook.CreatedAt, hook.UpdatedAt = now, now
+	if err := wd.firestoreClient.SaveWebhook(hook); err != nil {
+		return nil, err
+	}
+	wd.invalidate()
+	return &hook, nil
+}
+
+// Update replaces the URL, events, threshold and state of a webhook, keeping its secret. It
+// returns nil when the webhook does not exist.
+func (wd *WebhookDispatcher) Update(id string, changes Webhook) (*Webhook, error) {
+	if err := ValidateWebhook(changes); err != nil {
+		return nil, err
+	}
+	hook, err := wd.firestoreClient.GetWebhook(id)
+	if err != nil || hook == nil {
+		return nil, err
+	}
+	hook.URL, hook.Events, hook.ScoreThreshold, hook.Active = changes.URL, changes.Events, changes.ScoreThreshold, changes.Active
+	hook.UpdatedAt = time.Now()
+	if err := wd.firestoreClient.SaveWebhook(*hook); err != nil {
+		return nil, err
+	}
+	wd.invalidate()
+	hook.Secret = ""
+	return hook, nil
+}
+
+// Delete removes a webhook and reports whether it existed
+func (wd *WebhookDispatcher) Delete(id string) (bool, error) {
+	hook, err := wd.firestoreClient.GetWebhook(id)
+	if err != nil || hook == nil {
+		return false, err
+	}
+	if err := wd.firestoreClient.DeleteWebhook(id); err != nil {
+		return false, err
+	}
+	wd.invalidate()
+	return true, nil
+}
+
+// Get returns a webhook without its secret, or nil when it does not exist
+func (wd *WebhookDispatcher) Get(id string) (*Webhook, error) {
+	hook, err := wd.firestoreClient.GetWebhook(id)
+	if err != nil || hook == nil {
+		return nil, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+// List returns every webhook without its secret
+func (wd *WebhookDispatcher) List() ([]Webhook, error) {
+	hooks, err := wd.firestoreClient.ListWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+// Deliveries returns the latest deliveries of a webhook, newest first
+func (wd *WebhookDispatcher) Deliveries(id string, limit int) ([]WebhookDelivery, error) {
+	return wd.firestoreClient.GetWebhookDeliveries(id, limit)
+}
+
+// Test sends a test event to a webhook at once, without retries, and returns the logged
+// delivery. It returns nil when the webhook does not exist.
+func (wd *WebhookDispatcher) Test(id string) (*WebhookDelivery, error) {
+	hook, err := wd.firestoreClient.GetWebhook(id)
+	if err != nil || hook == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job, err := newWebhookJob(*hook, WebhookEventTest, WebhookTestData{
+		WebhookID: hook.ID,
+		Message:   "Test delivery of the viral intelligence webhooks",
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+	wd.send(&job)
+	wd.logDelivery(job.delivery)
+	return &job.delivery, nil
+}
+
+func (fc *FirestoreClient) webhooksRef() *firestore.CollectionRef {
+	return fc.client.Collection("webhooks")
+}
+
+// SaveWebhook creates or replaces a webhook
+func (fc *FirestoreClient) SaveWebhook(hook Webhook) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.webhooksRef().Doc(hook.ID).Set(fc.ctx, hook)
+	return err
+}
+
+// GetWebhook returns a webhook, or nil if it does not exist
+func (fc *FirestoreClient) GetWebhook(id string) (*Webhook, error) {
+	Quotas.Record(QuotaFirestore, 1)
+	doc, err := fc.webhooksRef().Doc(id).Get(fc.ctx)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hook Webhook
+	if err := doc.DataTo(&hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// ListWebhooks returns every registered webhook, oldest first
+func (fc *FirestoreClient) ListWebhooks() ([]Webhook, error) {
+	iter := fc.webhooksRef().OrderBy("CreatedAt", firestore.Asc).Documents(fc.ctx)
+	defer iter.Stop()
+
+	hooks := []Webhook{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		Quotas.Record(QuotaFirestore, 1)
+
+		var hook Webhook
+		if err := doc.DataTo(&hook); err != nil {
+			logger.Debugf(" Skipping unreadable webhook %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook removes a webhook; its delivery log is kept
+func (fc *FirestoreClient) DeleteWebhook(id string) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.webhooksRef().Doc(id).Delete(fc.ctx)
+	return err
+}
+
+// RecordWebhookDelivery logs the outcome of a delivery under its webhook
+func (fc *FirestoreClient) RecordWebhookDelivery(delivery WebhookDelivery) error {
+	Quotas.Record(QuotaFirestore, 1)
+	_, err := fc.webhooksRef().Doc(delivery.WebhookID).Collection("deliveries").Doc(delivery.ID).Set(fc.ctx, delivery)
+	return err
+}
+
+// GetWebhookDeliveries returns the latest logged deliveries of a webhook, newest first
+func (fc *FirestoreClient) GetWebhookDeliveries(id string, limit int) ([]WebhookDelivery, error) {
+	iter := fc.webhooksRef().Doc(id).Collection("deliveries").
+		OrderBy("CreatedAt", firestore.Desc).
+		Limit(limit).
+		Documents(fc.ctx)
+	defer iter.Stop()
+
+	deliveries := []WebhookDelivery{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		Quotas.Record(QuotaFirestore, 1)
+
+		var delivery WebhookDelivery
+		if err := doc.DataTo(&delivery); err != nil {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
+)
+
+func TestSignWebhook(t *testing.T) {
+	at := time.Unix(1717236000, 0)
+	body := []byte(`{"event":"viral_alert"}`)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1717236000." + string(body)))
+	want := "t=1717236000,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if got := SignWebhook("whsec_test", at, body); got != want {
+		t.Errorf("SignWebhook() = %s, want %s", got, want)
+	}
+	if SignWebhook("whsec_other", at, body) == want {
+		t.Error("Expected another secret to sign differently")
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	valid := Webhook{URL: "https://hooks.example.com/viral", Events: []string{WebhookEventViralAlert}}
+	if err := ValidateWebhook(valid); err != nil {
+		t.Errorf("Expected %+v to be valid, got %v", valid, err)
+	}
+
+	tests := []struct {
+		hook  Webhook
+		field string
+	}{
+		{Webhook{URL: "hooks.example.com/viral", Events: []string{WebhookEventViralAlert}}, "url"},
+		{Webhook{URL: "ftp://hooks.example.com", Events: []string{WebhookEventViralAlert}}, "url"},
+		{Webhook{URL: "https://hooks.example.com"}, "events"},
+		{Webhook{URL: "https://hooks.example.com", Events: []string{"anomaly_alert"}}, "events"},
+		{Webhook{URL: "https://hooks.example.com", Events: []string{WebhookEventScoreThreshold}}, "score_threshold"},
+	}
+	for _, tt := range tests {
+		err := ValidateWebhook(tt.hook)
+		invalid, ok := err.(*WebhookValidationError)
+		if !ok || invalid.Field != tt.field {
+			t.Errorf("Expected %+v to be rejected for %s, got %v", tt.hook, tt.field, err)
+		}
+	}
+}
+
+func TestCrossedThreshold(t *testing.T) {
+	tests := []struct {
+		previous, current, threshold float64
+		want                         bool
+	}{
+		{40, 55, 50, true},
+		{0, 50, 50, true},
+		{50, 60, 50, false}, // already above
+		{55, 45, 50, false}, // falling
+		{10, 20, 50, false},
+		{10, 20, 0, false}, // no threshold
+	}
+	for _, tt := range tests {
+		if got := crossedThreshold(tt.previous, tt.current, tt.threshold); got != tt.want {
+			t.Errorf("crossedThreshold(%v, %v, %v) = %v, want %v", tt.previous, tt.current, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestNewTrendingEntries(t *testing.T) {
+	top := []models.TrendingScore{{PostID: "a", Score: 90}, {PostID: "b", Score: 80}, {PostID: "c", Score: 70}}
+
+	if entries := newTrendingEntries(nil, top); entries != nil {
+		t.Errorf("Expected the first check to only record the top posts, got %+v", entries)
+	}
+
+	entries := newTrendingEntries(map[string]bool{"a": true, "c": true, "gone": true}, top)
+	if len(entries) != 1 || entries[0] != (TrendingEntryData{PostID: "b", Rank: 2, Score: 80}) {
+		t.Errorf("Expected b to enter at rank 2, got %+v", entries)
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	status := http.StatusOK
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	wd := NewWebhookDispatcher(nil, &config.Config{WebhookTimeoutSeconds: 5})
+	hook := Webhook{ID: "wh_1", URL: server.URL, Secret: "whsec_test", Events: []string{WebhookEventViralAlert}, Active: true}
+	job, err := newWebhookJob(hook, WebhookEventViralAlert, models.ViralAlert{PostID: "p1", Tier: "viral"}, time.Now())
+	if err != nil {
+		t.Fatalf("newWebhookJob failed: %v", err)
+	}
+
+	wd.send(&job)
+	if !job.delivery.Delivered || job.delivery.Attempts != 1 || job.delivery.StatusCode != http.StatusOK || job.delivery.Response != "ok" {
+		t.Fatalf("Expected one successful attempt, got %+v", job.delivery)
+	}
+	if received.Header.Get(WebhookEventHeader) != WebhookEventViralAlert || received.Header.Get(WebhookDeliveryHeader) != job.delivery.ID {
+		t.Errorf("Unexpected delivery headers %v", received.Header)
+	}
+
+	// The receiver can check the signature from the timestamp it carries
+	signature := received.Header.Get(WebhookSignatureHeader)
+	unix, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+	var seconds int64
+	json.Unmarshal([]byte(unix), &seconds)
+	if SignWebhook(hook.Secret, time.Unix(seconds, 0), body) != signature {
+		t.Errorf("Signature %s does not match the body", signature)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.ID != job.delivery.ID || payload.Event != WebhookEventViralAlert {
+		t.Errorf("Unexpected payload %s: %v", body, err)
+	}
+
+	status = http.StatusInternalServerError
+	wd.send(&job)
+	if job.delivery.Delivered || job.delivery.Attempts != 2 || job.delivery.StatusCode != http.StatusInternalServerError || job.delivery.Error == "" {
+		t.Errorf("Expected a failed second attempt, got %+v", job.delivery)
+	}
+}
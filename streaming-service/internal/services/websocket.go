@@ -1,9 +1,17 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"confluent-viral-intelligence/internal/config"
 	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,8 +22,8 @@ type WebSocketHub struct {
 	// Registered clients
 	clients map[*WebSocketClient]bool
 
-	// Inbound messages from clients
-	broadcast chan []byte
+	// Messages to broadcast to every client
+	broadcast chan outboundMessage
 
 	// Register requests from clients
 	register chan *WebSocketClient
@@ -23,20 +31,115 @@ type WebSocketHub struct {
 	// Unregister requests from clients
 	unregister chan *WebSocketClient
 
+	// Requests to replay missed broadcasts to reconnected clients
+	resume chan resumeRequest
+
+	// Ring buffer of the most recent broadcasts, the index of the oldest one and the sequence
+	// ID of the last one; only touched by Run
+	history []sequencedMessage
+	oldest  int
+	seq     uint64
+
+	// Publishes messages to the hubs of every service instance instead of only this one, nil
+	// when the instance runs alone
+	relay HubRelay
+
+	// Answers the commands clients send over their connection, nil when commands are disabled
+	commands *CommandDispatcher
+
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
 }
 
 // WebSocketClient represents a single WebSocket connection
 type WebSocketClient struct {
+	// Identifies the client in the admin API, unique within the instance
+	id string
+
 	// The WebSocket connection
 	conn *websocket.Conn
 
-	// Buffered channel of outbound messages
-	send chan []byte
+	// Bounded queue of outbound messages
+	queue *clientQueue
 
 	// Reference to the hub
 	hub *WebSocketHub
+
+	// Authenticated user, empty for anonymous connections
+	userID string
+
+	// Wire format of the messages written to the connection (WireFormat*)
+	format string
+
+	// Transport name and connection time reported in the client metrics
+	transport   string
+	connectedAt time.Time
+
+	// Messages the client receives, set before it registers
+	subscriptions []string
+
+	// Heartbeat of WebSocket connections: when the last ping was sent, when the last pong
+	// arrived and the round trip it measured
+	livenessMu sync.Mutex
+	lastPingAt time.Time
+	lastPongAt time.Time
+	rtt        time.Duration
+
+	// Posts watched with the watch_post command and the revision of their stats last sent
+	watchMu  sync.Mutex
+	watching map[string]postRevision
+}
+
+// Source of client IDs
+var clientIDs atomic.Uint64
+
+// outboundMessage is a broadcast and the key under which queued copies are coalesced
+type outboundMessage struct {
+	data []byte
+	key  string
+
+	// Only this user's connections receive the message when set; such messages are not replayed
+	userID string
+
+	// Cluster-wide sequence ID of a relayed broadcast, 0 to continue the local sequence
+	seq uint64
+
+	// Periodic snapshots such as dashboard ticks go to current clients only, without a
+	// sequence ID, since replaying a stale one is of no use
+	ephemeral bool
+}
+
+// HubRelay publishes hub messages to the hubs of every service instance, which deliver them
+// with Deliver
+type HubRelay interface {
+	PublishHubBroadcast(broadcast models.HubBroadcast) error
+}
+
+// sequencedMessage is a broadcast kept for replay
+type sequencedMessage struct {
+	seq   uint64
+	frame *wireFrame
+}
+
+// resumeRequest asks the hub to replay the broadcasts after lastSeq to a client
+type resumeRequest struct {
+	client  *WebSocketClient
+	lastSeq uint64
+}
+
+// ResumeMessage is sent by a reconnected client to receive the broadcasts it missed
+type ResumeMessage struct {
+	Type    string `json:"type"` // resume
+	LastSeq uint64 `json:"last_seq"`
+}
+
+// ReplayGapMessage tells a resuming client that broadcasts it missed cannot be replayed, so it
+// has to reload its state; replay continues from OldestSeq
+type ReplayGapMessage struct {
+	Type      string `json:"type"`
+	LastSeq   uint64 `json:"last_seq"`
+	OldestSeq uint64 `json:"oldest_seq"`
+	Timestamp string `json:"timestamp"`
 }
 
 // TrendingUpdateMessage represents a trending score update
@@ -52,12 +155,35 @@ type TrendingUpdateMessage struct {
 type ViralAlertMessage struct {
 	Type             string  `json:"type"`
 	PostID           string  `json:"post_id"`
+	Tier             string  `json:"tier"` // warming, trending, viral or mega_viral by default
 	ViralProbability float64 `json:"viral_probability"`
 	Score            float64 `json:"score"`
 	Message          string  `json:"message"`
 	Timestamp        string  `json:"timestamp"`
 }
 
+// CreatorViralAlertMessage tells a creator that one of their posts reached a higher viral tier
+type CreatorViralAlertMessage struct {
+	Type             string  `json:"type"`
+	PostID           string  `json:"post_id"`
+	Tier             string  `json:"tier"`
+	ViralProbability float64 `json:"viral_probability"`
+	Message          string  `json:"message"`
+	Timestamp        string  `json:"timestamp"`
+}
+
+// AnomalyAlertMessage represents a post whose engagement velocity departed from its baseline
+type AnomalyAlertMessage struct {
+	Type             string  `json:"type"`
+	PostID           string  `json:"post_id"`
+	Kind             string  `json:"kind"` // spike or drop
+	Velocity         float64 `json:"velocity"`
+	ExpectedVelocity float64 `json:"expected_velocity"`
+	ZScore           float64 `json:"z_score"`
+	Message          string  `json:"message"`
+	Timestamp        string  `json:"timestamp"`
+}
+
 const (
 	// Time allowed to write a message to the peer
 	writeWait = 10 * time.Second
@@ -71,17 +197,21 @@ const (
 	// Maximum message size allowed from peer
 	maxMessageSize = 512
 
-	// Buffer size for client send channel
+	// Messages queued per client before the oldest are dropped
 	sendBufferSize = 256
+
+	// Broadcasts kept for replay to reconnecting clients
+	replayBufferSize = 1000
 )
 
 // NewWebSocketHub creates a new WebSocket hub
 func NewWebSocketHub() *WebSocketHub {
 	return &WebSocketHub{
 		clients:    make(map[*WebSocketClient]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan outboundMessage, 256),
 		register:   make(chan *WebSocketClient),
 		unregister: make(chan *WebSocketClient),
+		resume:     make(chan resumeRequest),
 	}
 }
 
@@ -99,34 +229,124 @@ func (h *WebSocketHub) Run() {
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.queue.close()
 				logger.Infof("WebSocket client unregistered. Total clients: %d", len(h.clients))
 			}
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
+			if message.userID != "" {
+				h.SendToUser(message.userID, message.data)
+				continue
+			}
+			if message.ephemeral {
+				frame := newWireFrame(message.data)
+				h.mu.RLock()
+				for client := range h.clients {
+					client.queue.push(frame, message.key)
+				}
+				h.mu.RUnlock()
+				continue
+			}
+
+			// Queues never block: slow clients lose superseded or old messages instead. Every
+			// client shares the frame, which encodes each wire format only once.
+			frame := h.record(message.data, message.seq)
 			h.mu.RLock()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-					// Message sent successfully
-				default:
-					// Client's send buffer is full, close the connection
-					h.mu.RUnlock()
-					h.mu.Lock()
-					close(client.send)
-					delete(h.clients, client)
-					h.mu.Unlock()
-					h.mu.RLock()
-				}
+				client.queue.push(frame, message.key)
 			}
 			h.mu.RUnlock()
+
+		case request := <-h.resume:
+			h.replay(request.client, request.lastSeq)
+		}
+	}
+}
+
+// record assigns a sequence ID to a broadcast, adds it to the replay buffer and returns its
+// frame, the message with a "seq" field. Clients pass the last seq they saw when resuming.
+// Relayed broadcasts keep the sequence ID of the broadcast topic, so it means the same on
+// every instance; others continue the local sequence.
+func (h *WebSocketHub) record(message []byte, seq uint64) *wireFrame {
+	if seq > h.seq {
+		h.seq = seq
+	} else {
+		h.seq++
+	}
+	if len(message) > 1 && message[0] == '{' {
+		sequenced := []byte(fmt.Sprintf(`{"seq":%d`, h.seq))
+		if message[1] != '}' {
+			sequenced = append(sequenced, ',')
 		}
+		message = append(sequenced, message[1:]...)
+	}
+
+	entry := sequencedMessage{seq: h.seq, frame: newWireFrame(message)}
+	if len(h.history) < replayBufferSize {
+		h.history = append(h.history, entry)
+	} else {
+		h.history[h.oldest] = entry
+		h.oldest = (h.oldest + 1) % len(h.history)
 	}
+	return entry.frame
+}
+
+// missedSince returns the buffered broadcasts after lastSeq, oldest first. complete is false
+// when some of them have already left the buffer.
+func (h *WebSocketHub) missedSince(lastSeq uint64) (messages []sequencedMessage, complete bool) {
+	if lastSeq >= h.seq {
+		return nil, true
+	}
+	complete = len(h.history) > 0 && h.history[h.oldest].seq <= lastSeq+1
+	for i := range h.history {
+		if message := h.history[(h.oldest+i)%len(h.history)]; message.seq > lastSeq {
+			messages = append(messages, message)
+		}
+	}
+	return messages, complete
+}
+
+// replay queues the broadcasts a client missed since lastSeq. When they are no longer all
+// buffered, or more were missed than the client's send buffer holds, a replay_gap message
+// precedes the most recent ones so the client knows to reload its state.
+func (h *WebSocketHub) replay(client *WebSocketClient, lastSeq uint64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if !h.clients[client] {
+		return
+	}
+
+	messages, complete := h.missedSince(lastSeq)
+	if room := client.queue.room() - 1; len(messages) > room {
+		messages = messages[len(messages)-max(room, 0):]
+		complete = false
+	}
+	if !complete {
+		gap := ReplayGapMessage{
+			Type:      "replay_gap",
+			LastSeq:   lastSeq,
+			OldestSeq: h.seq + 1,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		if len(messages) > 0 {
+			gap.OldestSeq = messages[0].seq
+		}
+		if data, err := json.Marshal(gap); err == nil {
+			client.queue.push(newWireFrame(data), "")
+		}
+	}
+
+	for _, message := range messages {
+		client.queue.push(message.frame, "")
+	}
+	logger.Infof("Replayed %d missed messages to a resuming WebSocket client (after seq %d)", len(messages), lastSeq)
 }
 
 // BroadcastTrendingUpdate sends a trending score update to all connected clients
 func (h *WebSocketHub) BroadcastTrendingUpdate(postID string, score float64, viewCount int64) {
+	defer PipelineLatency.ObserveSince(StageBroadcast, time.Now())
+
 	message := TrendingUpdateMessage{
 		Type:      "trending_update",
 		PostID:    postID,
@@ -141,18 +361,36 @@ func (h *WebSocketHub) BroadcastTrendingUpdate(postID string, score float64, vie
 		return
 	}
 
-	h.broadcast <- data
-	logger.Infof("Broadcasted trending update for post %s (score: %.2f)", postID, score)
+	// Only the latest update of a post is worth sending to a client that is behind
+	Dashboard.RecordScore(postID, score, time.Now())
+	h.send(outboundMessage{data: data, key: "trending_update:" + postID})
+	logger.Debugf(" Broadcasted trending update for post %s (score: %.2f)", postID, score)
+}
+
+// BroadcastDashboardTick sends the live dashboard aggregates to this instance's clients; a
+// client that is behind only receives the latest tick
+func (h *WebSocketHub) BroadcastDashboardTick(tick DashboardTickMessage) {
+	data, err := json.Marshal(tick)
+	if err != nil {
+		logger.Infof("Error marshaling dashboard tick: %v", err)
+		return
+	}
+
+	h.broadcast <- outboundMessage{data: data, key: "dashboard_tick", ephemeral: true}
 }
 
-// BroadcastViralAlert sends a viral alert to all connected clients
-func (h *WebSocketHub) BroadcastViralAlert(postID string, viralProbability, score float64) {
+// BroadcastViralAlert sends a viral alert for a post that reached a higher tier to all
+// connected clients
+func (h *WebSocketHub) BroadcastViralAlert(postID, tier string, viralProbability, score float64) {
+	defer PipelineLatency.ObserveSince(StageBroadcast, time.Now())
+
 	message := ViralAlertMessage{
 		Type:             "viral_alert",
 		PostID:           postID,
+		Tier:             tier,
 		ViralProbability: viralProbability,
 		Score:            score,
-		Message:          "Content is predicted to go viral!",
+		Message:          viralAlertMessage(tier),
 		Timestamp:        time.Now().UTC().Format(time.RFC3339),
 	}
 
@@ -162,8 +400,156 @@ func (h *WebSocketHub) BroadcastViralAlert(postID string, viralProbability, scor
 		return
 	}
 
-	h.broadcast <- data
-	logger.Infof("Broadcasted viral alert for post %s (probability: %.2f%%)", postID, viralProbability*100)
+	h.send(outboundMessage{data: data})
+	logger.Infof("Broadcasted %s alert for post %s (probability: %.2f%%)", tier, postID, viralProbability*100)
+}
+
+// viralAlertMessage returns the message shown for a viral tier
+func viralAlertMessage(tier string) string {
+	switch tier {
+	case config.ViralTierWarming:
+		return "Content is warming up!"
+	case config.ViralTierTrending:
+		return "Content is trending!"
+	case config.ViralTierMegaViral:
+		return "Content is going mega-viral!"
+	default:
+		return "Content is predicted to go viral!"
+	}
+}
+
+// BroadcastAnomalyAlert sends an engagement anomaly to all connected clients
+func (h *WebSocketHub) BroadcastAnomalyAlert(anomaly models.EngagementAnomaly) {
+	defer PipelineLatency.ObserveSince(StageBroadcast, time.Now())
+
+	message := AnomalyAlertMessage{
+		Type:             "anomaly_alert",
+		PostID:           anomaly.PostID,
+		Kind:             anomaly.Kind,
+		Velocity:         anomaly.Velocity,
+		ExpectedVelocity: anomaly.ExpectedVelocity,
+		ZScore:           anomaly.ZScore,
+		Message:          fmt.Sprintf("Unusual engagement %s: %.1f events/min against %.1f expected", anomaly.Kind, anomaly.Velocity, anomaly.ExpectedVelocity),
+		Timestamp:        anomaly.DetectedAt.UTC().Format(time.RFC3339),
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		logger.Infof("Error marshaling anomaly alert: %v", err)
+		return
+	}
+
+	h.send(outboundMessage{data: data})
+	logger.Infof("Broadcasted anomaly alert for post %s (%s, z=%.1f)", anomaly.PostID, anomaly.Kind, anomaly.ZScore)
+}
+
+// UseRelay makes the hub publish its messages through relay, leaving their delivery to
+// Deliver on every instance. It has to be called before the hub is used.
+func (h *WebSocketHub) UseRelay(relay HubRelay) {
+	h.relay = relay
+}
+
+// UseCommands makes the hub's WebSocket clients answer the commands they send with commands.
+// It has to be called before the hub is used.
+func (h *WebSocketHub) UseCommands(commands *CommandDispatcher) {
+	h.commands = commands
+}
+
+// send hands a message to the relay, or delivers it to this instance's clients when there is
+// no relay or the relay fails
+func (h *WebSocketHub) send(message outboundMessage) {
+	if h.relay != nil {
+		err := h.relay.PublishHubBroadcast(models.HubBroadcast{
+			Message: message.data,
+			Key:     message.key,
+			UserID:  message.userID,
+			SentAt:  time.Now(),
+		})
+		if err == nil {
+			return
+		}
+		logger.Warnf("Failed to relay WebSocket message, delivering it locally only: %v", err)
+	}
+	h.broadcast <- message
+}
+
+// Deliver queues a relayed message for this instance's clients under the sequence ID it got
+// in the broadcast topic
+func (h *WebSocketHub) Deliver(broadcast models.HubBroadcast, seq uint64) {
+	// Trending updates sent by other instances feed this instance's dashboard ticks
+	if strings.HasPrefix(broadcast.Key, "trending_update:") {
+		var update TrendingUpdateMessage
+		if err := json.Unmarshal(broadcast.Message, &update); err == nil {
+			Dashboard.RecordScore(update.PostID, update.Score, time.Now())
+		}
+	}
+
+	h.broadcast <- outboundMessage{
+		data:   broadcast.Message,
+		key:    broadcast.Key,
+		userID: broadcast.UserID,
+		seq:    seq,
+	}
+}
+
+// SendToUser sends a message to every connection of an authenticated user and returns the
+// number of connections it was queued for
+func (h *WebSocketHub) SendToUser(userID string, data []byte) int {
+	if userID == "" {
+		return 0
+	}
+
+	var sent int
+	frame := newWireFrame(data)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if client.userID == userID && client.queue.push(frame, "") {
+			sent++
+		}
+	}
+	return sent
+}
+
+// SendCreatorViralAlert tells a creator's connections that their post reached a higher viral tier
+func (h *WebSocketHub) SendCreatorViralAlert(userID, postID, tier string, viralProbability float64) {
+	message := CreatorViralAlertMessage{
+		Type:             "creator_viral_alert",
+		PostID:           postID,
+		Tier:             tier,
+		ViralProbability: viralProbability,
+		Message:          creatorViralAlertMessage(tier),
+		Timestamp:        time.Now().UTC().Format(time.RFC3339),
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		logger.Infof("Error marshaling creator viral alert: %v", err)
+		return
+	}
+
+	// The creator may be connected to another instance
+	if h.relay != nil && userID != "" {
+		h.send(outboundMessage{data: data, userID: userID})
+		return
+	}
+	if sent := h.SendToUser(userID, data); sent > 0 {
+		logger.Infof("Sent %s alert for post %s to %d connections of its creator", tier, postID, sent)
+	}
+}
+
+// creatorViralAlertMessage returns the message shown to a creator for a viral tier
+func creatorViralAlertMessage(tier string) string {
+	switch tier {
+	case config.ViralTierWarming:
+		return "Your post is warming up!"
+	case config.ViralTierTrending:
+		return "Your post is trending!"
+	case config.ViralTierMegaViral:
+		return "Your post is going mega-viral!"
+	default:
+		return "Your post is going viral!"
+	}
 }
 
 // GetClientCount returns the number of connected clients
@@ -173,31 +559,210 @@ func (h *WebSocketHub) GetClientCount() int {
 	return len(h.clients)
 }
 
+// ClientStats returns the queue and liveness metrics of every connected client, those
+// dropping the most messages first
+func (h *WebSocketHub) ClientStats() []WebSocketClientStats {
+	h.mu.RLock()
+	stats := make([]WebSocketClientStats, 0, len(h.clients))
+	for client := range h.clients {
+		stats = append(stats, client.stats())
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Dropped != stats[j].Dropped {
+			return stats[i].Dropped > stats[j].Dropped
+		}
+		return stats[i].ConnectedAt.Before(stats[j].ConnectedAt)
+	})
+	return stats
+}
+
+// Disconnect unregisters the client with the given ID, closing its connection or stream. It
+// returns false when no such client is connected.
+func (h *WebSocketHub) Disconnect(id string) bool {
+	var target *WebSocketClient
+	h.mu.RLock()
+	for client := range h.clients {
+		if client.id == id {
+			target = client
+			break
+		}
+	}
+	h.mu.RUnlock()
+	if target == nil {
+		return false
+	}
+
+	h.unregister <- target
+	logger.Infof("WebSocket client %s disconnected by an admin", id)
+	return true
+}
+
 // RegisterClient registers a new client with the hub
 func (h *WebSocketHub) RegisterClient(client *WebSocketClient) {
 	h.register <- client
 }
 
-// NewWebSocketClient creates a new WebSocket client
-func NewWebSocketClient(conn *websocket.Conn, hub *WebSocketHub) *WebSocketClient {
+// Resume replays to a registered client the broadcasts after the last sequence ID it saw
+func (h *WebSocketHub) Resume(client *WebSocketClient, lastSeq uint64) {
+	h.resume <- resumeRequest{client: client, lastSeq: lastSeq}
+}
+
+// NewWebSocketClient creates a new WebSocket client for a user, or an anonymous one when
+// userID is empty, that is sent messages in the given wire format (JSON unless MessagePack)
+func NewWebSocketClient(conn *websocket.Conn, hub *WebSocketHub, userID, format string) *WebSocketClient {
 	return &WebSocketClient{
-		conn: conn,
-		send: make(chan []byte, sendBufferSize),
-		hub:  hub,
+		id:          newClientID("websocket"),
+		conn:        conn,
+		queue:       newClientQueue(sendBufferSize),
+		hub:         hub,
+		userID:      userID,
+		format:      negotiateWireFormat(format),
+		transport:   "websocket",
+		connectedAt: time.Now(),
 	}
 }
 
+// NewStreamClient creates a hub client without a WebSocket connection for transports that
+// read its messages with Next, such as Server-Sent Events ("sse") or gRPC ("grpc")
+func NewStreamClient(hub *WebSocketHub, userID, transport string) *WebSocketClient {
+	return &WebSocketClient{
+		id:          newClientID(transport),
+		queue:       newClientQueue(sendBufferSize),
+		hub:         hub,
+		userID:      userID,
+		format:      WireFormatJSON,
+		transport:   transport,
+		connectedAt: time.Now(),
+	}
+}
+
+// newClientID returns the next client ID, prefixed with the transport name
+func newClientID(transport string) string {
+	return transport + "-" + strconv.FormatUint(clientIDs.Add(1), 10)
+}
+
+// ID returns the identifier of the client in the admin API
+func (c *WebSocketClient) ID() string {
+	return c.id
+}
+
+// SetSubscriptions describes the messages the client receives, such as the message types and
+// filters of a gRPC subscription. Clients without subscriptions receive every broadcast.
+// Call it before registering the client.
+func (c *WebSocketClient) SetSubscriptions(subscriptions ...string) {
+	c.subscriptions = subscriptions
+}
+
+// Next waits up to timeout for the messages queued for a stream client, oldest first. It
+// returns no messages when the timeout passes first, and false once the hub dropped the client
+// or ctx ended.
+func (c *WebSocketClient) Next(ctx context.Context, timeout time.Duration) ([][]byte, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case _, ok := <-c.queue.ready:
+		if !ok {
+			return nil, false
+		}
+		frames := c.queue.drain()
+		messages := make([][]byte, len(frames))
+		for i, frame := range frames {
+			messages[i] = frame.data
+		}
+		return messages, true
+	case <-timer.C:
+		return nil, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// Close unregisters a stream client from the hub
+func (c *WebSocketClient) Close() {
+	c.hub.unregister <- c
+}
+
+// stats returns the queue and liveness metrics of the client
+func (c *WebSocketClient) stats() WebSocketClientStats {
+	stats := c.queue.stats()
+	stats.UserID = c.userID
+	stats.Transport = c.transport
+	stats.Format = c.format
+	stats.ConnectedAt = c.connectedAt
+	if c.conn != nil {
+		stats.RemoteAddr = c.conn.RemoteAddr().String()
+	}
+
+	stats.ID = c.id
+	stats.Subscriptions = c.subscriptions
+	if len(stats.Subscriptions) == 0 {
+		stats.Subscriptions = []string{"broadcasts"}
+		if c.userID != "" {
+			stats.Subscriptions = append(stats.Subscriptions, "user:"+c.userID)
+		}
+	}
+	watched := c.watchedPosts()
+	sort.Strings(watched)
+	for _, postID := range watched {
+		stats.Subscriptions = append(stats.Subscriptions, "post_stats:"+postID)
+	}
+
+	c.livenessMu.Lock()
+	if !c.lastPingAt.IsZero() {
+		lastPingAt := c.lastPingAt
+		stats.LastPingAt = &lastPingAt
+	}
+	if !c.lastPongAt.IsZero() {
+		lastPongAt := c.lastPongAt
+		stats.LastPongAt = &lastPongAt
+		stats.RTTMs = float64(c.rtt.Microseconds()) / 1000
+	}
+	c.livenessMu.Unlock()
+	return stats
+}
+
+// pinged records that a ping was sent at the given time
+func (c *WebSocketClient) pinged(at time.Time) {
+	c.livenessMu.Lock()
+	c.lastPingAt = at
+	c.livenessMu.Unlock()
+}
+
+// ponged records a pong; the payload echoes the send time of the ping it answers, in Unix
+// nanoseconds, from which the round trip is measured
+func (c *WebSocketClient) ponged(payload string) {
+	now := time.Now()
+	c.livenessMu.Lock()
+	defer c.livenessMu.Unlock()
+	c.lastPongAt = now
+	if sentAt, err := strconv.ParseInt(payload, 10, 64); err == nil {
+		c.rtt = now.Sub(time.Unix(0, sentAt))
+	}
+}
+
+// UserID returns the authenticated user of the connection, empty when anonymous
+func (c *WebSocketClient) UserID() string {
+	return c.userID
+}
+
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *WebSocketClient) readPump() {
 	defer func() {
 		c.hub.unregister <- c
+		if c.hub.commands != nil {
+			c.hub.commands.Forget(c)
+		}
 		c.conn.Close()
 	}()
 
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error {
+	c.conn.SetPongHandler(func(payload string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.ponged(payload)
 		return nil
 	})
 
@@ -210,6 +775,30 @@ func (c *WebSocketClient) readPump() {
 			break
 		}
 
+		// A reconnected client asks for the broadcasts it missed
+		var resume ResumeMessage
+		if err := json.Unmarshal(message, &resume); err == nil && resume.Type == "resume" {
+			c.hub.Resume(c, resume.LastSeq)
+			continue
+		}
+
+		// Other requests name an action, answered by the command dispatcher
+		var command ClientCommand
+		if err := json.Unmarshal(message, &command); err == nil && command.Action != "" {
+			if c.hub.commands == nil {
+				c.sendJSON(CommandErrorMessage{
+					Type:      "command_error",
+					Action:    command.Action,
+					RequestID: command.RequestID,
+					Error:     "commands are not served by this instance",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				}, "")
+				continue
+			}
+			c.hub.commands.Dispatch(c, command)
+			continue
+		}
+
 		// Log received message (for debugging)
 		logger.Infof("Received message from client: %s", message)
 	}
@@ -218,6 +807,7 @@ func (c *WebSocketClient) readPump() {
 // writePump pumps messages from the hub to the WebSocket connection
 func (c *WebSocketClient) writePump() {
 	ticker := time.NewTicker(pingPeriod)
+	// Messages still queued when the writer stops are released when the hub closes the queue
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -225,36 +815,35 @@ func (c *WebSocketClient) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case _, ok := <-c.queue.ready:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				// The hub closed the channel
+				// The hub closed the queue
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued messages to the current WebSocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
-				return
+			// Each message is its own frame, serialized and compressed once per broadcast
+			for _, frame := range c.queue.drain() {
+				prepared, err := frame.preparedFor(c.format)
+				if err != nil {
+					logger.Warnf("Dropping WebSocket message that cannot be encoded as %s: %v", c.format, err)
+					continue
+				}
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := c.conn.WritePreparedMessage(prepared); err != nil {
+					return
+				}
 			}
 
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			// The payload, echoed by the pong, times the round trip
+			now := time.Now()
+			c.conn.SetWriteDeadline(now.Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, []byte(strconv.FormatInt(now.UnixNano(), 10))); err != nil {
 				return
 			}
+			c.pinged(now)
 		}
 	}
 }
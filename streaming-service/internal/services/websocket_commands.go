@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+)
+
+// Actions of the commands WebSocket clients send over the connection
+const (
+	// CommandGetTrending answers with the current trending posts, up to limit
+	CommandGetTrending = "get_trending"
+	// CommandGetPostStats answers with the stats of post_id
+	CommandGetPostStats = "get_post_stats"
+	// CommandWatchPost answers with the stats of post_id and streams them whenever they change
+	CommandWatchPost = "watch_post"
+	// CommandUnwatchPost stops streaming the stats of post_id
+	CommandUnwatchPost = "unwatch_post"
+)
+
+const (
+	// Trending posts returned by get_trending without a limit, and the most it returns
+	defaultCommandTrendingLimit = 20
+	maxCommandTrendingLimit     = 100
+
+	// Posts one connection may watch at a time
+	maxWatchedPosts = 20
+)
+
+// ClientCommand is a request sent by a WebSocket client. It is answered with a
+// CommandResultMessage or a CommandErrorMessage carrying the same request ID.
+type ClientCommand struct {
+	Action    string `json:"action"`
+	RequestID string `json:"request_id,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+	PostID    string `json:"post_id,omitempty"`
+}
+
+// CommandResultMessage is the answer to a command
+type CommandResultMessage struct {
+	Type      string      `json:"type"`
+	Action    string      `json:"action"`
+	RequestID string      `json:"request_id,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// CommandErrorMessage tells a client why a command failed
+type CommandErrorMessage struct {
+	Type      string `json:"type"`
+	Action    string `json:"action"`
+	RequestID string `json:"request_id,omitempty"`
+	Error     string `json:"error"`
+	Timestamp string `json:"timestamp"`
+}
+
+// PostStatsMessage carries the changed stats of a watched post
+type PostStatsMessage struct {
+	Type      string                `json:"type"`
+	PostID    string                `json:"post_id"`
+	Stats     *models.TrendingScore `json:"stats"`
+	Timestamp string                `json:"timestamp"`
+}
+
+// CommandStore reads the data served to commands; FirestoreClient implements it
+type CommandStore interface {
+	GetTrendingPosts(limit int) ([]models.TrendingScore, error)
+	GetPostStats(postID string) (*models.TrendingScore, error)
+}
+
+// postRevision identifies the version of a post's stats a watcher was last sent
+type postRevision struct {
+	version   int64
+	updatedAt time.Time
+}
+
+func revisionOf(stats *models.TrendingScore) postRevision {
+	return postRevision{version: stats.Version, updatedAt: stats.UpdatedAt}
+}
+
+func (r postRevision) equal(other postRevision) bool {
+	return r.version == other.version && r.updatedAt.Equal(other.updatedAt)
+}
+
+// CommandDispatcher answers the commands of WebSocket clients and polls the posts they watch,
+// sending their stats whenever they change. Polling the shared store keeps watches working on
+// read replicas, which never see the events that change the stats.
+type CommandDispatcher struct {
+	store        CommandStore
+	pollInterval time.Duration
+
+	// Clients watching at least one post
+	mu       sync.Mutex
+	watchers map[*WebSocketClient]bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewCommandDispatcher creates a dispatcher that checks watched posts for changes every
+// pollInterval
+func NewCommandDispatcher(store CommandStore, pollInterval time.Duration) *CommandDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CommandDispatcher{
+		store:        store,
+		pollInterval: pollInterval,
+		watchers:     make(map[*WebSocketClient]bool),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Start begins polling watched posts; without a poll interval watched posts are only sent
+// once, in the answer to watch_post
+func (d *CommandDispatcher) Start() {
+	if d.pollInterval <= 0 {
+		return
+	}
+	logger.Infof("👀 Polling watched posts every %v", d.pollInterval)
+
+	ticker := time.NewTicker(d.pollInterval)
+	go func() {
+		for {
+			select {
+			case <-d.ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				d.pollWatchedPosts()
+			}
+		}
+	}()
+}
+
+// Stop ends polling
+func (d *CommandDispatcher) Stop() {
+	d.cancel()
+}
+
+// Dispatch runs a command of a client and queues the answer for it
+func (d *CommandDispatcher) Dispatch(client *WebSocketClient, command ClientCommand) {
+	data, err := d.run(client, command)
+	if err != nil {
+		client.sendJSON(CommandErrorMessage{
+			Type:      "command_error",
+			Action:    command.Action,
+			RequestID: command.RequestID,
+			Error:     err.Error(),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}, "")
+		return
+	}
+
+	client.sendJSON(CommandResultMessage{
+		Type:      "command_result",
+		Action:    command.Action,
+		RequestID: command.RequestID,
+		Data:      data,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}, "")
+}
+
+// run executes a command and returns the data of its answer
+func (d *CommandDispatcher) run(client *WebSocketClient, command ClientCommand) (interface{}, error) {
+	switch command.Action {
+	case CommandGetTrending:
+		limit := command.Limit
+		if limit == 0 {
+			limit = defaultCommandTrendingLimit
+		}
+		if limit < 0 || limit > maxCommandTrendingLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxCommandTrendingLimit)
+		}
+		posts, err := d.store.GetTrendingPosts(limit)
+		if err != nil {
+			logger.Warnf("WebSocket get_trending failed: %v", err)
+			return nil, errors.New("failed to fetch trending posts")
+		}
+		return posts, nil
+
+	case CommandGetPostStats:
+		return d.postStats(command.PostID)
+
+	case CommandWatchPost:
+		stats, err := d.postStats(command.PostID)
+		if err != nil {
+			return nil, err
+		}
+		if !client.watch(command.PostID, revisionOf(stats)) {
+			return nil, fmt.Errorf("a connection can watch at most %d posts", maxWatchedPosts)
+		}
+		d.mu.Lock()
+		d.watchers[client] = true
+		d.mu.Unlock()
+		return stats, nil
+
+	case CommandUnwatchPost:
+		if command.PostID == "" {
+			return nil, errors.New("post_id is required")
+		}
+		client.unwatch(command.PostID)
+		return nil, nil
+
+	default:
+		return nil, errors.New("unknown action " + command.Action)
+	}
+}
+
+// postStats returns the stats of a post for a command
+func (d *CommandDispatcher) postStats(postID string) (*models.TrendingScore, error) {
+	if postID == "" {
+		return nil, errors.New("post_id is required")
+	}
+	stats, err := d.store.GetPostStats(postID)
+	if err == nil && stats == nil {
+		return nil, errors.New("post not found")
+	}
+	if err != nil {
+		logger.Warnf("WebSocket command failed to fetch stats of post %s: %v", postID, err)
+		return nil, errors.New("failed to fetch post stats")
+	}
+	return stats, nil
+}
+
+// Forget drops the watches of a disconnected client
+func (d *CommandDispatcher) Forget(client *WebSocketClient) {
+	d.mu.Lock()
+	delete(d.watchers, client)
+	d.mu.Unlock()
+}
+
+// pollWatchedPosts reads every watched post once and sends the stats that changed to their
+// watchers
+func (d *CommandDispatcher) pollWatchedPosts() {
+	d.mu.Lock()
+	clients := make([]*WebSocketClient, 0, len(d.watchers))
+	for client := range d.watchers {
+		clients = append(clients, client)
+	}
+	d.mu.Unlock()
+
+	latest := make(map[string]*models.TrendingScore)
+	for _, client := range clients {
+		for _, postID := range client.watchedPosts() {
+			if _, ok := latest[postID]; ok {
+				continue
+			}
+			stats, err := d.store.GetPostStats(postID)
+			if err != nil {
+				logger.Debugf(" Failed to poll watched post %s: %v", postID, err)
+			}
+			latest[postID] = stats
+		}
+	}
+
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	for _, client := range clients {
+		for postID, stats := range latest {
+			if stats == nil || !client.watchChanged(postID, revisionOf(stats)) {
+				continue
+			}
+			// Stats not yet written to a slow client are replaced by newer ones
+			client.sendJSON(PostStatsMessage{
+				Type:      "post_stats",
+				PostID:    postID,
+				Stats:     stats,
+				Timestamp: timestamp,
+			}, "post_stats:"+postID)
+		}
+	}
+}
+
+// sendJSON queues a message for this client only
+func (c *WebSocketClient) sendJSON(message interface{}, key string) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		logger.Warnf("Failed to marshal WebSocket message: %v", err)
+		return
+	}
+	c.queue.push(newWireFrame(data), key)
+}
+
+// watch starts watching a post, false when the client already watches the most it may
+func (c *WebSocketClient) watch(postID string, revision postRevision) bool {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	if _, ok := c.watching[postID]; !ok && len(c.watching) >= maxWatchedPosts {
+		return false
+	}
+	if c.watching == nil {
+		c.watching = make(map[string]postRevision)
+	}
+	c.watching[postID] = revision
+	return true
+}
+
+// unwatch stops watching a post
+func (c *WebSocketClient) unwatch(postID string) {
+	c.watchMu.Lock()
+	delete(c.watching, postID)
+	c.watchMu.Unlock()
+}
+
+// watchedPosts returns the posts the client watches
+func (c *WebSocketClient) watchedPosts() []string {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	postIDs := make([]string, 0, len(c.watching))
+	for postID := range c.watching {
+		postIDs = append(postIDs, postID)
+	}
+	return postIDs
+}
+
+// watchChanged records the latest revision of a post and reports whether the client watches
+// it and was last sent another one
+func (c *WebSocketClient) watchChanged(postID string, revision postRevision) bool {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	sent, ok := c.watching[postID]
+	if !ok || sent.equal(revision) {
+		return false
+	}
+	c.watching[postID] = revision
+	return true
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+)
+
+// memoryCommandStore serves commands from a map of post stats
+type memoryCommandStore struct {
+	mu    sync.Mutex
+	posts map[string]models.TrendingScore
+}
+
+func (s *memoryCommandStore) GetTrendingPosts(limit int) ([]models.TrendingScore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var posts []models.TrendingScore
+	for _, post := range s.posts {
+		if len(posts) < limit {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (s *memoryCommandStore) GetPostStats(postID string) (*models.TrendingScore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post, ok := s.posts[postID]
+	if !ok {
+		return nil, nil
+	}
+	return &post, nil
+}
+
+func (s *memoryCommandStore) set(post models.TrendingScore) {
+	s.mu.Lock()
+	s.posts[post.PostID] = post
+	s.mu.Unlock()
+}
+
+// nextMessage returns the next message queued for a stream client, decoded into a map
+func nextMessage(t *testing.T, client *WebSocketClient) map[string]interface{} {
+	t.Helper()
+	messages, ok := client.Next(context.Background(), time.Second)
+	if !ok || len(messages) == 0 {
+		t.Fatalf("Expected a queued message, got %d (%v)", len(messages), ok)
+	}
+	var message map[string]interface{}
+	if err := json.Unmarshal(messages[0], &message); err != nil {
+		t.Fatalf("Invalid message %s: %v", messages[0], err)
+	}
+	return message
+}
+
+func TestCommandDispatcher(t *testing.T) {
+	store := &memoryCommandStore{posts: map[string]models.TrendingScore{
+		"post-1": {PostID: "post-1", Score: 42, Version: 1},
+		"post-2": {PostID: "post-2", Score: 7, Version: 1},
+	}}
+	dispatcher := NewCommandDispatcher(store, time.Minute)
+	hub := NewWebSocketHub()
+	go hub.Run()
+	client := NewStreamClient(hub, "", "websocket")
+	hub.RegisterClient(client)
+
+	dispatcher.Dispatch(client, ClientCommand{Action: CommandGetTrending, RequestID: "r1", Limit: 1})
+	result := nextMessage(t, client)
+	if result["type"] != "command_result" || result["request_id"] != "r1" || len(result["data"].([]interface{})) != 1 {
+		t.Errorf("Unexpected get_trending result %v", result)
+	}
+
+	for _, command := range []ClientCommand{
+		{Action: "drop_tables"},
+		{Action: CommandGetTrending, Limit: 500},
+		{Action: CommandWatchPost},
+		{Action: CommandWatchPost, PostID: "missing"},
+	} {
+		dispatcher.Dispatch(client, command)
+		if message := nextMessage(t, client); message["type"] != "command_error" || message["error"] == "" {
+			t.Errorf("Expected %+v to fail, got %v", command, message)
+		}
+	}
+
+	// Watching answers with the current stats, then streams them only when they change
+	dispatcher.Dispatch(client, ClientCommand{Action: CommandWatchPost, PostID: "post-1"})
+	if result := nextMessage(t, client); result["type"] != "command_result" || result["data"].(map[string]interface{})["score"] != 42.0 {
+		t.Errorf("Unexpected watch_post result %v", result)
+	}
+	dispatcher.pollWatchedPosts()
+	if messages, _ := client.Next(context.Background(), 20*time.Millisecond); len(messages) != 0 {
+		t.Errorf("Expected unchanged stats not to be sent, got %s", messages[0])
+	}
+
+	store.set(models.TrendingScore{PostID: "post-1", Score: 50, Version: 2})
+	dispatcher.pollWatchedPosts()
+	update := nextMessage(t, client)
+	if update["type"] != "post_stats" || update["post_id"] != "post-1" || update["stats"].(map[string]interface{})["score"] != 50.0 {
+		t.Errorf("Unexpected post_stats message %v", update)
+	}
+
+	dispatcher.Dispatch(client, ClientCommand{Action: CommandUnwatchPost, PostID: "post-1"})
+	nextMessage(t, client)
+	store.set(models.TrendingScore{PostID: "post-1", Score: 60, Version: 3})
+	dispatcher.pollWatchedPosts()
+	if messages, _ := client.Next(context.Background(), 20*time.Millisecond); len(messages) != 0 {
+		t.Errorf("Expected no stats after unwatching, got %s", messages[0])
+	}
+}
+
+func TestCommandDispatcher_WatchLimit(t *testing.T) {
+	store := &memoryCommandStore{posts: map[string]models.TrendingScore{}}
+	client := NewStreamClient(NewWebSocketHub(), "", "websocket")
+	for i := 0; i <= maxWatchedPosts; i++ {
+		postID := "post-" + string(rune('a'+i))
+		store.set(models.TrendingScore{PostID: postID})
+		if _, err := NewCommandDispatcher(store, 0).run(client, ClientCommand{Action: CommandWatchPost, PostID: postID}); (err != nil) != (i == maxWatchedPosts) {
+			t.Fatalf("Watch %d: unexpected error %v", i, err)
+		}
+	}
+	if len(client.watchedPosts()) != maxWatchedPosts {
+		t.Errorf("Expected %d watched posts, got %d", maxWatchedPosts, len(client.watchedPosts()))
+	}
+}
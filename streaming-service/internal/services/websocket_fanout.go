@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/logger"
+	"confluent-viral-intelligence/internal/models"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// fanoutConsumer is the part of the Kafka consumer the fan-out reads the broadcast topic with
+type fanoutConsumer interface {
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+	Subscribe(topic string, rebalanceCb kafka.RebalanceCb) error
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	Close() error
+}
+
+// HubFanout delivers the hub messages relayed through the broadcast topic to this instance's
+// WebSocket and stream clients. Every instance reads the whole topic with a consumer group of
+// its own, starting at the latest offset, since clients only need messages sent while they are
+// connected. The topic must have a single partition: its offsets are the sequence IDs clients
+// resume from, so every instance has to see the broadcasts in the same order.
+type HubFanout struct {
+	consumer fanoutConsumer
+	topic    string
+	hub      *WebSocketHub
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func NewHubFanout(cfg *config.Config, hub *WebSocketHub) (*HubFanout, error) {
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  cfg.ConfluentBootstrapServers,
+		"security.protocol":  cfg.ConfluentSecurityProtocol,
+		"sasl.mechanisms":    cfg.ConfluentSASLMechanism,
+		"sasl.username":      cfg.ConfluentAPIKey,
+		"sasl.password":      cfg.ConfluentAPISecret,
+		"group.id":           fanoutGroupID(),
+		"auto.offset.reset":  "latest",
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fan-out consumer: %w", err)
+	}
+
+	return newHubFanout(c, cfg.TopicHubBroadcasts, hub), nil
+}
+
+func newHubFanout(consumer fanoutConsumer, topic string, hub *WebSocketHub) *HubFanout {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HubFanout{
+		consumer: consumer,
+		topic:    topic,
+		hub:      hub,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// fanoutGroupID returns a consumer group unique to this process, so every instance receives
+// every relayed message
+func fanoutGroupID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return "viral-intelligence-ws-" + host + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// Start checks that the broadcast topic has a single partition, subscribes to it and delivers
+// its messages until Stop
+func (hf *HubFanout) Start() error {
+	if err := hf.checkPartitions(); err != nil {
+		return err
+	}
+	if err := hf.consumer.Subscribe(hf.topic, nil); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", hf.topic, err)
+	}
+
+	logger.Infof("📡 WebSocket fan-out consuming %s", hf.topic)
+	go hf.run()
+	return nil
+}
+
+// checkPartitions fails unless the broadcast topic exists with exactly one partition; with more,
+// sequence IDs taken from offsets would collide and go backwards
+func (hf *HubFanout) checkPartitions() error {
+	metadata, err := hf.consumer.GetMetadata(&hf.topic, false, 10000)
+	if err != nil {
+		return fmt.Errorf("failed to read the metadata of %s: %w", hf.topic, err)
+	}
+	topic, ok := metadata.Topics[hf.topic]
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", hf.topic)
+	}
+	if topic.Error.Code() != kafka.ErrNoError {
+		return fmt.Errorf("topic %s is not available: %w", hf.topic, topic.Error)
+	}
+	if len(topic.Partitions) != 1 {
+		return fmt.Errorf("topic %s has %d partitions; the fan-out needs exactly one so its offsets order broadcasts on every instance", hf.topic, len(topic.Partitions))
+	}
+	return nil
+}
+
+func (hf *HubFanout) run() {
+	defer close(hf.done)
+	for {
+		select {
+		case <-hf.ctx.Done():
+			return
+		default:
+		}
+
+		msg, err := hf.consumer.ReadMessage(100 * time.Millisecond)
+		if err != nil {
+			if kafkaErr, ok := err.(kafka.Error); ok && kafkaErr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			logger.Warnf("WebSocket fan-out consumer error: %v", err)
+			continue
+		}
+
+		var broadcast models.HubBroadcast
+		if err := json.Unmarshal(msg.Value, &broadcast); err != nil || len(broadcast.Message) == 0 {
+			logger.Warnf("Skipping invalid hub broadcast at offset %v: %v", msg.TopicPartition.Offset, err)
+			continue
+		}
+
+		// Offsets of the single-partition topic order broadcasts the same way on every instance;
+		// a partition added since Start would break that order, so its messages are not delivered
+		if msg.TopicPartition.Partition != 0 {
+			logger.Errorf("❌ Skipping hub broadcast from partition %d of %s; the fan-out topic must have a single partition", msg.TopicPartition.Partition, hf.topic)
+			continue
+		}
+		hf.hub.Deliver(broadcast, uint64(msg.TopicPartition.Offset)+1)
+	}
+}
+
+// Stop ends delivery and closes the consumer
+func (hf *HubFanout) Stop() {
+	hf.cancel()
+	<-hf.done
+	if err := hf.consumer.Close(); err != nil {
+		logger.Warnf("Failed to close WebSocket fan-out consumer: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"confluent-viral-intelligence/internal/models"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// scriptedConsumer serves fixed topic metadata and hands out queued messages
+type scriptedConsumer struct {
+	partitions int
+
+	mu       sync.Mutex
+	messages []*kafka.Message
+}
+
+func (c *scriptedConsumer) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error) {
+	metadata := kafka.TopicMetadata{Topic: *topic, Partitions: make([]kafka.PartitionMetadata, c.partitions)}
+	return &kafka.Metadata{Topics: map[string]kafka.TopicMetadata{*topic: metadata}}, nil
+}
+
+func (c *scriptedConsumer) Subscribe(topic string, rebalanceCb kafka.RebalanceCb) error {
+	return nil
+}
+
+func (c *scriptedConsumer) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.messages) == 0 {
+		time.Sleep(time.Millisecond)
+		return nil, kafka.NewError(kafka.ErrTimedOut, "timed out", false)
+	}
+	msg := c.messages[0]
+	c.messages = c.messages[1:]
+	return msg, nil
+}
+
+func (c *scriptedConsumer) Close() error {
+	return nil
+}
+
+// queue adds a relayed trending update of postID at offset to partition
+func (c *scriptedConsumer) queue(t *testing.T, partition int32, offset kafka.Offset, postID string) {
+	t.Helper()
+	message, _ := json.Marshal(TrendingUpdateMessage{Type: "trending_update", PostID: postID})
+	value, err := json.Marshal(models.HubBroadcast{Key: "trending_update:" + postID, Message: message})
+	if err != nil {
+		t.Fatalf("Failed to encode broadcast: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Partition: partition, Offset: offset},
+		Value:          value,
+	})
+}
+
+func TestHubFanoutRequiresOnePartition(t *testing.T) {
+	fanout := newHubFanout(&scriptedConsumer{partitions: 3}, "ws-broadcast", NewWebSocketHub())
+	if err := fanout.Start(); err == nil || !strings.Contains(err.Error(), "3 partitions") {
+		t.Errorf("Expected a topic with 3 partitions to be refused, got %v", err)
+	}
+}
+
+func TestHubFanoutDeliversInOffsetOrder(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+	client := NewStreamClient(hub, "", "sse")
+	hub.RegisterClient(client)
+
+	consumer := &scriptedConsumer{partitions: 1}
+	consumer.queue(t, 0, 10, "post-1")
+	consumer.queue(t, 0, 11, "post-2")
+	consumer.queue(t, 1, 3, "post-stray")
+	consumer.queue(t, 0, 12, "post-3")
+	fanout := newHubFanout(consumer, "ws-broadcast", hub)
+	if err := fanout.Start(); err != nil {
+		t.Fatalf("Failed to start fan-out: %v", err)
+	}
+	defer fanout.Stop()
+
+	type update struct {
+		Seq    uint64 `json:"seq"`
+		PostID string `json:"post_id"`
+	}
+	var received []update
+	for len(received) < 3 {
+		messages, ok := client.Next(context.Background(), time.Second)
+		if !ok || len(messages) == 0 {
+			t.Fatalf("Expected 3 broadcasts, got %+v", received)
+		}
+		for _, message := range messages {
+			var u update
+			if err := json.Unmarshal(message, &u); err != nil {
+				t.Fatalf("Failed to decode %s: %v", message, err)
+			}
+			received = append(received, u)
+		}
+	}
+	expected := []update{{11, "post-1"}, {12, "post-2"}, {13, "post-3"}}
+	for i, u := range expected {
+		if received[i] != u {
+			t.Errorf("Broadcast %d: expected %+v, got %+v", i, u, received[i])
+		}
+	}
+
+	// A client reconnecting after seq 11 gets the rest replayed under the same sequence IDs
+	resumed := NewStreamClient(hub, "", "sse")
+	hub.RegisterClient(resumed)
+	hub.Resume(resumed, 11)
+	var replayed []update
+	for len(replayed) < 2 {
+		messages, ok := resumed.Next(context.Background(), time.Second)
+		if !ok || len(messages) == 0 {
+			t.Fatalf("Expected 2 replayed broadcasts, got %+v", replayed)
+		}
+		for _, message := range messages {
+			var u update
+			json.Unmarshal(message, &u)
+			replayed = append(replayed, u)
+		}
+	}
+	if replayed[0] != expected[1] || replayed[1] != expected[2] {
+		t.Errorf("Expected the broadcasts after seq 11 replayed, got %+v", replayed)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// Wire formats a WebSocket client can negotiate with the Sec-WebSocket-Protocol header or the
+// format query parameter
+const (
+	WireFormatJSON    = "json"
+	WireFormatMsgpack = "msgpack"
+)
+
+// WireFormats are the formats the hub serves, preferred first
+var WireFormats = []string{WireFormatJSON, WireFormatMsgpack}
+
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+// wireFrame is one hub message and its encodings. The hub builds one per broadcast and shares
+// it with every client queue, so each format is serialized and compressed once instead of per
+// client.
+type wireFrame struct {
+	// The message as JSON, which stream clients receive and queues account for
+	data []byte
+
+	mu       sync.Mutex
+	prepared map[string]*websocket.PreparedMessage
+}
+
+func newWireFrame(data []byte) *wireFrame {
+	return &wireFrame{data: data}
+}
+
+// preparedFor returns the frame encoded for a wire format, encoding it on first use. Prepared
+// messages also cache their compressed form, so compressing clients share it as well.
+func (f *wireFrame) preparedFor(format string) (*websocket.PreparedMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if prepared, ok := f.prepared[format]; ok {
+		return prepared, nil
+	}
+
+	messageType, payload := websocket.TextMessage, f.data
+	if format == WireFormatMsgpack {
+		encoded, err := jsonToMsgpack(f.data)
+		if err != nil {
+			return nil, err
+		}
+		messageType, payload = websocket.BinaryMessage, encoded
+	}
+
+	prepared, err := websocket.NewPreparedMessage(messageType, payload)
+	if err != nil {
+		return nil, err
+	}
+	if f.prepared == nil {
+		f.prepared = make(map[string]*websocket.PreparedMessage, len(WireFormats))
+	}
+	f.prepared[format] = prepared
+	return prepared, nil
+}
+
+// jsonToMsgpack re-encodes a JSON message as MessagePack with the same fields. Whole numbers
+// are written as integers so sequence IDs and counts keep their compact encoding.
+func jsonToMsgpack(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON message: %w", err)
+	}
+
+	var encoded []byte
+	if err := codec.NewEncoderBytes(&encoded, msgpackHandle).Encode(compactNumbers(value)); err != nil {
+		return nil, fmt.Errorf("failed to encode MessagePack: %w", err)
+	}
+	return encoded, nil
+}
+
+// compactNumbers replaces the JSON numbers in a decoded value with int64 when they are whole
+// and float64 otherwise
+func compactNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f)
+		}
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = compactNumbers(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = compactNumbers(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// negotiateWireFormat returns the format a client asked for, JSON unless it is MessagePack
+func negotiateWireFormat(format string) string {
+	if format == WireFormatMsgpack {
+		return WireFormatMsgpack
+	}
+	return WireFormatJSON
+}
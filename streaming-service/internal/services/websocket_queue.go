@@ -0,0 +1,164 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// queuedMessage is a message waiting to be written to a client. Messages with the same
+// non-empty key supersede each other while queued.
+type queuedMessage struct {
+	frame *wireFrame
+	key   string
+}
+
+// clientQueue is the bounded outbound queue of one client. The hub pushes without ever
+// blocking on a slow client: a newer message replaces a queued one with the same key (e.g.
+// trending updates of one post), and when the queue is full the oldest message is dropped.
+// The client's writer drains it whenever ready is signalled, and ready is closed with the queue.
+type clientQueue struct {
+	mu       sync.Mutex
+	messages []queuedMessage
+	capacity int
+	ready    chan struct{}
+	closed   bool
+
+	sent          uint64
+	dropped       uint64
+	coalesced     uint64
+	maxDepth      int
+	lastDroppedAt time.Time
+}
+
+func newClientQueue(capacity int) *clientQueue {
+	return &clientQueue{
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push queues a message, returning false when the queue is closed. Messages are accounted to
+// the WebSocket memory pool while queued; one that does not fit the pool is dropped.
+func (q *clientQueue) push(frame *wireFrame, key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+
+	if key != "" {
+		for i := range q.messages {
+			if q.messages[i].key != key {
+				continue
+			}
+			if !Memory.Reserve(MemoryPoolWebSocket, int64(len(frame.data))) {
+				q.dropLocked()
+				return true
+			}
+			Memory.Release(MemoryPoolWebSocket, int64(len(q.messages[i].frame.data)))
+			q.messages[i].frame = frame
+			q.coalesced++
+			return true
+		}
+	}
+
+	if !Memory.Reserve(MemoryPoolWebSocket, int64(len(frame.data))) {
+		q.dropLocked()
+		return true
+	}
+	if len(q.messages) >= q.capacity {
+		Memory.Release(MemoryPoolWebSocket, int64(len(q.messages[0].frame.data)))
+		q.messages = q.messages[1:]
+		q.dropLocked()
+	}
+	q.messages = append(q.messages, queuedMessage{frame: frame, key: key})
+	q.maxDepth = max(q.maxDepth, len(q.messages))
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// dropLocked counts a dropped message; callers hold the lock
+func (q *clientQueue) dropLocked() {
+	q.dropped++
+	q.lastDroppedAt = time.Now()
+}
+
+// drain removes and returns every queued message, oldest first
+func (q *clientQueue) drain() []*wireFrame {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	messages := make([]*wireFrame, len(q.messages))
+	for i, message := range q.messages {
+		messages[i] = message.frame
+		Memory.Release(MemoryPoolWebSocket, int64(len(message.frame.data)))
+	}
+	q.messages = nil
+	q.sent += uint64(len(messages))
+	return messages
+}
+
+// room returns how many messages fit before the oldest are dropped
+func (q *clientQueue) room() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.capacity - len(q.messages)
+}
+
+// close discards the queued messages and wakes the writer so it can stop
+func (q *clientQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	for _, message := range q.messages {
+		Memory.Release(MemoryPoolWebSocket, int64(len(message.frame.data)))
+	}
+	q.messages = nil
+	close(q.ready)
+}
+
+// WebSocketClientStats are the queue and liveness metrics of one connected client
+type WebSocketClientStats struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id,omitempty"`
+	RemoteAddr    string     `json:"remote_addr,omitempty"`
+	Transport     string     `json:"transport"` // websocket, sse or grpc
+	Format        string     `json:"format"`    // json or msgpack
+	ConnectedAt   time.Time  `json:"connected_at"`
+	Subscriptions []string   `json:"subscriptions"`
+	LastPingAt    *time.Time `json:"last_ping_at,omitempty"` // WebSocket heartbeat only
+	LastPongAt    *time.Time `json:"last_pong_at,omitempty"`
+	RTTMs         float64    `json:"rtt_ms,omitempty"`
+	QueueDepth    int        `json:"queue_depth"`
+	MaxQueueDepth int        `json:"max_queue_depth"`
+	Sent          uint64     `json:"sent"`
+	Dropped       uint64     `json:"dropped"`
+	Coalesced     uint64     `json:"coalesced"`
+	LastDroppedAt *time.Time `json:"last_dropped_at,omitempty"`
+}
+
+// stats returns the queue metrics of a client
+func (q *clientQueue) stats() WebSocketClientStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := WebSocketClientStats{
+		QueueDepth:    len(q.messages),
+		MaxQueueDepth: q.maxDepth,
+		Sent:          q.sent,
+		Dropped:       q.dropped,
+		Coalesced:     q.coalesced,
+	}
+	if !q.lastDroppedAt.IsZero() {
+		lastDroppedAt := q.lastDroppedAt
+		stats.LastDroppedAt = &lastDroppedAt
+	}
+	return stats
+}
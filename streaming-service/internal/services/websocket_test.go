@@ -1,14 +1,20 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"confluent-viral-intelligence/internal/config"
+	"confluent-viral-intelligence/internal/models"
 	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
 )
 
 var upgrader = websocket.Upgrader{
@@ -53,7 +59,7 @@ func TestWebSocketHub_ClientRegistration(t *testing.T) {
 		}
 		defer conn.Close()
 
-		client := NewWebSocketClient(conn, hub)
+		client := NewWebSocketClient(conn, hub, "", "")
 		hub.register <- client
 
 		// Wait a bit for registration
@@ -98,7 +104,7 @@ func TestWebSocketHub_BroadcastTrendingUpdate(t *testing.T) {
 		}
 		defer conn.Close()
 
-		client := NewWebSocketClient(conn, hub)
+		client := NewWebSocketClient(conn, hub, "", "")
 		hub.register <- client
 		client.Start()
 
@@ -178,7 +184,7 @@ func TestWebSocketHub_BroadcastViralAlert(t *testing.T) {
 		}
 		defer conn.Close()
 
-		client := NewWebSocketClient(conn, hub)
+		client := NewWebSocketClient(conn, hub, "", "")
 		hub.register <- client
 		client.Start()
 
@@ -208,7 +214,7 @@ func TestWebSocketHub_BroadcastViralAlert(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// Broadcast a viral alert
-	hub.BroadcastViralAlert("post456", 0.85, 120.0)
+	hub.BroadcastViralAlert("post456", config.ViralTierViral, 0.85, 120.0)
 
 	// Wait for message
 	select {
@@ -228,6 +234,10 @@ func TestWebSocketHub_BroadcastViralAlert(t *testing.T) {
 			t.Errorf("Expected PostID 'post456', got '%s'", alert.PostID)
 		}
 
+		if alert.Tier != config.ViralTierViral {
+			t.Errorf("Expected Tier 'viral', got '%s'", alert.Tier)
+		}
+
 		if alert.ViralProbability != 0.85 {
 			t.Errorf("Expected ViralProbability 0.85, got %.2f", alert.ViralProbability)
 		}
@@ -260,7 +270,7 @@ func TestWebSocketHub_MultipleClients(t *testing.T) {
 		}
 		defer conn.Close()
 
-		client := NewWebSocketClient(conn, hub)
+		client := NewWebSocketClient(conn, hub, "", "")
 		hub.register <- client
 		client.Start()
 
@@ -326,7 +336,7 @@ func TestNewWebSocketClient(t *testing.T) {
 		}
 		defer conn.Close()
 
-		client := NewWebSocketClient(conn, hub)
+		client := NewWebSocketClient(conn, hub, "", "")
 		
 		if client == nil {
 			t.Fatal("Expected client to be created, got nil")
@@ -336,8 +346,8 @@ func TestNewWebSocketClient(t *testing.T) {
 			t.Error("Expected conn to be set")
 		}
 
-		if client.send == nil {
-			t.Error("Expected send channel to be initialized")
+		if client.queue == nil {
+			t.Error("Expected send queue to be initialized")
 		}
 
 		if client.hub != hub {
@@ -372,7 +382,7 @@ func TestWebSocketHub_GetClientCount(t *testing.T) {
 		}
 		defer conn.Close()
 
-		client := NewWebSocketClient(conn, hub)
+		client := NewWebSocketClient(conn, hub, "", "")
 		hub.register <- client
 
 		time.Sleep(200 * time.Millisecond)
@@ -394,3 +404,336 @@ func TestWebSocketHub_GetClientCount(t *testing.T) {
 		t.Errorf("Expected 1 client, got %d", hub.GetClientCount())
 	}
 }
+
+func TestWebSocketHub_SendToUser(t *testing.T) {
+	hub := NewWebSocketHub()
+	creator := NewStreamClient(hub, "creator", "sse")
+	otherTab := NewStreamClient(hub, "creator", "sse")
+	viewer := NewStreamClient(hub, "viewer", "sse")
+	anonymous := NewStreamClient(hub, "", "sse")
+	for _, client := range []*WebSocketClient{creator, otherTab, viewer, anonymous} {
+		hub.clients[client] = true
+	}
+
+	hub.SendCreatorViralAlert("creator", "post-1", config.ViralTierViral, 0.82)
+
+	for _, client := range []*WebSocketClient{creator, otherTab} {
+		var message CreatorViralAlertMessage
+		messages := client.queue.drain()
+		if len(messages) != 1 {
+			t.Fatalf("Expected one message, got %d", len(messages))
+		}
+		if err := json.Unmarshal(messages[0].data, &message); err != nil {
+			t.Fatalf("Failed to unmarshal message: %v", err)
+		}
+		if message.Type != "creator_viral_alert" || message.PostID != "post-1" || message.Message != "Your post is going viral!" {
+			t.Errorf("Unexpected creator alert: %+v", message)
+		}
+	}
+	if len(viewer.queue.drain()) != 0 || len(anonymous.queue.drain()) != 0 {
+		t.Error("Expected only the creator's connections to receive the alert")
+	}
+	if sent := hub.SendToUser("", []byte("{}")); sent != 0 {
+		t.Errorf("Expected nothing sent to anonymous connections, got %d", sent)
+	}
+}
+
+func TestWebSocketHub_ReplayMissedBroadcasts(t *testing.T) {
+	hub := NewWebSocketHub()
+	for i := 0; i < replayBufferSize+5; i++ {
+		hub.record([]byte(`{"type":"trending_update"}`), 0)
+	}
+
+	var update struct {
+		Seq  uint64 `json:"seq"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(hub.history[hub.oldest].frame.data, &update); err != nil || update.Seq != 6 || update.Type != "trending_update" {
+		t.Fatalf("Expected the oldest buffered broadcast to be seq 6, got %+v (%v)", update, err)
+	}
+
+	missed, complete := hub.missedSince(uint64(replayBufferSize + 2))
+	if !complete || len(missed) != 3 || missed[0].seq != uint64(replayBufferSize+3) {
+		t.Errorf("Expected the last 3 broadcasts, got %d (complete %v)", len(missed), complete)
+	}
+	if missed, complete := hub.missedSince(2); complete || len(missed) != replayBufferSize {
+		t.Errorf("Expected a gap before the buffer, got %d (complete %v)", len(missed), complete)
+	}
+	if missed, complete := hub.missedSince(hub.seq); !complete || len(missed) != 0 {
+		t.Errorf("Expected nothing missed by an up-to-date client, got %d", len(missed))
+	}
+
+	// A resuming client gets a gap notice and the most recent broadcasts its buffer holds
+	client := NewStreamClient(hub, "", "sse")
+	hub.clients[client] = true
+	hub.replay(client, 2)
+	messages := client.queue.drain()
+	var gap ReplayGapMessage
+	if err := json.Unmarshal(messages[0].data, &gap); err != nil || gap.Type != "replay_gap" || gap.OldestSeq != hub.seq-sendBufferSize+2 {
+		t.Errorf("Expected a replay gap notice, got %+v (%v)", gap, err)
+	}
+	if len(messages) != sendBufferSize {
+		t.Errorf("Expected %d replayed broadcasts, got %d", sendBufferSize-1, len(messages)-1)
+	}
+}
+
+func TestStreamClient(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+
+	client := NewStreamClient(hub, "", "sse")
+	hub.RegisterClient(client)
+
+	// Nothing queued yet: the wait times out so the caller can send a heartbeat
+	if messages, ok := client.Next(context.Background(), 10*time.Millisecond); !ok || len(messages) != 0 {
+		t.Fatalf("Expected an empty wait, got %d messages (%v)", len(messages), ok)
+	}
+
+	hub.BroadcastTrendingUpdate("post-1", 42, 7)
+	messages, ok := client.Next(context.Background(), time.Second)
+	if !ok || len(messages) != 1 {
+		t.Fatalf("Expected the broadcast, got %d messages (%v)", len(messages), ok)
+	}
+	var update TrendingUpdateMessage
+	if err := json.Unmarshal(messages[0], &update); err != nil || update.PostID != "post-1" {
+		t.Errorf("Unexpected message %s (%v)", messages[0], err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := client.Next(ctx, time.Second); ok {
+		t.Error("Expected a cancelled context to end the stream")
+	}
+
+	client.Close()
+	time.Sleep(50 * time.Millisecond)
+	if hub.GetClientCount() != 0 {
+		t.Errorf("Expected the stream client to be unregistered, got %d clients", hub.GetClientCount())
+	}
+}
+
+func TestWebSocketHub_ClientLivenessAndDisconnect(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+
+	stream := NewStreamClient(hub, "user-1", "sse")
+	grpcClient := NewStreamClient(hub, "", "grpc")
+	grpcClient.SetSubscriptions("viral_alert", "tier:viral")
+	hub.RegisterClient(stream)
+	hub.RegisterClient(grpcClient)
+	time.Sleep(50 * time.Millisecond)
+	if stream.ID() == grpcClient.ID() || !strings.HasPrefix(stream.ID(), "sse-") {
+		t.Fatalf("Expected distinct transport-prefixed IDs, got %s and %s", stream.ID(), grpcClient.ID())
+	}
+
+	// Pongs echo the ping's send time, which measures the round trip
+	sentAt := time.Now().Add(-25 * time.Millisecond)
+	stream.pinged(sentAt)
+	stream.ponged(strconv.FormatInt(sentAt.UnixNano(), 10))
+
+	stats := make(map[string]WebSocketClientStats)
+	for _, client := range hub.ClientStats() {
+		stats[client.ID] = client
+	}
+	sse := stats[stream.ID()]
+	if len(sse.Subscriptions) != 2 || sse.Subscriptions[1] != "user:user-1" {
+		t.Errorf("Expected broadcasts and the user's messages, got %v", sse.Subscriptions)
+	}
+	if sse.LastPingAt == nil || sse.LastPongAt == nil || sse.RTTMs < 25 {
+		t.Errorf("Unexpected liveness %+v", sse)
+	}
+	if subscriptions := stats[grpcClient.ID()].Subscriptions; len(subscriptions) != 2 || subscriptions[0] != "viral_alert" {
+		t.Errorf("Unexpected gRPC subscriptions %v", subscriptions)
+	}
+
+	if hub.Disconnect("sse-unknown") {
+		t.Error("Expected disconnecting an unknown client to fail")
+	}
+	if !hub.Disconnect(stream.ID()) {
+		t.Fatal("Expected the stream client to be disconnected")
+	}
+	if _, ok := stream.Next(context.Background(), time.Second); ok {
+		t.Error("Expected a disconnected client's stream to end")
+	}
+	if hub.GetClientCount() != 1 {
+		t.Errorf("Expected one client left, got %d", hub.GetClientCount())
+	}
+}
+
+func TestClientQueue_DropPolicies(t *testing.T) {
+	queue := newClientQueue(3)
+
+	// Trending updates of a post coalesce into the latest one, keeping its place in the queue
+	queue.push(newWireFrame([]byte(`{"post_id":"a","score":1}`)), "trending_update:a")
+	queue.push(newWireFrame([]byte(`{"type":"viral_alert"}`)), "")
+	queue.push(newWireFrame([]byte(`{"post_id":"a","score":2}`)), "trending_update:a")
+	if stats := queue.stats(); stats.QueueDepth != 2 || stats.Coalesced != 1 {
+		t.Fatalf("Expected 2 queued messages and 1 coalesced, got %+v", stats)
+	}
+
+	// A full queue drops its oldest message
+	queue.push(newWireFrame([]byte(`{"post_id":"b"}`)), "trending_update:b")
+	queue.push(newWireFrame([]byte(`{"post_id":"c"}`)), "trending_update:c")
+	stats := queue.stats()
+	if stats.Dropped != 1 || stats.MaxQueueDepth != 3 || stats.LastDroppedAt == nil {
+		t.Errorf("Expected one dropped message, got %+v", stats)
+	}
+
+	messages := queue.drain()
+	if len(messages) != 3 || string(messages[0].data) != `{"type":"viral_alert"}` || string(messages[2].data) != `{"post_id":"c"}` {
+		t.Errorf("Unexpected queue contents %d", len(messages))
+	}
+	if stats := queue.stats(); stats.Sent != 3 || stats.QueueDepth != 0 {
+		t.Errorf("Expected 3 sent and an empty queue, got %+v", stats)
+	}
+
+	queue.close()
+	if queue.push(newWireFrame([]byte(`{}`)), "") {
+		t.Error("Expected a closed queue to refuse messages")
+	}
+	// The signal of the last push is still pending; after it the closed channel wakes the writer
+	<-queue.ready
+	if _, ok := <-queue.ready; ok {
+		t.Error("Expected closing the queue to wake its writer")
+	}
+}
+
+func TestWireFrame_Formats(t *testing.T) {
+	frame := newWireFrame([]byte(`{"seq":3,"type":"trending_update","score":1.5,"view_count":7,"keywords":["a"]}`))
+
+	jsonFrame, err := frame.preparedFor(WireFormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to prepare JSON frame: %v", err)
+	}
+	if again, _ := frame.preparedFor(WireFormatJSON); again != jsonFrame {
+		t.Error("Expected the JSON frame to be encoded once and cached")
+	}
+
+	encoded, err := jsonToMsgpack(frame.data)
+	if err != nil {
+		t.Fatalf("Failed to encode MessagePack: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := decodeMsgpack(encoded, &decoded); err != nil {
+		t.Fatalf("Failed to decode MessagePack: %v", err)
+	}
+	if decoded["seq"] != int64(3) || decoded["score"] != 1.5 || decoded["type"] != "trending_update" {
+		t.Errorf("Unexpected MessagePack message %+v", decoded)
+	}
+	if len(encoded) >= len(frame.data) {
+		t.Errorf("Expected MessagePack to be smaller than JSON, got %d >= %d bytes", len(encoded), len(frame.data))
+	}
+
+	// A client negotiating MessagePack and compression receives binary frames over the wire
+	hub := NewWebSocketHub()
+	go hub.Run()
+	registered := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{EnableCompression: true, Subprotocols: WireFormats}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade connection: %v", err)
+			return
+		}
+		client := NewWebSocketClient(conn, hub, "", conn.Subprotocol())
+		hub.RegisterClient(client)
+		client.Start()
+		close(registered)
+	}))
+	defer server.Close()
+
+	dialer := websocket.Dialer{EnableCompression: true, Subprotocols: []string{WireFormatMsgpack}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial WebSocket: %v", err)
+	}
+	defer conn.Close()
+	<-registered
+
+	hub.BroadcastTrendingUpdate("post-1", 42, 7)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	var update map[string]interface{}
+	if messageType != websocket.BinaryMessage {
+		t.Fatalf("Expected a binary frame, got type %d", messageType)
+	}
+	if err := decodeMsgpack(data, &update); err != nil || update["post_id"] != "post-1" || update["view_count"] != int64(7) {
+		t.Errorf("Unexpected update %+v (%v)", update, err)
+	}
+}
+
+func decodeMsgpack(data []byte, v interface{}) error {
+	var handle codec.MsgpackHandle
+	handle.RawToString = true
+	return codec.NewDecoderBytes(data, &handle).Decode(v)
+}
+
+type recordingRelay struct {
+	mu         sync.Mutex
+	broadcasts []models.HubBroadcast
+}
+
+func (r *recordingRelay) PublishHubBroadcast(broadcast models.HubBroadcast) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.broadcasts = append(r.broadcasts, broadcast)
+	return nil
+}
+
+func TestWebSocketHub_Relay(t *testing.T) {
+	hub := NewWebSocketHub()
+	relay := &recordingRelay{}
+	hub.UseRelay(relay)
+	go hub.Run()
+
+	creator := NewStreamClient(hub, "creator", "sse")
+	viewer := NewStreamClient(hub, "viewer", "sse")
+	hub.RegisterClient(creator)
+	hub.RegisterClient(viewer)
+
+	// Messages go to the relay instead of straight to local clients
+	hub.BroadcastTrendingUpdate("post-1", 42, 7)
+	hub.SendCreatorViralAlert("creator", "post-1", config.ViralTierViral, 0.82)
+	if messages, _ := viewer.Next(context.Background(), 20*time.Millisecond); len(messages) != 0 {
+		t.Fatalf("Expected no local delivery before the relay, got %d messages", len(messages))
+	}
+	relay.mu.Lock()
+	broadcasts := relay.broadcasts
+	relay.mu.Unlock()
+	if len(broadcasts) != 2 || broadcasts[0].Key != "trending_update:post-1" || broadcasts[1].UserID != "creator" {
+		t.Fatalf("Unexpected relayed broadcasts %+v", broadcasts)
+	}
+
+	// Relayed broadcasts are delivered under the topic's sequence ID
+	for _, broadcast := range broadcasts {
+		hub.Deliver(broadcast, 42)
+	}
+	messages, ok := viewer.Next(context.Background(), time.Second)
+	var update struct {
+		Seq    uint64 `json:"seq"`
+		PostID string `json:"post_id"`
+	}
+	if !ok || len(messages) != 1 {
+		t.Fatalf("Expected the viewer to receive only the trending update, got %d messages", len(messages))
+	}
+	if err := json.Unmarshal(messages[0], &update); err != nil || update.Seq != 42 || update.PostID != "post-1" {
+		t.Errorf("Unexpected relayed update %s (%v)", messages[0], err)
+	}
+
+	var received []string
+	for len(received) < 2 {
+		messages, ok := creator.Next(context.Background(), time.Second)
+		if !ok || len(messages) == 0 {
+			t.Fatalf("Expected the creator to receive both messages, got %q", received)
+		}
+		for _, message := range messages {
+			received = append(received, string(message))
+		}
+	}
+	if !strings.Contains(received[1], `"creator_viral_alert"`) {
+		t.Errorf("Expected the creator alert, got %s", received[1])
+	}
+}
@@ -7,6 +7,8 @@ set -e
 
 # Configuration
 API_URL="${API_URL:-http://localhost:8080}"
+# API key of the ingest role, sent as X-API-Key; the ingestion routes reject requests without one
+API_KEY="${API_KEY:-}"
 VERBOSE="${VERBOSE:-false}"
 
 # Colors for output
@@ -45,6 +47,7 @@ make_request() {
     
     response=$(curl -s -w "\n%{http_code}" -X POST \
         -H "Content-Type: application/json" \
+        -H "X-API-Key: ${API_KEY}" \
         -d "$data" \
         "${API_URL}${endpoint}")
     
@@ -72,6 +75,12 @@ get_random_user() {
     echo "${users[$RANDOM % ${#users[@]}]}"
 }
 
+# Generate random region a view comes from
+get_random_region() {
+    local regions=("US-CA" "US-NY" "GB" "DE" "TR" "BR" "IN" "JP")
+    echo "${regions[$RANDOM % ${#regions[@]}]}"
+}
+
 # Generate random post ID
 get_random_post() {
     echo "post_$(printf '%04d' $((RANDOM % 100 + 1)))"
@@ -96,7 +105,7 @@ create_content() {
 EOF
 )
     
-    make_request "/api/events/content" "$data"
+    make_request "/api/v1/events/content" "$data"
 }
 
 # Generate interaction event
@@ -116,7 +125,7 @@ create_interaction() {
 EOF
 )
     
-    make_request "/api/events/interaction" "$data"
+    make_request "/api/v1/events/interaction" "$data"
 }
 
 # Generate view event
@@ -133,12 +142,13 @@ create_view() {
     "user_id": "$user_id",
     "viewed_at": "$timestamp",
     "duration": $duration,
-    "platform": "$platform"
+    "platform": "$platform",
+    "region": "$(get_random_region)"
 }
 EOF
 )
     
-    make_request "/api/events/view" "$data"
+    make_request "/api/v1/events/view" "$data"
 }
 
 # Generate remix event
@@ -153,12 +163,13 @@ create_remix() {
     "original_post_id": "$original_post_id",
     "remix_post_id": "$remix_post_id",
     "user_id": "$user_id",
-    "remixed_at": "$timestamp"
+    "remixed_at": "$timestamp",
+    "remix_type": "variation"
 }
 EOF
 )
     
-    make_request "/api/events/remix" "$data"
+    make_request "/api/v1/events/remix" "$data"
 }
 
 # Main test scenarios
@@ -176,6 +187,11 @@ main() {
         log_success "API is reachable"
         echo ""
     fi
+
+    if [ -z "$API_KEY" ]; then
+        log_warning "API_KEY is not set; the service rejects events unless it allows unauthenticated requests."
+        echo ""
+    fi
     
     # Scenario 1: Create viral content with high engagement
     log_info "=== Scenario 1: Viral Content ==="
@@ -429,7 +445,7 @@ main() {
     echo "  4. Open dashboard to see real-time updates"
     echo ""
     log_info "To monitor events in real-time, run:"
-    echo "  watch -n 2 'curl -s ${API_URL}/api/analytics/trending?limit=10 | jq'"
+    echo "  watch -n 2 'curl -s ${API_URL}/api/v1/analytics/trending?limit=10 | jq'"
 }
 
 # Parse command line arguments
@@ -439,6 +455,10 @@ while [[ $# -gt 0 ]]; do
             API_URL="$2"
             shift 2
             ;;
+        --api-key)
+            API_KEY="$2"
+            shift 2
+            ;;
         --verbose)
             VERBOSE=true
             shift
@@ -448,17 +468,19 @@ while [[ $# -gt 0 ]]; do
             echo ""
             echo "Options:"
             echo "  --api-url URL     Set the API URL (default: http://localhost:8080)"
+            echo "  --api-key KEY     Set the API key of the ingest role"
             echo "  --verbose         Enable verbose output"
             echo "  --help            Show this help message"
             echo ""
             echo "Environment variables:"
             echo "  API_URL           Set the API URL"
+            echo "  API_KEY           Set the API key of the ingest role"
             echo "  VERBOSE           Enable verbose output (true/false)"
             echo ""
             echo "Examples:"
             echo "  $0"
             echo "  $0 --api-url https://viral-intelligence-streaming-xxxxx.run.app"
-            echo "  API_URL=http://localhost:8080 VERBOSE=true $0"
+            echo "  API_URL=http://localhost:8080 API_KEY=dev-key VERBOSE=true $0"
             exit 0
             ;;
         *)